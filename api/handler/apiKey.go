@@ -0,0 +1,220 @@
+package handler
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/OVillas/autentication/domain"
+	"github.com/OVillas/autentication/util"
+	"github.com/labstack/echo/v4"
+	"github.com/samber/do"
+)
+
+type apiKeyHandler struct {
+	i             *do.Injector
+	apiKeyService domain.ApiKeyService
+}
+
+func NewApiKeyHandler(i *do.Injector) (domain.ApiKeyHandler, error) {
+	apiKeyService := do.MustInvoke[domain.ApiKeyService](i)
+	return &apiKeyHandler{
+		i:             i,
+		apiKeyService: apiKeyService,
+	}, nil
+}
+
+// Create godoc
+// @Summary Create an api key
+// @Description Mint an api key for another service. The key is only returned once
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param apiKey body domain.ApiKeyPayLoad true "Api Key Payload"
+// @Success 201 {object} domain.ApiKeyResponse
+// @Failure 401
+// @Failure 422 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /v1/admin/api-keys [post]
+func (akh *apiKeyHandler) Create(c echo.Context) error {
+	log := slog.With(
+		slog.String("func", "Create"),
+		slog.String("handler", "apiKey"))
+
+	log.Info("Create initiated")
+
+	var payLoad domain.ApiKeyPayLoad
+	if err := c.Bind(&payLoad); err != nil {
+		log.Warn("Failed to bind api key data to domain")
+		return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
+			Error:     "Unprocessable Entity",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err := payLoad.Validate(); err != nil {
+		log.Warn("Invalid api key data")
+		return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
+			Error:     "Unprocessable Entity",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	response, err := akh.apiKeyService.Create(newViewer(c), payLoad)
+	if err != nil {
+		log.Error("Error trying to call create api key service.")
+		return c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
+			Error:     "Internal Server Error",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	log.Info("Api key created successfully")
+	return c.JSON(http.StatusCreated, response)
+}
+
+// GetAll godoc
+// @Summary List api keys
+// @Description List every api key, revoked ones included
+// @Tags admin
+// @Produce json
+// @Success 200 {array} domain.ApiKeyResponse
+// @Failure 401
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /v1/admin/api-keys [get]
+func (akh *apiKeyHandler) GetAll(c echo.Context) error {
+	log := slog.With(
+		slog.String("func", "GetAll"),
+		slog.String("handler", "apiKey"))
+
+	response, err := akh.apiKeyService.GetAll()
+	if err != nil {
+		log.Error("Error trying to call get api keys service.")
+		return c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
+			Error:     "Internal Server Error",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	log.Info("Api keys successfully retrieved")
+
+	if len(response) == 0 {
+		return c.NoContent(http.StatusNoContent)
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+// Rotate godoc
+// @Summary Rotate an api key
+// @Description Replace the secret of an api key. The previous secret stops working immediately
+// @Tags admin
+// @Produce json
+// @Param id path string true "Api Key ID"
+// @Success 200 {object} domain.ApiKeyResponse
+// @Failure 400 {object} domain.ErrorResponse
+// @Failure 401
+// @Failure 404 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /v1/admin/api-keys/{id}/rotate [post]
+func (akh *apiKeyHandler) Rotate(c echo.Context) error {
+	log := slog.With(
+		slog.String("func", "Rotate"),
+		slog.String("handler", "apiKey"))
+
+	id := c.Param("id")
+	if err := util.IsValidUUID(id); err != nil {
+		log.Warn("Invalid params")
+		return c.JSON(http.StatusBadRequest, domain.ErrorResponse{
+			Error:     "Bad Request",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	response, err := akh.apiKeyService.Rotate(newViewer(c), id)
+	if err != nil && errors.Is(err, domain.ErrApiKeyNotFound) {
+		log.Warn("Api key not found to rotate")
+		return c.JSON(http.StatusNotFound, domain.ErrorResponse{
+			Error:     "Not Found",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err != nil {
+		log.Error("Error trying to call rotate api key service.")
+		return c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
+			Error:     "Internal Server Error",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	log.Info("Api key rotated successfully")
+	return c.JSON(http.StatusOK, response)
+}
+
+// Revoke godoc
+// @Summary Revoke an api key
+// @Description Revoke an api key so it can no longer authenticate
+// @Tags admin
+// @Param id path string true "Api Key ID"
+// @Success 204
+// @Failure 400 {object} domain.ErrorResponse
+// @Failure 401
+// @Failure 404 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /v1/admin/api-keys/{id} [delete]
+func (akh *apiKeyHandler) Revoke(c echo.Context) error {
+	log := slog.With(
+		slog.String("func", "Revoke"),
+		slog.String("handler", "apiKey"))
+
+	id := c.Param("id")
+	if err := util.IsValidUUID(id); err != nil {
+		log.Warn("Invalid params")
+		return c.JSON(http.StatusBadRequest, domain.ErrorResponse{
+			Error:     "Bad Request",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	err := akh.apiKeyService.Revoke(newViewer(c), id)
+	if err != nil && errors.Is(err, domain.ErrApiKeyNotFound) {
+		log.Warn("Api key not found to revoke")
+		return c.JSON(http.StatusNotFound, domain.ErrorResponse{
+			Error:     "Not Found",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err != nil {
+		log.Error("Error trying to call revoke api key service.")
+		return c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
+			Error:     "Internal Server Error",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	log.Info("Api key revoked successfully")
+	return c.NoContent(http.StatusNoContent)
+}
@@ -0,0 +1,83 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/OVillas/autentication/domain"
+	"github.com/labstack/echo/v4"
+	"github.com/samber/do"
+)
+
+type auditHandler struct {
+	i            *do.Injector
+	auditService domain.AuditService
+}
+
+func NewAuditHandler(i *do.Injector) (domain.AuditHandler, error) {
+	auditService := do.MustInvoke[domain.AuditService](i)
+	return &auditHandler{
+		i:            i,
+		auditService: auditService,
+	}, nil
+}
+
+// GetAll godoc
+// @Summary Get the audit trail
+// @Description Get one page of the security relevant events, newest first: logins, password, email and two-factor changes, role changes, deletions and admin actions. Entries are written in the background, so the latest ones can take a second to show up
+// @Tags admin
+// @Produce json
+// @Param actor query string false "ID of the user who performed the action"
+// @Param target query string false "ID of the user, role or credential the event is about"
+// @Param event query string false "Event type, such as login_failed"
+// @Param from query string false "At or after, RFC 3339"
+// @Param to query string false "At or before, RFC 3339"
+// @Param page query int false "Page, starting at 1" default(1)
+// @Param per_page query int false "Entries per page, at most 100" default(20)
+// @Param cursor query string false "next_cursor of the previous page, instead of page"
+// @Success 200 {object} domain.AuditPage
+// @Failure 401
+// @Failure 422 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /v1/admin/audit [get]
+func (ah *auditHandler) GetAll(c echo.Context) error {
+	log := slog.With(
+		slog.String("func", "GetAll"),
+		slog.String("handler", "audit"))
+
+	var query domain.AuditQuery
+	if err := c.Bind(&query); err != nil {
+		log.Warn("Failed to bind audit query to domain")
+		return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
+			Error:     "Unprocessable Entity",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err := query.Validate(); err != nil {
+		log.Warn("Invalid audit query")
+		return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
+			Error:     "Unprocessable Entity",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	auditPage, err := ah.auditService.GetAll(query)
+	if err != nil {
+		log.Error("Error trying to call get audit entries service.")
+		return c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
+			Error:     "Internal Server Error",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	log.Info("Audit entries successfully retrieved")
+	return c.JSON(http.StatusOK, auditPage)
+}
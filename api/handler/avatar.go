@@ -0,0 +1,170 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/OVillas/autentication/config"
+	"github.com/OVillas/autentication/domain"
+	"github.com/OVillas/autentication/util"
+	"github.com/labstack/echo/v4"
+	"github.com/samber/do"
+)
+
+// avatarFormOverhead is what the multipart form may take besides the avatar itself.
+const avatarFormOverhead = 64 * 1024
+
+type avatarHandler struct {
+	i             *do.Injector
+	avatarService domain.AvatarService
+}
+
+func NewAvatarHandler(i *do.Injector) (domain.AvatarHandler, error) {
+	avatarService := do.MustInvoke[domain.AvatarService](i)
+	return &avatarHandler{
+		i:             i,
+		avatarService: avatarService,
+	}, nil
+}
+
+// Upload godoc
+// @Summary Upload my avatar
+// @Description Replace the avatar of the caller with a JPEG, PNG or GIF image of at most 1MB by default and 4096 pixels wide and high. The image is encoded again, so its EXIF and other metadata are not kept
+// @Tags users
+// @Accept multipart/form-data
+// @Produce json
+// @Param avatar formData file true "Avatar image"
+// @Success 200 {object} domain.AvatarResponse
+// @Failure 401
+// @Failure 404 {object} domain.ErrorResponse
+// @Failure 413 {object} domain.ErrorResponse
+// @Failure 422 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /v1/users/me/avatar [put]
+// @Security bearerToken
+func (ah *avatarHandler) Upload(c echo.Context) error {
+	log := slog.With(
+		slog.String("func", "Upload"),
+		slog.String("handler", "avatar"))
+
+	idFromToken, err := util.ExtractUserIdFromToken(c)
+	if err != nil {
+		log.Warn("Error getting id from token")
+		return c.NoContent(http.StatusUnauthorized)
+	}
+
+	request := c.Request()
+	request.Body = http.MaxBytesReader(c.Response(), request.Body, config.Avatar.MaxSize+avatarFormOverhead)
+
+	fileHeader, err := c.FormFile(domain.AvatarFormField)
+	if err != nil {
+		var maxBytesError *http.MaxBytesError
+		if errors.As(err, &maxBytesError) {
+			return avatarError(c, log, domain.ErrAvatarTooLarge)
+		}
+
+		log.Warn("Failed to read the avatar from the form")
+		return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
+			Error:     "Unprocessable Entity",
+			Message:   "the avatar must be sent in the " + domain.AvatarFormField + " field of a multipart form",
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if fileHeader.Size > config.Avatar.MaxSize {
+		return avatarError(c, log, domain.ErrAvatarTooLarge)
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		return avatarError(c, log, err)
+	}
+	defer file.Close()
+
+	content, err := io.ReadAll(io.LimitReader(file, config.Avatar.MaxSize+1))
+	if err != nil {
+		return avatarError(c, log, err)
+	}
+
+	avatarResponse, err := ah.avatarService.Upload(c.Request().Context(), idFromToken, content)
+	if err != nil {
+		return avatarError(c, log, err)
+	}
+
+	log.Info("Avatar uploaded")
+	return c.JSON(http.StatusOK, avatarResponse)
+}
+
+// Delete godoc
+// @Summary Delete my avatar
+// @Description Remove the avatar of the caller, who gets the fallback one again
+// @Tags users
+// @Success 204
+// @Failure 401
+// @Failure 404 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /v1/users/me/avatar [delete]
+// @Security bearerToken
+func (ah *avatarHandler) Delete(c echo.Context) error {
+	log := slog.With(
+		slog.String("func", "Delete"),
+		slog.String("handler", "avatar"))
+
+	idFromToken, err := util.ExtractUserIdFromToken(c)
+	if err != nil {
+		log.Warn("Error getting id from token")
+		return c.NoContent(http.StatusUnauthorized)
+	}
+
+	if err := ah.avatarService.Delete(c.Request().Context(), idFromToken); err != nil {
+		return avatarError(c, log, err)
+	}
+
+	log.Info("Avatar deleted")
+	return c.NoContent(http.StatusNoContent)
+}
+
+func avatarError(c echo.Context, log *slog.Logger, err error) error {
+	if errors.Is(err, domain.ErrUserNotFound) {
+		log.Warn("User not found")
+		return c.JSON(http.StatusNotFound, domain.ErrorResponse{
+			Error:     "Not Found",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if errors.Is(err, domain.ErrAvatarTooLarge) {
+		log.Warn("Avatar too large")
+		return c.JSON(http.StatusRequestEntityTooLarge, domain.ErrorResponse{
+			Error:     "Request Entity Too Large",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if errors.Is(err, domain.ErrAvatarType) || errors.Is(err, domain.ErrInvalidAvatar) ||
+		errors.Is(err, domain.ErrAvatarDimension) {
+		log.Warn("Avatar refused", slog.Any("error", err))
+		return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
+			Error:     "Unprocessable Entity",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	log.Error("Error trying to call avatar service.", slog.Any("error", err))
+	return c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
+		Error:     "Internal Server Error",
+		Message:   err.Error(),
+		TimeStamp: time.Now(),
+		Path:      c.Path(),
+	})
+}
@@ -0,0 +1,151 @@
+package handler
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/OVillas/autentication/domain"
+	"github.com/OVillas/autentication/util"
+	"github.com/labstack/echo/v4"
+	"github.com/samber/do"
+)
+
+type dataExportHandler struct {
+	i                 *do.Injector
+	dataExportService domain.DataExportService
+}
+
+func NewDataExportHandler(i *do.Injector) (domain.DataExportHandler, error) {
+	dataExportService := do.MustInvoke[domain.DataExportService](i)
+	return &dataExportHandler{
+		i:                 i,
+		dataExportService: dataExportService,
+	}, nil
+}
+
+// Request godoc
+// @Summary Export my data
+// @Description Queue a copy of everything kept about the authenticated user. An email with a download link, valid for 7 days, is sent once it is ready. Requires a login in the last minutes.
+// @Tags users
+// @Produce json
+// @Param format query string false "json or zip, a ZIP of CSV files" default(json)
+// @Success 202 {object} domain.DataExportResponse
+// @Failure 401 {object} domain.ErrorResponse
+// @Failure 403
+// @Failure 422 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /v1/users/me/export [get]
+// @Security bearerToken
+func (deh *dataExportHandler) Request(c echo.Context) error {
+	log := slog.With(
+		slog.String("func", "Request"),
+		slog.String("handler", "dataExport"))
+
+	idFromToken, err := util.ExtractUserIdFromToken(c)
+	if err != nil {
+		log.Warn("Error getting user ID from token")
+		return c.JSON(http.StatusUnauthorized, domain.ErrorResponse{
+			Error:     "Unauthorized",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	var query domain.DataExportQuery
+	if err := c.Bind(&query); err != nil {
+		log.Warn("Failed to bind data export query to domain")
+		return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
+			Error:     "Unprocessable Entity",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err := query.Validate(); err != nil {
+		log.Warn("Invalid data export query")
+		return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
+			Error:     "Unprocessable Entity",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	response, err := deh.dataExportService.Request(idFromToken, query.Format, newClientInfo(c))
+	if err != nil {
+		log.Error("Error trying to call request data export service.")
+		return c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
+			Error:     "Internal Server Error",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	log.Info("Data export successfully requested")
+	return c.JSON(http.StatusAccepted, response)
+}
+
+// Download godoc
+// @Summary Download exported data
+// @Description Download a data export through the signed link emailed to its owner
+// @Tags users
+// @Produce json
+// @Produce application/zip
+// @Param token query string true "Token of the download link"
+// @Success 200 {file} file
+// @Failure 401 {object} domain.ErrorResponse
+// @Failure 422 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /v1/users/export/download [get]
+func (deh *dataExportHandler) Download(c echo.Context) error {
+	log := slog.With(
+		slog.String("func", "Download"),
+		slog.String("handler", "dataExport"))
+
+	token := c.QueryParam("token")
+	if token == "" {
+		log.Warn("Missing download link token")
+		return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
+			Error:     "Unprocessable Entity",
+			Message:   "token is required",
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	dataExport, err := deh.dataExportService.Download(token)
+	if err != nil && errors.Is(err, domain.ErrInvalidDataExportLink) {
+		log.Warn("Download link refused")
+		return c.JSON(http.StatusUnauthorized, domain.ErrorResponse{
+			Error:     "Unauthorized",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err != nil {
+		log.Error("Error trying to call download data export service.")
+		return c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
+			Error:     "Internal Server Error",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	contentType, extension := echo.MIMEApplicationJSON, ".json"
+	if dataExport.Format == domain.DataExportFormatZIP {
+		contentType, extension = "application/zip", ".zip"
+	}
+
+	log.Info("Data export successfully downloaded")
+	c.Response().Header().Set(echo.HeaderContentDisposition, `attachment; filename="data-export`+extension+`"`)
+	c.Response().Header().Set("Cache-Control", "no-store")
+	return c.Blob(http.StatusOK, contentType, dataExport.Content)
+}
@@ -0,0 +1,230 @@
+package handler
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/OVillas/autentication/domain"
+	"github.com/labstack/echo/v4"
+	"github.com/samber/do"
+)
+
+type invitationHandler struct {
+	i                 *do.Injector
+	invitationService domain.InvitationService
+}
+
+func NewInvitationHandler(i *do.Injector) (domain.InvitationHandler, error) {
+	invitationService := do.MustInvoke[domain.InvitationService](i)
+	return &invitationHandler{
+		i:                 i,
+		invitationService: invitationService,
+	}, nil
+}
+
+// Create godoc
+// @Summary Invite someone to register
+// @Description Email an invitation to register with this email, which is taken as confirmed. Only admins can pick the role the invited user gets
+// @Tags invitations
+// @Accept json
+// @Produce json
+// @Param invitation body domain.InvitationPayLoad true "Email to invite and, for admins, a role"
+// @Success 201 {object} domain.InvitationResponse
+// @Failure 401
+// @Failure 403 {object} domain.ErrorResponse
+// @Failure 404 {object} domain.ErrorResponse
+// @Failure 409 {object} domain.ErrorResponse
+// @Failure 422 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /v1/invitations [post]
+// @Security bearerToken
+func (ih *invitationHandler) Create(c echo.Context) error {
+	log := slog.With(
+		slog.String("func", "Create"),
+		slog.String("handler", "invitation"))
+
+	var invitationPayLoad domain.InvitationPayLoad
+	if err := c.Bind(&invitationPayLoad); err != nil {
+		log.Warn("Failed to bind invitation data to domain")
+		return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
+			Error:     "Unprocessable Entity",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err := invitationPayLoad.Validate(); err != nil {
+		log.Warn("Invalid invitation data")
+		return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
+			Error:     "Unprocessable Entity",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	invitationResponse, err := ih.invitationService.Create(c.Request().Context(), newViewer(c), invitationPayLoad)
+	if err != nil && errors.Is(err, domain.ErrUserNotAuthorized) {
+		log.Warn("Invitation with a role by a non admin")
+		return c.JSON(http.StatusForbidden, domain.ErrorResponse{
+			Error:     "Forbidden",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err != nil && errors.Is(err, domain.ErrRoleNotFound) {
+		log.Warn("Invitation with an unknown role")
+		return c.JSON(http.StatusNotFound, domain.ErrorResponse{
+			Error:     "Not Found",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err != nil && errors.Is(err, domain.ErrUserAlreadyRegistered) {
+		log.Warn("Invitation to an already registered email")
+		return c.JSON(http.StatusConflict, domain.ErrorResponse{
+			Error:     "User already registered",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+			Fields:    domain.FieldErrors(err),
+		})
+	}
+
+	if err != nil {
+		log.Error("Error trying to call create invitation service.")
+		return c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
+			Error:     "Internal Server Error",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	log.Info("Invitation created")
+	return c.JSON(http.StatusCreated, invitationResponse)
+}
+
+// Accept godoc
+// @Summary Register with an invitation
+// @Description Create the account of an invited user, with the email already confirmed and the role of the invitation. The email must be the invited one
+// @Tags invitations
+// @Accept json
+// @Param registration body domain.InvitationRegistrationPayLoad true "User Payload and the token of the invitation"
+// @Success 201
+// @Failure 404 {object} domain.ErrorResponse
+// @Failure 409 {object} domain.ErrorResponse
+// @Failure 410 {object} domain.ErrorResponse "The invitation expired or was already accepted"
+// @Failure 422 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Failure 503 {object} domain.ErrorResponse
+// @Router /v1/users/invitation [post]
+func (ih *invitationHandler) Accept(c echo.Context) error {
+	log := slog.With(
+		slog.String("func", "Accept"),
+		slog.String("handler", "invitation"))
+
+	var registrationPayLoad domain.InvitationRegistrationPayLoad
+	if err := c.Bind(&registrationPayLoad); err != nil {
+		log.Warn("Failed to bind registration data to domain")
+		return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
+			Error:     "Unprocessable Entity",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err := registrationPayLoad.Validate(); err != nil {
+		log.Warn("Invalid registration data")
+		return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
+			Error:     "Invalid user data",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+			Fields:    domain.FieldErrors(err),
+		})
+	}
+
+	err := ih.invitationService.Accept(c.Request().Context(), registrationPayLoad, newClientInfo(c))
+	if err != nil && errors.Is(err, domain.ErrInvitationNotFound) {
+		log.Warn("Invitation not found")
+		return c.JSON(http.StatusNotFound, domain.ErrorResponse{
+			Error:     "Not Found",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err != nil && (errors.Is(err, domain.ErrInvitationExpired) || errors.Is(err, domain.ErrInvitationAccepted)) {
+		log.Warn("Invitation no longer valid", slog.Any("error", err))
+		return c.JSON(http.StatusGone, domain.ErrorResponse{
+			Error:     "Gone",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err != nil && (errors.Is(err, domain.ErrInvitationEmailMismatch) || errors.Is(err, domain.ErrPasswordBreached)) {
+		log.Warn("Registration with an invitation refused", slog.Any("error", err))
+		return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
+			Error:     "Invalid user data",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err != nil && (errors.Is(err, domain.ErrUserAlreadyRegistered) || errors.Is(err, domain.ErrUsernameTaken)) {
+		log.Warn("Registration with a taken email or username", slog.Any("error", err))
+		return c.JSON(http.StatusConflict, domain.ErrorResponse{
+			Error:     "Conflict",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+			Fields:    domain.FieldErrors(err),
+		})
+	}
+
+	if err != nil && errors.Is(err, domain.ErrTermsVersionOutdated) {
+		log.Warn("Invitation accepted with outdated terms version")
+		return c.JSON(http.StatusConflict, domain.ErrorResponse{
+			Error:     "Conflict",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err != nil && errors.Is(err, domain.ErrPasswordBreachCheck) {
+		log.Error("Breached password check unavailable")
+		return c.JSON(http.StatusServiceUnavailable, domain.ErrorResponse{
+			Error:     "Service Unavailable",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err != nil {
+		log.Error("Error trying to call accept invitation service.")
+		return c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
+			Error:     "Internal Server Error",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	log.Info("User registered with an invitation")
+	return c.NoContent(http.StatusCreated)
+}
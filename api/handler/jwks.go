@@ -0,0 +1,30 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/OVillas/autentication/auth"
+	"github.com/OVillas/autentication/domain"
+	"github.com/labstack/echo/v4"
+	"github.com/samber/do"
+)
+
+type jwksHandler struct {
+	i *do.Injector
+}
+
+func NewJWKSHandler(i *do.Injector) (domain.JWKSHandler, error) {
+	return &jwksHandler{i: i}, nil
+}
+
+// GetKeys godoc
+// @Summary Public signing keys
+// @Description Get the public keys used to verify RS256 tokens in JWKS format
+// @Tags authentication
+// @Produce json
+// @Success 200 {object} domain.JSONWebKeySet
+// @Router /.well-known/jwks.json [get]
+func (jh *jwksHandler) GetKeys(c echo.Context) error {
+	c.Response().Header().Set("Cache-Control", "public, max-age=300")
+	return c.JSON(http.StatusOK, domain.JSONWebKeySet{Keys: auth.PublicJSONWebKeys()})
+}
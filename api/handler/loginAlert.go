@@ -0,0 +1,141 @@
+package handler
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/OVillas/autentication/domain"
+	"github.com/OVillas/autentication/util"
+	"github.com/labstack/echo/v4"
+	"github.com/samber/do"
+)
+
+type loginAlertHandler struct {
+	i                 *do.Injector
+	loginAlertService domain.LoginAlertService
+}
+
+func NewLoginAlertHandler(i *do.Injector) (domain.LoginAlertHandler, error) {
+	loginAlertService := do.MustInvoke[domain.LoginAlertService](i)
+	return &loginAlertHandler{
+		i:                 i,
+		loginAlertService: loginAlertService,
+	}, nil
+}
+
+// GetPreference godoc
+// @Summary Get new sign-in alert preference
+// @Description Tell whether the authenticated user is emailed when their account is accessed from a device not used in a while
+// @Tags users
+// @Produce json
+// @Success 200 {object} domain.LoginAlertPreferenceResponse
+// @Failure 401 {object} domain.ErrorResponse
+// @Failure 404 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /v1/users/me/login-alerts [get]
+// @Security bearerToken
+func (lah *loginAlertHandler) GetPreference(c echo.Context) error {
+	log := slog.With(
+		slog.String("func", "GetPreference"),
+		slog.String("handler", "loginAlert"))
+
+	idFromToken, err := util.ExtractUserIdFromToken(c)
+	if err != nil {
+		log.Warn("Error getting user ID from token")
+		return c.JSON(http.StatusUnauthorized, domain.ErrorResponse{
+			Error:     "Unauthorized",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	response, err := lah.loginAlertService.GetPreference(c.Request().Context(), idFromToken)
+	if err != nil && errors.Is(err, domain.ErrUserNotFound) {
+		log.Warn("User not found")
+		return c.JSON(http.StatusNotFound, domain.ErrorResponse{
+			Error:     "Not Found",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err != nil {
+		log.Error("Error trying to call get login alert preference service.")
+		return c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
+			Error:     "Internal Server Error",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	log.Info("Login alert preference successfully retrieved")
+	return c.JSON(http.StatusOK, response)
+}
+
+// UpdatePreference godoc
+// @Summary Turn new sign-in alerts on or off
+// @Description Choose whether the authenticated user is emailed when their account is accessed from a device not used in a while
+// @Tags users
+// @Accept json
+// @Param payload body domain.LoginAlertPreferencePayLoad true "Preference"
+// @Success 204
+// @Failure 401 {object} domain.ErrorResponse
+// @Failure 422 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /v1/users/me/login-alerts [put]
+// @Security bearerToken
+func (lah *loginAlertHandler) UpdatePreference(c echo.Context) error {
+	log := slog.With(
+		slog.String("func", "UpdatePreference"),
+		slog.String("handler", "loginAlert"))
+
+	idFromToken, err := util.ExtractUserIdFromToken(c)
+	if err != nil {
+		log.Warn("Error getting user ID from token")
+		return c.JSON(http.StatusUnauthorized, domain.ErrorResponse{
+			Error:     "Unauthorized",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	var payLoad domain.LoginAlertPreferencePayLoad
+	if err := c.Bind(&payLoad); err != nil {
+		log.Warn("Failed to bind login alert preference to domain")
+		return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
+			Error:     "Unprocessable Entity",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err := payLoad.Validate(); err != nil {
+		log.Warn("Invalid login alert preference")
+		return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
+			Error:     "Unprocessable Entity",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err := lah.loginAlertService.UpdatePreference(c.Request().Context(), idFromToken, *payLoad.Enabled); err != nil {
+		log.Error("Error trying to call update login alert preference service.")
+		return c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
+			Error:     "Internal Server Error",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	log.Info("Login alert preference successfully updated")
+	return c.NoContent(http.StatusNoContent)
+}
@@ -0,0 +1,90 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/OVillas/autentication/domain"
+	"github.com/OVillas/autentication/util"
+	"github.com/labstack/echo/v4"
+	"github.com/samber/do"
+)
+
+type loginHistoryHandler struct {
+	i                   *do.Injector
+	loginHistoryService domain.LoginHistoryService
+}
+
+func NewLoginHistoryHandler(i *do.Injector) (domain.LoginHistoryHandler, error) {
+	loginHistoryService := do.MustInvoke[domain.LoginHistoryService](i)
+	return &loginHistoryHandler{
+		i:                   i,
+		loginHistoryService: loginHistoryService,
+	}, nil
+}
+
+// GetMe godoc
+// @Summary List login history
+// @Description List the latest 50 login attempts to the account of the authenticated user, successful or not, newest first
+// @Tags users
+// @Produce json
+// @Param page query int false "Page, starting at 1" default(1)
+// @Param per_page query int false "Attempts per page, at most 100" default(20)
+// @Success 200 {object} domain.LoginHistoryPage
+// @Failure 401 {object} domain.ErrorResponse
+// @Failure 422 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /v1/users/me/logins [get]
+// @Security bearerToken
+func (lhh *loginHistoryHandler) GetMe(c echo.Context) error {
+	log := slog.With(
+		slog.String("func", "GetMe"),
+		slog.String("handler", "loginHistory"))
+
+	idFromToken, err := util.ExtractUserIdFromToken(c)
+	if err != nil {
+		log.Warn("Error getting user ID from token")
+		return c.JSON(http.StatusUnauthorized, domain.ErrorResponse{
+			Error:     "Unauthorized",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	var query domain.LoginHistoryQuery
+	if err := c.Bind(&query); err != nil {
+		log.Warn("Failed to bind login history query to domain")
+		return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
+			Error:     "Unprocessable Entity",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err := query.Validate(); err != nil {
+		log.Warn("Invalid login history query")
+		return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
+			Error:     "Unprocessable Entity",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	loginHistoryPage, err := lhh.loginHistoryService.GetByUserID(idFromToken, query)
+	if err != nil {
+		log.Error("Error trying to call get login history service.")
+		return c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
+			Error:     "Internal Server Error",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	log.Info("Login history successfully retrieved")
+	return c.JSON(http.StatusOK, loginHistoryPage)
+}
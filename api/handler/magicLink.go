@@ -0,0 +1,175 @@
+package handler
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/OVillas/autentication/config"
+	"github.com/OVillas/autentication/domain"
+	"github.com/OVillas/autentication/util"
+	"github.com/labstack/echo/v4"
+	"github.com/samber/do"
+)
+
+type magicLinkHandler struct {
+	i                *do.Injector
+	magicLinkService domain.MagicLinkService
+}
+
+func NewMagicLinkHandler(i *do.Injector) (domain.MagicLinkHandler, error) {
+	magicLinkService := do.MustInvoke[domain.MagicLinkService](i)
+	return &magicLinkHandler{
+		i:                i,
+		magicLinkService: magicLinkService,
+	}, nil
+}
+
+// Send godoc
+// @Summary Request a magic link
+// @Description Email a single-use login link valid for 15 minutes. The answer is the same whether or not the email belongs to a user
+// @Tags authentication
+// @Accept json
+// @Param magicLinkPayLoad body domain.MagicLinkPayLoad true "Email of the account"
+// @Success 202
+// @Failure 422 {object} domain.ErrorResponse
+// @Failure 429 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /v1/auth/login/magic-link [post]
+func (mlh *magicLinkHandler) Send(c echo.Context) error {
+	log := slog.With(
+		slog.String("func", "Send"),
+		slog.String("handler", "magicLink"))
+
+	var magicLinkPayLoad domain.MagicLinkPayLoad
+	if err := c.Bind(&magicLinkPayLoad); err != nil {
+		log.Warn("Failed to bind magic link data to domain")
+		return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
+			Error:     "Unprocessable Entity",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err := magicLinkPayLoad.Validate(); err != nil {
+		log.Warn("Invalid magic link data")
+		return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
+			Error:     "Unprocessable Entity",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err := mlh.magicLinkService.Send(c.Request().Context(), util.ExtractTenant(c), magicLinkPayLoad.Email); err != nil {
+		log.Error("Error trying to call send magic link service.")
+		return c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
+			Error:     "Internal Server Error",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	log.Info("Magic link requested")
+	return c.NoContent(http.StatusAccepted)
+}
+
+// Verify godoc
+// @Summary Log in with a magic link
+// @Description Consume the token of a magic link and open a session, or answer with a two-factor challenge when the account has it on
+// @Tags authentication
+// @Produce json
+// @Param token query string true "Token of the magic link"
+// @Success 200 {object} domain.LoginResponse "Session, a domain.TwoFactorChallengeResponse when two-factor authentication is on or a domain.PasswordResetRequiredResponse when a password reset is required"
+// @Failure 401 {object} domain.ErrorResponse
+// @Failure 403 {object} domain.AccountDeactivatedResponse "Reactivation offer, or a domain.AccountSuspendedResponse when the account is suspended"
+// @Failure 409 {object} domain.ErrorResponse
+// @Failure 422 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /v1/auth/login/magic-link/verify [get]
+func (mlh *magicLinkHandler) Verify(c echo.Context) error {
+	log := slog.With(
+		slog.String("func", "Verify"),
+		slog.String("handler", "magicLink"))
+
+	token := c.QueryParam("token")
+	if token == "" {
+		log.Warn("Missing magic link token")
+		return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
+			Error:     "Unprocessable Entity",
+			Message:   "token is required",
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	loginResult, err := mlh.magicLinkService.Verify(c.Request().Context(), token, trustedDeviceTokenFromCookie(c), newClientInfo(c))
+	if err != nil && (errors.Is(err, domain.ErrInvalidMagicLink) || errors.Is(err, domain.ErrUserNotFound)) {
+		log.Warn("Magic link refused", slog.Any("error", err))
+		return c.JSON(http.StatusUnauthorized, domain.ErrorResponse{
+			Error:     "Unauthorized",
+			Message:   domain.ErrInvalidMagicLink.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err != nil && errors.Is(err, domain.ErrTooManySessions) {
+		log.Warn("Session limit reached")
+		return c.JSON(http.StatusConflict, domain.ErrorResponse{
+			Error:     "Conflict",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err != nil && errors.Is(err, domain.ErrAccountSuspended) {
+		log.Warn("Magic link login to a suspended account")
+		return accountSuspended(c, err)
+	}
+
+	if err != nil {
+		log.Error("Error trying to call verify magic link service.")
+		return c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
+			Error:     "Internal Server Error",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if loginResult.Challenge != nil {
+		log.Info("Magic link login waiting for second factor")
+		return c.JSON(http.StatusOK, loginResult.Challenge)
+	}
+
+	if loginResult.Deactivated != nil {
+		log.Info("Magic link login waiting for reactivation")
+		return c.JSON(http.StatusForbidden, loginResult.Deactivated)
+	}
+
+	if loginResult.PasswordReset != nil {
+		log.Info("Magic link login waiting for password reset")
+		return c.JSON(http.StatusOK, loginResult.PasswordReset)
+	}
+
+	loginResponse := loginResult.LoginResponse
+	if config.SessionCookie.Enabled {
+		if err := setSessionCookies(c, loginResponse); err != nil {
+			log.Error("Error trying to set session cookies", slog.Any("error", err))
+			return c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
+				Error:     "Internal Server Error",
+				Message:   err.Error(),
+				TimeStamp: time.Now(),
+				Path:      c.Path(),
+			})
+		}
+	}
+
+	log.Info("Magic link login executed successfully")
+	return c.JSON(http.StatusOK, loginResponse)
+}
@@ -0,0 +1,175 @@
+package handler
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/OVillas/autentication/domain"
+	"github.com/OVillas/autentication/util"
+	"github.com/labstack/echo/v4"
+	"github.com/samber/do"
+)
+
+type metadataHandler struct {
+	i               *do.Injector
+	metadataService domain.MetadataService
+}
+
+func NewMetadataHandler(i *do.Injector) (domain.MetadataHandler, error) {
+	metadataService := do.MustInvoke[domain.MetadataService](i)
+	return &metadataHandler{
+		i:               i,
+		metadataService: metadataService,
+	}, nil
+}
+
+// UpdateMe godoc
+// @Summary Update my metadata
+// @Description Merge the body into the user section of the metadata of the caller: a key set to null is removed and any other key replaces its value. The section takes at most 4KB and 50 keys. The admin section can only be changed by an admin
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param metadata body domain.MetadataPayLoad true "Keys to set or remove"
+// @Success 200 {object} domain.UserMetadata
+// @Failure 401
+// @Failure 404 {object} domain.ErrorResponse
+// @Failure 413 {object} domain.ErrorResponse
+// @Failure 422 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /v1/users/me/metadata [patch]
+// @Security bearerToken
+func (mh *metadataHandler) UpdateMe(c echo.Context) error {
+	log := slog.With(
+		slog.String("func", "UpdateMe"),
+		slog.String("handler", "metadata"))
+
+	idFromToken, err := util.ExtractUserIdFromToken(c)
+	if err != nil {
+		log.Warn("Error getting id from token")
+		return c.NoContent(http.StatusUnauthorized)
+	}
+
+	var metadataPayLoad domain.MetadataPayLoad
+	if err := new(echo.DefaultBinder).BindBody(c, &metadataPayLoad); err != nil {
+		log.Warn("Failed to bind metadata to domain")
+		return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
+			Error:     "Unprocessable Entity",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err := metadataPayLoad.Validate(); err != nil {
+		log.Warn("Invalid metadata")
+		return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
+			Error:     "Unprocessable Entity",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	metadata, err := mh.metadataService.UpdateMe(c.Request().Context(), idFromToken, metadataPayLoad)
+	if err != nil {
+		return metadataError(c, log, err)
+	}
+
+	log.Info("Metadata updated")
+	return c.JSON(http.StatusOK, metadata)
+}
+
+// AdminUpdate godoc
+// @Summary Update the admin metadata of a user
+// @Description Merge the body into the admin section of the metadata of the user, which they can read but not change: a key set to null is removed and any other key replaces its value. The section takes at most 4KB and 50 keys
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param id path string true "User ID"
+// @Param metadata body domain.MetadataPayLoad true "Keys to set or remove"
+// @Success 200 {object} domain.UserMetadata
+// @Failure 400 {object} domain.ErrorResponse
+// @Failure 401
+// @Failure 404 {object} domain.ErrorResponse
+// @Failure 413 {object} domain.ErrorResponse
+// @Failure 422 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /v1/admin/users/{id}/metadata [patch]
+func (mh *metadataHandler) AdminUpdate(c echo.Context) error {
+	log := slog.With(
+		slog.String("func", "AdminUpdate"),
+		slog.String("handler", "metadata"))
+
+	id := c.Param("id")
+	if err := util.IsValidUUID(id); err != nil {
+		log.Warn("Invalid params")
+		return c.JSON(http.StatusBadRequest, domain.ErrorResponse{
+			Error:     "Bad Request",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	// Only the body: Bind would also add the id param to the map as a key.
+	var metadataPayLoad domain.MetadataPayLoad
+	if err := new(echo.DefaultBinder).BindBody(c, &metadataPayLoad); err != nil {
+		log.Warn("Failed to bind metadata to domain")
+		return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
+			Error:     "Unprocessable Entity",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err := metadataPayLoad.Validate(); err != nil {
+		log.Warn("Invalid metadata")
+		return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
+			Error:     "Unprocessable Entity",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	metadata, err := mh.metadataService.AdminUpdate(c.Request().Context(), newViewer(c), id, metadataPayLoad)
+	if err != nil {
+		return metadataError(c, log, err)
+	}
+
+	log.Info("Admin metadata updated")
+	return c.JSON(http.StatusOK, metadata)
+}
+
+func metadataError(c echo.Context, log *slog.Logger, err error) error {
+	if errors.Is(err, domain.ErrUserNotFound) {
+		log.Warn("User not found")
+		return c.JSON(http.StatusNotFound, domain.ErrorResponse{
+			Error:     "Not Found",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if errors.Is(err, domain.ErrMetadataTooLarge) || errors.Is(err, domain.ErrMetadataTooManyKeys) {
+		log.Warn("Metadata refused", slog.Any("error", err))
+		return c.JSON(http.StatusRequestEntityTooLarge, domain.ErrorResponse{
+			Error:     "Request Entity Too Large",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	log.Error("Error trying to call metadata service.", slog.Any("error", err))
+	return c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
+		Error:     "Internal Server Error",
+		Message:   err.Error(),
+		TimeStamp: time.Now(),
+		Path:      c.Path(),
+	})
+}
@@ -0,0 +1,353 @@
+package handler
+
+import (
+	"bytes"
+	"errors"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/OVillas/autentication/domain"
+	"github.com/labstack/echo/v4"
+	"github.com/samber/do"
+)
+
+var authorizeTemplate = template.Must(template.New("authorize").Parse(`<!DOCTYPE html>
+<html lang="pt-BR">
+<head><meta charset="utf-8"><meta name="viewport" content="width=device-width, initial-scale=1"><title>Entrar</title></head>
+<body>
+<h1>Entrar</h1>
+{{if .Error}}<p role="alert">{{.Error}}</p>{{end}}
+<form method="post">
+<input type="hidden" name="response_type" value="{{.Request.ResponseType}}">
+<input type="hidden" name="client_id" value="{{.Request.ClientID}}">
+<input type="hidden" name="redirect_uri" value="{{.Request.RedirectURI}}">
+<input type="hidden" name="code_challenge" value="{{.Request.CodeChallenge}}">
+<input type="hidden" name="code_challenge_method" value="{{.Request.CodeChallengeMethod}}">
+<input type="hidden" name="scope" value="{{.Request.Scope}}">
+<input type="hidden" name="state" value="{{.Request.State}}">
+<label>Usuário ou e-mail <input name="username" value="{{.Request.Username}}" autocomplete="username" required></label>
+<label>Senha <input type="password" name="password" autocomplete="current-password" required></label>
+<button type="submit">Entrar</button>
+</form>
+</body>
+</html>`))
+
+type oauthHandler struct {
+	i            *do.Injector
+	oauthService domain.OAuthService
+}
+
+func NewOAuthHandler(i *do.Injector) (domain.OAuthHandler, error) {
+	oauthService := do.MustInvoke[domain.OAuthService](i)
+	return &oauthHandler{
+		i:            i,
+		oauthService: oauthService,
+	}, nil
+}
+
+// Authorize godoc
+// @Summary OAuth authorization endpoint
+// @Description Render the login form of the authorization code flow. PKCE with S256 is required
+// @Tags oauth
+// @Produce html
+// @Param response_type query string true "Must be code"
+// @Param client_id query string true "Client ID"
+// @Param redirect_uri query string true "Registered redirect URI"
+// @Param code_challenge query string true "PKCE code challenge"
+// @Param code_challenge_method query string true "Must be S256"
+// @Param scope query string false "Scope"
+// @Param state query string false "Opaque value returned to the client"
+// @Success 200
+// @Failure 400 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /v1/oauth/authorize [get]
+func (oh *oauthHandler) Authorize(c echo.Context) error {
+	log := slog.With(
+		slog.String("func", "Authorize"),
+		slog.String("handler", "oauth"))
+
+	log.Info("Authorize initiated")
+
+	payLoad, err := oh.bindAuthorizeRequest(c)
+	if payLoad == nil {
+		log.Warn("Invalid authorization request")
+		return err
+	}
+
+	log.Info("Authorize form rendered")
+	return renderAuthorizeForm(c, http.StatusOK, *payLoad, "")
+}
+
+// AuthorizeLogin godoc
+// @Summary OAuth authorization login
+// @Description Check the credentials posted by the login form and redirect to the client with a single-use code valid for 60 seconds
+// @Tags oauth
+// @Accept x-www-form-urlencoded
+// @Produce html
+// @Success 302
+// @Failure 400 {object} domain.ErrorResponse
+// @Failure 401
+// @Failure 403
+// @Failure 423
+// @Failure 429
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /v1/oauth/authorize [post]
+func (oh *oauthHandler) AuthorizeLogin(c echo.Context) error {
+	log := slog.With(
+		slog.String("func", "AuthorizeLogin"),
+		slog.String("handler", "oauth"))
+
+	log.Info("AuthorizeLogin initiated")
+
+	payLoad, err := oh.bindAuthorizeRequest(c)
+	if payLoad == nil {
+		log.Warn("Invalid authorization request")
+		return err
+	}
+
+	code, err := oh.oauthService.Authorize(c.Request().Context(), *payLoad, newClientInfo(c))
+	if err != nil && (errors.Is(err, domain.ErrUserNotFound) || errors.Is(err, domain.ErrPasswordNotMatch)) {
+		log.Warn("Invalid credentials")
+		payLoad.Password = ""
+		return renderAuthorizeForm(c, http.StatusUnauthorized, *payLoad, "Usuário ou senha inválidos.")
+	}
+
+	if err != nil && errors.Is(err, domain.ErrAccountDeactivated) {
+		log.Warn("Login to a deactivated account")
+		payLoad.Password = ""
+		return renderAuthorizeForm(c, http.StatusForbidden, *payLoad,
+			"Conta desativada. Entre pelo aplicativo para reativá-la.")
+	}
+
+	if err != nil && errors.Is(err, domain.ErrAccountSuspended) {
+		log.Warn("Login to a suspended account")
+		payLoad.Password = ""
+		return renderAuthorizeForm(c, http.StatusForbidden, *payLoad, suspendedMessage(err))
+	}
+
+	if err != nil && errors.Is(err, domain.ErrEmailNotConfirmed) {
+		log.Warn("Login to an account with the email not confirmed")
+		payLoad.Password = ""
+		return renderAuthorizeForm(c, http.StatusForbidden, *payLoad,
+			"Confirme seu e-mail antes de entrar. Enviamos um novo código para ele.")
+	}
+
+	if err != nil && errors.Is(err, domain.ErrAccountLocked) {
+		log.Warn("Login to a locked account")
+		payLoad.Password = ""
+		setRetryAfter(c, err)
+		return renderAuthorizeForm(c, http.StatusLocked, *payLoad,
+			"Conta bloqueada temporariamente por excesso de tentativas. Tente novamente mais tarde.")
+	}
+
+	if err != nil && errors.Is(err, domain.ErrLoginDelayed) {
+		log.Warn("Login attempted before the login delay ended")
+		payLoad.Password = ""
+		setRetryAfter(c, err)
+		return renderAuthorizeForm(c, http.StatusTooManyRequests, *payLoad,
+			"Muitas tentativas com a senha errada. Aguarde alguns segundos e tente novamente.")
+	}
+
+	if err != nil {
+		log.Error("Error trying to call authorize service.")
+		return c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
+			Error:     "Internal Server Error",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	redirectURI, err := url.Parse(payLoad.RedirectURI)
+	if err != nil {
+		log.Warn("Invalid redirect uri")
+		return c.JSON(http.StatusBadRequest, domain.ErrorResponse{
+			Error:     "Bad Request",
+			Message:   domain.ErrInvalidRedirectURI.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	query := redirectURI.Query()
+	query.Set("code", code)
+	if payLoad.State != "" {
+		query.Set("state", payLoad.State)
+	}
+	redirectURI.RawQuery = query.Encode()
+
+	log.Info("Authorization code issued successfully")
+	return c.Redirect(http.StatusFound, redirectURI.String())
+}
+
+// Token godoc
+// @Summary OAuth token endpoint
+// @Description Exchange an authorization code and its PKCE verifier, or a refresh token, for an access and refresh token pair. With client_credentials a confidential client gets a token for itself, limited to the requested scopes it is allowed. Confidential clients authenticate with basic auth or client_secret
+// @Tags oauth
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Param grant_type formData string true "authorization_code, refresh_token or client_credentials"
+// @Param code formData string false "Authorization code"
+// @Param redirect_uri formData string false "Redirect URI used on authorize"
+// @Param code_verifier formData string false "PKCE code verifier"
+// @Param refresh_token formData string false "Refresh token"
+// @Param scope formData string false "Space separated scopes for client_credentials"
+// @Param client_id formData string false "Client ID"
+// @Param client_secret formData string false "Client secret"
+// @Success 200 {object} domain.LoginResponse
+// @Failure 400 {object} domain.OAuthErrorResponse
+// @Failure 401 {object} domain.OAuthErrorResponse
+// @Failure 500 {object} domain.OAuthErrorResponse
+// @Router /v1/oauth/token [post]
+func (oh *oauthHandler) Token(c echo.Context) error {
+	log := slog.With(
+		slog.String("func", "Token"),
+		slog.String("handler", "oauth"))
+
+	log.Info("Token initiated")
+
+	c.Response().Header().Set("Cache-Control", "no-store")
+
+	var payLoad domain.OAuthTokenPayLoad
+	if err := c.Bind(&payLoad); err != nil {
+		log.Warn("Failed to bind token request")
+		return c.JSON(http.StatusBadRequest, domain.OAuthErrorResponse{Error: "invalid_request", ErrorDescription: err.Error()})
+	}
+
+	if clientID, clientSecret, ok := c.Request().BasicAuth(); ok {
+		payLoad.ClientID = clientID
+		payLoad.ClientSecret = clientSecret
+	}
+
+	if err := payLoad.Validate(); err != nil {
+		log.Warn("Invalid token request")
+		return c.JSON(http.StatusBadRequest, domain.OAuthErrorResponse{Error: "invalid_request", ErrorDescription: err.Error()})
+	}
+
+	if payLoad.GrantType == domain.GrantTypeClientCredentials {
+		return oh.clientCredentials(c, payLoad)
+	}
+
+	loginResponse, err := oh.oauthService.Token(c.Request().Context(), payLoad, newClientInfo(c))
+	if err != nil && errors.Is(err, domain.ErrInvalidClient) {
+		log.Warn("Invalid client")
+		return c.JSON(http.StatusUnauthorized, domain.OAuthErrorResponse{Error: "invalid_client", ErrorDescription: err.Error()})
+	}
+
+	if err != nil && (errors.Is(err, domain.ErrInvalidGrant) || errors.Is(err, domain.ErrInvalidToken) ||
+		errors.Is(err, domain.ErrRefreshTokenReused) || errors.Is(err, domain.ErrTokenBinding) || errors.Is(err, domain.ErrUserNotFound)) {
+		log.Warn("Invalid grant")
+		return c.JSON(http.StatusBadRequest, domain.OAuthErrorResponse{Error: "invalid_grant", ErrorDescription: err.Error()})
+	}
+
+	if err != nil && (errors.Is(err, domain.ErrTooManySessions) || errors.Is(err, domain.ErrPasswordResetRequired) ||
+		errors.Is(err, domain.ErrAccountDeactivated) || errors.Is(err, domain.ErrAccountSuspended)) {
+		log.Warn("Session refused", slog.Any("error", err))
+		return c.JSON(http.StatusBadRequest, domain.OAuthErrorResponse{Error: "access_denied", ErrorDescription: err.Error()})
+	}
+
+	if err != nil {
+		log.Error("Error trying to call token service.")
+		return c.JSON(http.StatusInternalServerError, domain.OAuthErrorResponse{Error: "server_error", ErrorDescription: err.Error()})
+	}
+
+	log.Info("Token executed successfully")
+	return c.JSON(http.StatusOK, loginResponse)
+}
+
+func (oh *oauthHandler) clientCredentials(c echo.Context, payLoad domain.OAuthTokenPayLoad) error {
+	log := slog.With(
+		slog.String("func", "clientCredentials"),
+		slog.String("handler", "oauth"))
+
+	clientTokenResponse, err := oh.oauthService.ClientCredentials(payLoad)
+	if err != nil && errors.Is(err, domain.ErrInvalidClient) {
+		log.Warn("Invalid client")
+		return c.JSON(http.StatusUnauthorized, domain.OAuthErrorResponse{Error: "invalid_client", ErrorDescription: err.Error()})
+	}
+
+	if err != nil && errors.Is(err, domain.ErrUnauthorizedClient) {
+		log.Warn("Unauthorized client")
+		return c.JSON(http.StatusBadRequest, domain.OAuthErrorResponse{Error: "unauthorized_client", ErrorDescription: err.Error()})
+	}
+
+	if err != nil && errors.Is(err, domain.ErrInvalidScope) {
+		log.Warn("Invalid scope")
+		return c.JSON(http.StatusBadRequest, domain.OAuthErrorResponse{Error: "invalid_scope", ErrorDescription: err.Error()})
+	}
+
+	if err != nil {
+		log.Error("Error trying to call client credentials service.")
+		return c.JSON(http.StatusInternalServerError, domain.OAuthErrorResponse{Error: "server_error", ErrorDescription: err.Error()})
+	}
+
+	log.Info("Client token issued successfully")
+	return c.JSON(http.StatusOK, clientTokenResponse)
+}
+
+// bindAuthorizeRequest writes the error response itself when the request is invalid and then
+// returns a nil payload. Errors about the client or redirect URI are never redirected.
+func (oh *oauthHandler) bindAuthorizeRequest(c echo.Context) (*domain.AuthorizePayLoad, error) {
+	var payLoad domain.AuthorizePayLoad
+	if err := c.Bind(&payLoad); err != nil {
+		return nil, c.JSON(http.StatusBadRequest, domain.ErrorResponse{
+			Error:     "Bad Request",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err := payLoad.Validate(); err != nil {
+		return nil, c.JSON(http.StatusBadRequest, domain.ErrorResponse{
+			Error:     "Bad Request",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	err := oh.oauthService.ValidateAuthorizeRequest(payLoad)
+	if err != nil && (errors.Is(err, domain.ErrOAuthClientNotFound) || errors.Is(err, domain.ErrInvalidRedirectURI)) {
+		return nil, c.JSON(http.StatusBadRequest, domain.ErrorResponse{
+			Error:     "Bad Request",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err != nil {
+		return nil, c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
+			Error:     "Internal Server Error",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	return &payLoad, nil
+}
+
+func renderAuthorizeForm(c echo.Context, status int, payLoad domain.AuthorizePayLoad, message string) error {
+	var page bytes.Buffer
+	err := authorizeTemplate.Execute(&page, map[string]interface{}{
+		"Request": payLoad,
+		"Error":   message,
+	})
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
+			Error:     "Internal Server Error",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	c.Response().Header().Set("X-Frame-Options", "DENY")
+	c.Response().Header().Set("Cache-Control", "no-store")
+	return c.HTML(status, page.String())
+}
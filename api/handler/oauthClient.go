@@ -0,0 +1,167 @@
+package handler
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/OVillas/autentication/domain"
+	"github.com/OVillas/autentication/util"
+	"github.com/labstack/echo/v4"
+	"github.com/samber/do"
+)
+
+type oauthClientHandler struct {
+	i                  *do.Injector
+	oauthClientService domain.OAuthClientService
+}
+
+func NewOAuthClientHandler(i *do.Injector) (domain.OAuthClientHandler, error) {
+	oauthClientService := do.MustInvoke[domain.OAuthClientService](i)
+	return &oauthClientHandler{
+		i:                  i,
+		oauthClientService: oauthClientService,
+	}, nil
+}
+
+// Create godoc
+// @Summary Register an oauth client
+// @Description Register an application for the authorization code flow. The secret of confidential clients is only returned once
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param oauthClient body domain.OAuthClientPayLoad true "OAuth Client Payload"
+// @Success 201 {object} domain.OAuthClientResponse
+// @Failure 401
+// @Failure 422 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /v1/admin/oauth-clients [post]
+func (och *oauthClientHandler) Create(c echo.Context) error {
+	log := slog.With(
+		slog.String("func", "Create"),
+		slog.String("handler", "oauthClient"))
+
+	log.Info("Create initiated")
+
+	var payLoad domain.OAuthClientPayLoad
+	if err := c.Bind(&payLoad); err != nil {
+		log.Warn("Failed to bind oauth client data to domain")
+		return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
+			Error:     "Unprocessable Entity",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err := payLoad.Validate(); err != nil {
+		log.Warn("Invalid oauth client data")
+		return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
+			Error:     "Unprocessable Entity",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	response, err := och.oauthClientService.Create(newViewer(c), payLoad)
+	if err != nil {
+		log.Error("Error trying to call create oauth client service.")
+		return c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
+			Error:     "Internal Server Error",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	log.Info("OAuth client created successfully")
+	return c.JSON(http.StatusCreated, response)
+}
+
+// GetAll godoc
+// @Summary List oauth clients
+// @Description List every registered oauth client
+// @Tags admin
+// @Produce json
+// @Success 200 {array} domain.OAuthClientResponse
+// @Failure 401
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /v1/admin/oauth-clients [get]
+func (och *oauthClientHandler) GetAll(c echo.Context) error {
+	log := slog.With(
+		slog.String("func", "GetAll"),
+		slog.String("handler", "oauthClient"))
+
+	response, err := och.oauthClientService.GetAll()
+	if err != nil {
+		log.Error("Error trying to call get oauth clients service.")
+		return c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
+			Error:     "Internal Server Error",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	log.Info("OAuth clients successfully retrieved")
+
+	if len(response) == 0 {
+		return c.NoContent(http.StatusNoContent)
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+// Delete godoc
+// @Summary Delete an oauth client
+// @Description Delete an oauth client. Codes not yet exchanged stop working, issued sessions are kept
+// @Tags admin
+// @Param id path string true "Client ID"
+// @Success 204
+// @Failure 400 {object} domain.ErrorResponse
+// @Failure 401
+// @Failure 404 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /v1/admin/oauth-clients/{id} [delete]
+func (och *oauthClientHandler) Delete(c echo.Context) error {
+	log := slog.With(
+		slog.String("func", "Delete"),
+		slog.String("handler", "oauthClient"))
+
+	id := c.Param("id")
+	if err := util.IsValidUUID(id); err != nil {
+		log.Warn("Invalid params")
+		return c.JSON(http.StatusBadRequest, domain.ErrorResponse{
+			Error:     "Bad Request",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	err := och.oauthClientService.Delete(newViewer(c), id)
+	if err != nil && errors.Is(err, domain.ErrOAuthClientNotFound) {
+		log.Warn("OAuth client not found to delete")
+		return c.JSON(http.StatusNotFound, domain.ErrorResponse{
+			Error:     "Not Found",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err != nil {
+		log.Error("Error trying to call delete oauth client service.")
+		return c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
+			Error:     "Internal Server Error",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	log.Info("OAuth client deleted successfully")
+	return c.NoContent(http.StatusNoContent)
+}
@@ -0,0 +1,110 @@
+package handler
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/OVillas/autentication/config"
+	"github.com/OVillas/autentication/domain"
+	"github.com/OVillas/autentication/util"
+	"github.com/labstack/echo/v4"
+	"github.com/samber/do"
+)
+
+type oidcHandler struct {
+	i           *do.Injector
+	oidcService domain.OIDCService
+}
+
+func NewOIDCHandler(i *do.Injector) (domain.OIDCHandler, error) {
+	oidcService := do.MustInvoke[domain.OIDCService](i)
+	return &oidcHandler{
+		i:           i,
+		oidcService: oidcService,
+	}, nil
+}
+
+// GetConfiguration godoc
+// @Summary OpenID Connect discovery
+// @Description Get the OpenID Connect provider metadata
+// @Tags oidc
+// @Produce json
+// @Success 200 {object} domain.OpenIDConfiguration
+// @Router /.well-known/openid-configuration [get]
+func (oh *oidcHandler) GetConfiguration(c echo.Context) error {
+	issuer := config.Token.Issuer
+	if issuer == "" {
+		issuer = c.Scheme() + "://" + c.Request().Host
+	}
+
+	c.Response().Header().Set("Cache-Control", "public, max-age=300")
+	return c.JSON(http.StatusOK, domain.OpenIDConfiguration{
+		Issuer:                            issuer,
+		AuthorizationEndpoint:             issuer + "/v1/oauth/authorize",
+		TokenEndpoint:                     issuer + "/v1/oauth/token",
+		UserinfoEndpoint:                  issuer + "/userinfo",
+		JwksURI:                           issuer + "/.well-known/jwks.json",
+		ResponseTypesSupported:            []string{domain.ResponseTypeCode},
+		GrantTypesSupported:               []string{domain.GrantTypeAuthorizationCode, domain.GrantTypeRefreshToken, domain.GrantTypeClientCredentials},
+		CodeChallengeMethodsSupported:     []string{domain.CodeChallengeMethodS256},
+		TokenEndpointAuthMethodsSupported: []string{"client_secret_basic", "client_secret_post", "none"},
+		SubjectTypesSupported:             []string{"public"},
+		IDTokenSigningAlgValuesSupported:  []string{config.JWTSigningMethod},
+		ScopesSupported:                   []string{"openid", "profile", "email", domain.ScopeReadUser, domain.ScopeWriteUser},
+		ClaimsSupported:                   []string{"sub", "name", "preferred_username", "email", "email_verified"},
+	})
+}
+
+// GetUserInfo godoc
+// @Summary OpenID Connect user info
+// @Description Get the standard claims of the user owning the access token
+// @Tags oidc
+// @Produce json
+// @Success 200 {object} domain.UserInfoResponse
+// @Failure 401 {object} domain.ErrorResponse
+// @Failure 404 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /userinfo [get]
+// @Security bearerToken
+func (oh *oidcHandler) GetUserInfo(c echo.Context) error {
+	log := slog.With(
+		slog.String("func", "GetUserInfo"),
+		slog.String("handler", "oidc"))
+
+	idFromToken, err := util.ExtractUserIdFromToken(c)
+	if err != nil {
+		log.Warn("Error getting user ID from token")
+		return c.JSON(http.StatusUnauthorized, domain.ErrorResponse{
+			Error:     "Unauthorized",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	userInfo, err := oh.oidcService.GetUserInfo(c.Request().Context(), idFromToken)
+	if err != nil && errors.Is(err, domain.ErrUserNotFound) {
+		log.Warn("User not found")
+		return c.JSON(http.StatusNotFound, domain.ErrorResponse{
+			Error:     "Not Found",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err != nil {
+		log.Error("Error trying to call get user info service.")
+		return c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
+			Error:     "Internal Server Error",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	log.Info("User info successfully retrieved")
+	return c.JSON(http.StatusOK, userInfo)
+}
@@ -0,0 +1,357 @@
+package handler
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/OVillas/autentication/domain"
+	"github.com/OVillas/autentication/util"
+	"github.com/labstack/echo/v4"
+	"github.com/samber/do"
+)
+
+type organizationHandler struct {
+	i                   *do.Injector
+	organizationService domain.OrganizationService
+}
+
+func NewOrganizationHandler(i *do.Injector) (domain.OrganizationHandler, error) {
+	organizationService := do.MustInvoke[domain.OrganizationService](i)
+	return &organizationHandler{
+		i:                   i,
+		organizationService: organizationService,
+	}, nil
+}
+
+// Create godoc
+// @Summary Create an organization
+// @Description Create an organization with the caller as its first owner
+// @Tags organizations
+// @Accept json
+// @Produce json
+// @Param organization body domain.OrganizationPayLoad true "Organization"
+// @Success 201 {object} domain.OrganizationResponse
+// @Failure 401
+// @Failure 422 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /v1/organizations [post]
+// @Security bearerToken
+func (oh *organizationHandler) Create(c echo.Context) error {
+	log := slog.With(
+		slog.String("func", "Create"),
+		slog.String("handler", "organization"))
+
+	var organizationPayLoad domain.OrganizationPayLoad
+	if err := c.Bind(&organizationPayLoad); err != nil {
+		log.Warn("Failed to bind organization data to domain")
+		return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
+			Error:     "Unprocessable Entity",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err := organizationPayLoad.Validate(); err != nil {
+		log.Warn("Invalid organization data")
+		return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
+			Error:     "Unprocessable Entity",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	organizationResponse, err := oh.organizationService.Create(newViewer(c), organizationPayLoad)
+	if err != nil {
+		return organizationError(c, log, err)
+	}
+
+	log.Info("Organization created")
+	return c.JSON(http.StatusCreated, organizationResponse)
+}
+
+// GetMine godoc
+// @Summary List my organizations
+// @Description List the organizations the caller is a member of, with the role held in each
+// @Tags organizations
+// @Produce json
+// @Success 200 {array} domain.OrganizationResponse
+// @Failure 401
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /v1/users/me/organizations [get]
+// @Security bearerToken
+func (oh *organizationHandler) GetMine(c echo.Context) error {
+	log := slog.With(
+		slog.String("func", "GetMine"),
+		slog.String("handler", "organization"))
+
+	idFromToken, err := util.ExtractUserIdFromToken(c)
+	if err != nil {
+		log.Warn("Error getting id from token")
+		return c.NoContent(http.StatusUnauthorized)
+	}
+
+	organizationsResponse, err := oh.organizationService.GetMine(idFromToken)
+	if err != nil {
+		return organizationError(c, log, err)
+	}
+
+	log.Info("Organizations listed")
+	return c.JSON(http.StatusOK, organizationsResponse)
+}
+
+// GetMembers godoc
+// @Summary List the members of an organization
+// @Description List the members of an organization the caller belongs to, owners first
+// @Tags organizations
+// @Produce json
+// @Param id path string true "Organization ID"
+// @Success 200 {array} domain.MemberResponse
+// @Failure 400 {object} domain.ErrorResponse
+// @Failure 401
+// @Failure 404 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /v1/organizations/{id}/members [get]
+// @Security bearerToken
+func (oh *organizationHandler) GetMembers(c echo.Context) error {
+	log := slog.With(
+		slog.String("func", "GetMembers"),
+		slog.String("handler", "organization"))
+
+	id := c.Param("id")
+	if err := util.IsValidUUID(id); err != nil {
+		log.Warn("Invalid params")
+		return c.JSON(http.StatusBadRequest, domain.ErrorResponse{
+			Error:     "Bad Request",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	membersResponse, err := oh.organizationService.GetMembers(newViewer(c), id)
+	if err != nil {
+		return organizationError(c, log, err)
+	}
+
+	log.Info("Members listed")
+	return c.JSON(http.StatusOK, membersResponse)
+}
+
+// InviteMember godoc
+// @Summary Invite a member by email
+// @Description Add the user registered with the email to the organization, or email an invitation to register and join it when there is none. Owners and admins can invite, and only owners can invite owners
+// @Tags organizations
+// @Accept json
+// @Produce json
+// @Param id path string true "Organization ID"
+// @Param member body domain.MemberInvitationPayLoad true "Email and role, member by default"
+// @Success 201 {object} domain.MemberInvitationResponse
+// @Failure 400 {object} domain.ErrorResponse
+// @Failure 401
+// @Failure 403 {object} domain.ErrorResponse
+// @Failure 404 {object} domain.ErrorResponse
+// @Failure 409 {object} domain.ErrorResponse
+// @Failure 422 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /v1/organizations/{id}/members [post]
+// @Security bearerToken
+func (oh *organizationHandler) InviteMember(c echo.Context) error {
+	log := slog.With(
+		slog.String("func", "InviteMember"),
+		slog.String("handler", "organization"))
+
+	id := c.Param("id")
+	if err := util.IsValidUUID(id); err != nil {
+		log.Warn("Invalid params")
+		return c.JSON(http.StatusBadRequest, domain.ErrorResponse{
+			Error:     "Bad Request",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	var memberPayLoad domain.MemberInvitationPayLoad
+	if err := c.Bind(&memberPayLoad); err != nil {
+		log.Warn("Failed to bind member data to domain")
+		return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
+			Error:     "Unprocessable Entity",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err := memberPayLoad.Validate(); err != nil {
+		log.Warn("Invalid member data")
+		return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
+			Error:     "Unprocessable Entity",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	memberInvitationResponse, err := oh.organizationService.InviteMember(c.Request().Context(), newViewer(c), id, memberPayLoad)
+	if err != nil {
+		return organizationError(c, log, err)
+	}
+
+	log.Info("Member invited")
+	return c.JSON(http.StatusCreated, memberInvitationResponse)
+}
+
+// UpdateMemberRole godoc
+// @Summary Change the role of a member
+// @Description Change the role of a member to owner, admin or member. Admins manage members and admins, owners manage everyone, and the last owner stays an owner
+// @Tags organizations
+// @Accept json
+// @Param id path string true "Organization ID"
+// @Param userId path string true "User ID of the member"
+// @Param role body domain.MemberRolePayLoad true "Role"
+// @Success 204
+// @Failure 400 {object} domain.ErrorResponse
+// @Failure 401
+// @Failure 403 {object} domain.ErrorResponse
+// @Failure 404 {object} domain.ErrorResponse
+// @Failure 409 {object} domain.ErrorResponse
+// @Failure 422 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /v1/organizations/{id}/members/{userId} [patch]
+// @Security bearerToken
+func (oh *organizationHandler) UpdateMemberRole(c echo.Context) error {
+	log := slog.With(
+		slog.String("func", "UpdateMemberRole"),
+		slog.String("handler", "organization"))
+
+	id, userID := c.Param("id"), c.Param("userId")
+	if err := validMemberParams(id, userID); err != nil {
+		log.Warn("Invalid params")
+		return c.JSON(http.StatusBadRequest, domain.ErrorResponse{
+			Error:     "Bad Request",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	var rolePayLoad domain.MemberRolePayLoad
+	if err := c.Bind(&rolePayLoad); err != nil {
+		log.Warn("Failed to bind role data to domain")
+		return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
+			Error:     "Unprocessable Entity",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err := rolePayLoad.Validate(); err != nil {
+		log.Warn("Invalid role data")
+		return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
+			Error:     "Unprocessable Entity",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err := oh.organizationService.UpdateMemberRole(newViewer(c), id, userID, rolePayLoad.Role); err != nil {
+		return organizationError(c, log, err)
+	}
+
+	log.Info("Member role changed")
+	return c.NoContent(http.StatusNoContent)
+}
+
+// RemoveMember godoc
+// @Summary Remove a member or leave an organization
+// @Description Take a member out of the organization, or leave it with the caller's own user ID. The last owner cannot leave
+// @Tags organizations
+// @Param id path string true "Organization ID"
+// @Param userId path string true "User ID of the member"
+// @Success 204
+// @Failure 400 {object} domain.ErrorResponse
+// @Failure 401
+// @Failure 403 {object} domain.ErrorResponse
+// @Failure 404 {object} domain.ErrorResponse
+// @Failure 409 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /v1/organizations/{id}/members/{userId} [delete]
+// @Security bearerToken
+func (oh *organizationHandler) RemoveMember(c echo.Context) error {
+	log := slog.With(
+		slog.String("func", "RemoveMember"),
+		slog.String("handler", "organization"))
+
+	id, userID := c.Param("id"), c.Param("userId")
+	if err := validMemberParams(id, userID); err != nil {
+		log.Warn("Invalid params")
+		return c.JSON(http.StatusBadRequest, domain.ErrorResponse{
+			Error:     "Bad Request",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err := oh.organizationService.RemoveMember(newViewer(c), id, userID); err != nil {
+		return organizationError(c, log, err)
+	}
+
+	log.Info("Member removed")
+	return c.NoContent(http.StatusNoContent)
+}
+
+func validMemberParams(organizationID string, userID string) error {
+	if err := util.IsValidUUID(organizationID); err != nil {
+		return err
+	}
+
+	return util.IsValidUUID(userID)
+}
+
+func organizationError(c echo.Context, log *slog.Logger, err error) error {
+	if errors.Is(err, domain.ErrUserNotAuthorized) {
+		log.Warn("Caller cannot manage this member")
+		return c.JSON(http.StatusForbidden, domain.ErrorResponse{
+			Error:     "Forbidden",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if errors.Is(err, domain.ErrOrganizationNotFound) || errors.Is(err, domain.ErrMemberNotFound) {
+		log.Warn("Organization or member not found")
+		return c.JSON(http.StatusNotFound, domain.ErrorResponse{
+			Error:     "Not Found",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if errors.Is(err, domain.ErrLastOwner) || errors.Is(err, domain.ErrAlreadyMember) ||
+		errors.Is(err, domain.ErrUserAlreadyRegistered) {
+		log.Warn("Membership change refused", slog.Any("error", err))
+		return c.JSON(http.StatusConflict, domain.ErrorResponse{
+			Error:     "Conflict",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	log.Error("Error trying to call organization service.")
+	return c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
+		Error:     "Internal Server Error",
+		Message:   err.Error(),
+		TimeStamp: time.Now(),
+		Path:      c.Path(),
+	})
+}
@@ -0,0 +1,203 @@
+package handler
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/OVillas/autentication/domain"
+	"github.com/OVillas/autentication/util"
+	"github.com/labstack/echo/v4"
+	"github.com/samber/do"
+)
+
+type personalAccessTokenHandler struct {
+	i                          *do.Injector
+	personalAccessTokenService domain.PersonalAccessTokenService
+}
+
+func NewPersonalAccessTokenHandler(i *do.Injector) (domain.PersonalAccessTokenHandler, error) {
+	personalAccessTokenService := do.MustInvoke[domain.PersonalAccessTokenService](i)
+	return &personalAccessTokenHandler{
+		i:                          i,
+		personalAccessTokenService: personalAccessTokenService,
+	}, nil
+}
+
+// Create godoc
+// @Summary Create a personal access token
+// @Description Create a personal access token for the authenticated user. The token is only returned once
+// @Tags tokens
+// @Accept json
+// @Produce json
+// @Param token body domain.PersonalAccessTokenPayLoad true "Personal Access Token Payload"
+// @Success 201 {object} domain.PersonalAccessTokenResponse
+// @Failure 401 {object} domain.ErrorResponse
+// @Failure 422 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /v1/users/me/tokens [post]
+// @Security bearerToken
+func (pah *personalAccessTokenHandler) Create(c echo.Context) error {
+	log := slog.With(
+		slog.String("func", "Create"),
+		slog.String("handler", "personalAccessToken"))
+
+	log.Info("Create initiated")
+
+	idFromToken, err := util.ExtractUserIdFromToken(c)
+	if err != nil {
+		log.Warn("Error getting user ID from token")
+		return c.JSON(http.StatusUnauthorized, domain.ErrorResponse{
+			Error:     "Unauthorized",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	var payLoad domain.PersonalAccessTokenPayLoad
+	if err := c.Bind(&payLoad); err != nil {
+		log.Warn("Failed to bind personal access token data to domain")
+		return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
+			Error:     "Unprocessable Entity",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err := payLoad.Validate(); err != nil {
+		log.Warn("Invalid personal access token data")
+		return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
+			Error:     "Unprocessable Entity",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	response, err := pah.personalAccessTokenService.Create(idFromToken, payLoad)
+	if err != nil {
+		log.Error("Error trying to call create personal access token service.")
+		return c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
+			Error:     "Internal Server Error",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	log.Info("Personal access token created successfully")
+	return c.JSON(http.StatusCreated, response)
+}
+
+// GetAll godoc
+// @Summary List personal access tokens
+// @Description List the active personal access tokens of the authenticated user
+// @Tags tokens
+// @Produce json
+// @Success 200 {array} domain.PersonalAccessTokenResponse
+// @Failure 401 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /v1/users/me/tokens [get]
+// @Security bearerToken
+func (pah *personalAccessTokenHandler) GetAll(c echo.Context) error {
+	log := slog.With(
+		slog.String("func", "GetAll"),
+		slog.String("handler", "personalAccessToken"))
+
+	idFromToken, err := util.ExtractUserIdFromToken(c)
+	if err != nil {
+		log.Warn("Error getting user ID from token")
+		return c.JSON(http.StatusUnauthorized, domain.ErrorResponse{
+			Error:     "Unauthorized",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	response, err := pah.personalAccessTokenService.GetAll(idFromToken)
+	if err != nil {
+		log.Error("Error trying to call get personal access tokens service.")
+		return c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
+			Error:     "Internal Server Error",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	log.Info("Personal access tokens successfully retrieved")
+
+	if len(response) == 0 {
+		return c.NoContent(http.StatusNoContent)
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+// Revoke godoc
+// @Summary Revoke a personal access token
+// @Description Revoke one of the authenticated user's personal access tokens
+// @Tags tokens
+// @Param id path string true "Token ID"
+// @Success 204
+// @Failure 400 {object} domain.ErrorResponse
+// @Failure 401 {object} domain.ErrorResponse
+// @Failure 404 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /v1/users/me/tokens/{id} [delete]
+// @Security bearerToken
+func (pah *personalAccessTokenHandler) Revoke(c echo.Context) error {
+	log := slog.With(
+		slog.String("func", "Revoke"),
+		slog.String("handler", "personalAccessToken"))
+
+	id := c.Param("id")
+	if err := util.IsValidUUID(id); err != nil {
+		log.Warn("Invalid params")
+		return c.JSON(http.StatusBadRequest, domain.ErrorResponse{
+			Error:     "Bad Request",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	idFromToken, err := util.ExtractUserIdFromToken(c)
+	if err != nil {
+		log.Warn("Error getting user ID from token")
+		return c.JSON(http.StatusUnauthorized, domain.ErrorResponse{
+			Error:     "Unauthorized",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	err = pah.personalAccessTokenService.Revoke(idFromToken, id)
+	if err != nil && errors.Is(err, domain.ErrPersonalAccessTokenNotFound) {
+		log.Warn("Personal access token not found to revoke")
+		return c.JSON(http.StatusNotFound, domain.ErrorResponse{
+			Error:     "Not Found",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err != nil {
+		log.Error("Error trying to call revoke personal access token service.")
+		return c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
+			Error:     "Internal Server Error",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	log.Info("Personal access token revoked successfully")
+	return c.NoContent(http.StatusNoContent)
+}
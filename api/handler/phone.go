@@ -0,0 +1,323 @@
+package handler
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/OVillas/autentication/domain"
+	"github.com/OVillas/autentication/util"
+	"github.com/labstack/echo/v4"
+	"github.com/samber/do"
+)
+
+type phoneHandler struct {
+	i            *do.Injector
+	phoneService domain.PhoneService
+}
+
+func NewPhoneHandler(i *do.Injector) (domain.PhoneHandler, error) {
+	phoneService := do.MustInvoke[domain.PhoneService](i)
+	return &phoneHandler{
+		i:            i,
+		phoneService: phoneService,
+	}, nil
+}
+
+// SetPhoneNumber godoc
+// @Summary Set my phone number
+// @Description Store the phone number of the caller unconfirmed and text it a confirmation code
+// @Tags phone
+// @Accept json
+// @Param phone body domain.PhoneNumberPayLoad true "Phone number"
+// @Success 204
+// @Failure 401
+// @Failure 409 {object} domain.ErrorResponse
+// @Failure 422 {object} domain.ErrorResponse
+// @Failure 429 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Failure 503 {object} domain.ErrorResponse
+// @Router /v1/users/me/phone [put]
+// @Security bearerToken
+func (ph *phoneHandler) SetPhoneNumber(c echo.Context) error {
+	log := slog.With(
+		slog.String("func", "SetPhoneNumber"),
+		slog.String("handler", "phone"))
+
+	idFromToken, err := util.ExtractUserIdFromToken(c)
+	if err != nil {
+		log.Warn("Error getting id from token")
+		return c.NoContent(http.StatusUnauthorized)
+	}
+
+	var phoneNumberPayLoad domain.PhoneNumberPayLoad
+	if err := c.Bind(&phoneNumberPayLoad); err != nil {
+		log.Warn("Failed to bind phone number data to domain")
+		return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
+			Error:     "Unprocessable Entity",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err := phoneNumberPayLoad.Validate(); err != nil {
+		log.Warn("Invalid phone number data")
+		return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
+			Error:     "Unprocessable Entity",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err := ph.phoneService.SetPhoneNumber(c.Request().Context(), idFromToken, phoneNumberPayLoad.PhoneNumber); err != nil {
+		return phoneError(c, log, err)
+	}
+
+	log.Info("Phone number set")
+	return c.NoContent(http.StatusNoContent)
+}
+
+// ResendConfirmation godoc
+// @Summary Resend the phone confirmation code
+// @Description Text a new confirmation code to the phone number of the caller waiting for confirmation
+// @Tags phone
+// @Success 204
+// @Failure 401
+// @Failure 409 {object} domain.ErrorResponse
+// @Failure 429 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Failure 503 {object} domain.ErrorResponse
+// @Router /v1/users/me/phone/resend [post]
+// @Security bearerToken
+func (ph *phoneHandler) ResendConfirmation(c echo.Context) error {
+	log := slog.With(
+		slog.String("func", "ResendConfirmation"),
+		slog.String("handler", "phone"))
+
+	idFromToken, err := util.ExtractUserIdFromToken(c)
+	if err != nil {
+		log.Warn("Error getting id from token")
+		return c.NoContent(http.StatusUnauthorized)
+	}
+
+	if err := ph.phoneService.ResendConfirmation(c.Request().Context(), idFromToken); err != nil {
+		return phoneError(c, log, err)
+	}
+
+	log.Info("Phone confirmation code resent")
+	return c.NoContent(http.StatusNoContent)
+}
+
+// ConfirmPhoneNumber godoc
+// @Summary Confirm my phone number
+// @Description Confirm the phone number of the caller with the code texted to it
+// @Tags phone
+// @Accept json
+// @Param code body domain.PhoneCodePayLoad true "Confirmation code"
+// @Success 204
+// @Failure 401 {object} domain.ErrorResponse
+// @Failure 409 {object} domain.ErrorResponse
+// @Failure 422 {object} domain.ErrorResponse
+// @Failure 429 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /v1/users/me/phone/confirm [post]
+// @Security bearerToken
+func (ph *phoneHandler) ConfirmPhoneNumber(c echo.Context) error {
+	log := slog.With(
+		slog.String("func", "ConfirmPhoneNumber"),
+		slog.String("handler", "phone"))
+
+	idFromToken, err := util.ExtractUserIdFromToken(c)
+	if err != nil {
+		log.Warn("Error getting id from token")
+		return c.NoContent(http.StatusUnauthorized)
+	}
+
+	var phoneCodePayLoad domain.PhoneCodePayLoad
+	if err := c.Bind(&phoneCodePayLoad); err != nil {
+		log.Warn("Failed to bind phone code data to domain")
+		return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
+			Error:     "Unprocessable Entity",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err := phoneCodePayLoad.Validate(); err != nil {
+		log.Warn("Invalid phone code data")
+		return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
+			Error:     "Unprocessable Entity",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	err = ph.phoneService.ConfirmPhoneNumber(c.Request().Context(), idFromToken, phoneCodePayLoad.Code, newClientInfo(c))
+	if err != nil {
+		return phoneError(c, log, err)
+	}
+
+	log.Info("Phone number confirmed")
+	return c.NoContent(http.StatusNoContent)
+}
+
+// RemovePhoneNumber godoc
+// @Summary Remove my phone number
+// @Description Remove the phone number of the caller, which stops the codes of a two-step login from being texted to it
+// @Tags phone
+// @Success 204
+// @Failure 401
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /v1/users/me/phone [delete]
+// @Security bearerToken
+func (ph *phoneHandler) RemovePhoneNumber(c echo.Context) error {
+	log := slog.With(
+		slog.String("func", "RemovePhoneNumber"),
+		slog.String("handler", "phone"))
+
+	idFromToken, err := util.ExtractUserIdFromToken(c)
+	if err != nil {
+		log.Warn("Error getting id from token")
+		return c.NoContent(http.StatusUnauthorized)
+	}
+
+	if err := ph.phoneService.RemovePhoneNumber(c.Request().Context(), idFromToken, newClientInfo(c)); err != nil {
+		return phoneError(c, log, err)
+	}
+
+	log.Info("Phone number removed")
+	return c.NoContent(http.StatusNoContent)
+}
+
+// SendTwoFactorCode godoc
+// @Summary Text a login verification code
+// @Description Send a one time code by SMS to the confirmed phone number, to complete a two-step login with the sms method
+// @Tags authentication
+// @Success 204
+// @Failure 401 {object} domain.ErrorResponse
+// @Failure 422 {object} domain.ErrorResponse
+// @Failure 429 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /v1/auth/login/2fa/sms [post]
+// @Security bearerToken
+func (ph *phoneHandler) SendTwoFactorCode(c echo.Context) error {
+	log := slog.With(
+		slog.String("func", "SendTwoFactorCode"),
+		slog.String("handler", "phone"))
+
+	idFromToken, err := util.ExtractUserIdFromToken(c)
+	if err != nil {
+		log.Warn("Error getting user ID from token")
+		return c.JSON(http.StatusUnauthorized, domain.ErrorResponse{
+			Error:     "Unauthorized",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	err = ph.phoneService.SendTwoFactorCode(c.Request().Context(), idFromToken)
+	if err != nil && errors.Is(err, domain.ErrUserNotFound) {
+		log.Warn("User not found to send two-factor code")
+		return c.JSON(http.StatusUnauthorized, domain.ErrorResponse{
+			Error:     "Unauthorized",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err != nil {
+		return phoneError(c, log, err)
+	}
+
+	log.Info("SendTwoFactorCode executed successfully")
+	return c.NoContent(http.StatusNoContent)
+}
+
+func phoneError(c echo.Context, log *slog.Logger, err error) error {
+	if errors.Is(err, domain.ErrUserNotFound) {
+		log.Warn("User not found")
+		return c.JSON(http.StatusNotFound, domain.ErrorResponse{
+			Error:     "Not Found",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if errors.Is(err, domain.ErrInvalidPhoneNumber) || errors.Is(err, domain.ErrTwoFactorMethod) {
+		log.Warn("Phone number refused", slog.Any("error", err))
+		return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
+			Error:     "Unprocessable Entity",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if errors.Is(err, domain.ErrPhoneTaken) || errors.Is(err, domain.ErrPhoneAlreadyConfirmed) ||
+		errors.Is(err, domain.ErrNoPhoneNumber) {
+		log.Warn("Phone number change refused", slog.Any("error", err))
+		return c.JSON(http.StatusConflict, domain.ErrorResponse{
+			Error:     "Conflict",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if errors.Is(err, domain.ErrTooManyCodeRequests) {
+		log.Warn("Too many codes requested for the phone number")
+		setRetryAfter(c, err)
+		return c.JSON(http.StatusTooManyRequests, domain.ErrorResponse{
+			Error:     "Too Many Requests",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if errors.Is(err, domain.ErrTooManyOTPAttempts) {
+		log.Warn("Too many wrong codes")
+		return c.JSON(http.StatusTooManyRequests, domain.ErrorResponse{
+			Error:     "Too Many Requests",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if errors.Is(err, domain.ErrInvalidOTP) || errors.Is(err, domain.ErrOTPNotFound) {
+		log.Warn("Expired token or wrong token")
+		return c.JSON(http.StatusUnauthorized, domain.ErrorResponse{
+			Error:     "Unauthorized",
+			Message:   "Expired token or wrong token",
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if errors.Is(err, domain.ErrSMSUnavailable) {
+		log.Warn("SMS is not enabled")
+		return c.JSON(http.StatusServiceUnavailable, domain.ErrorResponse{
+			Error:     "Service Unavailable",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	log.Error("Error trying to call phone service.", slog.Any("error", err))
+	return c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
+		Error:     "Internal Server Error",
+		Message:   err.Error(),
+		TimeStamp: time.Now(),
+		Path:      c.Path(),
+	})
+}
@@ -0,0 +1,35 @@
+package handler
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/OVillas/autentication/domain"
+	"github.com/labstack/echo/v4"
+)
+
+// setRetryAfter tells the client, in whole seconds, when a rate limited request or a refused
+// login can be retried.
+func setRetryAfter(c echo.Context, err error) {
+	var (
+		rateLimitError     *domain.RateLimitError
+		accountLockedError *domain.AccountLockedError
+		loginDelayedError  *domain.LoginDelayedError
+		retryAfter         time.Duration
+	)
+
+	switch {
+	case errors.As(err, &rateLimitError):
+		retryAfter = rateLimitError.RetryAfter
+	case errors.As(err, &accountLockedError):
+		retryAfter = accountLockedError.RetryAfter
+	case errors.As(err, &loginDelayedError):
+		retryAfter = loginDelayedError.RetryAfter
+	default:
+		return
+	}
+
+	c.Response().Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+}
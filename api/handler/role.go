@@ -0,0 +1,338 @@
+package handler
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/OVillas/autentication/domain"
+	"github.com/OVillas/autentication/util"
+	"github.com/labstack/echo/v4"
+	"github.com/samber/do"
+)
+
+type roleHandler struct {
+	i           *do.Injector
+	roleService domain.RoleService
+}
+
+func NewRoleHandler(i *do.Injector) (domain.RoleHandler, error) {
+	roleService := do.MustInvoke[domain.RoleService](i)
+	return &roleHandler{
+		i:           i,
+		roleService: roleService,
+	}, nil
+}
+
+// AssignRole godoc
+// @Summary Assign a role to a user
+// @Description Grant a role to a user. It is carried by the access tokens the user gets from the next login or refresh
+// @Tags admin
+// @Accept json
+// @Param id path string true "User ID"
+// @Param role body domain.RolePayLoad true "Role"
+// @Success 204
+// @Failure 400 {object} domain.ErrorResponse
+// @Failure 401
+// @Failure 403 {object} domain.ErrorResponse
+// @Failure 404 {object} domain.ErrorResponse
+// @Failure 422 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /v1/admin/users/{id}/roles [post]
+// @Security bearerToken
+func (rh *roleHandler) AssignRole(c echo.Context) error {
+	log := slog.With(
+		slog.String("func", "AssignRole"),
+		slog.String("handler", "role"))
+
+	id := c.Param("id")
+	if err := util.IsValidUUID(id); err != nil {
+		log.Warn("Invalid params")
+		return c.JSON(http.StatusBadRequest, domain.ErrorResponse{
+			Error:     "Bad Request",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	var rolePayLoad domain.RolePayLoad
+	if err := c.Bind(&rolePayLoad); err != nil {
+		log.Warn("Failed to bind role data to domain")
+		return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
+			Error:     "Unprocessable Entity",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err := rolePayLoad.Validate(); err != nil {
+		log.Warn("Invalid role data")
+		return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
+			Error:     "Unprocessable Entity",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	err := rh.roleService.AssignRole(c.Request().Context(), newViewer(c), id, rolePayLoad.Role)
+	if err != nil {
+		return roleError(c, log, err)
+	}
+
+	log.Info("Role assigned")
+	return c.NoContent(http.StatusNoContent)
+}
+
+// RevokeRole godoc
+// @Summary Revoke a role from a user
+// @Description Take a role away from a user. The access tokens of the user stop working, so the role is gone right away. The last admin keeps the admin role
+// @Tags admin
+// @Param id path string true "User ID"
+// @Param role path string true "Role"
+// @Success 204
+// @Failure 400 {object} domain.ErrorResponse
+// @Failure 401
+// @Failure 403 {object} domain.ErrorResponse
+// @Failure 404 {object} domain.ErrorResponse
+// @Failure 409 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /v1/admin/users/{id}/roles/{role} [delete]
+// @Security bearerToken
+func (rh *roleHandler) RevokeRole(c echo.Context) error {
+	log := slog.With(
+		slog.String("func", "RevokeRole"),
+		slog.String("handler", "role"))
+
+	id := c.Param("id")
+	if err := util.IsValidUUID(id); err != nil {
+		log.Warn("Invalid params")
+		return c.JSON(http.StatusBadRequest, domain.ErrorResponse{
+			Error:     "Bad Request",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	err := rh.roleService.RevokeRole(c.Request().Context(), newViewer(c), id, c.Param("role"))
+	if err != nil && errors.Is(err, domain.ErrRoleNotAssigned) {
+		log.Warn("User does not have the role")
+		return c.JSON(http.StatusNotFound, domain.ErrorResponse{
+			Error:     "Not Found",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err != nil && errors.Is(err, domain.ErrLastAdmin) {
+		log.Warn("Last admin kept")
+		return c.JSON(http.StatusConflict, domain.ErrorResponse{
+			Error:     "Conflict",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err != nil {
+		return roleError(c, log, err)
+	}
+
+	log.Info("Role revoked")
+	return c.NoContent(http.StatusNoContent)
+}
+
+// CreateRole godoc
+// @Summary Create a role
+// @Description Create a role without permissions, to be granted permissions and assigned to users
+// @Tags admin
+// @Accept json
+// @Param role body domain.RolePayLoad true "Role"
+// @Success 201
+// @Failure 401
+// @Failure 403 {object} domain.ErrorResponse
+// @Failure 409 {object} domain.ErrorResponse
+// @Failure 422 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /v1/admin/roles [post]
+// @Security bearerToken
+func (rh *roleHandler) CreateRole(c echo.Context) error {
+	log := slog.With(
+		slog.String("func", "CreateRole"),
+		slog.String("handler", "role"))
+
+	var rolePayLoad domain.RolePayLoad
+	if err := c.Bind(&rolePayLoad); err != nil {
+		log.Warn("Failed to bind role data to domain")
+		return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
+			Error:     "Unprocessable Entity",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err := rolePayLoad.Validate(); err != nil {
+		log.Warn("Invalid role data")
+		return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
+			Error:     "Unprocessable Entity",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	err := rh.roleService.CreateRole(newViewer(c), rolePayLoad.Role)
+	if err != nil {
+		return roleError(c, log, err)
+	}
+
+	log.Info("Role created")
+	return c.NoContent(http.StatusCreated)
+}
+
+// GrantPermission godoc
+// @Summary Grant a permission to a role
+// @Description Grant a permission to every user with the role. Their access tokens stop working and the next refresh issues tokens carrying it. The admin role always has every permission
+// @Tags admin
+// @Accept json
+// @Param role path string true "Role"
+// @Param permission body domain.PermissionPayLoad true "Permission"
+// @Success 204
+// @Failure 401
+// @Failure 403 {object} domain.ErrorResponse
+// @Failure 404 {object} domain.ErrorResponse
+// @Failure 409 {object} domain.ErrorResponse
+// @Failure 422 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /v1/admin/roles/{role}/permissions [post]
+// @Security bearerToken
+func (rh *roleHandler) GrantPermission(c echo.Context) error {
+	log := slog.With(
+		slog.String("func", "GrantPermission"),
+		slog.String("handler", "role"))
+
+	var permissionPayLoad domain.PermissionPayLoad
+	if err := c.Bind(&permissionPayLoad); err != nil {
+		log.Warn("Failed to bind permission data to domain")
+		return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
+			Error:     "Unprocessable Entity",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err := permissionPayLoad.Validate(); err != nil {
+		log.Warn("Invalid permission data")
+		return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
+			Error:     "Unprocessable Entity",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	err := rh.roleService.GrantPermission(c.Request().Context(), newViewer(c), c.Param("role"), permissionPayLoad.Permission)
+	if err != nil {
+		return roleError(c, log, err)
+	}
+
+	log.Info("Permission granted")
+	return c.NoContent(http.StatusNoContent)
+}
+
+// RevokePermission godoc
+// @Summary Revoke a permission from a role
+// @Description Take a permission away from a role. The access tokens of its users stop working, so the permission is gone right away
+// @Tags admin
+// @Param role path string true "Role"
+// @Param permission path string true "Permission"
+// @Success 204
+// @Failure 401
+// @Failure 403 {object} domain.ErrorResponse
+// @Failure 404 {object} domain.ErrorResponse
+// @Failure 409 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /v1/admin/roles/{role}/permissions/{permission} [delete]
+// @Security bearerToken
+func (rh *roleHandler) RevokePermission(c echo.Context) error {
+	log := slog.With(
+		slog.String("func", "RevokePermission"),
+		slog.String("handler", "role"))
+
+	err := rh.roleService.RevokePermission(c.Request().Context(), newViewer(c), c.Param("role"), c.Param("permission"))
+	if err != nil && errors.Is(err, domain.ErrPermissionNotGranted) {
+		log.Warn("Role does not have the permission")
+		return c.JSON(http.StatusNotFound, domain.ErrorResponse{
+			Error:     "Not Found",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err != nil {
+		return roleError(c, log, err)
+	}
+
+	log.Info("Permission revoked")
+	return c.NoContent(http.StatusNoContent)
+}
+
+// roleError answers with the errors the role endpoints have in common.
+func roleError(c echo.Context, log *slog.Logger, err error) error {
+	if errors.Is(err, domain.ErrUserNotAuthorized) {
+		log.Warn("Caller is not an admin")
+		return c.JSON(http.StatusForbidden, domain.ErrorResponse{
+			Error:     "Forbidden",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if errors.Is(err, domain.ErrUserNotFound) || errors.Is(err, domain.ErrRoleNotFound) ||
+		errors.Is(err, domain.ErrPermissionNotFound) {
+		log.Warn("User, role or permission not found")
+		return c.JSON(http.StatusNotFound, domain.ErrorResponse{
+			Error:     "Not Found",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if errors.Is(err, domain.ErrRoleExists) || errors.Is(err, domain.ErrAdminPermissionsImmutable) {
+		log.Warn("Role change refused")
+		return c.JSON(http.StatusConflict, domain.ErrorResponse{
+			Error:     "Conflict",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	log.Error("Error trying to call role service.")
+	return c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
+		Error:     "Internal Server Error",
+		Message:   err.Error(),
+		TimeStamp: time.Now(),
+		Path:      c.Path(),
+	})
+}
+
+// newViewer identifies who makes the request, for the service to decide what they may see or do.
+func newViewer(c echo.Context) domain.Viewer {
+	idFromToken, _ := util.ExtractUserIdFromToken(c)
+	return domain.Viewer{
+		UserID:     idFromToken,
+		Admin:      util.HasRole(c, domain.RoleAdmin),
+		ClientInfo: newClientInfo(c),
+	}
+}
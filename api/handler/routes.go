@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"github.com/OVillas/autentication/config"
 	"github.com/OVillas/autentication/domain"
 	"github.com/OVillas/autentication/middleware"
 	"github.com/labstack/echo/v4"
@@ -11,35 +12,115 @@ func SetupRoutes(e *echo.Echo, i *do.Injector) {
 	setupUserRoutes(e, i)
 	setupAuthRoutes(e, i)
 	setupHealthCheckRoutes(e, i)
+	setupWellKnownRoutes(e, i)
+	setupTokenRoutes(e, i)
+	setupPersonalAccessTokenRoutes(e, i)
+	setupAdminRoutes(e, i)
+	setupSessionRoutes(e, i)
+	setupOAuthRoutes(e, i)
+	setupUserIdentityRoutes(e, i)
+	setupTrustedDeviceRoutes(e, i)
+	setupWebAuthnRoutes(e, i)
+	setupInvitationRoutes(e, i)
+	setupOrganizationRoutes(e, i)
 }
 
 func setupUserRoutes(e *echo.Echo, i *do.Injector) {
 	userHandler := do.MustInvoke[domain.UserHandler](i)
 	userPasswordHandler := do.MustInvoke[domain.UserPasswordHandler](i)
+	loginHistoryHandler := do.MustInvoke[domain.LoginHistoryHandler](i)
+	loginAlertHandler := do.MustInvoke[domain.LoginAlertHandler](i)
+	dataExportHandler := do.MustInvoke[domain.DataExportHandler](i)
+	phoneHandler := do.MustInvoke[domain.PhoneHandler](i)
+	termsHandler := do.MustInvoke[domain.TermsHandler](i)
+	avatarHandler := do.MustInvoke[domain.AvatarHandler](i)
+	metadataHandler := do.MustInvoke[domain.MetadataHandler](i)
+	authMiddleware := do.MustInvoke[*middleware.AuthMiddleware](i)
+	rateLimitMiddleware := do.MustInvoke[*middleware.RateLimitMiddleware](i)
 
 	group := e.Group("v1/users")
-	group.POST("", userHandler.Create)
-	group.GET("", userHandler.GetAll, middleware.CheckLoggedIn)
-	group.GET("/:id", userHandler.GetById, middleware.CheckLoggedIn)
-	group.GET("/name", userHandler.GetByNameOrUsername, middleware.CheckLoggedIn)
-	group.GET("/email", userHandler.GetByEmail, middleware.CheckLoggedIn)
-	group.PUT("/:id", userHandler.Update, middleware.CheckLoggedIn)
-	group.DELETE("/:id", userHandler.Delete, middleware.CheckLoggedIn)
-	group.PATCH("/:id/password", userPasswordHandler.UpdatePassword, middleware.CheckLoggedIn)
-	group.PATCH("/email/confirm", userHandler.ConfirmEmail)
+	group.POST("", userHandler.Create, rateLimitMiddleware.LimitByIP("register", config.AuthRateLimit))
+	group.GET("", userHandler.GetAll, authMiddleware.CheckLoggedIn, authMiddleware.RequirePermission(domain.PermissionUsersRead))
+	group.GET("/me", userHandler.GetMe, authMiddleware.CheckLoggedIn)
+	group.PATCH("/me", userHandler.UpdateMe, authMiddleware.CheckLoggedIn)
+	group.PUT("/me", userHandler.UpdateMe, authMiddleware.CheckLoggedIn)
+	group.DELETE("/me", userHandler.DeleteMe, authMiddleware.CheckLoggedIn)
+	group.POST("/me/anonymize", userHandler.AnonymizeMe, authMiddleware.CheckSessionLoggedIn)
+	group.PUT("/me/password", userPasswordHandler.UpdatePasswordMe, authMiddleware.CheckLoggedIn)
+	group.GET("/:id", userHandler.GetById, authMiddleware.CheckLoggedInOrApiKey)
+	group.GET("/name", userHandler.GetByNameOrUsername, authMiddleware.CheckLoggedIn)
+	group.GET("/email", userHandler.GetByEmail, authMiddleware.CheckLoggedInOrApiKey,
+		authMiddleware.RequirePermission(domain.PermissionUsersRead))
+	group.PATCH("/:id", userHandler.Update, authMiddleware.CheckLoggedIn)
+	group.PUT("/:id", userHandler.Update, authMiddleware.CheckLoggedIn)
+	group.DELETE("/:id", userHandler.Delete, authMiddleware.CheckLoggedIn)
+	group.PATCH("/:id/password", userPasswordHandler.UpdatePassword, authMiddleware.CheckLoggedIn)
+	group.PATCH("/email/confirm", userHandler.ConfirmEmail, rateLimitMiddleware.LimitByIP("confirm_code", config.AuthRateLimit))
+	group.POST("/confirmation/resend", userHandler.ResendConfirmation,
+		rateLimitMiddleware.LimitByIP("send_code", config.AuthRateLimit))
+	group.GET("/confirm-email", userHandler.ConfirmEmailByLink)
+	group.GET("/email/revert", userHandler.RevertEmailChange)
+	group.POST("/me/logout-all", userHandler.LogoutAll, authMiddleware.CheckSessionLoggedIn)
+	group.POST("/me/2fa/totp", userHandler.EnableTOTP, authMiddleware.CheckSessionLoggedIn)
+	group.POST("/me/2fa/totp/confirm", userHandler.ConfirmTOTP, authMiddleware.CheckSessionLoggedIn)
+	group.GET("/me/2fa/recovery-codes", userHandler.GetRecoveryCodeCount, authMiddleware.CheckSessionLoggedIn)
+	group.POST("/me/2fa/recovery-codes", userHandler.RegenerateRecoveryCodes, authMiddleware.CheckSessionLoggedIn)
+	group.POST("/me/2fa/disable", userHandler.DisableTwoFactor, authMiddleware.CheckSessionLoggedIn)
+	group.PUT("/me/phone", phoneHandler.SetPhoneNumber, authMiddleware.CheckLoggedIn)
+	group.DELETE("/me/phone", phoneHandler.RemovePhoneNumber, authMiddleware.CheckLoggedIn)
+	group.POST("/me/phone/resend", phoneHandler.ResendConfirmation, authMiddleware.CheckLoggedIn)
+	group.POST("/me/phone/confirm", phoneHandler.ConfirmPhoneNumber,
+		rateLimitMiddleware.LimitByIP("confirm_code", config.AuthRateLimit), authMiddleware.CheckLoggedIn)
+	group.PUT("/me/avatar", avatarHandler.Upload, authMiddleware.CheckLoggedIn)
+	group.DELETE("/me/avatar", avatarHandler.Delete, authMiddleware.CheckLoggedIn)
+	group.PATCH("/me/metadata", metadataHandler.UpdateMe, authMiddleware.CheckLoggedIn)
+	group.POST("/me/accept-terms", termsHandler.Accept, authMiddleware.CheckSessionLoggedIn)
+	group.POST("/me/deactivate", userHandler.Deactivate, authMiddleware.CheckSessionLoggedIn)
+	group.GET("/me/logins", loginHistoryHandler.GetMe, authMiddleware.CheckLoggedIn)
+	group.GET("/me/login-alerts", loginAlertHandler.GetPreference, authMiddleware.CheckLoggedIn)
+	group.PUT("/me/login-alerts", loginAlertHandler.UpdatePreference, authMiddleware.CheckLoggedIn)
+	group.GET("/me/export", dataExportHandler.Request, authMiddleware.CheckSessionLoggedIn, authMiddleware.RequireRecentLogin)
+	group.GET("/export/download", dataExportHandler.Download, rateLimitMiddleware.LimitByIP("data_export", config.AuthRateLimit))
+	group.GET("/availability", userHandler.CheckAvailability,
+		rateLimitMiddleware.LimitByIP("availability", config.AvailabilityLimit))
 
-	e.GET("v1/user", userHandler.GetCredencials, middleware.CheckLoggedIn)
+	e.GET("v1/user", userHandler.GetCredencials, authMiddleware.CheckLoggedIn)
+
+	if config.Avatar.Storage == config.AvatarStorageLocal {
+		e.Static("/avatars", config.Avatar.LocalDir)
+	}
 }
 
 func setupAuthRoutes(e *echo.Echo, i *do.Injector) {
 	userHandler := do.MustInvoke[domain.UserHandler](i)
 	userPasswordHandler := do.MustInvoke[domain.UserPasswordHandler](i)
+	socialLoginHandler := do.MustInvoke[domain.SocialLoginHandler](i)
+	magicLinkHandler := do.MustInvoke[domain.MagicLinkHandler](i)
+	phoneHandler := do.MustInvoke[domain.PhoneHandler](i)
+	authMiddleware := do.MustInvoke[*middleware.AuthMiddleware](i)
+	rateLimitMiddleware := do.MustInvoke[*middleware.RateLimitMiddleware](i)
 
 	group := e.Group("v1/auth")
-	group.POST("/password/forgot", userPasswordHandler.ForgotPassword)
-	group.POST("/password/confirm", userPasswordHandler.ConfirmResetPasswordCode)
-	group.POST("/password/reset", userPasswordHandler.ResetPassword)
-	group.POST("/login", userHandler.Login)
+	group.POST("/password/forgot", userPasswordHandler.ForgotPassword,
+		rateLimitMiddleware.LimitByIP("send_code", config.AuthRateLimit))
+	group.POST("/password/confirm", userPasswordHandler.ConfirmResetPasswordCode,
+		rateLimitMiddleware.LimitByIP("confirm_code", config.AuthRateLimit))
+	group.POST("/password/reset", userPasswordHandler.ResetPassword, authMiddleware.CheckPasswordResetToken)
+	group.POST("/password/strength", userPasswordHandler.Strength,
+		rateLimitMiddleware.LimitByIP("password_strength", config.PasswordStrengthLimit))
+	group.POST("/login", userHandler.Login, rateLimitMiddleware.LimitByIP("login", config.AuthRateLimit))
+	group.POST("/login/2fa", userHandler.LoginTwoFactor,
+		rateLimitMiddleware.LimitByIP("confirm_code", config.AuthRateLimit), authMiddleware.CheckTwoFactorChallengeToken)
+	group.POST("/login/2fa/email", userHandler.SendTwoFactorCode, authMiddleware.CheckTwoFactorChallengeToken)
+	group.POST("/login/2fa/sms", phoneHandler.SendTwoFactorCode, authMiddleware.CheckTwoFactorChallengeToken)
+	group.POST("/reactivate", userHandler.Reactivate, authMiddleware.CheckReactivationToken)
+	group.POST("/login/magic-link", magicLinkHandler.Send,
+		rateLimitMiddleware.LimitByIP("send_code", config.AuthRateLimit))
+	group.GET("/login/magic-link/verify", magicLinkHandler.Verify)
+	group.POST("/refresh", userHandler.Refresh, middleware.CheckCSRF)
+	group.POST("/logout", userHandler.Logout, authMiddleware.CheckSessionLoggedIn, middleware.CheckCSRF)
+	group.GET("/:provider", socialLoginHandler.Redirect)
+	group.GET("/:provider/callback", socialLoginHandler.Callback)
 }
 
 func setupHealthCheckRoutes(e *echo.Echo, i *do.Injector) {
@@ -47,3 +128,145 @@ func setupHealthCheckRoutes(e *echo.Echo, i *do.Injector) {
 
 	e.GET("/", healthCheckHandler.HealthCheck)
 }
+
+func setupWellKnownRoutes(e *echo.Echo, i *do.Injector) {
+	jwksHandler := do.MustInvoke[domain.JWKSHandler](i)
+	oidcHandler := do.MustInvoke[domain.OIDCHandler](i)
+	authMiddleware := do.MustInvoke[*middleware.AuthMiddleware](i)
+
+	group := e.Group(".well-known")
+	group.GET("/jwks.json", jwksHandler.GetKeys)
+	group.GET("/openid-configuration", oidcHandler.GetConfiguration)
+
+	e.GET("/userinfo", oidcHandler.GetUserInfo, authMiddleware.CheckLoggedIn)
+}
+
+func setupTokenRoutes(e *echo.Echo, i *do.Injector) {
+	tokenHandler := do.MustInvoke[domain.TokenHandler](i)
+
+	group := e.Group("v1/token")
+	group.POST("/introspect", tokenHandler.Introspect, middleware.CheckServiceCredentials)
+}
+
+func setupPersonalAccessTokenRoutes(e *echo.Echo, i *do.Injector) {
+	personalAccessTokenHandler := do.MustInvoke[domain.PersonalAccessTokenHandler](i)
+	authMiddleware := do.MustInvoke[*middleware.AuthMiddleware](i)
+
+	group := e.Group("v1/users/me/tokens", authMiddleware.CheckSessionLoggedIn)
+	group.POST("", personalAccessTokenHandler.Create)
+	group.GET("", personalAccessTokenHandler.GetAll)
+	group.DELETE("/:id", personalAccessTokenHandler.Revoke)
+}
+
+func setupAdminRoutes(e *echo.Echo, i *do.Injector) {
+	apiKeyHandler := do.MustInvoke[domain.ApiKeyHandler](i)
+	oauthClientHandler := do.MustInvoke[domain.OAuthClientHandler](i)
+	userHandler := do.MustInvoke[domain.UserHandler](i)
+	roleHandler := do.MustInvoke[domain.RoleHandler](i)
+	auditHandler := do.MustInvoke[domain.AuditHandler](i)
+	metadataHandler := do.MustInvoke[domain.MetadataHandler](i)
+	userCacheHandler := do.MustInvoke[domain.UserCacheHandler](i)
+	authMiddleware := do.MustInvoke[*middleware.AuthMiddleware](i)
+
+	group := e.Group("v1/admin", authMiddleware.CheckAdmin)
+	group.POST("/api-keys", apiKeyHandler.Create)
+	group.GET("/api-keys", apiKeyHandler.GetAll)
+	group.POST("/api-keys/:id/rotate", apiKeyHandler.Rotate)
+	group.DELETE("/api-keys/:id", apiKeyHandler.Revoke)
+	group.POST("/oauth-clients", oauthClientHandler.Create)
+	group.GET("/oauth-clients", oauthClientHandler.GetAll)
+	group.DELETE("/oauth-clients/:id", oauthClientHandler.Delete)
+	group.DELETE("/users/:id/2fa", userHandler.AdminDisableTwoFactor)
+	group.POST("/users/:id/password-reset", userHandler.AdminForcePasswordReset)
+	group.POST("/users/:id/suspend", userHandler.AdminSuspend)
+	group.POST("/users/:id/unsuspend", userHandler.AdminUnsuspend)
+	group.GET("/users/:id", userHandler.AdminGetById)
+	group.POST("/users/:id/restore", userHandler.AdminRestore)
+	group.DELETE("/users/:id", userHandler.AdminDelete)
+	group.POST("/users/:id/anonymize", userHandler.AdminAnonymize)
+	group.PATCH("/users/:id/metadata", metadataHandler.AdminUpdate)
+	group.POST("/users/:id/roles", roleHandler.AssignRole)
+	group.DELETE("/users/:id/roles/:role", roleHandler.RevokeRole)
+	group.POST("/roles", roleHandler.CreateRole)
+	group.POST("/roles/:role/permissions", roleHandler.GrantPermission)
+	group.DELETE("/roles/:role/permissions/:permission", roleHandler.RevokePermission)
+	group.GET("/audit", auditHandler.GetAll)
+	group.GET("/user-cache", userCacheHandler.GetStats)
+}
+
+func setupSessionRoutes(e *echo.Echo, i *do.Injector) {
+	sessionHandler := do.MustInvoke[domain.SessionHandler](i)
+	authMiddleware := do.MustInvoke[*middleware.AuthMiddleware](i)
+
+	group := e.Group("v1/users/me/sessions", authMiddleware.CheckSessionLoggedIn)
+	group.GET("", sessionHandler.GetAll)
+	group.DELETE("/:id", sessionHandler.Revoke)
+}
+
+func setupOAuthRoutes(e *echo.Echo, i *do.Injector) {
+	oauthHandler := do.MustInvoke[domain.OAuthHandler](i)
+
+	group := e.Group("v1/oauth")
+	group.GET("/authorize", oauthHandler.Authorize)
+	group.POST("/authorize", oauthHandler.AuthorizeLogin)
+	group.POST("/token", oauthHandler.Token)
+}
+
+func setupUserIdentityRoutes(e *echo.Echo, i *do.Injector) {
+	userIdentityHandler := do.MustInvoke[domain.UserIdentityHandler](i)
+	authMiddleware := do.MustInvoke[*middleware.AuthMiddleware](i)
+
+	group := e.Group("v1/users/me/identities", authMiddleware.CheckSessionLoggedIn)
+	group.GET("", userIdentityHandler.GetAll)
+	group.POST("/:provider/link", userIdentityHandler.Link)
+	group.DELETE("/:id", userIdentityHandler.Unlink)
+}
+
+func setupTrustedDeviceRoutes(e *echo.Echo, i *do.Injector) {
+	trustedDeviceHandler := do.MustInvoke[domain.TrustedDeviceHandler](i)
+	authMiddleware := do.MustInvoke[*middleware.AuthMiddleware](i)
+
+	group := e.Group("v1/users/me/trusted-devices", authMiddleware.CheckSessionLoggedIn)
+	group.GET("", trustedDeviceHandler.GetAll)
+	group.DELETE("/:id", trustedDeviceHandler.Revoke)
+}
+
+func setupWebAuthnRoutes(e *echo.Echo, i *do.Injector) {
+	webAuthnHandler := do.MustInvoke[domain.WebAuthnHandler](i)
+	authMiddleware := do.MustInvoke[*middleware.AuthMiddleware](i)
+
+	e.POST("v1/auth/webauthn/login/begin", webAuthnHandler.BeginLogin)
+	e.POST("v1/auth/webauthn/login/finish", webAuthnHandler.FinishLogin)
+
+	group := e.Group("v1/users/me/webauthn", authMiddleware.CheckSessionLoggedIn)
+	group.POST("/register/begin", webAuthnHandler.BeginRegistration)
+	group.POST("/register/finish", webAuthnHandler.FinishRegistration)
+	group.GET("/credentials", webAuthnHandler.GetAll)
+	group.PATCH("/credentials/:id", webAuthnHandler.Rename)
+	group.DELETE("/credentials/:id", webAuthnHandler.Delete)
+}
+
+func setupInvitationRoutes(e *echo.Echo, i *do.Injector) {
+	invitationHandler := do.MustInvoke[domain.InvitationHandler](i)
+	authMiddleware := do.MustInvoke[*middleware.AuthMiddleware](i)
+	rateLimitMiddleware := do.MustInvoke[*middleware.RateLimitMiddleware](i)
+
+	e.POST("v1/invitations", invitationHandler.Create, authMiddleware.CheckSessionLoggedIn)
+	e.POST("v1/users/invitation", invitationHandler.Accept, rateLimitMiddleware.LimitByIP("register", config.AuthRateLimit))
+}
+
+func setupOrganizationRoutes(e *echo.Echo, i *do.Injector) {
+	organizationHandler := do.MustInvoke[domain.OrganizationHandler](i)
+	userHandler := do.MustInvoke[domain.UserHandler](i)
+	authMiddleware := do.MustInvoke[*middleware.AuthMiddleware](i)
+
+	e.GET("v1/users/me/organizations", organizationHandler.GetMine, authMiddleware.CheckLoggedIn)
+	e.POST("v1/users/me/organization", userHandler.SwitchOrganization, authMiddleware.CheckSessionLoggedIn)
+
+	group := e.Group("v1/organizations", authMiddleware.CheckSessionLoggedIn)
+	group.POST("", organizationHandler.Create)
+	group.GET("/:id/members", organizationHandler.GetMembers)
+	group.POST("/:id/members", organizationHandler.InviteMember)
+	group.PATCH("/:id/members/:userId", organizationHandler.UpdateMemberRole)
+	group.DELETE("/:id/members/:userId", organizationHandler.RemoveMember)
+}
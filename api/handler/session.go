@@ -0,0 +1,160 @@
+package handler
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/OVillas/autentication/domain"
+	"github.com/OVillas/autentication/util"
+	"github.com/labstack/echo/v4"
+	"github.com/samber/do"
+)
+
+type sessionHandler struct {
+	i              *do.Injector
+	sessionService domain.SessionService
+}
+
+func NewSessionHandler(i *do.Injector) (domain.SessionHandler, error) {
+	sessionService := do.MustInvoke[domain.SessionService](i)
+	return &sessionHandler{
+		i:              i,
+		sessionService: sessionService,
+	}, nil
+}
+
+// GetAll godoc
+// @Summary List sessions
+// @Description List the active sessions of the authenticated user, marking the one making the request
+// @Tags sessions
+// @Produce json
+// @Success 200 {array} domain.SessionResponse
+// @Failure 401 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /v1/users/me/sessions [get]
+// @Security bearerToken
+func (sh *sessionHandler) GetAll(c echo.Context) error {
+	log := slog.With(
+		slog.String("func", "GetAll"),
+		slog.String("handler", "session"))
+
+	claims, err := util.ExtractTokenClaims(c)
+	if err != nil {
+		log.Warn("Error getting claims from token")
+		return c.JSON(http.StatusUnauthorized, domain.ErrorResponse{
+			Error:     "Unauthorized",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	response, err := sh.sessionService.GetAll(claims.UserID, claims.SessionID)
+	if err != nil {
+		log.Error("Error trying to call get sessions service.")
+		return c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
+			Error:     "Internal Server Error",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	log.Info("Sessions successfully retrieved")
+
+	if len(response) == 0 {
+		return c.NoContent(http.StatusNoContent)
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+// Revoke godoc
+// @Summary Revoke a session
+// @Description Log out one of the authenticated user's sessions, including the access tokens it issued
+// @Tags sessions
+// @Param id path string true "Session ID"
+// @Success 204
+// @Failure 400 {object} domain.ErrorResponse
+// @Failure 401 {object} domain.ErrorResponse
+// @Failure 403 {object} domain.ErrorResponse
+// @Failure 404 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /v1/users/me/sessions/{id} [delete]
+// @Security bearerToken
+func (sh *sessionHandler) Revoke(c echo.Context) error {
+	log := slog.With(
+		slog.String("func", "Revoke"),
+		slog.String("handler", "session"))
+
+	id := c.Param("id")
+	if err := util.IsValidUUID(id); err != nil {
+		log.Warn("Invalid params")
+		return c.JSON(http.StatusBadRequest, domain.ErrorResponse{
+			Error:     "Bad Request",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	idFromToken, err := util.ExtractUserIdFromToken(c)
+	if err != nil {
+		log.Warn("Error getting user ID from token")
+		return c.JSON(http.StatusUnauthorized, domain.ErrorResponse{
+			Error:     "Unauthorized",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	err = sh.sessionService.Revoke(idFromToken, id)
+	if err != nil && errors.Is(err, domain.ErrUserNotAuthorized) {
+		log.Warn("Session belongs to another user")
+		return c.JSON(http.StatusForbidden, domain.ErrorResponse{
+			Error:     "Forbidden",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err != nil && errors.Is(err, domain.ErrSessionNotFound) {
+		log.Warn("Session not found to revoke")
+		return c.JSON(http.StatusNotFound, domain.ErrorResponse{
+			Error:     "Not Found",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err != nil {
+		log.Error("Error trying to call revoke session service.")
+		return c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
+			Error:     "Internal Server Error",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	log.Info("Session revoked successfully")
+	return c.NoContent(http.StatusNoContent)
+}
+
+func newClientInfo(c echo.Context) domain.ClientInfo {
+	userAgent := c.Request().UserAgent()
+	if len(userAgent) > 512 {
+		userAgent = userAgent[:512]
+	}
+
+	return domain.ClientInfo{
+		IP:        c.RealIP(),
+		UserAgent: userAgent,
+		TenantID:  util.ExtractTenant(c),
+	}
+}
@@ -0,0 +1,107 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/OVillas/autentication/config"
+	"github.com/OVillas/autentication/domain"
+	"github.com/OVillas/autentication/secure"
+	"github.com/labstack/echo/v4"
+)
+
+// setSessionCookies moves the refresh token out of the response body into an HttpOnly cookie
+// and issues the matching CSRF token, readable by the SPA so it can echo it in X-CSRF-Token.
+// Sessions opened without remember me get browser session cookies.
+func setSessionCookies(c echo.Context, loginResponse *domain.LoginResponse) error {
+	csrfToken, err := secure.GenerateOpaqueToken()
+	if err != nil {
+		return err
+	}
+
+	var expires time.Time
+	if loginResponse.Persistent {
+		expires = loginResponse.RefreshTokenExpiresAt
+	}
+
+	c.SetCookie(&http.Cookie{
+		Name:     config.SessionCookie.Name,
+		Value:    loginResponse.RefreshToken,
+		Path:     config.SessionCookie.Path,
+		Domain:   config.SessionCookie.Domain,
+		Expires:  expires,
+		Secure:   true,
+		HttpOnly: true,
+		SameSite: http.SameSiteStrictMode,
+	})
+
+	c.SetCookie(&http.Cookie{
+		Name:     domain.CSRFCookieName,
+		Value:    csrfToken,
+		Path:     "/",
+		Domain:   config.SessionCookie.Domain,
+		Expires:  expires,
+		Secure:   true,
+		SameSite: http.SameSiteStrictMode,
+	})
+
+	loginResponse.RefreshToken = ""
+	return nil
+}
+
+func clearSessionCookies(c echo.Context) {
+	for _, cookie := range []struct{ name, path string }{
+		{config.SessionCookie.Name, config.SessionCookie.Path},
+		{domain.CSRFCookieName, "/"},
+	} {
+		c.SetCookie(&http.Cookie{
+			Name:     cookie.name,
+			Path:     cookie.path,
+			Domain:   config.SessionCookie.Domain,
+			Expires:  time.Unix(0, 0),
+			MaxAge:   -1,
+			Secure:   true,
+			HttpOnly: cookie.name == config.SessionCookie.Name,
+			SameSite: http.SameSiteStrictMode,
+		})
+	}
+}
+
+func refreshTokenFromCookie(c echo.Context) string {
+	cookie, err := c.Cookie(config.SessionCookie.Name)
+	if err != nil {
+		return ""
+	}
+
+	return cookie.Value
+}
+
+// setTrustedDeviceCookie moves the trusted device token out of the response body into an
+// HttpOnly cookie, sent back only to the login routes.
+func setTrustedDeviceCookie(c echo.Context, loginResponse *domain.LoginResponse) {
+	if loginResponse.TrustedDeviceToken == "" {
+		return
+	}
+
+	c.SetCookie(&http.Cookie{
+		Name:     domain.TrustedDeviceCookieName,
+		Value:    loginResponse.TrustedDeviceToken,
+		Path:     config.SessionCookie.Path,
+		Domain:   config.SessionCookie.Domain,
+		Expires:  loginResponse.TrustedDeviceExpiresAt,
+		Secure:   true,
+		HttpOnly: true,
+		SameSite: http.SameSiteStrictMode,
+	})
+
+	loginResponse.TrustedDeviceToken = ""
+}
+
+func trustedDeviceTokenFromCookie(c echo.Context) string {
+	cookie, err := c.Cookie(domain.TrustedDeviceCookieName)
+	if err != nil {
+		return ""
+	}
+
+	return cookie.Value
+}
@@ -0,0 +1,219 @@
+package handler
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/OVillas/autentication/config"
+	"github.com/OVillas/autentication/domain"
+	"github.com/OVillas/autentication/util"
+	"github.com/labstack/echo/v4"
+	"github.com/samber/do"
+)
+
+type socialLoginHandler struct {
+	i                  *do.Injector
+	socialLoginService domain.SocialLoginService
+}
+
+func NewSocialLoginHandler(i *do.Injector) (domain.SocialLoginHandler, error) {
+	socialLoginService := do.MustInvoke[domain.SocialLoginService](i)
+	return &socialLoginHandler{
+		i:                  i,
+		socialLoginService: socialLoginService,
+	}, nil
+}
+
+// Redirect godoc
+// @Summary Sign in with an identity provider
+// @Description Redirect the browser to the identity provider, google for instance, with a single-use state
+// @Tags auth
+// @Param provider path string true "Identity provider"
+// @Success 302
+// @Failure 404 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /v1/auth/{provider} [get]
+func (slh *socialLoginHandler) Redirect(c echo.Context) error {
+	log := slog.With(
+		slog.String("func", "Redirect"),
+		slog.String("handler", "socialLogin"))
+
+	log.Info("Redirect initiated")
+
+	authCodeURL, err := slh.socialLoginService.Begin(util.ExtractTenant(c), c.Param("provider"))
+	if err != nil && errors.Is(err, domain.ErrUnknownProvider) {
+		log.Warn("Unknown identity provider")
+		return c.JSON(http.StatusNotFound, domain.ErrorResponse{
+			Error:     "Not Found",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err != nil {
+		log.Error("Error trying to call begin social login service.")
+		return c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
+			Error:     "Internal Server Error",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	log.Info("Redirecting to identity provider")
+	return c.Redirect(http.StatusFound, authCodeURL)
+}
+
+// Callback godoc
+// @Summary Identity provider callback
+// @Description Exchange the code returned by the identity provider and sign the user in, creating the account on first sign in. When the flow was started to link an identity, the linked identity is returned instead
+// @Tags auth
+// @Produce json
+// @Param provider path string true "Identity provider"
+// @Param state query string true "State sent on redirect"
+// @Param code query string true "Authorization code"
+// @Success 200 {object} domain.LoginResponse
+// @Failure 400 {object} domain.ErrorResponse
+// @Failure 401 {object} domain.ErrorResponse
+// @Failure 403 {object} domain.ErrorResponse "Refused, or a domain.AccountSuspendedResponse when the account is suspended"
+// @Failure 404 {object} domain.ErrorResponse
+// @Failure 409 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /v1/auth/{provider}/callback [get]
+func (slh *socialLoginHandler) Callback(c echo.Context) error {
+	log := slog.With(
+		slog.String("func", "Callback"),
+		slog.String("handler", "socialLogin"))
+
+	log.Info("Callback initiated")
+
+	if providerError := c.QueryParam("error"); providerError != "" {
+		log.Warn("Identity provider returned an error: " + providerError)
+		return c.JSON(http.StatusUnauthorized, domain.ErrorResponse{
+			Error:     "Unauthorized",
+			Message:   providerError,
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	state, code := c.QueryParam("state"), c.QueryParam("code")
+	if state == "" || code == "" {
+		log.Warn("Missing state or code")
+		return c.JSON(http.StatusBadRequest, domain.ErrorResponse{
+			Error:     "Bad Request",
+			Message:   "state and code are required",
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	result, err := slh.socialLoginService.Complete(c.Request().Context(), c.Param("provider"), state, code, newClientInfo(c))
+	if err != nil && errors.Is(err, domain.ErrUnknownProvider) {
+		log.Warn("Unknown identity provider")
+		return c.JSON(http.StatusNotFound, domain.ErrorResponse{
+			Error:     "Not Found",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err != nil && errors.Is(err, domain.ErrInvalidState) {
+		log.Warn("Invalid state")
+		return c.JSON(http.StatusBadRequest, domain.ErrorResponse{
+			Error:     "Bad Request",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err != nil && errors.Is(err, domain.ErrSocialLogin) {
+		log.Warn("Identity provider refused the sign in")
+		return c.JSON(http.StatusUnauthorized, domain.ErrorResponse{
+			Error:     "Unauthorized",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err != nil && (errors.Is(err, domain.ErrPasswordResetRequired) || errors.Is(err, domain.ErrAccountDeactivated)) {
+		log.Warn("Sign in with identity provider refused", slog.Any("error", err))
+		return c.JSON(http.StatusForbidden, domain.ErrorResponse{
+			Error:     "Forbidden",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err != nil && errors.Is(err, domain.ErrRegistrationClosed) {
+		log.Warn("Sign up with identity provider in invite only mode")
+		return c.JSON(http.StatusForbidden, domain.ErrorResponse{
+			Error:     "Forbidden",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err != nil && (errors.Is(err, domain.ErrEmailNotVerified) || errors.Is(err, domain.ErrNoVerifiedEmail)) {
+		log.Warn("Email not verified by identity provider")
+		return c.JSON(http.StatusForbidden, domain.ErrorResponse{
+			Error:     "Forbidden",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err != nil && (errors.Is(err, domain.ErrUserAlreadyRegistered) || errors.Is(err, domain.ErrIdentityLinked) || errors.Is(err, domain.ErrTooManySessions)) {
+		log.Warn("Sign in with identity provider refused", slog.Any("error", err))
+		return c.JSON(http.StatusConflict, domain.ErrorResponse{
+			Error:     "Conflict",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err != nil && errors.Is(err, domain.ErrAccountSuspended) {
+		log.Warn("Sign in with identity provider to a suspended account")
+		return accountSuspended(c, err)
+	}
+
+	if err != nil {
+		log.Error("Error trying to call complete social login service.")
+		return c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
+			Error:     "Internal Server Error",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if result.LinkedIdentity != nil {
+		log.Info("Identity linked successfully")
+		return c.JSON(http.StatusOK, result.LinkedIdentity)
+	}
+
+	if config.SessionCookie.Enabled {
+		if err := setSessionCookies(c, result.LoginResponse); err != nil {
+			log.Error("Error trying to set session cookies", slog.Any("error", err))
+			return c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
+				Error:     "Internal Server Error",
+				Message:   err.Error(),
+				TimeStamp: time.Now(),
+				Path:      c.Path(),
+			})
+		}
+	}
+
+	log.Info("Social login executed successfully")
+	return c.JSON(http.StatusOK, result.LoginResponse)
+}
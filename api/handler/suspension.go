@@ -0,0 +1,39 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/OVillas/autentication/domain"
+	"github.com/labstack/echo/v4"
+)
+
+// accountSuspended refuses a login to a suspended account, telling when the suspension ends if
+// it does.
+func accountSuspended(c echo.Context, err error) error {
+	response := domain.AccountSuspendedResponse{
+		Error:     "Forbidden",
+		Message:   err.Error(),
+		Code:      domain.AccountSuspendedCode,
+		TimeStamp: time.Now(),
+		Path:      c.Path(),
+	}
+
+	var accountSuspendedError *domain.AccountSuspendedError
+	if errors.As(err, &accountSuspendedError) {
+		response.SuspendedUntil = accountSuspendedError.Until
+	}
+
+	return c.JSON(http.StatusForbidden, response)
+}
+
+// suspendedMessage words the suspension for the pages shown to users.
+func suspendedMessage(err error) string {
+	var accountSuspendedError *domain.AccountSuspendedError
+	if errors.As(err, &accountSuspendedError) && accountSuspendedError.Until != nil {
+		return "Conta suspensa até " + accountSuspendedError.Until.Local().Format("02/01/2006 15:04") + "."
+	}
+
+	return "Conta suspensa."
+}
@@ -0,0 +1,97 @@
+package handler
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/OVillas/autentication/domain"
+	"github.com/OVillas/autentication/util"
+	"github.com/labstack/echo/v4"
+	"github.com/samber/do"
+)
+
+type termsHandler struct {
+	i            *do.Injector
+	termsService domain.TermsService
+}
+
+func NewTermsHandler(i *do.Injector) (domain.TermsHandler, error) {
+	termsService := do.MustInvoke[domain.TermsService](i)
+	return &termsHandler{
+		i:            i,
+		termsService: termsService,
+	}, nil
+}
+
+// Accept godoc
+// @Summary Accept the terms
+// @Description Record that the caller accepted the current version of the terms of service and privacy policy. Until then, the other requests of a user who accepted an older version are refused with 451
+// @Tags users
+// @Accept json
+// @Param terms body domain.AcceptTermsPayLoad true "Accepted version"
+// @Success 204
+// @Failure 401
+// @Failure 409 {object} domain.ErrorResponse "The version is not the current one"
+// @Failure 422 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /v1/users/me/accept-terms [post]
+// @Security bearerToken
+func (th *termsHandler) Accept(c echo.Context) error {
+	log := slog.With(
+		slog.String("func", "Accept"),
+		slog.String("handler", "terms"))
+
+	idFromToken, err := util.ExtractUserIdFromToken(c)
+	if err != nil {
+		log.Warn("Error getting id from token")
+		return c.NoContent(http.StatusUnauthorized)
+	}
+
+	var acceptTermsPayLoad domain.AcceptTermsPayLoad
+	if err := c.Bind(&acceptTermsPayLoad); err != nil {
+		log.Warn("Failed to bind terms data to domain")
+		return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
+			Error:     "Unprocessable Entity",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err := acceptTermsPayLoad.Validate(); err != nil {
+		log.Warn("Invalid terms data")
+		return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
+			Error:     "Unprocessable Entity",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+			Fields:    domain.FieldErrors(err),
+		})
+	}
+
+	err = th.termsService.Accept(idFromToken, acceptTermsPayLoad.TermsVersion, newClientInfo(c))
+	if err != nil && errors.Is(err, domain.ErrTermsVersionOutdated) {
+		log.Warn("Outdated terms version accepted")
+		return c.JSON(http.StatusConflict, domain.ErrorResponse{
+			Error:     "Conflict",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err != nil {
+		log.Error("Error trying to call terms service.")
+		return c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
+			Error:     "Internal Server Error",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	log.Info("Terms accepted")
+	return c.NoContent(http.StatusNoContent)
+}
@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/OVillas/autentication/domain"
+	"github.com/labstack/echo/v4"
+	"github.com/samber/do"
+)
+
+type tokenHandler struct {
+	i            *do.Injector
+	tokenService domain.TokenService
+}
+
+func NewTokenHandler(i *do.Injector) (domain.TokenHandler, error) {
+	tokenService := do.MustInvoke[domain.TokenService](i)
+	return &tokenHandler{
+		i:            i,
+		tokenService: tokenService,
+	}, nil
+}
+
+// Introspect godoc
+// @Summary Introspect a token
+// @Description Return RFC 7662 metadata for an access or refresh token. Requires service basic auth credentials
+// @Tags token
+// @Accept json
+// @Produce json
+// @Param introspection body domain.IntrospectionPayLoad true "Introspection Payload"
+// @Success 200 {object} domain.IntrospectionResponse
+// @Failure 401
+// @Failure 422 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /v1/token/introspect [post]
+func (th *tokenHandler) Introspect(c echo.Context) error {
+	log := slog.With(
+		slog.String("func", "Introspect"),
+		slog.String("handler", "token"))
+
+	log.Info("Introspect service initiated")
+
+	var introspectionPayLoad domain.IntrospectionPayLoad
+	if err := c.Bind(&introspectionPayLoad); err != nil {
+		log.Warn("Failed to bind introspection data to domain")
+		return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
+			Error:     "Unprocessable Entity",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err := introspectionPayLoad.Validate(); err != nil {
+		log.Warn("Invalid introspection data")
+		return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
+			Error:     "Unprocessable Entity",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	introspectionResponse, err := th.tokenService.Introspect(c.Request().Context(), introspectionPayLoad.Token)
+	if err != nil {
+		log.Error("Error trying to call introspect service.")
+		return c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
+			Error:     "Internal Server Error",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	log.Info("Introspect executed successfully")
+	return c.JSON(http.StatusOK, introspectionResponse)
+}
@@ -0,0 +1,136 @@
+package handler
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/OVillas/autentication/domain"
+	"github.com/OVillas/autentication/util"
+	"github.com/labstack/echo/v4"
+	"github.com/samber/do"
+)
+
+type trustedDeviceHandler struct {
+	i                    *do.Injector
+	trustedDeviceService domain.TrustedDeviceService
+}
+
+func NewTrustedDeviceHandler(i *do.Injector) (domain.TrustedDeviceHandler, error) {
+	trustedDeviceService := do.MustInvoke[domain.TrustedDeviceService](i)
+	return &trustedDeviceHandler{
+		i:                    i,
+		trustedDeviceService: trustedDeviceService,
+	}, nil
+}
+
+// GetAll godoc
+// @Summary List trusted devices
+// @Description List the devices allowed to skip the second step of the login of the authenticated user
+// @Tags two-factor
+// @Produce json
+// @Success 200 {array} domain.TrustedDeviceResponse
+// @Failure 401 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /v1/users/me/trusted-devices [get]
+// @Security bearerToken
+func (tdh *trustedDeviceHandler) GetAll(c echo.Context) error {
+	log := slog.With(
+		slog.String("func", "GetAll"),
+		slog.String("handler", "trustedDevice"))
+
+	idFromToken, err := util.ExtractUserIdFromToken(c)
+	if err != nil {
+		log.Warn("Error getting user ID from token")
+		return c.JSON(http.StatusUnauthorized, domain.ErrorResponse{
+			Error:     "Unauthorized",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	response, err := tdh.trustedDeviceService.GetAll(idFromToken)
+	if err != nil {
+		log.Error("Error trying to call get trusted devices service.")
+		return c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
+			Error:     "Internal Server Error",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	log.Info("Trusted devices successfully retrieved")
+
+	if len(response) == 0 {
+		return c.NoContent(http.StatusNoContent)
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+// Revoke godoc
+// @Summary Revoke a trusted device
+// @Description Make a device ask for the second factor again on its next login
+// @Tags two-factor
+// @Param id path string true "Trusted device ID"
+// @Success 204
+// @Failure 400 {object} domain.ErrorResponse
+// @Failure 401 {object} domain.ErrorResponse
+// @Failure 404 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /v1/users/me/trusted-devices/{id} [delete]
+// @Security bearerToken
+func (tdh *trustedDeviceHandler) Revoke(c echo.Context) error {
+	log := slog.With(
+		slog.String("func", "Revoke"),
+		slog.String("handler", "trustedDevice"))
+
+	id := c.Param("id")
+	if err := util.IsValidUUID(id); err != nil {
+		log.Warn("Invalid params")
+		return c.JSON(http.StatusBadRequest, domain.ErrorResponse{
+			Error:     "Bad Request",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	idFromToken, err := util.ExtractUserIdFromToken(c)
+	if err != nil {
+		log.Warn("Error getting user ID from token")
+		return c.JSON(http.StatusUnauthorized, domain.ErrorResponse{
+			Error:     "Unauthorized",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	err = tdh.trustedDeviceService.Revoke(idFromToken, id)
+	if err != nil && errors.Is(err, domain.ErrTrustedDeviceNotFound) {
+		log.Warn("Trusted device not found to revoke")
+		return c.JSON(http.StatusNotFound, domain.ErrorResponse{
+			Error:     "Not Found",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err != nil {
+		log.Error("Error trying to call revoke trusted device service.")
+		return c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
+			Error:     "Internal Server Error",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	log.Info("Trusted device revoked successfully")
+	return c.NoContent(http.StatusNoContent)
+}
@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/OVillas/autentication/config"
 	"github.com/OVillas/autentication/domain"
 	"github.com/OVillas/autentication/util"
 	"github.com/badoux/checkmail"
@@ -14,18 +15,18 @@ import (
 )
 
 type userHandler struct {
-	i                      *do.Injector
-	userService            domain.UserService
-	confirmatioCodeService domain.ConfirmationCodeService
+	i                 *do.Injector
+	userService       domain.UserService
+	permissionChecker domain.PermissionChecker
 }
 
 func NewUserHandler(i *do.Injector) (domain.UserHandler, error) {
 	userService := do.MustInvoke[domain.UserService](i)
-	confimatioCodeService := do.MustInvoke[domain.ConfirmationCodeService](i)
+	permissionChecker := do.MustInvoke[domain.PermissionChecker](i)
 	return &userHandler{
-		i:                      i,
-		userService:            userService,
-		confirmatioCodeService: confimatioCodeService,
+		i:                 i,
+		userService:       userService,
+		permissionChecker: permissionChecker,
 	}, nil
 }
 
@@ -37,9 +38,11 @@ func NewUserHandler(i *do.Injector) (domain.UserHandler, error) {
 // @Produce json
 // @Param user body domain.UserPayLoad true "User Payload"
 // @Success 201
+// @Failure 403 {object} domain.ErrorResponse "Registration is by invitation only"
 // @Failure 422 {object} domain.ErrorResponse
-// @Failure 409 {object} domain.ErrorResponse
+// @Failure 409 {object} domain.ErrorResponse "Taken email or username, or outdated terms version"
 // @Failure 500 {object} domain.ErrorResponse
+// @Failure 503 {object} domain.ErrorResponse
 // @Router /v1/users [post]
 func (uh *userHandler) Create(c echo.Context) error {
 	log := slog.With(
@@ -66,10 +69,21 @@ func (uh *userHandler) Create(c echo.Context) error {
 			Message:   err.Error(),
 			TimeStamp: time.Now(),
 			Path:      c.Path(),
+			Fields:    domain.FieldErrors(err),
 		})
 	}
 
-	err := uh.userService.Create(userPayLoad)
+	err := uh.userService.Create(c.Request().Context(), userPayLoad, newClientInfo(c))
+
+	if err != nil && errors.Is(err, domain.ErrRegistrationClosed) {
+		log.Warn("Open registration in invite only mode")
+		return c.JSON(http.StatusForbidden, domain.ErrorResponse{
+			Error:     "Forbidden",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
 
 	if err != nil && errors.Is(err, domain.ErrUserAlreadyRegistered) {
 		log.Warn("There is already a registered user with this email: " + userPayLoad.Email)
@@ -78,23 +92,75 @@ func (uh *userHandler) Create(c echo.Context) error {
 			Message:   err.Error(),
 			TimeStamp: time.Now(),
 			Path:      c.Path(),
+			Fields:    domain.FieldErrors(err),
 		})
 	}
 
-	if err != nil {
-		log.Error("Error trying to call Create user service.")
+	if err != nil && errors.Is(err, domain.ErrUsernameTaken) {
+		log.Warn("There is already a registered user with this username: " + userPayLoad.Username)
+		return c.JSON(http.StatusConflict, domain.ErrorResponse{
+			Error:     "Username taken",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+			Fields:    domain.FieldErrors(err),
+		})
+	}
+
+	if err != nil && errors.Is(err, domain.ErrTermsVersionOutdated) {
+		log.Warn("Registration with outdated terms version")
+		return c.JSON(http.StatusConflict, domain.ErrorResponse{
+			Error:     "Outdated terms",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err != nil && errors.Is(err, domain.ErrAccountDeleted) {
+		log.Warn("Registration with the email of a deleted account: " + userPayLoad.Email)
+		return c.JSON(http.StatusConflict, domain.ErrorResponse{
+			Error:     "Account deleted",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err != nil && errors.Is(err, domain.ErrPasswordBreached) {
+		log.Warn("Breached password refused")
+		return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
+			Error:     "Invalid user data",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err != nil && errors.Is(err, domain.ErrPasswordBreachCheck) {
+		log.Error("Breached password check unavailable")
+		return c.JSON(http.StatusServiceUnavailable, domain.ErrorResponse{
+			Error:     "Service Unavailable",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err != nil && errors.Is(err, domain.ErrToSendConfirmationCode) {
+		log.Error("User created but confirmation code not sent")
 		return c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
-			Error:     "Internal server error",
+			Error:     "Failed to send confirmation code",
 			Message:   err.Error(),
 			TimeStamp: time.Now(),
 			Path:      c.Path(),
 		})
 	}
 
-	err = uh.confirmatioCodeService.SendConfirmationCode(userPayLoad.Email)
 	if err != nil {
+		log.Error("Error trying to call Create user service.")
 		return c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
-			Error:     "Failed to send confirmation code",
+			Error:     "Internal server error",
 			Message:   err.Error(),
 			TimeStamp: time.Now(),
 			Path:      c.Path(),
@@ -107,10 +173,24 @@ func (uh *userHandler) Create(c echo.Context) error {
 
 // GetAll godoc
 // @Summary Get all users
-// @Description Get all users in the system
+// @Description Get one page of the users in the system, filtered and sorted, oldest first by default. Pages can be read by number or, with the next_cursor of the previous page, by cursor, which stays stable while users are created. Requires users:read
 // @Tags users
 // @Produce json
-// @Success 200 {array} domain.UserResponse
+// @Param q query string false "Part of the name, username or email"
+// @Param email_confirmed query bool false "Only users with or without a confirmed email"
+// @Param two_factor query bool false "Only users with or without two-factor authentication"
+// @Param created_from query string false "Created at or after, RFC 3339"
+// @Param created_to query string false "Created at or before, RFC 3339"
+// @Param never_logged_in query bool false "Only users who never logged in, or only the ones who did"
+// @Param inactive_days query int false "Only users who have not logged in for at least this many days"
+// @Param sort query string false "Sort column, users who never logged in first by last_login_at" Enums(created_at, name, username, last_login_at) default(created_at)
+// @Param order query string false "Sort direction" Enums(asc, desc) default(asc)
+// @Param page query int false "Page, starting at 1" default(1)
+// @Param per_page query int false "Users per page, at most 100" default(20)
+// @Param cursor query string false "next_cursor of the previous page, instead of page"
+// @Success 200 {object} domain.UserPage
+// @Failure 403
+// @Failure 422 {object} domain.ErrorResponse
 // @Failure 500 {object} domain.ErrorResponse
 // @Router /v1/users [get]
 // @Security bearerToken
@@ -119,7 +199,28 @@ func (uh *userHandler) GetAll(c echo.Context) error {
 		slog.String("func", "GetAll"),
 		slog.String("handler", "user"))
 
-	userResponse, err := uh.userService.GetAll()
+	var query domain.UserListQuery
+	if err := c.Bind(&query); err != nil {
+		log.Warn("Failed to bind listing query to domain")
+		return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
+			Error:     "Unprocessable Entity",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err := query.Validate(); err != nil {
+		log.Warn("Invalid listing query")
+		return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
+			Error:     "Unprocessable Entity",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	userPage, err := uh.userService.GetAll(c.Request().Context(), util.ExtractTenant(c), query)
 	if err != nil {
 		log.Error("Error trying to call get users service.")
 		return c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
@@ -131,25 +232,21 @@ func (uh *userHandler) GetAll(c echo.Context) error {
 	}
 
 	log.Info("Users successfully retrieved")
-
-	if len(userResponse) == 0 {
-		return c.NoContent(http.StatusNoContent)
-	}
-
-	return c.JSON(http.StatusOK, userResponse)
+	return c.JSON(http.StatusOK, userPage)
 }
 
 // GetById godoc
 // @Summary Get user by ID
-// @Description Get a user by ID
+// @Description Get a user by ID. Only the user themselves and callers with users:read get the account data; anyone else, api keys included, gets the public profile
 // @Tags users
 // @Produce json
 // @Param id path string true "User ID"
-// @Success 200 {object} domain.UserResponse
+// @Success 200 {object} domain.UserResponse "Account data for the user themselves, a domain.PublicUserResponse for anyone else"
 // @Failure 400 {object} domain.ErrorResponse
 // @Failure 500 {object} domain.ErrorResponse
 // @Router /v1/users/{id} [get]
 // @Security bearerToken
+// @Security apiKey
 func (uh *userHandler) GetById(c echo.Context) error {
 	log := slog.With(
 		slog.String("func", "GetById"),
@@ -167,24 +264,24 @@ func (uh *userHandler) GetById(c echo.Context) error {
 		})
 	}
 
-	userResponse, err := uh.userService.GetById(id)
+	// Api keys authenticate a service, not a user, so they only see public profiles.
+	viewer := newViewer(c)
+	canRead, err := uh.permissionChecker.Has(c, domain.PermissionUsersRead)
 	if err != nil {
-		log.Error("Error trying to call get user by id service.")
+		log.Error("Error trying to check permission", slog.Any("error", err))
 		return c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
 			Error:     "Error retrieving user by ID",
-			Message:   err.Error(),
+			Message:   domain.ErrGetPermission.Error(),
 			TimeStamp: time.Now(),
 			Path:      c.Path(),
 		})
 	}
 
-	log.Info("User successfully retrieved")
-
-	if userResponse == nil {
-		return c.NoContent(http.StatusNoContent)
+	if canRead {
+		viewer.Permissions = append(viewer.Permissions, domain.PermissionUsersRead)
 	}
 
-	return c.JSON(http.StatusOK, userResponse)
+	return uh.getById(c, log, id, viewer)
 }
 
 // GetById godoc
@@ -234,7 +331,7 @@ func (uh *userHandler) GetCredencials(c echo.Context) error {
 		})
 	}
 
-	userResponse, err := uh.userService.GetById(idFromToken)
+	userResponse, err := uh.userService.GetById(c.Request().Context(), idFromToken, domain.Viewer{UserID: idFromToken, ClientInfo: newClientInfo(c)})
 	if err != nil {
 		log.Error("Error trying to call get user by id service.")
 		return c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
@@ -256,13 +353,16 @@ func (uh *userHandler) GetCredencials(c echo.Context) error {
 
 // GetByNameOrUsername godoc
 // @Summary Get user by name or username
-// @Description Get a user by name or username
+// @Description Get one page of the public profiles of the users whose name or username contains the search, oldest first. Pages can be read by number or, with the next_cursor of the previous page, by cursor
 // @Tags users
 // @Produce json
 // @Param name query string true "Name or Username"
-// @Success 200 {array} domain.UserResponse
+// @Param page query int false "Page, starting at 1" default(1)
+// @Param per_page query int false "Users per page, at most 100" default(20)
+// @Param cursor query string false "next_cursor of the previous page, instead of page"
+// @Success 200 {object} domain.PublicUserPage
 // @Failure 400 {object} domain.ErrorResponse
-// @Failure 404
+// @Failure 422 {object} domain.ErrorResponse
 // @Failure 500 {object} domain.ErrorResponse
 // @Router /v1/users/name [get]
 // @Security bearerToken
@@ -283,7 +383,28 @@ func (uh *userHandler) GetByNameOrUsername(c echo.Context) error {
 		})
 	}
 
-	userResponse, err := uh.userService.GetByNameOrUsername(name)
+	var pageRequest domain.PageRequest
+	if err := c.Bind(&pageRequest); err != nil {
+		log.Warn("Failed to bind page data to domain")
+		return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
+			Error:     "Unprocessable Entity",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err := pageRequest.Validate(); err != nil {
+		log.Warn("Invalid page data")
+		return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
+			Error:     "Unprocessable Entity",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	userPage, err := uh.userService.GetByNameOrUsername(c.Request().Context(), util.ExtractTenant(c), name, pageRequest)
 	if err != nil {
 		log.Error("Error trying to call get user by name service.")
 		return c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
@@ -295,26 +416,23 @@ func (uh *userHandler) GetByNameOrUsername(c echo.Context) error {
 	}
 
 	log.Info("User successfully retrieved")
-
-	if userResponse == nil {
-		return c.NoContent(http.StatusNoContent)
-	}
-
-	return c.JSON(http.StatusOK, userResponse)
+	return c.JSON(http.StatusOK, userPage)
 }
 
 // GetByEmail godoc
 // @Summary Get user by email
-// @Description Get a user by their email address
+// @Description Get a user by their email address. Requires users:read, or an api key
 // @Tags users
 // @Accept json
 // @Produce json
 // @Param e query string true "e"
 // @Success 200 {object} domain.UserResponse
 // @Failure 400 {object} domain.ErrorResponse
+// @Failure 403
 // @Failure 500 {object} domain.ErrorResponse
 // @Router /v1/users/email [get]
 // @Security bearerToken
+// @Security apiKey
 func (uh *userHandler) GetByEmail(c echo.Context) error {
 	log := slog.With(
 		slog.String("func", "GetByEmail"),
@@ -342,7 +460,7 @@ func (uh *userHandler) GetByEmail(c echo.Context) error {
 		})
 	}
 
-	userResponse, err := uh.userService.GetByEmail(email)
+	userResponse, err := uh.userService.GetByEmail(c.Request().Context(), util.ExtractTenant(c), email)
 	if err != nil {
 		log.Error("Error trying to call get user by email service.")
 		return c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
@@ -362,20 +480,78 @@ func (uh *userHandler) GetByEmail(c echo.Context) error {
 	return c.JSON(http.StatusOK, userResponse)
 }
 
+// CheckAvailability godoc
+// @Summary Check username and email availability
+// @Description Tell whether a username and an email can still be registered, without telling anything about the accounts holding them. The email is not checked when registrations do not disclose taken emails
+// @Tags users
+// @Produce json
+// @Param username query string false "Username"
+// @Param email query string false "Email"
+// @Success 200 {object} domain.AvailabilityResponse
+// @Failure 422 {object} domain.ErrorResponse
+// @Failure 429
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /v1/users/availability [get]
+func (uh *userHandler) CheckAvailability(c echo.Context) error {
+	log := slog.With(
+		slog.String("func", "CheckAvailability"),
+		slog.String("handler", "user"))
+
+	var query domain.AvailabilityQuery
+	if err := c.Bind(&query); err != nil {
+		log.Warn("Failed to bind availability query to domain")
+		return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
+			Error:     "Unprocessable Entity",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err := query.Validate(); err != nil {
+		log.Warn("Invalid availability query")
+		return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
+			Error:     "Unprocessable Entity",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	response, err := uh.userService.CheckAvailability(c.Request().Context(), util.ExtractTenant(c), query)
+	if err != nil {
+		log.Error("Error trying to call check availability service.")
+		return c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
+			Error:     "Internal Server Error",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	log.Info("Availability successfully checked")
+	return c.JSON(http.StatusOK, response)
+}
+
 // Update godoc
 // @Summary Update a user
-// @Description Update a user's information
+// @Description Update a user's information, only changing the fields sent. Changing the email requires the current password, and the new email is only switched once the code sent to it is confirmed through the email confirmation endpoint, and the old address gets a link to undo the change
 // @Tags users
 // @Accept json
 // @Produce json
 // @Param id path string true "User ID"
 // @Param user body domain.UserUpdatePayLoad true "User Update Payload"
+// @Success 202 "A new email waits for the code sent to it"
 // @Success 204
 // @Failure 400 {object} domain.ErrorResponse
 // @Failure 401 {object} domain.ErrorResponse
 // @Failure 403
 // @Failure 404 {object} domain.ErrorResponse
+// @Failure 409 {object} domain.ErrorResponse
+// @Failure 422 {object} domain.ErrorResponse
+// @Failure 429 {object} domain.ErrorResponse
 // @Failure 500 {object} domain.ErrorResponse
+// @Router /v1/users/{id} [patch]
 // @Router /v1/users/{id} [put]
 // @Security bearerToken
 func (uh *userHandler) Update(c echo.Context) error {
@@ -394,6 +570,16 @@ func (uh *userHandler) Update(c echo.Context) error {
 		})
 	}
 
+	if util.IsClientCaller(c) {
+		log.Warn("Machine client tried to update a user")
+		return c.JSON(http.StatusForbidden, domain.ErrorResponse{
+			Error:     "Forbidden",
+			Message:   domain.ErrClientNotAllowed.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
 	idFromToken, err := util.ExtractUserIdFromToken(c)
 	if err != nil {
 		log.Warn("Error getting user ID from token")
@@ -415,97 +601,24 @@ func (uh *userHandler) Update(c echo.Context) error {
 		})
 	}
 
-	var userUpdatePayLoad domain.UserUpdatePayLoad
-	if err := c.Bind(&userUpdatePayLoad); err != nil {
-		log.Warn("Failed to bind user data to domain")
-		return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
-			Error:     "Unprocessable Entity",
-			Message:   err.Error(),
-			TimeStamp: time.Now(),
-			Path:      c.Path(),
-		})
-	}
-
-	if userUpdatePayLoad.Email == "" && userUpdatePayLoad.Name == "" {
-		log.Warn("Both name and email are empty")
-		return c.JSON(http.StatusBadRequest, domain.ErrorResponse{
-			Error:     "Bad Request",
-			Message:   "Both name and email cannot be empty",
-			TimeStamp: time.Now(),
-			Path:      c.Path(),
-		})
-	}
-
-	if userUpdatePayLoad.Name != "" {
-		if err := userUpdatePayLoad.Validate(); err != nil {
-			log.Warn("Invalid user data")
-			return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
-				Error:     "Unprocessable Entity",
-				Message:   err.Error(),
-				TimeStamp: time.Now(),
-				Path:      c.Path(),
-			})
-		}
-	}
-
-	if userUpdatePayLoad.Email != "" {
-		if err := checkmail.ValidateFormat(userUpdatePayLoad.Email); err != nil {
-			log.Warn("Invalid user data")
-			return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
-				Error:     "Unprocessable Entity",
-				Message:   err.Error(),
-				TimeStamp: time.Now(),
-				Path:      c.Path(),
-			})
-		}
-	}
-
-	err = uh.userService.Update(id, userUpdatePayLoad)
-
-	if err != nil && errors.Is(err, domain.ErrUserNotFound) {
-		log.Warn("User not found to update your information's")
-		return c.JSON(http.StatusNotFound, domain.ErrorResponse{
-			Error:     "Not Found",
-			Message:   err.Error(),
-			TimeStamp: time.Now(),
-			Path:      c.Path(),
-		})
-	}
-
-	if err != nil && errors.Is(err, domain.ErrSameEmail) {
-		log.Warn("User not found to update your information's")
-		return c.JSON(http.StatusNotFound, domain.ErrorResponse{
-			Error:     "Not Found",
-			Message:   err.Error(),
-			TimeStamp: time.Now(),
-			Path:      c.Path(),
-		})
-	}
-
-	if err != nil {
-		log.Error("Error trying to call update user service.")
-		return c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
-			Error:     "Internal Server Error",
-			Message:   err.Error(),
-			TimeStamp: time.Now(),
-			Path:      c.Path(),
-		})
-	}
-
-	log.Info("Update executed successfully")
-	return c.NoContent(http.StatusNoContent)
+	return uh.update(c, log, id)
 }
 
 // Delete godoc
 // @Summary Delete a user
-// @Description Delete a user by ID
+// @Description Schedule the deletion of a user by ID, confirmed with the current password. Its sessions end and the account and its data are permanently removed after the grace period, unless the user logs in again before then. A caller with users:delete deleting another user deletes it right away, without the password, as AdminDelete does
 // @Tags users
+// @Accept json
+// @Produce json
 // @Param id path string true "User ID"
-// @Success 204
+// @Param deleteUser body domain.DeleteUserPayLoad true "Current password"
+// @Success 202 {object} domain.DeletionScheduledResponse
+// @Success 204 "Another user deleted by an admin"
 // @Failure 400 {object} domain.ErrorResponse
 // @Failure 401 {object} domain.ErrorResponse
 // @Failure 403
 // @Failure 404 {object} domain.ErrorResponse
+// @Failure 422 {object} domain.ErrorResponse
 // @Failure 500 {object} domain.ErrorResponse
 // @Router /v1/users/{id} [delete]
 // @Security bearerToken
@@ -525,6 +638,16 @@ func (uh *userHandler) Delete(c echo.Context) error {
 		})
 	}
 
+	if util.IsClientCaller(c) {
+		log.Warn("Machine client tried to delete a user")
+		return c.JSON(http.StatusForbidden, domain.ErrorResponse{
+			Error:     "Forbidden",
+			Message:   domain.ErrClientNotAllowed.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
 	idFromToken, err := util.ExtractUserIdFromToken(c)
 	if err != nil {
 		log.Warn("Error getting user ID from token")
@@ -537,59 +660,176 @@ func (uh *userHandler) Delete(c echo.Context) error {
 	}
 
 	if id != idFromToken {
-		log.Warn("You cannot delete the data of a user other than yourself")
-		return c.NoContent(http.StatusForbidden)
+		canDelete, err := uh.permissionChecker.Has(c, domain.PermissionUsersDelete)
+		if err != nil {
+			log.Error("Error trying to check permission", slog.Any("error", err))
+			return c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
+				Error:     "Internal Server Error",
+				Message:   domain.ErrGetPermission.Error(),
+				TimeStamp: time.Now(),
+				Path:      c.Path(),
+			})
+		}
+
+		if !canDelete {
+			log.Warn("You cannot delete the data of a user other than yourself")
+			return c.NoContent(http.StatusForbidden)
+		}
+
+		return uh.adminDelete(c, log, id)
 	}
 
-	err = uh.userService.Delete(id)
+	return uh.delete(c, log, id)
+}
 
-	if err != nil && errors.Is(err, domain.ErrUserNotFound) {
-		log.Warn("User not found to delete")
-		return c.JSON(http.StatusNotFound, domain.ErrorResponse{
-			Error:     "Not Found",
+// GetMe godoc
+// @Summary Get the authenticated user
+// @Description Get the user the access token belongs to
+// @Tags users
+// @Produce json
+// @Success 200 {object} domain.UserResponse
+// @Failure 401
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /v1/users/me [get]
+// @Security bearerToken
+func (uh *userHandler) GetMe(c echo.Context) error {
+	log := slog.With(
+		slog.String("func", "GetMe"),
+		slog.String("handler", "user"))
+
+	idFromToken, err := util.ExtractUserIdFromToken(c)
+	if err != nil {
+		log.Warn("Error getting user ID from token")
+		return c.JSON(http.StatusUnauthorized, domain.ErrorResponse{
+			Error:     "Unauthorized",
 			Message:   err.Error(),
 			TimeStamp: time.Now(),
 			Path:      c.Path(),
 		})
 	}
 
-	if err != nil {
-		log.Error("Error trying to call delete service.")
-		return c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
-			Error:     "Internal Server Error",
-			Message:   err.Error(),
+	return uh.getById(c, log, idFromToken, domain.Viewer{UserID: idFromToken, ClientInfo: newClientInfo(c)})
+}
+
+// UpdateMe godoc
+// @Summary Update the authenticated user
+// @Description Update the name, username or email of the user the access token belongs to, only changing the fields sent. A new email must be confirmed with the current password and only replaces the current one once the code sent to it is confirmed
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param user body domain.UserUpdatePayLoad true "User Update Payload"
+// @Success 202 "A new email waits for the code sent to it"
+// @Success 204
+// @Failure 400 {object} domain.ErrorResponse
+// @Failure 401 {object} domain.ErrorResponse
+// @Failure 403 {object} domain.ErrorResponse
+// @Failure 404 {object} domain.ErrorResponse
+// @Failure 409 {object} domain.ErrorResponse
+// @Failure 422 {object} domain.ErrorResponse
+// @Failure 429 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /v1/users/me [patch]
+// @Router /v1/users/me [put]
+// @Security bearerToken
+func (uh *userHandler) UpdateMe(c echo.Context) error {
+	log := slog.With(
+		slog.String("func", "UpdateMe"),
+		slog.String("handler", "user"))
+
+	if util.IsClientCaller(c) {
+		log.Warn("Machine client tried to update a user")
+		return c.JSON(http.StatusForbidden, domain.ErrorResponse{
+			Error:     "Forbidden",
+			Message:   domain.ErrClientNotAllowed.Error(),
 			TimeStamp: time.Now(),
 			Path:      c.Path(),
 		})
 	}
 
-	log.Info("User successfully deleted")
+	idFromToken, err := util.ExtractUserIdFromToken(c)
+	if err != nil {
+		log.Warn("Error getting user ID from token")
+		return c.JSON(http.StatusUnauthorized, domain.ErrorResponse{
+			Error:     "Unauthorized",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
 
-	return c.NoContent(http.StatusNoContent)
+	return uh.update(c, log, idFromToken)
 }
 
-// Login godoc
-// @Summary Login a user
-// @Description Authenticate user and return JWT token
-// @Tags authentication
+// DeleteMe godoc
+// @Summary Delete the authenticated user
+// @Description Schedule the deletion of the user the access token belongs to, confirmed with the current password. Its sessions end and the account and its data are permanently removed after the grace period, unless the user logs in again before then
+// @Tags users
 // @Accept json
 // @Produce json
-// @Param login body domain.Login true "Login Payload"
-// @Success 200 {object} string "JWT Token"
-// @Failure 422 {object} domain.ErrorResponse
-// @Failure 403
+// @Param deleteUser body domain.DeleteUserPayLoad true "Current password"
+// @Success 202 {object} domain.DeletionScheduledResponse
+// @Failure 401 {object} domain.ErrorResponse
+// @Failure 403 {object} domain.ErrorResponse
 // @Failure 404 {object} domain.ErrorResponse
+// @Failure 422 {object} domain.ErrorResponse
 // @Failure 500 {object} domain.ErrorResponse
-// @Router /v1/auth/login [post]
-func (uh *userHandler) Login(c echo.Context) error {
+// @Router /v1/users/me [delete]
+// @Security bearerToken
+func (uh *userHandler) DeleteMe(c echo.Context) error {
 	log := slog.With(
-		slog.String("func", "Login"),
-		slog.String("handler", "authentication"))
+		slog.String("func", "DeleteMe"),
+		slog.String("handler", "user"))
 
-	log.Info("Login service initiated")
+	if util.IsClientCaller(c) {
+		log.Warn("Machine client tried to delete a user")
+		return c.JSON(http.StatusForbidden, domain.ErrorResponse{
+			Error:     "Forbidden",
+			Message:   domain.ErrClientNotAllowed.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
 
-	var login domain.Login
-	if err := c.Bind(&login); err != nil {
+	idFromToken, err := util.ExtractUserIdFromToken(c)
+	if err != nil {
+		log.Warn("Error getting user ID from token")
+		return c.JSON(http.StatusUnauthorized, domain.ErrorResponse{
+			Error:     "Unauthorized",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	return uh.delete(c, log, idFromToken)
+}
+
+// getById answers with the user with id as viewer may see it, for GetById, GetMe and AdminGetById.
+func (uh *userHandler) getById(c echo.Context, log *slog.Logger, id string, viewer domain.Viewer) error {
+	userResponse, err := uh.userService.GetById(c.Request().Context(), id, viewer)
+	if err != nil {
+		log.Error("Error trying to call get user by id service.")
+		return c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
+			Error:     "Error retrieving user by ID",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	log.Info("User successfully retrieved")
+
+	if userResponse == nil {
+		return c.NoContent(http.StatusNoContent)
+	}
+
+	return c.JSON(http.StatusOK, userResponse)
+}
+
+// update applies the update in the body to the user with id, for Update and UpdateMe.
+func (uh *userHandler) update(c echo.Context, log *slog.Logger, id string) error {
+	var userUpdatePayLoad domain.UserUpdatePayLoad
+	if err := c.Bind(&userUpdatePayLoad); err != nil {
 		log.Warn("Failed to bind user data to domain")
 		return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
 			Error:     "Unprocessable Entity",
@@ -599,39 +839,98 @@ func (uh *userHandler) Login(c echo.Context) error {
 		})
 	}
 
-	if err := login.Validate(); err != nil {
-		log.Warn("Invalid login data")
+	if err := userUpdatePayLoad.Validate(); err != nil {
+		log.Warn("Invalid user data")
 		return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
 			Error:     "Unprocessable Entity",
 			Message:   err.Error(),
 			TimeStamp: time.Now(),
 			Path:      c.Path(),
+			Fields:    domain.FieldErrors(err),
 		})
 	}
 
-	token, err := uh.userService.Login(login)
-	if err != nil && errors.Is(err, domain.ErrPasswordNotMatch) {
-		log.Warn("Invalid username or password")
-		return c.JSON(http.StatusForbidden, domain.ErrorResponse{
-			Error:     "Forbidden",
-			Message:   "Invalid username or password",
+	if userUpdatePayLoad.IsEmpty() {
+		log.Info("Nothing to update")
+		return c.NoContent(http.StatusNoContent)
+	}
+
+	if userUpdatePayLoad.Email != nil && userUpdatePayLoad.Password == "" {
+		log.Warn("Email change without the current password")
+		return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
+			Error:     "Unprocessable Entity",
+			Message:   "password is required to change the email",
 			TimeStamp: time.Now(),
 			Path:      c.Path(),
 		})
 	}
 
+	err := uh.userService.Update(c.Request().Context(), id, userUpdatePayLoad)
+
 	if err != nil && errors.Is(err, domain.ErrUserNotFound) {
-		log.Warn("User not found with username: " + login.Username)
+		log.Warn("User not found to update your information's")
+		return c.JSON(http.StatusNotFound, domain.ErrorResponse{
+			Error:     "Not Found",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err != nil && errors.Is(err, domain.ErrSameEmail) {
+		log.Warn("User not found to update your information's")
 		return c.JSON(http.StatusNotFound, domain.ErrorResponse{
 			Error:     "Not Found",
-			Message:   "User not found with username: " + login.Username,
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err != nil && errors.Is(err, domain.ErrPasswordNotMatch) {
+		log.Warn("Invalid password")
+		return c.JSON(http.StatusUnauthorized, domain.ErrorResponse{
+			Error:     "Unauthorized",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err != nil && errors.Is(err, domain.ErrUsernameTaken) {
+		log.Warn("New username already taken")
+		return c.JSON(http.StatusConflict, domain.ErrorResponse{
+			Error:     "Conflict",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+			Fields:    domain.FieldErrors(err),
+		})
+	}
+
+	if err != nil && errors.Is(err, domain.ErrUserAlreadyRegistered) {
+		log.Warn("New email already registered")
+		return c.JSON(http.StatusConflict, domain.ErrorResponse{
+			Error:     "Conflict",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err != nil && errors.Is(err, domain.ErrTooManyCodeRequests) {
+		log.Warn("Too many codes requested for the new email")
+		setRetryAfter(c, err)
+		return c.JSON(http.StatusTooManyRequests, domain.ErrorResponse{
+			Error:     "Too Many Requests",
+			Message:   err.Error(),
 			TimeStamp: time.Now(),
 			Path:      c.Path(),
 		})
 	}
 
 	if err != nil {
-		log.Error("Error trying to call login service.")
+		log.Error("Error trying to call update user service.")
 		return c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
 			Error:     "Internal Server Error",
 			Message:   err.Error(),
@@ -640,33 +939,48 @@ func (uh *userHandler) Login(c echo.Context) error {
 		})
 	}
 
-	log.Info("Login executed successfully")
-	return c.JSON(http.StatusOK, token)
+	if userUpdatePayLoad.Email != nil {
+		log.Info("Update executed successfully, email change pending")
+		return c.NoContent(http.StatusAccepted)
+	}
+
+	log.Info("Update executed successfully")
+	return c.NoContent(http.StatusNoContent)
 }
 
-// ConfirmEmail godoc
-// @Summary Confirm user's email
-// @Description Confirm a user's email with the confirmation code
+// AnonymizeMe godoc
+// @Summary Erase the authenticated user
+// @Description Erase the personal data of the user the access token belongs to right away, confirmed with the current password. The account keeps only its ID, can no longer be logged in to, and its username and email can be registered again. This cannot be undone
 // @Tags users
 // @Accept json
-// @Produce json
-// @Param confirmCode body domain.ConfirmCode true "Confirmation Code Payload"
-// @Success 200
+// @Param anonymize body domain.AnonymizePayLoad true "Current password"
+// @Success 204
+// @Failure 401 {object} domain.ErrorResponse
+// @Failure 403 {object} domain.ErrorResponse
+// @Failure 404 {object} domain.ErrorResponse
 // @Failure 422 {object} domain.ErrorResponse
-// @Failure 401
 // @Failure 500 {object} domain.ErrorResponse
-// @Router /v1/users/email/confirm [post]
+// @Router /v1/users/me/anonymize [post]
 // @Security bearerToken
-func (uh *userHandler) ConfirmEmail(c echo.Context) error {
+func (uh *userHandler) AnonymizeMe(c echo.Context) error {
 	log := slog.With(
-		slog.String("func", "ConfirmEmail"),
-		slog.String("handler", "authentication"))
+		slog.String("func", "AnonymizeMe"),
+		slog.String("handler", "user"))
 
-	log.Info("ConfirmEmail service initiated")
+	idFromToken, err := util.ExtractUserIdFromToken(c)
+	if err != nil {
+		log.Warn("Error getting user ID from token")
+		return c.JSON(http.StatusUnauthorized, domain.ErrorResponse{
+			Error:     "Unauthorized",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
 
-	var confirmCodeEmail domain.ConfirmCode
-	if err := c.Bind(&confirmCodeEmail); err != nil {
-		log.Warn("Failed to bind confirmCodeData data to domain")
+	var anonymizePayLoad domain.AnonymizePayLoad
+	if err := c.Bind(&anonymizePayLoad); err != nil {
+		log.Warn("Failed to bind anonymize data to domain")
 		return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
 			Error:     "Unprocessable Entity",
 			Message:   err.Error(),
@@ -675,8 +989,8 @@ func (uh *userHandler) ConfirmEmail(c echo.Context) error {
 		})
 	}
 
-	if err := confirmCodeEmail.Validate(); err != nil {
-		log.Warn("Invalid confirmCodeEmail data")
+	if err := anonymizePayLoad.Validate(); err != nil {
+		log.Warn("Invalid anonymize data")
 		return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
 			Error:     "Unprocessable Entity",
 			Message:   err.Error(),
@@ -685,20 +999,88 @@ func (uh *userHandler) ConfirmEmail(c echo.Context) error {
 		})
 	}
 
-	err := uh.userService.ConfirmEmail(confirmCodeEmail)
+	err = uh.userService.Anonymize(c.Request().Context(), idFromToken, anonymizePayLoad.Password, newClientInfo(c))
+	if err != nil && errors.Is(err, domain.ErrPasswordNotMatch) {
+		log.Warn("Invalid password")
+		return c.JSON(http.StatusUnauthorized, domain.ErrorResponse{
+			Error:     "Unauthorized",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
 
-	if err != nil && errors.Is(err, domain.ErrInvalidOTP) {
-		log.Warn("Expired token or wrong token")
+	if err != nil && errors.Is(err, domain.ErrUserNotFound) {
+		log.Warn("User not found to anonymize")
+		return c.JSON(http.StatusNotFound, domain.ErrorResponse{
+			Error:     "Not Found",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err != nil {
+		log.Error("Error trying to call anonymize service.")
+		return c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
+			Error:     "Internal Server Error",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	log.Info("User successfully anonymized")
+	return c.NoContent(http.StatusNoContent)
+}
+
+// delete schedules the deletion of the user with id, for Delete and DeleteMe.
+func (uh *userHandler) delete(c echo.Context, log *slog.Logger, id string) error {
+	var deleteUserPayLoad domain.DeleteUserPayLoad
+	if err := c.Bind(&deleteUserPayLoad); err != nil {
+		log.Warn("Failed to bind delete data to domain")
+		return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
+			Error:     "Unprocessable Entity",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err := deleteUserPayLoad.Validate(); err != nil {
+		log.Warn("Invalid delete data")
+		return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
+			Error:     "Unprocessable Entity",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	deletionScheduled, err := uh.userService.Delete(c.Request().Context(), id, deleteUserPayLoad.Password, newClientInfo(c))
+
+	if err != nil && errors.Is(err, domain.ErrPasswordNotMatch) {
+		log.Warn("Invalid password")
 		return c.JSON(http.StatusUnauthorized, domain.ErrorResponse{
 			Error:     "Unauthorized",
-			Message:   "Expired token or wrong token",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err != nil && errors.Is(err, domain.ErrUserNotFound) {
+		log.Warn("User not found to delete")
+		return c.JSON(http.StatusNotFound, domain.ErrorResponse{
+			Error:     "Not Found",
+			Message:   err.Error(),
 			TimeStamp: time.Now(),
 			Path:      c.Path(),
 		})
 	}
 
 	if err != nil {
-		log.Error("Error trying to confirm email:", err)
+		log.Error("Error trying to call delete service.")
 		return c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
 			Error:     "Internal Server Error",
 			Message:   err.Error(),
@@ -707,6 +1089,2019 @@ func (uh *userHandler) ConfirmEmail(c echo.Context) error {
 		})
 	}
 
-	log.Info("Email confirmed successfully")
-	return c.NoContent(http.StatusOK)
+	log.Info("User deletion successfully scheduled")
+
+	return c.JSON(http.StatusAccepted, deletionScheduled)
+}
+
+// Login godoc
+// @Summary Login a user
+// @Description Authenticate user, by email or username, and return JWT token
+// @Tags authentication
+// @Accept json
+// @Produce json
+// @Param login body domain.Login true "Login Payload"
+// @Success 200 {object} domain.LoginResponse "Session, a domain.TwoFactorChallengeResponse when two-factor authentication is on or a domain.PasswordResetRequiredResponse when a password reset is required"
+// @Failure 401 {object} domain.ErrorResponse
+// @Failure 403 {object} domain.AccountDeactivatedResponse "Reactivation offer, a domain.AccountSuspendedResponse when the account is suspended or a domain.EmailNotConfirmedResponse when the email must be confirmed first"
+// @Failure 409 {object} domain.ErrorResponse
+// @Failure 422 {object} domain.ErrorResponse
+// @Failure 423 {object} domain.ErrorResponse
+// @Failure 429 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /v1/auth/login [post]
+func (uh *userHandler) Login(c echo.Context) error {
+	log := slog.With(
+		slog.String("func", "Login"),
+		slog.String("handler", "authentication"))
+
+	log.Info("Login service initiated")
+
+	var login domain.Login
+	if err := c.Bind(&login); err != nil {
+		log.Warn("Failed to bind user data to domain")
+		return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
+			Error:     "Unprocessable Entity",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err := login.Validate(); err != nil {
+		log.Warn("Invalid login data")
+		return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
+			Error:     "Unprocessable Entity",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if login.DeviceToken == "" {
+		login.DeviceToken = trustedDeviceTokenFromCookie(c)
+	}
+
+	loginResult, err := uh.userService.Login(c.Request().Context(), login, newClientInfo(c))
+	if err != nil && (errors.Is(err, domain.ErrPasswordNotMatch) || errors.Is(err, domain.ErrUserNotFound)) {
+		log.Warn("Invalid username or password", slog.Any("error", err))
+		return c.JSON(http.StatusUnauthorized, domain.ErrorResponse{
+			Error:     "Unauthorized",
+			Message:   "Invalid username or password",
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err != nil && errors.Is(err, domain.ErrAccountLocked) {
+		log.Warn("Login to a locked account", slog.Any("error", err))
+		setRetryAfter(c, err)
+		return c.JSON(http.StatusLocked, domain.ErrorResponse{
+			Error:     "Locked",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err != nil && errors.Is(err, domain.ErrLoginDelayed) {
+		log.Warn("Login attempted before the login delay ended")
+		setRetryAfter(c, err)
+		return c.JSON(http.StatusTooManyRequests, domain.ErrorResponse{
+			Error:     "Too Many Requests",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err != nil && errors.Is(err, domain.ErrTooManySessions) {
+		log.Warn("Session limit reached")
+		return c.JSON(http.StatusConflict, domain.ErrorResponse{
+			Error:     "Conflict",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err != nil && errors.Is(err, domain.ErrAccountSuspended) {
+		log.Warn("Login to a suspended account")
+		return accountSuspended(c, err)
+	}
+
+	if err != nil && errors.Is(err, domain.ErrEmailNotConfirmed) {
+		log.Warn("Login to an account with the email not confirmed")
+		return c.JSON(http.StatusForbidden, domain.EmailNotConfirmedResponse{
+			Error:     "Forbidden",
+			Message:   err.Error(),
+			Code:      domain.EmailNotConfirmedCode,
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err != nil {
+		log.Error("Error trying to call login service.")
+		return c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
+			Error:     "Internal Server Error",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if loginResult.Challenge != nil {
+		log.Info("Login waiting for second factor")
+		return c.JSON(http.StatusOK, loginResult.Challenge)
+	}
+
+	if loginResult.Deactivated != nil {
+		log.Info("Login waiting for reactivation")
+		return c.JSON(http.StatusForbidden, loginResult.Deactivated)
+	}
+
+	if loginResult.PasswordReset != nil {
+		log.Info("Login waiting for password reset")
+		return c.JSON(http.StatusOK, loginResult.PasswordReset)
+	}
+
+	loginResponse := loginResult.LoginResponse
+	if config.SessionCookie.Enabled {
+		if err := setSessionCookies(c, loginResponse); err != nil {
+			log.Error("Error trying to set session cookies", slog.Any("error", err))
+			return c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
+				Error:     "Internal Server Error",
+				Message:   err.Error(),
+				TimeStamp: time.Now(),
+				Path:      c.Path(),
+			})
+		}
+	}
+
+	log.Info("Login executed successfully")
+	return c.JSON(http.StatusOK, loginResponse)
+}
+
+// Refresh godoc
+// @Summary Refresh access token
+// @Description Exchange a valid refresh token for a new access token. In session cookie mode the token is read from the cookie and X-CSRF-Token is required
+// @Tags authentication
+// @Accept json
+// @Produce json
+// @Param refreshToken body domain.RefreshTokenPayLoad true "Refresh Token Payload"
+// @Success 200 {object} domain.LoginResponse
+// @Failure 401 {object} domain.ErrorResponse
+// @Failure 403 {object} domain.AccountSuspendedResponse
+// @Failure 422 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /v1/auth/refresh [post]
+func (uh *userHandler) Refresh(c echo.Context) error {
+	log := slog.With(
+		slog.String("func", "Refresh"),
+		slog.String("handler", "authentication"))
+
+	log.Info("Refresh service initiated")
+
+	var refreshTokenPayLoad domain.RefreshTokenPayLoad
+	if err := c.Bind(&refreshTokenPayLoad); err != nil {
+		log.Warn("Failed to bind refresh token data to domain")
+		return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
+			Error:     "Unprocessable Entity",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if config.SessionCookie.Enabled {
+		refreshTokenPayLoad.RefreshToken = refreshTokenFromCookie(c)
+	}
+
+	if err := refreshTokenPayLoad.Validate(); err != nil {
+		log.Warn("Invalid refresh token data")
+		return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
+			Error:     "Unprocessable Entity",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	loginResponse, err := uh.userService.Refresh(c.Request().Context(), refreshTokenPayLoad.RefreshToken, newClientInfo(c))
+	if err != nil && config.SessionCookie.Enabled && (errors.Is(err, domain.ErrRefreshTokenReused) || errors.Is(err, domain.ErrInvalidToken) || errors.Is(err, domain.ErrTokenBinding)) {
+		clearSessionCookies(c)
+	}
+
+	if err != nil && errors.Is(err, domain.ErrRefreshTokenReused) {
+		log.Warn("Refresh token reuse detected")
+		return c.JSON(http.StatusUnauthorized, domain.ErrorResponse{
+			Error:     "Unauthorized",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err != nil && errors.Is(err, domain.ErrTokenBinding) {
+		log.Warn("Refresh token bound to another client")
+		return c.JSON(http.StatusUnauthorized, domain.ErrorResponse{
+			Error:     "Unauthorized",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err != nil && errors.Is(err, domain.ErrInvalidToken) {
+		log.Warn("Invalid, expired or revoked refresh token")
+		return c.JSON(http.StatusUnauthorized, domain.ErrorResponse{
+			Error:     "Unauthorized",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err != nil && errors.Is(err, domain.ErrAccountSuspended) {
+		log.Warn("Refresh of a suspended account")
+		return accountSuspended(c, err)
+	}
+
+	if err != nil {
+		log.Error("Error trying to call refresh service.")
+		return c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
+			Error:     "Internal Server Error",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if config.SessionCookie.Enabled {
+		if err := setSessionCookies(c, loginResponse); err != nil {
+			log.Error("Error trying to set session cookies", slog.Any("error", err))
+			return c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
+				Error:     "Internal Server Error",
+				Message:   err.Error(),
+				TimeStamp: time.Now(),
+				Path:      c.Path(),
+			})
+		}
+	}
+
+	log.Info("Refresh executed successfully")
+	return c.JSON(http.StatusOK, loginResponse)
+}
+
+// SwitchOrganization godoc
+// @Summary Switch the organization of the session
+// @Description Pick the organization, among the ones the user is a member of, that the access tokens of the current session speak for through the org and org_role claims. An empty organization_id leaves every organization. Refreshed access tokens keep it while the user stays a member
+// @Tags organizations
+// @Accept json
+// @Produce json
+// @Param organization body domain.OrganizationSwitchPayLoad true "Organization"
+// @Success 200 {object} domain.AccessTokenResponse
+// @Failure 401 {object} domain.ErrorResponse
+// @Failure 404 {object} domain.ErrorResponse
+// @Failure 422 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /v1/users/me/organization [post]
+// @Security bearerToken
+func (uh *userHandler) SwitchOrganization(c echo.Context) error {
+	log := slog.With(
+		slog.String("func", "SwitchOrganization"),
+		slog.String("handler", "user"))
+
+	claims, err := util.ExtractTokenClaims(c)
+	if err != nil {
+		log.Warn("Error getting claims from token")
+		return c.JSON(http.StatusUnauthorized, domain.ErrorResponse{
+			Error:     "Unauthorized",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	var switchPayLoad domain.OrganizationSwitchPayLoad
+	if err := c.Bind(&switchPayLoad); err != nil {
+		log.Warn("Failed to bind organization data to domain")
+		return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
+			Error:     "Unprocessable Entity",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err := switchPayLoad.Validate(); err != nil {
+		log.Warn("Invalid organization data")
+		return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
+			Error:     "Unprocessable Entity",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	accessTokenResponse, err := uh.userService.SwitchOrganization(c.Request().Context(), *claims, switchPayLoad.OrganizationID)
+	if err != nil && (errors.Is(err, domain.ErrOrganizationNotFound) || errors.Is(err, domain.ErrUserNotFound)) {
+		log.Warn("Organization not found", slog.Any("error", err))
+		return c.JSON(http.StatusNotFound, domain.ErrorResponse{
+			Error:     "Not Found",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err != nil {
+		log.Error("Error trying to call switch organization service.")
+		return c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
+			Error:     "Internal Server Error",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	log.Info("Organization switched")
+	return c.JSON(http.StatusOK, accessTokenResponse)
+}
+
+// Logout godoc
+// @Summary Logout a user
+// @Description Revoke the current access token and its refresh token. In session cookie mode the token is read from the cookie, which is cleared, and X-CSRF-Token is required
+// @Tags authentication
+// @Accept json
+// @Param logout body domain.LogoutPayLoad true "Logout Payload"
+// @Success 204
+// @Failure 401 {object} domain.ErrorResponse
+// @Failure 422 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /v1/auth/logout [post]
+// @Security bearerToken
+func (uh *userHandler) Logout(c echo.Context) error {
+	log := slog.With(
+		slog.String("func", "Logout"),
+		slog.String("handler", "authentication"))
+
+	log.Info("Logout service initiated")
+
+	claims, err := util.ExtractTokenClaims(c)
+	if err != nil {
+		log.Warn("Error getting claims from token")
+		return c.JSON(http.StatusUnauthorized, domain.ErrorResponse{
+			Error:     "Unauthorized",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	var logoutPayLoad domain.LogoutPayLoad
+	if err := c.Bind(&logoutPayLoad); err != nil {
+		log.Warn("Failed to bind logout data to domain")
+		return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
+			Error:     "Unprocessable Entity",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if config.SessionCookie.Enabled {
+		logoutPayLoad.RefreshToken = refreshTokenFromCookie(c)
+	}
+
+	if err := logoutPayLoad.Validate(); err != nil {
+		log.Warn("Invalid logout data")
+		return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
+			Error:     "Unprocessable Entity",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	err = uh.userService.Logout(c.Request().Context(), *claims, logoutPayLoad.RefreshToken)
+	if err != nil && errors.Is(err, domain.ErrInvalidToken) {
+		log.Warn("Token already revoked or invalid")
+		return c.JSON(http.StatusUnauthorized, domain.ErrorResponse{
+			Error:     "Unauthorized",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err != nil {
+		log.Error("Error trying to call logout service.")
+		return c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
+			Error:     "Internal Server Error",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if config.SessionCookie.Enabled {
+		clearSessionCookies(c)
+	}
+
+	log.Info("Logout executed successfully")
+	return c.NoContent(http.StatusNoContent)
+}
+
+// LogoutAll godoc
+// @Summary Logout from all devices
+// @Description Revoke every refresh token and access token of the authenticated user
+// @Tags authentication
+// @Accept json
+// @Param logoutAll body domain.LogoutAllPayLoad true "Logout All Payload"
+// @Success 204
+// @Failure 401 {object} domain.ErrorResponse
+// @Failure 404 {object} domain.ErrorResponse
+// @Failure 422 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /v1/users/me/logout-all [post]
+// @Security bearerToken
+func (uh *userHandler) LogoutAll(c echo.Context) error {
+	log := slog.With(
+		slog.String("func", "LogoutAll"),
+		slog.String("handler", "authentication"))
+
+	log.Info("LogoutAll service initiated")
+
+	idFromToken, err := util.ExtractUserIdFromToken(c)
+	if err != nil {
+		log.Warn("Error getting user ID from token")
+		return c.JSON(http.StatusUnauthorized, domain.ErrorResponse{
+			Error:     "Unauthorized",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	var logoutAllPayLoad domain.LogoutAllPayLoad
+	if err := c.Bind(&logoutAllPayLoad); err != nil {
+		log.Warn("Failed to bind logout all data to domain")
+		return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
+			Error:     "Unprocessable Entity",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err := logoutAllPayLoad.Validate(); err != nil {
+		log.Warn("Invalid logout all data")
+		return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
+			Error:     "Unprocessable Entity",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	err = uh.userService.LogoutAll(c.Request().Context(), idFromToken, logoutAllPayLoad.Password)
+	if err != nil && errors.Is(err, domain.ErrPasswordNotMatch) {
+		log.Warn("Invalid password")
+		return c.JSON(http.StatusUnauthorized, domain.ErrorResponse{
+			Error:     "Unauthorized",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err != nil && errors.Is(err, domain.ErrUserNotFound) {
+		log.Warn("User not found to logout")
+		return c.JSON(http.StatusNotFound, domain.ErrorResponse{
+			Error:     "Not Found",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err != nil {
+		log.Error("Error trying to call logout all service.")
+		return c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
+			Error:     "Internal Server Error",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	log.Info("LogoutAll executed successfully")
+	return c.NoContent(http.StatusNoContent)
+}
+
+// Deactivate godoc
+// @Summary Deactivate the account
+// @Description Disable the account of the authenticated user, confirmed with the current password. Its sessions end and it leaves the user search; the next login offers to reactivate it
+// @Tags users
+// @Accept json
+// @Param deactivate body domain.DeactivatePayLoad true "Deactivate Payload"
+// @Success 204
+// @Failure 401 {object} domain.ErrorResponse
+// @Failure 404 {object} domain.ErrorResponse
+// @Failure 422 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /v1/users/me/deactivate [post]
+// @Security bearerToken
+func (uh *userHandler) Deactivate(c echo.Context) error {
+	log := slog.With(
+		slog.String("func", "Deactivate"),
+		slog.String("handler", "user"))
+
+	idFromToken, err := util.ExtractUserIdFromToken(c)
+	if err != nil {
+		log.Warn("Error getting user ID from token")
+		return c.JSON(http.StatusUnauthorized, domain.ErrorResponse{
+			Error:     "Unauthorized",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	var deactivatePayLoad domain.DeactivatePayLoad
+	if err := c.Bind(&deactivatePayLoad); err != nil {
+		log.Warn("Failed to bind deactivate data to domain")
+		return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
+			Error:     "Unprocessable Entity",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err := deactivatePayLoad.Validate(); err != nil {
+		log.Warn("Invalid deactivate data")
+		return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
+			Error:     "Unprocessable Entity",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	err = uh.userService.Deactivate(c.Request().Context(), idFromToken, deactivatePayLoad.Password, newClientInfo(c))
+	if err != nil && errors.Is(err, domain.ErrPasswordNotMatch) {
+		log.Warn("Invalid password")
+		return c.JSON(http.StatusUnauthorized, domain.ErrorResponse{
+			Error:     "Unauthorized",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err != nil && errors.Is(err, domain.ErrUserNotFound) {
+		log.Warn("User not found to deactivate")
+		return c.JSON(http.StatusNotFound, domain.ErrorResponse{
+			Error:     "Not Found",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err != nil {
+		log.Error("Error trying to call deactivate service.")
+		return c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
+			Error:     "Internal Server Error",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	log.Info("User deactivated successfully")
+	return c.NoContent(http.StatusNoContent)
+}
+
+// ConfirmEmail godoc
+// @Summary Confirm user's email
+// @Description Confirm a user's email with the confirmation code. For a pending email change, the code sent to the new address switches the account to it
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param confirmCode body domain.ConfirmCode true "Confirmation Code Payload"
+// @Success 200
+// @Failure 422 {object} domain.ErrorResponse
+// @Failure 401
+// @Failure 409 {object} domain.ErrorResponse
+// @Failure 429 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /v1/users/email/confirm [post]
+// @Security bearerToken
+func (uh *userHandler) ConfirmEmail(c echo.Context) error {
+	log := slog.With(
+		slog.String("func", "ConfirmEmail"),
+		slog.String("handler", "authentication"))
+
+	log.Info("ConfirmEmail service initiated")
+
+	var confirmCodeEmail domain.ConfirmCode
+	if err := c.Bind(&confirmCodeEmail); err != nil {
+		log.Warn("Failed to bind confirmCodeData data to domain")
+		return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
+			Error:     "Unprocessable Entity",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err := confirmCodeEmail.Validate(); err != nil {
+		log.Warn("Invalid confirmCodeEmail data")
+		return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
+			Error:     "Unprocessable Entity",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	err := uh.userService.ConfirmEmail(c.Request().Context(), confirmCodeEmail, newClientInfo(c))
+
+	if err != nil && errors.Is(err, domain.ErrTooManyOTPAttempts) {
+		log.Warn("Too many wrong codes")
+		return c.JSON(http.StatusTooManyRequests, domain.ErrorResponse{
+			Error:     "Too Many Requests",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err != nil && (errors.Is(err, domain.ErrInvalidOTP) || errors.Is(err, domain.ErrOTPNotFound)) {
+		log.Warn("Expired token or wrong token")
+		return c.JSON(http.StatusUnauthorized, domain.ErrorResponse{
+			Error:     "Unauthorized",
+			Message:   "Expired token or wrong token",
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err != nil && errors.Is(err, domain.ErrUserAlreadyRegistered) {
+		log.Warn("New email registered by another account meanwhile")
+		return c.JSON(http.StatusConflict, domain.ErrorResponse{
+			Error:     "Conflict",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err != nil {
+		log.Error("Error trying to confirm email:", slog.Any("error", err))
+		return c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
+			Error:     "Internal Server Error",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	log.Info("Email confirmed successfully")
+	return c.NoContent(http.StatusOK)
+}
+
+// ConfirmEmailByLink godoc
+// @Summary Confirm user's email with a link
+// @Description Confirm a user's email with the single-use token of the link sent along with the confirmation code
+// @Tags users
+// @Produce json
+// @Param token query string true "Token of the confirmation link"
+// @Success 204
+// @Failure 401 {object} domain.ErrorResponse
+// @Failure 422 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /v1/users/confirm-email [get]
+func (uh *userHandler) ConfirmEmailByLink(c echo.Context) error {
+	log := slog.With(
+		slog.String("func", "ConfirmEmailByLink"),
+		slog.String("handler", "user"))
+
+	log.Info("ConfirmEmailByLink service initiated")
+
+	token := c.QueryParam("token")
+	if token == "" {
+		log.Warn("Missing confirmation link token")
+		return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
+			Error:     "Unprocessable Entity",
+			Message:   "token is required",
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	err := uh.userService.ConfirmEmailByLink(c.Request().Context(), token)
+	if err != nil && errors.Is(err, domain.ErrInvalidConfirmationLink) {
+		log.Warn("Confirmation link refused")
+		return c.JSON(http.StatusUnauthorized, domain.ErrorResponse{
+			Error:     "Unauthorized",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err != nil {
+		log.Error("Error trying to confirm email:", slog.Any("error", err))
+		return c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
+			Error:     "Internal Server Error",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	log.Info("Email confirmed successfully")
+	return c.NoContent(http.StatusNoContent)
+}
+
+// RevertEmailChange godoc
+// @Summary Undo an email change
+// @Description Follow the single-use link sent to the old address when an email change is requested, valid for 72 hours. A change still waiting for its code is cancelled; a confirmed one is undone and every session of the account ends
+// @Tags users
+// @Produce json
+// @Param token query string true "Token of the revert link"
+// @Success 204
+// @Failure 401 {object} domain.ErrorResponse
+// @Failure 409 {object} domain.ErrorResponse
+// @Failure 422 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /v1/users/email/revert [get]
+func (uh *userHandler) RevertEmailChange(c echo.Context) error {
+	log := slog.With(
+		slog.String("func", "RevertEmailChange"),
+		slog.String("handler", "user"))
+
+	log.Info("RevertEmailChange service initiated")
+
+	token := c.QueryParam("token")
+	if token == "" {
+		log.Warn("Missing revert link token")
+		return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
+			Error:     "Unprocessable Entity",
+			Message:   "token is required",
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	err := uh.userService.RevertEmailChange(c.Request().Context(), token, newClientInfo(c))
+	if err != nil && errors.Is(err, domain.ErrInvalidRevertLink) {
+		log.Warn("Revert link refused")
+		return c.JSON(http.StatusUnauthorized, domain.ErrorResponse{
+			Error:     "Unauthorized",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err != nil && errors.Is(err, domain.ErrOldEmailUnavailable) {
+		log.Warn("Previous email taken by another account")
+		return c.JSON(http.StatusConflict, domain.ErrorResponse{
+			Error:     "Conflict",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err != nil {
+		log.Error("Error trying to revert email change:", slog.Any("error", err))
+		return c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
+			Error:     "Internal Server Error",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	log.Info("Email change reverted successfully")
+	return c.NoContent(http.StatusNoContent)
+}
+
+// ResendConfirmation godoc
+// @Summary Resend the email confirmation code
+// @Description Send a new confirmation code, replacing the previous one, when the email belongs to an account not confirmed yet. The answer is always the same
+// @Tags users
+// @Accept json
+// @Param resendConfirmationPayLoad body domain.ResendConfirmationPayLoad true "Email of the account"
+// @Success 202
+// @Failure 422 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /v1/users/confirmation/resend [post]
+func (uh *userHandler) ResendConfirmation(c echo.Context) error {
+	log := slog.With(
+		slog.String("func", "ResendConfirmation"),
+		slog.String("handler", "user"))
+
+	log.Info("ResendConfirmation service initiated")
+
+	var resendConfirmationPayLoad domain.ResendConfirmationPayLoad
+	if err := c.Bind(&resendConfirmationPayLoad); err != nil {
+		log.Warn("Failed to bind resend confirmation data to domain")
+		return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
+			Error:     "Unprocessable Entity",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err := resendConfirmationPayLoad.Validate(); err != nil {
+		log.Warn("Invalid resend confirmation data")
+		return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
+			Error:     "Unprocessable Entity",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err := uh.userService.ResendConfirmation(c.Request().Context(), util.ExtractTenant(c), resendConfirmationPayLoad.Email); err != nil {
+		log.Error("Error trying to call resend confirmation service.")
+		return c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
+			Error:     "Internal Server Error",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	log.Info("Confirmation resend requested")
+	return c.NoContent(http.StatusAccepted)
+}
+
+// EnableTOTP godoc
+// @Summary Enroll an authenticator app
+// @Description Generate a new TOTP secret for the authenticated user. The secret stays pending, and is replaced by a new enrollment, until a code generated from it is confirmed
+// @Tags two-factor
+// @Produce json
+// @Success 200 {object} domain.TOTPEnrollmentResponse
+// @Failure 401 {object} domain.ErrorResponse
+// @Failure 404 {object} domain.ErrorResponse
+// @Failure 409 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /v1/users/me/2fa/totp [post]
+// @Security bearerToken
+func (uh *userHandler) EnableTOTP(c echo.Context) error {
+	log := slog.With(
+		slog.String("func", "EnableTOTP"),
+		slog.String("handler", "user"))
+
+	log.Info("EnableTOTP service initiated")
+
+	idFromToken, err := util.ExtractUserIdFromToken(c)
+	if err != nil {
+		log.Warn("Error getting user ID from token")
+		return c.JSON(http.StatusUnauthorized, domain.ErrorResponse{
+			Error:     "Unauthorized",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	response, err := uh.userService.EnableTOTP(c.Request().Context(), idFromToken)
+	if err != nil && errors.Is(err, domain.ErrUserNotFound) {
+		log.Warn("User not found to enable totp")
+		return c.JSON(http.StatusNotFound, domain.ErrorResponse{
+			Error:     "Not Found",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err != nil && errors.Is(err, domain.ErrTOTPAlreadyEnabled) {
+		log.Warn("Two-factor authentication already enabled")
+		return c.JSON(http.StatusConflict, domain.ErrorResponse{
+			Error:     "Conflict",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err != nil {
+		log.Error("Error trying to call enable totp service.")
+		return c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
+			Error:     "Internal Server Error",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	log.Info("EnableTOTP executed successfully")
+	return c.JSON(http.StatusOK, response)
+}
+
+// ConfirmTOTP godoc
+// @Summary Confirm authenticator app enrollment
+// @Description Check a code from the authenticator app against the pending secret and turn on two-factor authentication
+// @Tags two-factor
+// @Accept json
+// @Param confirmTOTP body domain.TOTPCodePayLoad true "Authenticator code"
+// @Success 204
+// @Failure 401 {object} domain.ErrorResponse
+// @Failure 404 {object} domain.ErrorResponse
+// @Failure 409 {object} domain.ErrorResponse
+// @Failure 422 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /v1/users/me/2fa/totp/confirm [post]
+// @Security bearerToken
+func (uh *userHandler) ConfirmTOTP(c echo.Context) error {
+	log := slog.With(
+		slog.String("func", "ConfirmTOTP"),
+		slog.String("handler", "user"))
+
+	log.Info("ConfirmTOTP service initiated")
+
+	idFromToken, err := util.ExtractUserIdFromToken(c)
+	if err != nil {
+		log.Warn("Error getting user ID from token")
+		return c.JSON(http.StatusUnauthorized, domain.ErrorResponse{
+			Error:     "Unauthorized",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	var totpCodePayLoad domain.TOTPCodePayLoad
+	if err := c.Bind(&totpCodePayLoad); err != nil {
+		log.Warn("Failed to bind totp code data to domain")
+		return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
+			Error:     "Unprocessable Entity",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err := totpCodePayLoad.Validate(); err != nil {
+		log.Warn("Invalid totp code data")
+		return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
+			Error:     "Unprocessable Entity",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	err = uh.userService.ConfirmTOTP(c.Request().Context(), idFromToken, totpCodePayLoad.Code, newClientInfo(c))
+	if err != nil && errors.Is(err, domain.ErrUserNotFound) {
+		log.Warn("User not found to confirm totp")
+		return c.JSON(http.StatusNotFound, domain.ErrorResponse{
+			Error:     "Not Found",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err != nil && (errors.Is(err, domain.ErrTOTPAlreadyEnabled) || errors.Is(err, domain.ErrTOTPNotEnrolled)) {
+		log.Warn("No pending totp enrollment to confirm")
+		return c.JSON(http.StatusConflict, domain.ErrorResponse{
+			Error:     "Conflict",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err != nil && errors.Is(err, domain.ErrInvalidTOTPCode) {
+		log.Warn("Invalid totp code")
+		return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
+			Error:     "Unprocessable Entity",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err != nil {
+		log.Error("Error trying to call confirm totp service.")
+		return c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
+			Error:     "Internal Server Error",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	log.Info("ConfirmTOTP executed successfully")
+	return c.NoContent(http.StatusNoContent)
+}
+
+// LoginTwoFactor godoc
+// @Summary Complete a two-step login
+// @Description Exchange the challenge token returned by the login, sent as a bearer token, and a code from the authenticator app, the email, an SMS or a recovery code for a session. The challenge token works once
+// @Tags authentication
+// @Accept json
+// @Produce json
+// @Param loginTwoFactor body domain.TwoFactorLoginPayLoad true "Second factor"
+// @Success 200 {object} domain.LoginResponse "Session, or a domain.PasswordResetRequiredResponse when a password reset is required"
+// @Failure 401 {object} domain.ErrorResponse
+// @Failure 403 {object} domain.AccountDeactivatedResponse "Reactivation offer, or a domain.AccountSuspendedResponse when the account is suspended"
+// @Failure 409 {object} domain.ErrorResponse
+// @Failure 422 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /v1/auth/login/2fa [post]
+// @Security bearerToken
+func (uh *userHandler) LoginTwoFactor(c echo.Context) error {
+	log := slog.With(
+		slog.String("func", "LoginTwoFactor"),
+		slog.String("handler", "authentication"))
+
+	log.Info("LoginTwoFactor service initiated")
+
+	claims, err := util.ExtractTokenClaims(c)
+	if err != nil {
+		log.Warn("Error getting claims from token")
+		return c.JSON(http.StatusUnauthorized, domain.ErrorResponse{
+			Error:     "Unauthorized",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	var twoFactorLoginPayLoad domain.TwoFactorLoginPayLoad
+	if err := c.Bind(&twoFactorLoginPayLoad); err != nil {
+		log.Warn("Failed to bind two-factor login data to domain")
+		return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
+			Error:     "Unprocessable Entity",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err := twoFactorLoginPayLoad.Validate(); err != nil {
+		log.Warn("Invalid two-factor login data")
+		return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
+			Error:     "Unprocessable Entity",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	loginResult, err := uh.userService.LoginTwoFactor(c.Request().Context(), *claims, twoFactorLoginPayLoad, newClientInfo(c))
+	if err != nil && (errors.Is(err, domain.ErrInvalidTwoFactor) || errors.Is(err, domain.ErrInvalidToken) || errors.Is(err, domain.ErrUserNotFound)) {
+		log.Warn("Second factor refused", slog.Any("error", err))
+		return c.JSON(http.StatusUnauthorized, domain.ErrorResponse{
+			Error:     "Unauthorized",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err != nil && errors.Is(err, domain.ErrTwoFactorMethod) {
+		log.Warn("Two-factor method not enabled")
+		return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
+			Error:     "Unprocessable Entity",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err != nil && errors.Is(err, domain.ErrTooManySessions) {
+		log.Warn("Session limit reached")
+		return c.JSON(http.StatusConflict, domain.ErrorResponse{
+			Error:     "Conflict",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err != nil && errors.Is(err, domain.ErrAccountSuspended) {
+		log.Warn("Login to a suspended account")
+		return accountSuspended(c, err)
+	}
+
+	if err != nil {
+		log.Error("Error trying to call two-factor login service.")
+		return c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
+			Error:     "Internal Server Error",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if loginResult.Deactivated != nil {
+		log.Info("Login waiting for reactivation")
+		return c.JSON(http.StatusForbidden, loginResult.Deactivated)
+	}
+
+	if loginResult.PasswordReset != nil {
+		log.Info("Login waiting for password reset")
+		return c.JSON(http.StatusOK, loginResult.PasswordReset)
+	}
+
+	loginResponse := loginResult.LoginResponse
+	if config.SessionCookie.Enabled {
+		setTrustedDeviceCookie(c, loginResponse)
+		if err := setSessionCookies(c, loginResponse); err != nil {
+			log.Error("Error trying to set session cookies", slog.Any("error", err))
+			return c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
+				Error:     "Internal Server Error",
+				Message:   err.Error(),
+				TimeStamp: time.Now(),
+				Path:      c.Path(),
+			})
+		}
+	}
+
+	log.Info("LoginTwoFactor executed successfully")
+	return c.JSON(http.StatusOK, loginResponse)
+}
+
+// Reactivate godoc
+// @Summary Reactivate a deactivated account
+// @Description Confirm the reactivation offered by a login to a deactivated account, with the reactivation token sent as a bearer token, and open a session. The reactivation token works once
+// @Tags authentication
+// @Produce json
+// @Success 200 {object} domain.LoginResponse "Session, or a domain.PasswordResetRequiredResponse when a password reset is required"
+// @Failure 401 {object} domain.ErrorResponse
+// @Failure 403 {object} domain.AccountSuspendedResponse
+// @Failure 409 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /v1/auth/reactivate [post]
+// @Security bearerToken
+func (uh *userHandler) Reactivate(c echo.Context) error {
+	log := slog.With(
+		slog.String("func", "Reactivate"),
+		slog.String("handler", "authentication"))
+
+	claims, err := util.ExtractTokenClaims(c)
+	if err != nil {
+		log.Warn("Error getting claims from token")
+		return c.JSON(http.StatusUnauthorized, domain.ErrorResponse{
+			Error:     "Unauthorized",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	loginResult, err := uh.userService.Reactivate(c.Request().Context(), *claims, newClientInfo(c))
+	if err != nil && (errors.Is(err, domain.ErrInvalidToken) || errors.Is(err, domain.ErrUserNotFound)) {
+		log.Warn("Reactivation refused", slog.Any("error", err))
+		return c.JSON(http.StatusUnauthorized, domain.ErrorResponse{
+			Error:     "Unauthorized",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err != nil && errors.Is(err, domain.ErrTooManySessions) {
+		log.Warn("Session limit reached")
+		return c.JSON(http.StatusConflict, domain.ErrorResponse{
+			Error:     "Conflict",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err != nil && errors.Is(err, domain.ErrAccountSuspended) {
+		log.Warn("Reactivation of a suspended account")
+		return accountSuspended(c, err)
+	}
+
+	if err != nil {
+		log.Error("Error trying to call reactivate service.")
+		return c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
+			Error:     "Internal Server Error",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if loginResult.PasswordReset != nil {
+		log.Info("Login waiting for password reset")
+		return c.JSON(http.StatusOK, loginResult.PasswordReset)
+	}
+
+	loginResponse := loginResult.LoginResponse
+	if config.SessionCookie.Enabled {
+		if err := setSessionCookies(c, loginResponse); err != nil {
+			log.Error("Error trying to set session cookies", slog.Any("error", err))
+			return c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
+				Error:     "Internal Server Error",
+				Message:   err.Error(),
+				TimeStamp: time.Now(),
+				Path:      c.Path(),
+			})
+		}
+	}
+
+	log.Info("User reactivated successfully")
+	return c.JSON(http.StatusOK, loginResponse)
+}
+
+// SendTwoFactorCode godoc
+// @Summary Email a login verification code
+// @Description Send a one time code to the account email, to complete a two-step login with the email method
+// @Tags authentication
+// @Success 204
+// @Failure 401 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /v1/auth/login/2fa/email [post]
+// @Security bearerToken
+func (uh *userHandler) SendTwoFactorCode(c echo.Context) error {
+	log := slog.With(
+		slog.String("func", "SendTwoFactorCode"),
+		slog.String("handler", "authentication"))
+
+	log.Info("SendTwoFactorCode service initiated")
+
+	idFromToken, err := util.ExtractUserIdFromToken(c)
+	if err != nil {
+		log.Warn("Error getting user ID from token")
+		return c.JSON(http.StatusUnauthorized, domain.ErrorResponse{
+			Error:     "Unauthorized",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	err = uh.userService.SendTwoFactorCode(c.Request().Context(), idFromToken)
+	if err != nil && errors.Is(err, domain.ErrUserNotFound) {
+		log.Warn("User not found to send two-factor code")
+		return c.JSON(http.StatusUnauthorized, domain.ErrorResponse{
+			Error:     "Unauthorized",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err != nil {
+		log.Error("Error trying to call send two-factor code service.")
+		return c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
+			Error:     "Internal Server Error",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	log.Info("SendTwoFactorCode executed successfully")
+	return c.NoContent(http.StatusNoContent)
+}
+
+// GetRecoveryCodeCount godoc
+// @Summary Count recovery codes
+// @Description Return how many unused recovery codes the authenticated user has left
+// @Tags two-factor
+// @Produce json
+// @Success 200 {object} domain.RecoveryCodeCountResponse
+// @Failure 401 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /v1/users/me/2fa/recovery-codes [get]
+// @Security bearerToken
+func (uh *userHandler) GetRecoveryCodeCount(c echo.Context) error {
+	log := slog.With(
+		slog.String("func", "GetRecoveryCodeCount"),
+		slog.String("handler", "user"))
+
+	log.Info("GetRecoveryCodeCount service initiated")
+
+	idFromToken, err := util.ExtractUserIdFromToken(c)
+	if err != nil {
+		log.Warn("Error getting user ID from token")
+		return c.JSON(http.StatusUnauthorized, domain.ErrorResponse{
+			Error:     "Unauthorized",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	response, err := uh.userService.GetRecoveryCodeCount(c.Request().Context(), idFromToken)
+	if err != nil {
+		log.Error("Error trying to call get recovery code count service.")
+		return c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
+			Error:     "Internal Server Error",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	log.Info("GetRecoveryCodeCount executed successfully")
+	return c.JSON(http.StatusOK, response)
+}
+
+// RegenerateRecoveryCodes godoc
+// @Summary Regenerate recovery codes
+// @Description Replace every recovery code of the authenticated user with a new set, after checking the password. The old codes stop working
+// @Tags two-factor
+// @Accept json
+// @Produce json
+// @Param regenerateRecoveryCodes body domain.RegenerateRecoveryCodesPayLoad true "Current password"
+// @Success 200 {object} domain.RecoveryCodesResponse
+// @Failure 401 {object} domain.ErrorResponse
+// @Failure 404 {object} domain.ErrorResponse
+// @Failure 409 {object} domain.ErrorResponse
+// @Failure 422 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /v1/users/me/2fa/recovery-codes [post]
+// @Security bearerToken
+func (uh *userHandler) RegenerateRecoveryCodes(c echo.Context) error {
+	log := slog.With(
+		slog.String("func", "RegenerateRecoveryCodes"),
+		slog.String("handler", "user"))
+
+	log.Info("RegenerateRecoveryCodes service initiated")
+
+	idFromToken, err := util.ExtractUserIdFromToken(c)
+	if err != nil {
+		log.Warn("Error getting user ID from token")
+		return c.JSON(http.StatusUnauthorized, domain.ErrorResponse{
+			Error:     "Unauthorized",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	var regenerateRecoveryCodesPayLoad domain.RegenerateRecoveryCodesPayLoad
+	if err := c.Bind(&regenerateRecoveryCodesPayLoad); err != nil {
+		log.Warn("Failed to bind regenerate recovery codes data to domain")
+		return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
+			Error:     "Unprocessable Entity",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err := regenerateRecoveryCodesPayLoad.Validate(); err != nil {
+		log.Warn("Invalid regenerate recovery codes data")
+		return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
+			Error:     "Unprocessable Entity",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	response, err := uh.userService.RegenerateRecoveryCodes(c.Request().Context(), idFromToken, regenerateRecoveryCodesPayLoad.Password, newClientInfo(c))
+	if err != nil && errors.Is(err, domain.ErrPasswordNotMatch) {
+		log.Warn("Invalid password")
+		return c.JSON(http.StatusUnauthorized, domain.ErrorResponse{
+			Error:     "Unauthorized",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err != nil && errors.Is(err, domain.ErrUserNotFound) {
+		log.Warn("User not found to regenerate recovery codes")
+		return c.JSON(http.StatusNotFound, domain.ErrorResponse{
+			Error:     "Not Found",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err != nil && errors.Is(err, domain.ErrTwoFactorNotEnabled) {
+		log.Warn("Two-factor authentication not enabled")
+		return c.JSON(http.StatusConflict, domain.ErrorResponse{
+			Error:     "Conflict",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err != nil {
+		log.Error("Error trying to call regenerate recovery codes service.")
+		return c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
+			Error:     "Internal Server Error",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	log.Info("RegenerateRecoveryCodes executed successfully")
+	return c.JSON(http.StatusOK, response)
+}
+
+// DisableTwoFactor godoc
+// @Summary Disable two-factor authentication
+// @Description Turn two-factor authentication off after checking the password and a code from the authenticator app or a recovery code. The authenticator secret and the recovery codes are deleted
+// @Tags two-factor
+// @Accept json
+// @Param disableTwoFactor body domain.DisableTwoFactorPayLoad true "Password and second factor"
+// @Success 204
+// @Failure 401 {object} domain.ErrorResponse
+// @Failure 404 {object} domain.ErrorResponse
+// @Failure 409 {object} domain.ErrorResponse
+// @Failure 422 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /v1/users/me/2fa/disable [post]
+// @Security bearerToken
+func (uh *userHandler) DisableTwoFactor(c echo.Context) error {
+	log := slog.With(
+		slog.String("func", "DisableTwoFactor"),
+		slog.String("handler", "user"))
+
+	log.Info("DisableTwoFactor service initiated")
+
+	idFromToken, err := util.ExtractUserIdFromToken(c)
+	if err != nil {
+		log.Warn("Error getting user ID from token")
+		return c.JSON(http.StatusUnauthorized, domain.ErrorResponse{
+			Error:     "Unauthorized",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	var disableTwoFactorPayLoad domain.DisableTwoFactorPayLoad
+	if err := c.Bind(&disableTwoFactorPayLoad); err != nil {
+		log.Warn("Failed to bind disable two-factor data to domain")
+		return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
+			Error:     "Unprocessable Entity",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err := disableTwoFactorPayLoad.Validate(); err != nil {
+		log.Warn("Invalid disable two-factor data")
+		return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
+			Error:     "Unprocessable Entity",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	err = uh.userService.DisableTwoFactor(c.Request().Context(), idFromToken, disableTwoFactorPayLoad, newClientInfo(c))
+	if err != nil && (errors.Is(err, domain.ErrPasswordNotMatch) || errors.Is(err, domain.ErrInvalidTwoFactor)) {
+		log.Warn("Invalid password or second factor", slog.Any("error", err))
+		return c.JSON(http.StatusUnauthorized, domain.ErrorResponse{
+			Error:     "Unauthorized",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err != nil && errors.Is(err, domain.ErrUserNotFound) {
+		log.Warn("User not found to disable two-factor")
+		return c.JSON(http.StatusNotFound, domain.ErrorResponse{
+			Error:     "Not Found",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err != nil && errors.Is(err, domain.ErrTwoFactorNotEnabled) {
+		log.Warn("Two-factor authentication not enabled")
+		return c.JSON(http.StatusConflict, domain.ErrorResponse{
+			Error:     "Conflict",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err != nil && errors.Is(err, domain.ErrTwoFactorMethod) {
+		log.Warn("Two-factor method not enabled")
+		return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
+			Error:     "Unprocessable Entity",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err != nil {
+		log.Error("Error trying to call disable two-factor service.")
+		return c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
+			Error:     "Internal Server Error",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	log.Info("DisableTwoFactor executed successfully")
+	return c.NoContent(http.StatusNoContent)
+}
+
+// AdminDisableTwoFactor godoc
+// @Summary Disable two-factor authentication of a user
+// @Description Support override for users who lost every second factor. The user is notified by email
+// @Tags admin
+// @Param id path string true "User ID"
+// @Success 204
+// @Failure 400 {object} domain.ErrorResponse
+// @Failure 401
+// @Failure 404 {object} domain.ErrorResponse
+// @Failure 409 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /v1/admin/users/{id}/2fa [delete]
+func (uh *userHandler) AdminDisableTwoFactor(c echo.Context) error {
+	log := slog.With(
+		slog.String("func", "AdminDisableTwoFactor"),
+		slog.String("handler", "user"))
+
+	id := c.Param("id")
+	if err := util.IsValidUUID(id); err != nil {
+		log.Warn("Invalid params")
+		return c.JSON(http.StatusBadRequest, domain.ErrorResponse{
+			Error:     "Bad Request",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	err := uh.userService.AdminDisableTwoFactor(c.Request().Context(), newViewer(c), id)
+	if err != nil && errors.Is(err, domain.ErrUserNotFound) {
+		log.Warn("User not found to disable two-factor")
+		return c.JSON(http.StatusNotFound, domain.ErrorResponse{
+			Error:     "Not Found",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err != nil && errors.Is(err, domain.ErrTwoFactorNotEnabled) {
+		log.Warn("Two-factor authentication not enabled")
+		return c.JSON(http.StatusConflict, domain.ErrorResponse{
+			Error:     "Conflict",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err != nil {
+		log.Error("Error trying to call admin disable two-factor service.")
+		return c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
+			Error:     "Internal Server Error",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	log.Info("Two-factor authentication disabled by admin")
+	return c.NoContent(http.StatusNoContent)
+}
+
+// AdminForcePasswordReset godoc
+// @Summary Require a user to reset their password
+// @Description Flag an account believed compromised: its sessions end and the next login returns a reset token with the password_reset_required code instead of a session, until the password is reset
+// @Tags admin
+// @Param id path string true "User ID"
+// @Success 204
+// @Failure 400 {object} domain.ErrorResponse
+// @Failure 401
+// @Failure 404 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /v1/admin/users/{id}/password-reset [post]
+func (uh *userHandler) AdminForcePasswordReset(c echo.Context) error {
+	log := slog.With(
+		slog.String("func", "AdminForcePasswordReset"),
+		slog.String("handler", "user"))
+
+	id := c.Param("id")
+	if err := util.IsValidUUID(id); err != nil {
+		log.Warn("Invalid params")
+		return c.JSON(http.StatusBadRequest, domain.ErrorResponse{
+			Error:     "Bad Request",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	err := uh.userService.AdminForcePasswordReset(c.Request().Context(), newViewer(c), id)
+	if err != nil && errors.Is(err, domain.ErrUserNotFound) {
+		log.Warn("User not found to require password reset")
+		return c.JSON(http.StatusNotFound, domain.ErrorResponse{
+			Error:     "Not Found",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err != nil {
+		log.Error("Error trying to call admin force password reset service.")
+		return c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
+			Error:     "Internal Server Error",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	log.Info("Password reset required by admin")
+	return c.NoContent(http.StatusNoContent)
+}
+
+// AdminSuspend godoc
+// @Summary Suspend a user
+// @Description Keep a user from logging in until the given date, or indefinitely when it is omitted. The sessions of the user end right away and the reason is kept in the audit log
+// @Tags admin
+// @Accept json
+// @Param id path string true "User ID"
+// @Param suspend body domain.SuspendPayLoad true "Reason and optional end of the suspension"
+// @Success 204
+// @Failure 400 {object} domain.ErrorResponse
+// @Failure 401
+// @Failure 404 {object} domain.ErrorResponse
+// @Failure 422 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /v1/admin/users/{id}/suspend [post]
+func (uh *userHandler) AdminSuspend(c echo.Context) error {
+	log := slog.With(
+		slog.String("func", "AdminSuspend"),
+		slog.String("handler", "user"))
+
+	id := c.Param("id")
+	if err := util.IsValidUUID(id); err != nil {
+		log.Warn("Invalid params")
+		return c.JSON(http.StatusBadRequest, domain.ErrorResponse{
+			Error:     "Bad Request",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	var suspendPayLoad domain.SuspendPayLoad
+	if err := c.Bind(&suspendPayLoad); err != nil {
+		log.Warn("Failed to bind suspend data to domain")
+		return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
+			Error:     "Unprocessable Entity",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err := suspendPayLoad.Validate(); err != nil {
+		log.Warn("Invalid suspend data")
+		return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
+			Error:     "Unprocessable Entity",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	err := uh.userService.AdminSuspend(c.Request().Context(), newViewer(c), id, suspendPayLoad)
+	if err != nil && errors.Is(err, domain.ErrUserNotFound) {
+		log.Warn("User not found to suspend")
+		return c.JSON(http.StatusNotFound, domain.ErrorResponse{
+			Error:     "Not Found",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err != nil {
+		log.Error("Error trying to call admin suspend service.")
+		return c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
+			Error:     "Internal Server Error",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	log.Info("User suspended by admin")
+	return c.NoContent(http.StatusNoContent)
+}
+
+// AdminUnsuspend godoc
+// @Summary Lift the suspension of a user
+// @Description Let a suspended user log in again. The reason is kept in the audit log
+// @Tags admin
+// @Accept json
+// @Param id path string true "User ID"
+// @Param unsuspend body domain.UnsuspendPayLoad true "Reason"
+// @Success 204
+// @Failure 400 {object} domain.ErrorResponse
+// @Failure 401
+// @Failure 404 {object} domain.ErrorResponse
+// @Failure 409 {object} domain.ErrorResponse
+// @Failure 422 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /v1/admin/users/{id}/unsuspend [post]
+func (uh *userHandler) AdminUnsuspend(c echo.Context) error {
+	log := slog.With(
+		slog.String("func", "AdminUnsuspend"),
+		slog.String("handler", "user"))
+
+	id := c.Param("id")
+	if err := util.IsValidUUID(id); err != nil {
+		log.Warn("Invalid params")
+		return c.JSON(http.StatusBadRequest, domain.ErrorResponse{
+			Error:     "Bad Request",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	var unsuspendPayLoad domain.UnsuspendPayLoad
+	if err := c.Bind(&unsuspendPayLoad); err != nil {
+		log.Warn("Failed to bind unsuspend data to domain")
+		return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
+			Error:     "Unprocessable Entity",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err := unsuspendPayLoad.Validate(); err != nil {
+		log.Warn("Invalid unsuspend data")
+		return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
+			Error:     "Unprocessable Entity",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	err := uh.userService.AdminUnsuspend(c.Request().Context(), newViewer(c), id, unsuspendPayLoad.Reason)
+	if err != nil && errors.Is(err, domain.ErrUserNotFound) {
+		log.Warn("User not found to unsuspend")
+		return c.JSON(http.StatusNotFound, domain.ErrorResponse{
+			Error:     "Not Found",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err != nil && errors.Is(err, domain.ErrAccountNotSuspended) {
+		log.Warn("User not suspended")
+		return c.JSON(http.StatusConflict, domain.ErrorResponse{
+			Error:     "Conflict",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err != nil {
+		log.Error("Error trying to call admin unsuspend service.")
+		return c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
+			Error:     "Internal Server Error",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	log.Info("User unsuspended by admin")
+	return c.NoContent(http.StatusNoContent)
+}
+
+// AdminGetById godoc
+// @Summary Get the account data of a user
+// @Description Get a user by ID with its account data, whoever it is
+// @Tags admin
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 200 {object} domain.UserResponse
+// @Success 204
+// @Failure 400 {object} domain.ErrorResponse
+// @Failure 401
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /v1/admin/users/{id} [get]
+func (uh *userHandler) AdminGetById(c echo.Context) error {
+	log := slog.With(
+		slog.String("func", "AdminGetById"),
+		slog.String("handler", "user"))
+
+	id := c.Param("id")
+	if err := util.IsValidUUID(id); err != nil {
+		log.Warn("Invalid params")
+		return c.JSON(http.StatusBadRequest, domain.ErrorResponse{
+			Error:     "Bad Request",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	return uh.getById(c, log, id, domain.Viewer{Admin: true, ClientInfo: newClientInfo(c)})
+}
+
+// AdminAnonymize godoc
+// @Summary Erase a user
+// @Description Erase the personal data of an account right away. The account keeps only its ID, so the audit trail still points to it, and can no longer be logged in to. This cannot be undone
+// @Tags admin
+// @Param id path string true "User ID"
+// @Success 204
+// @Failure 400 {object} domain.ErrorResponse
+// @Failure 401
+// @Failure 404 {object} domain.ErrorResponse
+// @Failure 409 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /v1/admin/users/{id}/anonymize [post]
+func (uh *userHandler) AdminAnonymize(c echo.Context) error {
+	log := slog.With(
+		slog.String("func", "AdminAnonymize"),
+		slog.String("handler", "user"))
+
+	id := c.Param("id")
+	if err := util.IsValidUUID(id); err != nil {
+		log.Warn("Invalid params")
+		return c.JSON(http.StatusBadRequest, domain.ErrorResponse{
+			Error:     "Bad Request",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	err := uh.userService.AdminAnonymize(c.Request().Context(), newViewer(c), id)
+	if err != nil && errors.Is(err, domain.ErrUserNotFound) {
+		log.Warn("User not found to anonymize")
+		return c.JSON(http.StatusNotFound, domain.ErrorResponse{
+			Error:     "Not Found",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err != nil && errors.Is(err, domain.ErrUserAlreadyAnonymized) {
+		log.Warn("User already anonymized")
+		return c.JSON(http.StatusConflict, domain.ErrorResponse{
+			Error:     "Conflict",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err != nil {
+		log.Error("Error trying to call admin anonymize service.")
+		return c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
+			Error:     "Internal Server Error",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	log.Info("User successfully anonymized by admin")
+	return c.NoContent(http.StatusNoContent)
+}
+
+// AdminRestore godoc
+// @Summary Restore a deleted user
+// @Description Undo the deletion of an account, as long as it has not been purged after the retention period. The user has to log in again
+// @Tags admin
+// @Param id path string true "User ID"
+// @Success 204
+// @Failure 400 {object} domain.ErrorResponse
+// @Failure 401
+// @Failure 404 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /v1/admin/users/{id}/restore [post]
+func (uh *userHandler) AdminRestore(c echo.Context) error {
+	log := slog.With(
+		slog.String("func", "AdminRestore"),
+		slog.String("handler", "user"))
+
+	id := c.Param("id")
+	if err := util.IsValidUUID(id); err != nil {
+		log.Warn("Invalid params")
+		return c.JSON(http.StatusBadRequest, domain.ErrorResponse{
+			Error:     "Bad Request",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	err := uh.userService.AdminRestore(c.Request().Context(), newViewer(c), id)
+	if err != nil && errors.Is(err, domain.ErrUserNotFound) {
+		log.Warn("Deleted user not found to restore")
+		return c.JSON(http.StatusNotFound, domain.ErrorResponse{
+			Error:     "Not Found",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err != nil {
+		log.Error("Error trying to call admin restore service.")
+		return c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
+			Error:     "Internal Server Error",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	log.Info("User restored by admin")
+	return c.NoContent(http.StatusNoContent)
+}
+
+// AdminDelete godoc
+// @Summary Delete a user right away
+// @Description Delete an account without the grace period users get. Its sessions end; it can still be restored until it is purged after the retention period
+// @Tags admin
+// @Param id path string true "User ID"
+// @Success 204
+// @Failure 400 {object} domain.ErrorResponse
+// @Failure 401
+// @Failure 404 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /v1/admin/users/{id} [delete]
+func (uh *userHandler) AdminDelete(c echo.Context) error {
+	log := slog.With(
+		slog.String("func", "AdminDelete"),
+		slog.String("handler", "user"))
+
+	id := c.Param("id")
+	if err := util.IsValidUUID(id); err != nil {
+		log.Warn("Invalid params")
+		return c.JSON(http.StatusBadRequest, domain.ErrorResponse{
+			Error:     "Bad Request",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	return uh.adminDelete(c, log, id)
+}
+
+// adminDelete deletes the user with id right away, for AdminDelete and admins calling Delete.
+func (uh *userHandler) adminDelete(c echo.Context, log *slog.Logger, id string) error {
+	err := uh.userService.AdminDelete(c.Request().Context(), newViewer(c), id)
+	if err != nil && errors.Is(err, domain.ErrUserNotFound) {
+		log.Warn("User not found to delete")
+		return c.JSON(http.StatusNotFound, domain.ErrorResponse{
+			Error:     "Not Found",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err != nil {
+		log.Error("Error trying to call admin delete service.")
+		return c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
+			Error:     "Internal Server Error",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	log.Info("User deleted by admin")
+	return c.NoContent(http.StatusNoContent)
 }
@@ -0,0 +1,39 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/OVillas/autentication/domain"
+	"github.com/labstack/echo/v4"
+	"github.com/samber/do"
+)
+
+type userCacheHandler struct {
+	i              *do.Injector
+	userRepository domain.UserRepository
+}
+
+func NewUserCacheHandler(i *do.Injector) (domain.UserCacheHandler, error) {
+	userRepository := do.MustInvoke[domain.UserRepository](i)
+	return &userCacheHandler{
+		i:              i,
+		userRepository: userRepository,
+	}, nil
+}
+
+// GetStats godoc
+// @Summary Get the user cache counters
+// @Description Get how many user lookups were served from the user cache and how many went to the database since the instance started. The counters are per instance, and both stay at zero with an empty store when the cache is disabled
+// @Tags admin
+// @Produce json
+// @Success 200 {object} domain.UserCacheStats
+// @Failure 401
+// @Router /v1/admin/user-cache [get]
+func (uch *userCacheHandler) GetStats(c echo.Context) error {
+	userCache, ok := uch.userRepository.(domain.UserCache)
+	if !ok {
+		return c.JSON(http.StatusOK, domain.UserCacheStats{})
+	}
+
+	return c.JSON(http.StatusOK, userCache.Stats())
+}
@@ -0,0 +1,203 @@
+package handler
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/OVillas/autentication/domain"
+	"github.com/OVillas/autentication/util"
+	"github.com/labstack/echo/v4"
+	"github.com/samber/do"
+)
+
+type userIdentityHandler struct {
+	i                   *do.Injector
+	userIdentityService domain.UserIdentityService
+	socialLoginService  domain.SocialLoginService
+}
+
+func NewUserIdentityHandler(i *do.Injector) (domain.UserIdentityHandler, error) {
+	userIdentityService := do.MustInvoke[domain.UserIdentityService](i)
+	socialLoginService := do.MustInvoke[domain.SocialLoginService](i)
+	return &userIdentityHandler{
+		i:                   i,
+		userIdentityService: userIdentityService,
+		socialLoginService:  socialLoginService,
+	}, nil
+}
+
+// GetAll godoc
+// @Summary List linked identities
+// @Description List the external identities linked to the authenticated user
+// @Tags identities
+// @Produce json
+// @Success 200 {array} domain.UserIdentityResponse
+// @Failure 401 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /v1/users/me/identities [get]
+// @Security bearerToken
+func (uih *userIdentityHandler) GetAll(c echo.Context) error {
+	log := slog.With(
+		slog.String("func", "GetAll"),
+		slog.String("handler", "userIdentity"))
+
+	idFromToken, err := util.ExtractUserIdFromToken(c)
+	if err != nil {
+		log.Warn("Error getting user ID from token")
+		return c.JSON(http.StatusUnauthorized, domain.ErrorResponse{
+			Error:     "Unauthorized",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	response, err := uih.userIdentityService.GetAll(idFromToken)
+	if err != nil {
+		log.Error("Error trying to call get identities service.")
+		return c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
+			Error:     "Internal Server Error",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	log.Info("Identities successfully retrieved")
+
+	if len(response) == 0 {
+		return c.NoContent(http.StatusNoContent)
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+// Link godoc
+// @Summary Link an identity
+// @Description Start the sign in flow of an identity provider bound to the current session. Send the browser to the returned URL; the provider callback links the identity to the authenticated user
+// @Tags identities
+// @Produce json
+// @Param provider path string true "Identity provider"
+// @Success 200 {object} domain.LinkIdentityResponse
+// @Failure 401 {object} domain.ErrorResponse
+// @Failure 404 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /v1/users/me/identities/{provider}/link [post]
+// @Security bearerToken
+func (uih *userIdentityHandler) Link(c echo.Context) error {
+	log := slog.With(
+		slog.String("func", "Link"),
+		slog.String("handler", "userIdentity"))
+
+	claims, err := util.ExtractTokenClaims(c)
+	if err != nil {
+		log.Warn("Error getting claims from token")
+		return c.JSON(http.StatusUnauthorized, domain.ErrorResponse{
+			Error:     "Unauthorized",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	authorizationURL, err := uih.socialLoginService.BeginLink(util.ExtractTenant(c), c.Param("provider"), claims.UserID, claims.SessionID)
+	if err != nil && errors.Is(err, domain.ErrUnknownProvider) {
+		log.Warn("Unknown identity provider")
+		return c.JSON(http.StatusNotFound, domain.ErrorResponse{
+			Error:     "Not Found",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err != nil {
+		log.Error("Error trying to call begin link service.")
+		return c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
+			Error:     "Internal Server Error",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	log.Info("Identity link started")
+	return c.JSON(http.StatusOK, domain.LinkIdentityResponse{AuthorizationURL: authorizationURL})
+}
+
+// Unlink godoc
+// @Summary Unlink an identity
+// @Description Remove an external identity from the authenticated user. The last remaining way to sign in cannot be removed
+// @Tags identities
+// @Param id path string true "Identity ID"
+// @Success 204
+// @Failure 400 {object} domain.ErrorResponse
+// @Failure 401 {object} domain.ErrorResponse
+// @Failure 404 {object} domain.ErrorResponse
+// @Failure 409 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /v1/users/me/identities/{id} [delete]
+// @Security bearerToken
+func (uih *userIdentityHandler) Unlink(c echo.Context) error {
+	log := slog.With(
+		slog.String("func", "Unlink"),
+		slog.String("handler", "userIdentity"))
+
+	id := c.Param("id")
+	if err := util.IsValidUUID(id); err != nil {
+		log.Warn("Invalid params")
+		return c.JSON(http.StatusBadRequest, domain.ErrorResponse{
+			Error:     "Bad Request",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	idFromToken, err := util.ExtractUserIdFromToken(c)
+	if err != nil {
+		log.Warn("Error getting user ID from token")
+		return c.JSON(http.StatusUnauthorized, domain.ErrorResponse{
+			Error:     "Unauthorized",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	err = uih.userIdentityService.Unlink(c.Request().Context(), idFromToken, id)
+	if err != nil && (errors.Is(err, domain.ErrUserIdentityNotFound) || errors.Is(err, domain.ErrUserNotFound)) {
+		log.Warn("Identity not found to unlink")
+		return c.JSON(http.StatusNotFound, domain.ErrorResponse{
+			Error:     "Not Found",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err != nil && errors.Is(err, domain.ErrLastLoginMethod) {
+		log.Warn("Refused to unlink the last login method")
+		return c.JSON(http.StatusConflict, domain.ErrorResponse{
+			Error:     "Conflict",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err != nil {
+		log.Error("Error trying to call unlink identity service.")
+		return c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
+			Error:     "Internal Server Error",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	log.Info("Identity unlinked successfully")
+	return c.NoContent(http.StatusNoContent)
+}
@@ -13,18 +13,15 @@ import (
 )
 
 type userPasswordHandler struct {
-	i                       *do.Injector
-	userPasswordService     domain.UserPasswordService
-	confirmationCodeService domain.ConfirmationCodeService
+	i                   *do.Injector
+	userPasswordService domain.UserPasswordService
 }
 
 func NewUserPasswordHandler(i *do.Injector) (domain.UserPasswordHandler, error) {
 	userPasswordService := do.MustInvoke[domain.UserPasswordService](i)
-	confimatioCodeService := do.MustInvoke[domain.ConfirmationCodeService](i)
 	return &userPasswordHandler{
-		i:                       i,
-		userPasswordService:     userPasswordService,
-		confirmationCodeService: confimatioCodeService,
+		i:                   i,
+		userPasswordService: userPasswordService,
 	}, nil
 }
 
@@ -37,10 +34,12 @@ func NewUserPasswordHandler(i *do.Injector) (domain.UserPasswordHandler, error)
 // @Param id path string true "User ID"
 // @Param updatePassword body domain.UpdatePassword true "Update Password Payload"
 // @Success 200 {object} string "JWT Token"
+// @Failure 400 {object} domain.ErrorResponse
 // @Failure 422 {object} domain.ErrorResponse
 // @Failure 403
 // @Failure 404 {object} domain.ErrorResponse
 // @Failure 500 {object} domain.ErrorResponse
+// @Failure 503 {object} domain.ErrorResponse
 // @Router /v1/users/password/{id} [patch]
 // @Security bearerToken
 func (uph *userPasswordHandler) UpdatePassword(c echo.Context) error {
@@ -78,6 +77,47 @@ func (uph *userPasswordHandler) UpdatePassword(c echo.Context) error {
 		return c.NoContent(http.StatusForbidden)
 	}
 
+	return uph.updatePassword(c, log, userId)
+}
+
+// UpdatePasswordMe godoc
+// @Summary Update the password of the authenticated user
+// @Description Update the password of the user the access token belongs to. Every session ends
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param updatePassword body domain.UpdatePassword true "Update Password Payload"
+// @Success 204
+// @Failure 400 {object} domain.ErrorResponse
+// @Failure 401 {object} domain.ErrorResponse
+// @Failure 404 {object} domain.ErrorResponse
+// @Failure 422 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Failure 503 {object} domain.ErrorResponse
+// @Router /v1/users/me/password [put]
+// @Security bearerToken
+func (uph *userPasswordHandler) UpdatePasswordMe(c echo.Context) error {
+	log := slog.With(
+		slog.String("func", "UpdatePasswordMe"),
+		slog.String("handler", "authentication"))
+
+	userIdFromToken, err := util.ExtractUserIdFromToken(c)
+	if err != nil {
+		log.Warn("err to get user if from token")
+		return c.JSON(http.StatusUnauthorized, domain.ErrorResponse{
+			Error:     "Unauthorized",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	return uph.updatePassword(c, log, userIdFromToken)
+}
+
+// updatePassword changes the password of the user with userId, for UpdatePassword and
+// UpdatePasswordMe.
+func (uph *userPasswordHandler) updatePassword(c echo.Context, log *slog.Logger, userId string) error {
 	var updatePassword domain.UpdatePassword
 	if err := c.Bind(&updatePassword); err != nil {
 		log.Warn("Failed to bind user data to domain")
@@ -99,10 +139,10 @@ func (uph *userPasswordHandler) UpdatePassword(c echo.Context) error {
 		})
 	}
 
-	err = uph.userPasswordService.UpdatePassword(userId, updatePassword)
+	err := uph.userPasswordService.UpdatePassword(c.Request().Context(), userId, updatePassword, newClientInfo(c))
 
 	if err != nil && errors.Is(err, domain.ErrUserNotFound) {
-		log.Error("Error: ", err)
+		log.Error("Error: ", slog.Any("error", err))
 		return c.JSON(http.StatusNotFound, domain.ErrorResponse{
 			Error:     "Not found user",
 			Message:   err.Error(),
@@ -112,7 +152,7 @@ func (uph *userPasswordHandler) UpdatePassword(c echo.Context) error {
 	}
 
 	if err != nil && errors.Is(err, domain.ErrPasswordNotMatch) {
-		log.Error("Error: ", err)
+		log.Error("Error: ", slog.Any("error", err))
 		return c.JSON(http.StatusUnauthorized, domain.ErrorResponse{
 			Error:     "Uncorrect password",
 			Message:   err.Error(),
@@ -121,21 +161,52 @@ func (uph *userPasswordHandler) UpdatePassword(c echo.Context) error {
 		})
 	}
 
+	if err != nil && (errors.Is(err, domain.ErrPasswordReuse) || errors.Is(err, domain.ErrPasswordMatchesIdentity) ||
+		errors.Is(err, domain.ErrPasswordBreached)) {
+		log.Warn("New password refused", slog.Any("error", err))
+		return c.JSON(http.StatusBadRequest, domain.ErrorResponse{
+			Error:     "Bad Request",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err != nil && errors.Is(err, domain.ErrPasswordBreachCheck) {
+		log.Error("Breached password check unavailable")
+		return c.JSON(http.StatusServiceUnavailable, domain.ErrorResponse{
+			Error:     "Service Unavailable",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
+			Error:     "Internal Server Error",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
 	log.Info("UpdatePassword executed successfully")
 	return c.NoContent(http.StatusNoContent)
 }
 
 // ForgotPassword godoc
 // @Summary Forgot user password
-// @Description Send an OTP code to redeem your password
+// @Description Send an OTP code to redeem your password. The answer is the same whether or not the email has an account
 // @Tags authentication
 // @Accept json
 // @Produce json
-// @Param confirmCode body domain.ConfirmCode true "Confirmation Code Payload"
-// @Success 200 {object} string "JWT Token"
+// @Param requestResetPassword body domain.RequestResetPassword true "Email of the account"
+// @Success 202
 // @Failure 422 {object} domain.ErrorResponse
-// @Failure 500 {object} domain.ErrorResponse
-// @Router /v1/auth/password [post]
+// @Failure 429 {object} domain.ErrorResponse
+// @Router /v1/auth/password/forgot [post]
 func (uph *userPasswordHandler) ForgotPassword(c echo.Context) error {
 	log := slog.With(
 		slog.String("func", "ForgotPassword"),
@@ -164,18 +235,12 @@ func (uph *userPasswordHandler) ForgotPassword(c echo.Context) error {
 		})
 	}
 
-	if err := uph.confirmationCodeService.SendConfirmationCode(requestResetPassword.Email); err != nil {
-		log.Error("Errors: ", err)
-		return c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
-			Error:     "Internal Server Error",
-			Message:   err.Error(),
-			TimeStamp: time.Now(),
-			Path:      c.Path(),
-		})
+	if err := uph.userPasswordService.ForgotPassword(c.Request().Context(), util.ExtractTenant(c), requestResetPassword.Email); err != nil {
+		log.Error("Errors: ", slog.Any("error", err))
 	}
 
-	log.Info("Confirmation send successfully")
-	return c.NoContent(http.StatusOK)
+	log.Info("ForgotPassword executed successfully")
+	return c.NoContent(http.StatusAccepted)
 }
 
 // ConfirmResetPasswordCode godoc
@@ -188,6 +253,7 @@ func (uph *userPasswordHandler) ForgotPassword(c echo.Context) error {
 // @Success 200 {string} string "JWT Token"
 // @Failure 404 {object} domain.ErrorResponse "Not Found"
 // @Failure 401 {object} domain.ErrorResponse "Unauthorized"
+// @Failure 429 {object} domain.ErrorResponse "Too Many Requests"
 // @Failure 500 {object} domain.ErrorResponse "Internal Server Error"
 // @Router /v1/auth/password/confirm [post]
 func (uph *userPasswordHandler) ConfirmResetPasswordCode(c echo.Context) error {
@@ -218,7 +284,17 @@ func (uph *userPasswordHandler) ConfirmResetPasswordCode(c echo.Context) error {
 		})
 	}
 
-	token, err := uph.userPasswordService.ConfirmResetPasswordCode(confirmCode)
+	token, err := uph.userPasswordService.ConfirmResetPasswordCode(c.Request().Context(), util.ExtractTenant(c), confirmCode)
+
+	if err != nil && errors.Is(err, domain.ErrTooManyOTPAttempts) {
+		log.Warn("Too many wrong codes")
+		return c.JSON(http.StatusTooManyRequests, domain.ErrorResponse{
+			Error:     "Too Many Requests",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
 
 	if err != nil && errors.Is(err, domain.ErrOTPNotFound) {
 		log.Warn("OTP not found")
@@ -241,7 +317,7 @@ func (uph *userPasswordHandler) ConfirmResetPasswordCode(c echo.Context) error {
 	}
 
 	if err != nil {
-		log.Error("Errors: ", err)
+		log.Error("Errors: ", slog.Any("error", err))
 		return c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
 			Error:     "Internal Server Error",
 			Message:   err.Error(),
@@ -262,10 +338,13 @@ func (uph *userPasswordHandler) ConfirmResetPasswordCode(c echo.Context) error {
 // @Produce json
 // @Param resetPassword body domain.ResetPassword true "Reset Password Data"
 // @Success 204
+// @Failure 400 {object} domain.ErrorResponse "Bad Request"
 // @Failure 401 {object} domain.ErrorResponse "Unauthorized"
+// @Failure 403 {object} domain.ErrorResponse "Forbidden"
 // @Failure 422 {object} domain.ErrorResponse "Unprocessable Entity"
 // @Failure 404 {object} domain.ErrorResponse "Not Found"
 // @Failure 500 {object} domain.ErrorResponse "Internal Server Error"
+// @Failure 503 {object} domain.ErrorResponse "Service Unavailable"
 // @Router /v1/auth/password/reset [patch]
 // @Security bearerToken
 func (uph *userPasswordHandler) ResetPassword(c echo.Context) error {
@@ -286,6 +365,17 @@ func (uph *userPasswordHandler) ResetPassword(c echo.Context) error {
 		})
 	}
 
+	resetToken, err := util.ExtractTokenClaims(c)
+	if err != nil {
+		log.Warn("err to get reset token claims")
+		return c.JSON(http.StatusUnauthorized, domain.ErrorResponse{
+			Error:     "Unauthorized",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
 	var resetPassword domain.ResetPassword
 	if err := c.Bind(&resetPassword); err != nil {
 		log.Warn("Failed to bind resetPassword data to domain")
@@ -297,7 +387,18 @@ func (uph *userPasswordHandler) ResetPassword(c echo.Context) error {
 		})
 	}
 
-	if err := resetPassword.Validate(); err != nil {
+	err = resetPassword.Validate()
+	if err != nil && errors.Is(err, domain.ErrPasswordConfirmationMismatch) {
+		log.Warn("Password and confirm password do not match")
+		return c.JSON(http.StatusBadRequest, domain.ErrorResponse{
+			Error:     "Bad Request",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err != nil {
 		log.Warn("Invalid resetPassword data")
 		return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
 			Error:     "Unprocessable Entity",
@@ -307,7 +408,27 @@ func (uph *userPasswordHandler) ResetPassword(c echo.Context) error {
 		})
 	}
 
-	err = uph.userPasswordService.ResetPassword(userIdFromToken, resetPassword)
+	err = uph.userPasswordService.ResetPassword(c.Request().Context(), userIdFromToken, *resetToken, resetPassword, newClientInfo(c))
+
+	if err != nil && errors.Is(err, domain.ErrInvalidResetToken) {
+		log.Warn("Reset password token invalid or already used")
+		return c.JSON(http.StatusUnauthorized, domain.ErrorResponse{
+			Error:     "Unauthorized",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err != nil && errors.Is(err, domain.ErrUserIDMismatch) {
+		log.Warn("Reset password token issued to another user")
+		return c.JSON(http.StatusForbidden, domain.ErrorResponse{
+			Error:     "Forbidden",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
 
 	if err != nil && errors.Is(err, domain.ErrUserNotFound) {
 		log.Warn("User not found with this email")
@@ -319,10 +440,20 @@ func (uph *userPasswordHandler) ResetPassword(c echo.Context) error {
 		})
 	}
 
-	if err != nil && errors.Is(err, domain.ErrPasswordNotMatch) {
-		log.Warn("Password and confirm password do not match")
-		return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
-			Error:     "Unprocessable Entity",
+	if err != nil && (errors.Is(err, domain.ErrPasswordConfirmationMismatch) || errors.Is(err, domain.ErrPasswordBreached)) {
+		log.Warn("New password refused", slog.Any("error", err))
+		return c.JSON(http.StatusBadRequest, domain.ErrorResponse{
+			Error:     "Bad Request",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err != nil && errors.Is(err, domain.ErrPasswordBreachCheck) {
+		log.Error("Breached password check unavailable")
+		return c.JSON(http.StatusServiceUnavailable, domain.ErrorResponse{
+			Error:     "Service Unavailable",
 			Message:   err.Error(),
 			TimeStamp: time.Now(),
 			Path:      c.Path(),
@@ -330,7 +461,7 @@ func (uph *userPasswordHandler) ResetPassword(c echo.Context) error {
 	}
 
 	if err != nil {
-		log.Error("Errors: ", err)
+		log.Error("Errors: ", slog.Any("error", err))
 		return c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
 			Error:     "Internal Server Error",
 			Message:   err.Error(),
@@ -342,3 +473,48 @@ func (uph *userPasswordHandler) ResetPassword(c echo.Context) error {
 	log.Info("Password reset successfully")
 	return c.NoContent(http.StatusOK)
 }
+
+// Strength godoc
+// @Summary Estimate password strength
+// @Description Rate a candidate password and list the password policy rules it breaks
+// @Tags authentication
+// @Accept json
+// @Produce json
+// @Param passwordStrength body domain.PasswordStrengthPayLoad true "Candidate password"
+// @Success 200 {object} domain.PasswordStrengthResponse
+// @Failure 422 {object} domain.ErrorResponse
+// @Failure 429 {object} domain.ErrorResponse
+// @Router /v1/auth/password/strength [post]
+func (uph *userPasswordHandler) Strength(c echo.Context) error {
+	log := slog.With(
+		slog.String("func", "Strength"),
+		slog.String("handler", "authentication"))
+
+	log.Info("Strength initiated")
+
+	var passwordStrength domain.PasswordStrengthPayLoad
+	if err := c.Bind(&passwordStrength); err != nil {
+		log.Warn("Failed to bind passwordStrength data to domain")
+		return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
+			Error:     "Unprocessable Entity",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err := passwordStrength.Validate(); err != nil {
+		log.Warn("Invalid passwordStrength data")
+		return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
+			Error:     "Unprocessable Entity",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	strength := uph.userPasswordService.Strength(passwordStrength)
+
+	log.Info("Strength executed successfully")
+	return c.JSON(http.StatusOK, strength)
+}
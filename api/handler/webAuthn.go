@@ -0,0 +1,518 @@
+package handler
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/OVillas/autentication/config"
+	"github.com/OVillas/autentication/domain"
+	"github.com/OVillas/autentication/util"
+	"github.com/labstack/echo/v4"
+	"github.com/samber/do"
+)
+
+type webAuthnHandler struct {
+	i               *do.Injector
+	webAuthnService domain.WebAuthnService
+}
+
+func NewWebAuthnHandler(i *do.Injector) (domain.WebAuthnHandler, error) {
+	webAuthnService := do.MustInvoke[domain.WebAuthnService](i)
+	return &webAuthnHandler{
+		i:               i,
+		webAuthnService: webAuthnService,
+	}, nil
+}
+
+// BeginRegistration godoc
+// @Summary Begin passkey registration
+// @Description Create the options the browser passes to navigator.credentials.create for a new passkey of the authenticated user
+// @Tags passkeys
+// @Produce json
+// @Success 200 {object} domain.WebAuthnBeginResponse
+// @Failure 401 {object} domain.ErrorResponse
+// @Failure 404 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /v1/users/me/webauthn/register/begin [post]
+// @Security bearerToken
+func (wah *webAuthnHandler) BeginRegistration(c echo.Context) error {
+	log := slog.With(
+		slog.String("func", "BeginRegistration"),
+		slog.String("handler", "webAuthn"))
+
+	idFromToken, err := util.ExtractUserIdFromToken(c)
+	if err != nil {
+		log.Warn("Error getting user ID from token")
+		return c.JSON(http.StatusUnauthorized, domain.ErrorResponse{
+			Error:     "Unauthorized",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	response, err := wah.webAuthnService.BeginRegistration(c.Request().Context(), idFromToken)
+	if err != nil && (errors.Is(err, domain.ErrWebAuthnDisabled) || errors.Is(err, domain.ErrUserNotFound)) {
+		log.Warn("Passkey registration unavailable", slog.Any("error", err))
+		return c.JSON(http.StatusNotFound, domain.ErrorResponse{
+			Error:     "Not Found",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err != nil {
+		log.Error("Error trying to call begin passkey registration service.")
+		return c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
+			Error:     "Internal Server Error",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	log.Info("Passkey registration started")
+	return c.JSON(http.StatusOK, response)
+}
+
+// FinishRegistration godoc
+// @Summary Finish passkey registration
+// @Description Verify the attestation returned by the browser and store the new passkey
+// @Tags passkeys
+// @Accept json
+// @Produce json
+// @Param webAuthnRegisterPayLoad body domain.WebAuthnRegisterPayLoad true "Session and attestation response"
+// @Success 201 {object} domain.WebAuthnCredentialResponse
+// @Failure 400 {object} domain.ErrorResponse
+// @Failure 401 {object} domain.ErrorResponse
+// @Failure 404 {object} domain.ErrorResponse
+// @Failure 422 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /v1/users/me/webauthn/register/finish [post]
+// @Security bearerToken
+func (wah *webAuthnHandler) FinishRegistration(c echo.Context) error {
+	log := slog.With(
+		slog.String("func", "FinishRegistration"),
+		slog.String("handler", "webAuthn"))
+
+	idFromToken, err := util.ExtractUserIdFromToken(c)
+	if err != nil {
+		log.Warn("Error getting user ID from token")
+		return c.JSON(http.StatusUnauthorized, domain.ErrorResponse{
+			Error:     "Unauthorized",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	var webAuthnRegisterPayLoad domain.WebAuthnRegisterPayLoad
+	if err := c.Bind(&webAuthnRegisterPayLoad); err != nil {
+		log.Warn("Failed to bind passkey registration data to domain")
+		return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
+			Error:     "Unprocessable Entity",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err := webAuthnRegisterPayLoad.Validate(); err != nil {
+		log.Warn("Invalid passkey registration data")
+		return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
+			Error:     "Unprocessable Entity",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	response, err := wah.webAuthnService.FinishRegistration(c.Request().Context(), idFromToken, webAuthnRegisterPayLoad)
+	if err != nil && (errors.Is(err, domain.ErrWebAuthnDisabled) || errors.Is(err, domain.ErrUserNotFound)) {
+		log.Warn("Passkey registration unavailable", slog.Any("error", err))
+		return c.JSON(http.StatusNotFound, domain.ErrorResponse{
+			Error:     "Not Found",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err != nil && (errors.Is(err, domain.ErrInvalidWebAuthnSession) || errors.Is(err, domain.ErrWebAuthnVerification)) {
+		log.Warn("Passkey registration refused", slog.Any("error", err))
+		return c.JSON(http.StatusBadRequest, domain.ErrorResponse{
+			Error:     "Bad Request",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err != nil {
+		log.Error("Error trying to call finish passkey registration service.")
+		return c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
+			Error:     "Internal Server Error",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	log.Info("Passkey registered successfully")
+	return c.JSON(http.StatusCreated, response)
+}
+
+// BeginLogin godoc
+// @Summary Begin passkey login
+// @Description Create the options the browser passes to navigator.credentials.get, any passkey of the relying party is accepted
+// @Tags passkeys
+// @Produce json
+// @Success 200 {object} domain.WebAuthnBeginResponse
+// @Failure 404 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /v1/auth/webauthn/login/begin [post]
+func (wah *webAuthnHandler) BeginLogin(c echo.Context) error {
+	log := slog.With(
+		slog.String("func", "BeginLogin"),
+		slog.String("handler", "webAuthn"))
+
+	response, err := wah.webAuthnService.BeginLogin()
+	if err != nil && errors.Is(err, domain.ErrWebAuthnDisabled) {
+		log.Warn("Passkey login unavailable")
+		return c.JSON(http.StatusNotFound, domain.ErrorResponse{
+			Error:     "Not Found",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err != nil {
+		log.Error("Error trying to call begin passkey login service.")
+		return c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
+			Error:     "Internal Server Error",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	log.Info("Passkey login started")
+	return c.JSON(http.StatusOK, response)
+}
+
+// FinishLogin godoc
+// @Summary Finish passkey login
+// @Description Verify the assertion returned by the browser and open a session for the owner of the passkey, without password
+// @Tags passkeys
+// @Accept json
+// @Produce json
+// @Param webAuthnLoginPayLoad body domain.WebAuthnLoginPayLoad true "Session and assertion response"
+// @Success 200 {object} domain.LoginResponse
+// @Failure 401 {object} domain.ErrorResponse
+// @Failure 403 {object} domain.ErrorResponse "Refused, or a domain.AccountSuspendedResponse when the account is suspended"
+// @Failure 404 {object} domain.ErrorResponse
+// @Failure 409 {object} domain.ErrorResponse
+// @Failure 422 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /v1/auth/webauthn/login/finish [post]
+func (wah *webAuthnHandler) FinishLogin(c echo.Context) error {
+	log := slog.With(
+		slog.String("func", "FinishLogin"),
+		slog.String("handler", "webAuthn"))
+
+	var webAuthnLoginPayLoad domain.WebAuthnLoginPayLoad
+	if err := c.Bind(&webAuthnLoginPayLoad); err != nil {
+		log.Warn("Failed to bind passkey login data to domain")
+		return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
+			Error:     "Unprocessable Entity",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err := webAuthnLoginPayLoad.Validate(); err != nil {
+		log.Warn("Invalid passkey login data")
+		return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
+			Error:     "Unprocessable Entity",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	loginResponse, err := wah.webAuthnService.FinishLogin(c.Request().Context(), webAuthnLoginPayLoad, newClientInfo(c))
+	if err != nil && errors.Is(err, domain.ErrWebAuthnDisabled) {
+		log.Warn("Passkey login unavailable")
+		return c.JSON(http.StatusNotFound, domain.ErrorResponse{
+			Error:     "Not Found",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err != nil && (errors.Is(err, domain.ErrInvalidWebAuthnSession) || errors.Is(err, domain.ErrWebAuthnVerification) ||
+		errors.Is(err, domain.ErrWebAuthnCloneWarning)) {
+		log.Warn("Passkey login refused", slog.Any("error", err))
+		return c.JSON(http.StatusUnauthorized, domain.ErrorResponse{
+			Error:     "Unauthorized",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err != nil && (errors.Is(err, domain.ErrPasswordResetRequired) || errors.Is(err, domain.ErrAccountDeactivated)) {
+		log.Warn("Passkey login refused", slog.Any("error", err))
+		return c.JSON(http.StatusForbidden, domain.ErrorResponse{
+			Error:     "Forbidden",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err != nil && errors.Is(err, domain.ErrTooManySessions) {
+		log.Warn("Session limit reached")
+		return c.JSON(http.StatusConflict, domain.ErrorResponse{
+			Error:     "Conflict",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err != nil && errors.Is(err, domain.ErrAccountSuspended) {
+		log.Warn("Passkey login to a suspended account")
+		return accountSuspended(c, err)
+	}
+
+	if err != nil {
+		log.Error("Error trying to call finish passkey login service.")
+		return c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
+			Error:     "Internal Server Error",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if config.SessionCookie.Enabled {
+		if err := setSessionCookies(c, loginResponse); err != nil {
+			log.Error("Error trying to set session cookies", slog.Any("error", err))
+			return c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
+				Error:     "Internal Server Error",
+				Message:   err.Error(),
+				TimeStamp: time.Now(),
+				Path:      c.Path(),
+			})
+		}
+	}
+
+	log.Info("Passkey login executed successfully")
+	return c.JSON(http.StatusOK, loginResponse)
+}
+
+// GetAll godoc
+// @Summary List passkeys
+// @Description List the passkeys registered by the authenticated user
+// @Tags passkeys
+// @Produce json
+// @Success 200 {array} domain.WebAuthnCredentialResponse
+// @Success 204
+// @Failure 401 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /v1/users/me/webauthn/credentials [get]
+// @Security bearerToken
+func (wah *webAuthnHandler) GetAll(c echo.Context) error {
+	log := slog.With(
+		slog.String("func", "GetAll"),
+		slog.String("handler", "webAuthn"))
+
+	idFromToken, err := util.ExtractUserIdFromToken(c)
+	if err != nil {
+		log.Warn("Error getting user ID from token")
+		return c.JSON(http.StatusUnauthorized, domain.ErrorResponse{
+			Error:     "Unauthorized",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	response, err := wah.webAuthnService.GetAll(idFromToken)
+	if err != nil {
+		log.Error("Error trying to call get passkeys service.")
+		return c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
+			Error:     "Internal Server Error",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	log.Info("Passkeys successfully retrieved")
+
+	if len(response) == 0 {
+		return c.NoContent(http.StatusNoContent)
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+// Rename godoc
+// @Summary Rename a passkey
+// @Description Change the name shown for a passkey of the authenticated user
+// @Tags passkeys
+// @Accept json
+// @Param id path string true "Passkey ID"
+// @Param webAuthnCredentialPayLoad body domain.WebAuthnCredentialPayLoad true "New name"
+// @Success 204
+// @Failure 400 {object} domain.ErrorResponse
+// @Failure 401 {object} domain.ErrorResponse
+// @Failure 404 {object} domain.ErrorResponse
+// @Failure 422 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /v1/users/me/webauthn/credentials/{id} [patch]
+// @Security bearerToken
+func (wah *webAuthnHandler) Rename(c echo.Context) error {
+	log := slog.With(
+		slog.String("func", "Rename"),
+		slog.String("handler", "webAuthn"))
+
+	id := c.Param("id")
+	if err := util.IsValidUUID(id); err != nil {
+		log.Warn("Invalid params")
+		return c.JSON(http.StatusBadRequest, domain.ErrorResponse{
+			Error:     "Bad Request",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	idFromToken, err := util.ExtractUserIdFromToken(c)
+	if err != nil {
+		log.Warn("Error getting user ID from token")
+		return c.JSON(http.StatusUnauthorized, domain.ErrorResponse{
+			Error:     "Unauthorized",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	var webAuthnCredentialPayLoad domain.WebAuthnCredentialPayLoad
+	if err := c.Bind(&webAuthnCredentialPayLoad); err != nil {
+		log.Warn("Failed to bind passkey data to domain")
+		return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
+			Error:     "Unprocessable Entity",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err := webAuthnCredentialPayLoad.Validate(); err != nil {
+		log.Warn("Invalid passkey data")
+		return c.JSON(http.StatusUnprocessableEntity, domain.ErrorResponse{
+			Error:     "Unprocessable Entity",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	err = wah.webAuthnService.Rename(idFromToken, id, webAuthnCredentialPayLoad.Name)
+	if err != nil && errors.Is(err, domain.ErrWebAuthnCredentialNotFound) {
+		log.Warn("Passkey not found to rename")
+		return c.JSON(http.StatusNotFound, domain.ErrorResponse{
+			Error:     "Not Found",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err != nil {
+		log.Error("Error trying to call rename passkey service.")
+		return c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
+			Error:     "Internal Server Error",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	log.Info("Passkey renamed successfully")
+	return c.NoContent(http.StatusNoContent)
+}
+
+// Delete godoc
+// @Summary Delete a passkey
+// @Description Remove a passkey of the authenticated user, it can no longer be used to log in
+// @Tags passkeys
+// @Param id path string true "Passkey ID"
+// @Success 204
+// @Failure 400 {object} domain.ErrorResponse
+// @Failure 401 {object} domain.ErrorResponse
+// @Failure 404 {object} domain.ErrorResponse
+// @Failure 500 {object} domain.ErrorResponse
+// @Router /v1/users/me/webauthn/credentials/{id} [delete]
+// @Security bearerToken
+func (wah *webAuthnHandler) Delete(c echo.Context) error {
+	log := slog.With(
+		slog.String("func", "Delete"),
+		slog.String("handler", "webAuthn"))
+
+	id := c.Param("id")
+	if err := util.IsValidUUID(id); err != nil {
+		log.Warn("Invalid params")
+		return c.JSON(http.StatusBadRequest, domain.ErrorResponse{
+			Error:     "Bad Request",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	idFromToken, err := util.ExtractUserIdFromToken(c)
+	if err != nil {
+		log.Warn("Error getting user ID from token")
+		return c.JSON(http.StatusUnauthorized, domain.ErrorResponse{
+			Error:     "Unauthorized",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	err = wah.webAuthnService.Delete(idFromToken, id)
+	if err != nil && errors.Is(err, domain.ErrWebAuthnCredentialNotFound) {
+		log.Warn("Passkey not found to delete")
+		return c.JSON(http.StatusNotFound, domain.ErrorResponse{
+			Error:     "Not Found",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	if err != nil {
+		log.Error("Error trying to call delete passkey service.")
+		return c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
+			Error:     "Internal Server Error",
+			Message:   err.Error(),
+			TimeStamp: time.Now(),
+			Path:      c.Path(),
+		})
+	}
+
+	log.Info("Passkey deleted successfully")
+	return c.NoContent(http.StatusNoContent)
+}
@@ -0,0 +1,109 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/OVillas/autentication/config"
+	"github.com/OVillas/autentication/domain"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/github"
+)
+
+const (
+	ProviderGitHub = "github"
+
+	gitHubAPIURL = "https://api.github.com"
+)
+
+// gitHubProvider uses plain OAuth2: GitHub issues no ID token, so the identity comes from the
+// user and emails APIs and the nonce is not used.
+type gitHubProvider struct {
+	oauth2Config *oauth2.Config
+}
+
+func newGitHubProvider() *gitHubProvider {
+	return &gitHubProvider{
+		oauth2Config: &oauth2.Config{
+			ClientID:     config.GitHub.ClientID,
+			ClientSecret: config.GitHub.ClientSecret,
+			RedirectURL:  config.GitHub.RedirectURL,
+			Endpoint:     github.Endpoint,
+			Scopes:       []string{"read:user", "user:email"},
+		},
+	}
+}
+
+func (gp *gitHubProvider) AuthCodeURL(ctx context.Context, state string, nonce string, codeVerifier string) (string, error) {
+	return gp.oauth2Config.AuthCodeURL(state, oauth2.S256ChallengeOption(codeVerifier)), nil
+}
+
+// Exchange returns an identity without email when the account has no verified primary email.
+func (gp *gitHubProvider) Exchange(ctx context.Context, code string, codeVerifier string, nonce string) (*domain.ExternalIdentity, error) {
+	token, err := gp.oauth2Config.Exchange(ctx, code, oauth2.VerifierOption(codeVerifier))
+	if err != nil {
+		return nil, err
+	}
+
+	client := gp.oauth2Config.Client(ctx, token)
+
+	var user struct {
+		ID    int64  `json:"id"`
+		Login string `json:"login"`
+		Name  string `json:"name"`
+	}
+	if err := getGitHubResource(ctx, client, "/user", &user); err != nil {
+		return nil, err
+	}
+
+	var emails []struct {
+		Email    string `json:"email"`
+		Primary  bool   `json:"primary"`
+		Verified bool   `json:"verified"`
+	}
+	if err := getGitHubResource(ctx, client, "/user/emails", &emails); err != nil {
+		return nil, err
+	}
+
+	identity := &domain.ExternalIdentity{
+		Provider: ProviderGitHub,
+		Subject:  strconv.FormatInt(user.ID, 10),
+		Name:     user.Name,
+	}
+
+	if identity.Name == "" {
+		identity.Name = user.Login
+	}
+
+	for _, email := range emails {
+		if email.Primary && email.Verified {
+			identity.Email = email.Email
+			identity.EmailVerified = true
+		}
+	}
+
+	return identity, nil
+}
+
+func getGitHubResource(ctx context.Context, client *http.Client, path string, target interface{}) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, gitHubAPIURL+path, nil)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Accept", "application/vnd.github+json")
+
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("github api %s returned status %d", path, response.StatusCode)
+	}
+
+	return json.NewDecoder(response.Body).Decode(target)
+}
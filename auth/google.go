@@ -0,0 +1,16 @@
+package auth
+
+import (
+	"github.com/OVillas/autentication/config"
+)
+
+const ProviderGoogle = "google"
+
+func newGoogleProvider() *oidcProvider {
+	return &oidcProvider{
+		name:   ProviderGoogle,
+		issuer: "https://accounts.google.com",
+		config: config.Google,
+		scopes: []string{"email", "profile"},
+	}
+}
@@ -0,0 +1,19 @@
+package auth
+
+import (
+	"github.com/OVillas/autentication/config"
+	"github.com/OVillas/autentication/domain"
+)
+
+// CreateIDToken issues the OpenID Connect ID token. It is always a JWT signed with the JWT keys,
+// whatever format access tokens use, since relying parties expect to decode it themselves.
+func CreateIDToken(user domain.User) (string, error) {
+	claims := registeredClaims(config.Token.TTL)
+	claims["sub"] = user.ID
+	claims["name"] = user.Name
+	claims["preferred_username"] = user.Username
+	claims["email"] = user.Email
+	claims["email_verified"] = user.EmailConfirmed
+
+	return signToken(claims)
+}
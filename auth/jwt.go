@@ -0,0 +1,171 @@
+package auth
+
+import (
+	"time"
+
+	"github.com/OVillas/autentication/config"
+	"github.com/OVillas/autentication/domain"
+	"github.com/golang-jwt/jwt"
+)
+
+type jwtProvider struct{}
+
+func (jp *jwtProvider) CreateToken(user domain.User, sessionID string) (string, error) {
+	claims := registeredClaims(config.Token.TTL)
+	for key, value := range accessTokenClaims(user, sessionID) {
+		claims[key] = value
+	}
+
+	return signToken(claims)
+}
+
+func (jp *jwtProvider) CreateResetPasswordToken(user domain.User) (string, error) {
+	claims := registeredClaims(domain.ResetPasswordTokenTTL)
+	for key, value := range scopedTokenClaims(user, domain.ScopePasswordReset) {
+		claims[key] = value
+	}
+
+	return signToken(claims)
+}
+
+func (jp *jwtProvider) CreateTwoFactorChallengeToken(user domain.User) (string, error) {
+	claims := registeredClaims(domain.TwoFactorChallengeTTL)
+	for key, value := range scopedTokenClaims(user, domain.ScopeTwoFactorPending) {
+		claims[key] = value
+	}
+
+	return signToken(claims)
+}
+
+func (jp *jwtProvider) CreateEmailVerificationToken(user domain.User) (string, error) {
+	claims := registeredClaims(emailVerificationTokenTTL)
+	for key, value := range scopedTokenClaims(user, domain.ScopeEmailVerification) {
+		claims[key] = value
+	}
+
+	return signToken(claims)
+}
+
+func (jp *jwtProvider) CreateReactivationToken(user domain.User) (string, error) {
+	claims := registeredClaims(domain.ReactivationTokenTTL)
+	for key, value := range scopedTokenClaims(user, domain.ScopeReactivation) {
+		claims[key] = value
+	}
+
+	return signToken(claims)
+}
+
+func (jp *jwtProvider) CreateClientToken(clientID string, scope string) (string, error) {
+	claims := registeredClaims(config.Token.TTL)
+	for key, value := range clientTokenClaims(clientID, scope) {
+		claims[key] = value
+	}
+
+	return signToken(claims)
+}
+
+func (jp *jwtProvider) ParseToken(tokenString string) (*domain.TokenClaims, error) {
+	parser := jwt.Parser{SkipClaimsValidation: true}
+	token, err := parser.Parse(tokenString, getVerificationKey)
+	if err != nil {
+		return nil, domain.ErrInvalidToken
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return nil, domain.ErrInvalidToken
+	}
+
+	if err := validateClaims(claims); err != nil {
+		return nil, err
+	}
+
+	iat, _ := claims["iat"].(float64)
+	exp, _ := claims["exp"].(float64)
+
+	return newTokenClaims(claims, time.Unix(int64(iat), 0), time.Unix(int64(exp), 0)), nil
+}
+
+func registeredClaims(ttl time.Duration) jwt.MapClaims {
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iat": now.Unix(),
+		"nbf": now.Unix(),
+		"exp": now.Add(ttl).Unix(),
+	}
+
+	if config.Token.Issuer != "" {
+		claims["iss"] = config.Token.Issuer
+	}
+
+	if config.Token.Audience != "" {
+		claims["aud"] = config.Token.Audience
+	}
+
+	return claims
+}
+
+// validateClaims replaces the parser's own checks so exp, nbf and iat tolerate the configured
+// clock skew between instances, and iss/aud are enforced when configured.
+func validateClaims(claims jwt.MapClaims) error {
+	now := time.Now()
+	leeway := config.Token.Leeway
+
+	if !claims.VerifyExpiresAt(now.Add(-leeway).Unix(), true) {
+		return domain.ErrTokenExpired
+	}
+
+	if !claims.VerifyNotBefore(now.Add(leeway).Unix(), false) || !claims.VerifyIssuedAt(now.Add(leeway).Unix(), false) {
+		return domain.ErrInvalidToken
+	}
+
+	if config.Token.Issuer != "" && !claims.VerifyIssuer(config.Token.Issuer, true) {
+		return domain.ErrInvalidToken
+	}
+
+	if config.Token.Audience != "" && !claims.VerifyAudience(config.Token.Audience, true) {
+		return domain.ErrInvalidToken
+	}
+
+	return nil
+}
+
+func signToken(claims jwt.MapClaims) (string, error) {
+	if config.JWTSigningMethod == jwt.SigningMethodRS256.Alg() {
+		keys := currentSigningKeys()
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+		token.Header["kid"] = keys.activeKeyID
+		return token.SignedString(keys.privateKey)
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+
+	tokenString, err := token.SignedString([]byte(config.SecretKey))
+	if err != nil {
+		return "", err
+	}
+
+	return tokenString, nil
+}
+
+func getVerificationKey(token *jwt.Token) (interface{}, error) {
+	if token.Method.Alg() != config.JWTSigningMethod {
+		return nil, domain.ErrUnexpectedSigningMethod
+	}
+
+	if _, ok := token.Method.(*jwt.SigningMethodRSA); ok {
+		kid, _ := token.Header["kid"].(string)
+		publicKey, ok := currentSigningKeys().publicKeys[kid]
+		if !ok {
+			return nil, domain.ErrInvalidToken
+		}
+
+		return publicKey, nil
+	}
+
+	if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+		return nil, domain.ErrUnexpectedSigningMethod
+	}
+
+	return config.SecretKey, nil
+}
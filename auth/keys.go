@@ -0,0 +1,102 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"math/big"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/OVillas/autentication/config"
+	"github.com/OVillas/autentication/domain"
+	"github.com/golang-jwt/jwt"
+)
+
+type keySet struct {
+	activeKeyID string
+	privateKey  *rsa.PrivateKey
+	publicKeys  map[string]*rsa.PublicKey
+}
+
+var (
+	signingKeysMutex sync.RWMutex
+	signingKeys      = keySet{publicKeys: map[string]*rsa.PublicKey{}}
+)
+
+// LoadSigningKeys reads the RSA keys used for RS256 tokens. The active private key signs new
+// tokens while every configured public key, including retired ones, is accepted on verification
+// so tokens issued before a rotation stay valid until they expire.
+func LoadSigningKeys() error {
+	if config.JWTSigningMethod != jwt.SigningMethodRS256.Alg() {
+		return nil
+	}
+
+	privateKeyPEM, err := os.ReadFile(config.JWTPrivateKeyPath)
+	if err != nil {
+		return err
+	}
+
+	privateKey, err := jwt.ParseRSAPrivateKeyFromPEM(privateKeyPEM)
+	if err != nil {
+		return err
+	}
+
+	keys := keySet{
+		activeKeyID: config.JWTKeyID,
+		privateKey:  privateKey,
+		publicKeys:  map[string]*rsa.PublicKey{config.JWTKeyID: &privateKey.PublicKey},
+	}
+
+	for _, entry := range config.JWTPublicKeys {
+		kid, path, found := strings.Cut(entry, ":")
+		if !found {
+			continue
+		}
+
+		publicKeyPEM, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		publicKey, err := jwt.ParseRSAPublicKeyFromPEM(publicKeyPEM)
+		if err != nil {
+			return err
+		}
+
+		keys.publicKeys[kid] = publicKey
+	}
+
+	signingKeysMutex.Lock()
+	signingKeys = keys
+	signingKeysMutex.Unlock()
+
+	return nil
+}
+
+func currentSigningKeys() keySet {
+	signingKeysMutex.RLock()
+	defer signingKeysMutex.RUnlock()
+	return signingKeys
+}
+
+// PublicJSONWebKeys returns every public key accepted for verification in JWKS format.
+func PublicJSONWebKeys() []domain.JSONWebKey {
+	keys := currentSigningKeys()
+
+	jwks := make([]domain.JSONWebKey, 0, len(keys.publicKeys))
+	for kid, publicKey := range keys.publicKeys {
+		jwks = append(jwks, domain.JSONWebKey{
+			Kty: "RSA",
+			Use: "sig",
+			Alg: jwt.SigningMethodRS256.Alg(),
+			Kid: kid,
+			N:   base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes()),
+		})
+	}
+
+	sort.Slice(jwks, func(a, b int) bool { return jwks[a].Kid < jwks[b].Kid })
+	return jwks
+}
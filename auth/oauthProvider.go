@@ -0,0 +1,161 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sync"
+
+	"github.com/OVillas/autentication/config"
+	"github.com/OVillas/autentication/domain"
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/samber/do"
+	"golang.org/x/oauth2"
+)
+
+// OAuthProvider signs users in through an external identity provider with the authorization
+// code flow. Implementations must verify the ID token, including the nonce, before returning
+// the identity.
+type OAuthProvider interface {
+	AuthCodeURL(ctx context.Context, state string, nonce string, codeVerifier string) (string, error)
+	Exchange(ctx context.Context, code string, codeVerifier string, nonce string) (*domain.ExternalIdentity, error)
+}
+
+// OAuthProviders holds the configured providers by the name used in the routes.
+type OAuthProviders map[string]OAuthProvider
+
+func NewOAuthProviders(i *do.Injector) (OAuthProviders, error) {
+	providers := OAuthProviders{}
+
+	if config.Google.ClientID != "" {
+		providers[ProviderGoogle] = newGoogleProvider()
+	}
+
+	if config.GitHub.ClientID != "" {
+		providers[ProviderGitHub] = newGitHubProvider()
+	}
+
+	for slug, providerConfig := range config.OIDCProviders {
+		if !providerSlug.MatchString(slug) {
+			return nil, fmt.Errorf("invalid oidc provider slug %q", slug)
+		}
+
+		if _, exists := providers[slug]; exists {
+			return nil, fmt.Errorf("oidc provider %q is already configured", slug)
+		}
+
+		if providerConfig.Issuer == "" || providerConfig.ClientID == "" {
+			return nil, fmt.Errorf("oidc provider %q needs an issuer and a client id", slug)
+		}
+
+		providers[slug] = newOIDCProvider(slug, providerConfig)
+	}
+
+	return providers, nil
+}
+
+var (
+	errNonceMismatch = errors.New("id token nonce does not match")
+	providerSlug     = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+)
+
+// newOIDCProvider builds a provider from an issuer URL alone: endpoints and signing keys come
+// from its discovery document.
+func newOIDCProvider(slug string, providerConfig config.OAuthProviderConfig) *oidcProvider {
+	scopes := providerConfig.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{"email", "profile"}
+	}
+
+	return &oidcProvider{
+		name:   slug,
+		issuer: providerConfig.Issuer,
+		config: providerConfig,
+		scopes: scopes,
+	}
+}
+
+// oidcProvider talks to any OpenID Connect compliant provider. Discovery runs on first use so
+// the API still starts when the provider is unreachable.
+type oidcProvider struct {
+	name     string
+	issuer   string
+	config   config.OAuthProviderConfig
+	scopes   []string
+	mu       sync.Mutex
+	provider *oidc.Provider
+}
+
+func (op *oidcProvider) AuthCodeURL(ctx context.Context, state string, nonce string, codeVerifier string) (string, error) {
+	oauth2Config, _, err := op.oauth2Config(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	return oauth2Config.AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(codeVerifier)), nil
+}
+
+func (op *oidcProvider) Exchange(ctx context.Context, code string, codeVerifier string, nonce string) (*domain.ExternalIdentity, error) {
+	oauth2Config, provider, err := op.oauth2Config(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := oauth2Config.Exchange(ctx, code, oauth2.VerifierOption(codeVerifier))
+	if err != nil {
+		return nil, err
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return nil, errors.New("token response carries no id_token")
+	}
+
+	idToken, err := provider.Verifier(&oidc.Config{ClientID: op.config.ClientID}).Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, err
+	}
+
+	if idToken.Nonce != nonce {
+		return nil, errNonceMismatch
+	}
+
+	var claims struct {
+		Email         string `json:"email"`
+		EmailVerified bool   `json:"email_verified"`
+		Name          string `json:"name"`
+	}
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, err
+	}
+
+	return &domain.ExternalIdentity{
+		Provider:      op.name,
+		Subject:       idToken.Subject,
+		Email:         claims.Email,
+		EmailVerified: claims.EmailVerified,
+		Name:          claims.Name,
+	}, nil
+}
+
+func (op *oidcProvider) oauth2Config(ctx context.Context) (*oauth2.Config, *oidc.Provider, error) {
+	op.mu.Lock()
+	defer op.mu.Unlock()
+
+	if op.provider == nil {
+		provider, err := oidc.NewProvider(ctx, op.issuer)
+		if err != nil {
+			return nil, nil, err
+		}
+		op.provider = provider
+	}
+
+	return &oauth2.Config{
+		ClientID:     op.config.ClientID,
+		ClientSecret: op.config.ClientSecret,
+		RedirectURL:  op.config.RedirectURL,
+		Endpoint:     op.provider.Endpoint(),
+		Scopes:       append([]string{oidc.ScopeOpenID}, op.scopes...),
+	}, op.provider, nil
+}
@@ -0,0 +1,242 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/OVillas/autentication/config"
+	"github.com/go-jose/go-jose/v4"
+	"github.com/golang-jwt/jwt"
+)
+
+const (
+	testClientID     = "test-client"
+	testCode         = "authorization-code"
+	testCodeVerifier = "a code verifier long enough for the pkce rules of the issuer"
+	testNonce        = "test-nonce"
+)
+
+// fakeIssuer is an OpenID Connect provider serving discovery, its JWKS and a token endpoint that
+// answers testCode with an ID token of claims, signed by signingKey.
+type fakeIssuer struct {
+	server     *httptest.Server
+	key        *rsa.PrivateKey
+	signingKey *rsa.PrivateKey
+	claims     jwt.MapClaims
+}
+
+func newFakeIssuer(t *testing.T) *fakeIssuer {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fi := &fakeIssuer{key: key, signingKey: key}
+	fi.server = httptest.NewServer(http.HandlerFunc(fi.serveHTTP))
+	t.Cleanup(fi.server.Close)
+
+	fi.claims = jwt.MapClaims{
+		"iss":            fi.server.URL,
+		"aud":            testClientID,
+		"sub":            "external-subject",
+		"exp":            time.Now().Add(time.Hour).Unix(),
+		"iat":            time.Now().Unix(),
+		"nonce":          testNonce,
+		"email":          "user@example.com",
+		"email_verified": true,
+		"name":           "External User",
+	}
+
+	return fi
+}
+
+func (fi *fakeIssuer) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.URL.Path {
+	case "/.well-known/openid-configuration":
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"issuer":                                fi.server.URL,
+			"authorization_endpoint":                fi.server.URL + "/authorize",
+			"token_endpoint":                        fi.server.URL + "/token",
+			"jwks_uri":                              fi.server.URL + "/jwks",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	case "/jwks":
+		_ = json.NewEncoder(w).Encode(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+			{Key: &fi.key.PublicKey, KeyID: "issuer-key", Algorithm: "RS256", Use: "sig"},
+		}})
+	case "/token":
+		if r.PostFormValue("code") != testCode || r.PostFormValue("code_verifier") != testCodeVerifier {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, fi.claims)
+		token.Header["kid"] = "issuer-key"
+		idToken, err := token.SignedString(fi.signingKey)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": "access-token",
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+			"id_token":     idToken,
+		})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (fi *fakeIssuer) provider() *oidcProvider {
+	return newOIDCProvider("acme", config.OAuthProviderConfig{
+		Issuer:       fi.server.URL,
+		ClientID:     testClientID,
+		ClientSecret: "test-secret",
+		RedirectURL:  "https://api.example.com/v1/auth/acme/callback",
+	})
+}
+
+func TestOIDCProviderAuthCodeURL(t *testing.T) {
+	fi := newFakeIssuer(t)
+
+	authCodeURL, err := fi.provider().AuthCodeURL(context.Background(), "test-state", testNonce, testCodeVerifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasPrefix(authCodeURL, fi.server.URL+"/authorize?") {
+		t.Fatalf("%s is not the authorization endpoint of the issuer", authCodeURL)
+	}
+
+	parsed, err := url.Parse(authCodeURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	query := parsed.Query()
+	for key, want := range map[string]string{
+		"client_id":             testClientID,
+		"state":                 "test-state",
+		"nonce":                 testNonce,
+		"code_challenge_method": "S256",
+		"scope":                 "openid email profile",
+	} {
+		if got := query.Get(key); got != want {
+			t.Errorf("%s = %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestOIDCProviderExchange(t *testing.T) {
+	fi := newFakeIssuer(t)
+
+	identity, err := fi.provider().Exchange(context.Background(), testCode, testCodeVerifier, testNonce)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if identity.Provider != "acme" || identity.Subject != "external-subject" || identity.Email != "user@example.com" ||
+		!identity.EmailVerified || identity.Name != "External User" {
+		t.Errorf("identity = %+v, want the claims of the ID token", identity)
+	}
+}
+
+func TestOIDCProviderExchangeRejectsInvalidIDTokens(t *testing.T) {
+	anotherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		forge func(fi *fakeIssuer)
+	}{
+		{"signed by another key", func(fi *fakeIssuer) { fi.signingKey = anotherKey }},
+		{"for another client", func(fi *fakeIssuer) { fi.claims["aud"] = "another-client" }},
+		{"of another issuer", func(fi *fakeIssuer) { fi.claims["iss"] = "https://issuer.example.com" }},
+		{"expired", func(fi *fakeIssuer) { fi.claims["exp"] = time.Now().Add(-time.Hour).Unix() }},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fi := newFakeIssuer(t)
+			test.forge(fi)
+
+			identity, err := fi.provider().Exchange(context.Background(), testCode, testCodeVerifier, testNonce)
+			if err == nil {
+				t.Errorf("the exchange accepted the ID token: %+v", identity)
+			}
+		})
+	}
+}
+
+func TestOIDCProviderExchangeChecksTheNonce(t *testing.T) {
+	fi := newFakeIssuer(t)
+
+	_, err := fi.provider().Exchange(context.Background(), testCode, testCodeVerifier, "another-nonce")
+	if !errors.Is(err, errNonceMismatch) {
+		t.Errorf("err = %v, want %v", err, errNonceMismatch)
+	}
+}
+
+func TestNewOAuthProviders(t *testing.T) {
+	providers := config.OIDCProviders
+	t.Cleanup(func() { config.OIDCProviders = providers })
+
+	tests := []struct {
+		name      string
+		providers map[string]config.OAuthProviderConfig
+		wantErr   bool
+	}{
+		{"several providers", map[string]config.OAuthProviderConfig{
+			"acme":   {Issuer: "https://acme.example.com", ClientID: "acme-client"},
+			"globex": {Issuer: "https://globex.example.com", ClientID: "globex-client"},
+		}, false},
+		{"invalid slug", map[string]config.OAuthProviderConfig{
+			"Acme Corp": {Issuer: "https://acme.example.com", ClientID: "acme-client"},
+		}, true},
+		{"without issuer", map[string]config.OAuthProviderConfig{
+			"acme": {ClientID: "acme-client"},
+		}, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config.OIDCProviders = test.providers
+
+			configured, err := NewOAuthProviders(nil)
+			if test.wantErr {
+				if err == nil {
+					t.Error("the configuration was accepted")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for slug := range test.providers {
+				if configured[slug] == nil {
+					t.Errorf("provider %s is not configured", slug)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package auth
+
+import (
+	"time"
+
+	"aidanwoods.dev/go-paseto"
+	"github.com/OVillas/autentication/config"
+	"github.com/OVillas/autentication/domain"
+)
+
+// pasetoProvider issues v4.local tokens: encrypted and authenticated with a single symmetric
+// key, with no algorithm header an attacker could tamper with. Other services validate them
+// through the introspection endpoint.
+type pasetoProvider struct {
+	key paseto.V4SymmetricKey
+}
+
+func newPasetoProvider() (*pasetoProvider, error) {
+	key, err := paseto.V4SymmetricKeyFromHex(config.PasetoKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pasetoProvider{key: key}, nil
+}
+
+func (pp *pasetoProvider) CreateToken(user domain.User, sessionID string) (string, error) {
+	return pp.encrypt(accessTokenClaims(user, sessionID), config.Token.TTL)
+}
+
+func (pp *pasetoProvider) CreateResetPasswordToken(user domain.User) (string, error) {
+	return pp.encrypt(scopedTokenClaims(user, domain.ScopePasswordReset), domain.ResetPasswordTokenTTL)
+}
+
+func (pp *pasetoProvider) CreateTwoFactorChallengeToken(user domain.User) (string, error) {
+	return pp.encrypt(scopedTokenClaims(user, domain.ScopeTwoFactorPending), domain.TwoFactorChallengeTTL)
+}
+
+func (pp *pasetoProvider) CreateEmailVerificationToken(user domain.User) (string, error) {
+	return pp.encrypt(scopedTokenClaims(user, domain.ScopeEmailVerification), emailVerificationTokenTTL)
+}
+
+func (pp *pasetoProvider) CreateReactivationToken(user domain.User) (string, error) {
+	return pp.encrypt(scopedTokenClaims(user, domain.ScopeReactivation), domain.ReactivationTokenTTL)
+}
+
+func (pp *pasetoProvider) CreateClientToken(clientID string, scope string) (string, error) {
+	return pp.encrypt(clientTokenClaims(clientID, scope), config.Token.TTL)
+}
+
+func (pp *pasetoProvider) ParseToken(tokenString string) (*domain.TokenClaims, error) {
+	parser := paseto.NewParserWithoutExpiryCheck()
+	token, err := parser.ParseV4Local(pp.key, tokenString, nil)
+	if err != nil {
+		return nil, domain.ErrInvalidToken
+	}
+
+	issuedAt, expiresAt, err := validatePasetoClaims(*token)
+	if err != nil {
+		return nil, err
+	}
+
+	return newTokenClaims(token.Claims(), issuedAt, expiresAt), nil
+}
+
+func (pp *pasetoProvider) encrypt(claims map[string]interface{}, ttl time.Duration) (string, error) {
+	token := paseto.NewToken()
+	for key, value := range claims {
+		if err := token.Set(key, value); err != nil {
+			return "", err
+		}
+	}
+
+	now := time.Now()
+	token.SetIssuedAt(now)
+	token.SetNotBefore(now)
+	token.SetExpiration(now.Add(ttl))
+
+	if config.Token.Issuer != "" {
+		token.SetIssuer(config.Token.Issuer)
+	}
+
+	if config.Token.Audience != "" {
+		token.SetAudience(config.Token.Audience)
+	}
+
+	return token.V4Encrypt(pp.key, nil), nil
+}
+
+// validatePasetoClaims applies the same leeway, issuer and audience rules as the JWT provider.
+func validatePasetoClaims(token paseto.Token) (time.Time, time.Time, error) {
+	now := time.Now()
+	leeway := config.Token.Leeway
+
+	expiresAt, err := token.GetExpiration()
+	if err != nil || !now.Add(-leeway).Before(expiresAt) {
+		return time.Time{}, time.Time{}, domain.ErrTokenExpired
+	}
+
+	issuedAt, err := token.GetIssuedAt()
+	if err != nil || issuedAt.After(now.Add(leeway)) {
+		return time.Time{}, time.Time{}, domain.ErrInvalidToken
+	}
+
+	if notBefore, err := token.GetNotBefore(); err == nil && notBefore.After(now.Add(leeway)) {
+		return time.Time{}, time.Time{}, domain.ErrInvalidToken
+	}
+
+	if issuer, _ := token.GetIssuer(); config.Token.Issuer != "" && issuer != config.Token.Issuer {
+		return time.Time{}, time.Time{}, domain.ErrInvalidToken
+	}
+
+	if audience, _ := token.GetAudience(); config.Token.Audience != "" && audience != config.Token.Audience {
+		return time.Time{}, time.Time{}, domain.ErrInvalidToken
+	}
+
+	return issuedAt, expiresAt, nil
+}
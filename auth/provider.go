@@ -0,0 +1,167 @@
+package auth
+
+import (
+	"time"
+
+	"github.com/OVillas/autentication/config"
+	"github.com/OVillas/autentication/domain"
+	"github.com/google/uuid"
+	"github.com/samber/do"
+)
+
+const (
+	FormatJWT    = "jwt"
+	FormatPaseto = "paseto"
+
+	emailVerificationTokenTTL = 24 * time.Hour
+)
+
+// TokenProvider issues and verifies the tokens handed to clients. Every implementation carries
+// the same claims so the middleware and handlers do not care which format is active, and each
+// one refuses tokens of the other format with domain.ErrInvalidToken.
+type TokenProvider interface {
+	CreateToken(user domain.User, sessionID string) (string, error)
+	CreateResetPasswordToken(user domain.User) (string, error)
+	CreateTwoFactorChallengeToken(user domain.User) (string, error)
+	CreateEmailVerificationToken(user domain.User) (string, error)
+	CreateReactivationToken(user domain.User) (string, error)
+	CreateClientToken(clientID string, scope string) (string, error)
+	ParseToken(token string) (*domain.TokenClaims, error)
+}
+
+func NewTokenProvider(i *do.Injector) (TokenProvider, error) {
+	if config.TokenFormat == FormatPaseto {
+		return newPasetoProvider()
+	}
+
+	return &jwtProvider{}, nil
+}
+
+// ClaimsBuilder lets applications embedding this service add custom claims to access tokens.
+// Keys listed in reservedClaims are ignored so a builder cannot forge identity or lifetime.
+type ClaimsBuilder func(user domain.User) map[string]interface{}
+
+var (
+	claimsBuilders []ClaimsBuilder
+	reservedClaims = map[string]bool{
+		"sub": true, "exp": true, "iss": true, "aud": true, "iat": true, "nbf": true,
+		"jti": true, "id": true, "ver": true, "sid": true, "scope": true, "email_verified": true, "client_id": true,
+		"roles": true, "perms": true, "org": true, "org_role": true, "tenant": true,
+	}
+)
+
+func RegisterClaimsBuilder(builder ClaimsBuilder) {
+	claimsBuilders = append(claimsBuilders, builder)
+}
+
+// accessTokenClaims holds every claim of an access token except the time based ones, which
+// each format encodes its own way.
+func accessTokenClaims(user domain.User, sessionID string) map[string]interface{} {
+	claims := map[string]interface{}{}
+
+	for _, builder := range claimsBuilders {
+		for key, value := range builder(user) {
+			if !reservedClaims[key] {
+				claims[key] = value
+			}
+		}
+	}
+
+	claims["jti"] = uuid.NewString()
+	claims["sub"] = user.ID
+	claims["id"] = user.ID
+	claims["tenant"] = user.TenantID
+	claims["name"] = user.Name
+	claims["username"] = user.Username
+	claims["email"] = user.Email
+	claims["email_verified"] = user.EmailConfirmed
+	claims["ver"] = user.TokenVersion
+
+	if len(user.Roles) > 0 {
+		claims["roles"] = user.Roles
+	}
+
+	if len(user.Permissions) > 0 {
+		claims["perms"] = user.Permissions
+	}
+
+	if sessionID != "" {
+		claims["sid"] = sessionID
+	}
+
+	if user.OrganizationID != "" {
+		claims["org"] = user.OrganizationID
+		claims["org_role"] = user.OrganizationRole
+	}
+
+	return claims
+}
+
+// scopedTokenClaims identify the user like an access token does, but the scope claim keeps the
+// token away from every route other than the one step it was minted for.
+func scopedTokenClaims(user domain.User, scope string) map[string]interface{} {
+	return map[string]interface{}{
+		"jti":    uuid.NewString(),
+		"sub":    user.ID,
+		"id":     user.ID,
+		"tenant": user.TenantID,
+		"ver":    user.TokenVersion,
+		"scope":  scope,
+	}
+}
+
+// clientTokenClaims identify a machine client: there is no user id, sub and client_id both
+// carry the client ID.
+func clientTokenClaims(clientID string, scope string) map[string]interface{} {
+	return map[string]interface{}{
+		"jti":       uuid.NewString(),
+		"sub":       clientID,
+		"client_id": clientID,
+		"scope":     scope,
+	}
+}
+
+func newTokenClaims(claims map[string]interface{}, issuedAt time.Time, expiresAt time.Time) *domain.TokenClaims {
+	id, _ := claims["id"].(string)
+	jti, _ := claims["jti"].(string)
+	clientID, _ := claims["client_id"].(string)
+	tenantID, _ := claims["tenant"].(string)
+	username, _ := claims["username"].(string)
+	scope, _ := claims["scope"].(string)
+	sessionID, _ := claims["sid"].(string)
+	version, _ := claims["ver"].(float64)
+	organizationID, _ := claims["org"].(string)
+	organizationRole, _ := claims["org_role"].(string)
+
+	return &domain.TokenClaims{
+		ID:               jti,
+		UserID:           id,
+		ClientID:         clientID,
+		TenantID:         tenantID,
+		Username:         username,
+		Scope:            scope,
+		SessionID:        sessionID,
+		Version:          int(version),
+		Roles:            stringsClaim(claims, "roles"),
+		Permissions:      stringsClaim(claims, "perms"),
+		OrganizationID:   organizationID,
+		OrganizationRole: organizationRole,
+		IssuedAt:         issuedAt,
+		ExpiresAt:        expiresAt,
+	}
+}
+
+// stringsClaim reads a claim holding a list of strings, which both formats decode as a list of
+// interfaces.
+func stringsClaim(claims map[string]interface{}, key string) []string {
+	var values []string
+	if list, ok := claims[key].([]interface{}); ok {
+		for _, item := range list {
+			if value, ok := item.(string); ok {
+				values = append(values, value)
+			}
+		}
+	}
+
+	return values
+}
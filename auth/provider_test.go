@@ -0,0 +1,84 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/OVillas/autentication/config"
+	"github.com/OVillas/autentication/domain"
+	"github.com/golang-jwt/jwt"
+)
+
+const forged = "forged"
+
+// registerForgingBuilder registers, until the test ends, a claims builder setting every reserved
+// claim to forged, and a custom claim.
+func registerForgingBuilder(t *testing.T) {
+	t.Helper()
+
+	builders := claimsBuilders
+	t.Cleanup(func() { claimsBuilders = builders })
+
+	RegisterClaimsBuilder(func(user domain.User) map[string]interface{} {
+		claims := map[string]interface{}{"department": "sales"}
+		for key := range reservedClaims {
+			claims[key] = forged
+		}
+
+		return claims
+	})
+}
+
+func TestClaimsBuilderCannotOverrideReservedClaims(t *testing.T) {
+	registerForgingBuilder(t)
+
+	user := domain.User{ID: "user-id", TenantID: "tenant", Roles: []string{"admin"}}
+	claims := accessTokenClaims(user, "session-id")
+
+	for key := range reservedClaims {
+		if claims[key] == forged {
+			t.Errorf("the builder overrode the reserved claim %s", key)
+		}
+	}
+
+	if claims["department"] != "sales" {
+		t.Errorf("department = %v, want the custom claim of the builder", claims["department"])
+	}
+}
+
+func TestClaimsBuilderCannotOverrideTheClaimsOfASignedToken(t *testing.T) {
+	registerForgingBuilder(t)
+
+	secretKey, tokenConfig, signingMethod := config.SecretKey, config.Token, config.JWTSigningMethod
+	t.Cleanup(func() {
+		config.SecretKey, config.Token, config.JWTSigningMethod = secretKey, tokenConfig, signingMethod
+	})
+	config.SecretKey = []byte("a secret only the tests sign with")
+	config.Token = config.TokenConfig{TTL: time.Hour, Issuer: "https://auth.example.com"}
+	config.JWTSigningMethod = jwt.SigningMethodHS256.Alg()
+
+	user := domain.User{ID: "user-id", TenantID: "tenant"}
+	token, err := (&jwtProvider{}).CreateToken(user, "session-id")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parsed, err := jwt.Parse(token, func(*jwt.Token) (interface{}, error) { return config.SecretKey, nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims := parsed.Claims.(jwt.MapClaims)
+
+	if claims["sub"] != user.ID {
+		t.Errorf("sub = %v, want %s", claims["sub"], user.ID)
+	}
+
+	if claims["iss"] != config.Token.Issuer {
+		t.Errorf("iss = %v, want %s", claims["iss"], config.Token.Issuer)
+	}
+
+	exp, ok := claims["exp"].(float64)
+	if want := time.Now().Add(config.Token.TTL).Unix(); !ok || int64(exp) < want-5 || int64(exp) > want {
+		t.Errorf("exp = %v, want about %d", claims["exp"], want)
+	}
+}
@@ -0,0 +1,29 @@
+package auth
+
+import (
+	"github.com/OVillas/autentication/config"
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/samber/do"
+)
+
+// NewWebAuthn builds the passkey relying party. Passkeys are off, and a nil instance is
+// provided, until WEBAUTHN_RP_ID is set. Credentials must be discoverable and user verified so
+// a passkey alone, without username or password, is enough to sign in.
+func NewWebAuthn(i *do.Injector) (*webauthn.WebAuthn, error) {
+	if config.WebAuthn.RPID == "" {
+		return nil, nil
+	}
+
+	requireResidentKey := true
+	return webauthn.New(&webauthn.Config{
+		RPID:          config.WebAuthn.RPID,
+		RPDisplayName: config.WebAuthn.RPDisplayName,
+		RPOrigins:     config.WebAuthn.RPOrigins,
+		AuthenticatorSelection: protocol.AuthenticatorSelection{
+			RequireResidentKey: &requireResidentKey,
+			ResidentKey:        protocol.ResidentKeyRequirementRequired,
+			UserVerification:   protocol.VerificationRequired,
+		},
+	})
+}
@@ -1,17 +1,225 @@
 package config
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"log"
+	"net/url"
 	"os"
+	"regexp"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
+	"golang.org/x/text/language"
 )
 
+// SessionCookieConfig drives the opt-in browser mode where the refresh token travels in an
+// HttpOnly cookie instead of the response body.
+type SessionCookieConfig struct {
+	Enabled bool
+	Name    string
+	Domain  string
+	Path    string
+}
+
+const (
+	SessionLimitReject         = "reject"
+	SessionLimitEvictOldest    = "evict_oldest"
+	TokenBindingOff            = "off"
+	TokenBindingWarn           = "warn"
+	TokenBindingReject         = "reject"
+	CodeStoreDatabase          = "database"
+	CodeStoreRedis             = "redis"
+	RateLimitStoreMemory       = "memory"
+	RateLimitStoreRedis        = "redis"
+	UnverifiedCleanupDelete    = "delete"
+	UnverifiedCleanupAnonymize = "anonymize"
+	RegistrationOpen           = "open"
+	RegistrationInviteOnly     = "invite_only"
+	SMSProviderNone            = ""
+	SMSProviderTwilio          = "twilio"
+	SMSProviderLog             = "log"
+	AvatarStorageLocal         = "local"
+	AvatarStorageS3            = "s3"
+	DatabaseMySQL              = "mysql"
+	DatabasePostgres           = "postgres"
+	DatabaseSQLite             = "sqlite"
+	UserCacheMemory            = "memory"
+	UserCacheRedis             = "redis"
+)
+
+// OAuthProviderConfig holds the credentials registered with an external identity provider.
+// A provider without ClientID is disabled. Issuer and Scopes are only read for the generic
+// OpenID Connect providers.
+type OAuthProviderConfig struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+}
+
+// WebAuthnConfig describes this API as a passkey relying party. RPID is the domain passkeys are
+// bound to and RPOrigins the exact origins of the front ends allowed to use them.
+type WebAuthnConfig struct {
+	RPID          string
+	RPDisplayName string
+	RPOrigins     []string
+}
+
+// OTPConfig shapes the one-time codes sent by email and bounds how they can be requested and
+// guessed. Alphanumeric codes use upper case letters and digits that cannot be mistaken for
+// one another.
+type OTPConfig struct {
+	Length         int
+	Alphanumeric   bool
+	TTL            time.Duration
+	MaxAttempts    int
+	ResendInterval time.Duration
+	DailyLimit     int
+}
+
+// PasswordHashingConfig picks the algorithm new password hashes are made with. Argon2Memory is
+// in KiB. Hashes made with another algorithm or other parameters are replaced at login. Pepper,
+// when set, is mixed into every new hash and lives only in the environment, never in the database.
+type PasswordHashingConfig struct {
+	Algorithm     string
+	BcryptCost    int
+	Pepper        []byte
+	Argon2Memory  uint32
+	Argon2Time    uint32
+	Argon2Threads uint8
+}
+
+// PasswordBreachCheckConfig turns on the Have I Been Pwned lookup of new passwords. FailClosed
+// refuses the password when the lookup cannot be completed within Timeout.
+type PasswordBreachCheckConfig struct {
+	Enabled    bool
+	FailClosed bool
+	Timeout    time.Duration
+}
+
+// PasswordPolicyConfig are the rules new passwords must follow. Normalize applies Unicode NFKC
+// before checking and hashing, so the same password typed on different keyboards matches.
+type PasswordPolicyConfig struct {
+	MinLength     int
+	MaxLength     int
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+	Normalize     bool
+}
+
+// UnverifiedCleanupConfig removes, every Interval, the accounts whose email is still not
+// confirmed MaxAge after they were created and that never logged in, BatchSize at a time. Action
+// deletes or anonymizes them, and DryRun only counts them. A zero Interval turns the cleanup off.
+type UnverifiedCleanupConfig struct {
+	Interval  time.Duration
+	MaxAge    time.Duration
+	Action    string
+	BatchSize int
+	DryRun    bool
+}
+
+// UsernamePolicyConfig are the rules usernames must follow. Pattern must match the whole username,
+// and Reserved are the usernames nobody can take.
+type UsernamePolicyConfig struct {
+	MinLength int
+	MaxLength int
+	Pattern   *regexp.Regexp
+	Reserved  []string
+}
+
+// LoginLockoutConfig locks an account for Duration after MaxAttempts wrong passwords in a row.
+// Past DelayAfter failures, each login also waits DelayBase, doubled per failure up to DelayMax.
+type LoginLockoutConfig struct {
+	MaxAttempts int
+	Duration    time.Duration
+	DelayAfter  int
+	DelayBase   time.Duration
+	DelayMax    time.Duration
+}
+
+// RateLimitConfig lets each client IP make Burst requests at once and PerMinute on average.
+type RateLimitConfig struct {
+	PerMinute int
+	Burst     int
+}
+
+// GeoIPConfig points to the service locating the IPs of the new sign-in alerts. URL holds {ip}
+// where the address goes and must answer with the JSON of ip-api.com (city, regionName and
+// country). Alerts leave the location out when URL is empty.
+type GeoIPConfig struct {
+	URL     string
+	Timeout time.Duration
+}
+
+// SMSConfig picks how text messages are sent. Phone numbers cannot be verified with
+// SMSProviderNone. SMSProviderLog only writes the messages to the log, for development. Twilio
+// sends From the account of TwilioAccountSID, waiting up to Timeout for it to answer.
+type SMSConfig struct {
+	Provider         string
+	From             string
+	TwilioAccountSID string
+	TwilioAuthToken  string
+	Timeout          time.Duration
+}
+
+// AvatarConfig says where the avatars go: LocalDir on the disk of the server, served under
+// /avatars, or Bucket of an S3 compatible object storage at Endpoint. PublicURL is the base the
+// URL of a stored avatar starts with. Users without an avatar get FallbackURL, with {hash}
+// replaced by the SHA-256 of their email as Gravatar expects, or none when it is empty.
+type AvatarConfig struct {
+	Storage           string
+	MaxSize           int64
+	LocalDir          string
+	PublicURL         string
+	FallbackURL       string
+	S3Endpoint        string
+	S3Region          string
+	S3Bucket          string
+	S3AccessKeyID     string
+	S3SecretAccessKey string
+}
+
+// MigrationsConfig says what the server does with the migrations not yet applied to the database
+// when it starts: with OnStart it applies them, otherwise it refuses to start unless AllowPending.
+type MigrationsConfig struct {
+	OnStart      bool
+	AllowPending bool
+}
+
+// UserCacheConfig says where the user repository caches the users it looks up, in Store for TTL:
+// nowhere when Store is empty, in an LRU of Size users in process with memory, or in Redis. The
+// memory store of an instance misses the writes of the others, so it only fits a single instance.
+type UserCacheConfig struct {
+	Store string
+	TTL   time.Duration
+	Size  int
+}
+
+type RedisConfig struct {
+	Addr     string
+	Password string
+	DB       int
+}
+
+type TokenConfig struct {
+	TTL      time.Duration
+	Issuer   string
+	Audience string
+	Leeway   time.Duration
+}
+
 var (
 	Port                  = 0
-	MysqlConnectionString = ""
+	DatabaseDriver        = DatabaseMySQL
+	DatabaseDSN           = ""
+	Migrations            MigrationsConfig
 	SecretKey             []byte
 	FrontendURL           = ""
 	EmailSender           = ""
@@ -19,6 +227,73 @@ var (
 	SMTPServer            = ""
 	EmailSenderPassword   = ""
 	EmailSenderName       = ""
+	Token                 = TokenConfig{TTL: 6 * time.Hour, Leeway: 30 * time.Second}
+	RefreshTokenTTL       = 7 * 24 * time.Hour
+	RememberMeTTL         = 30 * 24 * time.Hour
+	SessionMaxLifetime    = 90 * 24 * time.Hour
+	MaxSessions           = 0
+	SessionLimitPolicy    = SessionLimitReject
+	TokenBinding          = TokenBindingOff
+	TrustedProxies        []string
+	TokenFormat           = "jwt"
+	PasetoKey             = ""
+	JWTSigningMethod      = "HS256"
+	JWTPrivateKeyPath     = ""
+	JWTKeyID              = ""
+	JWTPublicKeys         []string
+	ServiceClientID       = ""
+	ServiceClientSecret   = ""
+	AdminKey              = ""
+	BootstrapAdminEmail   = ""
+	SessionCookie         = SessionCookieConfig{Name: "refresh_token", Path: "/v1/auth"}
+	Google                OAuthProviderConfig
+	GitHub                OAuthProviderConfig
+	OIDCProviders         = map[string]OAuthProviderConfig{}
+	EncryptionKey         []byte
+	TOTPIssuer            = "Authentication API"
+	TrustedDeviceTTL      = 30 * 24 * time.Hour
+	WebAuthn              = WebAuthnConfig{RPDisplayName: "Authentication API"}
+	MagicLinkURL          = ""
+	EmailConfirmationLink = false
+	RequireConfirmedEmail = false
+	EmailConfirmationURL  = ""
+	EmailChangeRevertURL  = ""
+	DeletedUserRetention  = 30 * 24 * time.Hour
+	UnverifiedCleanup     = UnverifiedCleanupConfig{Interval: time.Hour, MaxAge: 7 * 24 * time.Hour, Action: UnverifiedCleanupDelete, BatchSize: 100}
+	LoginHistoryRetention = 90 * 24 * time.Hour
+	KnownDeviceRetention  = 90 * 24 * time.Hour
+	SessionsURL           = ""
+	GeoIP                 = GeoIPConfig{Timeout: 2 * time.Second}
+	SMS                   = SMSConfig{Timeout: 5 * time.Second}
+	DefaultLocale         = "pt-BR"
+	DefaultTimezone       = "UTC"
+	Avatar                = AvatarConfig{Storage: AvatarStorageLocal, MaxSize: 1 << 20, LocalDir: "avatars", FallbackURL: "https://www.gravatar.com/avatar/{hash}?d=identicon"}
+	RecentLoginWindow     = 15 * time.Minute
+	DataExportRetention   = 7 * 24 * time.Hour
+	DataExportURL         = ""
+	AccountDeletionGrace  = 14 * 24 * time.Hour
+	CodeStore             = CodeStoreDatabase
+	PasswordHashing       = PasswordHashingConfig{Algorithm: "argon2id", BcryptCost: 10, Argon2Memory: 64 * 1024, Argon2Time: 3, Argon2Threads: 2}
+	PasswordPolicy        = PasswordPolicyConfig{MinLength: 8, MaxLength: 64, Normalize: true}
+	PasswordStrengthLimit = RateLimitConfig{PerMinute: 30, Burst: 30}
+	UsernamePolicy        = UsernamePolicyConfig{MinLength: 3, MaxLength: 75, Pattern: regexp.MustCompile(`^[a-zA-Z0-9_.-]+$`), Reserved: []string{"admin", "administrator", "root", "system", "support", "security", "me", "api", "null", "undefined"}}
+	AuthRateLimit         = RateLimitConfig{PerMinute: 10, Burst: 5}
+	AvailabilityLimit     = RateLimitConfig{PerMinute: 5, Burst: 3}
+	RateLimitStore        = RateLimitStoreMemory
+	UniformRegistration   = false
+	RegistrationMode      = RegistrationOpen
+	InvitationTTL         = 7 * 24 * time.Hour
+	InvitationURL         = ""
+	TermsVersion          = ""
+	DefaultTenant         = "default"
+	TenantHeader          = "X-Tenant-ID"
+	TenantHosts           = map[string]string{}
+	Tenants               []string
+	LoginLockout          = LoginLockoutConfig{MaxAttempts: 5, Duration: 15 * time.Minute, DelayAfter: 3, DelayBase: time.Second, DelayMax: 30 * time.Second}
+	PasswordBreachCheck   = PasswordBreachCheckConfig{Timeout: 2 * time.Second}
+	OTP                   = OTPConfig{Length: 6, TTL: time.Hour, MaxAttempts: 5, ResendInterval: 60 * time.Second, DailyLimit: 10}
+	Redis                 = RedisConfig{Addr: "localhost:6379"}
+	UserCache             = UserCacheConfig{TTL: 30 * time.Second, Size: 10000}
 )
 
 func Load() {
@@ -33,11 +308,39 @@ func Load() {
 		Port = 3030
 	}
 
-	MysqlConnectionString = fmt.Sprintf("%s:%s@/%s?charset=utf8&parseTime=True&loc=Local",
-		os.Getenv("DB_USER"),
-		os.Getenv("DB_PASSWORD"),
-		os.Getenv("DB_NAME"),
-	)
+	if driver := os.Getenv("DB_DRIVER"); driver != "" {
+		if driver != DatabaseMySQL && driver != DatabasePostgres && driver != DatabaseSQLite {
+			panic("DB_DRIVER must be mysql, postgres or sqlite")
+		}
+		DatabaseDriver = driver
+	}
+	DatabaseDSN = os.Getenv("DB_DSN")
+	if DatabaseDSN == "" && DatabaseDriver == DatabasePostgres {
+		DatabaseDSN = (&url.URL{
+			Scheme:   "postgres",
+			User:     url.UserPassword(os.Getenv("DB_USER"), os.Getenv("DB_PASSWORD")),
+			Host:     "localhost",
+			Path:     "/" + os.Getenv("DB_NAME"),
+			RawQuery: "sslmode=disable",
+		}).String()
+	}
+	if DatabaseDSN == "" && DatabaseDriver == DatabaseSQLite {
+		name := os.Getenv("DB_NAME")
+		if name == "" {
+			name = "autentication"
+		}
+		DatabaseDSN = "file:" + name + ".db?_pragma=busy_timeout(5000)"
+	}
+	if DatabaseDSN == "" {
+		DatabaseDSN = fmt.Sprintf("%s:%s@/%s?charset=utf8&parseTime=True&loc=Local",
+			os.Getenv("DB_USER"),
+			os.Getenv("DB_PASSWORD"),
+			os.Getenv("DB_NAME"),
+		)
+	}
+
+	Migrations.OnStart, _ = strconv.ParseBool(os.Getenv("MIGRATE_ON_START"))
+	Migrations.AllowPending, _ = strconv.ParseBool(os.Getenv("ALLOW_PENDING_MIGRATIONS"))
 
 	SecretKey = []byte(os.Getenv("SECRET_KEY"))
 	FrontendURL = os.Getenv("FRONT_END_URL")
@@ -51,4 +354,404 @@ func Load() {
 	EmailSender = os.Getenv("EMAIL_SENDER")
 	EmailSenderPassword = os.Getenv("EMAIL_SENDER_PASSWORD")
 	EmailSenderName = os.Getenv("EMAIL_SENDER_NAME")
+
+	RefreshTokenTTL = durationFromEnv("REFRESH_TOKEN_TTL", RefreshTokenTTL)
+	RememberMeTTL = durationFromEnv("REMEMBER_ME_TTL", RememberMeTTL)
+	SessionMaxLifetime = durationFromEnv("SESSION_MAX_LIFETIME", SessionMaxLifetime)
+
+	MaxSessions, _ = strconv.Atoi(os.Getenv("MAX_SESSIONS"))
+	if os.Getenv("SESSION_LIMIT_POLICY") == SessionLimitEvictOldest {
+		SessionLimitPolicy = SessionLimitEvictOldest
+	}
+
+	switch binding := os.Getenv("TOKEN_BINDING"); binding {
+	case TokenBindingWarn, TokenBindingReject:
+		TokenBinding = binding
+	}
+	TrustedProxies = listFromEnv("TRUSTED_PROXIES")
+
+	Token.TTL = durationFromEnv("ACCESS_TOKEN_TTL", Token.TTL)
+	Token.Issuer = os.Getenv("TOKEN_ISSUER")
+	Token.Audience = os.Getenv("TOKEN_AUDIENCE")
+	Token.Leeway = durationFromEnv("TOKEN_LEEWAY", Token.Leeway)
+
+	if format := os.Getenv("TOKEN_FORMAT"); format != "" {
+		TokenFormat = strings.ToLower(format)
+	}
+	PasetoKey = os.Getenv("PASETO_KEY")
+
+	if method := os.Getenv("JWT_SIGNING_METHOD"); method != "" {
+		JWTSigningMethod = strings.ToUpper(method)
+	}
+	JWTPrivateKeyPath = os.Getenv("JWT_PRIVATE_KEY_PATH")
+	JWTKeyID = os.Getenv("JWT_KEY_ID")
+	JWTPublicKeys = listFromEnv("JWT_PUBLIC_KEYS")
+
+	ServiceClientID = os.Getenv("SERVICE_CLIENT_ID")
+	ServiceClientSecret = os.Getenv("SERVICE_CLIENT_SECRET")
+
+	AdminKey = os.Getenv("ADMIN_KEY")
+	BootstrapAdminEmail = strings.ToLower(strings.TrimSpace(os.Getenv("BOOTSTRAP_ADMIN_EMAIL")))
+
+	SessionCookie.Enabled, _ = strconv.ParseBool(os.Getenv("SESSION_COOKIE_MODE"))
+	SessionCookie.Domain = os.Getenv("SESSION_COOKIE_DOMAIN")
+
+	Google.ClientID = os.Getenv("GOOGLE_CLIENT_ID")
+	Google.ClientSecret = os.Getenv("GOOGLE_CLIENT_SECRET")
+	Google.RedirectURL = os.Getenv("GOOGLE_REDIRECT_URL")
+
+	GitHub.ClientID = os.Getenv("GITHUB_CLIENT_ID")
+	GitHub.ClientSecret = os.Getenv("GITHUB_CLIENT_SECRET")
+	GitHub.RedirectURL = os.Getenv("GITHUB_REDIRECT_URL")
+
+	// Without a dedicated key, secrets stored encrypted fall back to a key derived from SECRET_KEY.
+	derivedKey := sha256.Sum256(SecretKey)
+	EncryptionKey = derivedKey[:]
+	if value := os.Getenv("ENCRYPTION_KEY"); value != "" {
+		EncryptionKey, err = hex.DecodeString(value)
+		if err != nil || len(EncryptionKey) != 32 {
+			panic("ENCRYPTION_KEY must be 32 bytes encoded in hex")
+		}
+	}
+
+	if issuer := os.Getenv("TOTP_ISSUER"); issuer != "" {
+		TOTPIssuer = issuer
+	}
+
+	TrustedDeviceTTL = durationFromEnv("TRUSTED_DEVICE_TTL", TrustedDeviceTTL)
+
+	WebAuthn.RPID = os.Getenv("WEBAUTHN_RP_ID")
+	if name := os.Getenv("WEBAUTHN_RP_NAME"); name != "" {
+		WebAuthn.RPDisplayName = name
+	}
+	WebAuthn.RPOrigins = listFromEnv("WEBAUTHN_RP_ORIGINS")
+	if len(WebAuthn.RPOrigins) == 0 && FrontendURL != "" {
+		WebAuthn.RPOrigins = []string{FrontendURL}
+	}
+
+	if value := os.Getenv("OTP_LENGTH"); value != "" {
+		OTP.Length, err = strconv.Atoi(value)
+		if err != nil || OTP.Length < 4 || OTP.Length > 12 {
+			panic("OTP_LENGTH must be a number between 4 and 12")
+		}
+	}
+	OTP.Alphanumeric, _ = strconv.ParseBool(os.Getenv("OTP_ALPHANUMERIC"))
+	OTP.TTL = durationFromEnv("OTP_TTL", OTP.TTL)
+	if OTP.TTL <= 0 {
+		panic("OTP_TTL must be a positive duration")
+	}
+
+	if attempts, err := strconv.Atoi(os.Getenv("OTP_MAX_ATTEMPTS")); err == nil && attempts > 0 {
+		OTP.MaxAttempts = attempts
+	}
+	OTP.ResendInterval = durationFromEnv("OTP_RESEND_INTERVAL", OTP.ResendInterval)
+	if limit, err := strconv.Atoi(os.Getenv("OTP_DAILY_LIMIT")); err == nil && limit > 0 {
+		OTP.DailyLimit = limit
+	}
+
+	if algorithm := strings.ToLower(os.Getenv("PASSWORD_HASH_ALGORITHM")); algorithm != "" {
+		if algorithm != "argon2id" && algorithm != "bcrypt" {
+			panic("PASSWORD_HASH_ALGORITHM must be argon2id or bcrypt")
+		}
+		PasswordHashing.Algorithm = algorithm
+	}
+	if value := os.Getenv("BCRYPT_COST"); value != "" {
+		PasswordHashing.BcryptCost, err = strconv.Atoi(value)
+		if err != nil || PasswordHashing.BcryptCost < 10 || PasswordHashing.BcryptCost > 15 {
+			panic("BCRYPT_COST must be a number between 10 and 15")
+		}
+	}
+	PasswordHashing.Pepper = []byte(os.Getenv("PASSWORD_PEPPER"))
+	if memory, err := strconv.ParseUint(os.Getenv("ARGON2_MEMORY"), 10, 32); err == nil && memory >= 8 {
+		PasswordHashing.Argon2Memory = uint32(memory)
+	}
+	if iterations, err := strconv.ParseUint(os.Getenv("ARGON2_TIME"), 10, 32); err == nil && iterations > 0 {
+		PasswordHashing.Argon2Time = uint32(iterations)
+	}
+	if threads, err := strconv.ParseUint(os.Getenv("ARGON2_THREADS"), 10, 8); err == nil && threads > 0 {
+		PasswordHashing.Argon2Threads = uint8(threads)
+	}
+
+	if value := os.Getenv("PASSWORD_MIN_LENGTH"); value != "" {
+		PasswordPolicy.MinLength, err = strconv.Atoi(value)
+		if err != nil || PasswordPolicy.MinLength < 1 {
+			panic("PASSWORD_MIN_LENGTH must be a positive number")
+		}
+	}
+	if value := os.Getenv("PASSWORD_MAX_LENGTH"); value != "" {
+		PasswordPolicy.MaxLength, err = strconv.Atoi(value)
+		if err != nil || PasswordPolicy.MaxLength < PasswordPolicy.MinLength {
+			panic("PASSWORD_MAX_LENGTH must be a number not below PASSWORD_MIN_LENGTH")
+		}
+	}
+	PasswordPolicy.RequireUpper, _ = strconv.ParseBool(os.Getenv("PASSWORD_REQUIRE_UPPER"))
+	PasswordPolicy.RequireLower, _ = strconv.ParseBool(os.Getenv("PASSWORD_REQUIRE_LOWER"))
+	PasswordPolicy.RequireDigit, _ = strconv.ParseBool(os.Getenv("PASSWORD_REQUIRE_DIGIT"))
+	PasswordPolicy.RequireSymbol, _ = strconv.ParseBool(os.Getenv("PASSWORD_REQUIRE_SYMBOL"))
+	if normalize, err := strconv.ParseBool(os.Getenv("PASSWORD_NORMALIZE")); err == nil {
+		PasswordPolicy.Normalize = normalize
+	}
+
+	if value := os.Getenv("USERNAME_MIN_LENGTH"); value != "" {
+		UsernamePolicy.MinLength, err = strconv.Atoi(value)
+		if err != nil || UsernamePolicy.MinLength < 1 {
+			panic("USERNAME_MIN_LENGTH must be a positive number")
+		}
+	}
+	if value := os.Getenv("USERNAME_MAX_LENGTH"); value != "" {
+		UsernamePolicy.MaxLength, err = strconv.Atoi(value)
+		if err != nil || UsernamePolicy.MaxLength < UsernamePolicy.MinLength || UsernamePolicy.MaxLength > 255 {
+			panic("USERNAME_MAX_LENGTH must be a number between USERNAME_MIN_LENGTH and 255")
+		}
+	}
+	if value := os.Getenv("USERNAME_PATTERN"); value != "" {
+		UsernamePolicy.Pattern, err = regexp.Compile(`^(?:` + value + `)$`)
+		if err != nil {
+			panic("USERNAME_PATTERN must be a valid regular expression")
+		}
+	}
+	if reserved := listFromEnv("USERNAME_RESERVED"); len(reserved) > 0 {
+		UsernamePolicy.Reserved = reserved
+	}
+
+	if limit, err := strconv.Atoi(os.Getenv("PASSWORD_STRENGTH_RATE_LIMIT")); err == nil && limit > 0 {
+		PasswordStrengthLimit = RateLimitConfig{PerMinute: limit, Burst: limit}
+	}
+	if limit, err := strconv.Atoi(os.Getenv("AVAILABILITY_RATE_LIMIT")); err == nil && limit > 0 {
+		AvailabilityLimit.PerMinute = limit
+	}
+	if limit, err := strconv.Atoi(os.Getenv("AUTH_RATE_LIMIT")); err == nil && limit > 0 {
+		AuthRateLimit.PerMinute = limit
+	}
+	if burst, err := strconv.Atoi(os.Getenv("AUTH_RATE_BURST")); err == nil && burst > 0 {
+		AuthRateLimit.Burst = burst
+	}
+
+	PasswordBreachCheck.Enabled, _ = strconv.ParseBool(os.Getenv("HIBP_CHECK"))
+	PasswordBreachCheck.FailClosed, _ = strconv.ParseBool(os.Getenv("HIBP_FAIL_CLOSED"))
+	PasswordBreachCheck.Timeout = durationFromEnv("HIBP_TIMEOUT", PasswordBreachCheck.Timeout)
+
+	if attempts, err := strconv.Atoi(os.Getenv("LOGIN_MAX_ATTEMPTS")); err == nil && attempts > 0 {
+		LoginLockout.MaxAttempts = attempts
+	}
+	LoginLockout.Duration = durationFromEnv("LOGIN_LOCK_DURATION", LoginLockout.Duration)
+	if after, err := strconv.Atoi(os.Getenv("LOGIN_DELAY_AFTER")); err == nil && after >= 0 {
+		LoginLockout.DelayAfter = after
+	}
+	LoginLockout.DelayBase = durationFromEnv("LOGIN_DELAY_BASE", LoginLockout.DelayBase)
+	LoginLockout.DelayMax = durationFromEnv("LOGIN_DELAY_MAX", LoginLockout.DelayMax)
+
+	UniformRegistration, _ = strconv.ParseBool(os.Getenv("UNIFORM_REGISTRATION"))
+
+	if mode := os.Getenv("REGISTRATION_MODE"); mode != "" {
+		if mode != RegistrationOpen && mode != RegistrationInviteOnly {
+			panic("REGISTRATION_MODE must be open or invite_only")
+		}
+		RegistrationMode = mode
+	}
+	InvitationTTL = durationFromEnv("INVITATION_TTL", InvitationTTL)
+	// The front end page behind the link reads the token and registers with it.
+	InvitationURL = os.Getenv("INVITATION_URL")
+	if InvitationURL == "" {
+		InvitationURL = strings.TrimSuffix(FrontendURL, "/") + "/invitation"
+	}
+	TermsVersion = os.Getenv("TERMS_VERSION")
+
+	// Requests are scoped to the tenant of their header, or else of their host, or else the default.
+	// Only the tenants listed, those of the hosts and the default one are accepted.
+	if tenant := os.Getenv("DEFAULT_TENANT"); tenant != "" {
+		DefaultTenant = tenant
+	}
+	if header := os.Getenv("TENANT_HEADER"); header != "" {
+		TenantHeader = header
+	}
+	Tenants = append(listFromEnv("TENANTS"), DefaultTenant)
+	for _, pair := range listFromEnv("TENANT_HOSTS") {
+		host, tenant, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(host) == "" || strings.TrimSpace(tenant) == "" {
+			panic("TENANT_HOSTS must list host=tenant pairs")
+		}
+		TenantHosts[strings.ToLower(strings.TrimSpace(host))] = strings.TrimSpace(tenant)
+		Tenants = append(Tenants, strings.TrimSpace(tenant))
+	}
+	for _, tenant := range Tenants {
+		if !tenantPattern.MatchString(tenant) {
+			panic("tenants must be 1 to 36 letters, digits, dashes or underscores: " + tenant)
+		}
+	}
+
+	if os.Getenv("CODE_STORE") == CodeStoreRedis {
+		CodeStore = CodeStoreRedis
+	}
+	if os.Getenv("RATE_LIMIT_STORE") == RateLimitStoreRedis {
+		RateLimitStore = RateLimitStoreRedis
+	}
+	if addr := os.Getenv("REDIS_ADDR"); addr != "" {
+		Redis.Addr = addr
+	}
+	Redis.Password = os.Getenv("REDIS_PASSWORD")
+	Redis.DB, _ = strconv.Atoi(os.Getenv("REDIS_DB"))
+
+	if store := os.Getenv("USER_CACHE_STORE"); store != "" {
+		if store != UserCacheMemory && store != UserCacheRedis {
+			panic("USER_CACHE_STORE must be memory or redis")
+		}
+		UserCache.Store = store
+	}
+	UserCache.TTL = durationFromEnv("USER_CACHE_TTL", UserCache.TTL)
+	if UserCache.TTL <= 0 {
+		panic("USER_CACHE_TTL must be positive")
+	}
+	if value := os.Getenv("USER_CACHE_SIZE"); value != "" {
+		UserCache.Size, err = strconv.Atoi(value)
+		if err != nil || UserCache.Size <= 0 {
+			panic("USER_CACHE_SIZE must be a positive number of users")
+		}
+	}
+
+	// The front end page behind the link reads the token and calls the verify endpoint.
+	MagicLinkURL = os.Getenv("MAGIC_LINK_URL")
+	if MagicLinkURL == "" {
+		MagicLinkURL = strings.TrimSuffix(FrontendURL, "/") + "/login/magic-link"
+	}
+
+	EmailConfirmationLink, _ = strconv.ParseBool(os.Getenv("EMAIL_CONFIRMATION_LINK"))
+	RequireConfirmedEmail, _ = strconv.ParseBool(os.Getenv("REQUIRE_CONFIRMED_EMAIL"))
+	EmailConfirmationURL = os.Getenv("EMAIL_CONFIRMATION_URL")
+	if EmailConfirmationURL == "" {
+		EmailConfirmationURL = strings.TrimSuffix(FrontendURL, "/") + "/confirm-email"
+	}
+
+	EmailChangeRevertURL = os.Getenv("EMAIL_CHANGE_REVERT_URL")
+	if EmailChangeRevertURL == "" {
+		EmailChangeRevertURL = strings.TrimSuffix(FrontendURL, "/") + "/email/revert"
+	}
+
+	DeletedUserRetention = durationFromEnv("DELETED_USER_RETENTION", DeletedUserRetention)
+	AccountDeletionGrace = durationFromEnv("ACCOUNT_DELETION_GRACE", AccountDeletionGrace)
+	LoginHistoryRetention = durationFromEnv("LOGIN_HISTORY_RETENTION", LoginHistoryRetention)
+	KnownDeviceRetention = durationFromEnv("KNOWN_DEVICE_RETENTION", KnownDeviceRetention)
+
+	UnverifiedCleanup.Interval = durationFromEnv("UNVERIFIED_CLEANUP_INTERVAL", UnverifiedCleanup.Interval)
+	UnverifiedCleanup.MaxAge = durationFromEnv("UNVERIFIED_CLEANUP_AGE", UnverifiedCleanup.MaxAge)
+	if action := os.Getenv("UNVERIFIED_CLEANUP_ACTION"); action != "" {
+		if action != UnverifiedCleanupDelete && action != UnverifiedCleanupAnonymize {
+			panic("UNVERIFIED_CLEANUP_ACTION must be delete or anonymize")
+		}
+		UnverifiedCleanup.Action = action
+	}
+	if size, err := strconv.Atoi(os.Getenv("UNVERIFIED_CLEANUP_BATCH_SIZE")); err == nil && size > 0 {
+		UnverifiedCleanup.BatchSize = size
+	}
+	UnverifiedCleanup.DryRun, _ = strconv.ParseBool(os.Getenv("UNVERIFIED_CLEANUP_DRY_RUN"))
+
+	SessionsURL = os.Getenv("SESSIONS_URL")
+	if SessionsURL == "" {
+		SessionsURL = strings.TrimSuffix(FrontendURL, "/") + "/sessions"
+	}
+
+	GeoIP.URL = os.Getenv("GEOIP_URL")
+	GeoIP.Timeout = durationFromEnv("GEOIP_TIMEOUT", GeoIP.Timeout)
+
+	SMS.Provider = os.Getenv("SMS_PROVIDER")
+	if SMS.Provider != SMSProviderNone && SMS.Provider != SMSProviderTwilio && SMS.Provider != SMSProviderLog {
+		panic("SMS_PROVIDER must be twilio or log")
+	}
+	SMS.From = os.Getenv("SMS_FROM")
+	SMS.TwilioAccountSID = os.Getenv("TWILIO_ACCOUNT_SID")
+	SMS.TwilioAuthToken = os.Getenv("TWILIO_AUTH_TOKEN")
+	if SMS.Provider == SMSProviderTwilio && (SMS.From == "" || SMS.TwilioAccountSID == "" || SMS.TwilioAuthToken == "") {
+		panic("SMS_FROM, TWILIO_ACCOUNT_SID and TWILIO_AUTH_TOKEN are required to send SMS with twilio")
+	}
+	SMS.Timeout = durationFromEnv("SMS_TIMEOUT", SMS.Timeout)
+
+	if locale := os.Getenv("DEFAULT_LOCALE"); locale != "" {
+		tag, err := language.Parse(locale)
+		if err != nil {
+			panic("DEFAULT_LOCALE must be a BCP 47 language tag, such as pt-BR or en")
+		}
+		DefaultLocale = tag.String()
+	}
+	if timezone := os.Getenv("DEFAULT_TIMEZONE"); timezone != "" {
+		if _, err := time.LoadLocation(timezone); err != nil {
+			panic("DEFAULT_TIMEZONE must be an IANA time zone name, such as America/Sao_Paulo")
+		}
+		DefaultTimezone = timezone
+	}
+
+	if storage := os.Getenv("AVATAR_STORAGE"); storage != "" {
+		if storage != AvatarStorageLocal && storage != AvatarStorageS3 {
+			panic("AVATAR_STORAGE must be local or s3")
+		}
+		Avatar.Storage = storage
+	}
+	if value := os.Getenv("AVATAR_MAX_SIZE"); value != "" {
+		Avatar.MaxSize, err = strconv.ParseInt(value, 10, 64)
+		if err != nil || Avatar.MaxSize <= 0 {
+			panic("AVATAR_MAX_SIZE must be a positive number of bytes")
+		}
+	}
+	if dir := os.Getenv("AVATAR_DIR"); dir != "" {
+		Avatar.LocalDir = dir
+	}
+	if fallbackURL, ok := os.LookupEnv("AVATAR_FALLBACK_URL"); ok {
+		Avatar.FallbackURL = fallbackURL
+	}
+	Avatar.S3Endpoint = strings.TrimSuffix(os.Getenv("S3_ENDPOINT"), "/")
+	Avatar.S3Region = os.Getenv("S3_REGION")
+	Avatar.S3Bucket = os.Getenv("S3_BUCKET")
+	Avatar.S3AccessKeyID = os.Getenv("S3_ACCESS_KEY_ID")
+	Avatar.S3SecretAccessKey = os.Getenv("S3_SECRET_ACCESS_KEY")
+	if Avatar.Storage == AvatarStorageS3 && (Avatar.S3Endpoint == "" || Avatar.S3Region == "" || Avatar.S3Bucket == "" ||
+		Avatar.S3AccessKeyID == "" || Avatar.S3SecretAccessKey == "") {
+		panic("S3_ENDPOINT, S3_REGION, S3_BUCKET, S3_ACCESS_KEY_ID and S3_SECRET_ACCESS_KEY are required to store avatars in s3")
+	}
+	Avatar.PublicURL = strings.TrimSuffix(os.Getenv("AVATAR_PUBLIC_URL"), "/")
+	if Avatar.PublicURL == "" && Avatar.Storage == AvatarStorageS3 {
+		Avatar.PublicURL = Avatar.S3Endpoint + "/" + Avatar.S3Bucket
+	}
+	if Avatar.PublicURL == "" {
+		Avatar.PublicURL = "/avatars"
+	}
+
+	RecentLoginWindow = durationFromEnv("RECENT_LOGIN_WINDOW", RecentLoginWindow)
+	DataExportRetention = durationFromEnv("DATA_EXPORT_RETENTION", DataExportRetention)
+	DataExportURL = os.Getenv("DATA_EXPORT_URL")
+	if DataExportURL == "" {
+		DataExportURL = strings.TrimSuffix(FrontendURL, "/") + "/account/export"
+	}
+
+	for _, slug := range listFromEnv("OIDC_PROVIDERS") {
+		slug = strings.ToLower(slug)
+		prefix := "OIDC_" + strings.ToUpper(strings.ReplaceAll(slug, "-", "_")) + "_"
+		OIDCProviders[slug] = OAuthProviderConfig{
+			Issuer:       os.Getenv(prefix + "ISSUER"),
+			ClientID:     os.Getenv(prefix + "CLIENT_ID"),
+			ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+			RedirectURL:  os.Getenv(prefix + "REDIRECT_URL"),
+			Scopes:       listFromEnv(prefix + "SCOPES"),
+		}
+	}
+}
+
+var tenantPattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,36}$`)
+
+func listFromEnv(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+
+	return values
+}
+
+func durationFromEnv(key string, fallback time.Duration) time.Duration {
+	value, err := time.ParseDuration(os.Getenv(key))
+	if err != nil {
+		return fallback
+	}
+
+	return value
 }
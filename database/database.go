@@ -0,0 +1,122 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/OVillas/autentication/config"
+	"github.com/glebarez/sqlite"
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// NewConnection connects to the database of config.DatabaseDriver. The DSN is checked against the
+// driver first, so that the DSN of another database fails at startup with a clear error.
+func NewConnection() (*gorm.DB, error) {
+	if err := checkDSN(config.DatabaseDriver, config.DatabaseDSN); err != nil {
+		return nil, err
+	}
+
+	var dialector gorm.Dialector
+	switch config.DatabaseDriver {
+	case config.DatabasePostgres:
+		dialector = postgresDialector{postgres.Dialector{Config: &postgres.Config{DSN: config.DatabaseDSN}}}
+	case config.DatabaseSQLite:
+		dialector = sqlite.Open(config.DatabaseDSN)
+	default:
+		dsn, err := ansiQuotes(config.DatabaseDSN)
+		if err != nil {
+			return nil, err
+		}
+		dialector = mysql.Open(dsn)
+	}
+
+	db, err := gorm.Open(dialector, &gorm.Config{TranslateError: true})
+	if err != nil {
+		return nil, err
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+
+	// SQLite writes one transaction at a time, and an in-memory database refuses a second one as
+	// locked instead of waiting for it. A single connection queues them, and keeps the in-memory
+	// database alive, as it is dropped with its last connection.
+	if config.DatabaseDriver == config.DatabaseSQLite {
+		sqlDB.SetMaxOpenConns(1)
+	}
+
+	if err := sqlDB.Ping(); err != nil {
+		_ = sqlDB.Close()
+		return nil, err
+	}
+
+	return db, err
+}
+
+var settingName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.]*$`)
+
+func checkDSN(driver string, dsn string) error {
+	switch driver {
+	case config.DatabasePostgres:
+		pgConfig, err := pgconn.ParseConfig(dsn)
+		if err != nil {
+			return fmt.Errorf("invalid postgres DSN: %w", err)
+		}
+
+		// Unknown keys are passed on as settings of the session, so a MySQL DSN such as
+		// "user:password@/name" parses as one; no setting has such a name.
+		for key := range pgConfig.RuntimeParams {
+			if !settingName.MatchString(key) {
+				return fmt.Errorf("invalid postgres DSN: unknown setting %q", key)
+			}
+		}
+	case config.DatabaseMySQL:
+		if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+			return fmt.Errorf("invalid mysql DSN: it is a postgres URL, set DB_DRIVER=%s", config.DatabasePostgres)
+		}
+
+		if _, err := mysqldriver.ParseDSN(dsn); err != nil {
+			return fmt.Errorf("invalid mysql DSN: %w", err)
+		}
+	case config.DatabaseSQLite:
+		// The DSN of SQLite is a file name, optionally a file: URI, which any string may be: only
+		// the DSNs of the database servers are refused.
+		if strings.Contains(dsn, "@") || (strings.Contains(dsn, "://") && !strings.HasPrefix(dsn, "file:")) {
+			return errors.New("invalid sqlite DSN: it is the address of a database server, not a file")
+		}
+	default:
+		return fmt.Errorf("unknown database driver %q", driver)
+	}
+
+	return nil
+}
+
+// ansiQuotes adds ANSI_QUOTES to the sql_mode of the MySQL sessions, so that MySQL reads double
+// quotes as identifiers like Postgres and SQLite do. The queries quote the PascalCase columns that
+// way on every database, as Postgres would fold them to lower case unquoted.
+func ansiQuotes(dsn string) (string, error) {
+	dsnConfig, err := mysqldriver.ParseDSN(dsn)
+	if err != nil {
+		return "", err
+	}
+
+	sqlMode := "@@sql_mode"
+	if mode, ok := dsnConfig.Params["sql_mode"]; ok {
+		sqlMode = mode
+	}
+
+	if dsnConfig.Params == nil {
+		dsnConfig.Params = map[string]string{}
+	}
+	dsnConfig.Params["sql_mode"] = fmt.Sprintf("CONCAT(%s, ',ANSI_QUOTES')", sqlMode)
+
+	return dsnConfig.FormatDSN(), nil
+}
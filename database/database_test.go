@@ -0,0 +1,40 @@
+package database
+
+import (
+	"testing"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+)
+
+func TestANSIQuotes(t *testing.T) {
+	tests := []struct {
+		name    string
+		dsn     string
+		sqlMode string
+	}{
+		{"keeps the server mode", "user:password@tcp(localhost:3306)/autentication?parseTime=True", "CONCAT(@@sql_mode, ',ANSI_QUOTES')"},
+		{"keeps the mode of the DSN", "user:password@/autentication?sql_mode='TRADITIONAL'", "CONCAT('TRADITIONAL', ',ANSI_QUOTES')"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dsn, err := ansiQuotes(test.dsn)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			dsnConfig, err := mysqldriver.ParseDSN(dsn)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := dsnConfig.Params["sql_mode"]; got != test.sqlMode {
+				t.Errorf("sql_mode = %q, want %q", got, test.sqlMode)
+			}
+
+			if dsnConfig.DBName != "autentication" || dsnConfig.User != "user" || dsnConfig.Passwd != "password" {
+				t.Errorf("the DSN lost its database or credentials: %s", dsn)
+			}
+		})
+	}
+}
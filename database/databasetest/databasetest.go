@@ -0,0 +1,43 @@
+// Package databasetest connects tests to a migrated database. By default it is an in-memory
+// SQLite database, so the tests need no server. Built with the mysql or postgres tag, it is the
+// database of TEST_DB_DSN instead, which the tests share: they create rows under ids and tenants of
+// their own, so that database does not have to be empty.
+package databasetest
+
+import (
+	"sync"
+
+	"github.com/OVillas/autentication/config"
+	"github.com/OVillas/autentication/database"
+	"github.com/OVillas/autentication/database/migrations"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+var (
+	once sync.Once
+	db   *gorm.DB
+	err  error
+)
+
+// Open returns the database of the tests, connected and migrated by the first call.
+func Open() (*gorm.DB, error) {
+	once.Do(func() {
+		config.DatabaseDriver = driver
+		config.DatabaseDSN, err = dsn()
+		if err != nil {
+			return
+		}
+
+		db, err = database.NewConnection()
+		if err != nil {
+			return
+		}
+
+		// The tests look up missing rows on purpose, which gorm would log as errors.
+		db.Logger = logger.Default.LogMode(logger.Silent)
+		err = migrations.Run(db)
+	})
+
+	return db, err
+}
@@ -0,0 +1,21 @@
+//go:build mysql
+
+package databasetest
+
+import (
+	"errors"
+	"os"
+
+	"github.com/OVillas/autentication/config"
+)
+
+const driver = config.DatabaseMySQL
+
+func dsn() (string, error) {
+	dsn := os.Getenv("TEST_DB_DSN")
+	if dsn == "" {
+		return "", errors.New("the mysql tests need TEST_DB_DSN, the DSN of a database they may write to")
+	}
+
+	return dsn, nil
+}
@@ -0,0 +1,21 @@
+//go:build postgres
+
+package databasetest
+
+import (
+	"errors"
+	"os"
+
+	"github.com/OVillas/autentication/config"
+)
+
+const driver = config.DatabasePostgres
+
+func dsn() (string, error) {
+	dsn := os.Getenv("TEST_DB_DSN")
+	if dsn == "" {
+		return "", errors.New("the postgres tests need TEST_DB_DSN, the DSN of a database they may write to")
+	}
+
+	return dsn, nil
+}
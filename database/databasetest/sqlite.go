@@ -0,0 +1,11 @@
+//go:build !mysql && !postgres
+
+package databasetest
+
+import "github.com/OVillas/autentication/config"
+
+const driver = config.DatabaseSQLite
+
+func dsn() (string, error) {
+	return "file::memory:?cache=shared", nil
+}
@@ -0,0 +1,45 @@
+package migrations
+
+import (
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// caseCollision is a username or email held, in different casing or spacing, by more than one user.
+type caseCollision struct {
+	Value string `gorm:"column:Value"`
+	Ids   string `gorm:"column:Ids"`
+}
+
+// normalizeUserIdentifiers lowercases and trims the stored usernames and emails, so the unique
+// indexes on them become case-insensitive. Users whose values collide once normalized are only
+// reported: merging accounts is not a call a migration can make, so they must be renamed by hand
+// before it runs again.
+func normalizeUserIdentifiers(db *gorm.DB) error {
+	if !db.Migrator().HasTable("user") {
+		return nil
+	}
+
+	var report []string
+	for _, column := range []string{"Username", "Email"} {
+		var collisions []caseCollision
+		err := db.Raw(fmt.Sprintf(`SELECT LOWER(TRIM("%[1]s")) AS "Value", GROUP_CONCAT("Id") AS "Ids" FROM "user" `+
+			`GROUP BY LOWER(TRIM("%[1]s")) HAVING COUNT(*) > 1`, column)).Scan(&collisions).Error
+		if err != nil {
+			return err
+		}
+
+		for _, collision := range collisions {
+			report = append(report, fmt.Sprintf("%s %q is held by users %s", column, collision.Value, collision.Ids))
+		}
+	}
+
+	if len(report) > 0 {
+		return fmt.Errorf("%d usernames or emails differ only in case, rename them and migrate again: %s",
+			len(report), strings.Join(report, "; "))
+	}
+
+	return db.Exec(`UPDATE "user" SET "Username" = LOWER(TRIM("Username")), "Email" = LOWER(TRIM("Email"))`).Error
+}
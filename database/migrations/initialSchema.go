@@ -0,0 +1,497 @@
+package migrations
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/OVillas/autentication/config"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// initialSchema creates the tables of version 1. On a database created by an older release, which
+// ran on MySQL, it first brings the existing tables up to date, so that it also serves as their
+// baseline. The tables are those of the models below, copies of the domain models as they were at
+// version 1: a change to a domain model does not change them, it needs a migration of its own.
+func initialSchema(tx *gorm.DB) error {
+	if config.DatabaseDriver == config.DatabaseMySQL {
+		if err := normalizeUserIdentifiers(tx); err != nil {
+			return fmt.Errorf("normalize usernames and emails: %w", err)
+		}
+
+		if err := renameUserUpdatedAt(tx); err != nil {
+			return fmt.Errorf("rename the UpdateAt column of the users: %w", err)
+		}
+
+		if err := backfillTenants(tx); err != nil {
+			return fmt.Errorf("move the existing rows into the default tenant: %w", err)
+		}
+	}
+
+	return tx.AutoMigrate(
+		&userV1{},
+		&refreshTokenV1{},
+		&revokedTokenV1{},
+		&personalAccessTokenV1{},
+		&apiKeyV1{},
+		&oauthClientV1{},
+		&authorizationCodeV1{},
+		&oauthStateV1{},
+		&userIdentityV1{},
+		&recoveryCodeV1{},
+		&trustedDeviceV1{},
+		&webAuthnCredentialV1{},
+		&webAuthnSessionV1{},
+		&magicLinkV1{},
+		&confirmationCodeV1{},
+		&confirmationCodeSendV1{},
+		&pendingEmailV1{},
+		&roleV1{},
+		&userRoleV1{},
+		&permissionV1{},
+		&rolePermissionV1{},
+		&auditEntryV1{},
+		&loginAttemptV1{},
+		&knownDeviceV1{},
+		&dataExportV1{},
+		&invitationV1{},
+		&organizationV1{},
+		&membershipV1{},
+		&policyAcceptanceV1{},
+	)
+}
+
+// jsonColumn is the type of the JSON columns: JSONB on Postgres, where it can be indexed, and JSON
+// elsewhere.
+type jsonColumn []byte
+
+func (jsonColumn) GormDBDataType(db *gorm.DB, field *schema.Field) string {
+	if db.Dialector.Name() == "postgres" {
+		return "JSONB"
+	}
+
+	return "JSON"
+}
+
+type userV1 struct {
+	ID                  string         `gorm:"column:Id;type:char(36);primary_key;index:idx_user_created_at_id,priority:2"`
+	TenantID            string         `gorm:"column:TenantId;type:varchar(36);uniqueIndex:idx_user_tenant_username,priority:1;uniqueIndex:idx_user_tenant_email,priority:1;uniqueIndex:idx_user_tenant_phone,priority:1"`
+	Name                string         `gorm:"column:Name;type:varchar(75)"`
+	Username            string         `gorm:"column:Username;type:varchar(255);uniqueIndex:idx_user_tenant_username,priority:2"`
+	Email               string         `gorm:"column:Email;type:varchar(255);uniqueIndex:idx_user_tenant_email,priority:2"`
+	PhoneNumber         *string        `gorm:"column:PhoneNumber;type:varchar(16);uniqueIndex:idx_user_tenant_phone,priority:2"`
+	Password            string         `gorm:"column:PasswordHash;type:varchar(255)"`
+	EmailConfirmed      bool           `gorm:"column:EmailConfirmed;type:boolean"`
+	PhoneConfirmed      bool           `gorm:"column:PhoneConfirmed;type:boolean;default:false"`
+	TwoFactorAuthActive bool           `gorm:"column:TwoFactorAuthActive;type:boolean"`
+	TOTPSecret          string         `gorm:"column:TotpSecret;type:varchar(255)"`
+	Active              bool           `gorm:"column:Active;type:boolean;default:true"`
+	TokenVersion        int            `gorm:"column:TokenVersion;default:0"`
+	FailedLoginAttempts int            `gorm:"column:FailedLoginAttempts;default:0"`
+	LastFailedLoginAt   *time.Time     `gorm:"column:LastFailedLoginAt"`
+	LockedUntil         *time.Time     `gorm:"column:LockedUntil"`
+	MustResetPassword   bool           `gorm:"column:MustResetPassword;type:boolean;default:false"`
+	LoginAlertsEnabled  bool           `gorm:"column:LoginAlertsEnabled;type:boolean;default:true"`
+	Locale              string         `gorm:"column:Locale;type:varchar(35)"`
+	Timezone            string         `gorm:"column:Timezone;type:varchar(64)"`
+	AvatarURL           *string        `gorm:"column:AvatarUrl;type:varchar(512)"`
+	Metadata            jsonColumn     `gorm:"column:Metadata"`
+	SuspendedAt         *time.Time     `gorm:"column:SuspendedAt"`
+	SuspendedUntil      *time.Time     `gorm:"column:SuspendedUntil"`
+	LastLoginAt         *time.Time     `gorm:"column:LastLoginAt;index"`
+	CreatedAt           time.Time      `gorm:"column:CreatedAt;autoCreateTime;index:idx_user_created_at_id,priority:1"`
+	UpdatedAt           time.Time      `gorm:"column:UpdatedAt;autoUpdateTime"`
+	DeletionScheduledAt *time.Time     `gorm:"column:DeletionScheduledAt;index"`
+	DeletionRemindedAt  *time.Time     `gorm:"column:DeletionRemindedAt"`
+	AnonymizedAt        *time.Time     `gorm:"column:AnonymizedAt"`
+	DeletedAt           gorm.DeletedAt `gorm:"column:DeletedAt;index"`
+}
+
+func (userV1) TableName() string {
+	return "user"
+}
+
+type refreshTokenV1 struct {
+	ID             string     `gorm:"column:Id;type:char(36);primary_key"`
+	UserID         string     `gorm:"column:UserId;type:char(36);index;index:idx_refresh_token_active,priority:1"`
+	TenantID       string     `gorm:"column:TenantId;type:varchar(36)"`
+	FamilyID       string     `gorm:"column:FamilyId;type:char(36);index"`
+	TokenHash      string     `gorm:"column:TokenHash;type:char(64);uniqueIndex"`
+	ReplacedBy     string     `gorm:"column:ReplacedBy;type:char(36)"`
+	Persistent     bool       `gorm:"column:Persistent;default:false"`
+	UserAgent      string     `gorm:"column:UserAgent;type:varchar(512)"`
+	IP             string     `gorm:"column:Ip;type:varchar(45)"`
+	IPPrefix       string     `gorm:"column:IpPrefix;type:varchar(49)"`
+	UAHash         string     `gorm:"column:UserAgentHash;type:char(64)"`
+	ExpiresAt      time.Time  `gorm:"column:ExpiresAt;index:idx_refresh_token_active,priority:3"`
+	MaxExpiry      time.Time  `gorm:"column:MaxExpiry"`
+	RevokedAt      *time.Time `gorm:"column:RevokedAt;index:idx_refresh_token_active,priority:2"`
+	SessionAt      time.Time  `gorm:"column:SessionAt"`
+	OrganizationID string     `gorm:"column:OrganizationId;type:char(36)"`
+	CreatedAt      time.Time  `gorm:"column:CreatedAt"`
+}
+
+func (refreshTokenV1) TableName() string {
+	return "refresh_token"
+}
+
+type revokedTokenV1 struct {
+	JTI       string    `gorm:"column:Jti;type:char(36);primary_key"`
+	ExpiresAt time.Time `gorm:"column:ExpiresAt;index"`
+	CreatedAt time.Time `gorm:"column:CreatedAt"`
+}
+
+func (revokedTokenV1) TableName() string {
+	return "revoked_token"
+}
+
+type personalAccessTokenV1 struct {
+	ID         string     `gorm:"column:Id;type:char(36);primary_key"`
+	UserID     string     `gorm:"column:UserId;type:char(36);index"`
+	Name       string     `gorm:"column:Name;type:varchar(100)"`
+	TokenHash  string     `gorm:"column:TokenHash;type:char(64);uniqueIndex"`
+	Scopes     string     `gorm:"column:Scopes;type:varchar(255)"`
+	ExpiresAt  *time.Time `gorm:"column:ExpiresAt"`
+	LastUsedAt *time.Time `gorm:"column:LastUsedAt"`
+	RevokedAt  *time.Time `gorm:"column:RevokedAt"`
+	CreatedAt  time.Time  `gorm:"column:CreatedAt"`
+}
+
+func (personalAccessTokenV1) TableName() string {
+	return "personal_access_token"
+}
+
+type apiKeyV1 struct {
+	ID               string     `gorm:"column:Id;type:char(36);primary_key"`
+	Name             string     `gorm:"column:Name;type:varchar(100)"`
+	SecretHash       string     `gorm:"column:SecretHash;type:char(64);uniqueIndex"`
+	AllowedEndpoints string     `gorm:"column:AllowedEndpoints;type:varchar(1024)"`
+	LastUsedAt       *time.Time `gorm:"column:LastUsedAt"`
+	RevokedAt        *time.Time `gorm:"column:RevokedAt"`
+	CreatedAt        time.Time  `gorm:"column:CreatedAt"`
+}
+
+func (apiKeyV1) TableName() string {
+	return "api_key"
+}
+
+type oauthClientV1 struct {
+	ID           string    `gorm:"column:Id;type:char(36);primary_key"`
+	Name         string    `gorm:"column:Name;type:varchar(100)"`
+	SecretHash   string    `gorm:"column:SecretHash;type:char(64)"`
+	RedirectURIs string    `gorm:"column:RedirectUris;type:varchar(2048)"`
+	Scopes       string    `gorm:"column:Scopes;type:varchar(255)"`
+	Public       bool      `gorm:"column:Public;default:false"`
+	CreatedAt    time.Time `gorm:"column:CreatedAt"`
+}
+
+func (oauthClientV1) TableName() string {
+	return "oauth_client"
+}
+
+type authorizationCodeV1 struct {
+	CodeHash      string     `gorm:"column:CodeHash;type:char(64);primary_key"`
+	ClientID      string     `gorm:"column:ClientId;type:char(36)"`
+	UserID        string     `gorm:"column:UserId;type:char(36);index"`
+	RedirectURI   string     `gorm:"column:RedirectUri;type:varchar(512)"`
+	CodeChallenge string     `gorm:"column:CodeChallenge;type:varchar(128)"`
+	Scope         string     `gorm:"column:Scope;type:varchar(255)"`
+	ExpiresAt     time.Time  `gorm:"column:ExpiresAt;index"`
+	UsedAt        *time.Time `gorm:"column:UsedAt"`
+	CreatedAt     time.Time  `gorm:"column:CreatedAt"`
+}
+
+func (authorizationCodeV1) TableName() string {
+	return "authorization_code"
+}
+
+type oauthStateV1 struct {
+	StateHash    string     `gorm:"column:StateHash;type:char(64);primary_key"`
+	Provider     string     `gorm:"column:Provider;type:varchar(50)"`
+	TenantID     string     `gorm:"column:TenantId;type:varchar(36)"`
+	UserID       string     `gorm:"column:UserId;type:char(36)"`
+	SessionID    string     `gorm:"column:SessionId;type:char(36)"`
+	Nonce        string     `gorm:"column:Nonce;type:varchar(64)"`
+	CodeVerifier string     `gorm:"column:CodeVerifier;type:varchar(128)"`
+	ExpiresAt    time.Time  `gorm:"column:ExpiresAt;index"`
+	UsedAt       *time.Time `gorm:"column:UsedAt"`
+	CreatedAt    time.Time  `gorm:"column:CreatedAt"`
+}
+
+func (oauthStateV1) TableName() string {
+	return "oauth_state"
+}
+
+type userIdentityV1 struct {
+	ID             string    `gorm:"column:Id;type:char(36);primary_key"`
+	UserID         string    `gorm:"column:UserId;type:char(36);index"`
+	TenantID       string    `gorm:"column:TenantId;type:varchar(36);uniqueIndex:idx_user_identity_tenant_provider,priority:1"`
+	Provider       string    `gorm:"column:Provider;type:varchar(50);uniqueIndex:idx_user_identity_tenant_provider,priority:2"`
+	ProviderUserID string    `gorm:"column:ProviderUserId;type:varchar(255);uniqueIndex:idx_user_identity_tenant_provider,priority:3"`
+	Email          string    `gorm:"column:Email;type:varchar(255)"`
+	CreatedAt      time.Time `gorm:"column:CreatedAt"`
+}
+
+func (userIdentityV1) TableName() string {
+	return "user_identities"
+}
+
+type recoveryCodeV1 struct {
+	ID        string     `gorm:"column:Id;type:char(36);primary_key"`
+	UserID    string     `gorm:"column:UserId;type:char(36);index"`
+	CodeHash  string     `gorm:"column:CodeHash;type:char(64)"`
+	UsedAt    *time.Time `gorm:"column:UsedAt"`
+	CreatedAt time.Time  `gorm:"column:CreatedAt"`
+}
+
+func (recoveryCodeV1) TableName() string {
+	return "recovery_code"
+}
+
+type trustedDeviceV1 struct {
+	ID         string     `gorm:"column:Id;type:char(36);primary_key"`
+	UserID     string     `gorm:"column:UserId;type:char(36);index"`
+	TokenHash  string     `gorm:"column:TokenHash;type:char(64);uniqueIndex"`
+	UserAgent  string     `gorm:"column:UserAgent;type:varchar(512)"`
+	IP         string     `gorm:"column:Ip;type:varchar(45)"`
+	ExpiresAt  time.Time  `gorm:"column:ExpiresAt;index"`
+	LastUsedAt *time.Time `gorm:"column:LastUsedAt"`
+	CreatedAt  time.Time  `gorm:"column:CreatedAt"`
+}
+
+func (trustedDeviceV1) TableName() string {
+	return "trusted_device"
+}
+
+type webAuthnCredentialV1 struct {
+	ID              string     `gorm:"column:Id;type:char(36);primary_key"`
+	UserID          string     `gorm:"column:UserId;type:char(36);index"`
+	CredentialID    string     `gorm:"column:CredentialId;type:varchar(255);uniqueIndex"`
+	PublicKey       []byte     `gorm:"column:PublicKey;type:blob"`
+	AttestationType string     `gorm:"column:AttestationType;type:varchar(32)"`
+	AAGUID          []byte     `gorm:"column:Aaguid;type:varbinary(16)"`
+	SignCount       uint32     `gorm:"column:SignCount"`
+	Transports      string     `gorm:"column:Transports;type:varchar(255)"`
+	BackupEligible  bool       `gorm:"column:BackupEligible;default:false"`
+	BackupState     bool       `gorm:"column:BackupState;default:false"`
+	Name            string     `gorm:"column:Name;type:varchar(100)"`
+	LastUsedAt      *time.Time `gorm:"column:LastUsedAt"`
+	CreatedAt       time.Time  `gorm:"column:CreatedAt"`
+}
+
+func (webAuthnCredentialV1) TableName() string {
+	return "webauthn_credential"
+}
+
+type webAuthnSessionV1 struct {
+	ID        string     `gorm:"column:Id;type:char(36);primary_key"`
+	Ceremony  string     `gorm:"column:Ceremony;type:varchar(16)"`
+	UserID    string     `gorm:"column:UserId;type:char(36)"`
+	Data      string     `gorm:"column:Data;type:text"`
+	ExpiresAt time.Time  `gorm:"column:ExpiresAt;index"`
+	UsedAt    *time.Time `gorm:"column:UsedAt"`
+	CreatedAt time.Time  `gorm:"column:CreatedAt"`
+}
+
+func (webAuthnSessionV1) TableName() string {
+	return "webauthn_session"
+}
+
+type magicLinkV1 struct {
+	TokenHash string     `gorm:"column:TokenHash;type:char(64);primary_key"`
+	UserID    string     `gorm:"column:UserId;type:char(36);index"`
+	ExpiresAt time.Time  `gorm:"column:ExpiresAt;index"`
+	UsedAt    *time.Time `gorm:"column:UsedAt"`
+	CreatedAt time.Time  `gorm:"column:CreatedAt"`
+}
+
+func (magicLinkV1) TableName() string {
+	return "magic_link"
+}
+
+type confirmationCodeV1 struct {
+	TenantID   string    `gorm:"column:TenantId;type:varchar(36);primary_key"`
+	Email      string    `gorm:"column:Email;type:varchar(255);primary_key"`
+	CodeHash   string    `gorm:"column:CodeHash;type:char(64)"`
+	Attempts   int       `gorm:"column:Attempts;default:0"`
+	ExpiryTime time.Time `gorm:"column:ExpiryTime;index"`
+	CreatedAt  time.Time `gorm:"column:CreatedAt"`
+}
+
+func (confirmationCodeV1) TableName() string {
+	return "confirmation_code"
+}
+
+type confirmationCodeSendV1 struct {
+	TenantID    string    `gorm:"column:TenantId;type:varchar(36);primary_key"`
+	Email       string    `gorm:"column:Email;type:varchar(255);primary_key"`
+	LastSentAt  time.Time `gorm:"column:LastSentAt"`
+	WindowStart time.Time `gorm:"column:WindowStart;index"`
+	SendCount   int       `gorm:"column:SendCount;default:0"`
+}
+
+func (confirmationCodeSendV1) TableName() string {
+	return "confirmation_code_send"
+}
+
+type pendingEmailV1 struct {
+	RevertTokenHash string     `gorm:"column:RevertTokenHash;type:char(64);primary_key"`
+	UserID          string     `gorm:"column:UserId;type:char(36);index"`
+	OldEmail        string     `gorm:"column:OldEmail;type:varchar(255)"`
+	NewEmail        string     `gorm:"column:NewEmail;type:varchar(255);index"`
+	RevertExpiresAt time.Time  `gorm:"column:RevertExpiresAt;index"`
+	ConfirmedAt     *time.Time `gorm:"column:ConfirmedAt"`
+	CreatedAt       time.Time  `gorm:"column:CreatedAt"`
+}
+
+func (pendingEmailV1) TableName() string {
+	return "pending_email"
+}
+
+type roleV1 struct {
+	ID        string    `gorm:"column:Id;type:char(36);primary_key"`
+	Name      string    `gorm:"column:Name;type:varchar(50);uniqueIndex"`
+	CreatedAt time.Time `gorm:"column:CreatedAt"`
+}
+
+func (roleV1) TableName() string {
+	return "role"
+}
+
+type userRoleV1 struct {
+	UserID    string    `gorm:"column:UserId;type:char(36);primary_key"`
+	RoleID    string    `gorm:"column:RoleId;type:char(36);primary_key;index"`
+	CreatedAt time.Time `gorm:"column:CreatedAt"`
+}
+
+func (userRoleV1) TableName() string {
+	return "user_role"
+}
+
+type permissionV1 struct {
+	ID        string    `gorm:"column:Id;type:char(36);primary_key"`
+	Name      string    `gorm:"column:Name;type:varchar(50);uniqueIndex"`
+	CreatedAt time.Time `gorm:"column:CreatedAt"`
+}
+
+func (permissionV1) TableName() string {
+	return "permission"
+}
+
+type rolePermissionV1 struct {
+	RoleID       string    `gorm:"column:RoleId;type:char(36);primary_key"`
+	PermissionID string    `gorm:"column:PermissionId;type:char(36);primary_key;index"`
+	CreatedAt    time.Time `gorm:"column:CreatedAt"`
+}
+
+func (rolePermissionV1) TableName() string {
+	return "role_permission"
+}
+
+type auditEntryV1 struct {
+	ID        string    `gorm:"column:Id;type:char(36);primary_key;index:idx_audit_entry_created_at_id,priority:2"`
+	Event     string    `gorm:"column:Event;type:varchar(50);index"`
+	ActorID   string    `gorm:"column:ActorId;type:char(36);index"`
+	ActorType string    `gorm:"column:ActorType;type:varchar(20)"`
+	TargetID  string    `gorm:"column:TargetId;type:char(36);index"`
+	IP        string    `gorm:"column:Ip;type:varchar(45)"`
+	UserAgent string    `gorm:"column:UserAgent;type:varchar(512)"`
+	Details   string    `gorm:"column:Details;type:text"`
+	CreatedAt time.Time `gorm:"column:CreatedAt;index:idx_audit_entry_created_at_id,priority:1"`
+}
+
+func (auditEntryV1) TableName() string {
+	return "audit_entry"
+}
+
+type loginAttemptV1 struct {
+	ID        string    `gorm:"column:Id;type:char(36);primary_key"`
+	UserID    string    `gorm:"column:UserId;type:char(36);index"`
+	Outcome   string    `gorm:"column:Outcome;type:varchar(20)"`
+	IP        string    `gorm:"column:Ip;type:varchar(45)"`
+	UserAgent string    `gorm:"column:UserAgent;type:varchar(512)"`
+	CreatedAt time.Time `gorm:"column:CreatedAt;index"`
+}
+
+func (loginAttemptV1) TableName() string {
+	return "login_attempt"
+}
+
+type knownDeviceV1 struct {
+	ID          string    `gorm:"column:Id;type:char(36);primary_key"`
+	UserID      string    `gorm:"column:UserId;type:char(36);uniqueIndex:idx_known_device_user_fingerprint,priority:1"`
+	Fingerprint string    `gorm:"column:Fingerprint;type:char(64);uniqueIndex:idx_known_device_user_fingerprint,priority:2"`
+	LastSeenAt  time.Time `gorm:"column:LastSeenAt;index"`
+	CreatedAt   time.Time `gorm:"column:CreatedAt"`
+}
+
+func (knownDeviceV1) TableName() string {
+	return "known_device"
+}
+
+type dataExportV1 struct {
+	ID          string     `gorm:"column:Id;type:char(36);primary_key"`
+	UserID      string     `gorm:"column:UserId;type:char(36);index"`
+	Format      string     `gorm:"column:Format;type:varchar(10)"`
+	Status      string     `gorm:"column:Status;type:varchar(20);index"`
+	Content     []byte     `gorm:"column:Content;type:longblob"`
+	CreatedAt   time.Time  `gorm:"column:CreatedAt"`
+	CompletedAt *time.Time `gorm:"column:CompletedAt"`
+	ExpiresAt   time.Time  `gorm:"column:ExpiresAt;index"`
+}
+
+func (dataExportV1) TableName() string {
+	return "data_export"
+}
+
+type invitationV1 struct {
+	ID               string     `gorm:"column:Id;type:char(36);primary_key"`
+	TenantID         string     `gorm:"column:TenantId;type:varchar(36)"`
+	Email            string     `gorm:"column:Email;type:varchar(255);index"`
+	TokenHash        string     `gorm:"column:TokenHash;type:char(64);uniqueIndex"`
+	InviterID        string     `gorm:"column:InviterId;type:char(36);index"`
+	Role             string     `gorm:"column:Role;type:varchar(50)"`
+	OrganizationID   string     `gorm:"column:OrganizationId;type:char(36);index"`
+	OrganizationRole string     `gorm:"column:OrganizationRole;type:varchar(20)"`
+	ExpiresAt        time.Time  `gorm:"column:ExpiresAt"`
+	AcceptedAt       *time.Time `gorm:"column:AcceptedAt"`
+	CreatedAt        time.Time  `gorm:"column:CreatedAt"`
+}
+
+func (invitationV1) TableName() string {
+	return "invitation"
+}
+
+type organizationV1 struct {
+	ID        string    `gorm:"column:Id;type:char(36);primary_key"`
+	Name      string    `gorm:"column:Name;type:varchar(100)"`
+	CreatedAt time.Time `gorm:"column:CreatedAt;autoCreateTime"`
+}
+
+func (organizationV1) TableName() string {
+	return "organization"
+}
+
+type membershipV1 struct {
+	OrganizationID string    `gorm:"column:OrganizationId;type:char(36);primary_key"`
+	UserID         string    `gorm:"column:UserId;type:char(36);primary_key;index"`
+	Role           string    `gorm:"column:Role;type:varchar(20)"`
+	CreatedAt      time.Time `gorm:"column:CreatedAt;autoCreateTime"`
+}
+
+func (membershipV1) TableName() string {
+	return "membership"
+}
+
+type policyAcceptanceV1 struct {
+	ID         string    `gorm:"column:Id;type:char(36);primary_key"`
+	UserID     string    `gorm:"column:UserId;type:char(36);index:idx_policy_acceptance_user_accepted_at,priority:1"`
+	Version    string    `gorm:"column:Version;type:varchar(64)"`
+	IP         string    `gorm:"column:Ip;type:varchar(45)"`
+	UserAgent  string    `gorm:"column:UserAgent;type:varchar(512)"`
+	AcceptedAt time.Time `gorm:"column:AcceptedAt;index:idx_policy_acceptance_user_accepted_at,priority:2"`
+}
+
+func (policyAcceptanceV1) TableName() string {
+	return "policy_acceptance"
+}
@@ -1,28 +1,98 @@
-package main
+package migrations
 
 import (
-	"log"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
 
-	"github.com/OVillas/autentication/config"
-	"github.com/OVillas/autentication/database"
 	"github.com/OVillas/autentication/domain"
+	"gorm.io/gorm"
 )
 
-func main() {
-	config.Load()
+// Migration takes the database from the previous version to Version. Once released, a migration
+// is never edited: a later change to the schema or the data goes in a new one, at the end of
+// migrations. Each runs in a transaction, but MySQL commits every change to the schema right
+// away, so a migration must be able to run again after failing halfway.
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(tx *gorm.DB) error
+}
+
+var migrations = []Migration{
+	{Version: 1, Name: "initial_schema", Up: initialSchema},
+	{Version: 2, Name: "user_last_totp_step", Up: addUserLastTOTPStep},
+}
+
+// Run applies the pending migrations in order, recording each one as it succeeds.
+func Run(db *gorm.DB) error {
+	if err := db.AutoMigrate(&domain.SchemaMigration{}); err != nil {
+		return err
+	}
 
-	db, err := database.NewMysqlConnection()
+	pending, err := Pending(db)
 	if err != nil {
-		log.Fatal(err)
+		return err
+	}
+
+	for _, migration := range pending {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := migration.Up(tx); err != nil {
+				return err
+			}
+
+			return tx.Create(&domain.SchemaMigration{
+				Version:   migration.Version,
+				Name:      migration.Name,
+				AppliedAt: time.Now(),
+			}).Error
+		})
+		if err != nil {
+			return fmt.Errorf("migration %d %s: %w", migration.Version, migration.Name, err)
+		}
+
+		slog.Info("Migration applied", slog.Int("version", migration.Version), slog.String("name", migration.Name))
+	}
+
+	return nil
+}
+
+// Pending returns the migrations not applied to the database yet, in order.
+func Pending(db *gorm.DB) ([]Migration, error) {
+	var applied []int
+	if db.Migrator().HasTable(&domain.SchemaMigration{}) {
+		if err := db.Model(&domain.SchemaMigration{}).Pluck("Version", &applied).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	var pending []Migration
+	for _, migration := range migrations {
+		if !slices.Contains(applied, migration.Version) {
+			pending = append(pending, migration)
+		}
 	}
 
-	err = db.AutoMigrate(
-		&domain.User{},
-	)
+	return pending, nil
+}
 
+// CheckPending fails when a migration is not applied to the database yet, naming them.
+func CheckPending(db *gorm.DB) error {
+	pending, err := Pending(db)
 	if err != nil {
-		log.Fatalf("Failed to run migrations: %v", err)
+		return err
+	}
+
+	if len(pending) == 0 {
+		return nil
+	}
+
+	names := make([]string, 0, len(pending))
+	for _, migration := range pending {
+		names = append(names, fmt.Sprintf("%d %s", migration.Version, migration.Name))
 	}
 
-	log.Println("Migrations executed successfully.")
+	return fmt.Errorf("the database has pending migrations, run the migrate command: %s", strings.Join(names, ", "))
 }
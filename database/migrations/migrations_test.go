@@ -0,0 +1,66 @@
+package migrations_test
+
+import (
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+
+	"github.com/OVillas/autentication/database"
+	"github.com/OVillas/autentication/database/databasetest"
+	"github.com/OVillas/autentication/database/migrations"
+	"gorm.io/gorm/schema"
+)
+
+func TestMain(m *testing.M) {
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	m.Run()
+}
+
+func TestRunLeavesNothingPending(t *testing.T) {
+	db, err := databasetest.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := migrations.CheckPending(db); err != nil {
+		t.Fatal(err)
+	}
+
+	// Running again must be a no-op.
+	if err := migrations.Run(db); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestMigrationsCoverTheModels fails when a domain model has a table or a column that no migration
+// creates: a change to a model needs a migration of its own.
+func TestMigrationsCoverTheModels(t *testing.T) {
+	db, err := databasetest.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	migrator := db.Migrator()
+	for _, model := range database.Models {
+		modelSchema, err := schema.Parse(model, &sync.Map{}, db.NamingStrategy)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !migrator.HasTable(model) {
+			t.Errorf("no migration creates the table %s", modelSchema.Table)
+			continue
+		}
+
+		for _, field := range modelSchema.Fields {
+			if field.DBName == "" {
+				continue
+			}
+
+			if !migrator.HasColumn(model, field.DBName) {
+				t.Errorf("no migration creates the column %s of %s", field.DBName, modelSchema.Table)
+			}
+		}
+	}
+}
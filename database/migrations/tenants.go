@@ -0,0 +1,83 @@
+package migrations
+
+import (
+	"github.com/OVillas/autentication/config"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// backfillTenants moves the rows stored before tenants existed into the default tenant, and drops
+// the unique indexes that made usernames, emails and provider accounts unique across tenants.
+// AutoMigrate then creates their replacements scoped to a tenant.
+func backfillTenants(db *gorm.DB) error {
+	migrator := db.Migrator()
+	models := []any{
+		&userV1{},
+		&refreshTokenV1{},
+		&userIdentityV1{},
+		&oauthStateV1{},
+		&invitationV1{},
+		&confirmationCodeV1{},
+		&confirmationCodeSendV1{},
+	}
+
+	for _, model := range models {
+		if !migrator.HasTable(model) || migrator.HasColumn(model, "TenantID") {
+			continue
+		}
+
+		if err := migrator.AddColumn(model, "TenantID"); err != nil {
+			return err
+		}
+
+		err := db.Model(model).Where(`"TenantId" IS NULL OR "TenantId" = ''`).
+			Update("TenantId", config.DefaultTenant).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	oldIndexes := []struct {
+		model any
+		name  string
+	}{
+		{&userV1{}, "idx_user_username"},
+		{&userV1{}, "idx_user_email"},
+		{&userIdentityV1{}, "idx_user_identity_provider"},
+	}
+
+	for _, index := range oldIndexes {
+		if !migrator.HasTable(index.model) || !migrator.HasIndex(index.model, index.name) {
+			continue
+		}
+
+		if err := migrator.DropIndex(index.model, index.name); err != nil {
+			return err
+		}
+	}
+
+	// The codes were keyed by email alone, their key now starts with the tenant.
+	for _, table := range []string{"confirmation_code", "confirmation_code_send"} {
+		if !migrator.HasTable(table) {
+			continue
+		}
+
+		var keyColumns int64
+		err := db.Raw("SELECT COUNT(*) FROM information_schema.KEY_COLUMN_USAGE "+
+			"WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND CONSTRAINT_NAME = 'PRIMARY'", table).
+			Scan(&keyColumns).Error
+		if err != nil {
+			return err
+		}
+
+		if keyColumns == 2 {
+			continue
+		}
+
+		if err := db.Exec(`ALTER TABLE ? DROP PRIMARY KEY, ADD PRIMARY KEY ("TenantId", "Email")`, clause.Table{Name: table}).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,21 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// userV2 is the column migration 2 adds to the users: the last authenticator app step accepted,
+// so a code cannot be used again within its window.
+type userV2 struct {
+	LastTOTPStep int64 `gorm:"column:LastTotpStep;default:0"`
+}
+
+func (userV2) TableName() string {
+	return "user"
+}
+
+func addUserLastTOTPStep(tx *gorm.DB) error {
+	if tx.Migrator().HasColumn(&userV2{}, "LastTotpStep") {
+		return nil
+	}
+
+	return tx.Migrator().AddColumn(&userV2{}, "LastTOTPStep")
+}
@@ -0,0 +1,15 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// renameUserUpdatedAt renames the UpdateAt column of the users to UpdatedAt, the one GORM keeps
+// up to date on its own, keeping the dates already in it.
+func renameUserUpdatedAt(db *gorm.DB) error {
+	migrator := db.Migrator()
+	if !migrator.HasTable(&userV1{}) || !migrator.HasColumn(&userV1{}, "UpdateAt") ||
+		migrator.HasColumn(&userV1{}, "UpdatedAt") {
+		return nil
+	}
+
+	return migrator.RenameColumn(&userV1{}, "UpdateAt", "UpdatedAt")
+}
@@ -0,0 +1,36 @@
+package database
+
+import "github.com/OVillas/autentication/domain"
+
+// Models are the tables of the application, in the order they are migrated.
+var Models = []any{
+	&domain.User{},
+	&domain.RefreshToken{},
+	&domain.RevokedToken{},
+	&domain.PersonalAccessToken{},
+	&domain.ApiKey{},
+	&domain.OAuthClient{},
+	&domain.AuthorizationCode{},
+	&domain.OAuthState{},
+	&domain.UserIdentity{},
+	&domain.RecoveryCode{},
+	&domain.TrustedDevice{},
+	&domain.WebAuthnCredential{},
+	&domain.WebAuthnSession{},
+	&domain.MagicLink{},
+	&domain.ConfirmationCode{},
+	&domain.ConfirmationCodeSend{},
+	&domain.PendingEmail{},
+	&domain.Role{},
+	&domain.UserRole{},
+	&domain.Permission{},
+	&domain.RolePermission{},
+	&domain.AuditEntry{},
+	&domain.LoginAttempt{},
+	&domain.KnownDevice{},
+	&domain.DataExport{},
+	&domain.Invitation{},
+	&domain.Organization{},
+	&domain.Membership{},
+	&domain.PolicyAcceptance{},
+}
@@ -0,0 +1,35 @@
+package database
+
+import (
+	"strings"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/migrator"
+	"gorm.io/gorm/schema"
+)
+
+// postgresDialector is the postgres driver with the column types of the models translated.
+type postgresDialector struct {
+	postgres.Dialector
+}
+
+// DataTypeOf translates the MySQL binary types of the models, which Postgres does not have.
+func (d postgresDialector) DataTypeOf(field *schema.Field) string {
+	dataType := strings.ToLower(string(field.DataType))
+	if dataType == "blob" || dataType == "longblob" || strings.HasPrefix(dataType, "varbinary") {
+		return "bytea"
+	}
+
+	return d.Dialector.DataTypeOf(field)
+}
+
+// Migrator is the one of the postgres driver, but with d as its dialector so that it uses
+// DataTypeOf above.
+func (d postgresDialector) Migrator(db *gorm.DB) gorm.Migrator {
+	return postgres.Migrator{Migrator: migrator.Migrator{Config: migrator.Config{
+		DB:                          db,
+		Dialector:                   d,
+		CreateIndexAfterCreateTable: true,
+	}}}
+}
@@ -1,6 +1,23 @@
 package database
 
-//TODO: Implement NewRedisConnection function
-func NewRedisConnection() {
+import (
+	"context"
 
+	"github.com/OVillas/autentication/config"
+	"github.com/redis/go-redis/v9"
+)
+
+func NewRedisConnection() (*redis.Client, error) {
+	client := redis.NewClient(&redis.Options{
+		Addr:     config.Redis.Addr,
+		Password: config.Redis.Password,
+		DB:       config.Redis.DB,
+	})
+
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		_ = client.Close()
+		return nil, err
+	}
+
+	return client, nil
 }
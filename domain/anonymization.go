@@ -0,0 +1,41 @@
+package domain
+
+import (
+	"errors"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// AnonymizedName replaces the name of an anonymized user.
+const AnonymizedName = "Anonymized user"
+
+var (
+	ErrAnonymizeUser         = errors.New("error to anonymize user")
+	ErrUserAlreadyAnonymized = errors.New("this account was already anonymized")
+)
+
+// IsAnonymized tells whether the personal data of the account was erased. Its ID is kept so the
+// audit trail still points to it, but nobody can log in to it again.
+func (u *User) IsAnonymized() bool {
+	return u.AnonymizedAt != nil
+}
+
+// AnonymizedUsername and AnonymizedEmail are the placeholders of an anonymized user. They only
+// derive from the ID, so nothing of the erased data can be recovered from them, and they are
+// unique, so the erased username and email can be taken by new registrations.
+func AnonymizedUsername(id string) string {
+	return "anonymized-" + id
+}
+
+func AnonymizedEmail(id string) string {
+	return AnonymizedUsername(id) + "@anonymized.invalid"
+}
+
+type AnonymizePayLoad struct {
+	Password string `json:"password,omitempty" validate:"required"`
+}
+
+func (ap *AnonymizePayLoad) Validate() error {
+	validate := validator.New()
+	return validate.Struct(ap)
+}
@@ -0,0 +1,108 @@
+package domain
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	ApiKeyPrefix = "ak_"
+	ApiKeyHeader = "X-Api-Key"
+)
+
+var (
+	ErrCreateApiKey   = errors.New("error to create api key")
+	ErrGetApiKey      = errors.New("error to get api key")
+	ErrRotateApiKey   = errors.New("error to rotate api key")
+	ErrApiKeyNotFound = errors.New("api key not found")
+)
+
+// ApiKey authenticates another service rather than a user. AllowedEndpoints holds route
+// identifiers such as "GET /v1/users/:id", separated by commas.
+type ApiKey struct {
+	ID               string     `gorm:"column:Id;type:char(36);primary_key"`
+	Name             string     `gorm:"column:Name;type:varchar(100)"`
+	SecretHash       string     `gorm:"column:SecretHash;type:char(64);uniqueIndex"`
+	AllowedEndpoints string     `gorm:"column:AllowedEndpoints;type:varchar(1024)"`
+	LastUsedAt       *time.Time `gorm:"column:LastUsedAt"`
+	RevokedAt        *time.Time `gorm:"column:RevokedAt"`
+	CreatedAt        time.Time  `gorm:"column:CreatedAt"`
+}
+
+func (ApiKey) TableName() string {
+	return "api_key"
+}
+
+func (ak *ApiKey) IsActive() bool {
+	return ak.RevokedAt == nil
+}
+
+func (ak *ApiKey) Allows(method string, path string) bool {
+	endpoint := method + " " + path
+	for _, allowed := range strings.Split(ak.AllowedEndpoints, ",") {
+		if allowed == endpoint {
+			return true
+		}
+	}
+
+	return false
+}
+
+type ApiKeyPayLoad struct {
+	Name             string   `json:"name,omitempty" validate:"required,min=1,max=100"`
+	AllowedEndpoints []string `json:"allowed_endpoints,omitempty" validate:"required,min=1,dive,required"`
+}
+
+type ApiKeyResponse struct {
+	Id               string     `json:"id"`
+	Name             string     `json:"name"`
+	AllowedEndpoints []string   `json:"allowed_endpoints"`
+	LastUsedAt       *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt        *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt        time.Time  `json:"created_at"`
+	Key              string     `json:"key,omitempty"`
+}
+
+type ApiKeyHandler interface {
+	Create(ctx echo.Context) error
+	GetAll(ctx echo.Context) error
+	Rotate(ctx echo.Context) error
+	Revoke(ctx echo.Context) error
+}
+
+type ApiKeyService interface {
+	Create(viewer Viewer, payLoad ApiKeyPayLoad) (*ApiKeyResponse, error)
+	GetAll() ([]ApiKeyResponse, error)
+	Rotate(viewer Viewer, id string) (*ApiKeyResponse, error)
+	Revoke(viewer Viewer, id string) error
+}
+
+type ApiKeyRepository interface {
+	Create(apiKey ApiKey) error
+	GetAll() ([]ApiKey, error)
+	GetById(id string) (*ApiKey, error)
+	GetBySecretHash(secretHash string) (*ApiKey, error)
+	UpdateSecretHash(id string, secretHash string) (bool, error)
+	Revoke(id string) (bool, error)
+	UpdateLastUsedAt(id string) error
+}
+
+func (akp *ApiKeyPayLoad) Validate() error {
+	validate := validator.New()
+	return validate.Struct(akp)
+}
+
+func (ak *ApiKey) ToApiKeyResponse() *ApiKeyResponse {
+	return &ApiKeyResponse{
+		Id:               ak.ID,
+		Name:             ak.Name,
+		AllowedEndpoints: strings.Split(ak.AllowedEndpoints, ","),
+		LastUsedAt:       ak.LastUsedAt,
+		RevokedAt:        ak.RevokedAt,
+		CreatedAt:        ak.CreatedAt,
+	}
+}
@@ -0,0 +1,180 @@
+package domain
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	AuditActorUser   = "user"
+	AuditActorAdmin  = "admin"
+	AuditActorSystem = "system"
+
+	AuditEventLoginSucceeded           = "login_succeeded"
+	AuditEventLoginFailed              = "login_failed"
+	AuditEventPasswordChanged          = "password_changed"
+	AuditEventPasswordReset            = "password_reset"
+	AuditEventTwoFactorEnabled         = "two_factor_enabled"
+	AuditEventTwoFactorDisabled        = "two_factor_disabled"
+	AuditEventRecoveryCodesRegenerated = "recovery_codes_regenerated"
+	AuditEventAccountLocked            = "account_locked"
+	AuditEventWebAuthnCloneWarning     = "webauthn_clone_warning"
+	AuditEventPasswordResetForced      = "password_reset_forced"
+	AuditEventEmailChanged             = "email_changed"
+	AuditEventEmailChangeReverted      = "email_change_reverted"
+	AuditEventUserDeleted              = "user_deleted"
+	AuditEventUserRestored             = "user_restored"
+	AuditEventUserDeactivated          = "user_deactivated"
+	AuditEventUserReactivated          = "user_reactivated"
+	AuditEventUserSuspended            = "user_suspended"
+	AuditEventUserUnsuspended          = "user_unsuspended"
+	AuditEventDeletionScheduled        = "deletion_scheduled"
+	AuditEventDeletionCanceled         = "deletion_canceled"
+	AuditEventUserPurged               = "user_purged"
+	AuditEventUserAnonymized           = "user_anonymized"
+	AuditEventRoleCreated              = "role_created"
+	AuditEventRoleAssigned             = "role_assigned"
+	AuditEventRoleRevoked              = "role_revoked"
+	AuditEventPermissionGranted        = "permission_granted"
+	AuditEventPermissionRevoked        = "permission_revoked"
+	AuditEventApiKeyCreated            = "api_key_created"
+	AuditEventApiKeyRotated            = "api_key_rotated"
+	AuditEventApiKeyRevoked            = "api_key_revoked"
+	AuditEventOAuthClientCreated       = "oauth_client_created"
+	AuditEventOAuthClientDeleted       = "oauth_client_deleted"
+	AuditEventDataExportRequested      = "data_export_requested"
+	AuditEventInvitationSent           = "invitation_sent"
+	AuditEventInvitationAccepted       = "invitation_accepted"
+	AuditEventOrganizationCreated      = "organization_created"
+	AuditEventMemberAdded              = "member_added"
+	AuditEventMemberRoleChanged        = "member_role_changed"
+	AuditEventMemberRemoved            = "member_removed"
+	AuditEventPhoneConfirmed           = "phone_confirmed"
+	AuditEventPhoneRemoved             = "phone_removed"
+	AuditEventMetadataUpdated          = "metadata_updated"
+)
+
+var (
+	ErrGetAuditEntries    = errors.New("error to get audit entries")
+	ErrInvalidAuditPeriod = errors.New("to cannot be before from")
+)
+
+// Actor is who performed an audited action and where the request came from. ID is empty for
+// the system and for operators using the admin key.
+type Actor struct {
+	ID   string
+	Type string
+	ClientInfo
+}
+
+// SystemActor performs the actions of the background jobs.
+var SystemActor = Actor{Type: AuditActorSystem}
+
+// UserActor is a user acting on their own account.
+func UserActor(userID string, clientInfo ClientInfo) Actor {
+	return Actor{ID: userID, Type: AuditActorUser, ClientInfo: clientInfo}
+}
+
+// AnonymousActor is someone not authenticated yet, such as whoever attempts a login.
+func AnonymousActor(clientInfo ClientInfo) Actor {
+	return Actor{Type: AuditActorUser, ClientInfo: clientInfo}
+}
+
+// AuditEntry is one event of the audit trail. Entries are only ever inserted. TargetID is the
+// user, role or credential the event is about and Details holds its specifics as a JSON object.
+type AuditEntry struct {
+	ID        string    `gorm:"column:Id;type:char(36);primary_key;index:idx_audit_entry_created_at_id,priority:2"`
+	Event     string    `gorm:"column:Event;type:varchar(50);index"`
+	ActorID   string    `gorm:"column:ActorId;type:char(36);index"`
+	ActorType string    `gorm:"column:ActorType;type:varchar(20)"`
+	TargetID  string    `gorm:"column:TargetId;type:char(36);index"`
+	IP        string    `gorm:"column:Ip;type:varchar(45)"`
+	UserAgent string    `gorm:"column:UserAgent;type:varchar(512)"`
+	Details   string    `gorm:"column:Details;type:text"`
+	CreatedAt time.Time `gorm:"column:CreatedAt;index:idx_audit_entry_created_at_id,priority:1"`
+}
+
+func (AuditEntry) TableName() string {
+	return "audit_entry"
+}
+
+type AuditEntryResponse struct {
+	Id        string            `json:"id"`
+	Event     string            `json:"event"`
+	ActorID   string            `json:"actor_id,omitempty"`
+	ActorType string            `json:"actor_type"`
+	TargetID  string            `json:"target_id,omitempty"`
+	IP        string            `json:"ip,omitempty"`
+	UserAgent string            `json:"user_agent,omitempty"`
+	Details   map[string]string `json:"details,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+}
+
+func (ae *AuditEntry) ToAuditEntryResponse() *AuditEntryResponse {
+	var details map[string]string
+	if ae.Details != "" {
+		_ = json.Unmarshal([]byte(ae.Details), &details)
+	}
+
+	return &AuditEntryResponse{
+		Id:        ae.ID,
+		Event:     ae.Event,
+		ActorID:   ae.ActorID,
+		ActorType: ae.ActorType,
+		TargetID:  ae.TargetID,
+		IP:        ae.IP,
+		UserAgent: ae.UserAgent,
+		Details:   details,
+		CreatedAt: ae.CreatedAt,
+	}
+}
+
+// AuditPage is one page of the audit trail, newest entries first.
+type AuditPage = Page[AuditEntryResponse]
+
+// AuditQuery filters the audit trail. Every filter left out matches all entries; From and To
+// bound the date of the entries, both inclusive.
+type AuditQuery struct {
+	PageRequest
+	ActorID  string     `query:"actor" validate:"omitempty,uuid"`
+	TargetID string     `query:"target" validate:"omitempty,uuid"`
+	Event    string     `query:"event" validate:"max=50"`
+	From     *time.Time `query:"from"`
+	To       *time.Time `query:"to"`
+}
+
+func (q *AuditQuery) Validate() error {
+	if q.From != nil && q.To != nil && q.To.Before(*q.From) {
+		return ErrInvalidAuditPeriod
+	}
+
+	validate := validator.New()
+	if err := validate.StructExcept(q, "PageRequest"); err != nil {
+		return err
+	}
+
+	return q.PageRequest.Validate()
+}
+
+type AuditHandler interface {
+	GetAll(ctx echo.Context) error
+}
+
+// AuditService records the audit trail. Record never waits for the database: entries are
+// written in batches in the background, and are also logged with the "audit" message so none is
+// lost if the buffer fills up.
+type AuditService interface {
+	Record(event string, targetID string, actor Actor, details ...slog.Attr)
+	GetAll(query AuditQuery) (*AuditPage, error)
+}
+
+type AuditRepository interface {
+	CreateBatch(entries []AuditEntry) error
+	GetAll(query AuditQuery) ([]AuditEntry, int64, error)
+	GetByUserID(userID string) ([]AuditEntry, error)
+}
@@ -0,0 +1,26 @@
+package domain
+
+import (
+	"github.com/go-playground/validator/v10"
+)
+
+// AvailabilityQuery asks whether a username, an email or both can still be registered.
+type AvailabilityQuery struct {
+	Username string `query:"username" validate:"required_without=Email,omitempty,max=255"`
+	Email    string `query:"email" validate:"required_without=Username,omitempty,email"`
+}
+
+func (aq *AvailabilityQuery) Validate() error {
+	aq.Username = NormalizeUsername(aq.Username)
+	aq.Email = NormalizeEmail(aq.Email)
+
+	validate := validator.New()
+	return validate.Struct(aq)
+}
+
+// AvailabilityResponse tells for each field asked about whether it is still free. Email is left
+// out when config.UniformRegistration hides which emails are registered.
+type AvailabilityResponse struct {
+	Username *bool `json:"username,omitempty"`
+	Email    *bool `json:"email,omitempty"`
+}
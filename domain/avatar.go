@@ -0,0 +1,67 @@
+package domain
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+
+	"github.com/OVillas/autentication/config"
+	"github.com/labstack/echo/v4"
+)
+
+// AvatarFormField is the multipart field the avatar is uploaded in.
+const AvatarFormField = "avatar"
+
+var (
+	ErrAvatarTooLarge  = errors.New("avatar is larger than the maximum size allowed")
+	ErrAvatarType      = errors.New("avatar must be a JPEG, PNG or GIF image")
+	ErrInvalidAvatar   = errors.New("avatar could not be read as an image")
+	ErrAvatarDimension = errors.New("avatar must be at most 4096 pixels wide and high")
+	ErrSaveAvatar      = errors.New("error to save avatar")
+	ErrDeleteAvatar    = errors.New("error to delete avatar")
+)
+
+// AvatarFallbackURL is config.Avatar.FallbackURL for email, such as its Gravatar, or empty when
+// no fallback is configured.
+func AvatarFallbackURL(email string) string {
+	if config.Avatar.FallbackURL == "" {
+		return ""
+	}
+
+	hash := sha256.Sum256([]byte(strings.ToLower(strings.TrimSpace(email))))
+	return strings.ReplaceAll(config.Avatar.FallbackURL, "{hash}", hex.EncodeToString(hash[:]))
+}
+
+// AvatarOrFallback is the URL of the avatar the user uploaded, or else the fallback one.
+func (u *User) AvatarOrFallback() string {
+	if u.AvatarURL != nil {
+		return *u.AvatarURL
+	}
+
+	return AvatarFallbackURL(u.Email)
+}
+
+type AvatarResponse struct {
+	AvatarURL string `json:"avatar_url"`
+}
+
+// AvatarStorage keeps the avatars, one per key. Put replaces the content of key and returns the
+// URL it is served from; Delete succeeds when there is nothing to delete.
+type AvatarStorage interface {
+	Put(key string, content []byte, contentType string) (string, error)
+	Delete(key string) error
+}
+
+type AvatarHandler interface {
+	Upload(ctx echo.Context) error
+	Delete(ctx echo.Context) error
+}
+
+// AvatarService replaces the avatar of a user with an uploaded image. The image is decoded and
+// encoded again, so that only its pixels are stored, without EXIF or other metadata.
+type AvatarService interface {
+	Upload(ctx context.Context, userID string, content []byte) (*AvatarResponse, error)
+	Delete(ctx context.Context, userID string) error
+}
@@ -1,12 +1,60 @@
 package domain
 
 import (
+	"context"
+	"errors"
 	"time"
 )
 
+// CodeSendWindow is the period the daily cap of code emails per address applies to.
+const CodeSendWindow = 24 * time.Hour
+
+var (
+	ErrSaveConfirmationCode = errors.New("error to save confirmation code")
+	ErrGetConfirmationCode  = errors.New("error to get confirmation code")
+	ErrTooManyCodeRequests  = errors.New("too many codes requested for this email, try again later")
+)
+
+// RateLimitError refuses a request until RetryAfter has passed. It matches ErrTooManyCodeRequests
+// with errors.Is.
+type RateLimitError struct {
+	RetryAfter time.Duration
+}
+
+func (rle *RateLimitError) Error() string {
+	return ErrTooManyCodeRequests.Error()
+}
+
+func (rle *RateLimitError) Is(target error) bool {
+	return target == ErrTooManyCodeRequests
+}
+
+// ConfirmationCode is the OTP last sent to an email of a tenant, stored as its HMAC only. Only
+// the latest code of an address is kept and it is gone once ExpiryTime passes.
 type ConfirmationCode struct {
-	Code       string
-	ExpiryTime time.Time
+	TenantID   string    `gorm:"column:TenantId;type:varchar(36);primary_key"`
+	Email      string    `gorm:"column:Email;type:varchar(255);primary_key"`
+	CodeHash   string    `gorm:"column:CodeHash;type:char(64)"`
+	Attempts   int       `gorm:"column:Attempts;default:0"`
+	ExpiryTime time.Time `gorm:"column:ExpiryTime;index"`
+	CreatedAt  time.Time `gorm:"column:CreatedAt"`
+}
+
+func (ConfirmationCode) TableName() string {
+	return "confirmation_code"
+}
+
+// ConfirmationCodeSend counts the code emails sent to an address since WindowStart.
+type ConfirmationCodeSend struct {
+	TenantID    string    `gorm:"column:TenantId;type:varchar(36);primary_key"`
+	Email       string    `gorm:"column:Email;type:varchar(255);primary_key"`
+	LastSentAt  time.Time `gorm:"column:LastSentAt"`
+	WindowStart time.Time `gorm:"column:WindowStart;index"`
+	SendCount   int       `gorm:"column:SendCount;default:0"`
+}
+
+func (ConfirmationCodeSend) TableName() string {
+	return "confirmation_code_send"
 }
 
 type ConfirmCode struct {
@@ -15,6 +63,26 @@ type ConfirmCode struct {
 }
 
 type ConfirmationCodeService interface {
-	SendConfirmationCode(email string) error
-	ConfirmCode(confirmCode ConfirmCode) (*User, error)
+	SendConfirmationCode(ctx context.Context, tenantID string, email string) error
+	IssueConfirmationCode(confirmationCodes ConfirmationCodeRepository, tenantID string, email string) (string, error)
+	EmailConfirmationCode(ctx context.Context, tenantID string, email string, code string) error
+	SendTwoFactorCode(tenantID string, email string) error
+	SendEmailChangeCode(tenantID string, email string) error
+	ConfirmCode(ctx context.Context, tenantID string, confirmCode ConfirmCode) (*User, error)
+	CheckCode(tenantID string, confirmCode ConfirmCode) error
+	SendPhoneConfirmationCode(tenantID string, phoneNumber string) error
+	SendTwoFactorSMS(tenantID string, phoneNumber string) error
+}
+
+// ConfirmationCodeRepository stores codes by tenant and address for ttl. The address is an email
+// or, for the codes sent by SMS, a phone number. Get returns nil once the code has expired and
+// IncrementAttempts returns 0 when there is no code to count against. ReserveSend records a code
+// sent unless the address is within cooldown of the last one or already got limit codes in the
+// current window, in which case it returns how long to wait.
+type ConfirmationCodeRepository interface {
+	Set(tenantID string, email string, codeHash string, ttl time.Duration) error
+	Get(tenantID string, email string) (*ConfirmationCode, error)
+	Delete(tenantID string, email string) error
+	IncrementAttempts(tenantID string, email string) (int, error)
+	ReserveSend(tenantID string, email string, cooldown time.Duration, window time.Duration, limit int) (time.Duration, error)
 }
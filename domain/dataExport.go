@@ -0,0 +1,134 @@
+package domain
+
+import (
+	"errors"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	DataExportFormatJSON = "json"
+	DataExportFormatZIP  = "zip"
+
+	DataExportPending = "pending"
+	DataExportReady   = "ready"
+	DataExportFailed  = "failed"
+)
+
+var (
+	ErrCreateDataExport      = errors.New("error to create data export")
+	ErrGetDataExport         = errors.New("error to get data export")
+	ErrInvalidDataExportLink = errors.New("invalid or expired download link")
+	ErrRecentLoginRequired   = errors.New("sign in again to continue")
+)
+
+// DataExport is a copy of everything kept about a user, built in the background after they ask
+// for it. Content is the JSON document or the ZIP of CSVs, depending on Format, and is deleted
+// along with the export at ExpiresAt.
+type DataExport struct {
+	ID          string     `gorm:"column:Id;type:char(36);primary_key"`
+	UserID      string     `gorm:"column:UserId;type:char(36);index"`
+	Format      string     `gorm:"column:Format;type:varchar(10)"`
+	Status      string     `gorm:"column:Status;type:varchar(20);index"`
+	Content     []byte     `gorm:"column:Content;type:longblob"`
+	CreatedAt   time.Time  `gorm:"column:CreatedAt"`
+	CompletedAt *time.Time `gorm:"column:CompletedAt"`
+	ExpiresAt   time.Time  `gorm:"column:ExpiresAt;index"`
+}
+
+func (DataExport) TableName() string {
+	return "data_export"
+}
+
+func (de *DataExport) IsAvailable() bool {
+	return de.Status == DataExportReady && time.Now().Before(de.ExpiresAt)
+}
+
+// DataExportQuery picks the format of an export, JSON unless zip is asked for.
+type DataExportQuery struct {
+	Format string `query:"format" validate:"omitempty,oneof=json zip"`
+}
+
+func (q *DataExportQuery) Validate() error {
+	if q.Format == "" {
+		q.Format = DataExportFormatJSON
+	}
+
+	validate := validator.New()
+	return validate.Struct(q)
+}
+
+type DataExportResponse struct {
+	Id        string    `json:"id"`
+	Format    string    `json:"format"`
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func (de *DataExport) ToDataExportResponse() *DataExportResponse {
+	return &DataExportResponse{
+		Id:        de.ID,
+		Format:    de.Format,
+		Status:    de.Status,
+		CreatedAt: de.CreatedAt,
+		ExpiresAt: de.ExpiresAt,
+	}
+}
+
+// DataExportProfile is the account data of the export. Password hashes and secrets are left out.
+type DataExportProfile struct {
+	Id                  string       `json:"id"`
+	Name                string       `json:"name"`
+	Username            string       `json:"username"`
+	Email               string       `json:"email"`
+	EmailConfirmed      bool         `json:"email_confirmed"`
+	PhoneNumber         *string      `json:"phone_number,omitempty"`
+	PhoneConfirmed      bool         `json:"phone_confirmed"`
+	Locale              string       `json:"locale"`
+	Timezone            string       `json:"timezone"`
+	AvatarURL           *string      `json:"avatar_url,omitempty"`
+	Metadata            UserMetadata `json:"metadata"`
+	TwoFactorEnabled    bool         `json:"two_factor_enabled"`
+	LoginAlertsEnabled  bool         `json:"login_alerts_enabled"`
+	Roles               []string     `json:"roles"`
+	CreatedAt           time.Time    `json:"created_at"`
+	UpdatedAt           time.Time    `json:"updated_at"`
+	LastLoginAt         *time.Time   `json:"last_login_at,omitempty"`
+	DeletionScheduledAt *time.Time   `json:"deletion_scheduled_at,omitempty"`
+}
+
+// DataExportDocument is everything kept about a user. Audit entries about the user performed by
+// someone else do not tell who they were.
+type DataExportDocument struct {
+	GeneratedAt  time.Time              `json:"generated_at"`
+	Profile      DataExportProfile      `json:"profile"`
+	Identities   []UserIdentityResponse `json:"identities"`
+	Sessions     []SessionResponse      `json:"sessions"`
+	LoginHistory []LoginAttemptResponse `json:"login_history"`
+	AuditEntries []AuditEntryResponse   `json:"audit_entries"`
+}
+
+type DataExportHandler interface {
+	Request(ctx echo.Context) error
+	Download(ctx echo.Context) error
+}
+
+// DataExportService builds the exports in the background and emails a signed download link,
+// valid until the export expires, once each is ready.
+type DataExportService interface {
+	Request(userID string, format string, clientInfo ClientInfo) (*DataExportResponse, error)
+	Download(token string) (*DataExport, error)
+}
+
+type DataExportRepository interface {
+	Create(dataExport DataExport) error
+	GetByID(id string) (*DataExport, error)
+	GetPendingByUserID(userID string) (*DataExport, error)
+	GetPending() ([]DataExport, error)
+	Complete(id string, content []byte) error
+	Fail(id string) error
+	DeleteExpired() (int64, error)
+}
@@ -0,0 +1,39 @@
+package domain
+
+import (
+	"errors"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+)
+
+const (
+	AccountDeactivatedCode = "account_deactivated"
+	ReactivationTokenTTL   = 15 * time.Minute
+)
+
+var (
+	ErrAccountDeactivated = errors.New("this account is deactivated, confirm its reactivation to log in")
+	ErrDeactivateUser     = errors.New("error to deactivate user")
+	ErrReactivateUser     = errors.New("error to reactivate user")
+)
+
+type DeactivatePayLoad struct {
+	Password string `json:"password,omitempty" validate:"required"`
+}
+
+func (dp *DeactivatePayLoad) Validate() error {
+	validate := validator.New()
+	return validate.Struct(dp)
+}
+
+// AccountDeactivatedResponse refuses a login to a deactivated account once every factor was
+// proven. ReactivationToken only opens the reactivation, which the user has to confirm.
+type AccountDeactivatedResponse struct {
+	Error             string `json:"error"`
+	Message           string `json:"message"`
+	Code              string `json:"code"`
+	ReactivationToken string `json:"reactivation_token"`
+	TokenType         string `json:"token_type"`
+	ExpiresIn         int64  `json:"expires_in"`
+}
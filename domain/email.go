@@ -1,15 +1,49 @@
 package domain
 
+import "time"
+
+const (
+	NotificationPasswordChanged      = "password_changed"
+	NotificationPasswordReset        = "password_reset"
+	NotificationEmailChangeRequested = "email_change_requested"
+	NotificationTwoFactorEnabled     = "two_factor_enabled"
+	NotificationTwoFactorDisabled    = "two_factor_disabled"
+	NotificationDeletionScheduled    = "deletion_scheduled"
+	NotificationDeletionReminder     = "deletion_reminder"
+	NotificationNewLogin             = "new_login"
+	NotificationDataExportReady      = "data_export_ready"
+)
+
 type GmailSender struct {
 	Name              string
 	FromEmailAddress  string
 	FromEmailPassword string
 }
 
+// Notification tells the owner of an account about a security relevant change. Type and Locale
+// pick the template and Timezone the one its times are written in; the other fields fill it in
+// and may be left empty when they do not apply.
+type Notification struct {
+	Type       string
+	Locale     string
+	Timezone   string
+	Name       string
+	IP         string
+	NewEmail   string
+	Location   string
+	Device     string
+	Link       string
+	ByAdmin    bool
+	OccurredAt time.Time
+	DueAt      time.Time
+}
+
 type EmailService interface {
 	SendEmail(
 		subject string,
 		content string,
 		to []string,
 	) error
+	// Notify renders and sends notification in the background, failures are only logged.
+	Notify(notification Notification, to []string)
 }
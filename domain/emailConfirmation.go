@@ -0,0 +1,20 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+const EmailNotConfirmedCode = "email_not_confirmed"
+
+var ErrEmailNotConfirmed = errors.New("confirm your email before logging in, a new code was sent to it")
+
+// EmailNotConfirmedResponse refuses a login with the right password to an account whose email is
+// not confirmed yet, when config.RequireConfirmedEmail is on.
+type EmailNotConfirmedResponse struct {
+	Error     string    `json:"error"`
+	Message   string    `json:"message"`
+	Code      string    `json:"code"`
+	TimeStamp time.Time `json:"timeStamp"`
+	Path      string    `json:"path"`
+}
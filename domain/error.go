@@ -1,10 +1,44 @@
 package domain
 
-import "time"
+import (
+	"errors"
+	"time"
+)
 
 type ErrorResponse struct {
-	Error     string    `json:"error"`
-	Message   string    `json:"message"`
-	TimeStamp time.Time `json:"timeStamp"`
-	Path      string    `json:"path"`
+	Error     string       `json:"error"`
+	Message   string       `json:"message"`
+	TimeStamp time.Time    `json:"timeStamp"`
+	Path      string       `json:"path"`
+	Fields    []FieldError `json:"fields,omitempty"`
+}
+
+// FieldError names a field of a payload and the rule its value broke.
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// FieldErrors lists the field errors err carries, for the Fields of an ErrorResponse.
+func FieldErrors(err error) []FieldError {
+	if errors.Is(err, ErrEmailTaken) {
+		return []FieldError{{Field: "email", Rule: "taken", Message: err.Error()}}
+	}
+
+	if errors.Is(err, ErrUsernameTaken) {
+		return []FieldError{{Field: "username", Rule: "taken", Message: err.Error()}}
+	}
+
+	var usernamePolicyError *UsernamePolicyError
+	if errors.As(err, &usernamePolicyError) {
+		return []FieldError{usernamePolicyError.FieldError}
+	}
+
+	var passwordPolicyError *PasswordPolicyError
+	if errors.As(err, &passwordPolicyError) {
+		return []FieldError{{Field: "password", Rule: passwordPolicyError.Rule, Message: passwordPolicyError.Message}}
+	}
+
+	return nil
 }
@@ -0,0 +1,107 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+)
+
+var (
+	ErrCreateInvitation        = errors.New("error to create invitation")
+	ErrSendInvitation          = errors.New("error to send invitation")
+	ErrGetInvitation           = errors.New("error to get invitation")
+	ErrInvitationNotFound      = errors.New("invitation not found")
+	ErrInvitationExpired       = errors.New("this invitation has expired, ask for a new one")
+	ErrInvitationAccepted      = errors.New("this invitation was already accepted")
+	ErrInvitationEmailMismatch = errors.New("the email must be the one the invitation was sent to")
+	ErrRegistrationClosed      = errors.New("registration is by invitation only")
+)
+
+// Invitation lets whoever holds its token register with Email in TenantID, which is then taken as
+// confirmed, and be granted Role. Invitations to an organization also make the user a member of
+// it with OrganizationRole. The token is only stored hashed and works once, before ExpiresAt.
+type Invitation struct {
+	ID               string     `gorm:"column:Id;type:char(36);primary_key"`
+	TenantID         string     `gorm:"column:TenantId;type:varchar(36)"`
+	Email            string     `gorm:"column:Email;type:varchar(255);index"`
+	TokenHash        string     `gorm:"column:TokenHash;type:char(64);uniqueIndex"`
+	InviterID        string     `gorm:"column:InviterId;type:char(36);index"`
+	Role             string     `gorm:"column:Role;type:varchar(50)"`
+	OrganizationID   string     `gorm:"column:OrganizationId;type:char(36);index"`
+	OrganizationRole string     `gorm:"column:OrganizationRole;type:varchar(20)"`
+	ExpiresAt        time.Time  `gorm:"column:ExpiresAt"`
+	AcceptedAt       *time.Time `gorm:"column:AcceptedAt"`
+	CreatedAt        time.Time  `gorm:"column:CreatedAt"`
+}
+
+func (Invitation) TableName() string {
+	return "invitation"
+}
+
+// InvitationPayLoad invites Email. Only admins can pick a Role for the invited user.
+type InvitationPayLoad struct {
+	Email string `json:"email,omitempty" validate:"required,email"`
+	Role  string `json:"role,omitempty" validate:"omitempty,max=50,excludesall=/ "`
+}
+
+func (ip *InvitationPayLoad) Validate() error {
+	ip.Email = NormalizeEmail(ip.Email)
+
+	validate := validator.New()
+	return validate.Struct(ip)
+}
+
+// InvitationRegistrationPayLoad registers the user of an invitation. Email must be the invited one.
+type InvitationRegistrationPayLoad struct {
+	UserPayLoad
+	Token string `json:"token,omitempty" validate:"required"`
+}
+
+func (irp *InvitationRegistrationPayLoad) Validate() error {
+	if err := irp.UserPayLoad.Validate(); err != nil {
+		return err
+	}
+
+	validate := validator.New()
+	return validate.Var(irp.Token, "required")
+}
+
+type InvitationResponse struct {
+	Id        string    `json:"id"`
+	Email     string    `json:"email"`
+	Role      string    `json:"role,omitempty"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func (i *Invitation) ToInvitationResponse() *InvitationResponse {
+	return &InvitationResponse{
+		Id:        i.ID,
+		Email:     i.Email,
+		Role:      i.Role,
+		ExpiresAt: i.ExpiresAt,
+	}
+}
+
+type InvitationHandler interface {
+	Create(ctx echo.Context) error
+	Accept(ctx echo.Context) error
+}
+
+// InvitationService sends invitations and registers the users accepting them. Any logged in user
+// can invite, and config.RegistrationMode decides whether invitations are the only way in.
+type InvitationService interface {
+	Create(ctx context.Context, viewer Viewer, payLoad InvitationPayLoad) (*InvitationResponse, error)
+	InviteToOrganization(ctx context.Context, viewer Viewer, email string, organization Organization, role string) (*InvitationResponse, error)
+	Accept(ctx context.Context, payLoad InvitationRegistrationPayLoad, clientInfo ClientInfo) error
+}
+
+// InvitationRepository keeps the invitations. Accept reports false when the invitation was
+// already accepted or has expired, so it can only be accepted once.
+type InvitationRepository interface {
+	Create(invitation Invitation) error
+	GetByTokenHash(tokenHash string) (*Invitation, error)
+	Accept(id string) (bool, error)
+}
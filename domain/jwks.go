@@ -0,0 +1,20 @@
+package domain
+
+import "github.com/labstack/echo/v4"
+
+type JSONWebKey struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+type JWKSHandler interface {
+	GetKeys(ctx echo.Context) error
+}
@@ -0,0 +1,73 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/OVillas/autentication/config"
+	"github.com/go-playground/validator/v10"
+	"golang.org/x/text/language"
+)
+
+// NormalizeLocale returns the canonical form of a BCP 47 language tag, so that "pt-br" and
+// "pt-BR" are stored alike. An empty locale stays empty and means the configured default.
+func NormalizeLocale(locale string) string {
+	tag, err := language.Parse(locale)
+	if err != nil {
+		return locale
+	}
+
+	return tag.String()
+}
+
+// IsValidLocale tells whether locale is empty or a well-formed BCP 47 language tag.
+func IsValidLocale(locale string) bool {
+	if locale == "" {
+		return true
+	}
+
+	_, err := language.Parse(locale)
+	return err == nil
+}
+
+// IsValidTimezone tells whether timezone is empty or a name of the IANA time zone database, such
+// as America/Sao_Paulo. "Local" is refused, as it depends on where the server runs.
+func IsValidTimezone(timezone string) bool {
+	if timezone == "" {
+		return true
+	}
+
+	if timezone == "Local" {
+		return false
+	}
+
+	_, err := time.LoadLocation(timezone)
+	return err == nil
+}
+
+// PreferredLocale is the locale of the user, or config.DefaultLocale when they have not chosen one.
+func (u *User) PreferredLocale() string {
+	if u.Locale == "" {
+		return config.DefaultLocale
+	}
+
+	return u.Locale
+}
+
+// PreferredTimezone is the time zone of the user, or config.DefaultTimezone when they have not
+// chosen one.
+func (u *User) PreferredTimezone() string {
+	if u.Timezone == "" {
+		return config.DefaultTimezone
+	}
+
+	return u.Timezone
+}
+
+func registerPreferenceValidations(validate *validator.Validate) {
+	validate.RegisterValidation("locale", func(fl validator.FieldLevel) bool {
+		return IsValidLocale(fl.Field().String())
+	})
+	validate.RegisterValidation("iana_timezone", func(fl validator.FieldLevel) bool {
+		return IsValidTimezone(fl.Field().String())
+	})
+}
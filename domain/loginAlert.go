@@ -0,0 +1,88 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+)
+
+var (
+	ErrGetLoginAlertPreference    = errors.New("error to get login alert preference")
+	ErrUpdateLoginAlertPreference = errors.New("error to update login alert preference")
+)
+
+// KnownDevice is an IP and user agent pair a user logged in from. Only a hash of the pair is
+// kept; a login from a pair not seen within config.KnownDeviceRetention sends a new sign-in alert.
+type KnownDevice struct {
+	ID          string    `gorm:"column:Id;type:char(36);primary_key"`
+	UserID      string    `gorm:"column:UserId;type:char(36);uniqueIndex:idx_known_device_user_fingerprint,priority:1"`
+	Fingerprint string    `gorm:"column:Fingerprint;type:char(64);uniqueIndex:idx_known_device_user_fingerprint,priority:2"`
+	LastSeenAt  time.Time `gorm:"column:LastSeenAt;index"`
+	CreatedAt   time.Time `gorm:"column:CreatedAt"`
+}
+
+func (KnownDevice) TableName() string {
+	return "known_device"
+}
+
+// GeoLocation is the approximate place an IP address is in. Any field may be empty.
+type GeoLocation struct {
+	City    string
+	Region  string
+	Country string
+}
+
+func (gl *GeoLocation) String() string {
+	var parts []string
+	for _, part := range []string{gl.City, gl.Region, gl.Country} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+// GeoIPResolver locates IP addresses for the new sign-in alerts. It returns nil when the address
+// cannot be located, such as private addresses.
+type GeoIPResolver interface {
+	Resolve(ip string) (*GeoLocation, error)
+}
+
+type LoginAlertPreferencePayLoad struct {
+	Enabled *bool `json:"enabled" validate:"required"`
+}
+
+func (p *LoginAlertPreferencePayLoad) Validate() error {
+	validate := validator.New()
+	return validate.Struct(p)
+}
+
+type LoginAlertPreferenceResponse struct {
+	Enabled bool `json:"enabled"`
+}
+
+type LoginAlertHandler interface {
+	GetPreference(ctx echo.Context) error
+	UpdatePreference(ctx echo.Context) error
+}
+
+// LoginAlertService tells users about logins from devices they have not used recently. Check
+// returns right away: the device lookup, the location and the email happen in the background.
+type LoginAlertService interface {
+	Check(user User, clientInfo ClientInfo)
+	GetPreference(ctx context.Context, userID string) (*LoginAlertPreferenceResponse, error)
+	UpdatePreference(ctx context.Context, userID string, enabled bool) error
+}
+
+type KnownDeviceRepository interface {
+	GetByFingerprint(userID string, fingerprint string) (*KnownDevice, error)
+	CountByUserID(userID string) (int64, error)
+	Create(knownDevice KnownDevice) error
+	UpdateLastSeenAt(id string) error
+	DeleteSeenBefore(before time.Time) (int64, error)
+}
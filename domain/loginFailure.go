@@ -0,0 +1,25 @@
+package domain
+
+import "time"
+
+// LoginFailureTTL is how long the failures of an identifier are kept after the last one.
+const LoginFailureTTL = 24 * time.Hour
+
+// LoginFailures are the wrong logins in a row under a key: an identifier that matches no account,
+// counted as the ones of an account are so it is slowed down and locked the same way and the
+// answers do not tell whether the account exists, or a two-factor challenge, revoked after
+// TwoFactorChallengeMaxAttempts wrong codes.
+type LoginFailures struct {
+	Attempts     int
+	LastFailedAt *time.Time
+	LockedUntil  *time.Time
+}
+
+type LoginFailureRepository interface {
+	// Get returns the failures of key, zero when there are none.
+	Get(key string) (LoginFailures, error)
+	// Increment counts one more failure of key and returns how many there are in a row.
+	Increment(key string) (int, error)
+	// Lock starts the count of key over and locks it until lockedUntil.
+	Lock(key string, lockedUntil time.Time) error
+}
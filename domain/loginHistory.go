@@ -0,0 +1,94 @@
+package domain
+
+import (
+	"errors"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	LoginOutcomeSuccess       = "success"
+	LoginOutcomeWrongPassword = "wrong_password"
+	LoginOutcomeNoPassword    = "no_password"
+	LoginOutcomeLocked        = "locked"
+	LoginOutcomeSuspended     = "suspended"
+	LoginOutcomeSecondFactor  = "second_factor"
+	LoginOutcomeUnconfirmed   = "email_not_confirmed"
+
+	// LoginHistoryLimit is how many of their latest login attempts users can list.
+	LoginHistoryLimit = 50
+)
+
+var (
+	ErrGetLoginHistory    = errors.New("error to get login history")
+	ErrLoginHistoryCursor = errors.New("login history is only paginated by page")
+)
+
+// LoginAttempt is one login to the account of UserID, successful or not. Attempts to accounts
+// that do not exist are only in the audit trail, as they belong to no user.
+type LoginAttempt struct {
+	ID        string    `gorm:"column:Id;type:char(36);primary_key"`
+	UserID    string    `gorm:"column:UserId;type:char(36);index"`
+	Outcome   string    `gorm:"column:Outcome;type:varchar(20)"`
+	IP        string    `gorm:"column:Ip;type:varchar(45)"`
+	UserAgent string    `gorm:"column:UserAgent;type:varchar(512)"`
+	CreatedAt time.Time `gorm:"column:CreatedAt;index"`
+}
+
+func (LoginAttempt) TableName() string {
+	return "login_attempt"
+}
+
+type LoginAttemptResponse struct {
+	Id        string    `json:"id"`
+	Outcome   string    `json:"outcome"`
+	IP        string    `json:"ip"`
+	UserAgent string    `json:"user_agent"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (la *LoginAttempt) ToLoginAttemptResponse() *LoginAttemptResponse {
+	return &LoginAttemptResponse{
+		Id:        la.ID,
+		Outcome:   la.Outcome,
+		IP:        la.IP,
+		UserAgent: la.UserAgent,
+		CreatedAt: la.CreatedAt,
+	}
+}
+
+// LoginHistoryPage is one page of the latest LoginHistoryLimit login attempts, newest first.
+type LoginHistoryPage = Page[LoginAttemptResponse]
+
+// LoginHistoryQuery selects a page of the login history. Only the latest attempts are listed,
+// so pages are selected by number and cursors are refused.
+type LoginHistoryQuery struct {
+	PageRequest
+}
+
+func (q *LoginHistoryQuery) Validate() error {
+	if q.Cursor != "" {
+		return ErrLoginHistoryCursor
+	}
+
+	return q.PageRequest.Validate()
+}
+
+type LoginHistoryHandler interface {
+	GetMe(ctx echo.Context) error
+}
+
+// LoginHistoryService keeps the login attempts of each user. Record never fails the login it is
+// called from: errors are only logged.
+type LoginHistoryService interface {
+	Record(userID string, outcome string, clientInfo ClientInfo)
+	GetByUserID(userID string, query LoginHistoryQuery) (*LoginHistoryPage, error)
+}
+
+type LoginHistoryRepository interface {
+	Create(loginAttempt LoginAttempt) error
+	GetByUserID(userID string, offset int, limit int) ([]LoginAttempt, int64, error)
+	GetAllByUserID(userID string) ([]LoginAttempt, error)
+	DeleteBefore(before time.Time) (int64, error)
+}
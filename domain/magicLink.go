@@ -0,0 +1,55 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+)
+
+const MagicLinkTTL = 15 * time.Minute
+
+var (
+	ErrInvalidMagicLink = errors.New("magic link is invalid, expired or already used")
+)
+
+// MagicLink lets a user log in by clicking a link sent to their email. The link carries an
+// opaque token that is only stored here hashed and can be used once before ExpiresAt.
+type MagicLink struct {
+	TokenHash string     `gorm:"column:TokenHash;type:char(64);primary_key"`
+	UserID    string     `gorm:"column:UserId;type:char(36);index"`
+	ExpiresAt time.Time  `gorm:"column:ExpiresAt;index"`
+	UsedAt    *time.Time `gorm:"column:UsedAt"`
+	CreatedAt time.Time  `gorm:"column:CreatedAt"`
+}
+
+func (MagicLink) TableName() string {
+	return "magic_link"
+}
+
+type MagicLinkPayLoad struct {
+	Email string `json:"email,omitempty" validate:"required,email"`
+}
+
+type MagicLinkHandler interface {
+	Send(ctx echo.Context) error
+	Verify(ctx echo.Context) error
+}
+
+type MagicLinkService interface {
+	Send(ctx context.Context, tenantID string, email string) error
+	Verify(ctx context.Context, token string, deviceToken string, clientInfo ClientInfo) (*LoginResult, error)
+}
+
+type MagicLinkRepository interface {
+	Create(magicLink MagicLink) error
+	GetByTokenHash(tokenHash string) (*MagicLink, error)
+	Use(tokenHash string) (bool, error)
+}
+
+func (mlp *MagicLinkPayLoad) Validate() error {
+	validate := validator.New()
+	return validate.Struct(mlp)
+}
@@ -0,0 +1,152 @@
+package domain
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+const (
+	MetadataSectionUser  = "user"
+	MetadataSectionAdmin = "admin"
+	// MetadataMaxSize is the most bytes the JSON of a section may take.
+	MetadataMaxSize = 4 * 1024
+	// MetadataMaxKeys is the most keys a section may hold.
+	MetadataMaxKeys = 50
+)
+
+var (
+	ErrMetadataTooLarge    = fmt.Errorf("metadata must take at most %d bytes per section", MetadataMaxSize)
+	ErrMetadataTooManyKeys = fmt.Errorf("metadata must have at most %d keys per section", MetadataMaxKeys)
+	ErrUpdateMetadata      = errors.New("error to update metadata")
+)
+
+// UserMetadata is app-specific data kept on the user, such as onboarding flags or a plan tier. The
+// user edits the User section; the Admin section is read-only to them and edited by admins. It is
+// stored as a JSON column.
+type UserMetadata struct {
+	User  map[string]any `json:"user"`
+	Admin map[string]any `json:"admin"`
+}
+
+// Merge applies patch to section at the top level: a key set to null is removed and any other key
+// replaces the value it had. The section must still fit MetadataMaxSize and MetadataMaxKeys.
+func (um *UserMetadata) Merge(section string, patch map[string]any) error {
+	target := &um.User
+	if section == MetadataSectionAdmin {
+		target = &um.Admin
+	}
+
+	merged := map[string]any{}
+	for key, value := range *target {
+		merged[key] = value
+	}
+	for key, value := range patch {
+		if value == nil {
+			delete(merged, key)
+			continue
+		}
+		merged[key] = value
+	}
+
+	if len(merged) > MetadataMaxKeys {
+		return ErrMetadataTooManyKeys
+	}
+
+	encoded, err := json.Marshal(merged)
+	if err != nil {
+		return err
+	}
+
+	if len(encoded) > MetadataMaxSize {
+		return ErrMetadataTooLarge
+	}
+
+	*target = merged
+	return nil
+}
+
+// orEmpty has empty sections instead of nil ones, so that they are sent as {} and not null.
+func (um UserMetadata) orEmpty() UserMetadata {
+	if um.User == nil {
+		um.User = map[string]any{}
+	}
+	if um.Admin == nil {
+		um.Admin = map[string]any{}
+	}
+
+	return um
+}
+
+func (um UserMetadata) Value() (driver.Value, error) {
+	encoded, err := json.Marshal(um.orEmpty())
+	if err != nil {
+		return nil, err
+	}
+
+	return string(encoded), nil
+}
+
+// Scan reads the column, which is NULL for users who never had any metadata.
+func (um *UserMetadata) Scan(value any) error {
+	var encoded []byte
+	switch value := value.(type) {
+	case nil:
+		*um = UserMetadata{}.orEmpty()
+		return nil
+	case []byte:
+		encoded = value
+	case string:
+		encoded = []byte(value)
+	default:
+		return fmt.Errorf("cannot scan %T into UserMetadata", value)
+	}
+
+	var metadata UserMetadata
+	if err := json.Unmarshal(encoded, &metadata); err != nil {
+		return err
+	}
+
+	*um = metadata.orEmpty()
+	return nil
+}
+
+func (UserMetadata) GormDataType() string {
+	return "json"
+}
+
+// GormDBDataType is JSONB on Postgres, where it can be indexed, and JSON on MySQL.
+func (UserMetadata) GormDBDataType(db *gorm.DB, field *schema.Field) string {
+	if db.Dialector.Name() == "postgres" {
+		return "JSONB"
+	}
+
+	return "JSON"
+}
+
+// MetadataPayLoad updates a section of the metadata, as described in UserMetadata.Merge.
+type MetadataPayLoad map[string]any
+
+func (mp MetadataPayLoad) Validate() error {
+	validate := validator.New()
+	return validate.Var(map[string]any(mp), fmt.Sprintf("required,max=%d,dive,keys,required,max=64,endkeys", MetadataMaxKeys))
+}
+
+type MetadataHandler interface {
+	UpdateMe(ctx echo.Context) error
+	AdminUpdate(ctx echo.Context) error
+}
+
+// MetadataService updates the sections of the metadata. UpdateMe only reaches the User section of
+// the caller; AdminUpdate the Admin section of any user of the tenant of the viewer.
+type MetadataService interface {
+	UpdateMe(ctx context.Context, userID string, patch MetadataPayLoad) (*UserMetadata, error)
+	AdminUpdate(ctx context.Context, viewer Viewer, userID string, patch MetadataPayLoad) (*UserMetadata, error)
+}
@@ -0,0 +1,14 @@
+package domain
+
+import "time"
+
+// SchemaMigration records that the migration of Version was applied to the database.
+type SchemaMigration struct {
+	Version   int       `gorm:"column:Version;primary_key;autoIncrement:false"`
+	Name      string    `gorm:"column:Name;type:varchar(100)"`
+	AppliedAt time.Time `gorm:"column:AppliedAt"`
+}
+
+func (SchemaMigration) TableName() string {
+	return "schema_migration"
+}
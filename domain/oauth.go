@@ -0,0 +1,222 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	OAuthClientSecretPrefix    = "cs_"
+	ResponseTypeCode           = "code"
+	GrantTypeAuthorizationCode = "authorization_code"
+	GrantTypeRefreshToken      = "refresh_token"
+	GrantTypeClientCredentials = "client_credentials"
+	CodeChallengeMethodS256    = "S256"
+	AuthorizationCodeTTL       = 60 * time.Second
+)
+
+var (
+	ErrCreateOAuthClient       = errors.New("error to create oauth client")
+	ErrGetOAuthClient          = errors.New("error to get oauth client")
+	ErrDeleteOAuthClient       = errors.New("error to delete oauth client")
+	ErrOAuthClientNotFound     = errors.New("oauth client not found")
+	ErrInvalidClient           = errors.New("client authentication failed")
+	ErrInvalidRedirectURI      = errors.New("redirect_uri is not registered for this client")
+	ErrCreateAuthorizationCode = errors.New("error to create authorization code")
+	ErrInvalidGrant            = errors.New("authorization code is invalid, expired or already used")
+	ErrUnsupportedGrantType    = errors.New("unsupported grant_type")
+	ErrUnauthorizedClient      = errors.New("public clients cannot use the client credentials grant")
+	ErrInvalidScope            = errors.New("none of the requested scopes is allowed for this client")
+	ErrClientNotAllowed        = errors.New("machine clients cannot perform this action")
+)
+
+// OAuthClient is an application allowed to run the authorization code flow. Public clients,
+// such as mobile apps, cannot keep a secret and rely on PKCE alone. RedirectURIs holds the
+// exact callback URLs accepted for the client and Scopes the scopes it may request through the
+// client credentials grant, both separated by commas.
+type OAuthClient struct {
+	ID           string    `gorm:"column:Id;type:char(36);primary_key"`
+	Name         string    `gorm:"column:Name;type:varchar(100)"`
+	SecretHash   string    `gorm:"column:SecretHash;type:char(64)"`
+	RedirectURIs string    `gorm:"column:RedirectUris;type:varchar(2048)"`
+	Scopes       string    `gorm:"column:Scopes;type:varchar(255)"`
+	Public       bool      `gorm:"column:Public;default:false"`
+	CreatedAt    time.Time `gorm:"column:CreatedAt"`
+}
+
+func (OAuthClient) TableName() string {
+	return "oauth_client"
+}
+
+func (oc *OAuthClient) AllowsRedirectURI(redirectURI string) bool {
+	for _, allowed := range strings.Split(oc.RedirectURIs, ",") {
+		if allowed == redirectURI {
+			return true
+		}
+	}
+
+	return false
+}
+
+// GrantScopes returns, space separated, the requested scopes the client is allowed. An empty
+// request is granted every allowed scope.
+func (oc *OAuthClient) GrantScopes(requested string) string {
+	allowed := strings.Split(oc.Scopes, ",")
+	if strings.TrimSpace(requested) == "" {
+		return strings.Join(allowed, " ")
+	}
+
+	var granted []string
+	for _, scope := range strings.Fields(requested) {
+		for _, allowedScope := range allowed {
+			if scope == allowedScope {
+				granted = append(granted, scope)
+				break
+			}
+		}
+	}
+
+	return strings.Join(granted, " ")
+}
+
+// AuthorizationCode is only stored hashed and can be exchanged once, by the client it was
+// issued to, with the verifier matching CodeChallenge.
+type AuthorizationCode struct {
+	CodeHash      string     `gorm:"column:CodeHash;type:char(64);primary_key"`
+	ClientID      string     `gorm:"column:ClientId;type:char(36)"`
+	UserID        string     `gorm:"column:UserId;type:char(36);index"`
+	RedirectURI   string     `gorm:"column:RedirectUri;type:varchar(512)"`
+	CodeChallenge string     `gorm:"column:CodeChallenge;type:varchar(128)"`
+	Scope         string     `gorm:"column:Scope;type:varchar(255)"`
+	ExpiresAt     time.Time  `gorm:"column:ExpiresAt;index"`
+	UsedAt        *time.Time `gorm:"column:UsedAt"`
+	CreatedAt     time.Time  `gorm:"column:CreatedAt"`
+}
+
+func (AuthorizationCode) TableName() string {
+	return "authorization_code"
+}
+
+type OAuthClientPayLoad struct {
+	Name         string   `json:"name,omitempty" validate:"required,min=1,max=100"`
+	RedirectURIs []string `json:"redirect_uris,omitempty" validate:"required,min=1,dive,required,uri,excludesall=0x2C"`
+	Scopes       []string `json:"scopes,omitempty" validate:"omitempty,dive,oneof=read:user write:user"`
+	Public       bool     `json:"public,omitempty"`
+}
+
+type OAuthClientResponse struct {
+	ClientID     string    `json:"client_id"`
+	Name         string    `json:"name"`
+	RedirectURIs []string  `json:"redirect_uris"`
+	Scopes       []string  `json:"scopes,omitempty"`
+	Public       bool      `json:"public"`
+	CreatedAt    time.Time `json:"created_at"`
+	ClientSecret string    `json:"client_secret,omitempty"`
+}
+
+// AuthorizePayLoad holds the authorization request query, plus the credentials once the login
+// form is posted back.
+type AuthorizePayLoad struct {
+	ResponseType        string `query:"response_type" form:"response_type" validate:"required,eq=code"`
+	ClientID            string `query:"client_id" form:"client_id" validate:"required"`
+	RedirectURI         string `query:"redirect_uri" form:"redirect_uri" validate:"required"`
+	CodeChallenge       string `query:"code_challenge" form:"code_challenge" validate:"required,min=43,max=128"`
+	CodeChallengeMethod string `query:"code_challenge_method" form:"code_challenge_method" validate:"required,eq=S256"`
+	Scope               string `query:"scope" form:"scope" validate:"max=255"`
+	State               string `query:"state" form:"state" validate:"max=512"`
+	Username            string `form:"username"`
+	Password            string `form:"password"`
+}
+
+type OAuthTokenPayLoad struct {
+	GrantType    string `form:"grant_type" validate:"required,oneof=authorization_code refresh_token client_credentials"`
+	Code         string `form:"code" validate:"required_if=GrantType authorization_code"`
+	RedirectURI  string `form:"redirect_uri" validate:"required_if=GrantType authorization_code"`
+	CodeVerifier string `form:"code_verifier" validate:"required_if=GrantType authorization_code,omitempty,min=43,max=128"`
+	RefreshToken string `form:"refresh_token" validate:"required_if=GrantType refresh_token"`
+	Scope        string `form:"scope" validate:"max=255"`
+	ClientID     string `form:"client_id"`
+	ClientSecret string `form:"client_secret"`
+}
+
+type ClientTokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+	Scope       string `json:"scope"`
+}
+
+// OAuthErrorResponse is the error body defined by RFC 6749 for the token endpoint.
+type OAuthErrorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+type OAuthHandler interface {
+	Authorize(ctx echo.Context) error
+	AuthorizeLogin(ctx echo.Context) error
+	Token(ctx echo.Context) error
+}
+
+type OAuthService interface {
+	ValidateAuthorizeRequest(payLoad AuthorizePayLoad) error
+	Authorize(ctx context.Context, payLoad AuthorizePayLoad, clientInfo ClientInfo) (string, error)
+	Token(ctx context.Context, payLoad OAuthTokenPayLoad, clientInfo ClientInfo) (*LoginResponse, error)
+	ClientCredentials(payLoad OAuthTokenPayLoad) (*ClientTokenResponse, error)
+}
+
+type OAuthClientHandler interface {
+	Create(ctx echo.Context) error
+	GetAll(ctx echo.Context) error
+	Delete(ctx echo.Context) error
+}
+
+type OAuthClientService interface {
+	Create(viewer Viewer, payLoad OAuthClientPayLoad) (*OAuthClientResponse, error)
+	GetAll() ([]OAuthClientResponse, error)
+	Delete(viewer Viewer, id string) error
+}
+
+type OAuthClientRepository interface {
+	Create(oauthClient OAuthClient) error
+	GetAll() ([]OAuthClient, error)
+	GetById(id string) (*OAuthClient, error)
+	Delete(id string) (bool, error)
+}
+
+type AuthorizationCodeRepository interface {
+	Create(authorizationCode AuthorizationCode) error
+	GetByCodeHash(codeHash string) (*AuthorizationCode, error)
+	Use(codeHash string) (bool, error)
+}
+
+func (ocp *OAuthClientPayLoad) Validate() error {
+	validate := validator.New()
+	return validate.Struct(ocp)
+}
+
+func (ap *AuthorizePayLoad) Validate() error {
+	validate := validator.New()
+	return validate.Struct(ap)
+}
+
+func (otp *OAuthTokenPayLoad) Validate() error {
+	validate := validator.New()
+	return validate.Struct(otp)
+}
+
+func (oc *OAuthClient) ToOAuthClientResponse() *OAuthClientResponse {
+	return &OAuthClientResponse{
+		ClientID:     oc.ID,
+		Name:         oc.Name,
+		RedirectURIs: strings.Split(oc.RedirectURIs, ","),
+		Scopes:       strings.FieldsFunc(oc.Scopes, func(r rune) bool { return r == ',' }),
+		Public:       oc.Public,
+		CreatedAt:    oc.CreatedAt,
+	}
+}
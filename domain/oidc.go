@@ -0,0 +1,51 @@
+package domain
+
+import (
+	"context"
+
+	"github.com/labstack/echo/v4"
+)
+
+type OpenIDConfiguration struct {
+	Issuer                            string   `json:"issuer"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	UserinfoEndpoint                  string   `json:"userinfo_endpoint"`
+	JwksURI                           string   `json:"jwks_uri"`
+	ResponseTypesSupported            []string `json:"response_types_supported"`
+	GrantTypesSupported               []string `json:"grant_types_supported"`
+	CodeChallengeMethodsSupported     []string `json:"code_challenge_methods_supported"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+	SubjectTypesSupported             []string `json:"subject_types_supported"`
+	IDTokenSigningAlgValuesSupported  []string `json:"id_token_signing_alg_values_supported"`
+	ScopesSupported                   []string `json:"scopes_supported"`
+	ClaimsSupported                   []string `json:"claims_supported"`
+}
+
+// UserInfoResponse carries the standard OIDC claims, the same ones found in the ID token.
+type UserInfoResponse struct {
+	Sub               string `json:"sub"`
+	Name              string `json:"name"`
+	PreferredUsername string `json:"preferred_username"`
+	Email             string `json:"email"`
+	EmailVerified     bool   `json:"email_verified"`
+}
+
+type OIDCHandler interface {
+	GetConfiguration(ctx echo.Context) error
+	GetUserInfo(ctx echo.Context) error
+}
+
+type OIDCService interface {
+	GetUserInfo(ctx context.Context, userID string) (*UserInfoResponse, error)
+}
+
+func (u *User) ToUserInfoResponse() *UserInfoResponse {
+	return &UserInfoResponse{
+		Sub:               u.ID,
+		Name:              u.Name,
+		PreferredUsername: u.Username,
+		Email:             u.Email,
+		EmailVerified:     u.EmailConfirmed,
+	}
+}
@@ -0,0 +1,208 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+)
+
+// The roles of a member within an organization. They are unrelated to the roles of the API:
+// owners manage everything, admins manage the members below owner and members only belong.
+const (
+	OrganizationRoleOwner  = "owner"
+	OrganizationRoleAdmin  = "admin"
+	OrganizationRoleMember = "member"
+)
+
+var (
+	ErrCreateOrganization   = errors.New("error to create organization")
+	ErrGetOrganization      = errors.New("error to get organization")
+	ErrOrganizationNotFound = errors.New("organization not found")
+	ErrMemberNotFound       = errors.New("member not found")
+	ErrAlreadyMember        = errors.New("this user is already a member of the organization")
+	ErrAddMember            = errors.New("error to add member")
+	ErrUpdateMember         = errors.New("error to update member")
+	ErrRemoveMember         = errors.New("error to remove member")
+	ErrLastOwner            = errors.New("the last owner cannot leave the organization or stop being an owner")
+	ErrSwitchOrganization   = errors.New("error to switch organization")
+)
+
+type Organization struct {
+	ID        string    `gorm:"column:Id;type:char(36);primary_key"`
+	Name      string    `gorm:"column:Name;type:varchar(100)"`
+	CreatedAt time.Time `gorm:"column:CreatedAt;autoCreateTime"`
+}
+
+func (Organization) TableName() string {
+	return "organization"
+}
+
+// Membership puts a user in an organization with one of the OrganizationRole roles.
+type Membership struct {
+	OrganizationID string    `gorm:"column:OrganizationId;type:char(36);primary_key"`
+	UserID         string    `gorm:"column:UserId;type:char(36);primary_key;index"`
+	Role           string    `gorm:"column:Role;type:varchar(20)"`
+	CreatedAt      time.Time `gorm:"column:CreatedAt;autoCreateTime"`
+}
+
+func (Membership) TableName() string {
+	return "membership"
+}
+
+// CanManage tells whether the member can invite, change and remove members holding role.
+// Only owners manage other owners.
+func (m *Membership) CanManage(role string) bool {
+	if m.Role == OrganizationRoleOwner {
+		return true
+	}
+
+	return m.Role == OrganizationRoleAdmin && role != OrganizationRoleOwner
+}
+
+type OrganizationPayLoad struct {
+	Name string `json:"name,omitempty" validate:"required,min=1,max=100"`
+}
+
+func (op *OrganizationPayLoad) Validate() error {
+	validate := validator.New()
+	return validate.Struct(op)
+}
+
+// MemberInvitationPayLoad adds the user of Email to the organization, or invites them to register
+// when there is none. Role defaults to member.
+type MemberInvitationPayLoad struct {
+	Email string `json:"email,omitempty" validate:"required,email"`
+	Role  string `json:"role,omitempty" validate:"omitempty,oneof=owner admin member"`
+}
+
+func (mip *MemberInvitationPayLoad) Validate() error {
+	mip.Email = NormalizeEmail(mip.Email)
+	if mip.Role == "" {
+		mip.Role = OrganizationRoleMember
+	}
+
+	validate := validator.New()
+	return validate.Struct(mip)
+}
+
+type MemberRolePayLoad struct {
+	Role string `json:"role,omitempty" validate:"required,oneof=owner admin member"`
+}
+
+func (mrp *MemberRolePayLoad) Validate() error {
+	validate := validator.New()
+	return validate.Struct(mrp)
+}
+
+// OrganizationSwitchPayLoad picks the organization the access tokens of the session speak for.
+// An empty OrganizationID leaves every organization.
+type OrganizationSwitchPayLoad struct {
+	OrganizationID string `json:"organization_id,omitempty" validate:"omitempty,uuid"`
+}
+
+func (osp *OrganizationSwitchPayLoad) Validate() error {
+	validate := validator.New()
+	return validate.Struct(osp)
+}
+
+// UserOrganization is an organization with the role the user holds in it.
+type UserOrganization struct {
+	ID        string    `gorm:"column:Id"`
+	Name      string    `gorm:"column:Name"`
+	Role      string    `gorm:"column:Role"`
+	CreatedAt time.Time `gorm:"column:CreatedAt"`
+}
+
+// Member is a membership with the public profile of its user.
+type Member struct {
+	UserID    string    `gorm:"column:UserId"`
+	Name      string    `gorm:"column:Name"`
+	Username  string    `gorm:"column:Username"`
+	Role      string    `gorm:"column:Role"`
+	CreatedAt time.Time `gorm:"column:CreatedAt"`
+}
+
+type OrganizationResponse struct {
+	Id        string    `json:"id"`
+	Name      string    `json:"name"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type MemberResponse struct {
+	UserId    string    `json:"user_id"`
+	Name      string    `json:"name"`
+	Username  string    `json:"username"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// MemberInvitationResponse tells whether the user was added right away or, not being registered
+// yet, was sent an invitation.
+type MemberInvitationResponse struct {
+	Member     *MemberResponse     `json:"member,omitempty"`
+	Invitation *InvitationResponse `json:"invitation,omitempty"`
+}
+
+// AccessTokenResponse is an access token issued for a session that is already open.
+type AccessTokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+func (uo *UserOrganization) ToOrganizationResponse() *OrganizationResponse {
+	return &OrganizationResponse{
+		Id:        uo.ID,
+		Name:      uo.Name,
+		Role:      uo.Role,
+		CreatedAt: uo.CreatedAt,
+	}
+}
+
+func (m *Member) ToMemberResponse() *MemberResponse {
+	return &MemberResponse{
+		UserId:    m.UserID,
+		Name:      m.Name,
+		Username:  m.Username,
+		Role:      m.Role,
+		CreatedAt: m.CreatedAt,
+	}
+}
+
+type OrganizationHandler interface {
+	Create(ctx echo.Context) error
+	GetMine(ctx echo.Context) error
+	GetMembers(ctx echo.Context) error
+	InviteMember(ctx echo.Context) error
+	UpdateMemberRole(ctx echo.Context) error
+	RemoveMember(ctx echo.Context) error
+}
+
+// OrganizationService manages organizations on behalf of their members. Callers who are not
+// members get ErrOrganizationNotFound, so organizations cannot be discovered by ID.
+type OrganizationService interface {
+	Create(viewer Viewer, payLoad OrganizationPayLoad) (*OrganizationResponse, error)
+	GetMine(userID string) ([]OrganizationResponse, error)
+	GetMembers(viewer Viewer, organizationID string) ([]MemberResponse, error)
+	InviteMember(ctx context.Context, viewer Viewer, organizationID string, payLoad MemberInvitationPayLoad) (*MemberInvitationResponse, error)
+	UpdateMemberRole(viewer Viewer, organizationID string, userID string, role string) error
+	RemoveMember(viewer Viewer, organizationID string, userID string) error
+}
+
+// OrganizationRepository keeps organizations and their memberships. UpdateRole and RemoveMember
+// return ErrLastOwner rather than leave an organization without owners, and ErrMemberNotFound
+// when the user is not a member. AddMember reports false when the user already was one.
+type OrganizationRepository interface {
+	Create(organization Organization, owner Membership) error
+	GetById(id string) (*Organization, error)
+	GetByUserID(userID string) ([]UserOrganization, error)
+	GetMembership(organizationID string, userID string) (*Membership, error)
+	GetMembers(organizationID string) ([]Member, error)
+	AddMember(membership Membership) (bool, error)
+	UpdateRole(organizationID string, userID string, role string) error
+	RemoveMember(organizationID string, userID string) error
+}
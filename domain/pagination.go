@@ -0,0 +1,118 @@
+package domain
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+)
+
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+var (
+	ErrInvalidCursor  = errors.New("invalid cursor")
+	ErrCursorWithPage = errors.New("cursor and page cannot be used together")
+)
+
+// Cursor is the position of the last item of a page in the order of its listing, the sorted
+// column followed by ID. CreatedAt holds the position in the default (CreatedAt, ID) order and Key
+// the one in any other; Sort names that other order, so a cursor cannot be replayed against a
+// listing sorted differently. Clients only see it encoded, as an opaque string.
+type Cursor struct {
+	CreatedAt time.Time `json:"c,omitempty"`
+	Key       string    `json:"k,omitempty"`
+	Sort      string    `json:"s,omitempty"`
+	ID        string    `json:"i"`
+}
+
+func (c Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func DecodeCursor(encoded string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var cursor Cursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.ID == "" {
+		return nil, ErrInvalidCursor
+	}
+
+	return &cursor, nil
+}
+
+// PageRequest selects a page of a listing, either by number or, with Cursor, as the items after
+// the next_cursor of a previous page. Page starts at 1; both fields fall back to the first page of
+// DefaultPageSize items when left out.
+type PageRequest struct {
+	Page    int     `query:"page" validate:"min=1"`
+	PerPage int     `query:"per_page" validate:"min=1,max=100"`
+	Cursor  string  `query:"cursor"`
+	After   *Cursor `json:"-"`
+}
+
+func (pr *PageRequest) Offset() int {
+	return (pr.Page - 1) * pr.PerPage
+}
+
+// Validate fills the fields left out with their defaults and decodes Cursor into After before
+// checking them.
+func (pr *PageRequest) Validate() error {
+	return pr.validate("")
+}
+
+// validate is Validate for a listing in the order named sort, which only cursors issued by that
+// same order can continue.
+func (pr *PageRequest) validate(sort string) error {
+	if pr.Cursor != "" && pr.Page != 0 {
+		return ErrCursorWithPage
+	}
+
+	if pr.Page == 0 {
+		pr.Page = 1
+	}
+
+	if pr.PerPage == 0 {
+		pr.PerPage = DefaultPageSize
+	}
+
+	if pr.Cursor != "" {
+		after, err := DecodeCursor(pr.Cursor)
+		if err != nil {
+			return err
+		}
+		if after.Sort != sort || (sort == "" && after.CreatedAt.IsZero()) {
+			return ErrInvalidCursor
+		}
+		pr.After = after
+	}
+
+	validate := validator.New()
+	return validate.Struct(pr)
+}
+
+// Page is one page of a listing. Page is left out for pages read with a cursor, and NextCursor
+// is only set when another page follows.
+type Page[T any] struct {
+	Items      []T    `json:"items"`
+	Page       int    `json:"page,omitempty"`
+	PerPage    int    `json:"per_page"`
+	Total      int64  `json:"total"`
+	HasNext    bool   `json:"has_next"`
+	HasPrev    bool   `json:"has_prev"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// UserPage is one page of users with their account data.
+type UserPage = Page[UserResponse]
+
+// PublicUserPage is one page of users with only their public profile.
+type PublicUserPage = Page[PublicUserResponse]
@@ -0,0 +1,169 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/OVillas/autentication/config"
+	"github.com/OVillas/autentication/secure"
+	"github.com/go-playground/validator/v10"
+)
+
+const (
+	PasswordRuleMinLength = "min_length"
+	PasswordRuleMaxLength = "max_length"
+	PasswordRuleUpper     = "upper"
+	PasswordRuleLower     = "lower"
+	PasswordRuleDigit     = "digit"
+	PasswordRuleSymbol    = "symbol"
+	PasswordRuleIdentity  = "identity"
+)
+
+var ErrPasswordPolicy = errors.New("password does not meet the password policy")
+
+// PasswordPolicyError names the rule of the policy a password broke, so clients can tell the
+// user what to change. It matches ErrPasswordPolicy with errors.Is.
+type PasswordPolicyError struct {
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+func (ppe *PasswordPolicyError) Error() string {
+	return ppe.Message
+}
+
+func (ppe *PasswordPolicyError) Is(target error) bool {
+	return target == ErrPasswordPolicy
+}
+
+// PasswordPolicy holds the rules new passwords must follow. Lengths count characters, not bytes,
+// after the Unicode normalization the password is also hashed with.
+type PasswordPolicy struct {
+	MinLength     int
+	MaxLength     int
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+}
+
+func CurrentPasswordPolicy() PasswordPolicy {
+	return PasswordPolicy{
+		MinLength:     config.PasswordPolicy.MinLength,
+		MaxLength:     config.PasswordPolicy.MaxLength,
+		RequireUpper:  config.PasswordPolicy.RequireUpper,
+		RequireLower:  config.PasswordPolicy.RequireLower,
+		RequireDigit:  config.PasswordPolicy.RequireDigit,
+		RequireSymbol: config.PasswordPolicy.RequireSymbol,
+	}
+}
+
+// Check returns a *PasswordPolicyError for the first rule the password breaks.
+func (pp PasswordPolicy) Check(password string) error {
+	if violations := pp.Violations(password); len(violations) > 0 {
+		return &violations[0]
+	}
+
+	return nil
+}
+
+// Violations lists every rule the password breaks, in the order Check reports them.
+func (pp PasswordPolicy) Violations(password string) []PasswordPolicyError {
+	password = secure.NormalizePassword(password)
+
+	var violations []PasswordPolicyError
+
+	length := utf8.RuneCountInString(password)
+	if length < pp.MinLength {
+		violations = append(violations, PasswordPolicyError{
+			Rule:    PasswordRuleMinLength,
+			Message: fmt.Sprintf("password must have at least %d characters", pp.MinLength),
+		})
+	}
+
+	if pp.MaxLength > 0 && length > pp.MaxLength {
+		violations = append(violations, PasswordPolicyError{
+			Rule:    PasswordRuleMaxLength,
+			Message: fmt.Sprintf("password must have at most %d characters", pp.MaxLength),
+		})
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r):
+			hasSymbol = true
+		}
+	}
+
+	if pp.RequireUpper && !hasUpper {
+		violations = append(violations, PasswordPolicyError{Rule: PasswordRuleUpper, Message: "password must have an uppercase letter"})
+	}
+	if pp.RequireLower && !hasLower {
+		violations = append(violations, PasswordPolicyError{Rule: PasswordRuleLower, Message: "password must have a lowercase letter"})
+	}
+	if pp.RequireDigit && !hasDigit {
+		violations = append(violations, PasswordPolicyError{Rule: PasswordRuleDigit, Message: "password must have a digit"})
+	}
+	if pp.RequireSymbol && !hasSymbol {
+		violations = append(violations, PasswordPolicyError{Rule: PasswordRuleSymbol, Message: "password must have a symbol"})
+	}
+
+	return violations
+}
+
+// PasswordStrengthPayLoad is a candidate password to rate. Email and username, when given, lower
+// the score of passwords built from them.
+type PasswordStrengthPayLoad struct {
+	Password string `json:"password,omitempty" validate:"required,max=256"`
+	Email    string `json:"email,omitempty" validate:"omitempty,max=255"`
+	Username string `json:"username,omitempty" validate:"omitempty,max=255"`
+}
+
+// PasswordStrengthResponse rates a password from 0 (too guessable) to 4 (very unguessable), like
+// zxcvbn. Acceptable tells whether the API would take it, FailedRules why not.
+type PasswordStrengthResponse struct {
+	Score            int                   `json:"score"`
+	CrackTimeSeconds float64               `json:"crack_time_seconds"`
+	CrackTimeDisplay string                `json:"crack_time_display"`
+	Acceptable       bool                  `json:"acceptable"`
+	FailedRules      []PasswordPolicyError `json:"failed_rules"`
+}
+
+func (psp *PasswordStrengthPayLoad) Validate() error {
+	validate := validator.New()
+	return validate.Struct(psp)
+}
+
+// newPasswordValidator returns a validator that knows the "password" tag, which applies the
+// current PasswordPolicy.
+func newPasswordValidator() *validator.Validate {
+	validate := validator.New()
+	validate.RegisterValidation("password", func(fl validator.FieldLevel) bool {
+		return CurrentPasswordPolicy().Check(fl.Field().String()) == nil
+	})
+	return validate
+}
+
+// passwordPolicyError replaces the generic validator message of a failed "password" tag with the
+// rule that was broken.
+func passwordPolicyError(err error, password string) error {
+	var validationErrors validator.ValidationErrors
+	if errors.As(err, &validationErrors) {
+		for _, fieldError := range validationErrors {
+			if fieldError.Tag() == "password" {
+				return CurrentPasswordPolicy().Check(password)
+			}
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,46 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+// EmailChangeRevertTTL is how long the old address can undo an email change.
+const EmailChangeRevertTTL = 72 * time.Hour
+
+var (
+	ErrCreatePendingEmail  = errors.New("error to request email change")
+	ErrGetPendingEmail     = errors.New("error to get pending email change")
+	ErrConfirmEmailChange  = errors.New("error to change email")
+	ErrInvalidRevertLink   = errors.New("revert link is invalid, expired or already used")
+	ErrRevertEmailChange   = errors.New("error to revert email change")
+	ErrOldEmailUnavailable = errors.New("the previous email now belongs to another account")
+)
+
+// PendingEmail is an email change waiting for the new address to be confirmed with a code. It
+// is kept after ConfirmedAt so that the old address, warned with a link carrying an opaque token
+// only stored here hashed, can undo the change until RevertExpiresAt.
+type PendingEmail struct {
+	RevertTokenHash string     `gorm:"column:RevertTokenHash;type:char(64);primary_key"`
+	UserID          string     `gorm:"column:UserId;type:char(36);index"`
+	OldEmail        string     `gorm:"column:OldEmail;type:varchar(255)"`
+	NewEmail        string     `gorm:"column:NewEmail;type:varchar(255);index"`
+	RevertExpiresAt time.Time  `gorm:"column:RevertExpiresAt;index"`
+	ConfirmedAt     *time.Time `gorm:"column:ConfirmedAt"`
+	CreatedAt       time.Time  `gorm:"column:CreatedAt"`
+}
+
+func (PendingEmail) TableName() string {
+	return "pending_email"
+}
+
+// PendingEmailRepository keeps email changes. GetUnconfirmedByNewEmail returns the latest change
+// to that address, by a user of the tenant, still waiting for its code. Confirm and Delete
+// report false when another request got there first.
+type PendingEmailRepository interface {
+	Create(pendingEmail PendingEmail) error
+	GetUnconfirmedByNewEmail(tenantID string, newEmail string) (*PendingEmail, error)
+	GetByRevertTokenHash(revertTokenHash string) (*PendingEmail, error)
+	Confirm(revertTokenHash string) (bool, error)
+	Delete(revertTokenHash string) (bool, error)
+}
@@ -0,0 +1,77 @@
+package domain
+
+import (
+	"errors"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	PermissionUsersRead   = "users:read"
+	PermissionUsersWrite  = "users:write"
+	PermissionUsersDelete = "users:delete"
+)
+
+// Permissions are every permission known to the API. They are created at startup and the admin
+// role is granted all of them.
+var Permissions = []string{PermissionUsersRead, PermissionUsersWrite, PermissionUsersDelete}
+
+var (
+	ErrGetPermission             = errors.New("error to get permission")
+	ErrPermissionNotFound        = errors.New("permission not found")
+	ErrGrantPermission           = errors.New("error to grant permission")
+	ErrRevokePermission          = errors.New("error to revoke permission")
+	ErrPermissionNotGranted      = errors.New("role does not have this permission")
+	ErrAdminPermissionsImmutable = errors.New("the admin role always has every permission")
+)
+
+type Permission struct {
+	ID        string    `gorm:"column:Id;type:char(36);primary_key"`
+	Name      string    `gorm:"column:Name;type:varchar(50);uniqueIndex"`
+	CreatedAt time.Time `gorm:"column:CreatedAt"`
+}
+
+func (Permission) TableName() string {
+	return "permission"
+}
+
+// RolePermission grants a permission to every user with the role. The effective permissions of
+// a user travel in its access tokens, so changing them also invalidates the tokens of the role's
+// users.
+type RolePermission struct {
+	RoleID       string    `gorm:"column:RoleId;type:char(36);primary_key"`
+	PermissionID string    `gorm:"column:PermissionId;type:char(36);primary_key;index"`
+	CreatedAt    time.Time `gorm:"column:CreatedAt"`
+}
+
+func (RolePermission) TableName() string {
+	return "role_permission"
+}
+
+type PermissionPayLoad struct {
+	Permission string `json:"permission,omitempty" validate:"required,max=50"`
+}
+
+func (pp *PermissionPayLoad) Validate() error {
+	validate := validator.New()
+	return validate.Struct(pp)
+}
+
+// PermissionChecker tells whether the caller of a request holds a permission. Access tokens
+// carry the permissions of their user, so only credentials without them, such as personal
+// access tokens, are checked against the database.
+type PermissionChecker interface {
+	Has(ctx echo.Context, permission string) (bool, error)
+}
+
+// PermissionRepository keeps permissions and their grants to roles. Grant and Revoke report
+// false when the role already had, or never had, the permission.
+type PermissionRepository interface {
+	Create(permission Permission) error
+	GetByName(name string) (*Permission, error)
+	GetNamesByUserID(userID string) ([]string, error)
+	Grant(rolePermission RolePermission) (bool, error)
+	Revoke(roleID string, permissionID string) (bool, error)
+}
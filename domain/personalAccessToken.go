@@ -0,0 +1,110 @@
+package domain
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	PersonalAccessTokenPrefix = "pat_"
+	ScopeReadUser             = "read:user"
+	ScopeWriteUser            = "write:user"
+)
+
+var (
+	ErrCreatePersonalAccessToken   = errors.New("error to create personal access token")
+	ErrGetPersonalAccessToken      = errors.New("error to get personal access token")
+	ErrPersonalAccessTokenNotFound = errors.New("personal access token not found")
+)
+
+type PersonalAccessToken struct {
+	ID         string     `gorm:"column:Id;type:char(36);primary_key"`
+	UserID     string     `gorm:"column:UserId;type:char(36);index"`
+	Name       string     `gorm:"column:Name;type:varchar(100)"`
+	TokenHash  string     `gorm:"column:TokenHash;type:char(64);uniqueIndex"`
+	Scopes     string     `gorm:"column:Scopes;type:varchar(255)"`
+	ExpiresAt  *time.Time `gorm:"column:ExpiresAt"`
+	LastUsedAt *time.Time `gorm:"column:LastUsedAt"`
+	RevokedAt  *time.Time `gorm:"column:RevokedAt"`
+	CreatedAt  time.Time  `gorm:"column:CreatedAt"`
+}
+
+func (PersonalAccessToken) TableName() string {
+	return "personal_access_token"
+}
+
+func (pat *PersonalAccessToken) IsActive() bool {
+	if pat.RevokedAt != nil {
+		return false
+	}
+
+	return pat.ExpiresAt == nil || time.Now().Before(*pat.ExpiresAt)
+}
+
+func (pat *PersonalAccessToken) HasScope(scope string) bool {
+	for _, s := range strings.Split(pat.Scopes, ",") {
+		if s == scope {
+			return true
+		}
+	}
+
+	return false
+}
+
+type PersonalAccessTokenPayLoad struct {
+	Name      string     `json:"name,omitempty" validate:"required,min=1,max=100"`
+	Scopes    []string   `json:"scopes,omitempty" validate:"required,min=1,dive,oneof=read:user write:user"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty" validate:"omitempty,gtfield=CreatedAt"`
+	CreatedAt time.Time  `json:"-"`
+}
+
+type PersonalAccessTokenResponse struct {
+	Id         string     `json:"id"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	Token      string     `json:"token,omitempty"`
+}
+
+type PersonalAccessTokenHandler interface {
+	Create(ctx echo.Context) error
+	GetAll(ctx echo.Context) error
+	Revoke(ctx echo.Context) error
+}
+
+type PersonalAccessTokenService interface {
+	Create(userID string, payLoad PersonalAccessTokenPayLoad) (*PersonalAccessTokenResponse, error)
+	GetAll(userID string) ([]PersonalAccessTokenResponse, error)
+	Revoke(userID string, id string) error
+}
+
+type PersonalAccessTokenRepository interface {
+	Create(personalAccessToken PersonalAccessToken) error
+	GetByTokenHash(tokenHash string) (*PersonalAccessToken, error)
+	GetByUserID(userID string) ([]PersonalAccessToken, error)
+	Revoke(userID string, id string) (bool, error)
+	UpdateLastUsedAt(id string) error
+}
+
+func (patp *PersonalAccessTokenPayLoad) Validate() error {
+	patp.CreatedAt = time.Now()
+	validate := validator.New()
+	return validate.Struct(patp)
+}
+
+func (pat *PersonalAccessToken) ToPersonalAccessTokenResponse() *PersonalAccessTokenResponse {
+	return &PersonalAccessTokenResponse{
+		Id:         pat.ID,
+		Name:       pat.Name,
+		Scopes:     strings.Split(pat.Scopes, ","),
+		ExpiresAt:  pat.ExpiresAt,
+		LastUsedAt: pat.LastUsedAt,
+		CreatedAt:  pat.CreatedAt,
+	}
+}
@@ -0,0 +1,100 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/OVillas/autentication/config"
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+)
+
+var (
+	ErrInvalidPhoneNumber     = errors.New("phone number must be in the international format, such as +5511999999999")
+	ErrPhoneTaken             = errors.New("phone number already in use")
+	ErrNoPhoneNumber          = errors.New("no phone number waiting for confirmation")
+	ErrPhoneAlreadyConfirmed  = errors.New("phone number already confirmed")
+	ErrUpdatePhoneNumber      = errors.New("error to update phone number")
+	ErrSMSUnavailable         = errors.New("text messages are not enabled")
+	ErrSendSMS                = errors.New("error to send text message")
+	ErrToSendPhoneConfirmCode = errors.New("error to send phone confirmation code")
+)
+
+// e164 is a phone number in the E.164 format: a plus sign, a country code and at most 15 digits.
+var e164 = regexp.MustCompile(`^\+[1-9][0-9]{6,14}$`)
+
+// NormalizePhoneNumber returns phoneNumber in the E.164 format, dropping the spaces, dashes, dots
+// and parentheses people type it with. An international prefix of 00 is read as the plus sign.
+func NormalizePhoneNumber(phoneNumber string) (string, error) {
+	normalized := strings.Map(func(r rune) rune {
+		if strings.ContainsRune(" -.()", r) {
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(phoneNumber))
+
+	if strings.HasPrefix(normalized, "00") {
+		normalized = "+" + strings.TrimPrefix(normalized, "00")
+	}
+
+	if !e164.MatchString(normalized) {
+		return "", ErrInvalidPhoneNumber
+	}
+
+	return normalized, nil
+}
+
+// CanReceiveSMS tells whether the user has a confirmed phone number and SMS is enabled, which
+// makes the number a channel for the codes of a two-step login.
+func (u *User) CanReceiveSMS() bool {
+	return u.PhoneNumber != nil && u.PhoneConfirmed && config.SMS.Provider != config.SMSProviderNone
+}
+
+type PhoneNumberPayLoad struct {
+	PhoneNumber string `json:"phone_number,omitempty" validate:"required,max=32"`
+}
+
+type PhoneCodePayLoad struct {
+	Code string `json:"code,omitempty" validate:"required,max=32"`
+}
+
+// SMSSender delivers text messages to phone numbers in the E.164 format.
+type SMSSender interface {
+	Send(to string, message string) error
+}
+
+type PhoneHandler interface {
+	SetPhoneNumber(ctx echo.Context) error
+	ResendConfirmation(ctx echo.Context) error
+	ConfirmPhoneNumber(ctx echo.Context) error
+	RemovePhoneNumber(ctx echo.Context) error
+	SendTwoFactorCode(ctx echo.Context) error
+}
+
+// PhoneService verifies the phone number of a user the way their email is: the number is stored
+// unconfirmed and a code is sent to it by SMS. Once confirmed, the number can receive the codes
+// of a two-step login. Numbers are unique within a tenant.
+type PhoneService interface {
+	SetPhoneNumber(ctx context.Context, userID string, phoneNumber string) error
+	ResendConfirmation(ctx context.Context, userID string) error
+	ConfirmPhoneNumber(ctx context.Context, userID string, code string, clientInfo ClientInfo) error
+	RemovePhoneNumber(ctx context.Context, userID string, clientInfo ClientInfo) error
+	SendTwoFactorCode(ctx context.Context, userID string) error
+}
+
+func (pnp *PhoneNumberPayLoad) Validate() error {
+	validate := validator.New()
+	if err := validate.Struct(pnp); err != nil {
+		return err
+	}
+
+	_, err := NormalizePhoneNumber(pnp.PhoneNumber)
+	return err
+}
+
+func (pcp *PhoneCodePayLoad) Validate() error {
+	validate := validator.New()
+	return validate.Struct(pcp)
+}
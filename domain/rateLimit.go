@@ -0,0 +1,15 @@
+package domain
+
+import "time"
+
+// RateLimit lets a client make Burst requests at once and then one more every Interval.
+type RateLimit struct {
+	Interval time.Duration
+	Burst    int
+}
+
+type RateLimitRepository interface {
+	// Allow takes one request from the allowance of key. It returns 0 when the request may go
+	// on, or how long the client has to wait otherwise.
+	Allow(key string, limit RateLimit) (time.Duration, error)
+}
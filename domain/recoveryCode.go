@@ -0,0 +1,54 @@
+package domain
+
+import (
+	"errors"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+)
+
+const RecoveryCodeCount = 10
+
+var (
+	ErrCreateRecoveryCodes = errors.New("error to create recovery codes")
+	ErrGetRecoveryCodes    = errors.New("error to get recovery codes")
+	ErrTwoFactorNotEnabled = errors.New("two-factor authentication is not enabled")
+)
+
+// RecoveryCode stands in for the authenticator app once. Only its hash is stored; the codes
+// are shown to the user when the set is generated and never again.
+type RecoveryCode struct {
+	ID        string     `gorm:"column:Id;type:char(36);primary_key"`
+	UserID    string     `gorm:"column:UserId;type:char(36);index"`
+	CodeHash  string     `gorm:"column:CodeHash;type:char(64)"`
+	UsedAt    *time.Time `gorm:"column:UsedAt"`
+	CreatedAt time.Time  `gorm:"column:CreatedAt"`
+}
+
+func (RecoveryCode) TableName() string {
+	return "recovery_code"
+}
+
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type RecoveryCodeCountResponse struct {
+	Remaining int64 `json:"remaining"`
+}
+
+type RegenerateRecoveryCodesPayLoad struct {
+	Password string `json:"password,omitempty" validate:"required"`
+}
+
+type RecoveryCodeRepository interface {
+	Replace(userID string, recoveryCodes []RecoveryCode) error
+	CountUnused(userID string) (int64, error)
+	Use(userID string, codeHash string) (bool, error)
+	DeleteByUserID(userID string) error
+}
+
+func (rrcp *RegenerateRecoveryCodesPayLoad) Validate() error {
+	validate := validator.New()
+	return validate.Struct(rrcp)
+}
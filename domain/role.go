@@ -0,0 +1,87 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+)
+
+// RoleAdmin grants the admin endpoints and access to every user.
+const RoleAdmin = "admin"
+
+var (
+	ErrGetRole         = errors.New("error to get role")
+	ErrRoleNotFound    = errors.New("role not found")
+	ErrAssignRole      = errors.New("error to assign role")
+	ErrRevokeRole      = errors.New("error to revoke role")
+	ErrRoleNotAssigned = errors.New("user does not have this role")
+	ErrLastAdmin       = errors.New("the last admin cannot lose the admin role")
+	ErrBootstrapAdmin  = errors.New("error to bootstrap the first admin")
+	ErrCreateRole      = errors.New("error to create role")
+	ErrRoleExists      = errors.New("role already exists")
+)
+
+type Role struct {
+	ID        string    `gorm:"column:Id;type:char(36);primary_key"`
+	Name      string    `gorm:"column:Name;type:varchar(50);uniqueIndex"`
+	CreatedAt time.Time `gorm:"column:CreatedAt"`
+}
+
+func (Role) TableName() string {
+	return "role"
+}
+
+// UserRole grants a role to a user. Roles travel in the access tokens issued afterwards, so a
+// revoked role also invalidates the tokens of its user.
+type UserRole struct {
+	UserID    string    `gorm:"column:UserId;type:char(36);primary_key"`
+	RoleID    string    `gorm:"column:RoleId;type:char(36);primary_key;index"`
+	CreatedAt time.Time `gorm:"column:CreatedAt"`
+}
+
+func (UserRole) TableName() string {
+	return "user_role"
+}
+
+type RolePayLoad struct {
+	Role string `json:"role,omitempty" validate:"required,max=50,excludesall=/ "`
+}
+
+func (rp *RolePayLoad) Validate() error {
+	validate := validator.New()
+	return validate.Struct(rp)
+}
+
+type RoleHandler interface {
+	AssignRole(ctx echo.Context) error
+	RevokeRole(ctx echo.Context) error
+	GrantPermission(ctx echo.Context) error
+	RevokePermission(ctx echo.Context) error
+	CreateRole(ctx echo.Context) error
+}
+
+// RoleService manages roles, their permissions and the users holding them. Only an admin viewer
+// can change them. BootstrapAdmin creates the admin role with every permission and, when
+// configured, grants it to the first admin.
+type RoleService interface {
+	AssignRole(ctx context.Context, viewer Viewer, userID string, role string) error
+	RevokeRole(ctx context.Context, viewer Viewer, userID string, role string) error
+	GrantPermission(ctx context.Context, viewer Viewer, role string, permission string) error
+	RevokePermission(ctx context.Context, viewer Viewer, role string, permission string) error
+	CreateRole(viewer Viewer, role string) error
+	BootstrapAdmin(ctx context.Context) error
+}
+
+// RoleRepository keeps roles and their grants. Assign and Revoke report false when the user
+// already had, or never had, the role.
+type RoleRepository interface {
+	Create(role Role) error
+	GetByName(name string) (*Role, error)
+	GetNamesByUserID(userID string) ([]string, error)
+	CountUsers(roleID string) (int64, error)
+	Assign(userRole UserRole) (bool, error)
+	Revoke(userID string, roleID string) (bool, error)
+}
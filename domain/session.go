@@ -0,0 +1,47 @@
+package domain
+
+import (
+	"errors"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+var (
+	ErrGetSession      = errors.New("error to get session")
+	ErrSessionNotFound = errors.New("session not found")
+)
+
+// SessionResponse describes one refresh token family, that is one login kept alive by rotation.
+type SessionResponse struct {
+	Id         string    `json:"id"`
+	Current    bool      `json:"current"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	Persistent bool      `json:"persistent"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+type SessionHandler interface {
+	GetAll(ctx echo.Context) error
+	Revoke(ctx echo.Context) error
+}
+
+type SessionService interface {
+	GetAll(userID string, currentSessionID string) ([]SessionResponse, error)
+	Revoke(userID string, sessionID string) error
+}
+
+func (rt *RefreshToken) ToSessionResponse() *SessionResponse {
+	return &SessionResponse{
+		Id:         rt.FamilyID,
+		UserAgent:  rt.UserAgent,
+		IP:         rt.IP,
+		Persistent: rt.Persistent,
+		CreatedAt:  rt.SessionAt,
+		LastSeenAt: rt.CreatedAt,
+		ExpiresAt:  rt.ExpiresAt,
+	}
+}
@@ -0,0 +1,76 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+const OAuthStateTTL = 10 * time.Minute
+
+var (
+	ErrUnknownProvider  = errors.New("identity provider not supported or not configured")
+	ErrInvalidState     = errors.New("state is invalid, expired or already used")
+	ErrSocialLogin      = errors.New("error to sign in with identity provider")
+	ErrEmailNotVerified = errors.New("the identity provider did not verify this email")
+	ErrNoVerifiedEmail  = errors.New("the identity provider account has no verified primary email, verify one at the provider and try again")
+	ErrIdentityLinked   = errors.New("this identity is already linked to another account")
+	ErrCreateOAuthState = errors.New("error to start sign in with identity provider")
+)
+
+// ExternalIdentity is what an identity provider tells us about the user after a successful
+// sign in, taken from its verified ID token.
+type ExternalIdentity struct {
+	Provider      string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+// OAuthState protects the redirect to an external provider: the state is single-use, and the
+// nonce and PKCE verifier it carries must match the callback. TenantID is the tenant the flow
+// started in, since the provider redirects every brand to the same callback. UserID and SessionID
+// are set when a signed in user started the flow to link the identity to their account.
+type OAuthState struct {
+	StateHash    string     `gorm:"column:StateHash;type:char(64);primary_key"`
+	Provider     string     `gorm:"column:Provider;type:varchar(50)"`
+	TenantID     string     `gorm:"column:TenantId;type:varchar(36)"`
+	UserID       string     `gorm:"column:UserId;type:char(36)"`
+	SessionID    string     `gorm:"column:SessionId;type:char(36)"`
+	Nonce        string     `gorm:"column:Nonce;type:varchar(64)"`
+	CodeVerifier string     `gorm:"column:CodeVerifier;type:varchar(128)"`
+	ExpiresAt    time.Time  `gorm:"column:ExpiresAt;index"`
+	UsedAt       *time.Time `gorm:"column:UsedAt"`
+	CreatedAt    time.Time  `gorm:"column:CreatedAt"`
+}
+
+func (OAuthState) TableName() string {
+	return "oauth_state"
+}
+
+// SocialLoginResult holds the session opened by a sign in or, for a link flow, the identity
+// attached to the signed in user.
+type SocialLoginResult struct {
+	LoginResponse  *LoginResponse
+	LinkedIdentity *UserIdentityResponse
+}
+
+type SocialLoginHandler interface {
+	Redirect(ctx echo.Context) error
+	Callback(ctx echo.Context) error
+}
+
+type SocialLoginService interface {
+	Begin(tenantID string, provider string) (string, error)
+	BeginLink(tenantID string, provider string, userID string, sessionID string) (string, error)
+	Complete(ctx context.Context, provider string, state string, code string, clientInfo ClientInfo) (*SocialLoginResult, error)
+}
+
+type OAuthStateRepository interface {
+	Create(oauthState OAuthState) error
+	GetByStateHash(stateHash string) (*OAuthState, error)
+	Use(stateHash string) (bool, error)
+}
@@ -0,0 +1,82 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+)
+
+const AccountSuspendedCode = "account_suspended"
+
+var (
+	ErrAccountSuspended    = errors.New("this account is suspended")
+	ErrAccountNotSuspended = errors.New("this account is not suspended")
+	ErrSuspendUser         = errors.New("error to suspend user")
+	ErrUnsuspendUser       = errors.New("error to unsuspend user")
+	ErrSuspensionEndInPast = errors.New("the suspension must end in the future")
+)
+
+// AccountSuspendedError refuses a login to a suspended account. Until is nil when the suspension
+// has no end. It matches ErrAccountSuspended with errors.Is.
+type AccountSuspendedError struct {
+	Until *time.Time
+}
+
+func (ase *AccountSuspendedError) Error() string {
+	if ase.Until == nil {
+		return ErrAccountSuspended.Error()
+	}
+
+	return fmt.Sprintf("%s until %s", ErrAccountSuspended.Error(), ase.Until.UTC().Format(time.RFC3339))
+}
+
+func (ase *AccountSuspendedError) Is(target error) bool {
+	return target == ErrAccountSuspended
+}
+
+// IsSuspended tells whether an admin suspended the account and the suspension has not ended yet.
+func (u *User) IsSuspended() bool {
+	return u.SuspendedAt != nil && (u.SuspendedUntil == nil || u.SuspendedUntil.After(time.Now()))
+}
+
+// SuspendPayLoad suspends an account until Until, or indefinitely when it is omitted. Reason is
+// kept in the audit log.
+type SuspendPayLoad struct {
+	Reason string     `json:"reason,omitempty" validate:"required,max=500"`
+	Until  *time.Time `json:"until,omitempty"`
+}
+
+func (sp *SuspendPayLoad) Validate() error {
+	validate := validator.New()
+	if err := validate.Struct(sp); err != nil {
+		return err
+	}
+
+	if sp.Until != nil && !sp.Until.After(time.Now()) {
+		return ErrSuspensionEndInPast
+	}
+
+	return nil
+}
+
+type UnsuspendPayLoad struct {
+	Reason string `json:"reason,omitempty" validate:"required,max=500"`
+}
+
+func (up *UnsuspendPayLoad) Validate() error {
+	validate := validator.New()
+	return validate.Struct(up)
+}
+
+// AccountSuspendedResponse refuses a login to a suspended account. SuspendedUntil is omitted
+// when the suspension has no end.
+type AccountSuspendedResponse struct {
+	Error          string     `json:"error"`
+	Message        string     `json:"message"`
+	Code           string     `json:"code"`
+	SuspendedUntil *time.Time `json:"suspended_until,omitempty"`
+	TimeStamp      time.Time  `json:"timeStamp"`
+	Path           string     `json:"path"`
+}
@@ -0,0 +1,10 @@
+package domain
+
+import "errors"
+
+// Every user belongs to one tenant, the brand it registered with. Emails and usernames are only
+// unique within a tenant, and tokens issued for one tenant are refused by the others.
+var (
+	ErrUnknownTenant  = errors.New("unknown tenant")
+	ErrTenantMismatch = errors.New("token issued for another tenant")
+)
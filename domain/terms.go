@@ -0,0 +1,79 @@
+package domain
+
+import (
+	"errors"
+	"time"
+
+	"github.com/OVillas/autentication/config"
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+)
+
+const ReacceptanceRequiredCode = "reacceptance_required"
+
+var (
+	ErrTermsVersionRequired = errors.New("terms_version is required, accept the current terms of service and privacy policy")
+	ErrTermsVersionOutdated = errors.New("the terms version accepted is not the current one")
+	ErrReacceptanceRequired = errors.New("the terms of service and privacy policy changed, accept the current version to continue")
+	ErrSavePolicyAcceptance = errors.New("error to save policy acceptance")
+)
+
+// PolicyAcceptance records that a user accepted a version of the terms of service and privacy
+// policy, and from where. A new row is added on every acceptance, so the history is kept.
+type PolicyAcceptance struct {
+	ID         string    `gorm:"column:Id;type:char(36);primary_key"`
+	UserID     string    `gorm:"column:UserId;type:char(36);index:idx_policy_acceptance_user_accepted_at,priority:1"`
+	Version    string    `gorm:"column:Version;type:varchar(64)"`
+	IP         string    `gorm:"column:Ip;type:varchar(45)"`
+	UserAgent  string    `gorm:"column:UserAgent;type:varchar(512)"`
+	AcceptedAt time.Time `gorm:"column:AcceptedAt;index:idx_policy_acceptance_user_accepted_at,priority:2"`
+}
+
+func (PolicyAcceptance) TableName() string {
+	return "policy_acceptance"
+}
+
+// CheckTermsVersion refuses a version other than config.TermsVersion. Anything goes while no
+// terms are configured.
+func CheckTermsVersion(version string) error {
+	if config.TermsVersion == "" || version == config.TermsVersion {
+		return nil
+	}
+
+	return ErrTermsVersionOutdated
+}
+
+type AcceptTermsPayLoad struct {
+	TermsVersion string `json:"terms_version,omitempty" validate:"required,max=64"`
+}
+
+func (atp *AcceptTermsPayLoad) Validate() error {
+	validate := validator.New()
+	return validate.Struct(atp)
+}
+
+// ReacceptanceRequiredResponse refuses a request of a user whose latest acceptance is not of
+// TermsVersion, the current one. Accepting it lets the requests through again.
+type ReacceptanceRequiredResponse struct {
+	Error        string `json:"error"`
+	Message      string `json:"message"`
+	Code         string `json:"code"`
+	TermsVersion string `json:"terms_version"`
+}
+
+type TermsHandler interface {
+	Accept(ctx echo.Context) error
+}
+
+// TermsService records the acceptances of the terms of service and privacy policy. Only the
+// version in config.TermsVersion can be accepted.
+type TermsService interface {
+	Accept(userID string, version string, clientInfo ClientInfo) error
+}
+
+// PolicyAcceptanceRepository keeps every acceptance. GetLatestByUserID returns nil when the user
+// never accepted any version.
+type PolicyAcceptanceRepository interface {
+	Create(policyAcceptance PolicyAcceptance) error
+	GetLatestByUserID(userID string) (*PolicyAcceptance, error)
+}
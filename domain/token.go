@@ -0,0 +1,243 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+)
+
+var (
+	ErrCreateRefreshToken = errors.New("error to create refresh token")
+	ErrGetRefreshToken    = errors.New("error to get refresh token")
+	ErrRevokeToken        = errors.New("error to revoke token")
+	ErrRefreshTokenReused = errors.New("refresh token already used, all sessions were revoked")
+	ErrTokenExpired       = errors.New("token expired")
+	ErrTooManySessions    = errors.New("maximum number of active sessions reached")
+	ErrTokenBinding       = errors.New("refresh token presented from a different network or browser than the one it was issued to; " +
+		"changing networks, using a VPN or updating the browser also causes this, sign in again to continue")
+)
+
+type RefreshToken struct {
+	ID         string     `gorm:"column:Id;type:char(36);primary_key"`
+	UserID     string     `gorm:"column:UserId;type:char(36);index;index:idx_refresh_token_active,priority:1"`
+	TenantID   string     `gorm:"column:TenantId;type:varchar(36)"`
+	FamilyID   string     `gorm:"column:FamilyId;type:char(36);index"`
+	TokenHash  string     `gorm:"column:TokenHash;type:char(64);uniqueIndex"`
+	ReplacedBy string     `gorm:"column:ReplacedBy;type:char(36)"`
+	Persistent bool       `gorm:"column:Persistent;default:false"`
+	UserAgent  string     `gorm:"column:UserAgent;type:varchar(512)"`
+	IP         string     `gorm:"column:Ip;type:varchar(45)"`
+	IPPrefix   string     `gorm:"column:IpPrefix;type:varchar(49)"`
+	UAHash     string     `gorm:"column:UserAgentHash;type:char(64)"`
+	ExpiresAt  time.Time  `gorm:"column:ExpiresAt;index:idx_refresh_token_active,priority:3"`
+	MaxExpiry  time.Time  `gorm:"column:MaxExpiry"`
+	RevokedAt  *time.Time `gorm:"column:RevokedAt;index:idx_refresh_token_active,priority:2"`
+	SessionAt  time.Time  `gorm:"column:SessionAt"`
+	// OrganizationID is the organization the session picked, carried by its access tokens.
+	OrganizationID string    `gorm:"column:OrganizationId;type:char(36)"`
+	CreatedAt      time.Time `gorm:"column:CreatedAt"`
+}
+
+func (RefreshToken) TableName() string {
+	return "refresh_token"
+}
+
+// IsRotated reports whether the token was already exchanged for a newer one in its family.
+func (rt *RefreshToken) IsRotated() bool {
+	return rt.ReplacedBy != ""
+}
+
+// IsActive reports whether the token can still be exchanged. ExpiresAt slides forward on every
+// refresh while MaxExpiry, fixed when the family was created, caps the whole session.
+func (rt *RefreshToken) IsActive() bool {
+	now := time.Now()
+	if !rt.MaxExpiry.IsZero() && !now.Before(rt.MaxExpiry) {
+		return false
+	}
+
+	return rt.RevokedAt == nil && now.Before(rt.ExpiresAt)
+}
+
+type RevokedToken struct {
+	JTI       string    `gorm:"column:Jti;type:char(36);primary_key"`
+	ExpiresAt time.Time `gorm:"column:ExpiresAt;index"`
+	CreatedAt time.Time `gorm:"column:CreatedAt"`
+}
+
+func (RevokedToken) TableName() string {
+	return "revoked_token"
+}
+
+const (
+	ScopePasswordReset     = "password_reset"
+	ScopeTwoFactorPending  = "2fa_pending"
+	ScopeEmailVerification = "email_verification"
+	ScopeReactivation      = "reactivation"
+	CSRFCookieName         = "csrf_token"
+	CSRFHeader             = "X-CSRF-Token"
+)
+
+// ClientInfo identifies the device a session was opened from and the tenant it spoke to.
+type ClientInfo struct {
+	IP        string
+	UserAgent string
+	TenantID  string
+}
+
+const (
+	CallerUser   = "user"
+	CallerClient = "client"
+	CallerApiKey = "api_key"
+	CallerAdmin  = "admin"
+)
+
+type TokenClaims struct {
+	ID          string
+	UserID      string
+	ClientID    string
+	TenantID    string
+	Username    string
+	Scope       string
+	SessionID   string
+	Version     int
+	Roles       []string
+	Permissions []string
+	// OrganizationID and OrganizationRole are only set once the session picked an organization.
+	OrganizationID   string
+	OrganizationRole string
+	IssuedAt         time.Time
+	ExpiresAt        time.Time
+}
+
+// IsClient reports whether the token was issued to a machine client through the client
+// credentials grant rather than to a user.
+func (tc *TokenClaims) IsClient() bool {
+	return tc.UserID == "" && tc.ClientID != ""
+}
+
+// HasRole reports whether the user held role when the token was issued.
+func (tc *TokenClaims) HasRole(role string) bool {
+	for _, r := range tc.Roles {
+		if r == role {
+			return true
+		}
+	}
+
+	return false
+}
+
+// HasPermission reports whether the user held permission, through any of its roles, when the
+// token was issued.
+func (tc *TokenClaims) HasPermission(permission string) bool {
+	for _, p := range tc.Permissions {
+		if p == permission {
+			return true
+		}
+	}
+
+	return false
+}
+
+// HasScope checks the space separated scope claim of a client token.
+func (tc *TokenClaims) HasScope(scope string) bool {
+	for _, s := range strings.Fields(tc.Scope) {
+		if s == scope {
+			return true
+		}
+	}
+
+	return false
+}
+
+// LoginResponse carries the refresh token in the body unless the session cookie mode is on,
+// in which case the handler moves it to an HttpOnly cookie.
+type LoginResponse struct {
+	AccessToken            string            `json:"access_token"`
+	TokenType              string            `json:"token_type"`
+	ExpiresIn              int64             `json:"expires_in"`
+	RefreshToken           string            `json:"refresh_token,omitempty"`
+	RefreshTokenExpiresAt  time.Time         `json:"refresh_token_expires_at"`
+	IDToken                string            `json:"id_token,omitempty"`
+	TrustedDeviceToken     string            `json:"trusted_device_token,omitempty"`
+	TrustedDeviceExpiresAt time.Time         `json:"-"`
+	Persistent             bool              `json:"-"`
+	User                   UserInfosResponse `json:"user"`
+}
+
+type RefreshTokenPayLoad struct {
+	RefreshToken string `json:"refresh_token,omitempty" validate:"required"`
+}
+
+type LogoutPayLoad struct {
+	RefreshToken string `json:"refresh_token,omitempty" validate:"required"`
+}
+
+type LogoutAllPayLoad struct {
+	Password string `json:"password,omitempty" validate:"required"`
+}
+
+type IntrospectionPayLoad struct {
+	Token         string `json:"token,omitempty" form:"token" validate:"required"`
+	TokenTypeHint string `json:"token_type_hint,omitempty" form:"token_type_hint"`
+}
+
+type IntrospectionResponse struct {
+	Active    bool   `json:"active"`
+	Sub       string `json:"sub,omitempty"`
+	Exp       int64  `json:"exp,omitempty"`
+	Iat       int64  `json:"iat,omitempty"`
+	Scope     string `json:"scope,omitempty"`
+	Username  string `json:"username,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+}
+
+type TokenHandler interface {
+	Introspect(ctx echo.Context) error
+}
+
+type TokenService interface {
+	Introspect(ctx context.Context, token string) (*IntrospectionResponse, error)
+}
+
+type RefreshTokenRepository interface {
+	Create(refreshToken RefreshToken) error
+	GetByTokenHash(tokenHash string) (*RefreshToken, error)
+	Revoke(id string) error
+	Rotate(id string, replacedBy string) (bool, error)
+	RevokeFamily(familyID string) error
+	RevokeAllByUserID(userID string) error
+	GetActiveByUserID(userID string) ([]RefreshToken, error)
+	GetLatestByFamilyID(familyID string) (*RefreshToken, error)
+	CreateWithinLimit(refreshToken RefreshToken, limit int, evictOldest bool) (bool, []string, error)
+	SetOrganization(familyID string, organizationID string) error
+}
+
+type RevokedTokenRepository interface {
+	Create(revokedToken RevokedToken) error
+	Exists(ids ...string) (bool, error)
+}
+
+func (rtp *RefreshTokenPayLoad) Validate() error {
+	validate := validator.New()
+	return validate.Struct(rtp)
+}
+
+func (lp *LogoutPayLoad) Validate() error {
+	validate := validator.New()
+	return validate.Struct(lp)
+}
+
+func (lap *LogoutAllPayLoad) Validate() error {
+	validate := validator.New()
+	return validate.Struct(lap)
+}
+
+func (ip *IntrospectionPayLoad) Validate() error {
+	validate := validator.New()
+	return validate.Struct(ip)
+}
@@ -0,0 +1,19 @@
+package domain
+
+import "context"
+
+// TxRepositories are the repositories of a transaction of UnitOfWork: what they write is
+// committed or rolled back together.
+type TxRepositories struct {
+	Users             UserRepository
+	ConfirmationCodes ConfirmationCodeRepository
+}
+
+// UnitOfWork runs fn in a database transaction, committed when fn returns nil and rolled back
+// when it returns an error. Side effects outside the database, such as emails, belong after Do
+// returns, so they only happen once the writes are committed. A confirmation code store that is
+// not the database, such as Redis, writes right away: a code left by a rolled back transaction
+// just expires.
+type UnitOfWork interface {
+	Do(ctx context.Context, fn func(tx TxRepositories) error) error
+}
@@ -0,0 +1,78 @@
+package domain
+
+import (
+	"errors"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+const TrustedDeviceCookieName = "trusted_device"
+
+var (
+	ErrCreateTrustedDevice   = errors.New("error to create trusted device")
+	ErrGetTrustedDevice      = errors.New("error to get trusted device")
+	ErrRevokeTrustedDevice   = errors.New("error to revoke trusted device")
+	ErrTrustedDeviceNotFound = errors.New("trusted device not found")
+)
+
+// TrustedDevice lets a browser or app skip the second factor of the login until ExpiresAt. The
+// device keeps an opaque token, only stored here hashed, and it is only honoured for the user
+// it was issued to.
+type TrustedDevice struct {
+	ID         string     `gorm:"column:Id;type:char(36);primary_key"`
+	UserID     string     `gorm:"column:UserId;type:char(36);index"`
+	TokenHash  string     `gorm:"column:TokenHash;type:char(64);uniqueIndex"`
+	UserAgent  string     `gorm:"column:UserAgent;type:varchar(512)"`
+	IP         string     `gorm:"column:Ip;type:varchar(45)"`
+	ExpiresAt  time.Time  `gorm:"column:ExpiresAt;index"`
+	LastUsedAt *time.Time `gorm:"column:LastUsedAt"`
+	CreatedAt  time.Time  `gorm:"column:CreatedAt"`
+}
+
+func (TrustedDevice) TableName() string {
+	return "trusted_device"
+}
+
+func (td *TrustedDevice) IsActive() bool {
+	return time.Now().Before(td.ExpiresAt)
+}
+
+type TrustedDeviceResponse struct {
+	Id         string     `json:"id"`
+	UserAgent  string     `json:"user_agent"`
+	IP         string     `json:"ip"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+}
+
+type TrustedDeviceHandler interface {
+	GetAll(ctx echo.Context) error
+	Revoke(ctx echo.Context) error
+}
+
+type TrustedDeviceService interface {
+	GetAll(userID string) ([]TrustedDeviceResponse, error)
+	Revoke(userID string, id string) error
+}
+
+type TrustedDeviceRepository interface {
+	Create(trustedDevice TrustedDevice) error
+	GetByTokenHash(tokenHash string) (*TrustedDevice, error)
+	GetActiveByUserID(userID string) ([]TrustedDevice, error)
+	UpdateLastUsedAt(id string) error
+	Delete(userID string, id string) (bool, error)
+	DeleteByUserID(userID string) error
+}
+
+func (td *TrustedDevice) ToTrustedDeviceResponse() *TrustedDeviceResponse {
+	return &TrustedDeviceResponse{
+		Id:         td.ID,
+		UserAgent:  td.UserAgent,
+		IP:         td.IP,
+		CreatedAt:  td.CreatedAt,
+		LastUsedAt: td.LastUsedAt,
+		ExpiresAt:  td.ExpiresAt,
+	}
+}
@@ -0,0 +1,94 @@
+package domain
+
+import (
+	"errors"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+)
+
+const (
+	TwoFactorMethodTOTP     = "totp"
+	TwoFactorMethodEmail    = "email"
+	TwoFactorMethodRecovery = "recovery"
+	TwoFactorMethodSMS      = "sms"
+	TwoFactorChallengeTTL   = 5 * time.Minute
+	// TwoFactorChallengeMaxAttempts is how many wrong codes revoke a challenge token.
+	TwoFactorChallengeMaxAttempts = 5
+)
+
+var (
+	ErrEnableTOTP         = errors.New("error to enable authenticator app")
+	ErrTOTPAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	ErrTOTPNotEnrolled    = errors.New("no pending authenticator app enrollment, enable it first")
+	ErrInvalidTOTPCode    = errors.New("invalid authenticator code")
+	ErrInvalidTwoFactor   = errors.New("invalid or expired verification code")
+	ErrTwoFactorMethod    = errors.New("this verification method is not enabled for the account")
+	ErrSendTwoFactorCode  = errors.New("error to send login verification code")
+	ErrDisableTwoFactor   = errors.New("error to disable two-factor authentication")
+)
+
+// TOTPEnrollmentResponse gives the user what the authenticator app needs: the secret for manual
+// entry, the otpauth URI and a PNG QR code of that URI as a data URL. RecoveryCodes replace the
+// app if it is lost and are not shown again.
+type TOTPEnrollmentResponse struct {
+	Secret        string   `json:"secret"`
+	OtpauthURI    string   `json:"otpauth_uri"`
+	QRCode        string   `json:"qr_code"`
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// TwoFactorChallengeResponse replaces the tokens of a login when the account has two-factor
+// authentication on. ChallengeToken only opens the second step, once, for TwoFactorChallengeTTL
+// and up to TwoFactorChallengeMaxAttempts wrong codes.
+type TwoFactorChallengeResponse struct {
+	TwoFactorRequired bool     `json:"two_factor_required"`
+	ChallengeToken    string   `json:"challenge_token"`
+	TokenType         string   `json:"token_type"`
+	ExpiresIn         int64    `json:"expires_in"`
+	Methods           []string `json:"methods"`
+}
+
+// LoginResult holds either the session of a completed login, the challenge of a login waiting
+// for its second factor, for an account that must reset its password, the reset token or, for a
+// deactivated account, the reactivation token.
+type LoginResult struct {
+	LoginResponse *LoginResponse
+	Challenge     *TwoFactorChallengeResponse
+	PasswordReset *PasswordResetRequiredResponse
+	Deactivated   *AccountDeactivatedResponse
+}
+
+type TwoFactorLoginPayLoad struct {
+	Method      string `json:"method,omitempty" validate:"required,oneof=totp email recovery sms"`
+	Code        string `json:"code,omitempty" validate:"required,max=32"`
+	RememberMe  bool   `json:"remember_me,omitempty"`
+	TrustDevice bool   `json:"trust_device,omitempty"`
+}
+
+// DisableTwoFactorPayLoad asks for both factors again: the password and a code from the
+// authenticator app or a recovery code.
+type DisableTwoFactorPayLoad struct {
+	Password string `json:"password,omitempty" validate:"required"`
+	Method   string `json:"method,omitempty" validate:"required,oneof=totp recovery"`
+	Code     string `json:"code,omitempty" validate:"required,max=32"`
+}
+
+type TOTPCodePayLoad struct {
+	Code string `json:"code,omitempty" validate:"required,len=6,numeric"`
+}
+
+func (tcp *TOTPCodePayLoad) Validate() error {
+	validate := validator.New()
+	return validate.Struct(tcp)
+}
+
+func (tflp *TwoFactorLoginPayLoad) Validate() error {
+	validate := validator.New()
+	return validate.Struct(tflp)
+}
+
+func (dtfp *DisableTwoFactorPayLoad) Validate() error {
+	validate := validator.New()
+	return validate.Struct(dtfp)
+}
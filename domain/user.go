@@ -1,10 +1,14 @@
 package domain
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"slices"
 	"strings"
 	"time"
 
+	"github.com/OVillas/autentication/config"
 	"github.com/go-playground/validator/v10"
 	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
@@ -12,49 +16,131 @@ import (
 )
 
 var (
-	ErrHashPassword             = errors.New("error trying hashed password")
-	ErrUserAlreadyRegistered    = errors.New("there is already a registered user with this email")
-	ErrCreateUser               = errors.New("error to create user")
-	ErrGetUser                  = errors.New("error to get user")
-	ErrConvertUserPayLoadToUser = errors.New("error to create id from new user")
-	ErrInvalidId                = errors.New("the id passed is invalid")
-	ErrUserNotFound             = errors.New("user not found")
-	ErrDeleteUser               = errors.New("error to delete user")
-	ErrSameEmail                = errors.New("the email cannot be the same as the previous one")
-	ErrUserNotAuthorized        = errors.New("user not authorized to action")
-	ErrPasswordNotMatch         = errors.New("invalid password")
-	ErrGenToken                 = errors.New("error to generate new token jwt")
-	ErrUnexpectedSigningMethod  = errors.New("unexpected signature method")
-	ErrInvalidToken             = errors.New("token invalid")
-	ErrIdNotFoundInPermissions  = errors.New("error to get id in token")
-	ErrIdIsNotAString           = errors.New("'id' field value is not a string")
-	ErrUpdatePassword           = errors.New("error to update password")
-	ErrToSendConfirmationCode   = errors.New("error to send confirmation code")
-	ErrInvalidOTP               = errors.New("wrong or expired OTP")
-	ErrOTPNotFound              = errors.New("not found OTP from email")
-	ErrUserIDMismatch           = errors.New("user ID mismatch")
+	ErrHashPassword                 = errors.New("error trying hashed password")
+	ErrUserAlreadyRegistered        = errors.New("there is already a registered user with this email")
+	ErrCreateUser                   = errors.New("error to create user")
+	ErrGetUser                      = errors.New("error to get user")
+	ErrConvertUserPayLoadToUser     = errors.New("error to create id from new user")
+	ErrInvalidId                    = errors.New("the id passed is invalid")
+	ErrUserNotFound                 = errors.New("user not found")
+	ErrDeleteUser                   = errors.New("error to delete user")
+	ErrSameEmail                    = errors.New("the email cannot be the same as the previous one")
+	ErrEmailTaken                   = fmt.Errorf("%w", ErrUserAlreadyRegistered)
+	ErrUsernameTaken                = errors.New("this username is already taken")
+	ErrUserNotAuthorized            = errors.New("user not authorized to action")
+	ErrPasswordNotMatch             = errors.New("invalid password")
+	ErrPasswordConfirmationMismatch = errors.New("the password confirmation does not match the new password")
+	ErrPasswordReuse                = errors.New("the new password must be different from the current one")
+	ErrPasswordMatchesIdentity      = errors.New("the password cannot be the same as your email or username")
+	ErrPasswordBreached             = errors.New("this password has appeared in a data breach, choose another one")
+	ErrPasswordBreachCheck          = errors.New("could not check the password against known breaches, try again later")
+	ErrGenToken                     = errors.New("error to generate new token jwt")
+	ErrUnexpectedSigningMethod      = errors.New("unexpected signature method")
+	ErrInvalidToken                 = errors.New("token invalid")
+	ErrIdNotFoundInPermissions      = errors.New("error to get id in token")
+	ErrIdIsNotAString               = errors.New("'id' field value is not a string")
+	ErrUpdatePassword               = errors.New("error to update password")
+	ErrToSendConfirmationCode       = errors.New("error to send confirmation code")
+	ErrInvalidOTP                   = errors.New("wrong or expired OTP")
+	ErrOTPNotFound                  = errors.New("not found OTP from email")
+	ErrTooManyOTPAttempts           = errors.New("too many wrong codes, request a new one")
+	ErrInvalidConfirmationLink      = errors.New("confirmation link is invalid, expired or already used")
+	ErrInvalidResetToken            = errors.New("reset password token is invalid, expired or already used")
+	ErrUserIDMismatch               = errors.New("user ID mismatch")
+	ErrAccountLocked                = errors.New("account locked after too many failed logins")
+	ErrLoginDelayed                 = errors.New("too many failed logins, wait before trying again")
+	ErrPasswordResetRequired        = errors.New("a password reset is required, log in with your password to set a new one")
+	ErrForcePasswordReset           = errors.New("error to require password reset")
+	ErrAccountDeleted               = errors.New("an account with this email was deleted, contact support to restore it")
+	ErrRestoreUser                  = errors.New("error to restore user")
+	ErrScheduleDeletion             = errors.New("error to schedule user deletion")
 )
 
+// AccountLockedError refuses a login until RetryAfter has passed. It matches ErrAccountLocked
+// with errors.Is.
+type AccountLockedError struct {
+	RetryAfter time.Duration
+}
+
+func (ale *AccountLockedError) Error() string {
+	return fmt.Sprintf("%s, try again in %s", ErrAccountLocked.Error(), ale.RetryAfter.Round(time.Second))
+}
+
+func (ale *AccountLockedError) Is(target error) bool {
+	return target == ErrAccountLocked
+}
+
+// LoginDelayedError refuses to check a password until RetryAfter has passed. It matches
+// ErrLoginDelayed with errors.Is.
+type LoginDelayedError struct {
+	RetryAfter time.Duration
+}
+
+func (lde *LoginDelayedError) Error() string {
+	return ErrLoginDelayed.Error()
+}
+
+func (lde *LoginDelayedError) Is(target error) bool {
+	return target == ErrLoginDelayed
+}
+
 type User struct {
-	ID                  string    `gorm:"column:Id;type:char(36);primary_key"`
-	Name                string    `gorm:"column:Name;type:varchar(75)"`
-	Username            string    `gorm:"column:Username;type:varchar(255);unique_index"`
-	Email               string    `gorm:"column:Email;type:varchar(255);unique_index"`
-	Password            string    `gorm:"column:PasswordHash;type:varchar(255)"`
-	EmailConfirmed      bool      `gorm:"column:EmailConfirmed;type:boolean"`
-	TwoFactorAuthActive bool      `gorm:"column:TwoFactorAuthActive;type:boolean"`
-	Active              bool      `gorm:"column:Active;type:boolean;default:true"`
-	CreatedAt           time.Time `gorm:"column:CreatedAt"`
-	UpdateAt            time.Time `gorm:"column:UpdateAt"`
+	ID                  string         `gorm:"column:Id;type:char(36);primary_key;index:idx_user_created_at_id,priority:2"`
+	TenantID            string         `gorm:"column:TenantId;type:varchar(36);uniqueIndex:idx_user_tenant_username,priority:1;uniqueIndex:idx_user_tenant_email,priority:1;uniqueIndex:idx_user_tenant_phone,priority:1"`
+	Name                string         `gorm:"column:Name;type:varchar(75)"`
+	Username            string         `gorm:"column:Username;type:varchar(255);uniqueIndex:idx_user_tenant_username,priority:2"`
+	Email               string         `gorm:"column:Email;type:varchar(255);uniqueIndex:idx_user_tenant_email,priority:2"`
+	PhoneNumber         *string        `gorm:"column:PhoneNumber;type:varchar(16);uniqueIndex:idx_user_tenant_phone,priority:2"`
+	Password            string         `gorm:"column:PasswordHash;type:varchar(255)"`
+	EmailConfirmed      bool           `gorm:"column:EmailConfirmed;type:boolean"`
+	PhoneConfirmed      bool           `gorm:"column:PhoneConfirmed;type:boolean;default:false"`
+	TwoFactorAuthActive bool           `gorm:"column:TwoFactorAuthActive;type:boolean"`
+	TOTPSecret          string         `gorm:"column:TotpSecret;type:varchar(255)"`
+	LastTOTPStep        int64          `gorm:"column:LastTotpStep;default:0"`
+	Active              bool           `gorm:"column:Active;type:boolean;default:true"`
+	TokenVersion        int            `gorm:"column:TokenVersion;default:0"`
+	FailedLoginAttempts int            `gorm:"column:FailedLoginAttempts;default:0"`
+	LastFailedLoginAt   *time.Time     `gorm:"column:LastFailedLoginAt"`
+	LockedUntil         *time.Time     `gorm:"column:LockedUntil"`
+	MustResetPassword   bool           `gorm:"column:MustResetPassword;type:boolean;default:false"`
+	LoginAlertsEnabled  bool           `gorm:"column:LoginAlertsEnabled;type:boolean;default:true"`
+	Locale              string         `gorm:"column:Locale;type:varchar(35)"`
+	Timezone            string         `gorm:"column:Timezone;type:varchar(64)"`
+	AvatarURL           *string        `gorm:"column:AvatarUrl;type:varchar(512)"`
+	Metadata            UserMetadata   `gorm:"column:Metadata"`
+	SuspendedAt         *time.Time     `gorm:"column:SuspendedAt"`
+	SuspendedUntil      *time.Time     `gorm:"column:SuspendedUntil"`
+	LastLoginAt         *time.Time     `gorm:"column:LastLoginAt;index"`
+	CreatedAt           time.Time      `gorm:"column:CreatedAt;autoCreateTime;index:idx_user_created_at_id,priority:1"`
+	UpdatedAt           time.Time      `gorm:"column:UpdatedAt;autoUpdateTime"`
+	DeletionScheduledAt *time.Time     `gorm:"column:DeletionScheduledAt;index"`
+	DeletionRemindedAt  *time.Time     `gorm:"column:DeletionRemindedAt"`
+	AnonymizedAt        *time.Time     `gorm:"column:AnonymizedAt"`
+	DeletedAt           gorm.DeletedAt `gorm:"column:DeletedAt;index"`
+	// Roles and Permissions are not columns: the service loads them from the role tables before
+	// issuing an access token. So are OrganizationID and OrganizationRole, the organization the
+	// session speaks for, when it picked one.
+	Roles            []string `gorm:"-"`
+	Permissions      []string `gorm:"-"`
+	OrganizationID   string   `gorm:"-"`
+	OrganizationRole string   `gorm:"-"`
 }
 
 func (User) TableName() string {
 	return "user"
 }
 
+// UnusablePasswordPrefix marks the password of accounts created through an external identity
+// provider. It is not a valid bcrypt hash, so no password can ever match it.
+const UnusablePasswordPrefix = "!"
+
+func (u *User) HasPassword() bool {
+	return !strings.HasPrefix(u.Password, UnusablePasswordPrefix)
+}
+
 func (u *User) Normalize() {
-	u.Username = strings.ToLower(strings.TrimSpace(u.Username))
-	u.Email = strings.ToLower(strings.TrimSpace(u.Email))
+	u.Username = NormalizeUsername(u.Username)
+	u.Email = NormalizeEmail(u.Email)
 }
 
 func (u *User) BeforeSave(tx *gorm.DB) (err error) {
@@ -62,29 +148,119 @@ func (u *User) BeforeSave(tx *gorm.DB) (err error) {
 	return
 }
 
+// UserPayLoad registers a user. TermsVersion is the version of the terms of service and privacy
+// policy the user accepted, required once config.TermsVersion is set.
 type UserPayLoad struct {
-	Name     string `json:"name,omitempty" validate:"required,min=1,max=75"`
-	Username string `json:"username,omitempty" validate:"required,min=1,max=75"`
-	Email    string `json:"email,omitempty" validate:"required,email"`
-	Password string `json:"password,omitempty" validate:"required,min=6,containsany=!@#&?"`
+	Name         string `json:"name,omitempty" validate:"required,min=1,max=75"`
+	Username     string `json:"username,omitempty" validate:"required,username"`
+	Email        string `json:"email,omitempty" validate:"required,email"`
+	Password     string `json:"password,omitempty" validate:"required,password"`
+	TermsVersion string `json:"terms_version,omitempty" validate:"max=64"`
 }
 
+// UserUpdatePayLoad carries the fields to change: the ones left out stay as they are. Password is
+// the current password, required to change the email. Locale is a BCP 47 language tag and
+// Timezone an IANA time zone name; setting either to an empty string goes back to the default.
 type UserUpdatePayLoad struct {
-	Name     string `json:"name,omitempty" validate:"min=1,max=75"`
-	Email    string `json:"email,omitempty" validate:"required,email"`
-	Username string `json:"username,omitempty" validate:"required,min=6,max=75"`
+	Name     *string `json:"name,omitempty" validate:"omitempty,min=1,max=75"`
+	Email    *string `json:"email,omitempty" validate:"omitempty,email"`
+	Username *string `json:"username,omitempty" validate:"omitempty,username"`
+	Locale   *string `json:"locale,omitempty" validate:"omitempty,max=35,locale"`
+	Timezone *string `json:"timezone,omitempty" validate:"omitempty,max=64,iana_timezone"`
+	Password string  `json:"password,omitempty"`
+}
+
+type DeleteUserPayLoad struct {
+	Password string `json:"password,omitempty" validate:"required"`
+}
+
+// DeletionScheduledResponse tells when a scheduled deletion will happen. Logging in before then
+// cancels it.
+type DeletionScheduledResponse struct {
+	DeletionScheduledAt time.Time `json:"deletion_scheduled_at"`
 }
 
+// UserResponse dates the account with RFC 3339 timestamps in UTC. LastLoginAt is null until the
+// first login, and PhoneNumber until the user sets one. Locale and Timezone are the configured
+// defaults until the user picks their own, and AvatarURL the fallback one until they upload it.
 type UserResponse struct {
+	Id             string
+	Name           string
+	Email          string
+	Username       string
+	PhoneNumber    *string
+	PhoneConfirmed bool
+	Locale         string
+	Timezone       string
+	AvatarURL      string
+	Metadata       UserMetadata
+	CreatedAt      string
+	UpdatedAt      string
+	LastLoginAt    *string
+}
+
+// PublicUserResponse is the profile anyone logged in can see of another user.
+type PublicUserResponse struct {
 	Id       string
 	Name     string
-	Email    string
 	Username string
 }
 
+// Viewer is who asks for a user. Only the user themselves and viewers with users:read see the
+// account data in UserResponse; everyone else gets the PublicUserResponse. Admins hold every
+// permission.
+type Viewer struct {
+	UserID      string
+	Admin       bool
+	Permissions []string
+	ClientInfo  ClientInfo
+}
+
+// Actor is the viewer as recorded in the audit trail.
+func (v Viewer) Actor() Actor {
+	actorType := AuditActorUser
+	if v.Admin {
+		actorType = AuditActorAdmin
+	}
+
+	return Actor{ID: v.UserID, Type: actorType, ClientInfo: v.ClientInfo}
+}
+
+// SameTenant tells whether user belongs to the tenant the viewer made the request in. Users of
+// other tenants are treated as if they did not exist.
+func (v Viewer) SameTenant(user User) bool {
+	return user.TenantID == v.ClientInfo.TenantID
+}
+
+func (v Viewer) Can(permission string) bool {
+	return v.Admin || slices.Contains(v.Permissions, permission)
+}
+
+func (v Viewer) CanSeeAccountOf(userID string) bool {
+	return v.Can(PermissionUsersRead) || (v.UserID != "" && v.UserID == userID)
+}
+
+type UserInfosResponse struct {
+	Id             string `json:"id"`
+	Name           string `json:"name"`
+	Username       string `json:"username"`
+	Email          string `json:"email"`
+	EmailConfirmed bool   `json:"email_confirmed"`
+	AvatarURL      string `json:"avatar_url,omitempty"`
+}
+
+type ResendConfirmationPayLoad struct {
+	Email string `json:"email,omitempty" validate:"required,email"`
+}
+
+// Login takes the email or the username in Identifier. Username is its older name, still
+// accepted when Identifier is left out.
 type Login struct {
-	Username string `json:"username,omitempty" validate:"required,min=6"`
-	Password string `json:"password,omitempty" validate:"required"`
+	Identifier  string `json:"identifier,omitempty" validate:"required,max=255"`
+	Username    string `json:"username,omitempty"`
+	Password    string `json:"password,omitempty" validate:"required"`
+	RememberMe  bool   `json:"remember_me,omitempty"`
+	DeviceToken string `json:"device_token,omitempty"`
 }
 
 type UserHandler interface {
@@ -93,48 +269,168 @@ type UserHandler interface {
 	GetCredencials(ctx echo.Context) error
 	GetByNameOrUsername(ctx echo.Context) error
 	GetByEmail(ctx echo.Context) error
+	CheckAvailability(ctx echo.Context) error
 	GetAll(ctx echo.Context) error
 	Update(ctx echo.Context) error
 	Delete(ctx echo.Context) error
+	GetMe(ctx echo.Context) error
+	UpdateMe(ctx echo.Context) error
+	DeleteMe(ctx echo.Context) error
+	AnonymizeMe(ctx echo.Context) error
 	Login(ctx echo.Context) error
+	LoginTwoFactor(ctx echo.Context) error
+	SendTwoFactorCode(ctx echo.Context) error
+	Refresh(ctx echo.Context) error
+	SwitchOrganization(ctx echo.Context) error
+	Logout(ctx echo.Context) error
+	LogoutAll(ctx echo.Context) error
 	ConfirmEmail(c echo.Context) error
+	ResendConfirmation(ctx echo.Context) error
+	ConfirmEmailByLink(ctx echo.Context) error
+	RevertEmailChange(ctx echo.Context) error
+	EnableTOTP(ctx echo.Context) error
+	ConfirmTOTP(ctx echo.Context) error
+	GetRecoveryCodeCount(ctx echo.Context) error
+	RegenerateRecoveryCodes(ctx echo.Context) error
+	DisableTwoFactor(ctx echo.Context) error
+	AdminDisableTwoFactor(ctx echo.Context) error
+	AdminForcePasswordReset(ctx echo.Context) error
+	AdminSuspend(ctx echo.Context) error
+	AdminUnsuspend(ctx echo.Context) error
+	AdminGetById(ctx echo.Context) error
+	AdminRestore(ctx echo.Context) error
+	AdminDelete(ctx echo.Context) error
+	AdminAnonymize(ctx echo.Context) error
+	Deactivate(ctx echo.Context) error
+	Reactivate(ctx echo.Context) error
 }
 
 type UserService interface {
-	Create(userPayLoad UserPayLoad) error
-	GetById(id string) (*UserResponse, error)
-	GetByNameOrUsername(nameOrUsername string) ([]UserResponse, error)
-	GetByEmail(email string) (*UserResponse, error)
-	GetByUsername(username string) (*UserResponse, error)
-	GetAll() ([]UserResponse, error)
-	Update(id string, userUpdate UserUpdatePayLoad) error
-	Delete(id string) error
-	Login(login Login) (string, error)
-	ConfirmEmail(confirmCode ConfirmCode) error
-	CheckUserIDMatch(idFromToken string) error
+	Create(ctx context.Context, userPayLoad UserPayLoad, clientInfo ClientInfo) error
+	GetById(ctx context.Context, id string, viewer Viewer) (any, error)
+	GetByNameOrUsername(ctx context.Context, tenantID string, nameOrUsername string, pageRequest PageRequest) (*PublicUserPage, error)
+	GetByEmail(ctx context.Context, tenantID string, email string) (*UserResponse, error)
+	GetByUsername(ctx context.Context, tenantID string, username string) (*UserResponse, error)
+	CheckAvailability(ctx context.Context, tenantID string, query AvailabilityQuery) (*AvailabilityResponse, error)
+	GetAll(ctx context.Context, tenantID string, query UserListQuery) (*UserPage, error)
+	Update(ctx context.Context, id string, userUpdate UserUpdatePayLoad) error
+	Delete(ctx context.Context, id string, password string, clientInfo ClientInfo) (*DeletionScheduledResponse, error)
+	Anonymize(ctx context.Context, id string, password string, clientInfo ClientInfo) error
+	Login(ctx context.Context, login Login, clientInfo ClientInfo) (*LoginResult, error)
+	ContinueLogin(ctx context.Context, userID string, deviceToken string, clientInfo ClientInfo) (*LoginResult, error)
+	LoginTwoFactor(ctx context.Context, claims TokenClaims, payLoad TwoFactorLoginPayLoad, clientInfo ClientInfo) (*LoginResult, error)
+	SendTwoFactorCode(ctx context.Context, userID string) error
+	Authenticate(ctx context.Context, username string, password string, clientInfo ClientInfo) (*UserResponse, error)
+	CreateSession(ctx context.Context, userID string, clientInfo ClientInfo) (*LoginResponse, error)
+	Refresh(ctx context.Context, refreshToken string, clientInfo ClientInfo) (*LoginResponse, error)
+	SwitchOrganization(ctx context.Context, claims TokenClaims, organizationID string) (*AccessTokenResponse, error)
+	Logout(ctx context.Context, claims TokenClaims, refreshToken string) error
+	LogoutAll(ctx context.Context, userID string, password string) error
+	ConfirmEmail(ctx context.Context, confirmCode ConfirmCode, clientInfo ClientInfo) error
+	ResendConfirmation(ctx context.Context, tenantID string, email string) error
+	ConfirmEmailByLink(ctx context.Context, token string) error
+	RevertEmailChange(ctx context.Context, token string, clientInfo ClientInfo) error
+	CheckUserIDMatch(ctx context.Context, idFromToken string) error
+	EnableTOTP(ctx context.Context, userID string) (*TOTPEnrollmentResponse, error)
+	ConfirmTOTP(ctx context.Context, userID string, code string, clientInfo ClientInfo) error
+	GetRecoveryCodeCount(ctx context.Context, userID string) (*RecoveryCodeCountResponse, error)
+	RegenerateRecoveryCodes(ctx context.Context, userID string, password string, clientInfo ClientInfo) (*RecoveryCodesResponse, error)
+	DisableTwoFactor(ctx context.Context, userID string, payLoad DisableTwoFactorPayLoad, clientInfo ClientInfo) error
+	AdminDisableTwoFactor(ctx context.Context, viewer Viewer, userID string) error
+	AdminForcePasswordReset(ctx context.Context, viewer Viewer, userID string) error
+	AdminSuspend(ctx context.Context, viewer Viewer, userID string, payLoad SuspendPayLoad) error
+	AdminUnsuspend(ctx context.Context, viewer Viewer, userID string, reason string) error
+	AdminRestore(ctx context.Context, viewer Viewer, userID string) error
+	AdminDelete(ctx context.Context, viewer Viewer, userID string) error
+	AdminAnonymize(ctx context.Context, viewer Viewer, userID string) error
+	Deactivate(ctx context.Context, userID string, password string, clientInfo ClientInfo) error
+	Reactivate(ctx context.Context, claims TokenClaims, clientInfo ClientInfo) (*LoginResult, error)
 }
 
 type UserRepository interface {
-	Create(user User) error
-	GetById(id string) (*User, error)
-	GetByNameOrUsername(nameOrUsername string) ([]User, error)
-	GetByEmail(email string) (*User, error)
-	GetByUsername(username string) (*User, error)
-	GetAll() ([]User, error)
-	Update(id string, user User) error
-	Delete(id string) error
-	UpdatePassword(id string, password string) error
-	ConfirmedEmail(id string) error
+	Create(ctx context.Context, user User) error
+	GetById(ctx context.Context, id string) (*User, error)
+	GetByNameOrUsername(ctx context.Context, tenantID string, nameOrUsername string, pageRequest PageRequest) ([]User, int64, error)
+	GetByEmail(ctx context.Context, tenantID string, email string) (*User, error)
+	GetByUsername(ctx context.Context, tenantID string, username string) (*User, error)
+	IsUsernameTaken(ctx context.Context, tenantID string, username string) (bool, error)
+	IsEmailTaken(ctx context.Context, tenantID string, email string) (bool, error)
+	GetAll(ctx context.Context, tenantID string, query UserListQuery) ([]User, int64, error)
+	Update(ctx context.Context, id string, changes map[string]interface{}) error
+	Delete(ctx context.Context, id string) error
+	GetDeletedByEmail(ctx context.Context, tenantID string, email string) (*User, error)
+	Restore(ctx context.Context, tenantID string, id string) (bool, error)
+	PurgeDeleted(ctx context.Context, deletedBefore time.Time) (int64, error)
+	Anonymize(ctx context.Context, id string) error
+	SetActive(ctx context.Context, id string, active bool) error
+	Suspend(ctx context.Context, id string, until *time.Time) error
+	Unsuspend(ctx context.Context, id string) (bool, error)
+	ScheduleDeletion(ctx context.Context, id string, at time.Time) error
+	CancelDeletion(ctx context.Context, id string) (bool, error)
+	GetDeletionReminderDue(ctx context.Context, before time.Time) ([]User, error)
+	SetDeletionReminded(ctx context.Context, id string) error
+	GetDeletionDue(ctx context.Context, before time.Time) ([]User, error)
+	GetUnverified(ctx context.Context, createdBefore time.Time, afterID string, limit int) ([]User, error)
+	Purge(ctx context.Context, id string) error
+	UpdatePassword(ctx context.Context, id string, password string) error
+	RehashPassword(ctx context.Context, id string, currentHash string, newHash string) (bool, error)
+	ConfirmedEmail(ctx context.Context, id string) error
+	UpdateEmail(ctx context.Context, id string, email string) error
+	SetPhoneNumber(ctx context.Context, id string, phoneNumber *string) error
+	ConfirmPhoneNumber(ctx context.Context, id string, phoneNumber string) (bool, error)
+	SetAvatarURL(ctx context.Context, id string, avatarURL *string) error
+	UpdateMetadata(ctx context.Context, id string, section string, patch map[string]any) (*UserMetadata, error)
+	IncrementTokenVersion(ctx context.Context, id string) error
+	IncrementTokenVersionByRole(ctx context.Context, roleID string) error
+	IncrementFailedLogins(ctx context.Context, id string) (int, error)
+	LockAccount(ctx context.Context, id string, until time.Time) error
+	ResetFailedLogins(ctx context.Context, id string) error
+	SetMustResetPassword(ctx context.Context, id string, mustReset bool) error
+	SetLoginAlertsEnabled(ctx context.Context, id string, enabled bool) error
+	SetLastLoginAt(ctx context.Context, id string, at time.Time) error
+	UpdateTOTPSecret(ctx context.Context, id string, secret string) error
+	// UseTOTPStep records step as the last authenticator app step accepted for the user. It
+	// reports false when that step, or a later one, was already accepted.
+	UseTOTPStep(ctx context.Context, id string, step int64) (bool, error)
+	ActivateTwoFactor(ctx context.Context, id string, secret string) (bool, error)
+	DisableTwoFactor(ctx context.Context, id string) error
 }
 
 func (upl *UserPayLoad) Validate() error {
-	validate := validator.New()
-	return validate.Struct(upl)
+	validate := newPasswordValidator()
+	registerUsernameValidation(validate)
+	err := validate.Struct(upl)
+	if err := passwordPolicyError(usernamePolicyError(err, upl.Username), upl.Password); err != nil {
+		return err
+	}
+
+	if config.TermsVersion != "" && upl.TermsVersion == "" {
+		return ErrTermsVersionRequired
+	}
+
+	return nil
 }
 
 func (uu *UserUpdatePayLoad) Validate() error {
 	validate := validator.New()
-	return validate.Struct(uu)
+	registerUsernameValidation(validate)
+	registerPreferenceValidations(validate)
+
+	err := validate.Struct(uu)
+	if uu.Username != nil {
+		err = usernamePolicyError(err, *uu.Username)
+	}
+	return err
+}
+
+// IsEmpty tells whether the update changes nothing.
+func (uu *UserUpdatePayLoad) IsEmpty() bool {
+	return uu.Name == nil && uu.Email == nil && uu.Username == nil && uu.Locale == nil && uu.Timezone == nil
+}
+
+func (dup *DeleteUserPayLoad) Validate() error {
+	validate := validator.New()
+	return validate.Struct(dup)
 }
 
 func (upl *UserPayLoad) ToUser(hashedPassword string) (*User, error) {
@@ -145,35 +441,108 @@ func (upl *UserPayLoad) ToUser(hashedPassword string) (*User, error) {
 	return &User{
 		ID:       id.String(),
 		Name:     strings.TrimSpace(upl.Name),
-		Email:    strings.TrimSpace(upl.Email),
-		Username: strings.TrimSpace(upl.Username),
+		Email:    NormalizeEmail(upl.Email),
+		Username: NormalizeUsername(upl.Username),
 		Password: strings.TrimSpace(hashedPassword),
 	}, nil
 }
 
-func (uu *UserUpdatePayLoad) ToUser() *User {
-	return &User{
-		Name:     uu.Name,
-		Email:    uu.Email,
-		Username: uu.Username,
+// Changes returns the columns the update sets right away, by name. The email is left out, as it
+// only changes once the new one is confirmed.
+func (uu *UserUpdatePayLoad) Changes() map[string]interface{} {
+	changes := map[string]interface{}{}
+	if uu.Name != nil {
+		changes["Name"] = strings.TrimSpace(*uu.Name)
+	}
+	if uu.Username != nil {
+		changes["Username"] = NormalizeUsername(*uu.Username)
+	}
+	if uu.Locale != nil {
+		changes["Locale"] = NormalizeLocale(*uu.Locale)
 	}
+	if uu.Timezone != nil {
+		changes["Timezone"] = *uu.Timezone
+	}
+
+	return changes
 }
 
 func (u *User) ToUserResponse() *UserResponse {
-	return &UserResponse{
+	userResponse := &UserResponse{
+		Id:             u.ID,
+		Name:           u.Name,
+		Email:          u.Email,
+		Username:       u.Username,
+		PhoneNumber:    u.PhoneNumber,
+		PhoneConfirmed: u.PhoneConfirmed,
+		Locale:         u.PreferredLocale(),
+		Timezone:       u.PreferredTimezone(),
+		AvatarURL:      u.AvatarOrFallback(),
+		Metadata:       u.Metadata.orEmpty(),
+		CreatedAt:      u.CreatedAt.UTC().Format(time.RFC3339),
+		UpdatedAt:      u.UpdatedAt.UTC().Format(time.RFC3339),
+	}
+
+	if u.LastLoginAt != nil {
+		lastLoginAt := u.LastLoginAt.UTC().Format(time.RFC3339)
+		userResponse.LastLoginAt = &lastLoginAt
+	}
+
+	return userResponse
+}
+
+func (u *User) ToPublicUserResponse() *PublicUserResponse {
+	return &PublicUserResponse{
 		Id:       u.ID,
 		Name:     u.Name,
-		Email:    u.Email,
 		Username: u.Username,
 	}
 }
 
+func (u *User) ToUserInfosResponse() *UserInfosResponse {
+	return &UserInfosResponse{
+		Id:             u.ID,
+		Name:           u.Name,
+		Username:       u.Username,
+		Email:          u.Email,
+		EmailConfirmed: u.EmailConfirmed,
+		AvatarURL:      u.AvatarOrFallback(),
+	}
+}
+
 func (l *Login) Validate() error {
+	if l.Identifier == "" {
+		l.Identifier = l.Username
+	}
+	l.Identifier = strings.TrimSpace(l.Identifier)
+
 	validate := validator.New()
 	return validate.Struct(l)
 }
 
+func (rcp *ResendConfirmationPayLoad) Validate() error {
+	validate := validator.New()
+	return validate.Struct(rcp)
+}
+
+// Validate also checks the code against the configured OTP format, so codes of the wrong
+// length or alphabet never reach the code store.
 func (ce *ConfirmCode) Validate() error {
+	ce.Email = NormalizeEmail(ce.Email)
+
 	validate := validator.New()
-	return validate.Struct(ce)
+	if err := validate.Struct(ce); err != nil {
+		return err
+	}
+
+	format, characters := "numeric", "digits"
+	if config.OTP.Alphanumeric {
+		format, characters = "alphanum", "letters or digits"
+	}
+
+	if err := validate.Var(ce.Code, fmt.Sprintf("len=%d,%s", config.OTP.Length, format)); err != nil {
+		return fmt.Errorf("code must have %d %s", config.OTP.Length, characters)
+	}
+
+	return nil
 }
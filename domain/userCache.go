@@ -0,0 +1,49 @@
+package domain
+
+import (
+	"context"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+type cachedUserContextKey struct{}
+
+// AllowCachedUser lets the lookups of the user repository made with the returned context be served
+// from the user cache. The users in the cache lack their password hash and TOTP secret, and may be
+// up to config.UserCache.TTL old when a write skipped the cache, so only the lookups that need
+// neither opt in, such as checking the owner of a token or loading a profile.
+func AllowCachedUser(ctx context.Context) context.Context {
+	return context.WithValue(ctx, cachedUserContextKey{}, true)
+}
+
+func CachedUserAllowed(ctx context.Context) bool {
+	allowed, _ := ctx.Value(cachedUserContextKey{}).(bool)
+	return allowed
+}
+
+// UserCacheStore keeps encoded users under a key until ttl passes. Get returns nil for a key it
+// does not have.
+type UserCacheStore interface {
+	Get(ctx context.Context, key string) ([]byte, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Delete(ctx context.Context, keys ...string) error
+	Clear(ctx context.Context) error
+}
+
+// UserCacheStats counts the lookups allowed to use the user cache: Hits were served from it and
+// Misses went to the database.
+type UserCacheStats struct {
+	Store  string `json:"store"`
+	Hits   uint64 `json:"hits"`
+	Misses uint64 `json:"misses"`
+}
+
+// UserCache is the user repository when it caches the users it looks up.
+type UserCache interface {
+	Stats() UserCacheStats
+}
+
+type UserCacheHandler interface {
+	GetStats(ctx echo.Context) error
+}
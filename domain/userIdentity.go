@@ -0,0 +1,71 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+var (
+	ErrCreateUserIdentity   = errors.New("error to create user identity")
+	ErrGetUserIdentity      = errors.New("error to get user identity")
+	ErrDeleteUserIdentity   = errors.New("error to delete user identity")
+	ErrUserIdentityNotFound = errors.New("user identity not found")
+	ErrLastLoginMethod      = errors.New("cannot remove the last way to sign in to this account, set a password or link another identity first")
+)
+
+// UserIdentity links a user to an account at an external identity provider, so later sign ins
+// find the user even if the email at the provider changes.
+type UserIdentity struct {
+	ID             string    `gorm:"column:Id;type:char(36);primary_key"`
+	UserID         string    `gorm:"column:UserId;type:char(36);index"`
+	TenantID       string    `gorm:"column:TenantId;type:varchar(36);uniqueIndex:idx_user_identity_tenant_provider,priority:1"`
+	Provider       string    `gorm:"column:Provider;type:varchar(50);uniqueIndex:idx_user_identity_tenant_provider,priority:2"`
+	ProviderUserID string    `gorm:"column:ProviderUserId;type:varchar(255);uniqueIndex:idx_user_identity_tenant_provider,priority:3"`
+	Email          string    `gorm:"column:Email;type:varchar(255)"`
+	CreatedAt      time.Time `gorm:"column:CreatedAt"`
+}
+
+func (UserIdentity) TableName() string {
+	return "user_identities"
+}
+
+type UserIdentityResponse struct {
+	Id       string    `json:"id"`
+	Provider string    `json:"provider"`
+	Email    string    `json:"email"`
+	LinkedAt time.Time `json:"linked_at"`
+}
+
+type LinkIdentityResponse struct {
+	AuthorizationURL string `json:"authorization_url"`
+}
+
+type UserIdentityHandler interface {
+	GetAll(ctx echo.Context) error
+	Link(ctx echo.Context) error
+	Unlink(ctx echo.Context) error
+}
+
+type UserIdentityService interface {
+	GetAll(userID string) ([]UserIdentityResponse, error)
+	Unlink(ctx context.Context, userID string, id string) error
+}
+
+type UserIdentityRepository interface {
+	Create(userIdentity UserIdentity) error
+	GetByProvider(tenantID string, provider string, providerUserID string) (*UserIdentity, error)
+	GetByUserID(userID string) ([]UserIdentity, error)
+	Delete(id string) (bool, error)
+}
+
+func (ui *UserIdentity) ToUserIdentityResponse() *UserIdentityResponse {
+	return &UserIdentityResponse{
+		Id:       ui.ID,
+		Provider: ui.Provider,
+		Email:    ui.Email,
+		LinkedAt: ui.CreatedAt,
+	}
+}
@@ -0,0 +1,119 @@
+package domain
+
+import (
+	"errors"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+)
+
+const (
+	UserSortCreatedAt = "created_at"
+	UserSortName      = "name"
+	UserSortUsername  = "username"
+	UserSortLastLogin = "last_login_at"
+
+	SortAsc  = "asc"
+	SortDesc = "desc"
+)
+
+var ErrInvalidDateRange = errors.New("created_to cannot be before created_from")
+
+// neverLoggedIn stands for the last login of the users who never logged in when sorting by it,
+// so they come first and can be paged through like the others.
+var neverLoggedIn = time.Date(1000, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// UserSortColumns whitelists the columns the user listing can be sorted by. ORDER BY is built
+// from these values only, never from the request.
+var UserSortColumns = map[string]string{
+	UserSortCreatedAt: `"CreatedAt"`,
+	UserSortName:      `"Name"`,
+	UserSortUsername:  `"Username"`,
+	UserSortLastLogin: `COALESCE("LastLoginAt", '1000-01-01')`,
+}
+
+// UserListQuery filters and sorts the user listing. Every filter left out matches all users;
+// Search matches part of the name, username or email, and CreatedFrom and CreatedTo bound the
+// creation date, both inclusive. NeverLoggedIn picks the users who never logged in, or the ones
+// who did, and InactiveDays the ones who have not logged in, or were created if they never did,
+// for at least that many days. Users are listed oldest first by default.
+type UserListQuery struct {
+	PageRequest
+	Search         string     `query:"q" validate:"max=255"`
+	EmailConfirmed *bool      `query:"email_confirmed"`
+	TwoFactor      *bool      `query:"two_factor"`
+	CreatedFrom    *time.Time `query:"created_from"`
+	CreatedTo      *time.Time `query:"created_to"`
+	NeverLoggedIn  *bool      `query:"never_logged_in"`
+	InactiveDays   int        `query:"inactive_days" validate:"min=0"`
+	Sort           string     `query:"sort" validate:"oneof=created_at name username last_login_at"`
+	Order          string     `query:"order" validate:"oneof=asc desc"`
+}
+
+// Validate fills the sort left out with the default order, then checks the page and the filters.
+func (q *UserListQuery) Validate() error {
+	if q.Sort == "" {
+		q.Sort = UserSortCreatedAt
+	}
+
+	if q.Order == "" {
+		q.Order = SortAsc
+	}
+
+	if q.CreatedFrom != nil && q.CreatedTo != nil && q.CreatedTo.Before(*q.CreatedFrom) {
+		return ErrInvalidDateRange
+	}
+
+	validate := validator.New()
+	if err := validate.StructExcept(q, "PageRequest"); err != nil {
+		return err
+	}
+
+	return q.PageRequest.validate(q.cursorSort())
+}
+
+// SortColumn is the column named by Sort, and CreatedAt when Sort is not a known one.
+func (q *UserListQuery) SortColumn() string {
+	if column, ok := UserSortColumns[q.Sort]; ok {
+		return column
+	}
+	return UserSortColumns[UserSortCreatedAt]
+}
+
+func (q *UserListQuery) Descending() bool {
+	return q.Order == SortDesc
+}
+
+// CursorAt is the cursor continuing the listing after user.
+func (q *UserListQuery) CursorAt(user User) Cursor {
+	cursor := Cursor{ID: user.ID, Sort: q.cursorSort()}
+	switch q.SortColumn() {
+	case UserSortColumns[UserSortName]:
+		cursor.Key = user.Name
+	case UserSortColumns[UserSortUsername]:
+		cursor.Key = user.Username
+	case UserSortColumns[UserSortLastLogin]:
+		cursor.CreatedAt = neverLoggedIn
+		if user.LastLoginAt != nil {
+			cursor.CreatedAt = *user.LastLoginAt
+		}
+	default:
+		cursor.CreatedAt = user.CreatedAt
+	}
+	return cursor
+}
+
+// SortsByDate tells whether the cursor carries the sort key in CreatedAt rather than in Key.
+func (q *UserListQuery) SortsByDate() bool {
+	column := q.SortColumn()
+	return column == UserSortColumns[UserSortCreatedAt] || column == UserSortColumns[UserSortLastLogin]
+}
+
+// cursorSort names the order of the listing in its cursors. It is empty for the default order, so
+// cursors of listings that cannot be sorted keep working with it.
+func (q *UserListQuery) cursorSort() string {
+	if (q.Sort == "" || q.Sort == UserSortCreatedAt) && q.Order != SortDesc {
+		return ""
+	}
+	return q.Sort + ":" + q.Order
+}
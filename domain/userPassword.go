@@ -1,22 +1,40 @@
 package domain
 
 import (
+	"context"
+	"errors"
+	"time"
+
 	"github.com/go-playground/validator/v10"
 	"github.com/labstack/echo/v4"
 )
 
+const (
+	PasswordResetRequiredCode = "password_reset_required"
+	ResetPasswordTokenTTL     = 6 * time.Hour
+)
+
 type RequestResetPassword struct {
 	Email string `json:"email,omitempty" validate:"required,email"`
 }
 
 type UpdatePassword struct {
-	Current string `json:"current,omitempty" validate:"required,min=6,containsany=!@#&?"`
-	New     string `json:"new,omitempty" validate:"required,min=6,containsany=!@#&?"`
+	Current string `json:"current,omitempty" validate:"required"`
+	New     string `json:"new,omitempty" validate:"required,password"`
 }
 
 type ResetPassword struct {
-	New     string `json:"new,omitempty" validate:"required,min=6,containsany=!@#&?"`
-	Confirm string `json:"confirm,omitempty" validate:"required,min=6,containsany=!@#&?"`
+	New     string `json:"new,omitempty" validate:"required,password"`
+	Confirm string `json:"confirm,omitempty" validate:"required,eqfield=New"`
+}
+
+// PasswordResetRequiredResponse replaces the tokens of a login when support flagged the account:
+// ResetToken only opens the password reset, and the flag is lifted once it succeeds.
+type PasswordResetRequiredResponse struct {
+	Code       string `json:"code"`
+	ResetToken string `json:"reset_token"`
+	TokenType  string `json:"token_type"`
+	ExpiresIn  int64  `json:"expires_in"`
 }
 
 func (rrp *RequestResetPassword) Validate() error {
@@ -24,24 +42,40 @@ func (rrp *RequestResetPassword) Validate() error {
 	return validate.Struct(rrp)
 }
 func (up *UpdatePassword) Validate() error {
-	validate := validator.New()
-	return validate.Struct(up)
+	validate := newPasswordValidator()
+	return passwordPolicyError(validate.Struct(up), up.New)
 }
 
+// Validate reports a confirmation that differs from New as ErrPasswordConfirmationMismatch.
 func (rp *ResetPassword) Validate() error {
-	validate := validator.New()
-	return validate.Struct(rp)
+	validate := newPasswordValidator()
+	err := validate.Struct(rp)
+
+	var validationErrors validator.ValidationErrors
+	if errors.As(err, &validationErrors) {
+		for _, fieldError := range validationErrors {
+			if fieldError.Tag() == "eqfield" {
+				return ErrPasswordConfirmationMismatch
+			}
+		}
+	}
+
+	return passwordPolicyError(err, rp.New)
 }
 
 type UserPasswordHandler interface {
 	UpdatePassword(ctx echo.Context) error
+	UpdatePasswordMe(ctx echo.Context) error
 	ForgotPassword(ctx echo.Context) error
 	ConfirmResetPasswordCode(ctx echo.Context) error
 	ResetPassword(ctx echo.Context) error
+	Strength(ctx echo.Context) error
 }
 
 type UserPasswordService interface {
-	ConfirmResetPasswordCode(confirmCode ConfirmCode) (string, error)
-	ResetPassword(userId string, resetPassword ResetPassword) error
-	UpdatePassword(id string, updatePassword UpdatePassword) error
+	ForgotPassword(ctx context.Context, tenantID string, email string) error
+	ConfirmResetPasswordCode(ctx context.Context, tenantID string, confirmCode ConfirmCode) (string, error)
+	ResetPassword(ctx context.Context, userId string, resetToken TokenClaims, resetPassword ResetPassword, clientInfo ClientInfo) error
+	UpdatePassword(ctx context.Context, id string, updatePassword UpdatePassword, clientInfo ClientInfo) error
+	Strength(payLoad PasswordStrengthPayLoad) PasswordStrengthResponse
 }
@@ -0,0 +1,203 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/OVillas/autentication/config"
+	"github.com/go-playground/validator/v10"
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	UsernameRuleMinLength  = "min_length"
+	UsernameRuleMaxLength  = "max_length"
+	UsernameRuleCharset    = "charset"
+	UsernameRuleConfusable = "confusable"
+	UsernameRuleReserved   = "reserved"
+)
+
+var ErrUsernamePolicy = errors.New("username does not meet the username policy")
+
+// UsernamePolicyError names the field and the rule of the policy a username broke. It matches
+// ErrUsernamePolicy with errors.Is.
+type UsernamePolicyError struct {
+	FieldError
+}
+
+func (upe *UsernamePolicyError) Error() string {
+	return upe.Field + ": " + upe.Message
+}
+
+func (upe *UsernamePolicyError) Is(target error) bool {
+	return target == ErrUsernamePolicy
+}
+
+// confusables maps the characters that pass for latin letters to the letter they pass for, the
+// way Unicode skeletons do, so "аdmin" in cyrillic or "adm1n" still read as "admin".
+var confusables = map[rune]rune{
+	'0': 'o', '1': 'l', 'i': 'l', '|': 'l', '3': 'e', '5': 's', '@': 'a',
+	'а': 'a', 'в': 'b', 'е': 'e', 'к': 'k', 'м': 'm', 'н': 'h', 'о': 'o', 'р': 'p', 'с': 'c',
+	'т': 't', 'у': 'y', 'х': 'x', 'і': 'l', 'ј': 'j', 'ѕ': 's', 'ԁ': 'd', 'һ': 'h', 'ԛ': 'q',
+	'α': 'a', 'β': 'b', 'ε': 'e', 'ι': 'l', 'κ': 'k', 'ν': 'v', 'ο': 'o', 'ρ': 'p', 'τ': 't',
+	'υ': 'u', 'χ': 'x',
+}
+
+// UsernamePolicy holds the rules usernames must follow. Lengths count characters, not bytes.
+type UsernamePolicy struct {
+	MinLength int
+	MaxLength int
+	Pattern   *regexp.Regexp
+	Reserved  []string
+}
+
+func CurrentUsernamePolicy() UsernamePolicy {
+	return UsernamePolicy{
+		MinLength: config.UsernamePolicy.MinLength,
+		MaxLength: config.UsernamePolicy.MaxLength,
+		Pattern:   config.UsernamePolicy.Pattern,
+		Reserved:  config.UsernamePolicy.Reserved,
+	}
+}
+
+// Check returns a *UsernamePolicyError, naming the "username" field, for the first rule the
+// username breaks.
+func (up UsernamePolicy) Check(username string) error {
+	username = strings.TrimSpace(username)
+
+	length := utf8.RuneCountInString(username)
+	if length < up.MinLength {
+		return newUsernamePolicyError(UsernameRuleMinLength, fmt.Sprintf("must have at least %d characters", up.MinLength))
+	}
+
+	if up.MaxLength > 0 && length > up.MaxLength {
+		return newUsernamePolicyError(UsernameRuleMaxLength, fmt.Sprintf("must have at most %d characters", up.MaxLength))
+	}
+
+	for _, r := range username {
+		if unicode.IsControl(r) || unicode.Is(unicode.Cf, r) || r == '/' || r == '\\' {
+			return newUsernamePolicyError(UsernameRuleCharset, "must not have control, invisible or slash characters")
+		}
+	}
+
+	if up.Pattern != nil && !up.Pattern.MatchString(username) {
+		return newUsernamePolicyError(UsernameRuleCharset, "has characters that are not allowed")
+	}
+
+	if isConfusable(username) {
+		return newUsernamePolicyError(UsernameRuleConfusable, "must not mix scripts or use characters that pass for others")
+	}
+
+	if up.IsReserved(username) {
+		return newUsernamePolicyError(UsernameRuleReserved, "is reserved")
+	}
+
+	return nil
+}
+
+// IsReserved tells whether username is, or passes for, a reserved one. The placeholders of the
+// anonymized accounts are reserved too.
+func (up UsernamePolicy) IsReserved(username string) bool {
+	username = NormalizeUsername(username)
+	if strings.HasPrefix(username, AnonymizedUsername("")) {
+		return true
+	}
+
+	skeleton := usernameSkeleton(username)
+	for _, reserved := range up.Reserved {
+		if skeleton == usernameSkeleton(NormalizeUsername(reserved)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// NormalizeUsername and NormalizeEmail put usernames and emails in the form they are stored and
+// looked up in.
+func NormalizeUsername(username string) string {
+	return strings.ToLower(strings.TrimSpace(username))
+}
+
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+func newUsernamePolicyError(rule string, message string) error {
+	return &UsernamePolicyError{FieldError{Field: "username", Rule: rule, Message: message}}
+}
+
+func usernameSkeleton(username string) string {
+	return strings.Map(func(r rune) rune {
+		if confusable, ok := confusables[r]; ok {
+			return confusable
+		}
+		return r
+	}, username)
+}
+
+// isConfusable reports usernames that read differently than they are written: the ones
+// compatibility normalization changes, like fullwidth letters, the ones mixing letters of more
+// than one script, and the ones in another script spelling only latin lookalikes.
+func isConfusable(username string) bool {
+	if !norm.NFKC.IsNormalString(username) {
+		return true
+	}
+
+	var script string
+	lookalikes := true
+	for _, r := range username {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+
+		letterScript := scriptOf(r)
+		if script != "" && letterScript != script {
+			return true
+		}
+		script = letterScript
+
+		if _, ok := confusables[unicode.ToLower(r)]; !ok {
+			lookalikes = false
+		}
+	}
+
+	return script != "" && script != "Latin" && lookalikes
+}
+
+func scriptOf(r rune) string {
+	for name, table := range unicode.Scripts {
+		if name != "Common" && name != "Inherited" && unicode.Is(table, r) {
+			return name
+		}
+	}
+
+	return ""
+}
+
+// registerUsernameValidation teaches validate the "username" tag, which applies the current
+// UsernamePolicy.
+func registerUsernameValidation(validate *validator.Validate) {
+	validate.RegisterValidation("username", func(fl validator.FieldLevel) bool {
+		return CurrentUsernamePolicy().Check(fl.Field().String()) == nil
+	})
+}
+
+// usernamePolicyError replaces the generic validator message of a failed "username" tag with the
+// rule that was broken.
+func usernamePolicyError(err error, username string) error {
+	var validationErrors validator.ValidationErrors
+	if errors.As(err, &validationErrors) {
+		for _, fieldError := range validationErrors {
+			if fieldError.Tag() == "username" {
+				return CurrentUsernamePolicy().Check(username)
+			}
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,160 @@
+package domain
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	WebAuthnCeremonyRegistration = "registration"
+	WebAuthnCeremonyLogin        = "login"
+	WebAuthnSessionTTL           = 5 * time.Minute
+)
+
+var (
+	ErrWebAuthnDisabled           = errors.New("passkeys are not configured")
+	ErrBeginWebAuthn              = errors.New("error to start passkey ceremony")
+	ErrInvalidWebAuthnSession     = errors.New("passkey ceremony is invalid, expired or already finished")
+	ErrWebAuthnVerification       = errors.New("passkey could not be verified")
+	ErrWebAuthnCloneWarning       = errors.New("passkey signature counter did not increase, the authenticator may have been cloned")
+	ErrCreateWebAuthnCredential   = errors.New("error to create passkey")
+	ErrGetWebAuthnCredential      = errors.New("error to get passkey")
+	ErrUpdateWebAuthnCredential   = errors.New("error to update passkey")
+	ErrDeleteWebAuthnCredential   = errors.New("error to delete passkey")
+	ErrWebAuthnCredentialNotFound = errors.New("passkey not found")
+)
+
+// WebAuthnCredential is a passkey registered by a user. CredentialID is the base64url encoded
+// id chosen by the authenticator; SignCount is the last signature counter it reported, used to
+// spot cloned authenticators.
+type WebAuthnCredential struct {
+	ID              string     `gorm:"column:Id;type:char(36);primary_key"`
+	UserID          string     `gorm:"column:UserId;type:char(36);index"`
+	CredentialID    string     `gorm:"column:CredentialId;type:varchar(255);uniqueIndex"`
+	PublicKey       []byte     `gorm:"column:PublicKey;type:blob"`
+	AttestationType string     `gorm:"column:AttestationType;type:varchar(32)"`
+	AAGUID          []byte     `gorm:"column:Aaguid;type:varbinary(16)"`
+	SignCount       uint32     `gorm:"column:SignCount"`
+	Transports      string     `gorm:"column:Transports;type:varchar(255)"`
+	BackupEligible  bool       `gorm:"column:BackupEligible;default:false"`
+	BackupState     bool       `gorm:"column:BackupState;default:false"`
+	Name            string     `gorm:"column:Name;type:varchar(100)"`
+	LastUsedAt      *time.Time `gorm:"column:LastUsedAt"`
+	CreatedAt       time.Time  `gorm:"column:CreatedAt"`
+}
+
+func (WebAuthnCredential) TableName() string {
+	return "webauthn_credential"
+}
+
+// WebAuthnSession keeps the challenge of a registration or login ceremony between its begin and
+// finish requests. Data holds the library session data as JSON. UserID is empty for logins, where
+// the user is only known once the authenticator answers.
+type WebAuthnSession struct {
+	ID        string     `gorm:"column:Id;type:char(36);primary_key"`
+	Ceremony  string     `gorm:"column:Ceremony;type:varchar(16)"`
+	UserID    string     `gorm:"column:UserId;type:char(36)"`
+	Data      string     `gorm:"column:Data;type:text"`
+	ExpiresAt time.Time  `gorm:"column:ExpiresAt;index"`
+	UsedAt    *time.Time `gorm:"column:UsedAt"`
+	CreatedAt time.Time  `gorm:"column:CreatedAt"`
+}
+
+func (WebAuthnSession) TableName() string {
+	return "webauthn_session"
+}
+
+// WebAuthnBeginResponse carries the options to hand to navigator.credentials.create or get,
+// and the id of the ceremony to send back with the authenticator response.
+type WebAuthnBeginResponse struct {
+	SessionID string      `json:"session_id"`
+	Options   interface{} `json:"options"`
+}
+
+type WebAuthnRegisterPayLoad struct {
+	SessionID  string          `json:"session_id,omitempty" validate:"required,uuid"`
+	Name       string          `json:"name,omitempty" validate:"max=100"`
+	Credential json.RawMessage `json:"credential,omitempty" validate:"required" swaggertype:"object"`
+}
+
+type WebAuthnLoginPayLoad struct {
+	SessionID  string          `json:"session_id,omitempty" validate:"required,uuid"`
+	Credential json.RawMessage `json:"credential,omitempty" validate:"required" swaggertype:"object"`
+}
+
+type WebAuthnCredentialPayLoad struct {
+	Name string `json:"name,omitempty" validate:"required,min=1,max=100"`
+}
+
+type WebAuthnCredentialResponse struct {
+	Id         string     `json:"id"`
+	Name       string     `json:"name"`
+	BackedUp   bool       `json:"backed_up"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+type WebAuthnHandler interface {
+	BeginRegistration(ctx echo.Context) error
+	FinishRegistration(ctx echo.Context) error
+	BeginLogin(ctx echo.Context) error
+	FinishLogin(ctx echo.Context) error
+	GetAll(ctx echo.Context) error
+	Rename(ctx echo.Context) error
+	Delete(ctx echo.Context) error
+}
+
+type WebAuthnService interface {
+	BeginRegistration(ctx context.Context, userID string) (*WebAuthnBeginResponse, error)
+	FinishRegistration(ctx context.Context, userID string, payLoad WebAuthnRegisterPayLoad) (*WebAuthnCredentialResponse, error)
+	BeginLogin() (*WebAuthnBeginResponse, error)
+	FinishLogin(ctx context.Context, payLoad WebAuthnLoginPayLoad, clientInfo ClientInfo) (*LoginResponse, error)
+	GetAll(userID string) ([]WebAuthnCredentialResponse, error)
+	Rename(userID string, id string, name string) error
+	Delete(userID string, id string) error
+}
+
+type WebAuthnCredentialRepository interface {
+	Create(webAuthnCredential WebAuthnCredential) error
+	GetByUserID(userID string) ([]WebAuthnCredential, error)
+	GetByCredentialID(credentialID string) (*WebAuthnCredential, error)
+	UpdateSignCount(id string, signCount uint32, backupState bool) (bool, error)
+	Rename(userID string, id string, name string) (bool, error)
+	Delete(userID string, id string) (bool, error)
+}
+
+type WebAuthnSessionRepository interface {
+	Create(webAuthnSession WebAuthnSession) error
+	GetById(id string) (*WebAuthnSession, error)
+	Use(id string) (bool, error)
+}
+
+func (warp *WebAuthnRegisterPayLoad) Validate() error {
+	validate := validator.New()
+	return validate.Struct(warp)
+}
+
+func (walp *WebAuthnLoginPayLoad) Validate() error {
+	validate := validator.New()
+	return validate.Struct(walp)
+}
+
+func (wacp *WebAuthnCredentialPayLoad) Validate() error {
+	validate := validator.New()
+	return validate.Struct(wacp)
+}
+
+func (wac *WebAuthnCredential) ToWebAuthnCredentialResponse() *WebAuthnCredentialResponse {
+	return &WebAuthnCredentialResponse{
+		Id:         wac.ID,
+		Name:       wac.Name,
+		BackedUp:   wac.BackupState,
+		CreatedAt:  wac.CreatedAt,
+		LastUsedAt: wac.LastUsedAt,
+	}
+}
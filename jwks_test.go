@@ -0,0 +1,158 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/OVillas/autentication/auth"
+	"github.com/OVillas/autentication/config"
+	"github.com/OVillas/autentication/domain"
+	"github.com/golang-jwt/jwt"
+)
+
+func TestAccessTokensValidateAgainstTheJWKS(t *testing.T) {
+	retired := useRS256(t, "key-1")
+	ts := newTestServer(t)
+	user := ts.register(t)
+
+	before := ts.login(t, user, false).AccessToken
+	if err := verifyWithJWKS(t, ts, before); err != nil {
+		t.Fatalf("token of the current key: %v", err)
+	}
+
+	// Rotate: key-2 signs from now on, and key-1 is only published for the tokens it signed.
+	useRS256(t, "key-2", "key-1:"+retired)
+
+	after := ts.login(t, user, false).AccessToken
+	if err := verifyWithJWKS(t, ts, after); err != nil {
+		t.Errorf("token of the new key: %v", err)
+	}
+
+	if err := verifyWithJWKS(t, ts, before); err != nil {
+		t.Errorf("token of the retired key: %v", err)
+	}
+}
+
+func TestTokensOfAnotherKeyDoNotValidateAgainstTheJWKS(t *testing.T) {
+	useRS256(t, "key-1")
+	ts := newTestServer(t)
+
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{"sub": "someone"})
+	token.Header["kid"] = "key-1"
+	forged, err := token.SignedString(privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := verifyWithJWKS(t, ts, forged); err == nil {
+		t.Error("a token signed with another key validated against the JWKS")
+	}
+}
+
+// verifyWithJWKS verifies token the way a downstream service does: with the key of its kid in the
+// published JWKS only.
+func verifyWithJWKS(t *testing.T, ts *testServer, token string) error {
+	t.Helper()
+
+	response := ts.request(http.MethodGet, "/.well-known/jwks.json", nil, "")
+	if response.Code != http.StatusOK {
+		t.Fatalf("jwks: status %d: %s", response.Code, response.Body)
+	}
+
+	if cacheControl := response.Header().Get("Cache-Control"); cacheControl == "" {
+		t.Error("the JWKS has no Cache-Control header")
+	}
+
+	jwks := decode[domain.JSONWebKeySet](t, response)
+
+	_, err := jwt.Parse(token, func(token *jwt.Token) (interface{}, error) {
+		for _, key := range jwks.Keys {
+			if key.Kid != token.Header["kid"] {
+				continue
+			}
+
+			if key.Alg != token.Method.Alg() {
+				return nil, fmt.Errorf("key %s is for %s, the token is %s", key.Kid, key.Alg, token.Method.Alg())
+			}
+
+			return publicKeyOf(key)
+		}
+
+		return nil, fmt.Errorf("no key %v in the JWKS", token.Header["kid"])
+	})
+
+	return err
+}
+
+func publicKeyOf(key domain.JSONWebKey) (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(key.N)
+	if err != nil {
+		return nil, err
+	}
+
+	e, err := base64.RawURLEncoding.DecodeString(key.E)
+	if err != nil {
+		return nil, err
+	}
+
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+}
+
+// useRS256 has the API sign with a new key named kid, publishing the retired keys given as
+// "kid:path" as well, until the test ends. It returns the file of the public key.
+func useRS256(t *testing.T, kid string, retired ...string) string {
+	t.Helper()
+
+	method, path, keyID, publicKeys := config.JWTSigningMethod, config.JWTPrivateKeyPath, config.JWTKeyID, config.JWTPublicKeys
+	t.Cleanup(func() {
+		config.JWTSigningMethod, config.JWTPrivateKeyPath, config.JWTKeyID, config.JWTPublicKeys = method, path, keyID, publicKeys
+	})
+
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	publicKey, err := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	privatePath, publicPath := filepath.Join(dir, "private.pem"), filepath.Join(dir, "public.pem")
+	writePEM(t, privatePath, "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(privateKey))
+	writePEM(t, publicPath, "PUBLIC KEY", publicKey)
+
+	config.JWTSigningMethod = jwt.SigningMethodRS256.Alg()
+	config.JWTPrivateKeyPath = privatePath
+	config.JWTKeyID = kid
+	config.JWTPublicKeys = retired
+
+	if err := auth.LoadSigningKeys(); err != nil {
+		t.Fatal(err)
+	}
+
+	return publicPath
+}
+
+func writePEM(t *testing.T, path string, blockType string, bytes []byte) {
+	t.Helper()
+
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: bytes}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/OVillas/autentication/config"
+	"github.com/OVillas/autentication/domain"
+)
+
+func TestLoginDelayDoublesAfterRapidFireWrongPasswords(t *testing.T) {
+	lockout := config.LoginLockout
+	t.Cleanup(func() { config.LoginLockout = lockout })
+	config.LoginLockout = config.LoginLockoutConfig{
+		MaxAttempts: 100,
+		Duration:    time.Hour,
+		DelayAfter:  3,
+		DelayBase:   time.Minute,
+		DelayMax:    4 * time.Minute,
+	}
+
+	ts := newTestServer(t)
+	user := ts.register(t)
+	wrong := user
+	wrong.Password = "Wrong-Horse-42"
+
+	for n := 0; n < config.LoginLockout.DelayAfter; n++ {
+		assertLoginStatus(t, ts, wrong, http.StatusUnauthorized)
+	}
+
+	// Each failure past the third doubles the wait, up to the cap; a request during the wait is
+	// refused without checking the password, even the right one.
+	for _, delay := range []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute, 4 * time.Minute} {
+		assertLoginDelayed(t, ts, wrong, delay)
+		assertLoginDelayed(t, ts, user, delay)
+
+		elapse(t, user, delay)
+		assertLoginStatus(t, ts, wrong, http.StatusUnauthorized)
+	}
+
+	elapse(t, user, config.LoginLockout.DelayMax)
+	ts.login(t, user, false)
+
+	// The successful login starts the count over.
+	for n := 0; n < config.LoginLockout.DelayAfter; n++ {
+		assertLoginStatus(t, ts, wrong, http.StatusUnauthorized)
+	}
+	assertLoginDelayed(t, ts, wrong, config.LoginLockout.DelayBase)
+}
+
+func assertLoginStatus(t *testing.T, ts *testServer, user testUser, want int) {
+	t.Helper()
+
+	response := ts.request(http.MethodPost, "/v1/auth/login", domain.Login{Identifier: user.Username, Password: user.Password}, "")
+	if response.Code != want {
+		t.Fatalf("login: status %d, want %d: %s", response.Code, want, response.Body)
+	}
+}
+
+// assertLoginDelayed checks that a login is refused with a Retry-After of delay.
+func assertLoginDelayed(t *testing.T, ts *testServer, user testUser, delay time.Duration) {
+	t.Helper()
+
+	response := ts.request(http.MethodPost, "/v1/auth/login", domain.Login{Identifier: user.Username, Password: user.Password}, "")
+	if response.Code != http.StatusTooManyRequests {
+		t.Fatalf("login: status %d, want %d: %s", response.Code, http.StatusTooManyRequests, response.Body)
+	}
+
+	retryAfter, err := strconv.Atoi(response.Header().Get("Retry-After"))
+	if err != nil || retryAfter < int(delay.Seconds())-5 || retryAfter > int(delay.Seconds()) {
+		t.Errorf("Retry-After = %q, want %v", response.Header().Get("Retry-After"), delay)
+	}
+}
+
+// elapse moves the last failed login of user back by d, as if d had passed since.
+func elapse(t *testing.T, user testUser, d time.Duration) {
+	t.Helper()
+
+	err := testDB.Model(&domain.User{}).Where(`"Id" = ?`, user.ID).
+		Update("LastFailedLoginAt", time.Now().Add(-d)).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUnknownIdentifierIsDelayedAndLockedAsAnAccount(t *testing.T) {
+	lockout := config.LoginLockout
+	t.Cleanup(func() { config.LoginLockout = lockout })
+
+	tests := []struct {
+		name    string
+		lockout config.LoginLockoutConfig
+		want    []int
+	}{
+		{
+			name:    "delay",
+			lockout: config.LoginLockoutConfig{MaxAttempts: 100, Duration: time.Hour, DelayAfter: 2, DelayBase: time.Minute, DelayMax: 4 * time.Minute},
+			want:    []int{http.StatusUnauthorized, http.StatusUnauthorized, http.StatusTooManyRequests, http.StatusTooManyRequests},
+		},
+		{
+			name:    "lock",
+			lockout: config.LoginLockoutConfig{MaxAttempts: 3, Duration: time.Hour, DelayAfter: 100, DelayBase: time.Minute, DelayMax: 4 * time.Minute},
+			want:    []int{http.StatusUnauthorized, http.StatusUnauthorized, http.StatusLocked, http.StatusLocked},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config.LoginLockout = test.lockout
+
+			ts := newTestServer(t)
+			wrong := ts.register(t)
+			wrong.Password = "Wrong-Horse-42"
+
+			for n, want := range test.want {
+				account := ts.request(http.MethodPost, "/v1/auth/login", domain.Login{Identifier: wrong.Username, Password: wrong.Password}, "")
+				other := ts.request(http.MethodPost, "/v1/auth/login", domain.Login{Identifier: "unknown" + wrong.Username, Password: wrong.Password}, "")
+
+				if account.Code != want {
+					t.Fatalf("attempt %d: status %d, want %d: %s", n+1, account.Code, want, account.Body)
+				}
+
+				accountError := decode[domain.ErrorResponse](t, account)
+				otherError := decode[domain.ErrorResponse](t, other)
+				if other.Code != account.Code || otherError.Message != accountError.Message ||
+					other.Header().Get("Retry-After") != account.Header().Get("Retry-After") {
+					t.Errorf("attempt %d: unknown identifier answered %d %q, Retry-After %q, want %d %q, Retry-After %q as the account",
+						n+1, other.Code, otherError.Message, other.Header().Get("Retry-After"),
+						account.Code, accountError.Message, account.Header().Get("Retry-After"))
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/OVillas/autentication/config"
+	"github.com/OVillas/autentication/domain"
+	"github.com/OVillas/autentication/secure"
+	"github.com/samber/do"
+)
+
+const magicLinkSubject = "Seu link de acesso"
+
+func TestMagicLinkIsSentOnlyToExistingAccounts(t *testing.T) {
+	ts := newTestServer(t)
+	user := ts.register(t)
+
+	for _, email := range []string{"unknown" + user.Email, user.Email} {
+		response := ts.request(http.MethodPost, "/v1/auth/login/magic-link", domain.MagicLinkPayLoad{Email: email}, "")
+		if response.Code != http.StatusAccepted {
+			t.Fatalf("magic link for %s: status %d: %s", email, response.Code, response.Body)
+		}
+	}
+
+	// The link is sent in the background, after the answer.
+	deadline := time.Now().Add(5 * time.Second)
+	for ts.emails.sentWith(magicLinkSubject) < 1 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	if sent := ts.emails.sentWith(magicLinkSubject); sent != 1 {
+		t.Errorf("%d emails sent, want the link to the existing account only", sent)
+	}
+}
+
+func TestMagicLinkOfAnotherTenantIsRefused(t *testing.T) {
+	tenants := config.Tenants
+	t.Cleanup(func() { config.Tenants = tenants })
+	config.Tenants = append([]string{"other"}, tenants...)
+
+	ts := newTestServer(t)
+	user := ts.register(t)
+
+	token, err := secure.GenerateOpaqueToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = do.MustInvoke[domain.MagicLinkRepository](ts.i).Create(domain.MagicLink{
+		TokenHash: secure.HashToken(token),
+		UserID:    user.ID,
+		ExpiresAt: time.Now().Add(domain.MagicLinkTTL),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Without the header, the request is in the default tenant of the user.
+	verify := func(tenant string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/v1/auth/login/magic-link/verify?token="+url.QueryEscape(token), nil)
+		if tenant != "" {
+			req.Header.Set(config.TenantHeader, tenant)
+		}
+
+		recorder := httptest.NewRecorder()
+		ts.e.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	if response := verify("other"); response.Code != http.StatusUnauthorized {
+		t.Fatalf("link of the default tenant used in another: status %d, want %d: %s", response.Code, http.StatusUnauthorized, response.Body)
+	}
+
+	// The refused attempt did not consume the link.
+	if response := verify(""); response.Code != http.StatusOK {
+		t.Errorf("link used in its tenant: status %d, want %d: %s", response.Code, http.StatusOK, response.Body)
+	}
+}
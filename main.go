@@ -1,18 +1,30 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"log"
+	"log/slog"
+	"net"
+	"os"
 	"os/exec"
+	"os/signal"
 	"runtime"
+	"syscall"
 
 	"github.com/OVillas/autentication/api/handler"
+	"github.com/OVillas/autentication/auth"
 	"github.com/OVillas/autentication/config"
 	"github.com/OVillas/autentication/database"
+	"github.com/OVillas/autentication/database/migrations"
 	_ "github.com/OVillas/autentication/docs"
+	"github.com/OVillas/autentication/domain"
+	authMiddleware "github.com/OVillas/autentication/middleware"
 	"github.com/OVillas/autentication/repository"
 	"github.com/OVillas/autentication/service"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
+	"github.com/redis/go-redis/v9"
 	"github.com/samber/do"
 	echoSwagger "github.com/swaggo/echo-swagger"
 	"gorm.io/gorm"
@@ -34,44 +46,257 @@ import (
 // @in header
 // @name Authorization
 
+// @SecurityDefinitions.apiKey apiKey
+// @in header
+// @name X-Api-Key
+
 // @host localhost:8080
 // @BasePath /
 // @schemes http
 func main() {
 	config.Load()
-	e := echo.New()
-	i := do.New()
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		migrate()
+		return
+	}
 
-	e.Use(middleware.CORSWithConfig(middleware.CORSConfig{
-		AllowOrigins: []string{"*"},
-		AllowHeaders: []string{echo.HeaderOrigin, echo.HeaderContentType, echo.HeaderAccept, echo.HeaderAuthorization},
-	}))
+	if err := auth.LoadSigningKeys(); err != nil {
+		panic(err)
+	}
 
-	db, err := database.NewMysqlConnection()
+	db, err := database.NewConnection()
 	if err != nil {
 		panic(err)
 	}
 
+	if config.Migrations.OnStart {
+		if err := migrations.Run(db); err != nil {
+			panic(err)
+		}
+	} else if err := migrations.CheckPending(db); err != nil {
+		if !config.Migrations.AllowPending {
+			panic(err)
+		}
+		slog.Warn("Starting with pending migrations", slog.Any("error", err))
+	}
+
+	i := do.New()
+	provide(i, db)
+	e := newServer(i)
+
+	go reloadSigningKeysOnHangup()
+	go openBrowser(fmt.Sprintf("http://localhost:%d/swagger/index.html", config.Port))
+
+	e.Logger.Fatal(e.Start(fmt.Sprintf(":%d", config.Port)))
+}
+
+// provide registers the repositories, services, middleware and handlers of the API, on db.
+func provide(i *do.Injector, db *gorm.DB) {
 	do.Provide(i, func(i *do.Injector) (*gorm.DB, error) {
 		return db, nil
 	})
 
-	do.Provide(i, repository.NewUserRepository)
+	if config.CodeStore == config.CodeStoreRedis || config.RateLimitStore == config.RateLimitStoreRedis ||
+		config.UserCache.Store == config.UserCacheRedis {
+		redisClient, err := database.NewRedisConnection()
+		if err != nil {
+			panic(err)
+		}
+
+		do.Provide(i, func(i *do.Injector) (*redis.Client, error) {
+			return redisClient, nil
+		})
+	}
+
+	if config.CodeStore == config.CodeStoreRedis {
+		do.Provide(i, repository.NewRedisConfirmationCodeRepository)
+	} else {
+		do.Provide(i, repository.NewConfirmationCodeRepository)
+	}
+
+	if config.RateLimitStore == config.RateLimitStoreRedis {
+		do.Provide(i, repository.NewRedisRateLimitRepository)
+		do.Provide(i, repository.NewRedisLoginFailureRepository)
+	} else {
+		do.Provide(i, repository.NewMemoryRateLimitRepository)
+		do.Provide(i, repository.NewMemoryLoginFailureRepository)
+	}
+
+	switch config.UserCache.Store {
+	case config.UserCacheRedis:
+		do.Provide(i, repository.NewRedisUserCacheStore)
+		do.Provide(i, repository.NewCachedUserRepository)
+	case config.UserCacheMemory:
+		do.Provide(i, repository.NewMemoryUserCacheStore)
+		do.Provide(i, repository.NewCachedUserRepository)
+	default:
+		do.Provide(i, repository.NewUserRepository)
+	}
+
+	do.Provide(i, auth.NewTokenProvider)
+	do.Provide(i, auth.NewOAuthProviders)
+	do.Provide(i, auth.NewWebAuthn)
+	do.Provide(i, repository.NewRefreshTokenRepository)
+	do.Provide(i, repository.NewRevokedTokenRepository)
+	do.Provide(i, repository.NewPersonalAccessTokenRepository)
+	do.Provide(i, repository.NewApiKeyRepository)
+	do.Provide(i, repository.NewOAuthClientRepository)
+	do.Provide(i, repository.NewAuthorizationCodeRepository)
+	do.Provide(i, repository.NewOAuthStateRepository)
+	do.Provide(i, repository.NewUserIdentityRepository)
+	do.Provide(i, repository.NewRecoveryCodeRepository)
+	do.Provide(i, repository.NewTrustedDeviceRepository)
+	do.Provide(i, repository.NewWebAuthnCredentialRepository)
+	do.Provide(i, repository.NewWebAuthnSessionRepository)
+	do.Provide(i, repository.NewMagicLinkRepository)
+	do.Provide(i, repository.NewPendingEmailRepository)
+	do.Provide(i, repository.NewRoleRepository)
+	do.Provide(i, repository.NewPermissionRepository)
+	do.Provide(i, repository.NewAuditRepository)
+	do.Provide(i, repository.NewLoginHistoryRepository)
+	do.Provide(i, repository.NewKnownDeviceRepository)
+	do.Provide(i, repository.NewDataExportRepository)
+	do.Provide(i, repository.NewInvitationRepository)
+	do.Provide(i, repository.NewOrganizationRepository)
+	do.Provide(i, repository.NewPolicyAcceptanceRepository)
+	do.Provide(i, repository.NewUnitOfWork)
+	do.Provide(i, service.NewAuditService)
+	do.Provide(i, service.NewLoginHistoryService)
+	do.Provide(i, service.NewGeoIPResolver)
+	do.Provide(i, service.NewLoginAlertService)
+	do.Provide(i, service.NewDataExportService)
 	do.Provide(i, service.NewEmailService)
+	do.Provide(i, service.NewSMSSender)
 	do.Provide(i, service.NewUserService)
 	do.Provide(i, service.NewCodeService)
 	do.Provide(i, service.NewUserPasswordService)
+	do.Provide(i, service.NewTokenService)
+	do.Provide(i, service.NewPersonalAccessTokenService)
+	do.Provide(i, service.NewApiKeyService)
+	do.Provide(i, service.NewSessionService)
+	do.Provide(i, service.NewOIDCService)
+	do.Provide(i, service.NewOAuthClientService)
+	do.Provide(i, service.NewOAuthService)
+	do.Provide(i, service.NewSocialLoginService)
+	do.Provide(i, service.NewUserIdentityService)
+	do.Provide(i, service.NewTrustedDeviceService)
+	do.Provide(i, service.NewWebAuthnService)
+	do.Provide(i, service.NewMagicLinkService)
+	do.Provide(i, service.NewRoleService)
+	do.Provide(i, service.NewInvitationService)
+	do.Provide(i, service.NewOrganizationService)
+	do.Provide(i, service.NewPhoneService)
+	do.Provide(i, service.NewTermsService)
+	do.Provide(i, service.NewAvatarStorage)
+	do.Provide(i, service.NewAvatarService)
+	do.Provide(i, service.NewMetadataService)
+	do.Provide(i, authMiddleware.NewPermissionChecker)
+	do.Provide(i, authMiddleware.NewAuthMiddleware)
+	do.Provide(i, authMiddleware.NewRateLimitMiddleware)
 	do.Provide(i, handler.NewUserPasswordHandler)
 	do.Provide(i, handler.NewHealthCheckHandler)
 	do.Provide(i, handler.NewUserHandler)
+	do.Provide(i, handler.NewJWKSHandler)
+	do.Provide(i, handler.NewTokenHandler)
+	do.Provide(i, handler.NewPersonalAccessTokenHandler)
+	do.Provide(i, handler.NewApiKeyHandler)
+	do.Provide(i, handler.NewSessionHandler)
+	do.Provide(i, handler.NewOIDCHandler)
+	do.Provide(i, handler.NewOAuthClientHandler)
+	do.Provide(i, handler.NewOAuthHandler)
+	do.Provide(i, handler.NewSocialLoginHandler)
+	do.Provide(i, handler.NewUserIdentityHandler)
+	do.Provide(i, handler.NewTrustedDeviceHandler)
+	do.Provide(i, handler.NewWebAuthnHandler)
+	do.Provide(i, handler.NewMagicLinkHandler)
+	do.Provide(i, handler.NewRoleHandler)
+	do.Provide(i, handler.NewAuditHandler)
+	do.Provide(i, handler.NewLoginHistoryHandler)
+	do.Provide(i, handler.NewLoginAlertHandler)
+	do.Provide(i, handler.NewDataExportHandler)
+	do.Provide(i, handler.NewInvitationHandler)
+	do.Provide(i, handler.NewOrganizationHandler)
+	do.Provide(i, handler.NewPhoneHandler)
+	do.Provide(i, handler.NewTermsHandler)
+	do.Provide(i, handler.NewAvatarHandler)
+	do.Provide(i, handler.NewMetadataHandler)
+	do.Provide(i, handler.NewUserCacheHandler)
+}
+
+// newServer sets up the middleware and the routes of the API, whose dependencies are in i, once
+// the admin is bootstrapped.
+func newServer(i *do.Injector) *echo.Echo {
+	e := echo.New()
+	e.IPExtractor = newIPExtractor()
+
+	corsConfig := middleware.CORSConfig{
+		AllowOrigins: []string{"*"},
+		AllowHeaders: []string{echo.HeaderOrigin, echo.HeaderContentType, echo.HeaderAccept, echo.HeaderAuthorization, domain.ApiKeyHeader, domain.CSRFHeader, config.TenantHeader},
+	}
+
+	// Browsers only send the session cookies cross-origin to an explicitly allowed origin.
+	if config.SessionCookie.Enabled {
+		corsConfig.AllowOrigins = []string{config.FrontendURL}
+		corsConfig.AllowCredentials = true
+	}
+
+	e.Use(middleware.CORSWithConfig(corsConfig))
+	e.Use(authMiddleware.ResolveTenant)
+
+	if err := do.MustInvoke[domain.RoleService](i).BootstrapAdmin(context.Background()); err != nil {
+		panic(err)
+	}
 
 	handler.SetupRoutes(e, i)
 	e.GET("/swagger/*", echoSwagger.WrapHandler)
 
-	go openBrowser(fmt.Sprintf("http://localhost:%d/swagger/index.html", config.Port))
+	return e
+}
 
-	e.Logger.Fatal(e.Start(fmt.Sprintf(":%d", config.Port)))
+// migrate applies the pending migrations, for the migrate command.
+func migrate() {
+	db, err := database.NewConnection()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := migrations.Run(db); err != nil {
+		log.Fatalf("Failed to run migrations: %v", err)
+	}
+
+	log.Println("Migrations executed successfully.")
+}
+
+// reloadSigningKeysOnHangup re-reads the RS256 key files so operators can rotate keys without
+// a restart; the JWKS endpoint serves whatever set was loaded last.
+func reloadSigningKeysOnHangup() {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+
+	for range hangup {
+		if err := auth.LoadSigningKeys(); err != nil {
+			slog.Error("Error trying to reload the signing keys", slog.Any("error", err))
+		}
+	}
+}
+
+// newIPExtractor only honours X-Forwarded-For when the proxies in front of the API are listed in
+// TRUSTED_PROXIES, otherwise any client could pick the IP sessions are bound to.
+func newIPExtractor() echo.IPExtractor {
+	if len(config.TrustedProxies) == 0 {
+		return echo.ExtractIPDirect()
+	}
+
+	options := []echo.TrustOption{echo.TrustLoopback(false), echo.TrustLinkLocal(false), echo.TrustPrivateNet(false)}
+	for _, proxy := range config.TrustedProxies {
+		_, network, err := net.ParseCIDR(proxy)
+		if err != nil {
+			panic(err)
+		}
+		options = append(options, echo.TrustIPRange(network))
+	}
 
+	return echo.ExtractIPFromXFFHeader(options...)
 }
 
 func openBrowser(url string) {
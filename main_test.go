@@ -0,0 +1,222 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/OVillas/autentication/config"
+	"github.com/OVillas/autentication/database/databasetest"
+	"github.com/OVillas/autentication/domain"
+	"github.com/OVillas/autentication/secure"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/samber/do"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// The tests below drive the API over HTTP, wired as main wires it, on the database of
+// databasetest.
+
+var testDB *gorm.DB
+
+func TestMain(m *testing.M) {
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	config.SecretKey = []byte("a secret only the tests sign with")
+	config.EncryptionKey = []byte("a 32 byte key the tests encrypt!")
+	// A cheap hash keeps the many logins fast, and the tests log in more often than clients may.
+	config.PasswordHashing.Algorithm = secure.AlgorithmBcrypt
+	config.PasswordHashing.BcryptCost = bcrypt.MinCost
+	config.AuthRateLimit = config.RateLimitConfig{PerMinute: 6000, Burst: 1000}
+	config.UnverifiedCleanup.Interval = 0
+
+	db, err := databasetest.Open()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	testDB = db
+	os.Exit(m.Run())
+}
+
+// testServer is the API under test, and the injector its dependencies come from.
+type testServer struct {
+	e      *echo.Echo
+	i      *do.Injector
+	emails *testEmails
+}
+
+// newTestServer wires the API on the test database. The emails are recorded instead of sent, and
+// override may replace more dependencies before any is built.
+func newTestServer(t *testing.T, override ...func(i *do.Injector)) *testServer {
+	t.Helper()
+
+	i := do.New()
+	provide(i, testDB)
+
+	emails := &testEmails{}
+	do.OverrideValue[domain.EmailService](i, emails)
+	for _, override := range override {
+		override(i)
+	}
+
+	return &testServer{e: newServer(i), i: i, emails: emails}
+}
+
+// request sends a request with body encoded as JSON, authenticated by token when it is not empty.
+func (ts *testServer) request(method string, path string, body any, token string) *httptest.ResponseRecorder {
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			panic(err)
+		}
+		reader = bytes.NewReader(encoded)
+	}
+
+	req := httptest.NewRequest(method, path, reader)
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	if token != "" {
+		req.Header.Set(echo.HeaderAuthorization, "Bearer "+token)
+	}
+
+	recorder := httptest.NewRecorder()
+	ts.e.ServeHTTP(recorder, req)
+	return recorder
+}
+
+// testUser is an account registered through the API, with its password.
+type testUser struct {
+	ID       string
+	Username string
+	Email    string
+	Password string
+}
+
+// register signs up a new user with unique username and email.
+func (ts *testServer) register(t *testing.T) testUser {
+	t.Helper()
+
+	suffix := uuid.NewString()[:8]
+	user := testUser{
+		Username: "user" + suffix,
+		Email:    "user" + suffix + "@example.com",
+		Password: "Correct-Horse-42",
+	}
+
+	response := ts.request(http.MethodPost, "/v1/users", domain.UserPayLoad{
+		Name:     "Test User",
+		Username: user.Username,
+		Email:    user.Email,
+		Password: user.Password,
+	}, "")
+	if response.Code != http.StatusCreated {
+		t.Fatalf("register: status %d: %s", response.Code, response.Body)
+	}
+
+	stored, err := ts.userRepository().GetByUsername(context.Background(), config.DefaultTenant, user.Username)
+	if err != nil || stored == nil {
+		t.Fatalf("register: the user was not stored: %v", err)
+	}
+	user.ID = stored.ID
+
+	return user
+}
+
+// login logs user in and returns the session.
+func (ts *testServer) login(t *testing.T, user testUser, rememberMe bool) domain.LoginResponse {
+	t.Helper()
+
+	response := ts.request(http.MethodPost, "/v1/auth/login", domain.Login{
+		Identifier: user.Username,
+		Password:   user.Password,
+		RememberMe: rememberMe,
+	}, "")
+	if response.Code != http.StatusOK {
+		t.Fatalf("login: status %d: %s", response.Code, response.Body)
+	}
+
+	return decode[domain.LoginResponse](t, response)
+}
+
+// refresh exchanges a refresh token for a new session.
+func (ts *testServer) refresh(refreshToken string) *httptest.ResponseRecorder {
+	return ts.request(http.MethodPost, "/v1/auth/refresh", domain.RefreshTokenPayLoad{RefreshToken: refreshToken}, "")
+}
+
+func (ts *testServer) userRepository() domain.UserRepository {
+	return do.MustInvoke[domain.UserRepository](ts.i)
+}
+
+func decode[T any](t *testing.T, response *httptest.ResponseRecorder) T {
+	t.Helper()
+
+	var value T
+	if err := json.Unmarshal(response.Body.Bytes(), &value); err != nil {
+		t.Fatalf("decode %s: %v", response.Body, err)
+	}
+
+	return value
+}
+
+// testEmails records the emails of the API instead of sending them.
+type testEmails struct {
+	mu            sync.Mutex
+	sent          []string
+	notifications []domain.Notification
+}
+
+func (te *testEmails) SendEmail(subject string, content string, to []string) error {
+	te.mu.Lock()
+	defer te.mu.Unlock()
+
+	te.sent = append(te.sent, subject)
+	return nil
+}
+
+func (te *testEmails) Notify(notification domain.Notification, to []string) {
+	te.mu.Lock()
+	defer te.mu.Unlock()
+
+	te.notifications = append(te.notifications, notification)
+}
+
+// sentWith counts the emails sent with subject.
+func (te *testEmails) sentWith(subject string) int {
+	te.mu.Lock()
+	defer te.mu.Unlock()
+
+	count := 0
+	for _, sent := range te.sent {
+		if sent == subject {
+			count++
+		}
+	}
+
+	return count
+}
+
+// notified reports whether a notification of notificationType was sent.
+func (te *testEmails) notified(notificationType string) bool {
+	te.mu.Lock()
+	defer te.mu.Unlock()
+
+	for _, notification := range te.notifications {
+		if notification.Type == notificationType {
+			return true
+		}
+	}
+
+	return false
+}
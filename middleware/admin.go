@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/OVillas/autentication/config"
+	"github.com/OVillas/autentication/domain"
+	"github.com/OVillas/autentication/util"
+	"github.com/labstack/echo/v4"
+)
+
+const adminKeyHeader = "X-Admin-Key"
+
+// CheckAdminKey guards operator endpoints with the configured admin key sent in X-Admin-Key.
+// With no key configured every request is refused.
+func CheckAdminKey(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(ctx echo.Context) error {
+		adminKey := ctx.Request().Header.Get(adminKeyHeader)
+		if config.AdminKey == "" || subtle.ConstantTimeCompare([]byte(adminKey), []byte(config.AdminKey)) != 1 {
+			return ctx.NoContent(http.StatusUnauthorized)
+		}
+
+		ctx.Set(util.CallerTypeContextKey, domain.CallerAdmin)
+		return next(ctx)
+	}
+}
+
+// CheckAdmin guards the admin endpoints. Operators send the admin key; users log in and must
+// hold the admin role.
+func (am *AuthMiddleware) CheckAdmin(next echo.HandlerFunc) echo.HandlerFunc {
+	withKey := CheckAdminKey(next)
+	withRole := am.CheckSessionLoggedIn(RequireRole(domain.RoleAdmin)(next))
+	return func(ctx echo.Context) error {
+		if ctx.Request().Header.Get(adminKeyHeader) != "" {
+			return withKey(ctx)
+		}
+
+		return withRole(ctx)
+	}
+}
+
+// RequireRole lets through users whose access token carries role and operators with the admin
+// key. It goes after the middleware that authenticates the request. Services calling with an api
+// key pass too, as their key already lists the routes they may reach.
+func RequireRole(role string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			if callerType, _ := ctx.Get(util.CallerTypeContextKey).(string); callerType == domain.CallerApiKey {
+				return next(ctx)
+			}
+
+			if !util.HasRole(ctx, role) {
+				return ctx.JSON(http.StatusForbidden, map[string]string{"error": domain.ErrUserNotAuthorized.Error()})
+			}
+
+			return next(ctx)
+		}
+	}
+}
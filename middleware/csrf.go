@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/OVillas/autentication/config"
+	"github.com/OVillas/autentication/domain"
+	"github.com/labstack/echo/v4"
+)
+
+// CheckCSRF enforces the double-submit token on routes that read the refresh token cookie:
+// the X-CSRF-Token header must match the csrf_token cookie set at login. Outside the session
+// cookie mode the refresh token comes in the body and there is nothing to forge.
+func CheckCSRF(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(ctx echo.Context) error {
+		if !config.SessionCookie.Enabled {
+			return next(ctx)
+		}
+
+		cookie, err := ctx.Cookie(domain.CSRFCookieName)
+		header := ctx.Request().Header.Get(domain.CSRFHeader)
+		if err != nil || cookie.Value == "" || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(header)) != 1 {
+			return ctx.JSON(http.StatusForbidden, map[string]string{"error": domain.ErrUserNotAuthorized.Error()})
+		}
+
+		return next(ctx)
+	}
+}
@@ -1,15 +1,122 @@
 package middleware
 
 import (
+	"errors"
+	"log/slog"
 	"net/http"
 	"strings"
 
-	"github.com/OVillas/autentication/config"
-	"github.com/golang-jwt/jwt"
+	"github.com/OVillas/autentication/auth"
+	"github.com/OVillas/autentication/domain"
+	"github.com/OVillas/autentication/secure"
+	"github.com/OVillas/autentication/util"
 	"github.com/labstack/echo/v4"
+	"github.com/samber/do"
 )
 
-func CheckLoggedIn(next echo.HandlerFunc) echo.HandlerFunc {
+type AuthMiddleware struct {
+	i                             *do.Injector
+	userRepository                domain.UserRepository
+	revokedTokenRepository        domain.RevokedTokenRepository
+	refreshTokenRepository        domain.RefreshTokenRepository
+	personalAccessTokenRepository domain.PersonalAccessTokenRepository
+	apiKeyRepository              domain.ApiKeyRepository
+	oauthClientRepository         domain.OAuthClientRepository
+	policyAcceptanceRepository    domain.PolicyAcceptanceRepository
+	permissionChecker             domain.PermissionChecker
+	tokenProvider                 auth.TokenProvider
+}
+
+func NewAuthMiddleware(i *do.Injector) (*AuthMiddleware, error) {
+	userRepository := do.MustInvoke[domain.UserRepository](i)
+	revokedTokenRepository := do.MustInvoke[domain.RevokedTokenRepository](i)
+	refreshTokenRepository := do.MustInvoke[domain.RefreshTokenRepository](i)
+	personalAccessTokenRepository := do.MustInvoke[domain.PersonalAccessTokenRepository](i)
+	apiKeyRepository := do.MustInvoke[domain.ApiKeyRepository](i)
+	oauthClientRepository := do.MustInvoke[domain.OAuthClientRepository](i)
+	policyAcceptanceRepository := do.MustInvoke[domain.PolicyAcceptanceRepository](i)
+	permissionChecker := do.MustInvoke[domain.PermissionChecker](i)
+	tokenProvider := do.MustInvoke[auth.TokenProvider](i)
+	return &AuthMiddleware{
+		i:                             i,
+		userRepository:                userRepository,
+		revokedTokenRepository:        revokedTokenRepository,
+		refreshTokenRepository:        refreshTokenRepository,
+		personalAccessTokenRepository: personalAccessTokenRepository,
+		apiKeyRepository:              apiKeyRepository,
+		oauthClientRepository:         oauthClientRepository,
+		policyAcceptanceRepository:    policyAcceptanceRepository,
+		permissionChecker:             permissionChecker,
+		tokenProvider:                 tokenProvider,
+	}, nil
+}
+
+// CheckLoggedIn accepts regular access tokens, personal access tokens and tokens issued to
+// machine clients. Scoped tokens such as the one issued to reset a password are refused so
+// they cannot reach any other endpoint. Users must have accepted the current terms, see
+// RequireCurrentTerms.
+func (am *AuthMiddleware) CheckLoggedIn(next echo.HandlerFunc) echo.HandlerFunc {
+	return am.authenticate(next, "", true)
+}
+
+// CheckSessionLoggedIn accepts only access tokens issued by a login, for actions such as
+// managing sessions or personal access tokens that a script or a machine client should not be
+// able to perform.
+func (am *AuthMiddleware) CheckSessionLoggedIn(next echo.HandlerFunc) echo.HandlerFunc {
+	return am.authenticate(next, "", false)
+}
+
+// CheckLoggedInOrApiKey behaves like CheckLoggedIn unless the request carries an X-Api-Key
+// header, in which case the calling service must hold a key allowed to reach the route.
+func (am *AuthMiddleware) CheckLoggedInOrApiKey(next echo.HandlerFunc) echo.HandlerFunc {
+	loggedIn := am.CheckLoggedIn(next)
+	return func(ctx echo.Context) error {
+		key := ctx.Request().Header.Get(domain.ApiKeyHeader)
+		if key == "" {
+			return loggedIn(ctx)
+		}
+
+		apiKey, err := am.apiKeyRepository.GetBySecretHash(secure.HashToken(key))
+		if err != nil {
+			slog.Error("Error trying to get api key", slog.Any("error", err))
+			return ctx.NoContent(http.StatusInternalServerError)
+		}
+
+		if apiKey == nil || !apiKey.IsActive() {
+			return ctx.NoContent(http.StatusUnauthorized)
+		}
+
+		if !apiKey.Allows(ctx.Request().Method, ctx.Path()) {
+			return ctx.JSON(http.StatusForbidden, map[string]string{"error": domain.ErrUserNotAuthorized.Error()})
+		}
+
+		if err := am.apiKeyRepository.UpdateLastUsedAt(apiKey.ID); err != nil {
+			slog.Warn("Error trying to record api key usage", slog.Any("error", err))
+		}
+
+		ctx.Set(util.CallerTypeContextKey, domain.CallerApiKey)
+		return next(ctx)
+	}
+}
+
+// CheckPasswordResetToken accepts only tokens minted by ConfirmResetPasswordCode.
+func (am *AuthMiddleware) CheckPasswordResetToken(next echo.HandlerFunc) echo.HandlerFunc {
+	return am.authenticate(next, domain.ScopePasswordReset, false)
+}
+
+// CheckTwoFactorChallengeToken accepts only the challenge tokens returned by a login that still
+// needs a second factor.
+func (am *AuthMiddleware) CheckTwoFactorChallengeToken(next echo.HandlerFunc) echo.HandlerFunc {
+	return am.authenticate(next, domain.ScopeTwoFactorPending, false)
+}
+
+// CheckReactivationToken accepts only the reactivation tokens returned by a login to a
+// deactivated account.
+func (am *AuthMiddleware) CheckReactivationToken(next echo.HandlerFunc) echo.HandlerFunc {
+	return am.authenticate(next, domain.ScopeReactivation, false)
+}
+
+func (am *AuthMiddleware) authenticate(next echo.HandlerFunc, scope string, allowMachine bool) echo.HandlerFunc {
 	return func(ctx echo.Context) error {
 		authorizationHeader := ctx.Request().Header.Get("Authorization")
 
@@ -24,23 +131,152 @@ func CheckLoggedIn(next echo.HandlerFunc) echo.HandlerFunc {
 
 		tokenString := parts[1]
 
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			return []byte(config.SecretKey), nil
-		})
+		if strings.HasPrefix(tokenString, domain.PersonalAccessTokenPrefix) {
+			if !allowMachine {
+				return ctx.JSON(http.StatusForbidden, map[string]string{"error": domain.ErrUserNotAuthorized.Error()})
+			}
+			return am.authenticatePersonalAccessToken(ctx, next, tokenString)
+		}
 
+		claims, err := am.tokenProvider.ParseToken(tokenString)
 		if err != nil {
-			if ve, ok := err.(*jwt.ValidationError); ok {
-				if ve.Errors&jwt.ValidationErrorExpired != 0 {
-					return ctx.JSON(http.StatusUnauthorized, map[string]string{"error": "token expired"})
-				}
+			if errors.Is(err, domain.ErrTokenExpired) {
+				return ctx.JSON(http.StatusUnauthorized, map[string]string{"error": "token expired"})
 			}
 			return ctx.JSON(http.StatusUnauthorized, map[string]string{"error": "invalid token"})
 		}
 
-		if !token.Valid {
-			return ctx.NoContent(http.StatusUnauthorized)
+		if claims.IsClient() {
+			if !allowMachine {
+				return ctx.JSON(http.StatusForbidden, map[string]string{"error": domain.ErrClientNotAllowed.Error()})
+			}
+			return am.authenticateClient(ctx, next, claims)
 		}
 
-		return next(ctx)
+		if claims.Scope != scope {
+			return ctx.JSON(http.StatusForbidden, map[string]string{"error": domain.ErrUserNotAuthorized.Error()})
+		}
+
+		revoked, err := am.revokedTokenRepository.Exists(claims.ID, claims.SessionID)
+		if err != nil {
+			slog.Error("Error trying to check token revocation", slog.Any("error", err))
+			return ctx.NoContent(http.StatusInternalServerError)
+		}
+
+		if revoked {
+			return ctx.JSON(http.StatusUnauthorized, map[string]string{"error": domain.ErrInvalidToken.Error()})
+		}
+
+		user, err := am.userRepository.GetById(domain.AllowCachedUser(ctx.Request().Context()), claims.UserID)
+		if err != nil {
+			slog.Error("Error trying to get token owner", slog.Any("error", err))
+			return ctx.NoContent(http.StatusInternalServerError)
+		}
+
+		if user == nil || user.TokenVersion != claims.Version {
+			return ctx.JSON(http.StatusUnauthorized, map[string]string{"error": domain.ErrInvalidToken.Error()})
+		}
+
+		if claims.TenantID != user.TenantID || user.TenantID != util.ExtractTenant(ctx) {
+			return ctx.JSON(http.StatusUnauthorized, map[string]string{"error": domain.ErrTenantMismatch.Error()})
+		}
+
+		if user.IsSuspended() {
+			return ctx.JSON(http.StatusForbidden, map[string]string{"error": domain.ErrAccountSuspended.Error()})
+		}
+
+		ctx.Set(util.UserIDContextKey, claims.UserID)
+		ctx.Set(util.TokenClaimsContextKey, claims)
+		ctx.Set(util.CallerTypeContextKey, domain.CallerUser)
+		if scope != "" {
+			return next(ctx)
+		}
+
+		return am.RequireCurrentTerms(next)(ctx)
+	}
+}
+
+func (am *AuthMiddleware) authenticatePersonalAccessToken(ctx echo.Context, next echo.HandlerFunc, tokenString string) error {
+	personalAccessToken, err := am.personalAccessTokenRepository.GetByTokenHash(secure.HashToken(tokenString))
+	if err != nil {
+		slog.Error("Error trying to get personal access token", slog.Any("error", err))
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+
+	if personalAccessToken == nil || !personalAccessToken.IsActive() {
+		return ctx.JSON(http.StatusUnauthorized, map[string]string{"error": domain.ErrInvalidToken.Error()})
+	}
+
+	if !personalAccessToken.HasScope(requiredScope(ctx)) {
+		return ctx.JSON(http.StatusForbidden, map[string]string{"error": domain.ErrUserNotAuthorized.Error()})
+	}
+
+	// Personal access tokens outlive a suspension, so the owner is checked on every request.
+	user, err := am.userRepository.GetById(domain.AllowCachedUser(ctx.Request().Context()), personalAccessToken.UserID)
+	if err != nil {
+		slog.Error("Error trying to get token owner", slog.Any("error", err))
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+
+	if user == nil {
+		return ctx.JSON(http.StatusUnauthorized, map[string]string{"error": domain.ErrInvalidToken.Error()})
+	}
+
+	if user.TenantID != util.ExtractTenant(ctx) {
+		return ctx.JSON(http.StatusUnauthorized, map[string]string{"error": domain.ErrTenantMismatch.Error()})
+	}
+
+	if user.IsSuspended() {
+		return ctx.JSON(http.StatusForbidden, map[string]string{"error": domain.ErrAccountSuspended.Error()})
+	}
+
+	if err := am.personalAccessTokenRepository.UpdateLastUsedAt(personalAccessToken.ID); err != nil {
+		slog.Warn("Error trying to record personal access token usage", slog.Any("error", err))
+	}
+
+	ctx.Set(util.UserIDContextKey, personalAccessToken.UserID)
+	ctx.Set(util.CallerTypeContextKey, domain.CallerUser)
+	return am.RequireCurrentTerms(next)(ctx)
+}
+
+// authenticateClient lets a client credentials token through when the client still exists and
+// was granted the scope the method needs. No user id is set, so handlers acting on "the current
+// user" reject the request by themselves.
+func (am *AuthMiddleware) authenticateClient(ctx echo.Context, next echo.HandlerFunc, claims *domain.TokenClaims) error {
+	revoked, err := am.revokedTokenRepository.Exists(claims.ID)
+	if err != nil {
+		slog.Error("Error trying to check token revocation", slog.Any("error", err))
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+
+	if revoked {
+		return ctx.JSON(http.StatusUnauthorized, map[string]string{"error": domain.ErrInvalidToken.Error()})
 	}
+
+	oauthClient, err := am.oauthClientRepository.GetById(claims.ClientID)
+	if err != nil {
+		slog.Error("Error trying to get token client", slog.Any("error", err))
+		return ctx.NoContent(http.StatusInternalServerError)
+	}
+
+	if oauthClient == nil {
+		return ctx.JSON(http.StatusUnauthorized, map[string]string{"error": domain.ErrInvalidToken.Error()})
+	}
+
+	if !claims.HasScope(requiredScope(ctx)) {
+		return ctx.JSON(http.StatusForbidden, map[string]string{"error": domain.ErrUserNotAuthorized.Error()})
+	}
+
+	ctx.Set(util.TokenClaimsContextKey, claims)
+	ctx.Set(util.CallerTypeContextKey, domain.CallerClient)
+	return next(ctx)
+}
+
+// requiredScope maps the request method to the scope a delegated credential needs.
+func requiredScope(ctx echo.Context) string {
+	if method := ctx.Request().Method; method == http.MethodGet || method == http.MethodHead {
+		return domain.ScopeReadUser
+	}
+
+	return domain.ScopeWriteUser
 }
@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+	"slices"
+
+	"github.com/OVillas/autentication/domain"
+	"github.com/OVillas/autentication/util"
+	"github.com/labstack/echo/v4"
+	"github.com/samber/do"
+)
+
+type permissionChecker struct {
+	i                    *do.Injector
+	permissionRepository domain.PermissionRepository
+}
+
+func NewPermissionChecker(i *do.Injector) (domain.PermissionChecker, error) {
+	permissionRepository := do.MustInvoke[domain.PermissionRepository](i)
+	return &permissionChecker{
+		i:                    i,
+		permissionRepository: permissionRepository,
+	}, nil
+}
+
+// Has lets operators with the admin key hold every permission. Users are checked against the
+// permissions in their access token, or looked up when they authenticated with a personal access
+// token. Machine clients and api keys hold none.
+func (pc *permissionChecker) Has(ctx echo.Context, permission string) (bool, error) {
+	if callerType, _ := ctx.Get(util.CallerTypeContextKey).(string); callerType == domain.CallerAdmin {
+		return true, nil
+	}
+
+	if claims, err := util.ExtractTokenClaims(ctx); err == nil {
+		return claims.UserID != "" && claims.HasPermission(permission), nil
+	}
+
+	userID, err := util.ExtractUserIdFromToken(ctx)
+	if err != nil {
+		return false, nil
+	}
+
+	permissions, err := pc.permissionRepository.GetNamesByUserID(userID)
+	if err != nil {
+		return false, err
+	}
+
+	return slices.Contains(permissions, permission), nil
+}
+
+// RequirePermission lets through the callers holding permission. It goes after the middleware
+// that authenticates the request. Services calling with an api key pass too, as their key
+// already lists the routes they may reach.
+func (am *AuthMiddleware) RequirePermission(permission string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			if callerType, _ := ctx.Get(util.CallerTypeContextKey).(string); callerType == domain.CallerApiKey {
+				return next(ctx)
+			}
+
+			allowed, err := am.permissionChecker.Has(ctx, permission)
+			if err != nil {
+				slog.Error("Error trying to check permission", slog.Any("error", err))
+				return ctx.NoContent(http.StatusInternalServerError)
+			}
+
+			if !allowed {
+				return ctx.JSON(http.StatusForbidden, map[string]string{"error": domain.ErrUserNotAuthorized.Error()})
+			}
+
+			return next(ctx)
+		}
+	}
+}
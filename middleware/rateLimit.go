@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/OVillas/autentication/config"
+	"github.com/OVillas/autentication/domain"
+	"github.com/labstack/echo/v4"
+	"github.com/samber/do"
+)
+
+type RateLimitMiddleware struct {
+	i                   *do.Injector
+	rateLimitRepository domain.RateLimitRepository
+}
+
+func NewRateLimitMiddleware(i *do.Injector) (*RateLimitMiddleware, error) {
+	rateLimitRepository := do.MustInvoke[domain.RateLimitRepository](i)
+	return &RateLimitMiddleware{
+		i:                   i,
+		rateLimitRepository: rateLimitRepository,
+	}, nil
+}
+
+// LimitByIP gives each client IP its own allowance on the routes sharing name. The IP is the one
+// echo resolves, so X-Forwarded-For only counts when TRUSTED_PROXIES lists the proxy. If the
+// limiter store fails the request goes through rather than taking the endpoint down.
+func (rlm *RateLimitMiddleware) LimitByIP(name string, limit config.RateLimitConfig) echo.MiddlewareFunc {
+	rateLimit := domain.RateLimit{Interval: time.Minute / time.Duration(limit.PerMinute), Burst: limit.Burst}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			wait, err := rlm.rateLimitRepository.Allow(name+":"+ctx.RealIP(), rateLimit)
+			if err != nil {
+				slog.Error("Error trying to apply rate limit", slog.String("name", name), slog.Any("error", err))
+				return next(ctx)
+			}
+
+			if wait > 0 {
+				ctx.Response().Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+				return ctx.JSON(http.StatusTooManyRequests, domain.ErrorResponse{
+					Error:     "Too Many Requests",
+					Message:   "too many requests, try again later",
+					TimeStamp: time.Now(),
+					Path:      ctx.Path(),
+				})
+			}
+
+			return next(ctx)
+		}
+	}
+}
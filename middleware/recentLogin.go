@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/OVillas/autentication/config"
+	"github.com/OVillas/autentication/domain"
+	"github.com/OVillas/autentication/util"
+	"github.com/labstack/echo/v4"
+)
+
+// RequireRecentLogin lets through only sessions opened within config.RecentLoginWindow, for
+// actions sensitive enough that a stolen, long lived session should not reach them. It goes after
+// CheckSessionLoggedIn; refreshing the access token does not count as logging in again.
+func (am *AuthMiddleware) RequireRecentLogin(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(ctx echo.Context) error {
+		claims, err := util.ExtractTokenClaims(ctx)
+		if err != nil || claims.SessionID == "" {
+			return ctx.JSON(http.StatusForbidden, map[string]string{"error": domain.ErrRecentLoginRequired.Error()})
+		}
+
+		refreshToken, err := am.refreshTokenRepository.GetLatestByFamilyID(claims.SessionID)
+		if err != nil {
+			slog.Error("Error trying to get session", slog.Any("error", err))
+			return ctx.NoContent(http.StatusInternalServerError)
+		}
+
+		if refreshToken == nil || time.Since(refreshToken.SessionAt) > config.RecentLoginWindow {
+			return ctx.JSON(http.StatusForbidden, map[string]string{"error": domain.ErrRecentLoginRequired.Error()})
+		}
+
+		return next(ctx)
+	}
+}
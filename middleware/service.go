@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"crypto/subtle"
+
+	"github.com/OVillas/autentication/config"
+	"github.com/labstack/echo/v4"
+	echoMiddleware "github.com/labstack/echo/v4/middleware"
+)
+
+// CheckServiceCredentials guards endpoints meant for other services with HTTP basic auth
+// against the configured service client. With no client configured every request is refused.
+func CheckServiceCredentials(next echo.HandlerFunc) echo.HandlerFunc {
+	return echoMiddleware.BasicAuth(func(clientID, clientSecret string, ctx echo.Context) (bool, error) {
+		if config.ServiceClientID == "" || config.ServiceClientSecret == "" {
+			return false, nil
+		}
+
+		validID := subtle.ConstantTimeCompare([]byte(clientID), []byte(config.ServiceClientID)) == 1
+		validSecret := subtle.ConstantTimeCompare([]byte(clientSecret), []byte(config.ServiceClientSecret)) == 1
+
+		return validID && validSecret, nil
+	})(next)
+}
@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/OVillas/autentication/config"
+	"github.com/OVillas/autentication/domain"
+	"github.com/OVillas/autentication/util"
+	"github.com/labstack/echo/v4"
+)
+
+// ResolveTenant scopes the request to the tenant named by the tenant header, or else to the one
+// its host is mapped to, or else to the default tenant. A header naming an unknown tenant is
+// refused rather than falling back, so a typo cannot land users in the wrong brand.
+func ResolveTenant(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(ctx echo.Context) error {
+		tenant := ctx.Request().Header.Get(config.TenantHeader)
+		if tenant != "" && !slices.Contains(config.Tenants, tenant) {
+			return ctx.JSON(http.StatusBadRequest, map[string]string{"error": domain.ErrUnknownTenant.Error()})
+		}
+
+		if tenant == "" {
+			tenant = hostTenant(ctx.Request().Host)
+		}
+
+		ctx.Set(util.TenantContextKey, tenant)
+		return next(ctx)
+	}
+}
+
+func hostTenant(host string) string {
+	if hostname, _, err := net.SplitHostPort(host); err == nil {
+		host = hostname
+	}
+
+	if tenant, ok := config.TenantHosts[strings.ToLower(host)]; ok {
+		return tenant
+	}
+
+	return config.DefaultTenant
+}
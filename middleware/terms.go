@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+	"slices"
+
+	"github.com/OVillas/autentication/config"
+	"github.com/OVillas/autentication/domain"
+	"github.com/OVillas/autentication/util"
+	"github.com/labstack/echo/v4"
+)
+
+// termsExemptRoutes stay open to users who did not accept the current terms: the acceptance
+// itself, and what a user who does not agree needs to look at their account and leave.
+var termsExemptRoutes = []string{
+	"POST /v1/users/me/accept-terms",
+	"GET /v1/users/me",
+	"DELETE /v1/users/me",
+	"POST /v1/users/me/anonymize",
+	"GET /v1/users/me/export",
+	"POST /v1/users/me/logout-all",
+	"POST /v1/auth/logout",
+}
+
+// RequireCurrentTerms refuses the requests of a user whose latest acceptance is not of
+// config.TermsVersion with 451 and a domain.ReacceptanceRequiredResponse, except on
+// termsExemptRoutes. authenticate runs it for every user, so it needs no place in the routes.
+func (am *AuthMiddleware) RequireCurrentTerms(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(ctx echo.Context) error {
+		if config.TermsVersion == "" || slices.Contains(termsExemptRoutes, ctx.Request().Method+" "+ctx.Path()) {
+			return next(ctx)
+		}
+
+		userID, err := util.ExtractUserIdFromToken(ctx)
+		if err != nil {
+			return next(ctx)
+		}
+
+		policyAcceptance, err := am.policyAcceptanceRepository.GetLatestByUserID(userID)
+		if err != nil {
+			slog.Error("Error trying to get policy acceptance", slog.Any("error", err))
+			return ctx.NoContent(http.StatusInternalServerError)
+		}
+
+		if policyAcceptance == nil || policyAcceptance.Version != config.TermsVersion {
+			return ctx.JSON(http.StatusUnavailableForLegalReasons, domain.ReacceptanceRequiredResponse{
+				Error:        "Unavailable For Legal Reasons",
+				Message:      domain.ErrReacceptanceRequired.Error(),
+				Code:         domain.ReacceptanceRequiredCode,
+				TermsVersion: config.TermsVersion,
+			})
+		}
+
+		return next(ctx)
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/OVillas/autentication/auth"
+	"github.com/OVillas/autentication/domain"
+	"github.com/samber/do"
+)
+
+func TestPasswordChangeEndsEverySession(t *testing.T) {
+	const newPassword = "Battery-Staple-43"
+
+	tests := []struct {
+		name         string
+		notification string
+		status       int
+		change       func(t *testing.T, ts *testServer, user testUser, session domain.LoginResponse) *httptest.ResponseRecorder
+	}{
+		{"change", domain.NotificationPasswordChanged, http.StatusNoContent, func(t *testing.T, ts *testServer, user testUser, session domain.LoginResponse) *httptest.ResponseRecorder {
+			return ts.request(http.MethodPut, "/v1/users/me/password",
+				domain.UpdatePassword{Current: user.Password, New: newPassword}, session.AccessToken)
+		}},
+		{"reset", domain.NotificationPasswordReset, http.StatusOK, func(t *testing.T, ts *testServer, user testUser, session domain.LoginResponse) *httptest.ResponseRecorder {
+			stored, err := ts.userRepository().GetById(context.Background(), user.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			resetToken, err := do.MustInvoke[auth.TokenProvider](ts.i).CreateResetPasswordToken(*stored)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			return ts.request(http.MethodPost, "/v1/auth/password/reset",
+				domain.ResetPassword{New: newPassword, Confirm: newPassword}, resetToken)
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ts := newTestServer(t)
+			user := ts.register(t)
+			session := ts.login(t, user, false)
+			otherSession := ts.login(t, user, true)
+
+			if response := test.change(t, ts, user, session); response.Code != test.status {
+				t.Fatalf("%s: status %d: %s", test.name, response.Code, response.Body)
+			}
+
+			for name, refreshToken := range map[string]string{
+				"the session that changed it": session.RefreshToken,
+				"another session":             otherSession.RefreshToken,
+			} {
+				if response := ts.refresh(refreshToken); response.Code != http.StatusUnauthorized {
+					t.Errorf("refresh with %s: status %d, want %d", name, response.Code, http.StatusUnauthorized)
+				}
+			}
+
+			if response := ts.request(http.MethodGet, "/v1/users/me", nil, otherSession.AccessToken); response.Code != http.StatusUnauthorized {
+				t.Errorf("old access token: status %d, want %d", response.Code, http.StatusUnauthorized)
+			}
+
+			user.Password = newPassword
+			ts.login(t, user, false)
+
+			if !ts.emails.notified(test.notification) {
+				t.Errorf("no %s notification was sent", test.notification)
+			}
+		})
+	}
+}
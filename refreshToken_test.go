@@ -0,0 +1,100 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/OVillas/autentication/domain"
+	"github.com/OVillas/autentication/repository"
+	"github.com/samber/do"
+)
+
+func TestRefreshRotatesTheToken(t *testing.T) {
+	ts := newTestServer(t)
+	session := ts.login(t, ts.register(t), false)
+
+	response := ts.refresh(session.RefreshToken)
+	if response.Code != http.StatusOK {
+		t.Fatalf("refresh: status %d: %s", response.Code, response.Body)
+	}
+
+	rotated := decode[domain.LoginResponse](t, response)
+	if rotated.RefreshToken == "" || rotated.RefreshToken == session.RefreshToken {
+		t.Fatal("refresh did not issue a new refresh token")
+	}
+
+	if response := ts.refresh(rotated.RefreshToken); response.Code != http.StatusOK {
+		t.Fatalf("refresh with the new token: status %d: %s", response.Code, response.Body)
+	}
+}
+
+func TestRefreshReuseRevokesAllSessions(t *testing.T) {
+	ts := newTestServer(t)
+	user := ts.register(t)
+	stolen := ts.login(t, user, false)
+	otherSession := ts.login(t, user, false)
+
+	response := ts.refresh(stolen.RefreshToken)
+	if response.Code != http.StatusOK {
+		t.Fatalf("refresh: status %d: %s", response.Code, response.Body)
+	}
+	rotated := decode[domain.LoginResponse](t, response)
+
+	response = ts.refresh(stolen.RefreshToken)
+	assertReused(t, response)
+
+	for name, refreshToken := range map[string]string{
+		"the token that replaced it": rotated.RefreshToken,
+		"another session":            otherSession.RefreshToken,
+	} {
+		if response := ts.refresh(refreshToken); response.Code != http.StatusUnauthorized {
+			t.Errorf("refresh with %s after the reuse: status %d, want %d", name, response.Code, http.StatusUnauthorized)
+		}
+	}
+}
+
+// lostRotation is a refresh token repository where another refresh always rotated the token
+// first, as when two requests present it at once.
+type lostRotation struct {
+	domain.RefreshTokenRepository
+}
+
+func (lostRotation) Rotate(id string, replacedBy string) (bool, error) {
+	return false, nil
+}
+
+func TestRefreshLosingAConcurrentRotationRevokesAllSessions(t *testing.T) {
+	ts := newTestServer(t, func(i *do.Injector) {
+		do.Override(i, func(i *do.Injector) (domain.RefreshTokenRepository, error) {
+			refreshTokenRepository, err := repository.NewRefreshTokenRepository(i)
+			return lostRotation{refreshTokenRepository}, err
+		})
+	})
+	user := ts.register(t)
+	session := ts.login(t, user, false)
+
+	assertReused(t, ts.refresh(session.RefreshToken))
+
+	sessions, err := do.MustInvoke[domain.RefreshTokenRepository](ts.i).GetActiveByUserID(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(sessions) != 0 {
+		t.Errorf("%d sessions still active after the concurrent rotation", len(sessions))
+	}
+}
+
+// assertReused checks that a refresh was refused as the reuse of a rotated token.
+func assertReused(t *testing.T, response *httptest.ResponseRecorder) {
+	t.Helper()
+
+	if response.Code != http.StatusUnauthorized {
+		t.Fatalf("refresh with a rotated token: status %d, want %d: %s", response.Code, http.StatusUnauthorized, response.Body)
+	}
+
+	if message := decode[domain.ErrorResponse](t, response).Message; message != domain.ErrRefreshTokenReused.Error() {
+		t.Errorf("refresh with a rotated token: message %q, want %q", message, domain.ErrRefreshTokenReused)
+	}
+}
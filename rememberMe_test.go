@@ -0,0 +1,102 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/OVillas/autentication/config"
+	"github.com/OVillas/autentication/domain"
+)
+
+var rememberMeTests = []struct {
+	name       string
+	rememberMe bool
+	ttl        func() time.Duration
+}{
+	{"without remember me", false, func() time.Duration { return config.RefreshTokenTTL }},
+	{"with remember me", true, func() time.Duration { return config.RememberMeTTL }},
+}
+
+func TestRememberMeSetsTheRefreshTokenExpiration(t *testing.T) {
+	for _, test := range rememberMeTests {
+		t.Run(test.name, func(t *testing.T) {
+			ts := newTestServer(t)
+			user := ts.register(t)
+
+			before := time.Now()
+			session := ts.login(t, user, test.rememberMe)
+			assertExpiresIn(t, "login", session.RefreshTokenExpiresAt, before, test.ttl())
+
+			before = time.Now()
+			response := ts.refresh(session.RefreshToken)
+			if response.Code != http.StatusOK {
+				t.Fatalf("refresh: status %d: %s", response.Code, response.Body)
+			}
+			session = decode[domain.LoginResponse](t, response)
+			assertExpiresIn(t, "refresh", session.RefreshTokenExpiresAt, before, test.ttl())
+
+			response = ts.request(http.MethodGet, "/v1/users/me/sessions", nil, session.AccessToken)
+			if response.Code != http.StatusOK {
+				t.Fatalf("sessions: status %d: %s", response.Code, response.Body)
+			}
+
+			sessions := decode[[]domain.SessionResponse](t, response)
+			if len(sessions) != 1 || sessions[0].Persistent != test.rememberMe {
+				t.Errorf("sessions = %+v, want one with persistent %v", sessions, test.rememberMe)
+			}
+		})
+	}
+}
+
+func TestRememberMeSetsTheSessionCookieExpiration(t *testing.T) {
+	sessionCookie := config.SessionCookie
+	t.Cleanup(func() { config.SessionCookie = sessionCookie })
+	config.SessionCookie.Enabled = true
+
+	for _, test := range rememberMeTests {
+		t.Run(test.name, func(t *testing.T) {
+			ts := newTestServer(t)
+			user := ts.register(t)
+
+			before := time.Now()
+			response := ts.request(http.MethodPost, "/v1/auth/login", domain.Login{
+				Identifier: user.Username,
+				Password:   user.Password,
+				RememberMe: test.rememberMe,
+			}, "")
+			if response.Code != http.StatusOK {
+				t.Fatalf("login: status %d: %s", response.Code, response.Body)
+			}
+
+			var refreshCookie *http.Cookie
+			for _, cookie := range response.Result().Cookies() {
+				if cookie.Name == config.SessionCookie.Name {
+					refreshCookie = cookie
+				}
+			}
+
+			if refreshCookie == nil {
+				t.Fatal("the login set no refresh token cookie")
+			}
+
+			if !test.rememberMe {
+				if !refreshCookie.Expires.IsZero() || refreshCookie.MaxAge != 0 {
+					t.Errorf("the refresh token cookie expires at %v, want a browser session cookie", refreshCookie.Expires)
+				}
+				return
+			}
+
+			assertExpiresIn(t, "cookie", refreshCookie.Expires, before.Truncate(time.Second), test.ttl())
+		})
+	}
+}
+
+// assertExpiresIn checks that expiresAt is ttl after a moment between before and now.
+func assertExpiresIn(t *testing.T, name string, expiresAt time.Time, before time.Time, ttl time.Duration) {
+	t.Helper()
+
+	if expiresAt.Before(before.Add(ttl)) || expiresAt.After(time.Now().Add(ttl)) {
+		t.Errorf("%s: the refresh token expires at %v, want %v after %v", name, expiresAt, ttl, before)
+	}
+}
@@ -0,0 +1,157 @@
+package repository
+
+import (
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/OVillas/autentication/domain"
+	"github.com/samber/do"
+	"gorm.io/gorm"
+)
+
+type apiKeyRepository struct {
+	i  *do.Injector
+	db *gorm.DB
+}
+
+func NewApiKeyRepository(i *do.Injector) (domain.ApiKeyRepository, error) {
+	db := do.MustInvoke[*gorm.DB](i)
+	return &apiKeyRepository{
+		db: db,
+		i:  i,
+	}, nil
+}
+
+func (akr *apiKeyRepository) Create(apiKey domain.ApiKey) error {
+	log := slog.With(
+		slog.String("func", "Create"),
+		slog.String("repository", "apiKey"))
+
+	log.Info("Create initiated")
+
+	apiKey.CreatedAt = time.Now()
+
+	if err := akr.db.Create(&apiKey).Error; err != nil {
+		log.Error("Error to create api key in database", slog.Any("error", err))
+		return err
+	}
+
+	log.Info("Create executed successfully")
+	return nil
+}
+
+func (akr *apiKeyRepository) GetAll() ([]domain.ApiKey, error) {
+	log := slog.With(
+		slog.String("func", "GetAll"),
+		slog.String("repository", "apiKey"))
+
+	log.Info("GetAll initiated")
+
+	var apiKeys []domain.ApiKey
+	if err := akr.db.Order(`"CreatedAt" DESC`).Find(&apiKeys).Error; err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return nil, err
+	}
+
+	log.Info("GetAll executed successfully")
+	return apiKeys, nil
+}
+
+func (akr *apiKeyRepository) GetById(id string) (*domain.ApiKey, error) {
+	log := slog.With(
+		slog.String("func", "GetById"),
+		slog.String("repository", "apiKey"))
+
+	log.Info("GetById initiated")
+
+	var apiKey domain.ApiKey
+	err := akr.db.Where(`"Id" = ?`, id).First(&apiKey).Error
+
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		log.Error("Error: ", slog.Any("error", err))
+		return nil, err
+	}
+
+	log.Info("GetById executed successfully")
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+
+	return &apiKey, nil
+}
+
+func (akr *apiKeyRepository) GetBySecretHash(secretHash string) (*domain.ApiKey, error) {
+	log := slog.With(
+		slog.String("func", "GetBySecretHash"),
+		slog.String("repository", "apiKey"))
+
+	log.Info("GetBySecretHash initiated")
+
+	var apiKey domain.ApiKey
+	err := akr.db.Where(`"SecretHash" = ?`, secretHash).First(&apiKey).Error
+
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		log.Error("Error: ", slog.Any("error", err))
+		return nil, err
+	}
+
+	log.Info("GetBySecretHash executed successfully")
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+
+	return &apiKey, nil
+}
+
+func (akr *apiKeyRepository) UpdateSecretHash(id string, secretHash string) (bool, error) {
+	log := slog.With(
+		slog.String("func", "UpdateSecretHash"),
+		slog.String("repository", "apiKey"))
+
+	log.Info("UpdateSecretHash initiated")
+
+	result := akr.db.Model(&domain.ApiKey{}).
+		Where(`"Id" = ? AND "RevokedAt" IS NULL`, id).
+		Update("SecretHash", secretHash)
+	if result.Error != nil {
+		log.Error("Error: ", slog.Any("error", result.Error))
+		return false, result.Error
+	}
+
+	log.Info("UpdateSecretHash executed successfully")
+	return result.RowsAffected == 1, nil
+}
+
+func (akr *apiKeyRepository) Revoke(id string) (bool, error) {
+	log := slog.With(
+		slog.String("func", "Revoke"),
+		slog.String("repository", "apiKey"))
+
+	log.Info("Revoke initiated")
+
+	result := akr.db.Model(&domain.ApiKey{}).
+		Where(`"Id" = ? AND "RevokedAt" IS NULL`, id).
+		Update("RevokedAt", time.Now())
+	if result.Error != nil {
+		log.Error("Error: ", slog.Any("error", result.Error))
+		return false, result.Error
+	}
+
+	log.Info("Revoke executed successfully")
+	return result.RowsAffected == 1, nil
+}
+
+func (akr *apiKeyRepository) UpdateLastUsedAt(id string) error {
+	log := slog.With(
+		slog.String("func", "UpdateLastUsedAt"),
+		slog.String("repository", "apiKey"))
+
+	err := akr.db.Model(&domain.ApiKey{}).Where(`"Id" = ?`, id).Update("LastUsedAt", time.Now()).Error
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return err
+	}
+
+	return nil
+}
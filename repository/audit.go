@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"log/slog"
+
+	"github.com/OVillas/autentication/domain"
+	"github.com/samber/do"
+	"gorm.io/gorm"
+)
+
+type auditRepository struct {
+	i  *do.Injector
+	db *gorm.DB
+}
+
+func NewAuditRepository(i *do.Injector) (domain.AuditRepository, error) {
+	db := do.MustInvoke[*gorm.DB](i)
+	return &auditRepository{
+		db: db,
+		i:  i,
+	}, nil
+}
+
+func (ar *auditRepository) CreateBatch(entries []domain.AuditEntry) error {
+	log := slog.With(
+		slog.String("func", "CreateBatch"),
+		slog.String("repository", "audit"))
+
+	log.Info("CreateBatch initiated")
+
+	if err := ar.db.Create(&entries).Error; err != nil {
+		log.Error("Error to create audit entries in database", slog.Any("error", err))
+		return err
+	}
+
+	log.Info("CreateBatch executed successfully")
+	return nil
+}
+
+// GetAll reads one page of the entries matched by query, newest first, and one entry more when
+// another page follows.
+func (ar *auditRepository) GetAll(query domain.AuditQuery) ([]domain.AuditEntry, int64, error) {
+	log := slog.With(
+		slog.String("func", "GetAll"),
+		slog.String("repository", "audit"))
+
+	log.Info("GetAll initiated")
+
+	db := ar.db.Model(&domain.AuditEntry{})
+	if query.ActorID != "" {
+		db = db.Where(`"ActorId" = ?`, query.ActorID)
+	}
+
+	if query.TargetID != "" {
+		db = db.Where(`"TargetId" = ?`, query.TargetID)
+	}
+
+	if query.Event != "" {
+		db = db.Where(`"Event" = ?`, query.Event)
+	}
+
+	if query.From != nil {
+		db = db.Where(`"CreatedAt" >= ?`, *query.From)
+	}
+
+	if query.To != nil {
+		db = db.Where(`"CreatedAt" <= ?`, *query.To)
+	}
+
+	var total int64
+	if err := db.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return nil, 0, err
+	}
+
+	pageRequest := query.PageRequest
+	page := db.Order(`"CreatedAt" DESC, "Id" DESC`).Limit(pageRequest.PerPage + 1)
+	if pageRequest.After != nil {
+		page = page.Where(`("CreatedAt", "Id") < (?, ?)`, pageRequest.After.CreatedAt, pageRequest.After.ID)
+	} else {
+		page = page.Offset(pageRequest.Offset())
+	}
+
+	var entries []domain.AuditEntry
+	if err := page.Find(&entries).Error; err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return nil, 0, err
+	}
+
+	log.Info("GetAll executed successfully")
+	return entries, total, nil
+}
+
+// GetByUserID reads every entry the user performed or is the target of, oldest first.
+func (ar *auditRepository) GetByUserID(userID string) ([]domain.AuditEntry, error) {
+	log := slog.With(
+		slog.String("func", "GetByUserID"),
+		slog.String("repository", "audit"))
+
+	log.Info("GetByUserID initiated")
+
+	var entries []domain.AuditEntry
+	err := ar.db.Where(`"ActorId" = ? OR "TargetId" = ?`, userID, userID).Order(`"CreatedAt" ASC, "Id" ASC`).Find(&entries).Error
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return nil, err
+	}
+
+	log.Info("GetByUserID executed successfully")
+	return entries, nil
+}
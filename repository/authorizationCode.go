@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/OVillas/autentication/domain"
+	"github.com/samber/do"
+	"gorm.io/gorm"
+)
+
+type authorizationCodeRepository struct {
+	i  *do.Injector
+	db *gorm.DB
+}
+
+func NewAuthorizationCodeRepository(i *do.Injector) (domain.AuthorizationCodeRepository, error) {
+	db := do.MustInvoke[*gorm.DB](i)
+	return &authorizationCodeRepository{
+		db: db,
+		i:  i,
+	}, nil
+}
+
+func (acr *authorizationCodeRepository) Create(authorizationCode domain.AuthorizationCode) error {
+	log := slog.With(
+		slog.String("func", "Create"),
+		slog.String("repository", "authorizationCode"))
+
+	log.Info("Create initiated")
+
+	authorizationCode.CreatedAt = time.Now()
+
+	if err := acr.db.Create(&authorizationCode).Error; err != nil {
+		log.Error("Error to create authorization code in database", slog.Any("error", err))
+		return err
+	}
+
+	log.Info("Create executed successfully")
+	return nil
+}
+
+func (acr *authorizationCodeRepository) GetByCodeHash(codeHash string) (*domain.AuthorizationCode, error) {
+	log := slog.With(
+		slog.String("func", "GetByCodeHash"),
+		slog.String("repository", "authorizationCode"))
+
+	log.Info("GetByCodeHash initiated")
+
+	var authorizationCode domain.AuthorizationCode
+	err := acr.db.Where(`"CodeHash" = ?`, codeHash).First(&authorizationCode).Error
+
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		log.Error("Error: ", slog.Any("error", err))
+		return nil, err
+	}
+
+	log.Info("GetByCodeHash executed successfully")
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+
+	return &authorizationCode, nil
+}
+
+// Use marks the code as exchanged. It reports false when the code was already used or has
+// expired, so two concurrent exchanges cannot both succeed.
+func (acr *authorizationCodeRepository) Use(codeHash string) (bool, error) {
+	log := slog.With(
+		slog.String("func", "Use"),
+		slog.String("repository", "authorizationCode"))
+
+	log.Info("Use initiated")
+
+	now := time.Now()
+	result := acr.db.Model(&domain.AuthorizationCode{}).
+		Where(`"CodeHash" = ? AND "UsedAt" IS NULL AND "ExpiresAt" > ?`, codeHash, now).
+		Update("UsedAt", now)
+	if result.Error != nil {
+		log.Error("Error: ", slog.Any("error", result.Error))
+		return false, result.Error
+	}
+
+	log.Info("Use executed successfully")
+	return result.RowsAffected == 1, nil
+}
@@ -0,0 +1,204 @@
+package repository
+
+import (
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/OVillas/autentication/domain"
+	"github.com/samber/do"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// confirmationCodePurgeInterval is how often expired codes are removed from the table.
+const confirmationCodePurgeInterval = 10 * time.Minute
+
+type confirmationCodeRepository struct {
+	i  *do.Injector
+	db *gorm.DB
+}
+
+func NewConfirmationCodeRepository(i *do.Injector) (domain.ConfirmationCodeRepository, error) {
+	db := do.MustInvoke[*gorm.DB](i)
+	ccr := &confirmationCodeRepository{
+		db: db,
+		i:  i,
+	}
+
+	go ccr.purgeExpired()
+
+	return ccr, nil
+}
+
+// Set replaces any previous code of the email and resets its attempts.
+func (ccr *confirmationCodeRepository) Set(tenantID string, email string, codeHash string, ttl time.Duration) error {
+	log := slog.With(
+		slog.String("func", "Set"),
+		slog.String("repository", "confirmationCode"))
+
+	log.Info("Set initiated")
+
+	now := time.Now()
+	confirmationCode := domain.ConfirmationCode{
+		TenantID:   tenantID,
+		Email:      email,
+		CodeHash:   codeHash,
+		Attempts:   0,
+		ExpiryTime: now.Add(ttl),
+		CreatedAt:  now,
+	}
+
+	err := ccr.db.Clauses(clause.OnConflict{UpdateAll: true}).Create(&confirmationCode).Error
+	if err != nil {
+		log.Error("Error to save confirmation code in database", slog.Any("error", err))
+		return err
+	}
+
+	log.Info("Set executed successfully")
+	return nil
+}
+
+func (ccr *confirmationCodeRepository) Get(tenantID string, email string) (*domain.ConfirmationCode, error) {
+	log := slog.With(
+		slog.String("func", "Get"),
+		slog.String("repository", "confirmationCode"))
+
+	log.Info("Get initiated")
+
+	var confirmationCode domain.ConfirmationCode
+	err := ccr.db.Where(`"TenantId" = ? AND "Email" = ? AND "ExpiryTime" > ?`, tenantID, email, time.Now()).First(&confirmationCode).Error
+
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		log.Error("Error: ", slog.Any("error", err))
+		return nil, err
+	}
+
+	log.Info("Get executed successfully")
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+
+	return &confirmationCode, nil
+}
+
+func (ccr *confirmationCodeRepository) Delete(tenantID string, email string) error {
+	log := slog.With(
+		slog.String("func", "Delete"),
+		slog.String("repository", "confirmationCode"))
+
+	log.Info("Delete initiated")
+
+	if err := ccr.db.Where(`"TenantId" = ? AND "Email" = ?`, tenantID, email).Delete(&domain.ConfirmationCode{}).Error; err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return err
+	}
+
+	log.Info("Delete executed successfully")
+	return nil
+}
+
+func (ccr *confirmationCodeRepository) IncrementAttempts(tenantID string, email string) (int, error) {
+	log := slog.With(
+		slog.String("func", "IncrementAttempts"),
+		slog.String("repository", "confirmationCode"))
+
+	log.Info("IncrementAttempts initiated")
+
+	var attempts int
+	err := ccr.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&domain.ConfirmationCode{}).
+			Where(`"TenantId" = ? AND "Email" = ? AND "ExpiryTime" > ?`, tenantID, email, time.Now()).
+			Update("Attempts", gorm.Expr(`"Attempts" + 1`))
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+
+		return tx.Model(&domain.ConfirmationCode{}).Where(`"TenantId" = ? AND "Email" = ?`, tenantID, email).Pluck("Attempts", &attempts).Error
+	})
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return 0, err
+	}
+
+	log.Info("IncrementAttempts executed successfully")
+	return attempts, nil
+}
+
+func (ccr *confirmationCodeRepository) ReserveSend(tenantID string, email string, cooldown time.Duration, window time.Duration, limit int) (time.Duration, error) {
+	log := slog.With(
+		slog.String("func", "ReserveSend"),
+		slog.String("repository", "confirmationCode"))
+
+	log.Info("ReserveSend initiated")
+
+	var wait time.Duration
+	err := ccr.db.Transaction(func(tx *gorm.DB) error {
+		// A first send finds an empty row whose window is long over.
+		epoch := time.Unix(0, 0)
+		err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&domain.ConfirmationCodeSend{TenantID: tenantID, Email: email, LastSentAt: epoch, WindowStart: epoch}).Error
+		if err != nil {
+			return err
+		}
+
+		var confirmationCodeSend domain.ConfirmationCodeSend
+		err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where(`"TenantId" = ? AND "Email" = ?`, tenantID, email).First(&confirmationCodeSend).Error
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		if now.Sub(confirmationCodeSend.WindowStart) >= window {
+			confirmationCodeSend.WindowStart = now
+			confirmationCodeSend.SendCount = 0
+		}
+
+		if since := now.Sub(confirmationCodeSend.LastSentAt); since < cooldown {
+			wait = cooldown - since
+			return nil
+		}
+
+		if confirmationCodeSend.SendCount >= limit {
+			wait = confirmationCodeSend.WindowStart.Add(window).Sub(now)
+			return nil
+		}
+
+		confirmationCodeSend.LastSentAt = now
+		confirmationCodeSend.SendCount++
+		return tx.Save(&confirmationCodeSend).Error
+	})
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return 0, err
+	}
+
+	log.Info("ReserveSend executed successfully")
+	return wait, nil
+}
+
+func (ccr *confirmationCodeRepository) purgeExpired() {
+	log := slog.With(
+		slog.String("func", "purgeExpired"),
+		slog.String("repository", "confirmationCode"))
+
+	ticker := time.NewTicker(confirmationCodePurgeInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		result := ccr.db.Where(`"ExpiryTime" <= ?`, time.Now()).Delete(&domain.ConfirmationCode{})
+		if result.Error != nil {
+			log.Error("Error: ", slog.Any("error", result.Error))
+			continue
+		}
+
+		if result.RowsAffected > 0 {
+			log.Info("Expired confirmation codes removed", slog.Int64("count", result.RowsAffected))
+		}
+
+		err := ccr.db.Where(`"WindowStart" <= ?`, time.Now().Add(-domain.CodeSendWindow)).Delete(&domain.ConfirmationCodeSend{}).Error
+		if err != nil {
+			log.Error("Error: ", slog.Any("error", err))
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/OVillas/autentication/domain"
+	"github.com/redis/go-redis/v9"
+	"github.com/samber/do"
+)
+
+const (
+	confirmationCodeKeyPrefix     = "confirmation_code:"
+	confirmationCodeSendKeyPrefix = "confirmation_code_send:"
+)
+
+// incrementAttemptsScript only counts attempts against a code that still exists, so a late
+// attempt cannot recreate the key without expiry.
+var incrementAttemptsScript = redis.NewScript(`
+if redis.call("EXISTS", KEYS[1]) == 0 then
+	return 0
+end
+return redis.call("HINCRBY", KEYS[1], "attempts", 1)
+`)
+
+// reserveSendScript applies the cooldown and the cap of a window in one step. Times are in
+// milliseconds and the key expires with its window.
+var reserveSendScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local cooldown = tonumber(ARGV[2])
+local window = tonumber(ARGV[3])
+local limit = tonumber(ARGV[4])
+local last = tonumber(redis.call("HGET", KEYS[1], "last_sent_at") or "0")
+local start = tonumber(redis.call("HGET", KEYS[1], "window_start") or "0")
+local count = tonumber(redis.call("HGET", KEYS[1], "count") or "0")
+if now - start >= window then
+	start = now
+	count = 0
+end
+if now - last < cooldown then
+	return cooldown - (now - last)
+end
+if count >= limit then
+	return start + window - now
+end
+redis.call("HSET", KEYS[1], "last_sent_at", now, "window_start", start, "count", count + 1)
+redis.call("PEXPIRE", KEYS[1], start + window - now)
+return 0
+`)
+
+// redisConfirmationCodeRepository keeps each code in a hash whose key expires with the code, so
+// Redis removes expired codes on its own.
+type redisConfirmationCodeRepository struct {
+	i      *do.Injector
+	client *redis.Client
+}
+
+func NewRedisConfirmationCodeRepository(i *do.Injector) (domain.ConfirmationCodeRepository, error) {
+	client := do.MustInvoke[*redis.Client](i)
+	return &redisConfirmationCodeRepository{
+		client: client,
+		i:      i,
+	}, nil
+}
+
+func (rccr *redisConfirmationCodeRepository) Set(tenantID string, email string, codeHash string, ttl time.Duration) error {
+	log := slog.With(
+		slog.String("func", "Set"),
+		slog.String("repository", "redisConfirmationCode"))
+
+	log.Info("Set initiated")
+
+	ctx := context.Background()
+	key := codeKey(confirmationCodeKeyPrefix, tenantID, email)
+	now := time.Now()
+
+	_, err := rccr.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, key)
+		pipe.HSet(ctx, key, "code_hash", codeHash, "attempts", 0, "created_at", now.Unix())
+		pipe.Expire(ctx, key, ttl)
+		return nil
+	})
+	if err != nil {
+		log.Error("Error to save confirmation code in redis", slog.Any("error", err))
+		return err
+	}
+
+	log.Info("Set executed successfully")
+	return nil
+}
+
+func (rccr *redisConfirmationCodeRepository) Get(tenantID string, email string) (*domain.ConfirmationCode, error) {
+	log := slog.With(
+		slog.String("func", "Get"),
+		slog.String("repository", "redisConfirmationCode"))
+
+	log.Info("Get initiated")
+
+	ctx := context.Background()
+	key := codeKey(confirmationCodeKeyPrefix, tenantID, email)
+
+	var fields *redis.MapStringStringCmd
+	var ttl *redis.DurationCmd
+	_, err := rccr.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		fields = pipe.HGetAll(ctx, key)
+		ttl = pipe.PTTL(ctx, key)
+		return nil
+	})
+	if err != nil && !errors.Is(err, redis.Nil) {
+		log.Error("Error: ", slog.Any("error", err))
+		return nil, err
+	}
+
+	values := fields.Val()
+	if len(values) == 0 || ttl.Val() <= 0 {
+		log.Info("Get executed successfully")
+		return nil, nil
+	}
+
+	attempts, _ := strconv.Atoi(values["attempts"])
+	createdAt, _ := strconv.ParseInt(values["created_at"], 10, 64)
+
+	log.Info("Get executed successfully")
+	return &domain.ConfirmationCode{
+		TenantID:   tenantID,
+		Email:      email,
+		CodeHash:   values["code_hash"],
+		Attempts:   attempts,
+		ExpiryTime: time.Now().Add(ttl.Val()),
+		CreatedAt:  time.Unix(createdAt, 0),
+	}, nil
+}
+
+func (rccr *redisConfirmationCodeRepository) Delete(tenantID string, email string) error {
+	log := slog.With(
+		slog.String("func", "Delete"),
+		slog.String("repository", "redisConfirmationCode"))
+
+	log.Info("Delete initiated")
+
+	if err := rccr.client.Del(context.Background(), codeKey(confirmationCodeKeyPrefix, tenantID, email)).Err(); err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return err
+	}
+
+	log.Info("Delete executed successfully")
+	return nil
+}
+
+func (rccr *redisConfirmationCodeRepository) IncrementAttempts(tenantID string, email string) (int, error) {
+	log := slog.With(
+		slog.String("func", "IncrementAttempts"),
+		slog.String("repository", "redisConfirmationCode"))
+
+	log.Info("IncrementAttempts initiated")
+
+	attempts, err := incrementAttemptsScript.Run(context.Background(), rccr.client, []string{codeKey(confirmationCodeKeyPrefix, tenantID, email)}).Int()
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return 0, err
+	}
+
+	log.Info("IncrementAttempts executed successfully")
+	return attempts, nil
+}
+
+func (rccr *redisConfirmationCodeRepository) ReserveSend(tenantID string, email string, cooldown time.Duration, window time.Duration, limit int) (time.Duration, error) {
+	log := slog.With(
+		slog.String("func", "ReserveSend"),
+		slog.String("repository", "redisConfirmationCode"))
+
+	log.Info("ReserveSend initiated")
+
+	wait, err := reserveSendScript.Run(context.Background(), rccr.client, []string{codeKey(confirmationCodeSendKeyPrefix, tenantID, email)},
+		time.Now().UnixMilli(), cooldown.Milliseconds(), window.Milliseconds(), limit).Int64()
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return 0, err
+	}
+
+	log.Info("ReserveSend executed successfully")
+	return time.Duration(wait) * time.Millisecond, nil
+}
+
+// codeKey keeps the codes of the same address in different tenants apart.
+func codeKey(prefix string, tenantID string, email string) string {
+	return prefix + tenantID + ":" + email
+}
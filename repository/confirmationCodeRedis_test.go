@@ -0,0 +1,60 @@
+//go:build redis
+
+package repository
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/OVillas/autentication/config"
+	"github.com/OVillas/autentication/database"
+	"github.com/OVillas/autentication/secure"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/samber/do"
+)
+
+func TestRedisConfirmationCodeIsStoredHashed(t *testing.T) {
+	addr := os.Getenv("TEST_REDIS_ADDR")
+	if addr == "" {
+		t.Fatal("the redis tests need TEST_REDIS_ADDR, the address of a Redis they may write to")
+	}
+
+	redisConfig := config.Redis
+	t.Cleanup(func() { config.Redis = redisConfig })
+	config.Redis = config.RedisConfig{Addr: addr}
+
+	client, err := database.NewRedisConnection()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = client.Close() })
+
+	i := newTestInjector()
+	do.Provide(i, func(i *do.Injector) (*redis.Client, error) {
+		return client, nil
+	})
+
+	confirmationCodeRepository, _ := NewRedisConfirmationCodeRepository(i)
+	tenantID := uuid.NewString()
+	email := "user" + tenantID[:8] + "@example.com"
+
+	if err := confirmationCodeRepository.Set(tenantID, email, secure.HashOTP(email, testCode), time.Hour); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	t.Cleanup(func() { _ = confirmationCodeRepository.Delete(tenantID, email) })
+
+	stored, err := client.HGet(context.Background(), codeKey(confirmationCodeKeyPrefix, tenantID, email), "code_hash").Result()
+	if err != nil {
+		t.Fatalf("HGet: %v", err)
+	}
+	assertHashedCode(t, stored)
+
+	confirmationCode, err := confirmationCodeRepository.Get(tenantID, email)
+	if err != nil || confirmationCode == nil {
+		t.Fatalf("Get: %v, %v", confirmationCode, err)
+	}
+	assertCheckOTP(t, confirmationCode.CodeHash, email)
+}
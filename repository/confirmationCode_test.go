@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/OVillas/autentication/domain"
+	"github.com/OVillas/autentication/secure"
+	"github.com/google/uuid"
+)
+
+const (
+	testCode      = "483920"
+	testWrongCode = "483921"
+)
+
+func TestConfirmationCodeIsStoredHashed(t *testing.T) {
+	confirmationCodeRepository, _ := NewConfirmationCodeRepository(newTestInjector())
+	tenantID := uuid.NewString()
+	email := "user" + tenantID[:8] + "@example.com"
+
+	if err := confirmationCodeRepository.Set(tenantID, email, secure.HashOTP(email, testCode), time.Hour); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+
+	var stored []string
+	err := testDB.Model(&domain.ConfirmationCode{}).Where(`"TenantId" = ? AND "Email" = ?`, tenantID, email).
+		Pluck("CodeHash", &stored).Error
+	if err != nil || len(stored) != 1 {
+		t.Fatalf("got the stored codes %v, %v, want one", stored, err)
+	}
+	assertHashedCode(t, stored[0])
+
+	confirmationCode, err := confirmationCodeRepository.Get(tenantID, email)
+	if err != nil || confirmationCode == nil {
+		t.Fatalf("Get: %v, %v", confirmationCode, err)
+	}
+	assertCheckOTP(t, confirmationCode.CodeHash, email)
+}
+
+// assertHashedCode checks that the stored value of testCode does not hold the code itself.
+func assertHashedCode(t *testing.T, stored string) {
+	t.Helper()
+
+	if stored == "" || stored == testCode || strings.Contains(stored, testCode) {
+		t.Errorf("stored %q for the code %s, want a hash", stored, testCode)
+	}
+}
+
+// assertCheckOTP checks that codeHash accepts testCode, and only it, for email.
+func assertCheckOTP(t *testing.T, codeHash string, email string) {
+	t.Helper()
+
+	if !secure.CheckOTP(codeHash, email, testCode) {
+		t.Error("CheckOTP refused the code")
+	}
+	if secure.CheckOTP(codeHash, email, testWrongCode) {
+		t.Error("CheckOTP accepted a wrong code")
+	}
+}
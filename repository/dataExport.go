@@ -0,0 +1,159 @@
+package repository
+
+import (
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/OVillas/autentication/domain"
+	"github.com/samber/do"
+	"gorm.io/gorm"
+)
+
+type dataExportRepository struct {
+	i  *do.Injector
+	db *gorm.DB
+}
+
+func NewDataExportRepository(i *do.Injector) (domain.DataExportRepository, error) {
+	db := do.MustInvoke[*gorm.DB](i)
+	return &dataExportRepository{
+		db: db,
+		i:  i,
+	}, nil
+}
+
+func (der *dataExportRepository) Create(dataExport domain.DataExport) error {
+	log := slog.With(
+		slog.String("func", "Create"),
+		slog.String("repository", "dataExport"))
+
+	log.Info("Create initiated")
+
+	if err := der.db.Create(&dataExport).Error; err != nil {
+		log.Error("Error to create data export in database", slog.Any("error", err))
+		return err
+	}
+
+	log.Info("Create executed successfully")
+	return nil
+}
+
+func (der *dataExportRepository) GetByID(id string) (*domain.DataExport, error) {
+	log := slog.With(
+		slog.String("func", "GetByID"),
+		slog.String("repository", "dataExport"))
+
+	log.Info("GetByID initiated")
+
+	var dataExport domain.DataExport
+	err := der.db.Where(`"Id" = ?`, id).First(&dataExport).Error
+
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		log.Error("Error: ", slog.Any("error", err))
+		return nil, err
+	}
+
+	log.Info("GetByID executed successfully")
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+
+	return &dataExport, nil
+}
+
+func (der *dataExportRepository) GetPendingByUserID(userID string) (*domain.DataExport, error) {
+	log := slog.With(
+		slog.String("func", "GetPendingByUserID"),
+		slog.String("repository", "dataExport"))
+
+	log.Info("GetPendingByUserID initiated")
+
+	var dataExport domain.DataExport
+	err := der.db.Where(`"UserId" = ? AND "Status" = ?`, userID, domain.DataExportPending).First(&dataExport).Error
+
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		log.Error("Error: ", slog.Any("error", err))
+		return nil, err
+	}
+
+	log.Info("GetPendingByUserID executed successfully")
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+
+	return &dataExport, nil
+}
+
+func (der *dataExportRepository) GetPending() ([]domain.DataExport, error) {
+	log := slog.With(
+		slog.String("func", "GetPending"),
+		slog.String("repository", "dataExport"))
+
+	log.Info("GetPending initiated")
+
+	var dataExports []domain.DataExport
+	err := der.db.Where(`"Status" = ?`, domain.DataExportPending).Order(`"CreatedAt" ASC`).Find(&dataExports).Error
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return nil, err
+	}
+
+	log.Info("GetPending executed successfully")
+	return dataExports, nil
+}
+
+func (der *dataExportRepository) Complete(id string, content []byte) error {
+	log := slog.With(
+		slog.String("func", "Complete"),
+		slog.String("repository", "dataExport"))
+
+	log.Info("Complete initiated")
+
+	err := der.db.Model(&domain.DataExport{}).Where(`"Id" = ?`, id).Updates(map[string]any{
+		"Status":      domain.DataExportReady,
+		"Content":     content,
+		"CompletedAt": time.Now(),
+	}).Error
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return err
+	}
+
+	log.Info("Complete executed successfully")
+	return nil
+}
+
+func (der *dataExportRepository) Fail(id string) error {
+	log := slog.With(
+		slog.String("func", "Fail"),
+		slog.String("repository", "dataExport"))
+
+	log.Info("Fail initiated")
+
+	err := der.db.Model(&domain.DataExport{}).Where(`"Id" = ?`, id).Update("Status", domain.DataExportFailed).Error
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return err
+	}
+
+	log.Info("Fail executed successfully")
+	return nil
+}
+
+func (der *dataExportRepository) DeleteExpired() (int64, error) {
+	log := slog.With(
+		slog.String("func", "DeleteExpired"),
+		slog.String("repository", "dataExport"))
+
+	log.Info("DeleteExpired initiated")
+
+	result := der.db.Where(`"ExpiresAt" <= ?`, time.Now()).Delete(&domain.DataExport{})
+	if result.Error != nil {
+		log.Error("Error: ", slog.Any("error", result.Error))
+		return 0, result.Error
+	}
+
+	log.Info("DeleteExpired executed successfully")
+	return result.RowsAffected, nil
+}
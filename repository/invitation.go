@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/OVillas/autentication/domain"
+	"github.com/samber/do"
+	"gorm.io/gorm"
+)
+
+type invitationRepository struct {
+	i  *do.Injector
+	db *gorm.DB
+}
+
+func NewInvitationRepository(i *do.Injector) (domain.InvitationRepository, error) {
+	db := do.MustInvoke[*gorm.DB](i)
+	return &invitationRepository{
+		db: db,
+		i:  i,
+	}, nil
+}
+
+func (ir *invitationRepository) Create(invitation domain.Invitation) error {
+	log := slog.With(
+		slog.String("func", "Create"),
+		slog.String("repository", "invitation"))
+
+	log.Info("Create initiated")
+
+	invitation.CreatedAt = time.Now()
+
+	if err := ir.db.Create(&invitation).Error; err != nil {
+		log.Error("Error to create invitation in database", slog.Any("error", err))
+		return err
+	}
+
+	log.Info("Create executed successfully")
+	return nil
+}
+
+func (ir *invitationRepository) GetByTokenHash(tokenHash string) (*domain.Invitation, error) {
+	log := slog.With(
+		slog.String("func", "GetByTokenHash"),
+		slog.String("repository", "invitation"))
+
+	log.Info("GetByTokenHash initiated")
+
+	var invitation domain.Invitation
+	err := ir.db.Where(`"TokenHash" = ?`, tokenHash).First(&invitation).Error
+
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		log.Error("Error: ", slog.Any("error", err))
+		return nil, err
+	}
+
+	log.Info("GetByTokenHash executed successfully")
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+
+	return &invitation, nil
+}
+
+func (ir *invitationRepository) Accept(id string) (bool, error) {
+	log := slog.With(
+		slog.String("func", "Accept"),
+		slog.String("repository", "invitation"))
+
+	log.Info("Accept initiated")
+
+	now := time.Now()
+	result := ir.db.Model(&domain.Invitation{}).
+		Where(`"Id" = ? AND "AcceptedAt" IS NULL AND "ExpiresAt" > ?`, id, now).
+		Update("AcceptedAt", now)
+	if result.Error != nil {
+		log.Error("Error: ", slog.Any("error", result.Error))
+		return false, result.Error
+	}
+
+	log.Info("Accept executed successfully")
+	return result.RowsAffected == 1, nil
+}
@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/OVillas/autentication/domain"
+	"github.com/samber/do"
+	"gorm.io/gorm"
+)
+
+type knownDeviceRepository struct {
+	i  *do.Injector
+	db *gorm.DB
+}
+
+func NewKnownDeviceRepository(i *do.Injector) (domain.KnownDeviceRepository, error) {
+	db := do.MustInvoke[*gorm.DB](i)
+	return &knownDeviceRepository{
+		db: db,
+		i:  i,
+	}, nil
+}
+
+func (kdr *knownDeviceRepository) GetByFingerprint(userID string, fingerprint string) (*domain.KnownDevice, error) {
+	log := slog.With(
+		slog.String("func", "GetByFingerprint"),
+		slog.String("repository", "knownDevice"))
+
+	log.Info("GetByFingerprint initiated")
+
+	var knownDevice domain.KnownDevice
+	err := kdr.db.Where(`"UserId" = ? AND "Fingerprint" = ?`, userID, fingerprint).First(&knownDevice).Error
+
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		log.Error("Error: ", slog.Any("error", err))
+		return nil, err
+	}
+
+	log.Info("GetByFingerprint executed successfully")
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+
+	return &knownDevice, nil
+}
+
+func (kdr *knownDeviceRepository) CountByUserID(userID string) (int64, error) {
+	log := slog.With(
+		slog.String("func", "CountByUserID"),
+		slog.String("repository", "knownDevice"))
+
+	log.Info("CountByUserID initiated")
+
+	var count int64
+	if err := kdr.db.Model(&domain.KnownDevice{}).Where(`"UserId" = ?`, userID).Count(&count).Error; err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return 0, err
+	}
+
+	log.Info("CountByUserID executed successfully")
+	return count, nil
+}
+
+func (kdr *knownDeviceRepository) Create(knownDevice domain.KnownDevice) error {
+	log := slog.With(
+		slog.String("func", "Create"),
+		slog.String("repository", "knownDevice"))
+
+	log.Info("Create initiated")
+
+	if err := kdr.db.Create(&knownDevice).Error; err != nil {
+		log.Error("Error to create known device in database", slog.Any("error", err))
+		return err
+	}
+
+	log.Info("Create executed successfully")
+	return nil
+}
+
+func (kdr *knownDeviceRepository) UpdateLastSeenAt(id string) error {
+	log := slog.With(
+		slog.String("func", "UpdateLastSeenAt"),
+		slog.String("repository", "knownDevice"))
+
+	log.Info("UpdateLastSeenAt initiated")
+
+	err := kdr.db.Model(&domain.KnownDevice{}).Where(`"Id" = ?`, id).Update("LastSeenAt", time.Now()).Error
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return err
+	}
+
+	log.Info("UpdateLastSeenAt executed successfully")
+	return nil
+}
+
+func (kdr *knownDeviceRepository) DeleteSeenBefore(before time.Time) (int64, error) {
+	log := slog.With(
+		slog.String("func", "DeleteSeenBefore"),
+		slog.String("repository", "knownDevice"))
+
+	log.Info("DeleteSeenBefore initiated")
+
+	result := kdr.db.Where(`"LastSeenAt" < ?`, before).Delete(&domain.KnownDevice{})
+	if result.Error != nil {
+		log.Error("Error: ", slog.Any("error", result.Error))
+		return 0, result.Error
+	}
+
+	log.Info("DeleteSeenBefore executed successfully")
+	return result.RowsAffected, nil
+}
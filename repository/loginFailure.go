@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/OVillas/autentication/domain"
+	"github.com/samber/do"
+)
+
+// loginFailurePurgeInterval is how often identifiers are forgotten, once domain.LoginFailureTTL
+// has passed since their last failure and the end of their lock.
+const loginFailurePurgeInterval = time.Minute
+
+// memoryLoginFailureRepository keeps the failures in process. Each instance counts on its own.
+type memoryLoginFailureRepository struct {
+	i        *do.Injector
+	mutex    sync.Mutex
+	failures map[string]domain.LoginFailures
+}
+
+func NewMemoryLoginFailureRepository(i *do.Injector) (domain.LoginFailureRepository, error) {
+	mlfr := &memoryLoginFailureRepository{
+		i:        i,
+		failures: make(map[string]domain.LoginFailures),
+	}
+
+	go mlfr.purgeExpired()
+
+	return mlfr, nil
+}
+
+func (mlfr *memoryLoginFailureRepository) Get(key string) (domain.LoginFailures, error) {
+	mlfr.mutex.Lock()
+	defer mlfr.mutex.Unlock()
+
+	return mlfr.failures[key], nil
+}
+
+func (mlfr *memoryLoginFailureRepository) Increment(key string) (int, error) {
+	mlfr.mutex.Lock()
+	defer mlfr.mutex.Unlock()
+
+	now := time.Now()
+	failures := mlfr.failures[key]
+	failures.Attempts++
+	failures.LastFailedAt = &now
+	mlfr.failures[key] = failures
+
+	return failures.Attempts, nil
+}
+
+func (mlfr *memoryLoginFailureRepository) Lock(key string, lockedUntil time.Time) error {
+	mlfr.mutex.Lock()
+	defer mlfr.mutex.Unlock()
+
+	failures := mlfr.failures[key]
+	failures.Attempts = 0
+	failures.LockedUntil = &lockedUntil
+	mlfr.failures[key] = failures
+
+	return nil
+}
+
+func (mlfr *memoryLoginFailureRepository) purgeExpired() {
+	log := slog.With(
+		slog.String("func", "purgeExpired"),
+		slog.String("repository", "memoryLoginFailure"))
+
+	ticker := time.NewTicker(loginFailurePurgeInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		idleSince := time.Now().Add(-domain.LoginFailureTTL)
+		removed := 0
+
+		mlfr.mutex.Lock()
+		for key, failures := range mlfr.failures {
+			idle := failures.LastFailedAt == nil || failures.LastFailedAt.Before(idleSince)
+			if idle && (failures.LockedUntil == nil || failures.LockedUntil.Before(idleSince)) {
+				delete(mlfr.failures, key)
+				removed++
+			}
+		}
+		mlfr.mutex.Unlock()
+
+		if removed > 0 {
+			log.Debug("Idle login failures removed", slog.Int("count", removed))
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/OVillas/autentication/domain"
+	"github.com/redis/go-redis/v9"
+	"github.com/samber/do"
+)
+
+const loginFailureKeyPrefix = "login_failure:"
+
+// redisLoginFailureRepository keeps the failures of each identifier in a hash that expires
+// domain.LoginFailureTTL after the last one or the end of the lock, so every instance of the API
+// shares the count. Times are in milliseconds.
+type redisLoginFailureRepository struct {
+	i      *do.Injector
+	client *redis.Client
+}
+
+func NewRedisLoginFailureRepository(i *do.Injector) (domain.LoginFailureRepository, error) {
+	client := do.MustInvoke[*redis.Client](i)
+	return &redisLoginFailureRepository{
+		i:      i,
+		client: client,
+	}, nil
+}
+
+func (rlfr *redisLoginFailureRepository) Get(key string) (domain.LoginFailures, error) {
+	log := slog.With(
+		slog.String("func", "Get"),
+		slog.String("repository", "redisLoginFailure"))
+
+	values, err := rlfr.client.HGetAll(context.Background(), loginFailureKeyPrefix+key).Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		log.Error("Error: ", slog.Any("error", err))
+		return domain.LoginFailures{}, err
+	}
+
+	attempts, _ := strconv.Atoi(values["attempts"])
+	return domain.LoginFailures{
+		Attempts:     attempts,
+		LastFailedAt: millisecondsTime(values["last_failed_at"]),
+		LockedUntil:  millisecondsTime(values["locked_until"]),
+	}, nil
+}
+
+func (rlfr *redisLoginFailureRepository) Increment(key string) (int, error) {
+	log := slog.With(
+		slog.String("func", "Increment"),
+		slog.String("repository", "redisLoginFailure"))
+
+	ctx := context.Background()
+	key = loginFailureKeyPrefix + key
+
+	var attempts *redis.IntCmd
+	_, err := rlfr.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		attempts = pipe.HIncrBy(ctx, key, "attempts", 1)
+		pipe.HSet(ctx, key, "last_failed_at", time.Now().UnixMilli())
+		pipe.Expire(ctx, key, domain.LoginFailureTTL)
+		return nil
+	})
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return 0, err
+	}
+
+	return int(attempts.Val()), nil
+}
+
+func (rlfr *redisLoginFailureRepository) Lock(key string, lockedUntil time.Time) error {
+	log := slog.With(
+		slog.String("func", "Lock"),
+		slog.String("repository", "redisLoginFailure"))
+
+	ctx := context.Background()
+	key = loginFailureKeyPrefix + key
+
+	_, err := rlfr.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, key, "attempts", 0, "locked_until", lockedUntil.UnixMilli())
+		pipe.ExpireAt(ctx, key, lockedUntil.Add(domain.LoginFailureTTL))
+		return nil
+	})
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return err
+	}
+
+	return nil
+}
+
+// millisecondsTime reads a time stored in milliseconds, nil when there is none.
+func millisecondsTime(value string) *time.Time {
+	milliseconds, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return nil
+	}
+
+	t := time.UnixMilli(milliseconds)
+	return &t
+}
@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/OVillas/autentication/domain"
+	"github.com/samber/do"
+	"gorm.io/gorm"
+)
+
+type loginHistoryRepository struct {
+	i  *do.Injector
+	db *gorm.DB
+}
+
+func NewLoginHistoryRepository(i *do.Injector) (domain.LoginHistoryRepository, error) {
+	db := do.MustInvoke[*gorm.DB](i)
+	return &loginHistoryRepository{
+		db: db,
+		i:  i,
+	}, nil
+}
+
+func (lhr *loginHistoryRepository) Create(loginAttempt domain.LoginAttempt) error {
+	log := slog.With(
+		slog.String("func", "Create"),
+		slog.String("repository", "loginHistory"))
+
+	log.Info("Create initiated")
+
+	if err := lhr.db.Create(&loginAttempt).Error; err != nil {
+		log.Error("Error to create login attempt in database", slog.Any("error", err))
+		return err
+	}
+
+	log.Info("Create executed successfully")
+	return nil
+}
+
+// GetByUserID reads limit login attempts of the user, newest first, skipping the offset newest
+// ones, along with how many the user has in total.
+func (lhr *loginHistoryRepository) GetByUserID(userID string, offset int, limit int) ([]domain.LoginAttempt, int64, error) {
+	log := slog.With(
+		slog.String("func", "GetByUserID"),
+		slog.String("repository", "loginHistory"))
+
+	log.Info("GetByUserID initiated")
+
+	db := lhr.db.Model(&domain.LoginAttempt{}).Where(`"UserId" = ?`, userID)
+
+	var total int64
+	if err := db.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return nil, 0, err
+	}
+
+	var loginAttempts []domain.LoginAttempt
+	err := db.Order(`"CreatedAt" DESC, "Id" DESC`).Offset(offset).Limit(limit).Find(&loginAttempts).Error
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return nil, 0, err
+	}
+
+	log.Info("GetByUserID executed successfully")
+	return loginAttempts, total, nil
+}
+
+// GetAllByUserID reads every login attempt of the user still kept, oldest first.
+func (lhr *loginHistoryRepository) GetAllByUserID(userID string) ([]domain.LoginAttempt, error) {
+	log := slog.With(
+		slog.String("func", "GetAllByUserID"),
+		slog.String("repository", "loginHistory"))
+
+	log.Info("GetAllByUserID initiated")
+
+	var loginAttempts []domain.LoginAttempt
+	err := lhr.db.Where(`"UserId" = ?`, userID).Order(`"CreatedAt" ASC, "Id" ASC`).Find(&loginAttempts).Error
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return nil, err
+	}
+
+	log.Info("GetAllByUserID executed successfully")
+	return loginAttempts, nil
+}
+
+func (lhr *loginHistoryRepository) DeleteBefore(before time.Time) (int64, error) {
+	log := slog.With(
+		slog.String("func", "DeleteBefore"),
+		slog.String("repository", "loginHistory"))
+
+	log.Info("DeleteBefore initiated")
+
+	result := lhr.db.Where(`"CreatedAt" < ?`, before).Delete(&domain.LoginAttempt{})
+	if result.Error != nil {
+		log.Error("Error: ", slog.Any("error", result.Error))
+		return 0, result.Error
+	}
+
+	log.Info("DeleteBefore executed successfully")
+	return result.RowsAffected, nil
+}
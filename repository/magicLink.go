@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/OVillas/autentication/domain"
+	"github.com/samber/do"
+	"gorm.io/gorm"
+)
+
+type magicLinkRepository struct {
+	i  *do.Injector
+	db *gorm.DB
+}
+
+func NewMagicLinkRepository(i *do.Injector) (domain.MagicLinkRepository, error) {
+	db := do.MustInvoke[*gorm.DB](i)
+	return &magicLinkRepository{
+		db: db,
+		i:  i,
+	}, nil
+}
+
+func (mlr *magicLinkRepository) Create(magicLink domain.MagicLink) error {
+	log := slog.With(
+		slog.String("func", "Create"),
+		slog.String("repository", "magicLink"))
+
+	log.Info("Create initiated")
+
+	magicLink.CreatedAt = time.Now()
+
+	if err := mlr.db.Create(&magicLink).Error; err != nil {
+		log.Error("Error to create magic link in database", slog.Any("error", err))
+		return err
+	}
+
+	log.Info("Create executed successfully")
+	return nil
+}
+
+func (mlr *magicLinkRepository) GetByTokenHash(tokenHash string) (*domain.MagicLink, error) {
+	log := slog.With(
+		slog.String("func", "GetByTokenHash"),
+		slog.String("repository", "magicLink"))
+
+	log.Info("GetByTokenHash initiated")
+
+	var magicLink domain.MagicLink
+	err := mlr.db.Where(`"TokenHash" = ?`, tokenHash).First(&magicLink).Error
+
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		log.Error("Error: ", slog.Any("error", err))
+		return nil, err
+	}
+
+	log.Info("GetByTokenHash executed successfully")
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+
+	return &magicLink, nil
+}
+
+// Use consumes the link. It reports false when the link was already used or has expired, so
+// two clicks racing each other cannot both log in.
+func (mlr *magicLinkRepository) Use(tokenHash string) (bool, error) {
+	log := slog.With(
+		slog.String("func", "Use"),
+		slog.String("repository", "magicLink"))
+
+	log.Info("Use initiated")
+
+	now := time.Now()
+	result := mlr.db.Model(&domain.MagicLink{}).
+		Where(`"TokenHash" = ? AND "UsedAt" IS NULL AND "ExpiresAt" > ?`, tokenHash, now).
+		Update("UsedAt", now)
+	if result.Error != nil {
+		log.Error("Error: ", slog.Any("error", result.Error))
+		return false, result.Error
+	}
+
+	log.Info("Use executed successfully")
+	return result.RowsAffected == 1, nil
+}
@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"testing"
+
+	"github.com/OVillas/autentication/database/databasetest"
+	"github.com/OVillas/autentication/domain"
+	"github.com/google/uuid"
+	"github.com/samber/do"
+	"gorm.io/gorm"
+)
+
+var testDB *gorm.DB
+
+func TestMain(m *testing.M) {
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	db, err := databasetest.Open()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	testDB = db
+	os.Exit(m.Run())
+}
+
+// newTestInjector provides the test database to the repositories.
+func newTestInjector() *do.Injector {
+	i := do.New()
+	do.Provide(i, func(i *do.Injector) (*gorm.DB, error) {
+		return testDB, nil
+	})
+
+	return i
+}
+
+// newUnsavedUser returns a user in a tenant of its own, so the tests never see each other's users.
+func newUnsavedUser() domain.User {
+	id := uuid.NewString()
+	return domain.User{
+		ID:       id,
+		TenantID: uuid.NewString(),
+		Name:     "Test User",
+		Username: "user" + id[:8],
+		Email:    "user" + id[:8] + "@example.com",
+		Password: "hash",
+	}
+}
+
+// newTestUser creates a user returned by newUnsavedUser.
+func newTestUser(t *testing.T) domain.User {
+	t.Helper()
+
+	user := newUnsavedUser()
+	userRepository, _ := NewUserRepository(newTestInjector())
+	if err := userRepository.Create(context.Background(), user); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	return user
+}
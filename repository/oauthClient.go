@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/OVillas/autentication/domain"
+	"github.com/samber/do"
+	"gorm.io/gorm"
+)
+
+type oauthClientRepository struct {
+	i  *do.Injector
+	db *gorm.DB
+}
+
+func NewOAuthClientRepository(i *do.Injector) (domain.OAuthClientRepository, error) {
+	db := do.MustInvoke[*gorm.DB](i)
+	return &oauthClientRepository{
+		db: db,
+		i:  i,
+	}, nil
+}
+
+func (ocr *oauthClientRepository) Create(oauthClient domain.OAuthClient) error {
+	log := slog.With(
+		slog.String("func", "Create"),
+		slog.String("repository", "oauthClient"))
+
+	log.Info("Create initiated")
+
+	oauthClient.CreatedAt = time.Now()
+
+	if err := ocr.db.Create(&oauthClient).Error; err != nil {
+		log.Error("Error to create oauth client in database", slog.Any("error", err))
+		return err
+	}
+
+	log.Info("Create executed successfully")
+	return nil
+}
+
+func (ocr *oauthClientRepository) GetAll() ([]domain.OAuthClient, error) {
+	log := slog.With(
+		slog.String("func", "GetAll"),
+		slog.String("repository", "oauthClient"))
+
+	log.Info("GetAll initiated")
+
+	var oauthClients []domain.OAuthClient
+	if err := ocr.db.Order(`"CreatedAt" DESC`).Find(&oauthClients).Error; err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return nil, err
+	}
+
+	log.Info("GetAll executed successfully")
+	return oauthClients, nil
+}
+
+func (ocr *oauthClientRepository) GetById(id string) (*domain.OAuthClient, error) {
+	log := slog.With(
+		slog.String("func", "GetById"),
+		slog.String("repository", "oauthClient"))
+
+	log.Info("GetById initiated")
+
+	var oauthClient domain.OAuthClient
+	err := ocr.db.Where(`"Id" = ?`, id).First(&oauthClient).Error
+
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		log.Error("Error: ", slog.Any("error", err))
+		return nil, err
+	}
+
+	log.Info("GetById executed successfully")
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+
+	return &oauthClient, nil
+}
+
+func (ocr *oauthClientRepository) Delete(id string) (bool, error) {
+	log := slog.With(
+		slog.String("func", "Delete"),
+		slog.String("repository", "oauthClient"))
+
+	log.Info("Delete initiated")
+
+	result := ocr.db.Where(`"Id" = ?`, id).Delete(&domain.OAuthClient{})
+	if result.Error != nil {
+		log.Error("Error: ", slog.Any("error", result.Error))
+		return false, result.Error
+	}
+
+	log.Info("Delete executed successfully")
+	return result.RowsAffected == 1, nil
+}
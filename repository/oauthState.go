@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/OVillas/autentication/domain"
+	"github.com/samber/do"
+	"gorm.io/gorm"
+)
+
+type oauthStateRepository struct {
+	i  *do.Injector
+	db *gorm.DB
+}
+
+func NewOAuthStateRepository(i *do.Injector) (domain.OAuthStateRepository, error) {
+	db := do.MustInvoke[*gorm.DB](i)
+	return &oauthStateRepository{
+		db: db,
+		i:  i,
+	}, nil
+}
+
+func (osr *oauthStateRepository) Create(oauthState domain.OAuthState) error {
+	log := slog.With(
+		slog.String("func", "Create"),
+		slog.String("repository", "oauthState"))
+
+	log.Info("Create initiated")
+
+	oauthState.CreatedAt = time.Now()
+
+	if err := osr.db.Create(&oauthState).Error; err != nil {
+		log.Error("Error to create oauth state in database", slog.Any("error", err))
+		return err
+	}
+
+	log.Info("Create executed successfully")
+	return nil
+}
+
+func (osr *oauthStateRepository) GetByStateHash(stateHash string) (*domain.OAuthState, error) {
+	log := slog.With(
+		slog.String("func", "GetByStateHash"),
+		slog.String("repository", "oauthState"))
+
+	log.Info("GetByStateHash initiated")
+
+	var oauthState domain.OAuthState
+	err := osr.db.Where(`"StateHash" = ?`, stateHash).First(&oauthState).Error
+
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		log.Error("Error: ", slog.Any("error", err))
+		return nil, err
+	}
+
+	log.Info("GetByStateHash executed successfully")
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+
+	return &oauthState, nil
+}
+
+// Use consumes the state. It reports false when the state was already used or has expired,
+// so a callback cannot be replayed.
+func (osr *oauthStateRepository) Use(stateHash string) (bool, error) {
+	log := slog.With(
+		slog.String("func", "Use"),
+		slog.String("repository", "oauthState"))
+
+	log.Info("Use initiated")
+
+	now := time.Now()
+	result := osr.db.Model(&domain.OAuthState{}).
+		Where(`"StateHash" = ? AND "UsedAt" IS NULL AND "ExpiresAt" > ?`, stateHash, now).
+		Update("UsedAt", now)
+	if result.Error != nil {
+		log.Error("Error: ", slog.Any("error", result.Error))
+		return false, result.Error
+	}
+
+	log.Info("Use executed successfully")
+	return result.RowsAffected == 1, nil
+}
@@ -0,0 +1,235 @@
+package repository
+
+import (
+	"errors"
+	"log/slog"
+
+	"github.com/OVillas/autentication/domain"
+	"github.com/samber/do"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type organizationRepository struct {
+	i  *do.Injector
+	db *gorm.DB
+}
+
+func NewOrganizationRepository(i *do.Injector) (domain.OrganizationRepository, error) {
+	db := do.MustInvoke[*gorm.DB](i)
+	return &organizationRepository{
+		db: db,
+		i:  i,
+	}, nil
+}
+
+// Create stores the organization together with the membership of its first owner.
+func (ogr *organizationRepository) Create(organization domain.Organization, owner domain.Membership) error {
+	log := slog.With(
+		slog.String("func", "Create"),
+		slog.String("repository", "organization"))
+
+	log.Info("Create initiated")
+
+	err := ogr.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&organization).Error; err != nil {
+			return err
+		}
+
+		return tx.Create(&owner).Error
+	})
+	if err != nil {
+		log.Error("Error to create organization in database", slog.Any("error", err))
+		return err
+	}
+
+	log.Info("Create executed successfully")
+	return nil
+}
+
+func (ogr *organizationRepository) GetById(id string) (*domain.Organization, error) {
+	log := slog.With(
+		slog.String("func", "GetById"),
+		slog.String("repository", "organization"))
+
+	log.Info("GetById initiated")
+
+	var organization domain.Organization
+	err := ogr.db.Where(`"Id" = ?`, id).First(&organization).Error
+
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		log.Error("Error: ", slog.Any("error", err))
+		return nil, err
+	}
+
+	log.Info("GetById executed successfully")
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+
+	return &organization, nil
+}
+
+func (ogr *organizationRepository) GetByUserID(userID string) ([]domain.UserOrganization, error) {
+	log := slog.With(
+		slog.String("func", "GetByUserID"),
+		slog.String("repository", "organization"))
+
+	log.Info("GetByUserID initiated")
+
+	var organizations []domain.UserOrganization
+	err := ogr.db.Table("organization").
+		Select(`"organization"."Id", "organization"."Name", "membership"."Role", "organization"."CreatedAt"`).
+		Joins(`JOIN "membership" ON "membership"."OrganizationId" = "organization"."Id"`).
+		Where(`"membership"."UserId" = ?`, userID).
+		Order(`"organization"."Name"`).
+		Scan(&organizations).Error
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return nil, err
+	}
+
+	log.Info("GetByUserID executed successfully")
+	return organizations, nil
+}
+
+func (ogr *organizationRepository) GetMembership(organizationID string, userID string) (*domain.Membership, error) {
+	log := slog.With(
+		slog.String("func", "GetMembership"),
+		slog.String("repository", "organization"))
+
+	log.Info("GetMembership initiated")
+
+	var membership domain.Membership
+	err := ogr.db.Where(`"OrganizationId" = ? AND "UserId" = ?`, organizationID, userID).First(&membership).Error
+
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		log.Error("Error: ", slog.Any("error", err))
+		return nil, err
+	}
+
+	log.Info("GetMembership executed successfully")
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+
+	return &membership, nil
+}
+
+// GetMembers lists the members of the organization, owners first. Deleted users are left out.
+func (ogr *organizationRepository) GetMembers(organizationID string) ([]domain.Member, error) {
+	log := slog.With(
+		slog.String("func", "GetMembers"),
+		slog.String("repository", "organization"))
+
+	log.Info("GetMembers initiated")
+
+	// The user table goes by an alias, as user is a reserved word on Postgres.
+	var members []domain.Member
+	err := ogr.db.Table("membership").
+		Select(`"membership"."UserId", u."Name", u."Username", "membership"."Role", "membership"."CreatedAt"`).
+		Joins(`JOIN ? AS u ON u."Id" = "membership"."UserId" AND u."DeletedAt" IS NULL`, clause.Table{Name: "user"}).
+		Where(`"membership"."OrganizationId" = ?`, organizationID).
+		Order(`CASE "membership"."Role" WHEN 'owner' THEN 0 WHEN 'admin' THEN 1 ELSE 2 END, u."Username"`).
+		Scan(&members).Error
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return nil, err
+	}
+
+	log.Info("GetMembers executed successfully")
+	return members, nil
+}
+
+func (ogr *organizationRepository) AddMember(membership domain.Membership) (bool, error) {
+	log := slog.With(
+		slog.String("func", "AddMember"),
+		slog.String("repository", "organization"))
+
+	log.Info("AddMember initiated")
+
+	result := ogr.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&membership)
+	if result.Error != nil {
+		log.Error("Error: ", slog.Any("error", result.Error))
+		return false, result.Error
+	}
+
+	log.Info("AddMember executed successfully")
+	return result.RowsAffected == 1, nil
+}
+
+func (ogr *organizationRepository) UpdateRole(organizationID string, userID string, role string) error {
+	log := slog.With(
+		slog.String("func", "UpdateRole"),
+		slog.String("repository", "organization"))
+
+	log.Info("UpdateRole initiated")
+
+	err := ogr.changeMembership(organizationID, userID, role != domain.OrganizationRoleOwner, func(tx *gorm.DB) error {
+		return tx.Model(&domain.Membership{}).
+			Where(`"OrganizationId" = ? AND "UserId" = ?`, organizationID, userID).
+			Update("Role", role).Error
+	})
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return err
+	}
+
+	log.Info("UpdateRole executed successfully")
+	return nil
+}
+
+func (ogr *organizationRepository) RemoveMember(organizationID string, userID string) error {
+	log := slog.With(
+		slog.String("func", "RemoveMember"),
+		slog.String("repository", "organization"))
+
+	log.Info("RemoveMember initiated")
+
+	err := ogr.changeMembership(organizationID, userID, true, func(tx *gorm.DB) error {
+		return tx.Where(`"OrganizationId" = ? AND "UserId" = ?`, organizationID, userID).Delete(&domain.Membership{}).Error
+	})
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return err
+	}
+
+	log.Info("RemoveMember executed successfully")
+	return nil
+}
+
+// changeMembership applies change to a membership while the memberships of the organization are
+// locked, so two owners demoting or removing each other at once cannot leave it without owners.
+// losesOwnership tells whether the change takes the owner role away from an owner.
+func (ogr *organizationRepository) changeMembership(organizationID string, userID string, losesOwnership bool, change func(tx *gorm.DB) error) error {
+	return ogr.db.Transaction(func(tx *gorm.DB) error {
+		var memberships []domain.Membership
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where(`"OrganizationId" = ?`, organizationID).
+			Find(&memberships).Error
+		if err != nil {
+			return err
+		}
+
+		var member *domain.Membership
+		owners := 0
+		for index := range memberships {
+			if memberships[index].UserID == userID {
+				member = &memberships[index]
+			}
+			if memberships[index].Role == domain.OrganizationRoleOwner {
+				owners++
+			}
+		}
+
+		if member == nil {
+			return domain.ErrMemberNotFound
+		}
+
+		if losesOwnership && member.Role == domain.OrganizationRoleOwner && owners <= 1 {
+			return domain.ErrLastOwner
+		}
+
+		return change(tx)
+	})
+}
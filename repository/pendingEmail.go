@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/OVillas/autentication/domain"
+	"github.com/samber/do"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type pendingEmailRepository struct {
+	i  *do.Injector
+	db *gorm.DB
+}
+
+func NewPendingEmailRepository(i *do.Injector) (domain.PendingEmailRepository, error) {
+	db := do.MustInvoke[*gorm.DB](i)
+	return &pendingEmailRepository{
+		db: db,
+		i:  i,
+	}, nil
+}
+
+func (per *pendingEmailRepository) Create(pendingEmail domain.PendingEmail) error {
+	log := slog.With(
+		slog.String("func", "Create"),
+		slog.String("repository", "pendingEmail"))
+
+	log.Info("Create initiated")
+
+	pendingEmail.CreatedAt = time.Now()
+
+	if err := per.db.Create(&pendingEmail).Error; err != nil {
+		log.Error("Error to create pending email in database", slog.Any("error", err))
+		return err
+	}
+
+	log.Info("Create executed successfully")
+	return nil
+}
+
+func (per *pendingEmailRepository) GetUnconfirmedByNewEmail(tenantID string, newEmail string) (*domain.PendingEmail, error) {
+	log := slog.With(
+		slog.String("func", "GetUnconfirmedByNewEmail"),
+		slog.String("repository", "pendingEmail"))
+
+	log.Info("GetUnconfirmedByNewEmail initiated")
+
+	var pendingEmail domain.PendingEmail
+	err := per.db.Joins(`JOIN ? AS u ON u."Id" = "pending_email"."UserId" AND u."TenantId" = ?`, clause.Table{Name: "user"}, tenantID).
+		Where(`"pending_email"."NewEmail" = ? AND "pending_email"."ConfirmedAt" IS NULL AND "pending_email"."RevertExpiresAt" > ?`, newEmail, time.Now()).
+		Order(`"pending_email"."CreatedAt" DESC`).First(&pendingEmail).Error
+
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		log.Error("Error: ", slog.Any("error", err))
+		return nil, err
+	}
+
+	log.Info("GetUnconfirmedByNewEmail executed successfully")
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+
+	return &pendingEmail, nil
+}
+
+func (per *pendingEmailRepository) GetByRevertTokenHash(revertTokenHash string) (*domain.PendingEmail, error) {
+	log := slog.With(
+		slog.String("func", "GetByRevertTokenHash"),
+		slog.String("repository", "pendingEmail"))
+
+	log.Info("GetByRevertTokenHash initiated")
+
+	var pendingEmail domain.PendingEmail
+	err := per.db.Where(`"RevertTokenHash" = ?`, revertTokenHash).First(&pendingEmail).Error
+
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		log.Error("Error: ", slog.Any("error", err))
+		return nil, err
+	}
+
+	log.Info("GetByRevertTokenHash executed successfully")
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+
+	return &pendingEmail, nil
+}
+
+func (per *pendingEmailRepository) Confirm(revertTokenHash string) (bool, error) {
+	log := slog.With(
+		slog.String("func", "Confirm"),
+		slog.String("repository", "pendingEmail"))
+
+	log.Info("Confirm initiated")
+
+	result := per.db.Model(&domain.PendingEmail{}).
+		Where(`"RevertTokenHash" = ? AND "ConfirmedAt" IS NULL`, revertTokenHash).
+		Update("ConfirmedAt", time.Now())
+	if result.Error != nil {
+		log.Error("Error: ", slog.Any("error", result.Error))
+		return false, result.Error
+	}
+
+	log.Info("Confirm executed successfully")
+	return result.RowsAffected == 1, nil
+}
+
+func (per *pendingEmailRepository) Delete(revertTokenHash string) (bool, error) {
+	log := slog.With(
+		slog.String("func", "Delete"),
+		slog.String("repository", "pendingEmail"))
+
+	log.Info("Delete initiated")
+
+	result := per.db.Where(`"RevertTokenHash" = ?`, revertTokenHash).Delete(&domain.PendingEmail{})
+	if result.Error != nil {
+		log.Error("Error: ", slog.Any("error", result.Error))
+		return false, result.Error
+	}
+
+	log.Info("Delete executed successfully")
+	return result.RowsAffected == 1, nil
+}
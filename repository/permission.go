@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/OVillas/autentication/domain"
+	"github.com/samber/do"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type permissionRepository struct {
+	i  *do.Injector
+	db *gorm.DB
+}
+
+func NewPermissionRepository(i *do.Injector) (domain.PermissionRepository, error) {
+	db := do.MustInvoke[*gorm.DB](i)
+	return &permissionRepository{
+		db: db,
+		i:  i,
+	}, nil
+}
+
+func (pr *permissionRepository) Create(permission domain.Permission) error {
+	log := slog.With(
+		slog.String("func", "Create"),
+		slog.String("repository", "permission"))
+
+	log.Info("Create initiated")
+
+	permission.CreatedAt = time.Now()
+
+	if err := pr.db.Create(&permission).Error; err != nil {
+		log.Error("Error to create permission in database", slog.Any("error", err))
+		return err
+	}
+
+	log.Info("Create executed successfully")
+	return nil
+}
+
+func (pr *permissionRepository) GetByName(name string) (*domain.Permission, error) {
+	log := slog.With(
+		slog.String("func", "GetByName"),
+		slog.String("repository", "permission"))
+
+	log.Info("GetByName initiated")
+
+	var permission domain.Permission
+	err := pr.db.Where(`"Name" = ?`, name).First(&permission).Error
+
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		log.Error("Error: ", slog.Any("error", err))
+		return nil, err
+	}
+
+	log.Info("GetByName executed successfully")
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+
+	return &permission, nil
+}
+
+// GetNamesByUserID returns the permissions the user holds through any of its roles.
+func (pr *permissionRepository) GetNamesByUserID(userID string) ([]string, error) {
+	log := slog.With(
+		slog.String("func", "GetNamesByUserID"),
+		slog.String("repository", "permission"))
+
+	log.Info("GetNamesByUserID initiated")
+
+	var names []string
+	err := pr.db.Model(&domain.Permission{}).
+		Distinct("permission.Name").
+		Joins(`JOIN "role_permission" ON "role_permission"."PermissionId" = "permission"."Id"`).
+		Joins(`JOIN "user_role" ON "user_role"."RoleId" = "role_permission"."RoleId"`).
+		Where(`"user_role"."UserId" = ?`, userID).
+		Order(`"permission"."Name"`).
+		Pluck("permission.Name", &names).Error
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return nil, err
+	}
+
+	log.Info("GetNamesByUserID executed successfully")
+	return names, nil
+}
+
+func (pr *permissionRepository) Grant(rolePermission domain.RolePermission) (bool, error) {
+	log := slog.With(
+		slog.String("func", "Grant"),
+		slog.String("repository", "permission"))
+
+	log.Info("Grant initiated")
+
+	rolePermission.CreatedAt = time.Now()
+
+	result := pr.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&rolePermission)
+	if result.Error != nil {
+		log.Error("Error: ", slog.Any("error", result.Error))
+		return false, result.Error
+	}
+
+	log.Info("Grant executed successfully")
+	return result.RowsAffected == 1, nil
+}
+
+func (pr *permissionRepository) Revoke(roleID string, permissionID string) (bool, error) {
+	log := slog.With(
+		slog.String("func", "Revoke"),
+		slog.String("repository", "permission"))
+
+	log.Info("Revoke initiated")
+
+	result := pr.db.Where(`"RoleId" = ? AND "PermissionId" = ?`, roleID, permissionID).Delete(&domain.RolePermission{})
+	if result.Error != nil {
+		log.Error("Error: ", slog.Any("error", result.Error))
+		return false, result.Error
+	}
+
+	log.Info("Revoke executed successfully")
+	return result.RowsAffected == 1, nil
+}
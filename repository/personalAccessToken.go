@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/OVillas/autentication/domain"
+	"github.com/samber/do"
+	"gorm.io/gorm"
+)
+
+type personalAccessTokenRepository struct {
+	i  *do.Injector
+	db *gorm.DB
+}
+
+func NewPersonalAccessTokenRepository(i *do.Injector) (domain.PersonalAccessTokenRepository, error) {
+	db := do.MustInvoke[*gorm.DB](i)
+	return &personalAccessTokenRepository{
+		db: db,
+		i:  i,
+	}, nil
+}
+
+func (patr *personalAccessTokenRepository) Create(personalAccessToken domain.PersonalAccessToken) error {
+	log := slog.With(
+		slog.String("func", "Create"),
+		slog.String("repository", "personalAccessToken"))
+
+	log.Info("Create initiated")
+
+	personalAccessToken.CreatedAt = time.Now()
+
+	if err := patr.db.Create(&personalAccessToken).Error; err != nil {
+		log.Error("Error to create personal access token in database", slog.Any("error", err))
+		return err
+	}
+
+	log.Info("Create executed successfully")
+	return nil
+}
+
+func (patr *personalAccessTokenRepository) GetByTokenHash(tokenHash string) (*domain.PersonalAccessToken, error) {
+	log := slog.With(
+		slog.String("func", "GetByTokenHash"),
+		slog.String("repository", "personalAccessToken"))
+
+	log.Info("GetByTokenHash initiated")
+
+	var personalAccessToken domain.PersonalAccessToken
+	err := patr.db.Where(`"TokenHash" = ?`, tokenHash).First(&personalAccessToken).Error
+
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		log.Error("Error: ", slog.Any("error", err))
+		return nil, err
+	}
+
+	log.Info("GetByTokenHash executed successfully")
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+
+	return &personalAccessToken, nil
+}
+
+func (patr *personalAccessTokenRepository) GetByUserID(userID string) ([]domain.PersonalAccessToken, error) {
+	log := slog.With(
+		slog.String("func", "GetByUserID"),
+		slog.String("repository", "personalAccessToken"))
+
+	log.Info("GetByUserID initiated")
+
+	var personalAccessTokens []domain.PersonalAccessToken
+	err := patr.db.Where(`"UserId" = ? AND "RevokedAt" IS NULL`, userID).Order(`"CreatedAt" DESC`).Find(&personalAccessTokens).Error
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return nil, err
+	}
+
+	log.Info("GetByUserID executed successfully")
+	return personalAccessTokens, nil
+}
+
+func (patr *personalAccessTokenRepository) Revoke(userID string, id string) (bool, error) {
+	log := slog.With(
+		slog.String("func", "Revoke"),
+		slog.String("repository", "personalAccessToken"))
+
+	log.Info("Revoke initiated")
+
+	result := patr.db.Model(&domain.PersonalAccessToken{}).
+		Where(`"Id" = ? AND "UserId" = ? AND "RevokedAt" IS NULL`, id, userID).
+		Update("RevokedAt", time.Now())
+	if result.Error != nil {
+		log.Error("Error: ", slog.Any("error", result.Error))
+		return false, result.Error
+	}
+
+	log.Info("Revoke executed successfully")
+	return result.RowsAffected == 1, nil
+}
+
+func (patr *personalAccessTokenRepository) UpdateLastUsedAt(id string) error {
+	log := slog.With(
+		slog.String("func", "UpdateLastUsedAt"),
+		slog.String("repository", "personalAccessToken"))
+
+	err := patr.db.Model(&domain.PersonalAccessToken{}).Where(`"Id" = ?`, id).Update("LastUsedAt", time.Now()).Error
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"errors"
+	"log/slog"
+
+	"github.com/OVillas/autentication/domain"
+	"github.com/samber/do"
+	"gorm.io/gorm"
+)
+
+type policyAcceptanceRepository struct {
+	i  *do.Injector
+	db *gorm.DB
+}
+
+func NewPolicyAcceptanceRepository(i *do.Injector) (domain.PolicyAcceptanceRepository, error) {
+	db := do.MustInvoke[*gorm.DB](i)
+	return &policyAcceptanceRepository{
+		db: db,
+		i:  i,
+	}, nil
+}
+
+func (par *policyAcceptanceRepository) Create(policyAcceptance domain.PolicyAcceptance) error {
+	log := slog.With(
+		slog.String("func", "Create"),
+		slog.String("repository", "policyAcceptance"))
+
+	log.Info("Create initiated")
+
+	if err := par.db.Create(&policyAcceptance).Error; err != nil {
+		log.Error("Error to create policy acceptance in database", slog.Any("error", err))
+		return err
+	}
+
+	log.Info("Create executed successfully")
+	return nil
+}
+
+func (par *policyAcceptanceRepository) GetLatestByUserID(userID string) (*domain.PolicyAcceptance, error) {
+	log := slog.With(
+		slog.String("func", "GetLatestByUserID"),
+		slog.String("repository", "policyAcceptance"))
+
+	log.Info("GetLatestByUserID initiated")
+
+	var policyAcceptance domain.PolicyAcceptance
+	err := par.db.Where(`"UserId" = ?`, userID).Order(`"AcceptedAt" DESC`).First(&policyAcceptance).Error
+
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		log.Error("Error: ", slog.Any("error", err))
+		return nil, err
+	}
+
+	log.Info("GetLatestByUserID executed successfully")
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+
+	return &policyAcceptance, nil
+}
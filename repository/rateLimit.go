@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/OVillas/autentication/domain"
+	"github.com/samber/do"
+)
+
+// rateLimitPurgeInterval is how often clients whose allowance is full again are forgotten.
+const rateLimitPurgeInterval = time.Minute
+
+// memoryRateLimitRepository applies the generic cell rate algorithm in process: for each key it
+// only keeps the theoretical arrival time of the next request. Each instance counts on its own.
+type memoryRateLimitRepository struct {
+	i     *do.Injector
+	mutex sync.Mutex
+	tats  map[string]time.Time
+}
+
+func NewMemoryRateLimitRepository(i *do.Injector) (domain.RateLimitRepository, error) {
+	mrlr := &memoryRateLimitRepository{
+		i:    i,
+		tats: make(map[string]time.Time),
+	}
+
+	go mrlr.purgeExpired()
+
+	return mrlr, nil
+}
+
+func (mrlr *memoryRateLimitRepository) Allow(key string, limit domain.RateLimit) (time.Duration, error) {
+	mrlr.mutex.Lock()
+	defer mrlr.mutex.Unlock()
+
+	now := time.Now()
+	tat := mrlr.tats[key]
+	if tat.Before(now) {
+		tat = now
+	}
+
+	next := tat.Add(limit.Interval)
+	allowAt := next.Add(-time.Duration(limit.Burst) * limit.Interval)
+	if allowAt.After(now) {
+		return allowAt.Sub(now), nil
+	}
+
+	mrlr.tats[key] = next
+	return 0, nil
+}
+
+func (mrlr *memoryRateLimitRepository) purgeExpired() {
+	log := slog.With(
+		slog.String("func", "purgeExpired"),
+		slog.String("repository", "memoryRateLimit"))
+
+	ticker := time.NewTicker(rateLimitPurgeInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		now := time.Now()
+		removed := 0
+
+		mrlr.mutex.Lock()
+		for key, tat := range mrlr.tats {
+			if tat.Before(now) {
+				delete(mrlr.tats, key)
+				removed++
+			}
+		}
+		mrlr.mutex.Unlock()
+
+		if removed > 0 {
+			log.Debug("Idle rate limit keys removed", slog.Int("count", removed))
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/OVillas/autentication/domain"
+	"github.com/redis/go-redis/v9"
+	"github.com/samber/do"
+)
+
+const rateLimitKeyPrefix = "rate_limit:"
+
+// allowScript is the generic cell rate algorithm on the clock of the Redis server, so every
+// instance of the API shares the same allowance. Times are in microseconds and the key expires
+// once the allowance is full again.
+var allowScript = redis.NewScript(`
+local time = redis.call("TIME")
+local now = tonumber(time[1]) * 1000000 + tonumber(time[2])
+local interval = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local tat = tonumber(redis.call("GET", KEYS[1]) or "0")
+if tat < now then
+	tat = now
+end
+local newTat = tat + interval
+local allowAt = newTat - burst * interval
+if allowAt > now then
+	return allowAt - now
+end
+redis.call("SET", KEYS[1], newTat, "PX", math.ceil((newTat - now) / 1000))
+return 0
+`)
+
+type redisRateLimitRepository struct {
+	i      *do.Injector
+	client *redis.Client
+}
+
+func NewRedisRateLimitRepository(i *do.Injector) (domain.RateLimitRepository, error) {
+	client := do.MustInvoke[*redis.Client](i)
+	return &redisRateLimitRepository{
+		i:      i,
+		client: client,
+	}, nil
+}
+
+func (rrlr *redisRateLimitRepository) Allow(key string, limit domain.RateLimit) (time.Duration, error) {
+	log := slog.With(
+		slog.String("func", "Allow"),
+		slog.String("repository", "redisRateLimit"))
+
+	wait, err := allowScript.Run(context.Background(), rrlr.client, []string{rateLimitKeyPrefix + key},
+		limit.Interval.Microseconds(), limit.Burst).Int64()
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return 0, err
+	}
+
+	return time.Duration(wait) * time.Microsecond, nil
+}
@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/OVillas/autentication/domain"
+	"github.com/samber/do"
+	"gorm.io/gorm"
+)
+
+type recoveryCodeRepository struct {
+	i  *do.Injector
+	db *gorm.DB
+}
+
+func NewRecoveryCodeRepository(i *do.Injector) (domain.RecoveryCodeRepository, error) {
+	db := do.MustInvoke[*gorm.DB](i)
+	return &recoveryCodeRepository{
+		db: db,
+		i:  i,
+	}, nil
+}
+
+// Replace swaps the whole set of a user in one transaction, so the old codes stop working
+// exactly when the new ones start.
+func (rcr *recoveryCodeRepository) Replace(userID string, recoveryCodes []domain.RecoveryCode) error {
+	log := slog.With(
+		slog.String("func", "Replace"),
+		slog.String("repository", "recoveryCode"))
+
+	log.Info("Replace initiated")
+
+	now := time.Now()
+	for index := range recoveryCodes {
+		recoveryCodes[index].CreatedAt = now
+	}
+
+	err := rcr.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where(`"UserId" = ?`, userID).Delete(&domain.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Create(&recoveryCodes).Error
+	})
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return err
+	}
+
+	log.Info("Replace executed successfully")
+	return nil
+}
+
+func (rcr *recoveryCodeRepository) CountUnused(userID string) (int64, error) {
+	log := slog.With(
+		slog.String("func", "CountUnused"),
+		slog.String("repository", "recoveryCode"))
+
+	log.Info("CountUnused initiated")
+
+	var count int64
+	err := rcr.db.Model(&domain.RecoveryCode{}).Where(`"UserId" = ? AND "UsedAt" IS NULL`, userID).Count(&count).Error
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return 0, err
+	}
+
+	log.Info("CountUnused executed successfully")
+	return count, nil
+}
+
+// Use consumes a code. Only the request that flips UsedAt gets true, so a code cannot be
+// spent twice by concurrent logins.
+func (rcr *recoveryCodeRepository) Use(userID string, codeHash string) (bool, error) {
+	log := slog.With(
+		slog.String("func", "Use"),
+		slog.String("repository", "recoveryCode"))
+
+	log.Info("Use initiated")
+
+	result := rcr.db.Model(&domain.RecoveryCode{}).
+		Where(`"UserId" = ? AND "CodeHash" = ? AND "UsedAt" IS NULL`, userID, codeHash).
+		Update("UsedAt", time.Now())
+	if result.Error != nil {
+		log.Error("Error: ", slog.Any("error", result.Error))
+		return false, result.Error
+	}
+
+	log.Info("Use executed successfully")
+	return result.RowsAffected == 1, nil
+}
+
+func (rcr *recoveryCodeRepository) DeleteByUserID(userID string) error {
+	log := slog.With(
+		slog.String("func", "DeleteByUserID"),
+		slog.String("repository", "recoveryCode"))
+
+	log.Info("DeleteByUserID initiated")
+
+	if err := rcr.db.Where(`"UserId" = ?`, userID).Delete(&domain.RecoveryCode{}).Error; err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return err
+	}
+
+	log.Info("DeleteByUserID executed successfully")
+	return nil
+}
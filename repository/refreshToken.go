@@ -0,0 +1,262 @@
+package repository
+
+import (
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/OVillas/autentication/domain"
+	"github.com/samber/do"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type refreshTokenRepository struct {
+	i  *do.Injector
+	db *gorm.DB
+}
+
+func NewRefreshTokenRepository(i *do.Injector) (domain.RefreshTokenRepository, error) {
+	db := do.MustInvoke[*gorm.DB](i)
+	return &refreshTokenRepository{
+		db: db,
+		i:  i,
+	}, nil
+}
+
+func (rtr *refreshTokenRepository) Create(refreshToken domain.RefreshToken) error {
+	log := slog.With(
+		slog.String("func", "Create"),
+		slog.String("repository", "refreshToken"))
+
+	log.Info("Create initiated")
+
+	refreshToken.CreatedAt = time.Now()
+
+	if err := rtr.db.Create(&refreshToken).Error; err != nil {
+		log.Error("Error to create refresh token in database", slog.Any("error", err))
+		return err
+	}
+
+	log.Info("Create executed successfully")
+	return nil
+}
+
+func (rtr *refreshTokenRepository) GetByTokenHash(tokenHash string) (*domain.RefreshToken, error) {
+	log := slog.With(
+		slog.String("func", "GetByTokenHash"),
+		slog.String("repository", "refreshToken"))
+
+	log.Info("GetByTokenHash initiated")
+
+	var refreshToken domain.RefreshToken
+	err := rtr.db.Where(`"TokenHash" = ?`, tokenHash).First(&refreshToken).Error
+
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		log.Error("Error: ", slog.Any("error", err))
+		return nil, err
+	}
+
+	log.Info("GetByTokenHash executed successfully")
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+
+	return &refreshToken, nil
+}
+
+func (rtr *refreshTokenRepository) Revoke(id string) error {
+	log := slog.With(
+		slog.String("func", "Revoke"),
+		slog.String("repository", "refreshToken"))
+
+	log.Info("Revoke initiated")
+
+	err := rtr.db.Model(&domain.RefreshToken{}).Where(`"Id" = ? AND "RevokedAt" IS NULL`, id).Update("RevokedAt", time.Now()).Error
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return err
+	}
+
+	log.Info("Revoke executed successfully")
+	return nil
+}
+
+func (rtr *refreshTokenRepository) Rotate(id string, replacedBy string) (bool, error) {
+	log := slog.With(
+		slog.String("func", "Rotate"),
+		slog.String("repository", "refreshToken"))
+
+	log.Info("Rotate initiated")
+
+	result := rtr.db.Model(&domain.RefreshToken{}).
+		Where(`"Id" = ? AND "RevokedAt" IS NULL AND "ReplacedBy" = ''`, id).
+		Updates(map[string]interface{}{"RevokedAt": time.Now(), "ReplacedBy": replacedBy})
+	if result.Error != nil {
+		log.Error("Error: ", slog.Any("error", result.Error))
+		return false, result.Error
+	}
+
+	log.Info("Rotate executed successfully")
+	return result.RowsAffected == 1, nil
+}
+
+func (rtr *refreshTokenRepository) RevokeFamily(familyID string) error {
+	log := slog.With(
+		slog.String("func", "RevokeFamily"),
+		slog.String("repository", "refreshToken"))
+
+	log.Info("RevokeFamily initiated")
+
+	err := rtr.db.Model(&domain.RefreshToken{}).Where(`"FamilyId" = ? AND "RevokedAt" IS NULL`, familyID).Update("RevokedAt", time.Now()).Error
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return err
+	}
+
+	log.Info("RevokeFamily executed successfully")
+	return nil
+}
+
+// SetOrganization records the organization picked by the session on its tokens still in use.
+func (rtr *refreshTokenRepository) SetOrganization(familyID string, organizationID string) error {
+	log := slog.With(
+		slog.String("func", "SetOrganization"),
+		slog.String("repository", "refreshToken"))
+
+	log.Info("SetOrganization initiated")
+
+	err := rtr.db.Model(&domain.RefreshToken{}).Where(`"FamilyId" = ? AND "RevokedAt" IS NULL AND "ReplacedBy" = ''`, familyID).
+		Update("OrganizationId", organizationID).Error
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return err
+	}
+
+	log.Info("SetOrganization executed successfully")
+	return nil
+}
+
+func (rtr *refreshTokenRepository) RevokeAllByUserID(userID string) error {
+	log := slog.With(
+		slog.String("func", "RevokeAllByUserID"),
+		slog.String("repository", "refreshToken"))
+
+	log.Info("RevokeAllByUserID initiated")
+
+	err := rtr.db.Model(&domain.RefreshToken{}).Where(`"UserId" = ? AND "RevokedAt" IS NULL`, userID).Update("RevokedAt", time.Now()).Error
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return err
+	}
+
+	log.Info("RevokeAllByUserID executed successfully")
+	return nil
+}
+
+func (rtr *refreshTokenRepository) GetActiveByUserID(userID string) ([]domain.RefreshToken, error) {
+	log := slog.With(
+		slog.String("func", "GetActiveByUserID"),
+		slog.String("repository", "refreshToken"))
+
+	log.Info("GetActiveByUserID initiated")
+
+	var refreshTokens []domain.RefreshToken
+	err := rtr.db.Where(`"UserId" = ? AND "RevokedAt" IS NULL AND "ExpiresAt" > ?`, userID, time.Now()).
+		Order(`"CreatedAt" DESC`).
+		Find(&refreshTokens).Error
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return nil, err
+	}
+
+	log.Info("GetActiveByUserID executed successfully")
+	return refreshTokens, nil
+}
+
+func (rtr *refreshTokenRepository) GetLatestByFamilyID(familyID string) (*domain.RefreshToken, error) {
+	log := slog.With(
+		slog.String("func", "GetLatestByFamilyID"),
+		slog.String("repository", "refreshToken"))
+
+	log.Info("GetLatestByFamilyID initiated")
+
+	var refreshToken domain.RefreshToken
+	err := rtr.db.Where(`"FamilyId" = ?`, familyID).Order(`"CreatedAt" DESC`).First(&refreshToken).Error
+
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		log.Error("Error: ", slog.Any("error", err))
+		return nil, err
+	}
+
+	log.Info("GetLatestByFamilyID executed successfully")
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+
+	return &refreshToken, nil
+}
+
+// CreateWithinLimit stores a token opening a new session unless the user already holds limit
+// active sessions. Over the limit it either refuses, returning false, or revokes the oldest
+// sessions and returns their family ids. The owner row is locked for the duration so two
+// logins racing at the limit cannot both pass the count.
+func (rtr *refreshTokenRepository) CreateWithinLimit(refreshToken domain.RefreshToken, limit int, evictOldest bool) (bool, []string, error) {
+	log := slog.With(
+		slog.String("func", "CreateWithinLimit"),
+		slog.String("repository", "refreshToken"))
+
+	log.Info("CreateWithinLimit initiated")
+
+	created := false
+	var evicted []string
+
+	err := rtr.db.Transaction(func(tx *gorm.DB) error {
+		var user domain.User
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("Id").Where(`"Id" = ?`, refreshToken.UserID).First(&user).Error
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		active := func() *gorm.DB {
+			return tx.Model(&domain.RefreshToken{}).Where(`"UserId" = ? AND "RevokedAt" IS NULL AND "ExpiresAt" > ?`, refreshToken.UserID, now)
+		}
+
+		var count int64
+		if err := active().Count(&count).Error; err != nil {
+			return err
+		}
+
+		if count >= int64(limit) {
+			if !evictOldest {
+				return nil
+			}
+
+			err := active().Order(`"SessionAt" ASC`).Limit(int(count)-limit+1).Pluck("FamilyId", &evicted).Error
+			if err != nil {
+				return err
+			}
+
+			err = tx.Model(&domain.RefreshToken{}).Where(`"FamilyId" IN ? AND "RevokedAt" IS NULL`, evicted).Update("RevokedAt", now).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		refreshToken.CreatedAt = now
+		if err := tx.Create(&refreshToken).Error; err != nil {
+			return err
+		}
+
+		created = true
+		return nil
+	})
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return false, nil, err
+	}
+
+	log.Info("CreateWithinLimit executed successfully")
+	return created, evicted, nil
+}
@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/OVillas/autentication/domain"
+	"github.com/google/uuid"
+)
+
+// newSession returns a refresh token opening a session of user at sessionAt.
+func newSession(user domain.User, sessionAt time.Time) domain.RefreshToken {
+	return domain.RefreshToken{
+		ID:        uuid.NewString(),
+		UserID:    user.ID,
+		TenantID:  user.TenantID,
+		FamilyID:  uuid.NewString(),
+		TokenHash: uuid.NewString(),
+		ExpiresAt: time.Now().Add(time.Hour),
+		MaxExpiry: time.Now().Add(time.Hour),
+		SessionAt: sessionAt,
+	}
+}
+
+// openSessions opens count sessions of user, the oldest first.
+func openSessions(t *testing.T, refreshTokenRepository domain.RefreshTokenRepository, user domain.User, count int) []domain.RefreshToken {
+	t.Helper()
+
+	sessions := make([]domain.RefreshToken, count)
+	for n := range sessions {
+		sessions[n] = newSession(user, time.Now().Add(time.Duration(n-count)*time.Minute))
+		if err := refreshTokenRepository.Create(sessions[n]); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+	}
+
+	return sessions
+}
+
+func assertActiveSessions(t *testing.T, refreshTokenRepository domain.RefreshTokenRepository, user domain.User, want int) {
+	t.Helper()
+
+	active, err := refreshTokenRepository.GetActiveByUserID(user.ID)
+	if err != nil {
+		t.Fatalf("GetActiveByUserID: %v", err)
+	}
+
+	if len(active) != want {
+		t.Errorf("%d active sessions, want %d", len(active), want)
+	}
+}
+
+func TestCreateWithinLimitRejects(t *testing.T) {
+	refreshTokenRepository, _ := NewRefreshTokenRepository(newTestInjector())
+	user := newTestUser(t)
+	openSessions(t, refreshTokenRepository, user, 2)
+
+	created, evicted, err := refreshTokenRepository.CreateWithinLimit(newSession(user, time.Now()), 2, false)
+	if err != nil {
+		t.Fatalf("CreateWithinLimit: %v", err)
+	}
+
+	if created || len(evicted) != 0 {
+		t.Errorf("created %v and evicted %v at the limit, want a refusal", created, evicted)
+	}
+
+	assertActiveSessions(t, refreshTokenRepository, user, 2)
+}
+
+func TestCreateWithinLimitEvictsTheOldest(t *testing.T) {
+	refreshTokenRepository, _ := NewRefreshTokenRepository(newTestInjector())
+	user := newTestUser(t)
+	sessions := openSessions(t, refreshTokenRepository, user, 2)
+
+	created, evicted, err := refreshTokenRepository.CreateWithinLimit(newSession(user, time.Now()), 2, true)
+	if err != nil {
+		t.Fatalf("CreateWithinLimit: %v", err)
+	}
+
+	if !created {
+		t.Fatal("the session was not created")
+	}
+
+	if len(evicted) != 1 || evicted[0] != sessions[0].FamilyID {
+		t.Errorf("evicted %v, want the oldest session %s", evicted, sessions[0].FamilyID)
+	}
+
+	oldest, err := refreshTokenRepository.GetByTokenHash(sessions[0].TokenHash)
+	if err != nil {
+		t.Fatalf("GetByTokenHash: %v", err)
+	}
+
+	if oldest == nil || oldest.RevokedAt == nil {
+		t.Error("the oldest session was not revoked")
+	}
+
+	assertActiveSessions(t, refreshTokenRepository, user, 2)
+}
+
+func TestCreateWithinLimitConcurrentLogins(t *testing.T) {
+	refreshTokenRepository, _ := NewRefreshTokenRepository(newTestInjector())
+	user := newTestUser(t)
+	openSessions(t, refreshTokenRepository, user, 1)
+
+	// Both logins find one session under a limit of two: only the first to lock the user may
+	// open the second. SQLite runs one transaction at a time, so the race is only run with the
+	// mysql and postgres tags.
+	var wg sync.WaitGroup
+	results := make([]bool, 2)
+	for n := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			created, _, err := refreshTokenRepository.CreateWithinLimit(newSession(user, time.Now()), 2, false)
+			if err != nil {
+				t.Errorf("CreateWithinLimit: %v", err)
+			}
+			results[n] = created
+		}()
+	}
+	wg.Wait()
+
+	if results[0] == results[1] {
+		t.Errorf("created %v, want exactly one login to open a session", results)
+	}
+
+	assertActiveSessions(t, refreshTokenRepository, user, 2)
+}
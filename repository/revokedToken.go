@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/OVillas/autentication/domain"
+	"github.com/samber/do"
+	"gorm.io/gorm"
+)
+
+type revokedTokenRepository struct {
+	i  *do.Injector
+	db *gorm.DB
+}
+
+func NewRevokedTokenRepository(i *do.Injector) (domain.RevokedTokenRepository, error) {
+	db := do.MustInvoke[*gorm.DB](i)
+	return &revokedTokenRepository{
+		db: db,
+		i:  i,
+	}, nil
+}
+
+func (rtr *revokedTokenRepository) Create(revokedToken domain.RevokedToken) error {
+	log := slog.With(
+		slog.String("func", "Create"),
+		slog.String("repository", "revokedToken"))
+
+	log.Info("Create initiated")
+
+	revokedToken.CreatedAt = time.Now()
+
+	if err := rtr.db.Create(&revokedToken).Error; err != nil {
+		log.Error("Error to create revoked token in database", slog.Any("error", err))
+		return err
+	}
+
+	log.Info("Create executed successfully")
+	return nil
+}
+
+// Exists reports whether any of the given identifiers, a token jti or a session id, was revoked.
+func (rtr *revokedTokenRepository) Exists(ids ...string) (bool, error) {
+	log := slog.With(
+		slog.String("func", "Exists"),
+		slog.String("repository", "revokedToken"))
+
+	log.Info("Exists initiated")
+
+	var count int64
+	err := rtr.db.Model(&domain.RevokedToken{}).Where(`"Jti" IN ?`, ids).Count(&count).Error
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return false, err
+	}
+
+	log.Info("Exists executed successfully")
+	return count > 0, nil
+}
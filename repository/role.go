@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/OVillas/autentication/domain"
+	"github.com/samber/do"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type roleRepository struct {
+	i  *do.Injector
+	db *gorm.DB
+}
+
+func NewRoleRepository(i *do.Injector) (domain.RoleRepository, error) {
+	db := do.MustInvoke[*gorm.DB](i)
+	return &roleRepository{
+		db: db,
+		i:  i,
+	}, nil
+}
+
+func (rr *roleRepository) Create(role domain.Role) error {
+	log := slog.With(
+		slog.String("func", "Create"),
+		slog.String("repository", "role"))
+
+	log.Info("Create initiated")
+
+	role.CreatedAt = time.Now()
+
+	if err := rr.db.Create(&role).Error; err != nil {
+		log.Error("Error to create role in database", slog.Any("error", err))
+		return err
+	}
+
+	log.Info("Create executed successfully")
+	return nil
+}
+
+func (rr *roleRepository) GetByName(name string) (*domain.Role, error) {
+	log := slog.With(
+		slog.String("func", "GetByName"),
+		slog.String("repository", "role"))
+
+	log.Info("GetByName initiated")
+
+	var role domain.Role
+	err := rr.db.Where(`"Name" = ?`, name).First(&role).Error
+
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		log.Error("Error: ", slog.Any("error", err))
+		return nil, err
+	}
+
+	log.Info("GetByName executed successfully")
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+
+	return &role, nil
+}
+
+func (rr *roleRepository) GetNamesByUserID(userID string) ([]string, error) {
+	log := slog.With(
+		slog.String("func", "GetNamesByUserID"),
+		slog.String("repository", "role"))
+
+	log.Info("GetNamesByUserID initiated")
+
+	var names []string
+	err := rr.db.Model(&domain.Role{}).
+		Joins(`JOIN "user_role" ON "user_role"."RoleId" = "role"."Id"`).
+		Where(`"user_role"."UserId" = ?`, userID).
+		Order(`"role"."Name"`).
+		Pluck("role.Name", &names).Error
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return nil, err
+	}
+
+	log.Info("GetNamesByUserID executed successfully")
+	return names, nil
+}
+
+func (rr *roleRepository) CountUsers(roleID string) (int64, error) {
+	log := slog.With(
+		slog.String("func", "CountUsers"),
+		slog.String("repository", "role"))
+
+	log.Info("CountUsers initiated")
+
+	var count int64
+	err := rr.db.Model(&domain.UserRole{}).Where(`"RoleId" = ?`, roleID).Count(&count).Error
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return 0, err
+	}
+
+	log.Info("CountUsers executed successfully")
+	return count, nil
+}
+
+func (rr *roleRepository) Assign(userRole domain.UserRole) (bool, error) {
+	log := slog.With(
+		slog.String("func", "Assign"),
+		slog.String("repository", "role"))
+
+	log.Info("Assign initiated")
+
+	userRole.CreatedAt = time.Now()
+
+	result := rr.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&userRole)
+	if result.Error != nil {
+		log.Error("Error: ", slog.Any("error", result.Error))
+		return false, result.Error
+	}
+
+	log.Info("Assign executed successfully")
+	return result.RowsAffected == 1, nil
+}
+
+func (rr *roleRepository) Revoke(userID string, roleID string) (bool, error) {
+	log := slog.With(
+		slog.String("func", "Revoke"),
+		slog.String("repository", "role"))
+
+	log.Info("Revoke initiated")
+
+	result := rr.db.Where(`"UserId" = ? AND "RoleId" = ?`, userID, roleID).Delete(&domain.UserRole{})
+	if result.Error != nil {
+		log.Error("Error: ", slog.Any("error", result.Error))
+		return false, result.Error
+	}
+
+	log.Info("Revoke executed successfully")
+	return result.RowsAffected == 1, nil
+}
@@ -0,0 +1,136 @@
+package repository
+
+import (
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/OVillas/autentication/domain"
+	"github.com/samber/do"
+	"gorm.io/gorm"
+)
+
+type trustedDeviceRepository struct {
+	i  *do.Injector
+	db *gorm.DB
+}
+
+func NewTrustedDeviceRepository(i *do.Injector) (domain.TrustedDeviceRepository, error) {
+	db := do.MustInvoke[*gorm.DB](i)
+	return &trustedDeviceRepository{
+		db: db,
+		i:  i,
+	}, nil
+}
+
+func (tdr *trustedDeviceRepository) Create(trustedDevice domain.TrustedDevice) error {
+	log := slog.With(
+		slog.String("func", "Create"),
+		slog.String("repository", "trustedDevice"))
+
+	log.Info("Create initiated")
+
+	trustedDevice.CreatedAt = time.Now()
+
+	if err := tdr.db.Create(&trustedDevice).Error; err != nil {
+		log.Error("Error to create trusted device in database", slog.Any("error", err))
+		return err
+	}
+
+	log.Info("Create executed successfully")
+	return nil
+}
+
+func (tdr *trustedDeviceRepository) GetByTokenHash(tokenHash string) (*domain.TrustedDevice, error) {
+	log := slog.With(
+		slog.String("func", "GetByTokenHash"),
+		slog.String("repository", "trustedDevice"))
+
+	log.Info("GetByTokenHash initiated")
+
+	var trustedDevice domain.TrustedDevice
+	err := tdr.db.Where(`"TokenHash" = ?`, tokenHash).First(&trustedDevice).Error
+
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		log.Error("Error: ", slog.Any("error", err))
+		return nil, err
+	}
+
+	log.Info("GetByTokenHash executed successfully")
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+
+	return &trustedDevice, nil
+}
+
+func (tdr *trustedDeviceRepository) GetActiveByUserID(userID string) ([]domain.TrustedDevice, error) {
+	log := slog.With(
+		slog.String("func", "GetActiveByUserID"),
+		slog.String("repository", "trustedDevice"))
+
+	log.Info("GetActiveByUserID initiated")
+
+	var trustedDevices []domain.TrustedDevice
+	err := tdr.db.Where(`"UserId" = ? AND "ExpiresAt" > ?`, userID, time.Now()).
+		Order(`"CreatedAt" DESC`).
+		Find(&trustedDevices).Error
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return nil, err
+	}
+
+	log.Info("GetActiveByUserID executed successfully")
+	return trustedDevices, nil
+}
+
+func (tdr *trustedDeviceRepository) UpdateLastUsedAt(id string) error {
+	log := slog.With(
+		slog.String("func", "UpdateLastUsedAt"),
+		slog.String("repository", "trustedDevice"))
+
+	log.Info("UpdateLastUsedAt initiated")
+
+	err := tdr.db.Model(&domain.TrustedDevice{}).Where(`"Id" = ?`, id).Update("LastUsedAt", time.Now()).Error
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return err
+	}
+
+	log.Info("UpdateLastUsedAt executed successfully")
+	return nil
+}
+
+// Delete only removes the device when it belongs to userID, reporting whether it did.
+func (tdr *trustedDeviceRepository) Delete(userID string, id string) (bool, error) {
+	log := slog.With(
+		slog.String("func", "Delete"),
+		slog.String("repository", "trustedDevice"))
+
+	log.Info("Delete initiated")
+
+	result := tdr.db.Where(`"Id" = ? AND "UserId" = ?`, id, userID).Delete(&domain.TrustedDevice{})
+	if result.Error != nil {
+		log.Error("Error: ", slog.Any("error", result.Error))
+		return false, result.Error
+	}
+
+	log.Info("Delete executed successfully")
+	return result.RowsAffected == 1, nil
+}
+
+func (tdr *trustedDeviceRepository) DeleteByUserID(userID string) error {
+	log := slog.With(
+		slog.String("func", "DeleteByUserID"),
+		slog.String("repository", "trustedDevice"))
+
+	log.Info("DeleteByUserID initiated")
+
+	if err := tdr.db.Where(`"UserId" = ?`, userID).Delete(&domain.TrustedDevice{}).Error; err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return err
+	}
+
+	log.Info("DeleteByUserID executed successfully")
+	return nil
+}
@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/OVillas/autentication/domain"
+	"github.com/samber/do"
+	"gorm.io/gorm"
+)
+
+type unitOfWork struct {
+	i                          *do.Injector
+	db                         *gorm.DB
+	confirmationCodeRepository domain.ConfirmationCodeRepository
+}
+
+func NewUnitOfWork(i *do.Injector) (domain.UnitOfWork, error) {
+	db := do.MustInvoke[*gorm.DB](i)
+	confirmationCodeRepository := do.MustInvoke[domain.ConfirmationCodeRepository](i)
+	return &unitOfWork{
+		i:                          i,
+		db:                         db,
+		confirmationCodeRepository: confirmationCodeRepository,
+	}, nil
+}
+
+func (uow *unitOfWork) Do(ctx context.Context, fn func(tx domain.TxRepositories) error) error {
+	log := slog.With(
+		slog.String("func", "Do"),
+		slog.String("repository", "unitOfWork"))
+
+	return uow.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Only the codes kept in the database can join the transaction.
+		confirmationCodes := uow.confirmationCodeRepository
+		if _, ok := confirmationCodes.(*confirmationCodeRepository); ok {
+			confirmationCodes = &confirmationCodeRepository{i: uow.i, db: tx}
+		}
+
+		err := fn(domain.TxRepositories{
+			Users:             &userRepository{i: uow.i, db: tx},
+			ConfirmationCodes: confirmationCodes,
+		})
+		if err != nil {
+			log.Warn("Transaction rolled back", slog.Any("error", err))
+		}
+
+		return err
+	})
+}
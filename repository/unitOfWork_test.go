@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/OVillas/autentication/domain"
+	"github.com/samber/do"
+	"gorm.io/gorm"
+)
+
+var errCodeStore = errors.New("code store unavailable")
+
+// failConfirmationCodeWrites makes every insert of a confirmation code fail until the test ends.
+func failConfirmationCodeWrites(t *testing.T) {
+	t.Helper()
+
+	const name = "test:fail_confirmation_code"
+	err := testDB.Callback().Create().Before("gorm:create").Register(name, func(tx *gorm.DB) {
+		if tx.Statement.Table == (domain.ConfirmationCode{}).TableName() {
+			_ = tx.AddError(errCodeStore)
+		}
+	})
+	if err != nil {
+		t.Fatalf("register the failing callback: %v", err)
+	}
+
+	t.Cleanup(func() {
+		_ = testDB.Callback().Create().Remove(name)
+	})
+}
+
+func newTestUnitOfWork() domain.UnitOfWork {
+	i := newTestInjector()
+	do.Provide(i, NewConfirmationCodeRepository)
+	unitOfWork, _ := NewUnitOfWork(i)
+	return unitOfWork
+}
+
+// register does what the registration does in its transaction: it creates the user, reserves a
+// send of its confirmation code and stores the code.
+func register(ctx context.Context, tx domain.TxRepositories, user domain.User) error {
+	if err := tx.Users.Create(ctx, user); err != nil {
+		return err
+	}
+
+	if _, err := tx.ConfirmationCodes.ReserveSend(user.TenantID, user.Email, time.Minute, domain.CodeSendWindow, 10); err != nil {
+		return err
+	}
+
+	return tx.ConfirmationCodes.Set(user.TenantID, user.Email, "code hash", time.Hour)
+}
+
+func TestUnitOfWorkCommit(t *testing.T) {
+	ctx := context.Background()
+	user := newUnsavedUser()
+
+	err := newTestUnitOfWork().Do(ctx, func(tx domain.TxRepositories) error {
+		return register(ctx, tx, user)
+	})
+	if err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+
+	userRepository, _ := NewUserRepository(newTestInjector())
+	if found, err := userRepository.GetById(ctx, user.ID); err != nil || found == nil {
+		t.Fatalf("GetById = %v, %v, want the user", found, err)
+	}
+
+	confirmationCodeRepository, _ := NewConfirmationCodeRepository(newTestInjector())
+	if code, err := confirmationCodeRepository.Get(user.TenantID, user.Email); err != nil || code == nil {
+		t.Fatalf("Get = %v, %v, want the code", code, err)
+	}
+}
+
+func TestUnitOfWorkRollbackWhenTheCodeStoreFails(t *testing.T) {
+	ctx := context.Background()
+	user := newUnsavedUser()
+	failConfirmationCodeWrites(t)
+
+	err := newTestUnitOfWork().Do(ctx, func(tx domain.TxRepositories) error {
+		return register(ctx, tx, user)
+	})
+	if !errors.Is(err, errCodeStore) {
+		t.Fatalf("Do = %v, want %v", err, errCodeStore)
+	}
+
+	var users int64
+	if err := testDB.Unscoped().Model(&domain.User{}).Where(&domain.User{ID: user.ID}).Count(&users).Error; err != nil {
+		t.Fatalf("count the users: %v", err)
+	}
+
+	if users != 0 {
+		t.Errorf("%d user rows remain, want the user rolled back", users)
+	}
+
+	var sends int64
+	err = testDB.Model(&domain.ConfirmationCodeSend{}).
+		Where(&domain.ConfirmationCodeSend{TenantID: user.TenantID, Email: user.Email}).Count(&sends).Error
+	if err != nil {
+		t.Fatalf("count the code sends: %v", err)
+	}
+
+	if sends != 0 {
+		t.Errorf("%d code sends remain, want the reservation rolled back", sends)
+	}
+}
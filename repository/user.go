@@ -1,13 +1,17 @@
 package repository
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"log/slog"
+	"strings"
 	"time"
 
 	"github.com/OVillas/autentication/domain"
 	"github.com/samber/do"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type userRepository struct {
@@ -23,55 +27,86 @@ func NewUserRepository(i *do.Injector) (domain.UserRepository, error) {
 	}, nil
 }
 
-func (ur *userRepository) Create(user domain.User) error {
+func (ur *userRepository) Create(ctx context.Context, user domain.User) error {
 	log := slog.With(
 		slog.String("func", "Create"),
 		slog.String("repository", "user"))
 
 	log.Info("Create initiated")
 
-	now := time.Now()
-	user.CreatedAt = now
-	user.UpdateAt = now
+	// In a transaction of its own, a savepoint when ur.db already is one: on Postgres a failed
+	// insert aborts the transaction, and takenError still has to query it.
+	err := ur.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return tx.Create(&user).Error
+	})
 
-	result := ur.db.Create(&user)
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		log.Warn("Email or username already taken")
+		return takenError(ur.db.WithContext(ctx), user.TenantID, map[string]interface{}{"Email": user.Email, "Username": user.Username}, "")
+	}
 
-	if result.Error != nil {
-		log.Error("Error to create user in database", result.Error)
-		return result.Error
+	if err != nil {
+		log.Error("Error to create user in database", slog.Any("error", err))
+		return err
 	}
 
 	log.Info("create executed successfully")
 	return nil
 }
 
-func (ur *userRepository) GetAll() ([]domain.User, error) {
+// GetAll returns one page of the users of the tenant matched by the filters of query, in its
+// order, and how many match in total. See paginate for the size of the page.
+func (ur *userRepository) GetAll(ctx context.Context, tenantID string, query domain.UserListQuery) ([]domain.User, int64, error) {
 	log := slog.With(
 		slog.String("func", "GetAll"),
 		slog.String("repository", "user"))
 
 	log.Info("GetAll initiated")
 
-	var users []domain.User
+	db := ur.db.WithContext(ctx).Model(&domain.User{}).Where(`"TenantId" = ?`, tenantID)
+	if query.Search != "" {
+		// LOWER keeps the search case-insensitive on Postgres too, where LIKE is not.
+		searchPattern := "%" + strings.ToLower(query.Search) + "%"
+		db = db.Where(`(LOWER("Name") LIKE ? OR LOWER("Username") LIKE ? OR LOWER("Email") LIKE ?)`, searchPattern, searchPattern, searchPattern)
+	}
 
-	result := ur.db.Find(&users)
+	if query.EmailConfirmed != nil {
+		db = db.Where(`"EmailConfirmed" = ?`, *query.EmailConfirmed)
+	}
 
-	err := result.Error
+	if query.TwoFactor != nil {
+		db = db.Where(`"TwoFactorAuthActive" = ?`, *query.TwoFactor)
+	}
 
-	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
-		log.Error("Error: ", err)
-		return nil, err
+	if query.CreatedFrom != nil {
+		db = db.Where(`"CreatedAt" >= ?`, *query.CreatedFrom)
 	}
 
-	log.Info("GetAll executed successfully")
-	if errors.Is(err, gorm.ErrRecordNotFound) {
-		return nil, nil
+	if query.CreatedTo != nil {
+		db = db.Where(`"CreatedAt" <= ?`, *query.CreatedTo)
 	}
 
-	return users, nil
+	if query.NeverLoggedIn != nil && *query.NeverLoggedIn {
+		db = db.Where(`"LastLoginAt" IS NULL`)
+	} else if query.NeverLoggedIn != nil {
+		db = db.Where(`"LastLoginAt" IS NOT NULL`)
+	}
+
+	if query.InactiveDays > 0 {
+		db = db.Where(`COALESCE("LastLoginAt", "CreatedAt") < ?`, time.Now().AddDate(0, 0, -query.InactiveDays))
+	}
+
+	users, total, err := paginate(db, query)
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return nil, 0, err
+	}
+
+	log.Info("GetAll executed successfully")
+	return users, total, nil
 }
 
-func (ur *userRepository) GetById(id string) (*domain.User, error) {
+func (ur *userRepository) GetById(ctx context.Context, id string) (*domain.User, error) {
 	log := slog.With(
 		slog.String("func", "GetById"),
 		slog.String("repository", "user"))
@@ -79,7 +114,7 @@ func (ur *userRepository) GetById(id string) (*domain.User, error) {
 	log.Info("GetById initiated")
 
 	var user domain.User
-	err := ur.db.Where("id = ?", id).First(&user).Error
+	err := ur.db.WithContext(ctx).Where(`"Id" = ?`, id).First(&user).Error
 
 	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
 		return nil, err
@@ -93,7 +128,7 @@ func (ur *userRepository) GetById(id string) (*domain.User, error) {
 	return &user, nil
 }
 
-func (ur *userRepository) GetByUsername(username string) (*domain.User, error) {
+func (ur *userRepository) GetByUsername(ctx context.Context, tenantID string, username string) (*domain.User, error) {
 	log := slog.With(
 		slog.String("func", "GetByUsername"),
 		slog.String("repository", "user"))
@@ -101,10 +136,10 @@ func (ur *userRepository) GetByUsername(username string) (*domain.User, error) {
 	log.Info("GetByUsername initiated")
 
 	var user domain.User
-	err := ur.db.Where("username = ?", username).First(&user).Error
+	err := ur.db.WithContext(ctx).Where(`"TenantId" = ? AND "Username" = ?`, tenantID, domain.NormalizeUsername(username)).First(&user).Error
 
 	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
-		log.Error("Error: ", err)
+		log.Error("Error: ", slog.Any("error", err))
 		return nil, err
 	}
 
@@ -116,31 +151,68 @@ func (ur *userRepository) GetByUsername(username string) (*domain.User, error) {
 	return &user, nil
 }
 
-func (ur *userRepository) GetByNameOrUsername(nameOrUsername string) ([]domain.User, error) {
+// IsUsernameTaken and IsEmailTaken also count the soft deleted users, whose username and email
+// stay theirs until they are purged. Other tenants are free to hold the same ones.
+func (ur *userRepository) IsUsernameTaken(ctx context.Context, tenantID string, username string) (bool, error) {
+	log := slog.With(
+		slog.String("func", "IsUsernameTaken"),
+		slog.String("repository", "user"))
+
+	log.Info("IsUsernameTaken initiated")
+
+	var count int64
+	if err := ur.db.WithContext(ctx).Unscoped().Model(&domain.User{}).Where(`"TenantId" = ? AND "Username" = ?`, tenantID, domain.NormalizeUsername(username)).Count(&count).Error; err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return false, err
+	}
+
+	log.Info("IsUsernameTaken executed successfully")
+	return count > 0, nil
+}
+
+func (ur *userRepository) IsEmailTaken(ctx context.Context, tenantID string, email string) (bool, error) {
+	log := slog.With(
+		slog.String("func", "IsEmailTaken"),
+		slog.String("repository", "user"))
+
+	log.Info("IsEmailTaken initiated")
+
+	var count int64
+	if err := ur.db.WithContext(ctx).Unscoped().Model(&domain.User{}).Where(`"TenantId" = ? AND "Email" = ?`, tenantID, domain.NormalizeEmail(email)).Count(&count).Error; err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return false, err
+	}
+
+	log.Info("IsEmailTaken executed successfully")
+	return count > 0, nil
+}
+
+// GetByNameOrUsername returns one page of the active users of the tenant whose name or username
+// contains nameOrUsername, and how many match in total. See paginate for the size of the page.
+func (ur *userRepository) GetByNameOrUsername(ctx context.Context, tenantID string, nameOrUsername string, pageRequest domain.PageRequest) ([]domain.User, int64, error) {
 	log := slog.With(
 		slog.String("func", "GetByNameOrUsername"),
 		slog.String("repository", "user"))
 
 	log.Info("GetByNameOrUseraname initiated")
 
-	var users []domain.User
-	searchPattern := "%" + nameOrUsername + "%"
-	err := ur.db.Where("name LIKE ? OR username LIKE ?", searchPattern, searchPattern).Find(&users).Error
+	searchPattern := "%" + strings.ToLower(nameOrUsername) + "%"
+	query := ur.db.WithContext(ctx).Model(&domain.User{}).
+		Where(`"TenantId" = ?`, tenantID).
+		Where(`LOWER("Name") LIKE ? OR LOWER("Username") LIKE ?`, searchPattern, searchPattern).
+		Where(`"Active" = ?`, true)
 
-	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
-		log.Error("Error: ", err)
-		return nil, err
+	users, total, err := paginate(query, domain.UserListQuery{PageRequest: pageRequest})
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return nil, 0, err
 	}
 
 	log.Info("GetByNameOrUsername executed successfully")
-	if errors.Is(err, gorm.ErrRecordNotFound) {
-		return nil, nil
-	}
-
-	return users, nil
+	return users, total, nil
 }
 
-func (ur *userRepository) GetByEmail(email string) (*domain.User, error) {
+func (ur *userRepository) GetByEmail(ctx context.Context, tenantID string, email string) (*domain.User, error) {
 	log := slog.With(
 		slog.String("func", "GetByEmail"),
 		slog.String("repository", "user"))
@@ -148,10 +220,10 @@ func (ur *userRepository) GetByEmail(email string) (*domain.User, error) {
 	log.Info("GetByEmail initiated")
 
 	var user domain.User
-	err := ur.db.Where("email = ?", email).First(&user).Error
+	err := ur.db.WithContext(ctx).Where(`"TenantId" = ? AND "Email" = ?`, tenantID, domain.NormalizeEmail(email)).First(&user).Error
 
 	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
-		log.Error("Error: ", err)
+		log.Error("Error: ", slog.Any("error", err))
 		return nil, err
 	}
 
@@ -163,15 +235,61 @@ func (ur *userRepository) GetByEmail(email string) (*domain.User, error) {
 	return &user, nil
 }
 
-func (ur *userRepository) Update(id string, user domain.User) error {
+// updatableUserColumns are the columns Update may change. Everything else, like the password or
+// the two-factor settings, has a method of its own.
+var updatableUserColumns = map[string]bool{"Name": true, "Username": true, "Email": true, "Locale": true, "Timezone": true}
+
+// Update loads the user and sets only the columns in changes, so the fields left out of an update
+// keep their values, and dates the change in UpdatedAt. A new email is not confirmed yet, so it
+// resets EmailConfirmed.
+func (ur *userRepository) Update(ctx context.Context, id string, changes map[string]interface{}) error {
 	log := slog.With(
-		slog.String("func", "Create"),
+		slog.String("func", "Update"),
 		slog.String("repository", "user"))
 	log.Info("Update initiated")
 
-	err := ur.db.Model(&domain.User{}).Where("id = ?", id).Updates(domain.User{Name: user.Name, Email: user.Email}).Error
+	err := ur.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var user domain.User
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where(`"Id" = ?`, id).First(&user).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return domain.ErrUserNotFound
+		}
+
+		if err != nil {
+			return err
+		}
+
+		columns := map[string]interface{}{}
+		for column, value := range changes {
+			if !updatableUserColumns[column] {
+				return fmt.Errorf("column %s cannot be updated", column)
+			}
+			columns[column] = value
+		}
+
+		if username, ok := columns["Username"].(string); ok {
+			columns["Username"] = domain.NormalizeUsername(username)
+		}
+
+		if email, ok := columns["Email"].(string); ok {
+			email = domain.NormalizeEmail(email)
+			columns["Email"] = email
+			if email != user.Email {
+				columns["EmailConfirmed"] = false
+			}
+		}
+
+		columns["UpdatedAt"] = time.Now()
+		err = tx.Transaction(func(tx *gorm.DB) error {
+			return tx.Model(&user).Updates(columns).Error
+		})
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return takenError(tx, user.TenantID, columns, id)
+		}
+		return err
+	})
 	if err != nil {
-		log.Error("Error: ", err)
+		log.Error("Error: ", slog.Any("error", err))
 		return err
 	}
 
@@ -179,16 +297,18 @@ func (ur *userRepository) Update(id string, user domain.User) error {
 	return nil
 }
 
-func (ur *userRepository) Delete(id string) error {
+// Delete soft deletes the user: it is kept, with DeletedAt set, but left out of every other
+// query until Restore or PurgeDeleted.
+func (ur *userRepository) Delete(ctx context.Context, id string) error {
 	log := slog.With(
 		slog.String("func", "Delete"),
 		slog.String("repository", "user"))
 
 	log.Info("Delete initiated")
 
-	err := ur.db.Delete(&domain.User{}, "id = ?", id).Error
-	if err != nil {
-		log.Error("Error: ", err)
+	result := ur.db.WithContext(ctx).Delete(&domain.User{}, `"Id" = ?`, id)
+	if err := updatedUser(ur.db.WithContext(ctx), result, id); err != nil {
+		log.Error("Error: ", slog.Any("error", err))
 		return err
 	}
 
@@ -196,16 +316,275 @@ func (ur *userRepository) Delete(id string) error {
 	return nil
 }
 
-func (ur *userRepository) UpdatePassword(id string, password string) error {
+// GetDeletedByEmail returns the soft deleted user of the tenant holding email, which stays unique
+// until the user is purged.
+func (ur *userRepository) GetDeletedByEmail(ctx context.Context, tenantID string, email string) (*domain.User, error) {
+	log := slog.With(
+		slog.String("func", "GetDeletedByEmail"),
+		slog.String("repository", "user"))
+
+	log.Info("GetDeletedByEmail initiated")
+
+	var user domain.User
+	err := ur.db.WithContext(ctx).Unscoped().Where(`"TenantId" = ? AND "Email" = ? AND "DeletedAt" IS NOT NULL`, tenantID, domain.NormalizeEmail(email)).First(&user).Error
+
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		log.Error("Error: ", slog.Any("error", err))
+		return nil, err
+	}
+
+	log.Info("GetDeletedByEmail executed successfully")
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+
+	return &user, nil
+}
+
+// Restore undoes Delete. It reports false when the user is not soft deleted.
+func (ur *userRepository) Restore(ctx context.Context, tenantID string, id string) (bool, error) {
+	log := slog.With(
+		slog.String("func", "Restore"),
+		slog.String("repository", "user"))
+
+	log.Info("Restore initiated")
+
+	result := ur.db.WithContext(ctx).Unscoped().Model(&domain.User{}).
+		Where(`"TenantId" = ? AND "Id" = ? AND "DeletedAt" IS NOT NULL`, tenantID, id).
+		Updates(map[string]any{"DeletedAt": nil, "UpdatedAt": time.Now()})
+	if result.Error != nil {
+		log.Error("Error: ", slog.Any("error", result.Error))
+		return false, result.Error
+	}
+
+	log.Info("Restore executed successfully")
+	return result.RowsAffected == 1, nil
+}
+
+// PurgeDeleted permanently removes the users soft deleted before deletedBefore, as Purge does,
+// and returns how many were removed.
+func (ur *userRepository) PurgeDeleted(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	log := slog.With(
+		slog.String("func", "PurgeDeleted"),
+		slog.String("repository", "user"))
+
+	log.Info("PurgeDeleted initiated")
+
+	var ids []string
+	err := ur.db.WithContext(ctx).Unscoped().Model(&domain.User{}).Where(`"DeletedAt" <= ?`, deletedBefore).Pluck("Id", &ids).Error
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return 0, err
+	}
+
+	var purged int64
+	for _, id := range ids {
+		if err := ur.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error { return purgeUser(tx, id) }); err != nil {
+			log.Error("Error: ", slog.Any("error", err))
+			return purged, err
+		}
+		purged++
+	}
+
+	log.Info("PurgeDeleted executed successfully")
+	return purged, nil
+}
+
+// Purge permanently removes the user, deleted or not, with every row it owns.
+func (ur *userRepository) Purge(ctx context.Context, id string) error {
+	log := slog.With(
+		slog.String("func", "Purge"),
+		slog.String("repository", "user"))
+
+	log.Info("Purge initiated")
+
+	if err := ur.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error { return purgeUser(tx, id) }); err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return err
+	}
+
+	log.Info("Purge executed successfully")
+	return nil
+}
+
+// Anonymize deletes every row the user owns and replaces its personal data with placeholders,
+// keeping only the ID. The password is made unusable and the access tokens of the user end.
+func (ur *userRepository) Anonymize(ctx context.Context, id string) error {
+	log := slog.With(
+		slog.String("func", "Anonymize"),
+		slog.String("repository", "user"))
+
+	log.Info("Anonymize initiated")
+
+	err := ur.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var user domain.User
+		if err := tx.Where(`"Id" = ?`, id).First(&user).Error; err != nil {
+			return err
+		}
+
+		if err := deleteOwnedRows(tx, user); err != nil {
+			return err
+		}
+
+		return tx.Model(&domain.User{}).Where(`"Id" = ?`, id).Updates(map[string]any{
+			"Name":                domain.AnonymizedName,
+			"Username":            domain.AnonymizedUsername(id),
+			"Email":               domain.AnonymizedEmail(id),
+			"PasswordHash":        domain.UnusablePasswordPrefix,
+			"EmailConfirmed":      false,
+			"PhoneNumber":         nil,
+			"PhoneConfirmed":      false,
+			"TwoFactorAuthActive": false,
+			"TotpSecret":          "",
+			"LoginAlertsEnabled":  false,
+			"Locale":              "",
+			"Timezone":            "",
+			"AvatarUrl":           nil,
+			"Metadata":            nil,
+			"DeletionScheduledAt": nil,
+			"DeletionRemindedAt":  nil,
+			"AnonymizedAt":        time.Now(),
+			"TokenVersion":        gorm.Expr(`"TokenVersion" + 1`),
+		}).Error
+	})
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return err
+	}
+
+	log.Info("Anonymize executed successfully")
+	return nil
+}
+
+func (ur *userRepository) ScheduleDeletion(ctx context.Context, id string, at time.Time) error {
+	log := slog.With(
+		slog.String("func", "ScheduleDeletion"),
+		slog.String("repository", "user"))
+
+	log.Info("ScheduleDeletion initiated")
+
+	err := ur.db.WithContext(ctx).Model(&domain.User{}).Where(`"Id" = ?`, id).
+		Updates(map[string]any{"DeletionScheduledAt": at, "DeletionRemindedAt": nil}).Error
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return err
+	}
+
+	log.Info("ScheduleDeletion executed successfully")
+	return nil
+}
+
+// CancelDeletion reports false when no deletion of the user was scheduled.
+func (ur *userRepository) CancelDeletion(ctx context.Context, id string) (bool, error) {
+	log := slog.With(
+		slog.String("func", "CancelDeletion"),
+		slog.String("repository", "user"))
+
+	log.Info("CancelDeletion initiated")
+
+	result := ur.db.WithContext(ctx).Model(&domain.User{}).Where(`"Id" = ? AND "DeletionScheduledAt" IS NOT NULL`, id).
+		Updates(map[string]any{"DeletionScheduledAt": nil, "DeletionRemindedAt": nil})
+	if result.Error != nil {
+		log.Error("Error: ", slog.Any("error", result.Error))
+		return false, result.Error
+	}
+
+	log.Info("CancelDeletion executed successfully")
+	return result.RowsAffected == 1, nil
+}
+
+// GetDeletionReminderDue returns the users whose deletion is scheduled before before and who
+// were not reminded of it yet.
+func (ur *userRepository) GetDeletionReminderDue(ctx context.Context, before time.Time) ([]domain.User, error) {
+	log := slog.With(
+		slog.String("func", "GetDeletionReminderDue"),
+		slog.String("repository", "user"))
+
+	log.Info("GetDeletionReminderDue initiated")
+
+	var users []domain.User
+	err := ur.db.WithContext(ctx).Where(`"DeletionScheduledAt" <= ? AND "DeletionRemindedAt" IS NULL`, before).Find(&users).Error
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return nil, err
+	}
+
+	log.Info("GetDeletionReminderDue executed successfully")
+	return users, nil
+}
+
+func (ur *userRepository) SetDeletionReminded(ctx context.Context, id string) error {
+	log := slog.With(
+		slog.String("func", "SetDeletionReminded"),
+		slog.String("repository", "user"))
+
+	log.Info("SetDeletionReminded initiated")
+
+	err := ur.db.WithContext(ctx).Model(&domain.User{}).Where(`"Id" = ?`, id).Update("DeletionRemindedAt", time.Now()).Error
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return err
+	}
+
+	log.Info("SetDeletionReminded executed successfully")
+	return nil
+}
+
+// GetDeletionDue returns the users whose scheduled deletion is due at before.
+func (ur *userRepository) GetDeletionDue(ctx context.Context, before time.Time) ([]domain.User, error) {
+	log := slog.With(
+		slog.String("func", "GetDeletionDue"),
+		slog.String("repository", "user"))
+
+	log.Info("GetDeletionDue initiated")
+
+	var users []domain.User
+	err := ur.db.WithContext(ctx).Where(`"DeletionScheduledAt" <= ?`, before).Find(&users).Error
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return nil, err
+	}
+
+	log.Info("GetDeletionDue executed successfully")
+	return users, nil
+}
+
+// GetUnverified returns, by ID, up to limit of the users created before createdBefore that never
+// confirmed their email nor logged in, past afterID so the callers can go through them in
+// batches. Anonymized users are left out.
+func (ur *userRepository) GetUnverified(ctx context.Context, createdBefore time.Time, afterID string, limit int) ([]domain.User, error) {
+	log := slog.With(
+		slog.String("func", "GetUnverified"),
+		slog.String("repository", "user"))
+
+	log.Info("GetUnverified initiated")
+
+	var users []domain.User
+	err := ur.db.WithContext(ctx).
+		Where(`"EmailConfirmed" = ? AND "CreatedAt" < ? AND "Id" > ?`, false, createdBefore, afterID).
+		Where(`"LastLoginAt" IS NULL AND "AnonymizedAt" IS NULL`).
+		Where("NOT EXISTS (?)", ur.db.WithContext(ctx).Model(&domain.LoginAttempt{}).Select("1").
+			Where(`"login_attempt"."UserId" = ? AND "login_attempt"."Outcome" = ?`, clause.Column{Table: "user", Name: "Id"}, domain.LoginOutcomeSuccess)).
+		Order(`"Id"`).Limit(limit).Find(&users).Error
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return nil, err
+	}
+
+	log.Info("GetUnverified executed successfully")
+	return users, nil
+}
+
+func (ur *userRepository) UpdatePassword(ctx context.Context, id string, password string) error {
 	log := slog.With(
 		slog.String("func", "updatePassword"),
 		slog.String("repository", "user"))
 
 	log.Info("UpdatePassword initiated")
 
-	err := ur.db.Model(&domain.User{}).Where("id = ?", id).Updates(domain.User{Password: password}).Error
-	if err != nil {
-		log.Error("Error: ", err)
+	result := ur.db.WithContext(ctx).Model(&domain.User{}).Where(`"Id" = ?`, id).Updates(domain.User{Password: password})
+	if err := updatedUser(ur.db.WithContext(ctx), result, id); err != nil {
+		log.Error("Error: ", slog.Any("error", err))
 		return err
 	}
 
@@ -213,19 +592,590 @@ func (ur *userRepository) UpdatePassword(id string, password string) error {
 	return nil
 }
 
-func (ur *userRepository) ConfirmedEmail(id string) error {
+// RehashPassword only replaces the hash if it is still currentHash, so a password changed in the
+// meantime is never overwritten by the old one.
+func (ur *userRepository) RehashPassword(ctx context.Context, id string, currentHash string, newHash string) (bool, error) {
+	log := slog.With(
+		slog.String("func", "RehashPassword"),
+		slog.String("repository", "user"))
+
+	log.Info("RehashPassword initiated")
+
+	result := ur.db.WithContext(ctx).Model(&domain.User{}).Where(`"Id" = ? AND "PasswordHash" = ?`, id, currentHash).
+		Update("PasswordHash", newHash)
+	if result.Error != nil {
+		log.Error("Error: ", slog.Any("error", result.Error))
+		return false, result.Error
+	}
+
+	log.Info("RehashPassword executed successfully")
+	return result.RowsAffected == 1, nil
+}
+
+func (ur *userRepository) ConfirmedEmail(ctx context.Context, id string) error {
 	log := slog.With(
 		slog.String("func", "ConfirmedEmail"),
 		slog.String("repository", "user"))
 
 	log.Info("ConfirmedEmail initiated")
 
-	err := ur.db.Model(&domain.User{}).Where("id = ?", id).Updates(domain.User{EmailConfirmed: true}).Error
-	if err != nil {
-		log.Error("Error: ", err)
+	result := ur.db.WithContext(ctx).Model(&domain.User{}).Where(`"Id" = ?`, id).Updates(domain.User{EmailConfirmed: true})
+	if err := updatedUser(ur.db.WithContext(ctx), result, id); err != nil {
+		log.Error("Error: ", slog.Any("error", err))
 		return err
 	}
 
 	log.Info("ConfirmedEmail executed successfully")
 	return nil
 }
+
+// UpdateEmail switches the account to an address the user has just proven, so it is stored as
+// confirmed.
+func (ur *userRepository) UpdateEmail(ctx context.Context, id string, email string) error {
+	log := slog.With(
+		slog.String("func", "UpdateEmail"),
+		slog.String("repository", "user"))
+
+	log.Info("UpdateEmail initiated")
+
+	err := ur.db.WithContext(ctx).Model(&domain.User{}).Where(`"Id" = ?`, id).
+		Updates(map[string]interface{}{"Email": domain.NormalizeEmail(email), "EmailConfirmed": true}).Error
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		log.Warn("Email already taken")
+		return domain.ErrEmailTaken
+	}
+
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return err
+	}
+
+	log.Info("UpdateEmail executed successfully")
+	return nil
+}
+
+// SetPhoneNumber stores phoneNumber unconfirmed, or removes the number when it is nil.
+func (ur *userRepository) SetPhoneNumber(ctx context.Context, id string, phoneNumber *string) error {
+	log := slog.With(
+		slog.String("func", "SetPhoneNumber"),
+		slog.String("repository", "user"))
+
+	log.Info("SetPhoneNumber initiated")
+
+	err := ur.db.WithContext(ctx).Model(&domain.User{}).Where(`"Id" = ?`, id).
+		Updates(map[string]interface{}{"PhoneNumber": phoneNumber, "PhoneConfirmed": false}).Error
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		log.Warn("Phone number already taken")
+		return domain.ErrPhoneTaken
+	}
+
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return err
+	}
+
+	log.Info("SetPhoneNumber executed successfully")
+	return nil
+}
+
+// ConfirmPhoneNumber marks the number of the user confirmed, provided it is still phoneNumber, and
+// reports whether it did.
+func (ur *userRepository) ConfirmPhoneNumber(ctx context.Context, id string, phoneNumber string) (bool, error) {
+	log := slog.With(
+		slog.String("func", "ConfirmPhoneNumber"),
+		slog.String("repository", "user"))
+
+	log.Info("ConfirmPhoneNumber initiated")
+
+	result := ur.db.WithContext(ctx).Model(&domain.User{}).Where(`"Id" = ? AND "PhoneNumber" = ?`, id, phoneNumber).
+		Update("PhoneConfirmed", true)
+	if result.Error != nil {
+		log.Error("Error: ", slog.Any("error", result.Error))
+		return false, result.Error
+	}
+
+	log.Info("ConfirmPhoneNumber executed successfully")
+	return result.RowsAffected == 1, nil
+}
+
+func (ur *userRepository) SetAvatarURL(ctx context.Context, id string, avatarURL *string) error {
+	log := slog.With(
+		slog.String("func", "SetAvatarURL"),
+		slog.String("repository", "user"))
+
+	log.Info("SetAvatarURL initiated")
+
+	if err := ur.db.WithContext(ctx).Model(&domain.User{}).Where(`"Id" = ?`, id).Update("AvatarUrl", avatarURL).Error; err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return err
+	}
+
+	log.Info("SetAvatarURL executed successfully")
+	return nil
+}
+
+// UpdateMetadata merges patch into section of the metadata of the user, as described in
+// domain.UserMetadata.Merge, and returns the metadata as updated. The row is locked meanwhile, so
+// concurrent updates of different keys are all kept.
+func (ur *userRepository) UpdateMetadata(ctx context.Context, id string, section string, patch map[string]any) (*domain.UserMetadata, error) {
+	log := slog.With(
+		slog.String("func", "UpdateMetadata"),
+		slog.String("repository", "user"))
+
+	log.Info("UpdateMetadata initiated")
+
+	var user domain.User
+	err := ur.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where(`"Id" = ?`, id).First(&user).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return domain.ErrUserNotFound
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if err := user.Metadata.Merge(section, patch); err != nil {
+			return err
+		}
+
+		return tx.Model(&user).Update("Metadata", user.Metadata).Error
+	})
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return nil, err
+	}
+
+	log.Info("UpdateMetadata executed successfully")
+	return &user.Metadata, nil
+}
+
+// IncrementFailedLogins counts one more failed login in a row, dated now, and returns the new
+// count.
+func (ur *userRepository) IncrementFailedLogins(ctx context.Context, id string) (int, error) {
+	log := slog.With(
+		slog.String("func", "IncrementFailedLogins"),
+		slog.String("repository", "user"))
+
+	log.Info("IncrementFailedLogins initiated")
+
+	var user domain.User
+	err := ur.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&domain.User{}).Where(`"Id" = ?`, id).Updates(map[string]interface{}{
+			"FailedLoginAttempts": gorm.Expr(`"FailedLoginAttempts" + 1`),
+			"LastFailedLoginAt":   time.Now(),
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Select("FailedLoginAttempts").First(&user, `"Id" = ?`, id).Error
+	})
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return 0, err
+	}
+
+	log.Info("IncrementFailedLogins executed successfully")
+	return user.FailedLoginAttempts, nil
+}
+
+// LockAccount refuses logins until the given time and starts counting failures from zero again.
+func (ur *userRepository) LockAccount(ctx context.Context, id string, until time.Time) error {
+	log := slog.With(
+		slog.String("func", "LockAccount"),
+		slog.String("repository", "user"))
+
+	log.Info("LockAccount initiated")
+
+	err := ur.db.WithContext(ctx).Model(&domain.User{}).Where(`"Id" = ?`, id).
+		Updates(map[string]interface{}{"FailedLoginAttempts": 0, "LockedUntil": until}).Error
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return err
+	}
+
+	log.Info("LockAccount executed successfully")
+	return nil
+}
+
+// ResetFailedLogins clears the failure count, the login delay and any lock.
+func (ur *userRepository) ResetFailedLogins(ctx context.Context, id string) error {
+	log := slog.With(
+		slog.String("func", "ResetFailedLogins"),
+		slog.String("repository", "user"))
+
+	log.Info("ResetFailedLogins initiated")
+
+	err := ur.db.WithContext(ctx).Model(&domain.User{}).Where(`"Id" = ?`, id).
+		Updates(map[string]interface{}{"FailedLoginAttempts": 0, "LastFailedLoginAt": nil, "LockedUntil": nil}).Error
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return err
+	}
+
+	log.Info("ResetFailedLogins executed successfully")
+	return nil
+}
+
+func (ur *userRepository) SetMustResetPassword(ctx context.Context, id string, mustReset bool) error {
+	log := slog.With(
+		slog.String("func", "SetMustResetPassword"),
+		slog.String("repository", "user"))
+
+	log.Info("SetMustResetPassword initiated")
+
+	err := ur.db.WithContext(ctx).Model(&domain.User{}).Where(`"Id" = ?`, id).Update("MustResetPassword", mustReset).Error
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return err
+	}
+
+	log.Info("SetMustResetPassword executed successfully")
+	return nil
+}
+
+func (ur *userRepository) SetLoginAlertsEnabled(ctx context.Context, id string, enabled bool) error {
+	log := slog.With(
+		slog.String("func", "SetLoginAlertsEnabled"),
+		slog.String("repository", "user"))
+
+	log.Info("SetLoginAlertsEnabled initiated")
+
+	err := ur.db.WithContext(ctx).Model(&domain.User{}).Where(`"Id" = ?`, id).Update("LoginAlertsEnabled", enabled).Error
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return err
+	}
+
+	log.Info("SetLoginAlertsEnabled executed successfully")
+	return nil
+}
+
+// SetLastLoginAt dates the last login of the user. It leaves UpdatedAt alone, as logging in does
+// not change the account.
+func (ur *userRepository) SetLastLoginAt(ctx context.Context, id string, at time.Time) error {
+	log := slog.With(
+		slog.String("func", "SetLastLoginAt"),
+		slog.String("repository", "user"))
+
+	log.Info("SetLastLoginAt initiated")
+
+	err := ur.db.WithContext(ctx).Model(&domain.User{}).Where(`"Id" = ?`, id).UpdateColumn("LastLoginAt", at).Error
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return err
+	}
+
+	log.Info("SetLastLoginAt executed successfully")
+	return nil
+}
+
+func (ur *userRepository) SetActive(ctx context.Context, id string, active bool) error {
+	log := slog.With(
+		slog.String("func", "SetActive"),
+		slog.String("repository", "user"))
+
+	log.Info("SetActive initiated")
+
+	err := ur.db.WithContext(ctx).Model(&domain.User{}).Where(`"Id" = ?`, id).Update("Active", active).Error
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return err
+	}
+
+	log.Info("SetActive executed successfully")
+	return nil
+}
+
+// Suspend marks the user suspended until until, or indefinitely when it is nil. It also ends the
+// access tokens of the user.
+func (ur *userRepository) Suspend(ctx context.Context, id string, until *time.Time) error {
+	log := slog.With(
+		slog.String("func", "Suspend"),
+		slog.String("repository", "user"))
+
+	log.Info("Suspend initiated")
+
+	err := ur.db.WithContext(ctx).Model(&domain.User{}).Where(`"Id" = ?`, id).
+		Updates(map[string]any{
+			"SuspendedAt":    time.Now(),
+			"SuspendedUntil": until,
+			"TokenVersion":   gorm.Expr(`"TokenVersion" + 1`),
+		}).Error
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return err
+	}
+
+	log.Info("Suspend executed successfully")
+	return nil
+}
+
+// Unsuspend reports false when the user was not suspended.
+func (ur *userRepository) Unsuspend(ctx context.Context, id string) (bool, error) {
+	log := slog.With(
+		slog.String("func", "Unsuspend"),
+		slog.String("repository", "user"))
+
+	log.Info("Unsuspend initiated")
+
+	result := ur.db.WithContext(ctx).Model(&domain.User{}).Where(`"Id" = ? AND "SuspendedAt" IS NOT NULL`, id).
+		Updates(map[string]any{"SuspendedAt": nil, "SuspendedUntil": nil})
+	if result.Error != nil {
+		log.Error("Error: ", slog.Any("error", result.Error))
+		return false, result.Error
+	}
+
+	log.Info("Unsuspend executed successfully")
+	return result.RowsAffected == 1, nil
+}
+
+func (ur *userRepository) IncrementTokenVersion(ctx context.Context, id string) error {
+	log := slog.With(
+		slog.String("func", "IncrementTokenVersion"),
+		slog.String("repository", "user"))
+
+	log.Info("IncrementTokenVersion initiated")
+
+	err := ur.db.WithContext(ctx).Model(&domain.User{}).Where(`"Id" = ?`, id).Update("TokenVersion", gorm.Expr(`"TokenVersion" + 1`)).Error
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return err
+	}
+
+	log.Info("IncrementTokenVersion executed successfully")
+	return nil
+}
+
+// IncrementTokenVersionByRole ends the access tokens of every user with the role.
+func (ur *userRepository) IncrementTokenVersionByRole(ctx context.Context, roleID string) error {
+	log := slog.With(
+		slog.String("func", "IncrementTokenVersionByRole"),
+		slog.String("repository", "user"))
+
+	log.Info("IncrementTokenVersionByRole initiated")
+
+	err := ur.db.WithContext(ctx).Model(&domain.User{}).
+		Where(`"Id" IN (?)`, ur.db.WithContext(ctx).Model(&domain.UserRole{}).Select("UserId").Where(`"RoleId" = ?`, roleID)).
+		Update("TokenVersion", gorm.Expr(`"TokenVersion" + 1`)).Error
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return err
+	}
+
+	log.Info("IncrementTokenVersionByRole executed successfully")
+	return nil
+}
+
+func (ur *userRepository) UpdateTOTPSecret(ctx context.Context, id string, secret string) error {
+	log := slog.With(
+		slog.String("func", "UpdateTOTPSecret"),
+		slog.String("repository", "user"))
+
+	log.Info("UpdateTOTPSecret initiated")
+
+	err := ur.db.WithContext(ctx).Model(&domain.User{}).Where(`"Id" = ?`, id).Update("TotpSecret", secret).Error
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return err
+	}
+
+	log.Info("UpdateTOTPSecret executed successfully")
+	return nil
+}
+
+// UseTOTPStep only moves the step forward, so two requests with the same code cannot both pass.
+func (ur *userRepository) UseTOTPStep(ctx context.Context, id string, step int64) (bool, error) {
+	log := slog.With(
+		slog.String("func", "UseTOTPStep"),
+		slog.String("repository", "user"))
+
+	log.Info("UseTOTPStep initiated")
+
+	result := ur.db.WithContext(ctx).Model(&domain.User{}).
+		Where(`"Id" = ? AND "LastTotpStep" < ?`, id, step).
+		Update("LastTotpStep", step)
+	if result.Error != nil {
+		log.Error("Error: ", slog.Any("error", result.Error))
+		return false, result.Error
+	}
+
+	log.Info("UseTOTPStep executed successfully")
+	return result.RowsAffected == 1, nil
+}
+
+// ActivateTwoFactor only succeeds while the pending secret is still the one that was confirmed,
+// so a concurrent re-enrollment cannot be activated with a code from the replaced secret.
+func (ur *userRepository) ActivateTwoFactor(ctx context.Context, id string, secret string) (bool, error) {
+	log := slog.With(
+		slog.String("func", "ActivateTwoFactor"),
+		slog.String("repository", "user"))
+
+	log.Info("ActivateTwoFactor initiated")
+
+	result := ur.db.WithContext(ctx).Model(&domain.User{}).
+		Where(`"Id" = ? AND "TotpSecret" = ? AND "TwoFactorAuthActive" = ?`, id, secret, false).
+		Update("TwoFactorAuthActive", true)
+	if result.Error != nil {
+		log.Error("Error: ", slog.Any("error", result.Error))
+		return false, result.Error
+	}
+
+	log.Info("ActivateTwoFactor executed successfully")
+	return result.RowsAffected == 1, nil
+}
+
+func (ur *userRepository) DisableTwoFactor(ctx context.Context, id string) error {
+	log := slog.With(
+		slog.String("func", "DisableTwoFactor"),
+		slog.String("repository", "user"))
+
+	log.Info("DisableTwoFactor initiated")
+
+	err := ur.db.WithContext(ctx).Model(&domain.User{}).Where(`"Id" = ?`, id).Updates(map[string]interface{}{
+		"TwoFactorAuthActive": false,
+		"TotpSecret":          "",
+	}).Error
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return err
+	}
+
+	log.Info("DisableTwoFactor executed successfully")
+	return nil
+}
+
+// paginate counts the users matched by query and reads one page of them in the order of
+// listQuery, its sort column followed by Id, and one user more when another page follows.
+func paginate(query *gorm.DB, listQuery domain.UserListQuery) ([]domain.User, int64, error) {
+	query = query.Session(&gorm.Session{})
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// The column comes from domain.UserSortColumns, never from the request.
+	column := listQuery.SortColumn()
+	direction, comparison := "ASC", ">"
+	if listQuery.Descending() {
+		direction, comparison = "DESC", "<"
+	}
+
+	pageRequest := listQuery.PageRequest
+	page := query.Order(fmt.Sprintf(`%s %s, "Id" %s`, column, direction, direction)).Limit(pageRequest.PerPage + 1)
+	if pageRequest.After != nil {
+		var after any = pageRequest.After.Key
+		if listQuery.SortsByDate() {
+			after = pageRequest.After.CreatedAt
+		}
+		page = page.Where(fmt.Sprintf(`(%s, "Id") %s (?, ?)`, column, comparison), after, pageRequest.After.ID)
+	} else {
+		page = page.Offset(pageRequest.Offset())
+	}
+
+	var users []domain.User
+	if err := page.Find(&users).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return users, total, nil
+}
+
+// userOwnedModels are the tables whose rows belong to a user through their UserId column.
+var userOwnedModels = []any{
+	&domain.RefreshToken{},
+	&domain.UserIdentity{},
+	&domain.RecoveryCode{},
+	&domain.TrustedDevice{},
+	&domain.WebAuthnCredential{},
+	&domain.WebAuthnSession{},
+	&domain.MagicLink{},
+	&domain.AuthorizationCode{},
+	&domain.PersonalAccessToken{},
+	&domain.PendingEmail{},
+	&domain.OAuthState{},
+	&domain.UserRole{},
+	&domain.LoginAttempt{},
+	&domain.KnownDevice{},
+	&domain.DataExport{},
+	&domain.PolicyAcceptance{},
+}
+
+// purgeUser deletes the user with id and every row it owns.
+func purgeUser(tx *gorm.DB, id string) error {
+	var user domain.User
+	if err := tx.Unscoped().Where(`"Id" = ?`, id).First(&user).Error; err != nil {
+		return err
+	}
+
+	if err := deleteOwnedRows(tx, user); err != nil {
+		return err
+	}
+
+	return tx.Unscoped().Delete(&user).Error
+}
+
+// deleteOwnedRows deletes every row user owns, including the confirmation codes of its email,
+// which are keyed by the address.
+func deleteOwnedRows(tx *gorm.DB, user domain.User) error {
+	for _, model := range userOwnedModels {
+		if err := tx.Where(`"UserId" = ?`, user.ID).Delete(model).Error; err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Where(`"TenantId" = ? AND "Email" = ?`, user.TenantID, user.Email).Delete(&domain.ConfirmationCode{}).Error; err != nil {
+		return err
+	}
+
+	return tx.Where(`"TenantId" = ? AND "Email" = ?`, user.TenantID, user.Email).Delete(&domain.ConfirmationCodeSend{}).Error
+}
+
+// updatedUser returns the error of result, an update of the user of id, or domain.ErrUserNotFound
+// when there is no such user. MySQL leaves the rows an update did not change out of RowsAffected,
+// so a user that no row was affected for is only missing if it cannot be found either.
+func updatedUser(db *gorm.DB, result *gorm.DB, id string) error {
+	if result.Error != nil || result.RowsAffected > 0 {
+		return result.Error
+	}
+
+	var count int64
+	if err := db.Model(&domain.User{}).Where(`"Id" = ?`, id).Count(&count).Error; err != nil {
+		return err
+	}
+
+	if count == 0 {
+		return domain.ErrUserNotFound
+	}
+
+	return nil
+}
+
+// takenError tells which of the unique columns in columns another user of the tenant than
+// exceptID already holds, once the database refused them: domain.ErrEmailTaken or
+// domain.ErrUsernameTaken.
+func takenError(db *gorm.DB, tenantID string, columns map[string]interface{}, exceptID string) error {
+	for _, unique := range []struct {
+		column string
+		err    error
+	}{{"Email", domain.ErrEmailTaken}, {"Username", domain.ErrUsernameTaken}} {
+		value, ok := columns[unique.column]
+		if !ok {
+			continue
+		}
+
+		var count int64
+		err := db.Unscoped().Model(&domain.User{}).Where(`"TenantId" = ? AND ? = ? AND "Id" <> ?`, tenantID, clause.Column{Name: unique.column}, value, exceptID).Count(&count).Error
+		if err != nil {
+			return err
+		}
+
+		if count > 0 {
+			return unique.err
+		}
+	}
+
+	return gorm.ErrDuplicatedKey
+}
@@ -0,0 +1,315 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"github.com/OVillas/autentication/config"
+	"github.com/OVillas/autentication/domain"
+	"github.com/samber/do"
+)
+
+const (
+	userCacheIDKeyPrefix       = "id:"
+	userCacheUsernameKeyPrefix = "username:"
+)
+
+// cachedUserRepository caches the users looked up by GetById and GetByUsername with a context
+// from domain.AllowCachedUser, without their password hash and TOTP secret. A username is cached
+// as the id of its user, so a write only has to drop the user under its id: a username whose user
+// no longer holds it is a miss. The other methods go to userRepository, and every one of them
+// that writes a user drops it from the cache.
+type cachedUserRepository struct {
+	domain.UserRepository
+	i      *do.Injector
+	store  domain.UserCacheStore
+	hits   atomic.Uint64
+	misses atomic.Uint64
+}
+
+func NewCachedUserRepository(i *do.Injector) (domain.UserRepository, error) {
+	userRepository, err := NewUserRepository(i)
+	if err != nil {
+		return nil, err
+	}
+
+	store := do.MustInvoke[domain.UserCacheStore](i)
+	return &cachedUserRepository{
+		UserRepository: userRepository,
+		i:              i,
+		store:          store,
+	}, nil
+}
+
+func (cur *cachedUserRepository) Stats() domain.UserCacheStats {
+	return domain.UserCacheStats{
+		Store:  config.UserCache.Store,
+		Hits:   cur.hits.Load(),
+		Misses: cur.misses.Load(),
+	}
+}
+
+func (cur *cachedUserRepository) GetById(ctx context.Context, id string) (*domain.User, error) {
+	if !domain.CachedUserAllowed(ctx) {
+		return cur.UserRepository.GetById(ctx, id)
+	}
+
+	if user := cur.cached(ctx, id); user != nil {
+		cur.hits.Add(1)
+		return user, nil
+	}
+
+	cur.misses.Add(1)
+	user, err := cur.UserRepository.GetById(ctx, id)
+	if err != nil || user == nil {
+		return user, err
+	}
+
+	cur.cache(ctx, *user)
+	return user, nil
+}
+
+func (cur *cachedUserRepository) GetByUsername(ctx context.Context, tenantID string, username string) (*domain.User, error) {
+	if !domain.CachedUserAllowed(ctx) {
+		return cur.UserRepository.GetByUsername(ctx, tenantID, username)
+	}
+
+	username = domain.NormalizeUsername(username)
+	usernameKey := userCacheUsernameKeyPrefix + tenantID + ":" + username
+	if id := cur.get(ctx, usernameKey); id != nil {
+		user := cur.cached(ctx, string(id))
+		if user != nil && user.TenantID == tenantID && user.Username == username {
+			cur.hits.Add(1)
+			return user, nil
+		}
+	}
+
+	cur.misses.Add(1)
+	user, err := cur.UserRepository.GetByUsername(ctx, tenantID, username)
+	if err != nil || user == nil {
+		return user, err
+	}
+
+	cur.cache(ctx, *user)
+	cur.set(ctx, usernameKey, []byte(user.ID))
+	return user, nil
+}
+
+func (cur *cachedUserRepository) Update(ctx context.Context, id string, changes map[string]interface{}) error {
+	defer cur.drop(ctx, id)
+	return cur.UserRepository.Update(ctx, id, changes)
+}
+
+func (cur *cachedUserRepository) Delete(ctx context.Context, id string) error {
+	defer cur.drop(ctx, id)
+	return cur.UserRepository.Delete(ctx, id)
+}
+
+func (cur *cachedUserRepository) Restore(ctx context.Context, tenantID string, id string) (bool, error) {
+	defer cur.drop(ctx, id)
+	return cur.UserRepository.Restore(ctx, tenantID, id)
+}
+
+func (cur *cachedUserRepository) Anonymize(ctx context.Context, id string) error {
+	defer cur.drop(ctx, id)
+	return cur.UserRepository.Anonymize(ctx, id)
+}
+
+func (cur *cachedUserRepository) SetActive(ctx context.Context, id string, active bool) error {
+	defer cur.drop(ctx, id)
+	return cur.UserRepository.SetActive(ctx, id, active)
+}
+
+func (cur *cachedUserRepository) Suspend(ctx context.Context, id string, until *time.Time) error {
+	defer cur.drop(ctx, id)
+	return cur.UserRepository.Suspend(ctx, id, until)
+}
+
+func (cur *cachedUserRepository) Unsuspend(ctx context.Context, id string) (bool, error) {
+	defer cur.drop(ctx, id)
+	return cur.UserRepository.Unsuspend(ctx, id)
+}
+
+func (cur *cachedUserRepository) ScheduleDeletion(ctx context.Context, id string, at time.Time) error {
+	defer cur.drop(ctx, id)
+	return cur.UserRepository.ScheduleDeletion(ctx, id, at)
+}
+
+func (cur *cachedUserRepository) CancelDeletion(ctx context.Context, id string) (bool, error) {
+	defer cur.drop(ctx, id)
+	return cur.UserRepository.CancelDeletion(ctx, id)
+}
+
+func (cur *cachedUserRepository) SetDeletionReminded(ctx context.Context, id string) error {
+	defer cur.drop(ctx, id)
+	return cur.UserRepository.SetDeletionReminded(ctx, id)
+}
+
+func (cur *cachedUserRepository) Purge(ctx context.Context, id string) error {
+	defer cur.drop(ctx, id)
+	return cur.UserRepository.Purge(ctx, id)
+}
+
+func (cur *cachedUserRepository) UpdatePassword(ctx context.Context, id string, password string) error {
+	defer cur.drop(ctx, id)
+	return cur.UserRepository.UpdatePassword(ctx, id, password)
+}
+
+func (cur *cachedUserRepository) RehashPassword(ctx context.Context, id string, currentHash string, newHash string) (bool, error) {
+	defer cur.drop(ctx, id)
+	return cur.UserRepository.RehashPassword(ctx, id, currentHash, newHash)
+}
+
+func (cur *cachedUserRepository) ConfirmedEmail(ctx context.Context, id string) error {
+	defer cur.drop(ctx, id)
+	return cur.UserRepository.ConfirmedEmail(ctx, id)
+}
+
+func (cur *cachedUserRepository) UpdateEmail(ctx context.Context, id string, email string) error {
+	defer cur.drop(ctx, id)
+	return cur.UserRepository.UpdateEmail(ctx, id, email)
+}
+
+func (cur *cachedUserRepository) SetPhoneNumber(ctx context.Context, id string, phoneNumber *string) error {
+	defer cur.drop(ctx, id)
+	return cur.UserRepository.SetPhoneNumber(ctx, id, phoneNumber)
+}
+
+func (cur *cachedUserRepository) ConfirmPhoneNumber(ctx context.Context, id string, phoneNumber string) (bool, error) {
+	defer cur.drop(ctx, id)
+	return cur.UserRepository.ConfirmPhoneNumber(ctx, id, phoneNumber)
+}
+
+func (cur *cachedUserRepository) SetAvatarURL(ctx context.Context, id string, avatarURL *string) error {
+	defer cur.drop(ctx, id)
+	return cur.UserRepository.SetAvatarURL(ctx, id, avatarURL)
+}
+
+func (cur *cachedUserRepository) UpdateMetadata(ctx context.Context, id string, section string, patch map[string]any) (*domain.UserMetadata, error) {
+	defer cur.drop(ctx, id)
+	return cur.UserRepository.UpdateMetadata(ctx, id, section, patch)
+}
+
+func (cur *cachedUserRepository) IncrementTokenVersion(ctx context.Context, id string) error {
+	defer cur.drop(ctx, id)
+	return cur.UserRepository.IncrementTokenVersion(ctx, id)
+}
+
+// IncrementTokenVersionByRole empties the whole cache, as it does not know which users it changed.
+func (cur *cachedUserRepository) IncrementTokenVersionByRole(ctx context.Context, roleID string) error {
+	defer func() {
+		if err := cur.store.Clear(context.WithoutCancel(ctx)); err != nil {
+			slog.Error("Error trying to clear the user cache", slog.Any("error", err))
+		}
+	}()
+	return cur.UserRepository.IncrementTokenVersionByRole(ctx, roleID)
+}
+
+func (cur *cachedUserRepository) IncrementFailedLogins(ctx context.Context, id string) (int, error) {
+	defer cur.drop(ctx, id)
+	return cur.UserRepository.IncrementFailedLogins(ctx, id)
+}
+
+func (cur *cachedUserRepository) LockAccount(ctx context.Context, id string, until time.Time) error {
+	defer cur.drop(ctx, id)
+	return cur.UserRepository.LockAccount(ctx, id, until)
+}
+
+func (cur *cachedUserRepository) ResetFailedLogins(ctx context.Context, id string) error {
+	defer cur.drop(ctx, id)
+	return cur.UserRepository.ResetFailedLogins(ctx, id)
+}
+
+func (cur *cachedUserRepository) SetMustResetPassword(ctx context.Context, id string, mustReset bool) error {
+	defer cur.drop(ctx, id)
+	return cur.UserRepository.SetMustResetPassword(ctx, id, mustReset)
+}
+
+func (cur *cachedUserRepository) SetLoginAlertsEnabled(ctx context.Context, id string, enabled bool) error {
+	defer cur.drop(ctx, id)
+	return cur.UserRepository.SetLoginAlertsEnabled(ctx, id, enabled)
+}
+
+func (cur *cachedUserRepository) SetLastLoginAt(ctx context.Context, id string, at time.Time) error {
+	defer cur.drop(ctx, id)
+	return cur.UserRepository.SetLastLoginAt(ctx, id, at)
+}
+
+func (cur *cachedUserRepository) UpdateTOTPSecret(ctx context.Context, id string, secret string) error {
+	defer cur.drop(ctx, id)
+	return cur.UserRepository.UpdateTOTPSecret(ctx, id, secret)
+}
+
+func (cur *cachedUserRepository) UseTOTPStep(ctx context.Context, id string, step int64) (bool, error) {
+	defer cur.drop(ctx, id)
+	return cur.UserRepository.UseTOTPStep(ctx, id, step)
+}
+
+func (cur *cachedUserRepository) ActivateTwoFactor(ctx context.Context, id string, secret string) (bool, error) {
+	defer cur.drop(ctx, id)
+	return cur.UserRepository.ActivateTwoFactor(ctx, id, secret)
+}
+
+func (cur *cachedUserRepository) DisableTwoFactor(ctx context.Context, id string) error {
+	defer cur.drop(ctx, id)
+	return cur.UserRepository.DisableTwoFactor(ctx, id)
+}
+
+// cached returns the user of id in the cache, or nil. The cache only ever makes a lookup faster:
+// when the store fails, the lookup goes to the database, and a write it missed expires with
+// config.UserCache.TTL.
+func (cur *cachedUserRepository) cached(ctx context.Context, id string) *domain.User {
+	encoded := cur.get(ctx, userCacheIDKeyPrefix+id)
+	if encoded == nil {
+		return nil
+	}
+
+	var user domain.User
+	if err := json.Unmarshal(encoded, &user); err != nil {
+		slog.Warn("Invalid user in the cache", slog.String("userId", id), slog.Any("error", err))
+		return nil
+	}
+
+	return &user
+}
+
+func (cur *cachedUserRepository) cache(ctx context.Context, user domain.User) {
+	user.Password = ""
+	user.TOTPSecret = ""
+
+	encoded, err := json.Marshal(user)
+	if err != nil {
+		slog.Warn("Error trying to encode the user to cache", slog.String("userId", user.ID), slog.Any("error", err))
+		return
+	}
+
+	cur.set(ctx, userCacheIDKeyPrefix+user.ID, encoded)
+}
+
+func (cur *cachedUserRepository) get(ctx context.Context, key string) []byte {
+	value, err := cur.store.Get(ctx, key)
+	if err != nil {
+		slog.Warn("Error trying to read the user cache", slog.Any("error", err))
+		return nil
+	}
+
+	return value
+}
+
+func (cur *cachedUserRepository) set(ctx context.Context, key string, value []byte) {
+	if err := cur.store.Set(ctx, key, value, config.UserCache.TTL); err != nil {
+		slog.Warn("Error trying to write the user cache", slog.Any("error", err))
+	}
+}
+
+// drop runs once the write is done: dropping first would let a lookup made during the write cache
+// the user as it was. It is not canceled with ctx, as a write that went through must still reach
+// the cache.
+func (cur *cachedUserRepository) drop(ctx context.Context, id string) {
+	if err := cur.store.Delete(context.WithoutCancel(ctx), userCacheIDKeyPrefix+id); err != nil {
+		slog.Error("Error trying to drop the user from the cache", slog.String("userId", id), slog.Any("error", err))
+	}
+}
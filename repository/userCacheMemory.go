@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/OVillas/autentication/config"
+	"github.com/OVillas/autentication/domain"
+	"github.com/samber/do"
+)
+
+type memoryUserCacheEntry struct {
+	key       string
+	value     []byte
+	expiresAt time.Time
+}
+
+// memoryUserCacheStore keeps the entries in process, at most config.UserCache.Size of them: past
+// that, the least recently used one is dropped. Expired entries stay until they are read or pushed
+// out.
+type memoryUserCacheStore struct {
+	i       *do.Injector
+	mutex   sync.Mutex
+	size    int
+	entries map[string]*list.Element
+	recency *list.List
+}
+
+func NewMemoryUserCacheStore(i *do.Injector) (domain.UserCacheStore, error) {
+	return &memoryUserCacheStore{
+		i:       i,
+		size:    config.UserCache.Size,
+		entries: make(map[string]*list.Element),
+		recency: list.New(),
+	}, nil
+}
+
+func (mucs *memoryUserCacheStore) Get(ctx context.Context, key string) ([]byte, error) {
+	mucs.mutex.Lock()
+	defer mucs.mutex.Unlock()
+
+	element, ok := mucs.entries[key]
+	if !ok {
+		return nil, nil
+	}
+
+	entry := element.Value.(*memoryUserCacheEntry)
+	if time.Now().After(entry.expiresAt) {
+		mucs.remove(element)
+		return nil, nil
+	}
+
+	mucs.recency.MoveToFront(element)
+	return entry.value, nil
+}
+
+func (mucs *memoryUserCacheStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	mucs.mutex.Lock()
+	defer mucs.mutex.Unlock()
+
+	entry := &memoryUserCacheEntry{key: key, value: value, expiresAt: time.Now().Add(ttl)}
+	if element, ok := mucs.entries[key]; ok {
+		element.Value = entry
+		mucs.recency.MoveToFront(element)
+		return nil
+	}
+
+	mucs.entries[key] = mucs.recency.PushFront(entry)
+	for mucs.recency.Len() > mucs.size {
+		mucs.remove(mucs.recency.Back())
+	}
+
+	return nil
+}
+
+func (mucs *memoryUserCacheStore) Delete(ctx context.Context, keys ...string) error {
+	mucs.mutex.Lock()
+	defer mucs.mutex.Unlock()
+
+	for _, key := range keys {
+		if element, ok := mucs.entries[key]; ok {
+			mucs.remove(element)
+		}
+	}
+
+	return nil
+}
+
+func (mucs *memoryUserCacheStore) Clear(ctx context.Context) error {
+	mucs.mutex.Lock()
+	defer mucs.mutex.Unlock()
+
+	mucs.entries = make(map[string]*list.Element)
+	mucs.recency.Init()
+	return nil
+}
+
+func (mucs *memoryUserCacheStore) remove(element *list.Element) {
+	mucs.recency.Remove(element)
+	delete(mucs.entries, element.Value.(*memoryUserCacheEntry).key)
+}
@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/OVillas/autentication/domain"
+	"github.com/redis/go-redis/v9"
+	"github.com/samber/do"
+)
+
+const userCacheKeyPrefix = "user_cache:"
+
+// redisUserCacheStore shares the cache between the instances of the API, so a write on one of
+// them is seen by all.
+type redisUserCacheStore struct {
+	i      *do.Injector
+	client *redis.Client
+}
+
+func NewRedisUserCacheStore(i *do.Injector) (domain.UserCacheStore, error) {
+	client := do.MustInvoke[*redis.Client](i)
+	return &redisUserCacheStore{
+		i:      i,
+		client: client,
+	}, nil
+}
+
+func (rucs *redisUserCacheStore) Get(ctx context.Context, key string) ([]byte, error) {
+	value, err := rucs.client.Get(ctx, userCacheKeyPrefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+
+	return value, err
+}
+
+func (rucs *redisUserCacheStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return rucs.client.Set(ctx, userCacheKeyPrefix+key, value, ttl).Err()
+}
+
+func (rucs *redisUserCacheStore) Delete(ctx context.Context, keys ...string) error {
+	prefixed := make([]string, 0, len(keys))
+	for _, key := range keys {
+		prefixed = append(prefixed, userCacheKeyPrefix+key)
+	}
+
+	return rucs.client.Del(ctx, prefixed...).Err()
+}
+
+// Clear goes through the keys with SCAN, so that Redis keeps serving the other clients meanwhile.
+func (rucs *redisUserCacheStore) Clear(ctx context.Context) error {
+	log := slog.With(
+		slog.String("func", "Clear"),
+		slog.String("repository", "redisUserCache"))
+
+	iterator := rucs.client.Scan(ctx, 0, userCacheKeyPrefix+"*", 1000).Iterator()
+	removed := 0
+	for iterator.Next(ctx) {
+		if err := rucs.client.Del(ctx, iterator.Val()).Err(); err != nil {
+			return err
+		}
+		removed++
+	}
+
+	if err := iterator.Err(); err != nil {
+		return err
+	}
+
+	log.Info("User cache cleared", slog.Int("count", removed))
+	return nil
+}
@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/OVillas/autentication/domain"
+	"github.com/samber/do"
+	"gorm.io/gorm"
+)
+
+type userIdentityRepository struct {
+	i  *do.Injector
+	db *gorm.DB
+}
+
+func NewUserIdentityRepository(i *do.Injector) (domain.UserIdentityRepository, error) {
+	db := do.MustInvoke[*gorm.DB](i)
+	return &userIdentityRepository{
+		db: db,
+		i:  i,
+	}, nil
+}
+
+func (uir *userIdentityRepository) Create(userIdentity domain.UserIdentity) error {
+	log := slog.With(
+		slog.String("func", "Create"),
+		slog.String("repository", "userIdentity"))
+
+	log.Info("Create initiated")
+
+	userIdentity.CreatedAt = time.Now()
+
+	if err := uir.db.Create(&userIdentity).Error; err != nil {
+		log.Error("Error to create user identity in database", slog.Any("error", err))
+		return err
+	}
+
+	log.Info("Create executed successfully")
+	return nil
+}
+
+func (uir *userIdentityRepository) GetByProvider(tenantID string, provider string, providerUserID string) (*domain.UserIdentity, error) {
+	log := slog.With(
+		slog.String("func", "GetByProvider"),
+		slog.String("repository", "userIdentity"))
+
+	log.Info("GetByProvider initiated")
+
+	var userIdentity domain.UserIdentity
+	err := uir.db.Where(`"TenantId" = ? AND "Provider" = ? AND "ProviderUserId" = ?`, tenantID, provider, providerUserID).First(&userIdentity).Error
+
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		log.Error("Error: ", slog.Any("error", err))
+		return nil, err
+	}
+
+	log.Info("GetByProvider executed successfully")
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+
+	return &userIdentity, nil
+}
+
+func (uir *userIdentityRepository) GetByUserID(userID string) ([]domain.UserIdentity, error) {
+	log := slog.With(
+		slog.String("func", "GetByUserID"),
+		slog.String("repository", "userIdentity"))
+
+	log.Info("GetByUserID initiated")
+
+	var userIdentities []domain.UserIdentity
+	if err := uir.db.Where(`"UserId" = ?`, userID).Order(`"CreatedAt"`).Find(&userIdentities).Error; err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return nil, err
+	}
+
+	log.Info("GetByUserID executed successfully")
+	return userIdentities, nil
+}
+
+func (uir *userIdentityRepository) Delete(id string) (bool, error) {
+	log := slog.With(
+		slog.String("func", "Delete"),
+		slog.String("repository", "userIdentity"))
+
+	log.Info("Delete initiated")
+
+	result := uir.db.Where(`"Id" = ?`, id).Delete(&domain.UserIdentity{})
+	if result.Error != nil {
+		log.Error("Error: ", slog.Any("error", result.Error))
+		return false, result.Error
+	}
+
+	log.Info("Delete executed successfully")
+	return result.RowsAffected == 1, nil
+}
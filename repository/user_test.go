@@ -0,0 +1,357 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/OVillas/autentication/domain"
+	"gorm.io/gorm/clause"
+)
+
+func TestUserRepositoryCreate(t *testing.T) {
+	ctx := context.Background()
+	userRepository, _ := NewUserRepository(newTestInjector())
+	user := newTestUser(t)
+
+	found, err := userRepository.GetByEmail(ctx, user.TenantID, "  "+user.Email+" ")
+	if err != nil || found == nil || found.ID != user.ID {
+		t.Fatalf("GetByEmail = %v, %v, want the user", found, err)
+	}
+
+	if found.CreatedAt.IsZero() || found.UpdatedAt.IsZero() {
+		t.Errorf("CreatedAt = %v, UpdatedAt = %v, want them set", found.CreatedAt, found.UpdatedAt)
+	}
+
+	found, err = userRepository.GetByUsername(ctx, user.TenantID, user.Username)
+	if err != nil || found == nil || found.ID != user.ID {
+		t.Fatalf("GetByUsername = %v, %v, want the user", found, err)
+	}
+
+	found, err = userRepository.GetByEmail(ctx, "another-tenant", user.Email)
+	if err != nil || found != nil {
+		t.Fatalf("GetByEmail in another tenant = %v, %v, want nil, nil", found, err)
+	}
+}
+
+func TestUserRepositoryCreateTaken(t *testing.T) {
+	ctx := context.Background()
+	userRepository, _ := NewUserRepository(newTestInjector())
+	user := newTestUser(t)
+
+	tests := []struct {
+		name     string
+		username string
+		email    string
+		want     error
+	}{
+		{"email", "other" + user.Username, user.Email, domain.ErrEmailTaken},
+		{"username", user.Username, "other" + user.Email, domain.ErrUsernameTaken},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			taken := user
+			taken.ID = "taken-" + test.name + "-" + user.ID[:8]
+			taken.Username = test.username
+			taken.Email = test.email
+
+			if err := userRepository.Create(ctx, taken); !errors.Is(err, test.want) {
+				t.Fatalf("Create = %v, want %v", err, test.want)
+			}
+		})
+	}
+}
+
+func TestUserRepositoryUpdate(t *testing.T) {
+	ctx := context.Background()
+	userRepository, _ := NewUserRepository(newTestInjector())
+	user := newTestUser(t)
+
+	if err := userRepository.ConfirmedEmail(ctx, user.ID); err != nil {
+		t.Fatalf("ConfirmedEmail: %v", err)
+	}
+
+	err := userRepository.Update(ctx, user.ID, map[string]interface{}{"Name": "Renamed", "Email": "Renamed" + user.Email})
+	if err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+
+	found, err := userRepository.GetById(ctx, user.ID)
+	if err != nil || found == nil {
+		t.Fatalf("GetById = %v, %v, want the user", found, err)
+	}
+
+	if found.Name != "Renamed" || found.Email != "renamed"+user.Email {
+		t.Errorf("Name, Email = %q, %q, want the updated ones", found.Name, found.Email)
+	}
+
+	if found.EmailConfirmed {
+		t.Error("EmailConfirmed = true, want a new email unconfirmed")
+	}
+
+	err = userRepository.Update(ctx, user.ID, map[string]interface{}{"Password": "other"})
+	if err == nil {
+		t.Error("Update of the password succeeded, want it refused")
+	}
+}
+
+func TestUserRepositoryUpdateKeepsTheOtherColumns(t *testing.T) {
+	ctx := context.Background()
+	userRepository, _ := NewUserRepository(newTestInjector())
+
+	user := newUnsavedUser()
+	user.TwoFactorAuthActive = true
+	user.TOTPSecret = "secret"
+	user.CreatedAt = time.Now().Add(-time.Hour).Truncate(time.Second)
+	if err := userRepository.Create(ctx, user); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	err := userRepository.Update(ctx, user.ID, map[string]interface{}{"Name": "Renamed", "Username": "re" + user.Username})
+	if err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+
+	found, err := userRepository.GetById(ctx, user.ID)
+	if err != nil || found == nil {
+		t.Fatalf("GetById = %v, %v, want the user", found, err)
+	}
+
+	if found.Password != user.Password {
+		t.Errorf("Password = %q, want %q", found.Password, user.Password)
+	}
+
+	if !found.CreatedAt.Equal(user.CreatedAt) {
+		t.Errorf("CreatedAt = %v, want %v", found.CreatedAt, user.CreatedAt)
+	}
+
+	if !found.TwoFactorAuthActive || found.TOTPSecret != user.TOTPSecret {
+		t.Errorf("TwoFactorAuthActive, TOTPSecret = %v, %q, want two-factor still active", found.TwoFactorAuthActive, found.TOTPSecret)
+	}
+}
+
+func TestUserRepositoryTimestamps(t *testing.T) {
+	ctx := context.Background()
+	userRepository, _ := NewUserRepository(newTestInjector())
+
+	before := time.Now()
+	user := newTestUser(t)
+
+	created, err := userRepository.GetById(ctx, user.ID)
+	if err != nil || created == nil {
+		t.Fatalf("GetById = %v, %v, want the user", created, err)
+	}
+
+	if created.CreatedAt.Before(before.Truncate(time.Second)) || created.CreatedAt.After(time.Now()) ||
+		!created.UpdatedAt.Equal(created.CreatedAt) {
+		t.Errorf("CreatedAt, UpdatedAt = %v, %v, want both the time of Create", created.CreatedAt, created.UpdatedAt)
+	}
+
+	// Dated an hour back, so the update cannot land on the same instant.
+	hourAgo := time.Now().Add(-time.Hour).Truncate(time.Second)
+	err = testDB.Model(&domain.User{}).Where(`"Id" = ?`, user.ID).
+		UpdateColumns(map[string]interface{}{"CreatedAt": hourAgo, "UpdatedAt": hourAgo}).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	before = time.Now()
+	if err := userRepository.Update(ctx, user.ID, map[string]interface{}{"Name": "Renamed"}); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+
+	updated, err := userRepository.GetById(ctx, user.ID)
+	if err != nil || updated == nil {
+		t.Fatalf("GetById = %v, %v, want the user", updated, err)
+	}
+
+	if !updated.CreatedAt.Equal(hourAgo) {
+		t.Errorf("CreatedAt = %v, want %v, unchanged by Update", updated.CreatedAt, hourAgo)
+	}
+
+	if updated.UpdatedAt.Before(before.Truncate(time.Second)) || updated.UpdatedAt.After(time.Now()) {
+		t.Errorf("UpdatedAt = %v, want the time of Update", updated.UpdatedAt)
+	}
+}
+
+func TestUserRepositoryDelete(t *testing.T) {
+	ctx := context.Background()
+	userRepository, _ := NewUserRepository(newTestInjector())
+	user := newTestUser(t)
+
+	if err := userRepository.Delete(ctx, user.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+
+	found, err := userRepository.GetById(ctx, user.ID)
+	if err != nil || found != nil {
+		t.Fatalf("GetById = %v, %v, want nil, nil", found, err)
+	}
+
+	deleted, err := userRepository.GetDeletedByEmail(ctx, user.TenantID, user.Email)
+	if err != nil || deleted == nil || deleted.ID != user.ID {
+		t.Fatalf("GetDeletedByEmail = %v, %v, want the user", deleted, err)
+	}
+}
+
+func TestUserRepositoryUpdatePassword(t *testing.T) {
+	ctx := context.Background()
+	userRepository, _ := NewUserRepository(newTestInjector())
+	user := newTestUser(t)
+
+	if err := userRepository.UpdatePassword(ctx, user.ID, "new hash"); err != nil {
+		t.Fatalf("UpdatePassword: %v", err)
+	}
+
+	found, err := userRepository.GetById(ctx, user.ID)
+	if err != nil || found == nil {
+		t.Fatalf("GetById = %v, %v, want the user", found, err)
+	}
+
+	if found.Password != "new hash" {
+		t.Errorf("Password = %q, want %q", found.Password, "new hash")
+	}
+}
+
+func TestUserRepositoryConfirmedEmail(t *testing.T) {
+	ctx := context.Background()
+	userRepository, _ := NewUserRepository(newTestInjector())
+	user := newTestUser(t)
+
+	// Confirming twice changes no row the second time, which must not read as a missing user.
+	for range 2 {
+		if err := userRepository.ConfirmedEmail(ctx, user.ID); err != nil {
+			t.Fatalf("ConfirmedEmail: %v", err)
+		}
+	}
+
+	found, err := userRepository.GetById(ctx, user.ID)
+	if err != nil || found == nil {
+		t.Fatalf("GetById = %v, %v, want the user", found, err)
+	}
+
+	if !found.EmailConfirmed {
+		t.Error("EmailConfirmed = false, want true")
+	}
+}
+
+func TestUserRepositoryNotFound(t *testing.T) {
+	ctx := context.Background()
+	userRepository, _ := NewUserRepository(newTestInjector())
+	deleted := newTestUser(t)
+	if err := userRepository.Delete(ctx, deleted.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		write func(id string) error
+	}{
+		{"Update", func(id string) error {
+			return userRepository.Update(ctx, id, map[string]interface{}{"Name": "Renamed"})
+		}},
+		{"Delete", func(id string) error {
+			return userRepository.Delete(ctx, id)
+		}},
+		{"UpdatePassword", func(id string) error {
+			return userRepository.UpdatePassword(ctx, id, "new hash")
+		}},
+		{"ConfirmedEmail", func(id string) error {
+			return userRepository.ConfirmedEmail(ctx, id)
+		}},
+		{"UpdateMetadata", func(id string) error {
+			_, err := userRepository.UpdateMetadata(ctx, id, domain.MetadataSectionUser, map[string]any{"theme": "dark"})
+			return err
+		}},
+	}
+
+	for _, test := range tests {
+		for _, id := range []struct {
+			name string
+			id   string
+		}{{"unknown", "00000000-0000-0000-0000-000000000000"}, {"deleted", deleted.ID}} {
+			t.Run(test.name+"/"+id.name, func(t *testing.T) {
+				if err := test.write(id.id); !errors.Is(err, domain.ErrUserNotFound) {
+					t.Fatalf("%s = %v, want %v", test.name, err, domain.ErrUserNotFound)
+				}
+			})
+		}
+	}
+}
+
+// The lookups report a missing user as nil, nil, which their callers turn into ErrUserNotFound.
+func TestUserRepositoryLookupNotFound(t *testing.T) {
+	ctx := context.Background()
+	userRepository, _ := NewUserRepository(newTestInjector())
+	user := newTestUser(t)
+
+	tests := []struct {
+		name   string
+		lookup func() (*domain.User, error)
+	}{
+		{"GetById", func() (*domain.User, error) {
+			return userRepository.GetById(ctx, "00000000-0000-0000-0000-000000000000")
+		}},
+		{"GetByEmail", func() (*domain.User, error) {
+			return userRepository.GetByEmail(ctx, user.TenantID, "missing"+user.Email)
+		}},
+		{"GetByUsername", func() (*domain.User, error) {
+			return userRepository.GetByUsername(ctx, user.TenantID, "missing"+user.Username)
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if found, err := test.lookup(); found != nil || err != nil {
+				t.Fatalf("%s = %v, %v, want nil, nil", test.name, found, err)
+			}
+		})
+	}
+
+	users, total, err := userRepository.GetByNameOrUsername(ctx, user.TenantID, "missing", domain.PageRequest{Page: 1, PerPage: 10})
+	if err != nil || len(users) != 0 || total != 0 {
+		t.Fatalf("GetByNameOrUsername = %v, %d, %v, want an empty page", users, total, err)
+	}
+}
+
+func TestUserRepositoryCancelledContext(t *testing.T) {
+	userRepository, _ := NewUserRepository(newTestInjector())
+	user := newTestUser(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if found, err := userRepository.GetById(ctx, user.ID); !errors.Is(err, context.Canceled) {
+		t.Fatalf("GetById = %v, %v, want %v", found, err, context.Canceled)
+	}
+}
+
+// An update waiting for the row of the user, locked by another transaction, gives up as soon as
+// its context is cancelled instead of running once the lock is released.
+func TestUserRepositoryCancelledInFlight(t *testing.T) {
+	userRepository, _ := NewUserRepository(newTestInjector())
+	user := newTestUser(t)
+
+	tx := testDB.Begin()
+	defer tx.Rollback()
+
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&domain.User{ID: user.ID}).Error; err != nil {
+		t.Fatalf("lock the user: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	err := userRepository.Update(ctx, user.ID, map[string]interface{}{"Name": "Renamed"})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Update = %v, want %v", err, context.Canceled)
+	}
+
+	tx.Rollback()
+	found, err := userRepository.GetById(context.Background(), user.ID)
+	if err != nil || found == nil || found.Name != user.Name {
+		t.Fatalf("GetById = %v, %v, want the user unchanged", found, err)
+	}
+}
@@ -0,0 +1,154 @@
+package repository
+
+import (
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/OVillas/autentication/domain"
+	"github.com/samber/do"
+	"gorm.io/gorm"
+)
+
+type webAuthnCredentialRepository struct {
+	i  *do.Injector
+	db *gorm.DB
+}
+
+func NewWebAuthnCredentialRepository(i *do.Injector) (domain.WebAuthnCredentialRepository, error) {
+	db := do.MustInvoke[*gorm.DB](i)
+	return &webAuthnCredentialRepository{
+		db: db,
+		i:  i,
+	}, nil
+}
+
+func (wacr *webAuthnCredentialRepository) Create(webAuthnCredential domain.WebAuthnCredential) error {
+	log := slog.With(
+		slog.String("func", "Create"),
+		slog.String("repository", "webAuthnCredential"))
+
+	log.Info("Create initiated")
+
+	webAuthnCredential.CreatedAt = time.Now()
+
+	if err := wacr.db.Create(&webAuthnCredential).Error; err != nil {
+		log.Error("Error to create webauthn credential in database", slog.Any("error", err))
+		return err
+	}
+
+	log.Info("Create executed successfully")
+	return nil
+}
+
+func (wacr *webAuthnCredentialRepository) GetByUserID(userID string) ([]domain.WebAuthnCredential, error) {
+	log := slog.With(
+		slog.String("func", "GetByUserID"),
+		slog.String("repository", "webAuthnCredential"))
+
+	log.Info("GetByUserID initiated")
+
+	var webAuthnCredentials []domain.WebAuthnCredential
+	err := wacr.db.Where(`"UserId" = ?`, userID).Order(`"CreatedAt" ASC`).Find(&webAuthnCredentials).Error
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return nil, err
+	}
+
+	log.Info("GetByUserID executed successfully")
+	return webAuthnCredentials, nil
+}
+
+func (wacr *webAuthnCredentialRepository) GetByCredentialID(credentialID string) (*domain.WebAuthnCredential, error) {
+	log := slog.With(
+		slog.String("func", "GetByCredentialID"),
+		slog.String("repository", "webAuthnCredential"))
+
+	log.Info("GetByCredentialID initiated")
+
+	var webAuthnCredential domain.WebAuthnCredential
+	err := wacr.db.Where(`"CredentialId" = ?`, credentialID).First(&webAuthnCredential).Error
+
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		log.Error("Error: ", slog.Any("error", err))
+		return nil, err
+	}
+
+	log.Info("GetByCredentialID executed successfully")
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+
+	return &webAuthnCredential, nil
+}
+
+// UpdateSignCount records a login. The counter must move forward, unless the authenticator does
+// not implement one and always reports zero; otherwise nothing is updated and false is returned,
+// which also catches two logins racing with the same counter value.
+func (wacr *webAuthnCredentialRepository) UpdateSignCount(id string, signCount uint32, backupState bool) (bool, error) {
+	log := slog.With(
+		slog.String("func", "UpdateSignCount"),
+		slog.String("repository", "webAuthnCredential"))
+
+	log.Info("UpdateSignCount initiated")
+
+	result := wacr.db.Model(&domain.WebAuthnCredential{}).
+		Where(`"Id" = ? AND ("SignCount" < ? OR ("SignCount" = 0 AND ? = 0))`, id, signCount, signCount).
+		Updates(map[string]interface{}{
+			"SignCount":   signCount,
+			"BackupState": backupState,
+			"LastUsedAt":  time.Now(),
+		})
+	if result.Error != nil {
+		log.Error("Error: ", slog.Any("error", result.Error))
+		return false, result.Error
+	}
+
+	log.Info("UpdateSignCount executed successfully")
+	return result.RowsAffected == 1, nil
+}
+
+func (wacr *webAuthnCredentialRepository) Rename(userID string, id string, name string) (bool, error) {
+	log := slog.With(
+		slog.String("func", "Rename"),
+		slog.String("repository", "webAuthnCredential"))
+
+	log.Info("Rename initiated")
+
+	var count int64
+	err := wacr.db.Model(&domain.WebAuthnCredential{}).Where(`"Id" = ? AND "UserId" = ?`, id, userID).Count(&count).Error
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return false, err
+	}
+
+	if count == 0 {
+		return false, nil
+	}
+
+	err = wacr.db.Model(&domain.WebAuthnCredential{}).Where(`"Id" = ? AND "UserId" = ?`, id, userID).Update("Name", name).Error
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return false, err
+	}
+
+	log.Info("Rename executed successfully")
+	return true, nil
+}
+
+func (wacr *webAuthnCredentialRepository) Delete(userID string, id string) (bool, error) {
+	log := slog.With(
+		slog.String("func", "Delete"),
+		slog.String("repository", "webAuthnCredential"))
+
+	log.Info("Delete initiated")
+
+	result := wacr.db.Where(`"Id" = ? AND "UserId" = ?`, id, userID).Delete(&domain.WebAuthnCredential{})
+	if result.Error != nil {
+		log.Error("Error: ", slog.Any("error", result.Error))
+		return false, result.Error
+	}
+
+	log.Info("Delete executed successfully")
+	return result.RowsAffected == 1, nil
+}
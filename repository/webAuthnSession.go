@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/OVillas/autentication/domain"
+	"github.com/samber/do"
+	"gorm.io/gorm"
+)
+
+type webAuthnSessionRepository struct {
+	i  *do.Injector
+	db *gorm.DB
+}
+
+func NewWebAuthnSessionRepository(i *do.Injector) (domain.WebAuthnSessionRepository, error) {
+	db := do.MustInvoke[*gorm.DB](i)
+	return &webAuthnSessionRepository{
+		db: db,
+		i:  i,
+	}, nil
+}
+
+func (wasr *webAuthnSessionRepository) Create(webAuthnSession domain.WebAuthnSession) error {
+	log := slog.With(
+		slog.String("func", "Create"),
+		slog.String("repository", "webAuthnSession"))
+
+	log.Info("Create initiated")
+
+	webAuthnSession.CreatedAt = time.Now()
+
+	if err := wasr.db.Create(&webAuthnSession).Error; err != nil {
+		log.Error("Error to create webauthn session in database", slog.Any("error", err))
+		return err
+	}
+
+	log.Info("Create executed successfully")
+	return nil
+}
+
+func (wasr *webAuthnSessionRepository) GetById(id string) (*domain.WebAuthnSession, error) {
+	log := slog.With(
+		slog.String("func", "GetById"),
+		slog.String("repository", "webAuthnSession"))
+
+	log.Info("GetById initiated")
+
+	var webAuthnSession domain.WebAuthnSession
+	err := wasr.db.Where(`"Id" = ?`, id).First(&webAuthnSession).Error
+
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		log.Error("Error: ", slog.Any("error", err))
+		return nil, err
+	}
+
+	log.Info("GetById executed successfully")
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+
+	return &webAuthnSession, nil
+}
+
+// Use consumes the ceremony. It reports false when it was already finished or has expired, so
+// an authenticator response cannot be replayed.
+func (wasr *webAuthnSessionRepository) Use(id string) (bool, error) {
+	log := slog.With(
+		slog.String("func", "Use"),
+		slog.String("repository", "webAuthnSession"))
+
+	log.Info("Use initiated")
+
+	now := time.Now()
+	result := wasr.db.Model(&domain.WebAuthnSession{}).
+		Where(`"Id" = ? AND "UsedAt" IS NULL AND "ExpiresAt" > ?`, id, now).
+		Update("UsedAt", now)
+	if result.Error != nil {
+		log.Error("Error: ", slog.Any("error", result.Error))
+		return false, result.Error
+	}
+
+	log.Info("Use executed successfully")
+	return result.RowsAffected == 1, nil
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/OVillas/autentication/auth"
+	"github.com/OVillas/autentication/domain"
+	"github.com/samber/do"
+)
+
+func TestResetTokenCannotUpdateOrDeleteTheUser(t *testing.T) {
+	ts := newTestServer(t)
+	user := ts.register(t)
+
+	stored, err := ts.userRepository().GetById(context.Background(), user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resetToken, err := do.MustInvoke[auth.TokenProvider](ts.i).CreateResetPasswordToken(*stored)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	name := "Renamed By A Reset Token"
+	for _, method := range []string{http.MethodPatch, http.MethodDelete} {
+		t.Run(method, func(t *testing.T) {
+			var body any
+			if method == http.MethodPatch {
+				body = domain.UserUpdatePayLoad{Name: &name}
+			}
+
+			response := ts.request(method, "/v1/users/"+user.ID, body, resetToken)
+			if response.Code != http.StatusForbidden {
+				t.Fatalf("status %d, want %d: %s", response.Code, http.StatusForbidden, response.Body)
+			}
+
+			if message := decode[map[string]string](t, response)["error"]; message != domain.ErrUserNotAuthorized.Error() {
+				t.Errorf("error %q, want %q", message, domain.ErrUserNotAuthorized)
+			}
+		})
+	}
+
+	after, err := ts.userRepository().GetById(context.Background(), user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if after == nil || after.Name != stored.Name {
+		t.Errorf("the reset token changed the user: %+v", after)
+	}
+}
@@ -0,0 +1,58 @@
+package secure
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/OVillas/autentication/config"
+)
+
+const pwnedPasswordsRangeURL = "https://api.pwnedpasswords.com/range/"
+
+// IsPasswordBreached asks Have I Been Pwned whether the password appears in a known breach. Only
+// the first five characters of its SHA-1 leave the process (k-anonymity); the suffix is looked up
+// in the returned range locally. Padding is requested so the response size leaks nothing either.
+func IsPasswordBreached(password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:5], hash[5:]
+
+	ctx, cancel := context.WithTimeout(context.Background(), config.PasswordBreachCheck.Timeout)
+	defer cancel()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, pwnedPasswordsRangeURL+prefix, nil)
+	if err != nil {
+		return false, err
+	}
+	request.Header.Set("Add-Padding", "true")
+
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return false, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("pwned passwords api returned status %d", response.StatusCode)
+	}
+
+	scanner := bufio.NewScanner(response.Body)
+	for scanner.Scan() {
+		candidate, count, found := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if !found || candidate != suffix {
+			continue
+		}
+
+		// Padding entries carry a count of 0.
+		occurrences, err := strconv.Atoi(count)
+		return err == nil && occurrences > 0, nil
+	}
+
+	return false, scanner.Err()
+}
@@ -0,0 +1,22 @@
+package secure
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+
+	"github.com/OVillas/autentication/config"
+)
+
+// HashOTP returns the HMAC-SHA256 of a one-time code bound to the email it was sent to. The key
+// keeps a dump of stored hashes from being brute forced over the few million possible codes.
+func HashOTP(email string, code string) string {
+	mac := hmac.New(sha256.New, config.EncryptionKey)
+	mac.Write([]byte(email + ":" + code))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// CheckOTP reports in constant time whether code is the one hashed by HashOTP for email.
+func CheckOTP(codeHash string, email string, code string) bool {
+	return hmac.Equal([]byte(codeHash), []byte(HashOTP(email, code)))
+}
@@ -1,11 +1,262 @@
 package secure
 
-import "golang.org/x/crypto/bcrypt"
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
 
+	"github.com/OVillas/autentication/config"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	AlgorithmBcrypt   = "bcrypt"
+	AlgorithmArgon2id = "argon2id"
+
+	argon2SaltLength = 16
+	argon2KeyLength  = 32
+
+	// pepperPrefix marks hashes whose password went through the pepper first, so hashes made
+	// before the pepper was set keep verifying.
+	pepperPrefix = "$pepper$"
+)
+
+var (
+	ErrPasswordMismatch    = errors.New("password does not match")
+	ErrUnknownPasswordHash = errors.New("unknown password hash format")
+	ErrPepperNotConfigured = errors.New("password hash is peppered but no pepper is configured")
+)
+
+// PasswordHasher is one password hashing algorithm. Hashes are self-describing strings that
+// carry the algorithm and its parameters, so a stored hash can always be verified by the
+// hasher that recognises it, whatever the current configuration is.
+type PasswordHasher interface {
+	Hash(password string) (string, error)
+	Compare(hashedPassword string, password string) error
+	// Recognizes reports whether hashedPassword was produced by this algorithm.
+	Recognizes(hashedPassword string) bool
+	// Outdated reports whether hashedPassword used other parameters than the configured ones.
+	Outdated(hashedPassword string) bool
+}
+
+type bcryptHasher struct{}
+
+func (bh bcryptHasher) Hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), config.PasswordHashing.BcryptCost)
+	return string(hash), err
+}
+
+func (bh bcryptHasher) Compare(hashedPassword string, password string) error {
+	if err := bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password)); err != nil {
+		return ErrPasswordMismatch
+	}
+
+	return nil
+}
+
+func (bh bcryptHasher) Recognizes(hashedPassword string) bool {
+	return strings.HasPrefix(hashedPassword, "$2a$") || strings.HasPrefix(hashedPassword, "$2b$") ||
+		strings.HasPrefix(hashedPassword, "$2y$")
+}
+
+func (bh bcryptHasher) Outdated(hashedPassword string) bool {
+	cost, err := bcrypt.Cost([]byte(hashedPassword))
+	return err != nil || cost != config.PasswordHashing.BcryptCost
+}
+
+// argon2idHasher writes hashes in the PHC format used by the reference implementation:
+// $argon2id$v=19$m=65536,t=3,p=2$<salt>$<key>, base64 without padding.
+type argon2idHasher struct{}
+
+type argon2Params struct {
+	memory  uint32
+	time    uint32
+	threads uint8
+}
+
+func (ah argon2idHasher) Hash(password string) (string, error) {
+	salt := make([]byte, argon2SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	params := currentArgon2Params()
+	key := argon2.IDKey([]byte(password), salt, params.time, params.memory, params.threads, argon2KeyLength)
+
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, params.memory, params.time, params.threads,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+func (ah argon2idHasher) Compare(hashedPassword string, password string) error {
+	params, salt, key, err := decodeArgon2id(hashedPassword)
+	if err != nil {
+		return err
+	}
+
+	candidate := argon2.IDKey([]byte(password), salt, params.time, params.memory, params.threads, uint32(len(key)))
+	if subtle.ConstantTimeCompare(key, candidate) != 1 {
+		return ErrPasswordMismatch
+	}
+
+	return nil
+}
+
+func (ah argon2idHasher) Recognizes(hashedPassword string) bool {
+	return strings.HasPrefix(hashedPassword, "$argon2id$")
+}
+
+func (ah argon2idHasher) Outdated(hashedPassword string) bool {
+	params, _, _, err := decodeArgon2id(hashedPassword)
+	return err != nil || params != currentArgon2Params()
+}
+
+var (
+	dummyHashOnce sync.Once
+	dummyHash     string
+)
+
+var hashers = map[string]PasswordHasher{
+	AlgorithmBcrypt:   bcryptHasher{},
+	AlgorithmArgon2id: argon2idHasher{},
+}
+
+// NormalizePassword applies Unicode NFKC when the password policy asks for it, so composed and
+// decomposed forms of the same characters hash the same.
+func NormalizePassword(password string) string {
+	if !config.PasswordPolicy.Normalize {
+		return password
+	}
+
+	return norm.NFKC.String(password)
+}
+
+// Hash hashes a password with the configured algorithm and, if set, the pepper.
 func Hash(password string) ([]byte, error) {
-	return bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	password = NormalizePassword(password)
+
+	if pepperEnabled() {
+		hash, err := currentHasher().Hash(pepper(password))
+		return []byte(pepperPrefix + hash), err
+	}
+
+	hash, err := currentHasher().Hash(password)
+	return []byte(hash), err
 }
 
+// CheckPassword verifies a password against the algorithm the stored hash declares. Hashes made
+// before normalization was enabled are still checked against the password as typed.
 func CheckPassword(hashedPassword, password string) error {
-	return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
+	err := checkPassword(hashedPassword, NormalizePassword(password))
+	if errors.Is(err, ErrPasswordMismatch) && NormalizePassword(password) != password {
+		return checkPassword(hashedPassword, password)
+	}
+
+	return err
+}
+
+func checkPassword(hashedPassword, password string) error {
+	if strings.HasPrefix(hashedPassword, pepperPrefix) {
+		if !pepperEnabled() {
+			return ErrPepperNotConfigured
+		}
+		hashedPassword = strings.TrimPrefix(hashedPassword, pepperPrefix)
+		password = pepper(password)
+	}
+
+	for _, hasher := range hashers {
+		if hasher.Recognizes(hashedPassword) {
+			return hasher.Compare(hashedPassword, password)
+		}
+	}
+
+	return ErrUnknownPasswordHash
+}
+
+// CheckDummyPassword spends the time of a real password check against a hash made with the
+// current settings, so a login for an unknown account cannot be told apart by its duration.
+func CheckDummyPassword(password string) {
+	dummyHashOnce.Do(func() {
+		hash, _ := Hash("dummy password to compare against")
+		dummyHash = string(hash)
+	})
+
+	_ = CheckPassword(dummyHash, password)
+}
+
+// NeedsRehash reports whether a stored hash should be replaced, after a successful check, by
+// one made with the configured algorithm and parameters.
+func NeedsRehash(hashedPassword string) bool {
+	if strings.HasPrefix(hashedPassword, pepperPrefix) != pepperEnabled() {
+		return true
+	}
+	hashedPassword = strings.TrimPrefix(hashedPassword, pepperPrefix)
+
+	hasher := currentHasher()
+	return !hasher.Recognizes(hashedPassword) || hasher.Outdated(hashedPassword)
+}
+
+func currentHasher() PasswordHasher {
+	if hasher, ok := hashers[config.PasswordHashing.Algorithm]; ok {
+		return hasher
+	}
+
+	return hashers[AlgorithmArgon2id]
+}
+
+func pepperEnabled() bool {
+	return len(config.PasswordHashing.Pepper) > 0
+}
+
+// pepper keys the password with an HMAC. The base64 digest keeps the input printable and under
+// the 72 bytes bcrypt reads.
+func pepper(password string) string {
+	mac := hmac.New(sha256.New, config.PasswordHashing.Pepper)
+	mac.Write([]byte(password))
+	return base64.RawStdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func currentArgon2Params() argon2Params {
+	return argon2Params{
+		memory:  config.PasswordHashing.Argon2Memory,
+		time:    config.PasswordHashing.Argon2Time,
+		threads: config.PasswordHashing.Argon2Threads,
+	}
+}
+
+func decodeArgon2id(hashedPassword string) (argon2Params, []byte, []byte, error) {
+	var params argon2Params
+
+	parts := strings.Split(hashedPassword, "$")
+	if len(parts) != 6 || parts[1] != AlgorithmArgon2id {
+		return params, nil, nil, ErrUnknownPasswordHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, ErrUnknownPasswordHash
+	}
+
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.memory, &params.time, &params.threads); err != nil {
+		return params, nil, nil, ErrUnknownPasswordHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, ErrUnknownPasswordHash
+	}
+
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return params, nil, nil, ErrUnknownPasswordHash
+	}
+
+	return params, salt, key, nil
 }
@@ -0,0 +1,37 @@
+package secure
+
+import (
+	"crypto/rand"
+	"math/big"
+	"strings"
+)
+
+const recoveryCodeAlphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+
+// GenerateRecoveryCode returns a random code such as "k7m2p-x9qrt", leaving out characters
+// easily mistaken for one another when copied from paper.
+func GenerateRecoveryCode() (string, error) {
+	var code strings.Builder
+	max := big.NewInt(int64(len(recoveryCodeAlphabet)))
+
+	for i := 0; i < 10; i++ {
+		if i == 5 {
+			code.WriteByte('-')
+		}
+
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		code.WriteByte(recoveryCodeAlphabet[n.Int64()])
+	}
+
+	return code.String(), nil
+}
+
+// HashRecoveryCode hashes a recovery code ignoring case, spaces and dashes, so it matches
+// however the user typed it.
+func HashRecoveryCode(code string) string {
+	normalized := strings.NewReplacer("-", "", " ", "").Replace(strings.ToLower(code))
+	return HashToken(normalized)
+}
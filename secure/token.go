@@ -0,0 +1,40 @@
+package secure
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+
+	"github.com/OVillas/autentication/config"
+)
+
+// GenerateOpaqueToken returns a random 256-bit value encoded as URL-safe base64.
+func GenerateOpaqueToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// HashToken returns the hex encoded SHA-256 of an opaque token, the only form we persist.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Sign returns an HMAC-SHA256 of message under the secret key, encoded as URL-safe base64, so
+// links such as the data export downloads can be checked without storing them.
+func Sign(message string) string {
+	mac := hmac.New(sha256.New, config.SecretKey)
+	mac.Write([]byte(message))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySignature reports, in constant time, whether signature was returned by Sign for message.
+func VerifySignature(message string, signature string) bool {
+	return hmac.Equal([]byte(Sign(message)), []byte(signature))
+}
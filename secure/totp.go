@@ -0,0 +1,81 @@
+package secure
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image/png"
+	"time"
+
+	"github.com/OVillas/autentication/config"
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/hotp"
+	"github.com/pquerna/otp/totp"
+)
+
+const totpQRCodeSize = 256
+
+var totpValidateOptions = totp.ValidateOpts{
+	Period:    30,
+	Skew:      1,
+	Digits:    otp.DigitsSix,
+	Algorithm: otp.AlgorithmSHA1,
+}
+
+// TOTPKey is a freshly generated authenticator app secret with what the user needs to add it:
+// the otpauth URI and the same URI as a PNG QR code in a data URL.
+type TOTPKey struct {
+	Secret string
+	URI    string
+	QRCode string
+}
+
+func GenerateTOTPKey(accountName string) (*TOTPKey, error) {
+	key, err := totp.Generate(totp.GenerateOpts{
+		Issuer:      config.TOTPIssuer,
+		AccountName: accountName,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	image, err := key.Image(totpQRCodeSize, totpQRCodeSize)
+	if err != nil {
+		return nil, err
+	}
+
+	var qrCode bytes.Buffer
+	if err := png.Encode(&qrCode, image); err != nil {
+		return nil, err
+	}
+
+	return &TOTPKey{
+		Secret: key.Secret(),
+		URI:    key.URL(),
+		QRCode: "data:image/png;base64," + base64.StdEncoding.EncodeToString(qrCode.Bytes()),
+	}, nil
+}
+
+// ValidateTOTP accepts the code of the current 30 second step or of the adjacent ones, to
+// tolerate clock drift on the phone, and returns the step it matched. Within those steps a code is
+// good more than once: the caller refuses steps it already accepted.
+func ValidateTOTP(code string, secret string) (int64, bool) {
+	period := int64(totpValidateOptions.Period)
+	skew := int64(totpValidateOptions.Skew)
+	current := time.Now().Unix() / period
+
+	for step := current - skew; step <= current+skew; step++ {
+		valid, err := hotp.ValidateCustom(code, uint64(step), secret, hotp.ValidateOpts{
+			Digits:    totpValidateOptions.Digits,
+			Algorithm: totpValidateOptions.Algorithm,
+		})
+		if err != nil {
+			return 0, false
+		}
+
+		if valid {
+			return step, true
+		}
+	}
+
+	return 0, false
+}
@@ -0,0 +1,167 @@
+package service
+
+import (
+	"log/slog"
+	"strings"
+
+	"github.com/OVillas/autentication/domain"
+	"github.com/OVillas/autentication/secure"
+	"github.com/google/uuid"
+	"github.com/samber/do"
+)
+
+type apiKeyService struct {
+	i                *do.Injector
+	apiKeyRepository domain.ApiKeyRepository
+	auditService     domain.AuditService
+}
+
+func NewApiKeyService(i *do.Injector) (domain.ApiKeyService, error) {
+	apiKeyRepository := do.MustInvoke[domain.ApiKeyRepository](i)
+	auditService := do.MustInvoke[domain.AuditService](i)
+	return &apiKeyService{
+		i:                i,
+		apiKeyRepository: apiKeyRepository,
+		auditService:     auditService,
+	}, nil
+}
+
+func (aks *apiKeyService) Create(viewer domain.Viewer, payLoad domain.ApiKeyPayLoad) (*domain.ApiKeyResponse, error) {
+	log := slog.With(
+		slog.String("service", "apiKey"),
+		slog.String("func", "Create"))
+
+	log.Info("Create initiated")
+
+	key, err := newApiKey()
+	if err != nil {
+		log.Error("Error trying to generate api key", slog.Any("error", err))
+		return nil, domain.ErrCreateApiKey
+	}
+
+	id, err := uuid.NewRandom()
+	if err != nil {
+		log.Error("Error trying to generate api key id", slog.Any("error", err))
+		return nil, domain.ErrCreateApiKey
+	}
+
+	var allowedEndpoints []string
+	for _, endpoint := range payLoad.AllowedEndpoints {
+		allowedEndpoints = append(allowedEndpoints, strings.TrimSpace(endpoint))
+	}
+
+	apiKey := domain.ApiKey{
+		ID:               id.String(),
+		Name:             strings.TrimSpace(payLoad.Name),
+		SecretHash:       secure.HashToken(key),
+		AllowedEndpoints: strings.Join(allowedEndpoints, ","),
+	}
+
+	if err := aks.apiKeyRepository.Create(apiKey); err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return nil, domain.ErrCreateApiKey
+	}
+
+	aks.auditService.Record(domain.AuditEventApiKeyCreated, apiKey.ID, viewer.Actor(), slog.String("name", apiKey.Name))
+
+	response := apiKey.ToApiKeyResponse()
+	response.Key = key
+
+	log.Info("Create executed successfully")
+	return response, nil
+}
+
+func (aks *apiKeyService) GetAll() ([]domain.ApiKeyResponse, error) {
+	log := slog.With(
+		slog.String("service", "apiKey"),
+		slog.String("func", "GetAll"))
+
+	log.Info("GetAll initiated")
+
+	apiKeys, err := aks.apiKeyRepository.GetAll()
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return nil, domain.ErrGetApiKey
+	}
+
+	var response []domain.ApiKeyResponse
+	for _, apiKey := range apiKeys {
+		response = append(response, *apiKey.ToApiKeyResponse())
+	}
+
+	log.Info("GetAll executed successfully")
+	return response, nil
+}
+
+// Rotate replaces the secret of an active key; the previous secret stops working at once.
+func (aks *apiKeyService) Rotate(viewer domain.Viewer, id string) (*domain.ApiKeyResponse, error) {
+	log := slog.With(
+		slog.String("service", "apiKey"),
+		slog.String("func", "Rotate"))
+
+	log.Info("Rotate initiated")
+
+	key, err := newApiKey()
+	if err != nil {
+		log.Error("Error trying to generate api key", slog.Any("error", err))
+		return nil, domain.ErrRotateApiKey
+	}
+
+	rotated, err := aks.apiKeyRepository.UpdateSecretHash(id, secure.HashToken(key))
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return nil, domain.ErrRotateApiKey
+	}
+
+	if !rotated {
+		log.Warn("Api key not found with this id: " + id)
+		return nil, domain.ErrApiKeyNotFound
+	}
+
+	aks.auditService.Record(domain.AuditEventApiKeyRotated, id, viewer.Actor())
+
+	apiKey, err := aks.apiKeyRepository.GetById(id)
+	if err != nil || apiKey == nil {
+		log.Error("Error trying to get rotated api key", slog.Any("error", err))
+		return nil, domain.ErrGetApiKey
+	}
+
+	response := apiKey.ToApiKeyResponse()
+	response.Key = key
+
+	log.Info("Rotate executed successfully")
+	return response, nil
+}
+
+func (aks *apiKeyService) Revoke(viewer domain.Viewer, id string) error {
+	log := slog.With(
+		slog.String("service", "apiKey"),
+		slog.String("func", "Revoke"))
+
+	log.Info("Revoke initiated")
+
+	revoked, err := aks.apiKeyRepository.Revoke(id)
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return domain.ErrRevokeToken
+	}
+
+	if !revoked {
+		log.Warn("Api key not found with this id: " + id)
+		return domain.ErrApiKeyNotFound
+	}
+
+	aks.auditService.Record(domain.AuditEventApiKeyRevoked, id, viewer.Actor())
+
+	log.Info("Revoke executed successfully")
+	return nil
+}
+
+func newApiKey() (string, error) {
+	secret, err := secure.GenerateOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+
+	return domain.ApiKeyPrefix + secret, nil
+}
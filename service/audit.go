@@ -0,0 +1,160 @@
+package service
+
+import (
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"github.com/OVillas/autentication/domain"
+	"github.com/google/uuid"
+	"github.com/samber/do"
+)
+
+const (
+	auditBufferSize    = 4096
+	auditBatchSize     = 100
+	auditFlushInterval = time.Second
+)
+
+type auditService struct {
+	i               *do.Injector
+	auditRepository domain.AuditRepository
+	entries         chan domain.AuditEntry
+}
+
+func NewAuditService(i *do.Injector) (domain.AuditService, error) {
+	auditRepository := do.MustInvoke[domain.AuditRepository](i)
+	as := &auditService{
+		i:               i,
+		auditRepository: auditRepository,
+		entries:         make(chan domain.AuditEntry, auditBufferSize),
+	}
+
+	go as.writeEntries()
+
+	return as, nil
+}
+
+// Record adds a security relevant event to the audit trail. Entries carry the "audit" message in
+// the logs so they can be routed apart from the application logs. Details of the event, such as
+// the reason an admin gave, go in details.
+func (as *auditService) Record(event string, targetID string, actor domain.Actor, details ...slog.Attr) {
+	args := []any{
+		slog.String("event", event),
+		slog.String("targetId", targetID),
+		slog.String("actorId", actor.ID),
+		slog.String("actor", actor.Type),
+		slog.String("ip", actor.IP),
+	}
+
+	values := make(map[string]string, len(details))
+	for _, detail := range details {
+		args = append(args, detail)
+		values[detail.Key] = detail.Value.String()
+	}
+
+	slog.Info("audit", args...)
+
+	entry := domain.AuditEntry{
+		ID:        uuid.NewString(),
+		Event:     event,
+		ActorID:   actor.ID,
+		ActorType: actor.Type,
+		TargetID:  targetID,
+		IP:        actor.IP,
+		UserAgent: actor.UserAgent,
+		CreatedAt: time.Now(),
+	}
+
+	if len(values) > 0 {
+		data, _ := json.Marshal(values)
+		entry.Details = string(data)
+	}
+
+	select {
+	case as.entries <- entry:
+	default:
+		slog.Error("Audit buffer full, entry only kept in the logs", slog.String("event", event))
+	}
+}
+
+func (as *auditService) GetAll(query domain.AuditQuery) (*domain.AuditPage, error) {
+	log := slog.With(
+		slog.String("service", "audit"),
+		slog.String("func", "GetAll"))
+
+	log.Info("GetAll initiated")
+
+	entries, total, err := as.auditRepository.GetAll(query)
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return nil, domain.ErrGetAuditEntries
+	}
+
+	pageRequest := query.PageRequest
+	auditPage := &domain.AuditPage{
+		Items:   make([]domain.AuditEntryResponse, 0, len(entries)),
+		PerPage: pageRequest.PerPage,
+		Total:   total,
+		HasNext: len(entries) > pageRequest.PerPage,
+		HasPrev: pageRequest.After != nil || pageRequest.Page > 1,
+	}
+
+	if auditPage.HasNext {
+		entries = entries[:pageRequest.PerPage]
+	}
+
+	for _, entry := range entries {
+		auditPage.Items = append(auditPage.Items, *entry.ToAuditEntryResponse())
+	}
+
+	if pageRequest.After == nil {
+		auditPage.Page = pageRequest.Page
+	}
+
+	if auditPage.HasNext {
+		last := entries[len(entries)-1]
+		auditPage.NextCursor = domain.Cursor{CreatedAt: last.CreatedAt, ID: last.ID}.Encode()
+	}
+
+	log.Info("GetAll executed successfully")
+	return auditPage, nil
+}
+
+// Private session
+
+// writeEntries stores the recorded entries in batches of up to auditBatchSize, waiting at most
+// auditFlushInterval before writing a smaller one.
+func (as *auditService) writeEntries() {
+	log := slog.With(
+		slog.String("service", "audit"),
+		slog.String("func", "writeEntries"))
+
+	ticker := time.NewTicker(auditFlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]domain.AuditEntry, 0, auditBatchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+
+		if err := as.auditRepository.CreateBatch(batch); err != nil {
+			log.Error("Error trying to write audit entries, they are only kept in the logs",
+				slog.Int("count", len(batch)), slog.Any("error", err))
+		}
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case entry := <-as.entries:
+			batch = append(batch, entry)
+			if len(batch) == auditBatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/OVillas/autentication/config"
+	"github.com/OVillas/autentication/domain"
+	"github.com/samber/do"
+)
+
+// maxAvatarDimension bounds the width and the height of an avatar, so that a small file cannot
+// decode into a huge image.
+const maxAvatarDimension = 4096
+
+type avatarService struct {
+	i              *do.Injector
+	userRepository domain.UserRepository
+	avatarStorage  domain.AvatarStorage
+}
+
+func NewAvatarService(i *do.Injector) (domain.AvatarService, error) {
+	userRepository := do.MustInvoke[domain.UserRepository](i)
+	avatarStorage := do.MustInvoke[domain.AvatarStorage](i)
+	return &avatarService{
+		i:              i,
+		userRepository: userRepository,
+		avatarStorage:  avatarStorage,
+	}, nil
+}
+
+// Upload stores content, a JPEG, PNG or GIF image of at most config.Avatar.MaxSize bytes, as the
+// avatar of the user. JPEGs stay JPEGs and the others become PNGs. The URL changes on every
+// upload, so caches do not keep showing the previous avatar.
+func (as *avatarService) Upload(ctx context.Context, userID string, content []byte) (*domain.AvatarResponse, error) {
+	log := slog.With(
+		slog.String("service", "avatar"),
+		slog.String("func", "Upload"))
+
+	log.Info("Upload initiated")
+
+	if int64(len(content)) > config.Avatar.MaxSize {
+		log.Warn("Avatar too large", slog.Int("size", len(content)))
+		return nil, domain.ErrAvatarTooLarge
+	}
+
+	user, err := as.userRepository.GetById(ctx, userID)
+	if err != nil {
+		log.Error("Failed to obtain user by id", slog.Any("error", err))
+		return nil, domain.ErrGetUser
+	}
+
+	if user == nil {
+		log.Warn("User not found with this id: " + userID)
+		return nil, domain.ErrUserNotFound
+	}
+
+	encoded, contentType, err := encodeAvatar(content)
+	if err != nil {
+		log.Warn("Avatar refused", slog.Any("error", err))
+		return nil, err
+	}
+
+	avatarURL, err := as.avatarStorage.Put(user.ID, encoded, contentType)
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return nil, domain.ErrSaveAvatar
+	}
+
+	avatarURL += "?v=" + strconv.FormatInt(time.Now().Unix(), 10)
+	if err := as.userRepository.SetAvatarURL(ctx, user.ID, &avatarURL); err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return nil, domain.ErrSaveAvatar
+	}
+
+	log.Info("Upload executed successfully")
+	return &domain.AvatarResponse{AvatarURL: avatarURL}, nil
+}
+
+// Delete removes the avatar of the user, who gets the fallback one again.
+func (as *avatarService) Delete(ctx context.Context, userID string) error {
+	log := slog.With(
+		slog.String("service", "avatar"),
+		slog.String("func", "Delete"))
+
+	log.Info("Delete initiated")
+
+	user, err := as.userRepository.GetById(ctx, userID)
+	if err != nil {
+		log.Error("Failed to obtain user by id", slog.Any("error", err))
+		return domain.ErrGetUser
+	}
+
+	if user == nil {
+		log.Warn("User not found with this id: " + userID)
+		return domain.ErrUserNotFound
+	}
+
+	if user.AvatarURL == nil {
+		log.Info("Delete executed successfully, no avatar")
+		return nil
+	}
+
+	if err := as.userRepository.SetAvatarURL(ctx, user.ID, nil); err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return domain.ErrDeleteAvatar
+	}
+
+	if err := as.avatarStorage.Delete(user.ID); err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return domain.ErrDeleteAvatar
+	}
+
+	log.Info("Delete executed successfully")
+	return nil
+}
+
+// Private session
+// encodeAvatar decodes content and encodes its pixels again, leaving out the metadata of the
+// file such as the EXIF location of a photo. It returns the new file and its content type.
+func encodeAvatar(content []byte) ([]byte, string, error) {
+	switch http.DetectContentType(content) {
+	case "image/jpeg", "image/png", "image/gif":
+	default:
+		return nil, "", domain.ErrAvatarType
+	}
+
+	imageConfig, format, err := image.DecodeConfig(bytes.NewReader(content))
+	if err != nil {
+		return nil, "", domain.ErrInvalidAvatar
+	}
+
+	if imageConfig.Width > maxAvatarDimension || imageConfig.Height > maxAvatarDimension {
+		return nil, "", domain.ErrAvatarDimension
+	}
+
+	decoded, _, err := image.Decode(bytes.NewReader(content))
+	if err != nil {
+		return nil, "", domain.ErrInvalidAvatar
+	}
+
+	var encoded bytes.Buffer
+	if format == "jpeg" {
+		if err := jpeg.Encode(&encoded, decoded, &jpeg.Options{Quality: 90}); err != nil {
+			return nil, "", err
+		}
+		return encoded.Bytes(), "image/jpeg", nil
+	}
+
+	if err := png.Encode(&encoded, decoded); err != nil {
+		return nil, "", err
+	}
+	return encoded.Bytes(), "image/png", nil
+}
@@ -0,0 +1,166 @@
+package service
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/OVillas/autentication/config"
+	"github.com/OVillas/autentication/domain"
+	"github.com/samber/do"
+)
+
+var avatarStorageClient = &http.Client{Timeout: 10 * time.Second}
+
+// NewAvatarStorage stores the avatars as config.Avatar.Storage says. Another domain.AvatarStorage
+// can be provided in its place.
+func NewAvatarStorage(i *do.Injector) (domain.AvatarStorage, error) {
+	if config.Avatar.Storage == config.AvatarStorageS3 {
+		return &s3AvatarStorage{
+			endpoint:        config.Avatar.S3Endpoint,
+			region:          config.Avatar.S3Region,
+			bucket:          config.Avatar.S3Bucket,
+			accessKeyID:     config.Avatar.S3AccessKeyID,
+			secretAccessKey: config.Avatar.S3SecretAccessKey,
+			publicURL:       config.Avatar.PublicURL,
+		}, nil
+	}
+
+	return &localAvatarStorage{dir: config.Avatar.LocalDir, publicURL: config.Avatar.PublicURL}, nil
+}
+
+// localAvatarStorage writes the avatars as files of dir, named by their key.
+type localAvatarStorage struct {
+	dir       string
+	publicURL string
+}
+
+func (las *localAvatarStorage) Put(key string, content []byte, contentType string) (string, error) {
+	if err := os.MkdirAll(las.dir, 0o755); err != nil {
+		return "", err
+	}
+
+	// Written aside and renamed, so the avatar being served is never half written.
+	temporary := filepath.Join(las.dir, "."+key)
+	if err := os.WriteFile(temporary, content, 0o644); err != nil {
+		return "", err
+	}
+
+	if err := os.Rename(temporary, filepath.Join(las.dir, key)); err != nil {
+		return "", err
+	}
+
+	return las.publicURL + "/" + url.PathEscape(key), nil
+}
+
+func (las *localAvatarStorage) Delete(key string) error {
+	err := os.Remove(filepath.Join(las.dir, key))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
+
+// s3AvatarStorage keeps the avatars as objects of bucket, addressed path style so that any S3
+// compatible storage works. Requests are signed with AWS Signature Version 4.
+type s3AvatarStorage struct {
+	endpoint        string
+	region          string
+	bucket          string
+	accessKeyID     string
+	secretAccessKey string
+	publicURL       string
+}
+
+func (sas *s3AvatarStorage) Put(key string, content []byte, contentType string) (string, error) {
+	response, err := sas.do(http.MethodPut, key, content, contentType)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("object storage returned status %d", response.StatusCode)
+	}
+
+	return sas.publicURL + "/" + url.PathEscape(key), nil
+}
+
+func (sas *s3AvatarStorage) Delete(key string) error {
+	response, err := sas.do(http.MethodDelete, key, nil, "")
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusNoContent && response.StatusCode != http.StatusOK &&
+		response.StatusCode != http.StatusNotFound {
+		return fmt.Errorf("object storage returned status %d", response.StatusCode)
+	}
+
+	return nil
+}
+
+func (sas *s3AvatarStorage) do(method string, key string, content []byte, contentType string) (*http.Response, error) {
+	path := "/" + url.PathEscape(sas.bucket) + "/" + url.PathEscape(key)
+	request, err := http.NewRequest(method, sas.endpoint+path, bytes.NewReader(content))
+	if err != nil {
+		return nil, err
+	}
+
+	if contentType != "" {
+		request.Header.Set("Content-Type", contentType)
+	}
+	sas.sign(request, path, content, time.Now().UTC())
+
+	return avatarStorageClient.Do(request)
+}
+
+// sign adds the Authorization header of AWS Signature Version 4, covering the host, the date and
+// the hash of the payload.
+func (sas *s3AvatarStorage) sign(request *http.Request, path string, content []byte, now time.Time) {
+	payloadHash := sha256Hex(content)
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+
+	request.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	request.Header.Set("X-Amz-Date", amzDate)
+
+	signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+	canonicalRequest := request.Method + "\n" + path + "\n\n" +
+		"host:" + request.URL.Host + "\n" +
+		"x-amz-content-sha256:" + payloadHash + "\n" +
+		"x-amz-date:" + amzDate + "\n\n" +
+		signedHeaders + "\n" + payloadHash
+
+	scope := date + "/" + sas.region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	signingKey := hmacSHA256([]byte("AWS4"+sas.secretAccessKey), date)
+	for _, part := range []string{sas.region, "s3", "aws4_request"} {
+		signingKey = hmacSHA256(signingKey, part)
+	}
+
+	request.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+sas.accessKeyID+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+hex.EncodeToString(hmacSHA256(signingKey, stringToSign)))
+}
+
+func sha256Hex(content []byte) string {
+	hash := sha256.Sum256(content)
+	return hex.EncodeToString(hash[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
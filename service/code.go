@@ -1,73 +1,267 @@
 package service
 
 import (
+	"context"
 	"fmt"
 	"log/slog"
+	"math"
+	"net/url"
+	"strings"
 	"time"
 
+	"github.com/OVillas/autentication/auth"
+	"github.com/OVillas/autentication/config"
 	"github.com/OVillas/autentication/domain"
+	"github.com/OVillas/autentication/secure"
 	"github.com/OVillas/autentication/util"
 	"github.com/samber/do"
 )
 
-var confirmationsCodes map[string]domain.ConfirmationCode
-
-func init() {
-	confirmationsCodes = make(map[string]domain.ConfirmationCode)
-}
-
 type confirmationCodeService struct {
-	i              *do.Injector
-	userRepository domain.UserRepository
-	emailService   domain.EmailService
+	i                          *do.Injector
+	userRepository             domain.UserRepository
+	confirmationCodeRepository domain.ConfirmationCodeRepository
+	emailService               domain.EmailService
+	smsSender                  domain.SMSSender
+	tokenProvider              auth.TokenProvider
 }
 
 func NewCodeService(i *do.Injector) (domain.ConfirmationCodeService, error) {
 	emailService := do.MustInvoke[domain.EmailService](i)
 	userRepository := do.MustInvoke[domain.UserRepository](i)
+	confirmationCodeRepository := do.MustInvoke[domain.ConfirmationCodeRepository](i)
+	smsSender := do.MustInvoke[domain.SMSSender](i)
+	tokenProvider := do.MustInvoke[auth.TokenProvider](i)
 	return &confirmationCodeService{
-		i:              i,
-		emailService:   emailService,
-		userRepository: userRepository,
+		i:                          i,
+		emailService:               emailService,
+		smsSender:                  smsSender,
+		userRepository:             userRepository,
+		confirmationCodeRepository: confirmationCodeRepository,
+		tokenProvider:              tokenProvider,
 	}, nil
 }
 
-func (ccs *confirmationCodeService) SendConfirmationCode(email string) error {
+func (ccs *confirmationCodeService) SendConfirmationCode(ctx context.Context, tenantID string, email string) error {
 	log := slog.With(
 		slog.String("service", "user"),
 		slog.String("func", "SendConfirmationEmailCode"))
 
 	log.Info("SendConfirmationEmailCode service initiated")
 
-	otp := domain.ConfirmationCode{
-		Code:       util.GenerateOTP(6),
-		ExpiryTime: time.Now().Add(time.Hour),
+	code, err := ccs.IssueConfirmationCode(ccs.confirmationCodeRepository, tenantID, email)
+	if err != nil {
+		return err
 	}
 
-	ccs.addOrUpdateConfirmationCode(email, otp)
+	if err := ccs.EmailConfirmationCode(ctx, tenantID, email, code); err != nil {
+		return err
+	}
+
+	log.Info("SendConfirmationEmailCode executed successfully")
+	return nil
+}
+
+// IssueConfirmationCode stores a new confirmation code of email in confirmationCodes, within the
+// rate limits of the sends, and returns it to be emailed with EmailConfirmationCode. Registration
+// passes the repository of its transaction, so the code is only kept if the user is.
+func (ccs *confirmationCodeService) IssueConfirmationCode(confirmationCodes domain.ConfirmationCodeRepository, tenantID string, email string) (string, error) {
+	log := slog.With(
+		slog.String("service", "code"),
+		slog.String("func", "IssueConfirmationCode"))
+
+	wait, err := confirmationCodes.ReserveSend(tenantID, email, config.OTP.ResendInterval, domain.CodeSendWindow, config.OTP.DailyLimit)
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return "", domain.ErrSaveConfirmationCode
+	}
+
+	if wait > 0 {
+		log.Warn("Confirmation code requested too often for email: " + email)
+		return "", &domain.RateLimitError{RetryAfter: wait}
+	}
+
+	code := generateOTP()
+	if err := confirmationCodes.Set(tenantID, email, secure.HashOTP(email, code), config.OTP.TTL); err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return "", domain.ErrSaveConfirmationCode
+	}
+
+	return code, nil
+}
+
+// EmailConfirmationCode sends code, issued by IssueConfirmationCode, to email.
+func (ccs *confirmationCodeService) EmailConfirmationCode(ctx context.Context, tenantID string, email string, code string) error {
+	log := slog.With(
+		slog.String("service", "code"),
+		slog.String("func", "EmailConfirmationCode"))
 
 	subject := "Confirmação de cadastro"
-	content := fmt.Sprintf("<h1>Olá!</h1><p>Seu código de confirmação é: <h2><b>%s</b></h2></p>", otp.Code)
+	content := fmt.Sprintf("<h1>Olá!</h1><p>Seu código de confirmação é: <h2><b>%s</b></h2></p>"+
+		"<p>Ele vale por %s.</p>", code, formatTTL(config.OTP.TTL))
+	if link := ccs.confirmationLink(ctx, tenantID, email); link != "" {
+		content += fmt.Sprintf("<p>Ou confirme seu e-mail pelo link abaixo, válido por 24 horas:</p><p><a href=\"%s\">Confirmar e-mail</a></p>", link)
+	}
 	to := []string{email}
 
 	err := ccs.emailService.SendEmail(subject, content, to)
 	if err != nil {
-		log.Error("Errors: ", err)
+		log.Error("Errors: ", slog.Any("error", err))
 		return domain.ErrToSendConfirmationCode
 	}
 
-	log.Info("SendConfirmationEmailCode executed successfully")
 	return nil
 }
 
-func (c *confirmationCodeService) ConfirmCode(confirmCode domain.ConfirmCode) (*domain.User, error) {
+// SendTwoFactorCode emails the second factor of a login. The code lives as long as the login
+// challenge it completes.
+func (ccs *confirmationCodeService) SendTwoFactorCode(tenantID string, email string) error {
+	log := slog.With(
+		slog.String("service", "code"),
+		slog.String("func", "SendTwoFactorCode"))
+
+	log.Info("SendTwoFactorCode service initiated")
+
+	code := generateOTP()
+	if err := ccs.confirmationCodeRepository.Set(tenantID, email, secure.HashOTP(email, code), domain.TwoFactorChallengeTTL); err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return domain.ErrSaveConfirmationCode
+	}
+
+	subject := "Código de verificação de login"
+	content := fmt.Sprintf("<h1>Olá!</h1><p>Seu código para concluir o login é: <h2><b>%s</b></h2></p>"+
+		"<p>Se não foi você quem tentou entrar, altere sua senha.</p>", code)
+	to := []string{email}
+
+	err := ccs.emailService.SendEmail(subject, content, to)
+	if err != nil {
+		log.Error("Errors: ", slog.Any("error", err))
+		return domain.ErrSendTwoFactorCode
+	}
+
+	log.Info("SendTwoFactorCode executed successfully")
+	return nil
+}
+
+// SendEmailChangeCode emails the code confirming the new address of an email change. It shares
+// the rate limits of the confirmation code.
+func (ccs *confirmationCodeService) SendEmailChangeCode(tenantID string, email string) error {
+	log := slog.With(
+		slog.String("service", "code"),
+		slog.String("func", "SendEmailChangeCode"))
+
+	log.Info("SendEmailChangeCode service initiated")
+
+	wait, err := ccs.confirmationCodeRepository.ReserveSend(tenantID, email, config.OTP.ResendInterval, domain.CodeSendWindow, config.OTP.DailyLimit)
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return domain.ErrSaveConfirmationCode
+	}
+
+	if wait > 0 {
+		log.Warn("Email change code requested too often for email: " + email)
+		return &domain.RateLimitError{RetryAfter: wait}
+	}
+
+	code := generateOTP()
+	if err := ccs.confirmationCodeRepository.Set(tenantID, email, secure.HashOTP(email, code), config.OTP.TTL); err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return domain.ErrSaveConfirmationCode
+	}
+
+	subject := "Confirme seu novo e-mail"
+	content := fmt.Sprintf("<h1>Olá!</h1><p>Para passar a usar este e-mail na sua conta, informe o código: <h2><b>%s</b></h2></p>"+
+		"<p>Ele vale por %s. Se não foi você quem pediu, ignore este e-mail.</p>", code, formatTTL(config.OTP.TTL))
+
+	if err := ccs.emailService.SendEmail(subject, content, []string{email}); err != nil {
+		log.Error("Errors: ", slog.Any("error", err))
+		return domain.ErrToSendConfirmationCode
+	}
+
+	log.Info("SendEmailChangeCode executed successfully")
+	return nil
+}
+
+// SendPhoneConfirmationCode texts the code confirming a phone number. It shares the rate limits
+// of the email codes, counted per number.
+func (ccs *confirmationCodeService) SendPhoneConfirmationCode(tenantID string, phoneNumber string) error {
+	log := slog.With(
+		slog.String("service", "code"),
+		slog.String("func", "SendPhoneConfirmationCode"))
+
+	log.Info("SendPhoneConfirmationCode service initiated")
+
+	wait, err := ccs.confirmationCodeRepository.ReserveSend(tenantID, phoneNumber, config.OTP.ResendInterval, domain.CodeSendWindow, config.OTP.DailyLimit)
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return domain.ErrSaveConfirmationCode
+	}
+
+	if wait > 0 {
+		log.Warn("Phone confirmation code requested too often for number: " + phoneNumber)
+		return &domain.RateLimitError{RetryAfter: wait}
+	}
+
+	code := generateOTP()
+	if err := ccs.confirmationCodeRepository.Set(tenantID, phoneNumber, secure.HashOTP(phoneNumber, code), config.OTP.TTL); err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return domain.ErrSaveConfirmationCode
+	}
+
+	message := fmt.Sprintf("Seu código de confirmação é %s. Ele vale por %s.", code, formatTTL(config.OTP.TTL))
+	if err := ccs.smsSender.Send(phoneNumber, message); err != nil {
+		log.Error("Errors: ", slog.Any("error", err))
+		return domain.ErrToSendPhoneConfirmCode
+	}
+
+	log.Info("SendPhoneConfirmationCode executed successfully")
+	return nil
+}
+
+// SendTwoFactorSMS texts the second factor of a login to a confirmed phone number. The code
+// lives as long as the login challenge it completes.
+func (ccs *confirmationCodeService) SendTwoFactorSMS(tenantID string, phoneNumber string) error {
+	log := slog.With(
+		slog.String("service", "code"),
+		slog.String("func", "SendTwoFactorSMS"))
+
+	log.Info("SendTwoFactorSMS service initiated")
+
+	wait, err := ccs.confirmationCodeRepository.ReserveSend(tenantID, phoneNumber, config.OTP.ResendInterval, domain.CodeSendWindow, config.OTP.DailyLimit)
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return domain.ErrSaveConfirmationCode
+	}
+
+	if wait > 0 {
+		log.Warn("Login code requested too often for number: " + phoneNumber)
+		return &domain.RateLimitError{RetryAfter: wait}
+	}
+
+	code := generateOTP()
+	if err := ccs.confirmationCodeRepository.Set(tenantID, phoneNumber, secure.HashOTP(phoneNumber, code), domain.TwoFactorChallengeTTL); err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return domain.ErrSaveConfirmationCode
+	}
+
+	message := fmt.Sprintf("Seu código para concluir o login é %s. Se não foi você quem tentou entrar, altere sua senha.", code)
+	if err := ccs.smsSender.Send(phoneNumber, message); err != nil {
+		log.Error("Errors: ", slog.Any("error", err))
+		return domain.ErrSendTwoFactorCode
+	}
+
+	log.Info("SendTwoFactorSMS executed successfully")
+	return nil
+}
+
+func (c *confirmationCodeService) ConfirmCode(ctx context.Context, tenantID string, confirmCode domain.ConfirmCode) (*domain.User, error) {
 	log := slog.With(
 		slog.String("service", "user"),
 		slog.String("func", "confirmCode"))
 
 	log.Info("Confirming code service initiated")
 
-	user, err := c.userRepository.GetByEmail(confirmCode.Email)
+	user, err := c.userRepository.GetByEmail(ctx, tenantID, confirmCode.Email)
 	if err != nil {
 		log.Warn("Failed to obtain user by email")
 		return nil, domain.ErrGetUser
@@ -78,41 +272,126 @@ func (c *confirmationCodeService) ConfirmCode(confirmCode domain.ConfirmCode) (*
 		return nil, domain.ErrUserNotFound
 	}
 
-	confirmationCode, ok := confirmationsCodes[confirmCode.Email]
-	if !ok {
-		log.Error("OTP not found with this email: " + confirmCode.Email)
-		return nil, domain.ErrOTPNotFound
+	if err := c.CheckCode(tenantID, confirmCode); err != nil {
+		return nil, err
+	}
+
+	log.Info("Code confirmed successfully")
+	return user, nil
+}
+
+// CheckCode consumes the code sent to an email, whether or not the email belongs to an account.
+func (c *confirmationCodeService) CheckCode(tenantID string, confirmCode domain.ConfirmCode) error {
+	log := slog.With(
+		slog.String("service", "code"),
+		slog.String("func", "CheckCode"))
+
+	confirmationCode, err := c.confirmationCodeRepository.Get(tenantID, confirmCode.Email)
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return domain.ErrGetConfirmationCode
+	}
+
+	if confirmationCode == nil {
+		log.Warn("OTP not found or expired with this email: " + confirmCode.Email)
+		return domain.ErrOTPNotFound
 	}
 
-	if time.Now().After(confirmationCode.ExpiryTime) {
-		log.Warn("Token expired")
-		return nil, domain.ErrInvalidOTP
+	if confirmationCode.Attempts >= config.OTP.MaxAttempts {
+		log.Warn("OTP locked after too many attempts for email: " + confirmCode.Email)
+		return domain.ErrTooManyOTPAttempts
 	}
 
-	if confirmationCode.Code != confirmCode.Code {
+	if !secure.CheckOTP(confirmationCode.CodeHash, confirmCode.Email, strings.ToUpper(confirmCode.Code)) {
 		log.Warn("incorrect token")
-		return nil, domain.ErrInvalidOTP
+		return c.countFailedAttempt(tenantID, confirmCode.Email)
 	}
 
-	log.Info("Code confirmed successfully")
-	return user, nil
+	// A code opens one confirmation only.
+	if err := c.confirmationCodeRepository.Delete(tenantID, confirmCode.Email); err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return domain.ErrGetConfirmationCode
+	}
+
+	return nil
 }
 
 // Private session
-func (ccs *confirmationCodeService) addOrUpdateConfirmationCode(email string, code domain.ConfirmationCode) {
+// countFailedAttempt records a wrong code in the store holding it and throws the code away once
+// OTP.MaxAttempts is reached, so guesses are limited across every instance of the API.
+func (c *confirmationCodeService) countFailedAttempt(tenantID string, email string) error {
 	log := slog.With(
-		slog.String("service", "user"),
-		slog.String("func", "addOrUpdateConfirmationCode"))
+		slog.String("service", "code"),
+		slog.String("func", "countFailedAttempt"))
+
+	attempts, err := c.confirmationCodeRepository.IncrementAttempts(tenantID, email)
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return domain.ErrInvalidOTP
+	}
+
+	if attempts < config.OTP.MaxAttempts {
+		return domain.ErrInvalidOTP
+	}
+
+	log.Warn("OTP invalidated after too many attempts for email: " + email)
+	if err := c.confirmationCodeRepository.Delete(tenantID, email); err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+	}
+
+	return domain.ErrTooManyOTPAttempts
+}
+
+// confirmationLink returns a link confirming the email in one click, only when the mode is on
+// and the email belongs to an account not confirmed yet.
+func (ccs *confirmationCodeService) confirmationLink(ctx context.Context, tenantID string, email string) string {
+	log := slog.With(
+		slog.String("service", "code"),
+		slog.String("func", "confirmationLink"))
 
-	log.Info("Add or updating confirmation code initiated")
+	if !config.EmailConfirmationLink {
+		return ""
+	}
+
+	user, err := ccs.userRepository.GetByEmail(ctx, tenantID, email)
+	if err != nil {
+		log.Error("Failed to obtain user by email", slog.Any("error", err))
+		return ""
+	}
+
+	if user == nil || user.EmailConfirmed {
+		return ""
+	}
+
+	token, err := ccs.tokenProvider.CreateEmailVerificationToken(*user)
+	if err != nil {
+		log.Error("Error trying to create email verification token", slog.Any("error", err))
+		return ""
+	}
+
+	return config.EmailConfirmationURL + "?token=" + url.QueryEscape(token)
+}
+
+func generateOTP() string {
+	if config.OTP.Alphanumeric {
+		return util.GenerateCode(config.OTP.Length, util.OTPAlphanumeric)
+	}
+
+	return util.GenerateCode(config.OTP.Length, util.OTPDigits)
+}
 
-	if existingCode, ok := confirmationsCodes[email]; ok {
-		existingCode.Code = code.Code
-		existingCode.ExpiryTime = code.ExpiryTime
-		confirmationsCodes[email] = existingCode
-	} else {
-		confirmationsCodes[email] = code
+func formatTTL(ttl time.Duration) string {
+	if ttl >= time.Hour && ttl%time.Hour == 0 {
+		hours := int(ttl.Hours())
+		if hours == 1 {
+			return "1 hora"
+		}
+		return fmt.Sprintf("%d horas", hours)
 	}
 
-	log.Info("Add or updating confirmation code executed successfully")
+	minutes := int(math.Ceil(ttl.Minutes()))
+	if minutes == 1 {
+		return "1 minuto"
+	}
+	return fmt.Sprintf("%d minutos", minutes)
 }
@@ -0,0 +1,426 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"log/slog"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/OVillas/autentication/config"
+	"github.com/OVillas/autentication/domain"
+	"github.com/OVillas/autentication/secure"
+	"github.com/google/uuid"
+	"github.com/samber/do"
+)
+
+// dataExportPollInterval is how often pending exports are looked for besides when one is asked
+// for, so the ones left by a restart are still built.
+const dataExportPollInterval = time.Minute
+
+type dataExportService struct {
+	i                      *do.Injector
+	dataExportRepository   domain.DataExportRepository
+	userRepository         domain.UserRepository
+	roleRepository         domain.RoleRepository
+	userIdentityRepository domain.UserIdentityRepository
+	refreshTokenRepository domain.RefreshTokenRepository
+	loginHistoryRepository domain.LoginHistoryRepository
+	auditRepository        domain.AuditRepository
+	auditService           domain.AuditService
+	emailService           domain.EmailService
+	pending                chan struct{}
+}
+
+func NewDataExportService(i *do.Injector) (domain.DataExportService, error) {
+	dataExportRepository := do.MustInvoke[domain.DataExportRepository](i)
+	userRepository := do.MustInvoke[domain.UserRepository](i)
+	roleRepository := do.MustInvoke[domain.RoleRepository](i)
+	userIdentityRepository := do.MustInvoke[domain.UserIdentityRepository](i)
+	refreshTokenRepository := do.MustInvoke[domain.RefreshTokenRepository](i)
+	loginHistoryRepository := do.MustInvoke[domain.LoginHistoryRepository](i)
+	auditRepository := do.MustInvoke[domain.AuditRepository](i)
+	auditService := do.MustInvoke[domain.AuditService](i)
+	emailService := do.MustInvoke[domain.EmailService](i)
+	des := &dataExportService{
+		i:                      i,
+		dataExportRepository:   dataExportRepository,
+		userRepository:         userRepository,
+		roleRepository:         roleRepository,
+		userIdentityRepository: userIdentityRepository,
+		refreshTokenRepository: refreshTokenRepository,
+		loginHistoryRepository: loginHistoryRepository,
+		auditRepository:        auditRepository,
+		auditService:           auditService,
+		emailService:           emailService,
+		pending:                make(chan struct{}, 1),
+	}
+
+	go des.buildExports(context.Background())
+
+	return des, nil
+}
+
+// Request queues an export of the data of the user. While one is still being built, it is
+// returned instead of queueing another.
+func (des *dataExportService) Request(userID string, format string, clientInfo domain.ClientInfo) (*domain.DataExportResponse, error) {
+	log := slog.With(
+		slog.String("service", "dataExport"),
+		slog.String("func", "Request"))
+
+	log.Info("Request initiated")
+
+	pending, err := des.dataExportRepository.GetPendingByUserID(userID)
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return nil, domain.ErrGetDataExport
+	}
+
+	if pending != nil {
+		log.Info("Request executed successfully, export already pending")
+		return pending.ToDataExportResponse(), nil
+	}
+
+	now := time.Now()
+	dataExport := domain.DataExport{
+		ID:        uuid.NewString(),
+		UserID:    userID,
+		Format:    format,
+		Status:    domain.DataExportPending,
+		CreatedAt: now,
+		ExpiresAt: now.Add(config.DataExportRetention),
+	}
+
+	if err := des.dataExportRepository.Create(dataExport); err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return nil, domain.ErrCreateDataExport
+	}
+
+	des.auditService.Record(domain.AuditEventDataExportRequested, userID, domain.UserActor(userID, clientInfo),
+		slog.String("exportId", dataExport.ID), slog.String("format", format))
+
+	select {
+	case des.pending <- struct{}{}:
+	default:
+	}
+
+	log.Info("Request executed successfully")
+	return dataExport.ToDataExportResponse(), nil
+}
+
+// Download returns the export the signed token of its link points to, as long as it has not
+// expired.
+func (des *dataExportService) Download(token string) (*domain.DataExport, error) {
+	log := slog.With(
+		slog.String("service", "dataExport"),
+		slog.String("func", "Download"))
+
+	log.Info("Download initiated")
+
+	id, ok := verifyDataExportToken(token)
+	if !ok {
+		log.Warn("Invalid data export link")
+		return nil, domain.ErrInvalidDataExportLink
+	}
+
+	dataExport, err := des.dataExportRepository.GetByID(id)
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return nil, domain.ErrGetDataExport
+	}
+
+	if dataExport == nil || !dataExport.IsAvailable() {
+		log.Warn("Data export not available: " + id)
+		return nil, domain.ErrInvalidDataExportLink
+	}
+
+	log.Info("Download executed successfully")
+	return dataExport, nil
+}
+
+// Private session
+
+// buildExports builds the pending exports whenever one is asked for, and every
+// dataExportPollInterval, deleting the expired ones as well.
+func (des *dataExportService) buildExports(ctx context.Context) {
+	log := slog.With(
+		slog.String("service", "dataExport"),
+		slog.String("func", "buildExports"))
+
+	ticker := time.NewTicker(dataExportPollInterval)
+	defer ticker.Stop()
+
+	for {
+		deleted, err := des.dataExportRepository.DeleteExpired()
+		if err != nil {
+			log.Error("Error: ", slog.Any("error", err))
+		} else if deleted > 0 {
+			log.Info("Expired data exports deleted", slog.Int64("count", deleted))
+		}
+
+		dataExports, err := des.dataExportRepository.GetPending()
+		if err != nil {
+			log.Error("Error: ", slog.Any("error", err))
+		}
+
+		for _, dataExport := range dataExports {
+			des.buildExport(ctx, dataExport)
+		}
+
+		select {
+		case <-des.pending:
+		case <-ticker.C:
+		}
+	}
+}
+
+func (des *dataExportService) buildExport(ctx context.Context, dataExport domain.DataExport) {
+	log := slog.With(
+		slog.String("service", "dataExport"),
+		slog.String("func", "buildExport"),
+		slog.String("exportId", dataExport.ID))
+
+	user, err := des.userRepository.GetById(ctx, dataExport.UserID)
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return
+	}
+
+	if user == nil {
+		log.Warn("User of the data export no longer exists")
+		if err := des.dataExportRepository.Fail(dataExport.ID); err != nil {
+			log.Error("Error: ", slog.Any("error", err))
+		}
+		return
+	}
+
+	document, err := des.collect(*user)
+	if err != nil {
+		log.Error("Error trying to collect user data", slog.Any("error", err))
+		return
+	}
+
+	var content []byte
+	if dataExport.Format == domain.DataExportFormatZIP {
+		content, err = dataExportZIP(*document)
+	} else {
+		content, err = json.MarshalIndent(document, "", "  ")
+	}
+
+	if err != nil {
+		log.Error("Error trying to encode data export", slog.Any("error", err))
+		if err := des.dataExportRepository.Fail(dataExport.ID); err != nil {
+			log.Error("Error: ", slog.Any("error", err))
+		}
+		return
+	}
+
+	if err := des.dataExportRepository.Complete(dataExport.ID, content); err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return
+	}
+
+	des.emailService.Notify(domain.Notification{
+		Type:       domain.NotificationDataExportReady,
+		Name:       user.Name,
+		Locale:     user.Locale,
+		Timezone:   user.Timezone,
+		Link:       config.DataExportURL + "?token=" + signDataExportToken(dataExport),
+		OccurredAt: dataExport.CreatedAt,
+		DueAt:      dataExport.ExpiresAt,
+	}, []string{user.Email})
+}
+
+// collect gathers the data kept about user. Admins and other users acting on the account are
+// left anonymous in its audit entries.
+func (des *dataExportService) collect(user domain.User) (*domain.DataExportDocument, error) {
+	roles, err := des.roleRepository.GetNamesByUserID(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	userIdentities, err := des.userIdentityRepository.GetByUserID(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshTokens, err := des.refreshTokenRepository.GetActiveByUserID(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	loginAttempts, err := des.loginHistoryRepository.GetAllByUserID(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	auditEntries, err := des.auditRepository.GetByUserID(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	document := &domain.DataExportDocument{
+		GeneratedAt: time.Now(),
+		Profile: domain.DataExportProfile{
+			Id:                  user.ID,
+			Name:                user.Name,
+			Username:            user.Username,
+			Email:               user.Email,
+			EmailConfirmed:      user.EmailConfirmed,
+			PhoneNumber:         user.PhoneNumber,
+			PhoneConfirmed:      user.PhoneConfirmed,
+			Locale:              user.PreferredLocale(),
+			Timezone:            user.PreferredTimezone(),
+			AvatarURL:           user.AvatarURL,
+			Metadata:            user.Metadata,
+			TwoFactorEnabled:    user.TwoFactorAuthActive,
+			LoginAlertsEnabled:  user.LoginAlertsEnabled,
+			Roles:               roles,
+			CreatedAt:           user.CreatedAt,
+			UpdatedAt:           user.UpdatedAt,
+			LastLoginAt:         user.LastLoginAt,
+			DeletionScheduledAt: user.DeletionScheduledAt,
+		},
+		Identities:   []domain.UserIdentityResponse{},
+		Sessions:     []domain.SessionResponse{},
+		LoginHistory: []domain.LoginAttemptResponse{},
+		AuditEntries: []domain.AuditEntryResponse{},
+	}
+
+	for _, userIdentity := range userIdentities {
+		document.Identities = append(document.Identities, *userIdentity.ToUserIdentityResponse())
+	}
+
+	for _, refreshToken := range refreshTokens {
+		document.Sessions = append(document.Sessions, *refreshToken.ToSessionResponse())
+	}
+
+	for _, loginAttempt := range loginAttempts {
+		document.LoginHistory = append(document.LoginHistory, *loginAttempt.ToLoginAttemptResponse())
+	}
+
+	for _, auditEntry := range auditEntries {
+		if auditEntry.ActorID != user.ID {
+			auditEntry.ActorID = ""
+			auditEntry.IP = ""
+			auditEntry.UserAgent = ""
+		}
+		document.AuditEntries = append(document.AuditEntries, *auditEntry.ToAuditEntryResponse())
+	}
+
+	return document, nil
+}
+
+// signDataExportToken returns the token of the download link of dataExport: its ID and expiry,
+// signed so neither can be changed.
+func signDataExportToken(dataExport domain.DataExport) string {
+	message := dataExport.ID + "." + strconv.FormatInt(dataExport.ExpiresAt.Unix(), 10)
+	return message + "." + secure.Sign(message)
+}
+
+// verifyDataExportToken returns the export ID of a token made by signDataExportToken when its
+// signature matches and it has not expired.
+func verifyDataExportToken(token string) (string, bool) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 || !secure.VerifySignature(parts[0]+"."+parts[1], parts[2]) {
+		return "", false
+	}
+
+	expiresAt, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil || time.Now().Unix() >= expiresAt {
+		return "", false
+	}
+
+	return parts[0], true
+}
+
+// dataExportZIP writes each section of document as a CSV file of a ZIP archive.
+func dataExportZIP(document domain.DataExportDocument) ([]byte, error) {
+	formatTime := func(t time.Time) string { return t.Format(time.RFC3339) }
+	profile := document.Profile
+	phoneNumber := ""
+	if profile.PhoneNumber != nil {
+		phoneNumber = *profile.PhoneNumber
+	}
+	avatarURL := ""
+	if profile.AvatarURL != nil {
+		avatarURL = *profile.AvatarURL
+	}
+	metadata, err := json.Marshal(profile.Metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	files := []struct {
+		name   string
+		header []string
+		rows   [][]string
+	}{
+		{"profile.csv", []string{"id", "name", "username", "email", "email_confirmed", "phone_number",
+			"phone_confirmed", "locale", "timezone", "avatar_url", "metadata", "two_factor_enabled", "login_alerts_enabled",
+			"roles", "created_at", "updated_at"}, [][]string{{
+			profile.Id, profile.Name, profile.Username, profile.Email, strconv.FormatBool(profile.EmailConfirmed),
+			phoneNumber, strconv.FormatBool(profile.PhoneConfirmed), profile.Locale, profile.Timezone,
+			avatarURL, string(metadata),
+			strconv.FormatBool(profile.TwoFactorEnabled), strconv.FormatBool(profile.LoginAlertsEnabled),
+			strings.Join(profile.Roles, " "), formatTime(profile.CreatedAt), formatTime(profile.UpdatedAt)}}},
+		{"identities.csv", []string{"id", "provider", "email", "linked_at"}, nil},
+		{"sessions.csv", []string{"id", "user_agent", "ip", "persistent", "created_at", "last_seen_at", "expires_at"}, nil},
+		{"login_history.csv", []string{"id", "outcome", "ip", "user_agent", "created_at"}, nil},
+		{"audit_entries.csv", []string{"id", "event", "actor_id", "actor_type", "ip", "user_agent", "details", "created_at"}, nil},
+	}
+
+	for _, identity := range document.Identities {
+		files[1].rows = append(files[1].rows, []string{identity.Id, identity.Provider, identity.Email, formatTime(identity.LinkedAt)})
+	}
+
+	for _, session := range document.Sessions {
+		files[2].rows = append(files[2].rows, []string{session.Id, session.UserAgent, session.IP,
+			strconv.FormatBool(session.Persistent), formatTime(session.CreatedAt), formatTime(session.LastSeenAt),
+			formatTime(session.ExpiresAt)})
+	}
+
+	for _, attempt := range document.LoginHistory {
+		files[3].rows = append(files[3].rows, []string{attempt.Id, attempt.Outcome, attempt.IP, attempt.UserAgent,
+			formatTime(attempt.CreatedAt)})
+	}
+
+	for _, entry := range document.AuditEntries {
+		details := make([]string, 0, len(entry.Details))
+		for key, value := range entry.Details {
+			details = append(details, key+"="+value)
+		}
+		sort.Strings(details)
+
+		files[4].rows = append(files[4].rows, []string{entry.Id, entry.Event, entry.ActorID, entry.ActorType, entry.IP,
+			entry.UserAgent, strings.Join(details, "; "), formatTime(entry.CreatedAt)})
+	}
+
+	var archive bytes.Buffer
+	zipWriter := zip.NewWriter(&archive)
+	for _, file := range files {
+		fileWriter, err := zipWriter.Create(file.name)
+		if err != nil {
+			return nil, err
+		}
+
+		csvWriter := csv.NewWriter(fileWriter)
+		if err := csvWriter.Write(file.header); err != nil {
+			return nil, err
+		}
+		if err := csvWriter.WriteAll(file.rows); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := zipWriter.Close(); err != nil {
+		return nil, err
+	}
+
+	return archive.Bytes(), nil
+}
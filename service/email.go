@@ -1,14 +1,167 @@
 package service
 
 import (
+	"bytes"
+	"html/template"
+	"log/slog"
 	"net/smtp"
 	"strconv"
+	"time"
 
 	"github.com/OVillas/autentication/config"
 	"github.com/OVillas/autentication/domain"
 	"github.com/samber/do"
+	"golang.org/x/text/language"
 )
 
+type notificationTemplate struct {
+	subject string
+	content *template.Template
+}
+
+// notificationLocales are the locales with notification templates, the first one being the last
+// resort when neither the locale of the user nor config.DefaultLocale matches any.
+var notificationLocales = []language.Tag{language.BrazilianPortuguese, language.English}
+
+var notificationMatcher = language.NewMatcher(notificationLocales)
+
+// notificationTimeLayouts writes the times of the notifications the way each locale reads them.
+var notificationTimeLayouts = map[language.Tag]string{
+	language.BrazilianPortuguese: "02/01/2006 15:04 MST",
+	language.English:             "Jan 2, 2006 3:04 PM MST",
+}
+
+// notificationTemplates holds one template per locale and domain notification type. Fields of the
+// notification are escaped by html/template.
+var notificationTemplates = map[language.Tag]map[string]notificationTemplate{
+	language.BrazilianPortuguese: {
+		domain.NotificationPasswordChanged: {
+			subject: "Sua senha foi alterada",
+			content: template.Must(template.New(domain.NotificationPasswordChanged).Parse(
+				"<h1>Olá{{if .Name}}, {{.Name}}{{end}}!</h1><p>A senha da sua conta foi alterada em {{.Time}}{{if .IP}}, a partir do IP {{.IP}}{{end}}.</p>" +
+					"<p>Todas as sessões abertas foram encerradas.</p>" +
+					"<p>Se não foi você, redefina sua senha agora pela opção \"Esqueci minha senha\".</p>")),
+		},
+		domain.NotificationPasswordReset: {
+			subject: "Sua senha foi redefinida",
+			content: template.Must(template.New(domain.NotificationPasswordReset).Parse(
+				"<h1>Olá{{if .Name}}, {{.Name}}{{end}}!</h1><p>A senha da sua conta foi redefinida em {{.Time}}{{if .IP}}, a partir do IP {{.IP}}{{end}}.</p>" +
+					"<p>Todas as sessões abertas foram encerradas.</p>" +
+					"<p>Se não foi você, redefina sua senha de novo pela opção \"Esqueci minha senha\" e entre em contato com o suporte.</p>")),
+		},
+		domain.NotificationEmailChangeRequested: {
+			subject: "Troca de e-mail da sua conta",
+			content: template.Must(template.New(domain.NotificationEmailChangeRequested).Parse(
+				"<h1>Olá{{if .Name}}, {{.Name}}{{end}}!</h1><p>Em {{.Time}} foi pedida a troca do e-mail da sua conta para {{.NewEmail}}.</p>" +
+					"<p>Se não foi você, altere sua senha e entre em contato com o suporte.</p>" +
+					"{{if .Link}}<p>Você também pode desfazer a troca pelo link abaixo, válido por 72 horas:</p><p><a href=\"{{.Link}}\">Desfazer a troca</a></p>{{end}}")),
+		},
+		domain.NotificationTwoFactorEnabled: {
+			subject: "Verificação em duas etapas ativada",
+			content: template.Must(template.New(domain.NotificationTwoFactorEnabled).Parse(
+				"<h1>Olá{{if .Name}}, {{.Name}}{{end}}!</h1><p>A verificação em duas etapas da sua conta foi ativada em {{.Time}}.</p>" +
+					"<p>Se não foi você, altere sua senha e entre em contato com o suporte.</p>")),
+		},
+		domain.NotificationDeletionScheduled: {
+			subject: "Exclusão da sua conta agendada",
+			content: template.Must(template.New(domain.NotificationDeletionScheduled).Parse(
+				"<h1>Olá{{if .Name}}, {{.Name}}{{end}}!</h1><p>Em {{.Time}} foi pedida a exclusão da sua conta{{if .IP}}, a partir do IP {{.IP}}{{end}}.</p>" +
+					"<p>Ela e todos os seus dados serão apagados definitivamente em {{.DueTime}}. Todas as sessões abertas foram encerradas.</p>" +
+					"<p>Para cancelar a exclusão, basta entrar na sua conta antes dessa data.</p>")),
+		},
+		domain.NotificationDeletionReminder: {
+			subject: "Sua conta será excluída em breve",
+			content: template.Must(template.New(domain.NotificationDeletionReminder).Parse(
+				"<h1>Olá{{if .Name}}, {{.Name}}{{end}}!</h1><p>Sua conta e todos os seus dados serão apagados definitivamente em {{.DueTime}}.</p>" +
+					"<p>Para cancelar a exclusão, basta entrar na sua conta antes dessa data.</p>")),
+		},
+		domain.NotificationTwoFactorDisabled: {
+			subject: "Verificação em duas etapas desativada",
+			content: template.Must(template.New(domain.NotificationTwoFactorDisabled).Parse(
+				"<h1>Olá{{if .Name}}, {{.Name}}{{end}}!</h1><p>A verificação em duas etapas da sua conta foi desativada{{if .ByAdmin}} pelo suporte{{end}} em {{.Time}}.</p>" +
+					"<p>Se você não pediu isso, altere sua senha e entre em contato com o suporte.</p>")),
+		},
+		domain.NotificationNewLogin: {
+			subject: "Novo acesso à sua conta",
+			content: template.Must(template.New(domain.NotificationNewLogin).Parse(
+				"<h1>Olá{{if .Name}}, {{.Name}}{{end}}!</h1><p>Sua conta foi acessada em {{.Time}} de um dispositivo que não usamos há algum tempo.</p>" +
+					"<ul>{{if .Location}}<li>Local aproximado: {{.Location}}</li>{{end}}{{if .IP}}<li>IP: {{.IP}}</li>{{end}}{{if .Device}}<li>Dispositivo: {{.Device}}</li>{{end}}</ul>" +
+					"<p>Se foi você, não é preciso fazer nada.</p>" +
+					"<p>Se não foi, altere sua senha e encerre as sessões que você não reconhece{{if .Link}} em <a href=\"{{.Link}}\">Sessões abertas</a>{{end}}.</p>")),
+		},
+		domain.NotificationDataExportReady: {
+			subject: "Seus dados estão prontos para download",
+			content: template.Must(template.New(domain.NotificationDataExportReady).Parse(
+				"<h1>Olá{{if .Name}}, {{.Name}}{{end}}!</h1><p>A cópia dos seus dados pedida em {{.Time}} está pronta.</p>" +
+					"<p><a href=\"{{.Link}}\">Baixar meus dados</a></p>" +
+					"<p>O link é válido até {{.DueTime}}, quando a cópia será apagada. Não o compartilhe: qualquer pessoa com ele pode baixar seus dados.</p>")),
+		},
+	},
+	language.English: {
+		domain.NotificationPasswordChanged: {
+			subject: "Your password was changed",
+			content: template.Must(template.New(domain.NotificationPasswordChanged).Parse(
+				"<h1>Hello{{if .Name}}, {{.Name}}{{end}}!</h1><p>The password of your account was changed on {{.Time}}{{if .IP}}, from the IP {{.IP}}{{end}}.</p>" +
+					"<p>All open sessions were ended.</p>" +
+					"<p>If it was not you, reset your password now with the \"Forgot my password\" option.</p>")),
+		},
+		domain.NotificationPasswordReset: {
+			subject: "Your password was reset",
+			content: template.Must(template.New(domain.NotificationPasswordReset).Parse(
+				"<h1>Hello{{if .Name}}, {{.Name}}{{end}}!</h1><p>The password of your account was reset on {{.Time}}{{if .IP}}, from the IP {{.IP}}{{end}}.</p>" +
+					"<p>All open sessions were ended.</p>" +
+					"<p>If it was not you, reset your password again with the \"Forgot my password\" option and contact support.</p>")),
+		},
+		domain.NotificationEmailChangeRequested: {
+			subject: "Email change on your account",
+			content: template.Must(template.New(domain.NotificationEmailChangeRequested).Parse(
+				"<h1>Hello{{if .Name}}, {{.Name}}{{end}}!</h1><p>On {{.Time}} the email of your account was asked to change to {{.NewEmail}}.</p>" +
+					"<p>If it was not you, change your password and contact support.</p>" +
+					"{{if .Link}}<p>You can also undo the change with the link below, valid for 72 hours:</p><p><a href=\"{{.Link}}\">Undo the change</a></p>{{end}}")),
+		},
+		domain.NotificationTwoFactorEnabled: {
+			subject: "Two-step verification turned on",
+			content: template.Must(template.New(domain.NotificationTwoFactorEnabled).Parse(
+				"<h1>Hello{{if .Name}}, {{.Name}}{{end}}!</h1><p>Two-step verification was turned on for your account on {{.Time}}.</p>" +
+					"<p>If it was not you, change your password and contact support.</p>")),
+		},
+		domain.NotificationDeletionScheduled: {
+			subject: "Deletion of your account scheduled",
+			content: template.Must(template.New(domain.NotificationDeletionScheduled).Parse(
+				"<h1>Hello{{if .Name}}, {{.Name}}{{end}}!</h1><p>On {{.Time}} the deletion of your account was requested{{if .IP}}, from the IP {{.IP}}{{end}}.</p>" +
+					"<p>It and all your data will be permanently erased on {{.DueTime}}. All open sessions were ended.</p>" +
+					"<p>To cancel the deletion, just log in to your account before then.</p>")),
+		},
+		domain.NotificationDeletionReminder: {
+			subject: "Your account will be deleted soon",
+			content: template.Must(template.New(domain.NotificationDeletionReminder).Parse(
+				"<h1>Hello{{if .Name}}, {{.Name}}{{end}}!</h1><p>Your account and all your data will be permanently erased on {{.DueTime}}.</p>" +
+					"<p>To cancel the deletion, just log in to your account before then.</p>")),
+		},
+		domain.NotificationTwoFactorDisabled: {
+			subject: "Two-step verification turned off",
+			content: template.Must(template.New(domain.NotificationTwoFactorDisabled).Parse(
+				"<h1>Hello{{if .Name}}, {{.Name}}{{end}}!</h1><p>Two-step verification was turned off for your account{{if .ByAdmin}} by support{{end}} on {{.Time}}.</p>" +
+					"<p>If you did not ask for it, change your password and contact support.</p>")),
+		},
+		domain.NotificationNewLogin: {
+			subject: "New login to your account",
+			content: template.Must(template.New(domain.NotificationNewLogin).Parse(
+				"<h1>Hello{{if .Name}}, {{.Name}}{{end}}!</h1><p>Your account was accessed on {{.Time}} from a device we have not seen in a while.</p>" +
+					"<ul>{{if .Location}}<li>Approximate location: {{.Location}}</li>{{end}}{{if .IP}}<li>IP: {{.IP}}</li>{{end}}{{if .Device}}<li>Device: {{.Device}}</li>{{end}}</ul>" +
+					"<p>If it was you, there is nothing to do.</p>" +
+					"<p>If it was not, change your password and end the sessions you do not recognize{{if .Link}} in <a href=\"{{.Link}}\">Open sessions</a>{{end}}.</p>")),
+		},
+		domain.NotificationDataExportReady: {
+			subject: "Your data is ready to download",
+			content: template.Must(template.New(domain.NotificationDataExportReady).Parse(
+				"<h1>Hello{{if .Name}}, {{.Name}}{{end}}!</h1><p>The copy of your data requested on {{.Time}} is ready.</p>" +
+					"<p><a href=\"{{.Link}}\">Download my data</a></p>" +
+					"<p>The link is valid until {{.DueTime}}, when the copy will be erased. Do not share it: anyone with it can download your data.</p>")),
+		},
+	},
+}
+
 type emailService struct {
 	i           *do.Injector
 	gmailSender domain.GmailSender
@@ -46,3 +199,76 @@ func (sender *emailService) SendEmail(subject string, content string, to []strin
 
 	return nil
 }
+
+// Notify renders the template of the notification right away and sends it in the background, one
+// email per address so that recipients do not see each other.
+func (sender *emailService) Notify(notification domain.Notification, to []string) {
+	log := slog.With(
+		slog.String("service", "email"),
+		slog.String("func", "Notify"),
+		slog.String("type", notification.Type))
+
+	locale := notificationLocale(notification.Locale)
+	notificationTemplate, ok := notificationTemplates[locale][notification.Type]
+	if !ok {
+		log.Error("Unknown notification type")
+		return
+	}
+
+	if notification.OccurredAt.IsZero() {
+		notification.OccurredAt = time.Now()
+	}
+
+	location := notificationLocation(notification.Timezone)
+	layout := notificationTimeLayouts[locale]
+	data := struct {
+		domain.Notification
+		Time    string
+		DueTime string
+	}{notification, notification.OccurredAt.In(location).Format(layout), notification.DueAt.In(location).Format(layout)}
+
+	var content bytes.Buffer
+	if err := notificationTemplate.content.Execute(&content, data); err != nil {
+		log.Error("Error trying to render notification", slog.Any("error", err))
+		return
+	}
+
+	go func() {
+		for _, address := range to {
+			if err := sender.SendEmail(notificationTemplate.subject, content.String(), []string{address}); err != nil {
+				log.Warn("Error trying to send notification", slog.Any("error", err))
+			}
+		}
+	}()
+}
+
+// notificationLocale picks the templates closest to locale, or else to config.DefaultLocale.
+func notificationLocale(locale string) language.Tag {
+	for _, candidate := range []string{locale, config.DefaultLocale} {
+		tag, err := language.Parse(candidate)
+		if err != nil {
+			continue
+		}
+
+		if _, index, confidence := notificationMatcher.Match(tag); confidence != language.No {
+			return notificationLocales[index]
+		}
+	}
+
+	return notificationLocales[0]
+}
+
+// notificationLocation is the time zone named by timezone, or else config.DefaultTimezone.
+func notificationLocation(timezone string) *time.Location {
+	for _, candidate := range []string{timezone, config.DefaultTimezone} {
+		if candidate == "" {
+			continue
+		}
+
+		if location, err := time.LoadLocation(candidate); err == nil {
+			return location
+		}
+	}
+
+	return time.UTC
+}
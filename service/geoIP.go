@@ -0,0 +1,72 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/OVillas/autentication/config"
+	"github.com/OVillas/autentication/domain"
+	"github.com/samber/do"
+)
+
+// NewGeoIPResolver asks the service at config.GeoIP.URL, or locates nothing when it is not set.
+// Another domain.GeoIPResolver, such as one reading a local database, can be provided in its place.
+func NewGeoIPResolver(i *do.Injector) (domain.GeoIPResolver, error) {
+	if config.GeoIP.URL == "" {
+		return noGeoIPResolver{}, nil
+	}
+
+	return &httpGeoIPResolver{url: config.GeoIP.URL}, nil
+}
+
+type noGeoIPResolver struct{}
+
+func (noGeoIPResolver) Resolve(ip string) (*domain.GeoLocation, error) {
+	return nil, nil
+}
+
+type httpGeoIPResolver struct {
+	url string
+}
+
+func (hgr *httpGeoIPResolver) Resolve(ip string) (*domain.GeoLocation, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), config.GeoIP.Timeout)
+	defer cancel()
+
+	requestURL := strings.ReplaceAll(hgr.url, "{ip}", url.PathEscape(ip))
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, requestURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("geoip service returned status %d", response.StatusCode)
+	}
+
+	var body struct {
+		Status     string `json:"status"`
+		City       string `json:"city"`
+		RegionName string `json:"regionName"`
+		Country    string `json:"country"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&body); err != nil {
+		return nil, err
+	}
+
+	// ip-api.com answers with status "fail" for private and reserved addresses.
+	if body.Status == "fail" || (body.City == "" && body.RegionName == "" && body.Country == "") {
+		return nil, nil
+	}
+
+	return &domain.GeoLocation{City: body.City, Region: body.RegionName, Country: body.Country}, nil
+}
@@ -0,0 +1,320 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"log/slog"
+	"net/url"
+	"time"
+
+	"github.com/OVillas/autentication/config"
+	"github.com/OVillas/autentication/domain"
+	"github.com/OVillas/autentication/secure"
+	"github.com/google/uuid"
+	"github.com/samber/do"
+)
+
+type invitationService struct {
+	i                      *do.Injector
+	invitationRepository   domain.InvitationRepository
+	userRepository         domain.UserRepository
+	roleRepository         domain.RoleRepository
+	organizationRepository domain.OrganizationRepository
+	emailService           domain.EmailService
+	auditService           domain.AuditService
+	termsService           domain.TermsService
+}
+
+func NewInvitationService(i *do.Injector) (domain.InvitationService, error) {
+	invitationRepository := do.MustInvoke[domain.InvitationRepository](i)
+	userRepository := do.MustInvoke[domain.UserRepository](i)
+	roleRepository := do.MustInvoke[domain.RoleRepository](i)
+	organizationRepository := do.MustInvoke[domain.OrganizationRepository](i)
+	emailService := do.MustInvoke[domain.EmailService](i)
+	auditService := do.MustInvoke[domain.AuditService](i)
+	termsService := do.MustInvoke[domain.TermsService](i)
+	return &invitationService{
+		i:                      i,
+		invitationRepository:   invitationRepository,
+		userRepository:         userRepository,
+		roleRepository:         roleRepository,
+		organizationRepository: organizationRepository,
+		emailService:           emailService,
+		auditService:           auditService,
+		termsService:           termsService,
+	}, nil
+}
+
+// Create emails an invitation valid for config.InvitationTTL to an address not registered yet.
+func (is *invitationService) Create(ctx context.Context, viewer domain.Viewer, payLoad domain.InvitationPayLoad) (*domain.InvitationResponse, error) {
+	log := slog.With(
+		slog.String("service", "invitation"),
+		slog.String("func", "Create"))
+
+	log.Info("Create initiated")
+
+	if payLoad.Role != "" && !viewer.Admin {
+		log.Warn("Invitation with a role attempted by a non admin: " + viewer.UserID)
+		return nil, domain.ErrUserNotAuthorized
+	}
+
+	if payLoad.Role != "" {
+		role, err := is.roleRepository.GetByName(payLoad.Role)
+		if err != nil {
+			log.Error("Error: ", slog.Any("error", err))
+			return nil, domain.ErrGetRole
+		}
+
+		if role == nil {
+			log.Warn("Invitation with an unknown role: " + payLoad.Role)
+			return nil, domain.ErrRoleNotFound
+		}
+	}
+
+	emailTaken, err := is.userRepository.IsEmailTaken(ctx, viewer.ClientInfo.TenantID, payLoad.Email)
+	if err != nil {
+		log.Error("Error trying to check the email", slog.Any("error", err))
+		return nil, domain.ErrGetUser
+	}
+
+	if emailTaken {
+		log.Warn("Invitation to an already registered email: " + payLoad.Email)
+		return nil, domain.ErrEmailTaken
+	}
+
+	invitation, err := is.send(domain.Invitation{
+		TenantID:  viewer.ClientInfo.TenantID,
+		Email:     payLoad.Email,
+		InviterID: viewer.UserID,
+		Role:      payLoad.Role,
+	}, "Você foi convidado", "Você recebeu um convite para criar sua conta.")
+	if err != nil {
+		return nil, err
+	}
+
+	is.auditService.Record(domain.AuditEventInvitationSent, invitation.ID, viewer.Actor(),
+		slog.String("email", invitation.Email), slog.String("role", invitation.Role))
+
+	log.Info("Create executed successfully")
+	return invitation.ToInvitationResponse(), nil
+}
+
+// InviteToOrganization emails an invitation to register and join organization with role. The
+// caller checks that the viewer may invite to the organization.
+func (is *invitationService) InviteToOrganization(ctx context.Context, viewer domain.Viewer, email string, organization domain.Organization, role string) (*domain.InvitationResponse, error) {
+	log := slog.With(
+		slog.String("service", "invitation"),
+		slog.String("func", "InviteToOrganization"))
+
+	log.Info("InviteToOrganization initiated")
+
+	emailTaken, err := is.userRepository.IsEmailTaken(ctx, viewer.ClientInfo.TenantID, email)
+	if err != nil {
+		log.Error("Error trying to check the email", slog.Any("error", err))
+		return nil, domain.ErrGetUser
+	}
+
+	if emailTaken {
+		log.Warn("Organization invitation to an already registered email: " + email)
+		return nil, domain.ErrEmailTaken
+	}
+
+	invitation, err := is.send(domain.Invitation{
+		TenantID:         viewer.ClientInfo.TenantID,
+		Email:            email,
+		InviterID:        viewer.UserID,
+		OrganizationID:   organization.ID,
+		OrganizationRole: role,
+	}, "Você foi convidado para "+organization.Name,
+		fmt.Sprintf("Você recebeu um convite para participar da organização %s. Crie sua conta para entrar nela.", html.EscapeString(organization.Name)))
+	if err != nil {
+		return nil, err
+	}
+
+	is.auditService.Record(domain.AuditEventInvitationSent, invitation.ID, viewer.Actor(),
+		slog.String("email", invitation.Email), slog.String("organization_id", organization.ID),
+		slog.String("organization_role", role))
+
+	log.Info("InviteToOrganization executed successfully")
+	return invitation.ToInvitationResponse(), nil
+}
+
+// Accept registers the invited user with a confirmed email and the role of the invitation, as a
+// member of its organization when it has one. The invitation is only marked accepted once the
+// user exists, so a refused registration can be tried again with the same token.
+func (is *invitationService) Accept(ctx context.Context, payLoad domain.InvitationRegistrationPayLoad, clientInfo domain.ClientInfo) error {
+	log := slog.With(
+		slog.String("service", "invitation"),
+		slog.String("func", "Accept"))
+
+	log.Info("Accept initiated")
+
+	invitation, err := is.invitationRepository.GetByTokenHash(secure.HashToken(payLoad.Token))
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return domain.ErrGetInvitation
+	}
+
+	// An invitation only registers in the tenant it was sent from.
+	if invitation == nil || invitation.TenantID != clientInfo.TenantID {
+		log.Warn("Invitation not found")
+		return domain.ErrInvitationNotFound
+	}
+
+	if invitation.AcceptedAt != nil {
+		log.Warn("Invitation already accepted: " + invitation.ID)
+		return domain.ErrInvitationAccepted
+	}
+
+	if time.Now().After(invitation.ExpiresAt) {
+		log.Warn("Invitation expired: " + invitation.ID)
+		return domain.ErrInvitationExpired
+	}
+
+	if domain.NormalizeEmail(payLoad.Email) != invitation.Email {
+		log.Warn("Invitation accepted with another email: " + invitation.ID)
+		return domain.ErrInvitationEmailMismatch
+	}
+
+	if err := domain.CheckTermsVersion(payLoad.TermsVersion); err != nil {
+		log.Warn("Invitation accepted with outdated terms version: " + payLoad.TermsVersion)
+		return err
+	}
+
+	if err := checkPasswordBreached(payLoad.Password); err != nil {
+		log.Warn("Password refused by the breach check", slog.Any("error", err))
+		return err
+	}
+
+	usernameTaken, err := is.userRepository.IsUsernameTaken(ctx, invitation.TenantID, payLoad.Username)
+	if err != nil {
+		log.Error("Error trying to check the username", slog.Any("error", err))
+		return domain.ErrGetUser
+	}
+
+	if usernameTaken {
+		log.Warn("Registration with a taken username: " + payLoad.Username)
+		return domain.ErrUsernameTaken
+	}
+
+	hashedPassword, err := secure.Hash(payLoad.Password)
+	if err != nil {
+		log.Error("Error trying to hashed password")
+		return domain.ErrHashPassword
+	}
+
+	user, err := payLoad.ToUser(string(hashedPassword))
+	if err != nil {
+		log.Error("Error trying to convert userPayload to User")
+		return domain.ErrConvertUserPayLoadToUser
+	}
+	user.TenantID = invitation.TenantID
+	user.EmailConfirmed = true
+
+	// The email is unique, so the same invitation cannot register two users.
+	err = is.userRepository.Create(ctx, *user)
+	if errors.Is(err, domain.ErrEmailTaken) || errors.Is(err, domain.ErrUsernameTaken) {
+		log.Warn("Registration with a taken email or username", slog.Any("error", err))
+		return err
+	}
+
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return domain.ErrCreateUser
+	}
+
+	accepted, err := is.invitationRepository.Accept(invitation.ID)
+	if err != nil || !accepted {
+		log.Error("Failed to mark the invitation accepted: "+invitation.ID, slog.Any("error", err))
+	}
+
+	if payLoad.TermsVersion != "" {
+		if err := is.termsService.Accept(user.ID, payLoad.TermsVersion, clientInfo); err != nil {
+			log.Error("Terms acceptance not recorded for user: "+user.ID, slog.Any("error", err))
+		}
+	}
+
+	if invitation.Role != "" {
+		is.assignRole(user.ID, invitation.Role)
+	}
+
+	if invitation.OrganizationID != "" {
+		is.joinOrganization(user.ID, *invitation)
+	}
+
+	is.auditService.Record(domain.AuditEventInvitationAccepted, user.ID, domain.UserActor(user.ID, clientInfo),
+		slog.String("invitation_id", invitation.ID), slog.String("inviter_id", invitation.InviterID))
+
+	log.Info("Accept executed successfully")
+	return nil
+}
+
+// Private session
+// send stores invitation with a new token and emails the link to it, introduced by intro.
+func (is *invitationService) send(invitation domain.Invitation, subject string, intro string) (*domain.Invitation, error) {
+	log := slog.With(
+		slog.String("service", "invitation"),
+		slog.String("func", "send"))
+
+	token, err := secure.GenerateOpaqueToken()
+	if err != nil {
+		log.Error("Error trying to generate invitation token", slog.Any("error", err))
+		return nil, domain.ErrCreateInvitation
+	}
+
+	invitation.ID = uuid.NewString()
+	invitation.TokenHash = secure.HashToken(token)
+	invitation.ExpiresAt = time.Now().Add(config.InvitationTTL)
+
+	if err := is.invitationRepository.Create(invitation); err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return nil, domain.ErrCreateInvitation
+	}
+
+	link := config.InvitationURL + "?token=" + url.QueryEscape(token)
+	content := fmt.Sprintf("<h1>Olá!</h1><p>%s Ele vale até %s e só pode ser usado uma vez.</p>"+
+		"<p><a href=\"%s\">Criar minha conta</a></p><p>Se não esperava este convite, ignore este e-mail.</p>",
+		intro, invitation.ExpiresAt.Format("02/01/2006 15:04"), link)
+
+	if err := is.emailService.SendEmail(subject, content, []string{invitation.Email}); err != nil {
+		log.Error("Error trying to send invitation", slog.Any("error", err))
+		return nil, domain.ErrSendInvitation
+	}
+
+	return &invitation, nil
+}
+
+func (is *invitationService) assignRole(userID string, roleName string) {
+	log := slog.With(
+		slog.String("service", "invitation"),
+		slog.String("func", "assignRole"))
+
+	role, err := is.roleRepository.GetByName(roleName)
+	if err != nil || role == nil {
+		log.Error("Failed to get the role of the invitation: "+roleName, slog.Any("error", err))
+		return
+	}
+
+	if _, err := is.roleRepository.Assign(domain.UserRole{UserID: userID, RoleID: role.ID}); err != nil {
+		log.Error("Failed to assign the role of the invitation: "+roleName, slog.Any("error", err))
+	}
+}
+
+func (is *invitationService) joinOrganization(userID string, invitation domain.Invitation) {
+	log := slog.With(
+		slog.String("service", "invitation"),
+		slog.String("func", "joinOrganization"))
+
+	organization, err := is.organizationRepository.GetById(invitation.OrganizationID)
+	if err != nil || organization == nil {
+		log.Error("Failed to get the organization of the invitation: "+invitation.OrganizationID, slog.Any("error", err))
+		return
+	}
+
+	membership := domain.Membership{OrganizationID: organization.ID, UserID: userID, Role: invitation.OrganizationRole}
+	if _, err := is.organizationRepository.AddMember(membership); err != nil {
+		log.Error("Failed to add the member of the invitation: "+organization.ID, slog.Any("error", err))
+	}
+}
@@ -0,0 +1,180 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/OVillas/autentication/config"
+	"github.com/OVillas/autentication/domain"
+	"github.com/OVillas/autentication/secure"
+	"github.com/google/uuid"
+	"github.com/samber/do"
+)
+
+// knownDeviceCleanupInterval is how often the devices not seen within their retention are forgotten.
+const knownDeviceCleanupInterval = time.Hour
+
+type loginAlertService struct {
+	i                     *do.Injector
+	knownDeviceRepository domain.KnownDeviceRepository
+	userRepository        domain.UserRepository
+	emailService          domain.EmailService
+	geoIPResolver         domain.GeoIPResolver
+}
+
+func NewLoginAlertService(i *do.Injector) (domain.LoginAlertService, error) {
+	knownDeviceRepository := do.MustInvoke[domain.KnownDeviceRepository](i)
+	userRepository := do.MustInvoke[domain.UserRepository](i)
+	emailService := do.MustInvoke[domain.EmailService](i)
+	geoIPResolver := do.MustInvoke[domain.GeoIPResolver](i)
+	las := &loginAlertService{
+		i:                     i,
+		knownDeviceRepository: knownDeviceRepository,
+		userRepository:        userRepository,
+		emailService:          emailService,
+		geoIPResolver:         geoIPResolver,
+	}
+
+	go las.forgetDevices()
+
+	return las, nil
+}
+
+func (las *loginAlertService) Check(user domain.User, clientInfo domain.ClientInfo) {
+	go las.checkDevice(user, clientInfo)
+}
+
+func (las *loginAlertService) GetPreference(ctx context.Context, userID string) (*domain.LoginAlertPreferenceResponse, error) {
+	log := slog.With(
+		slog.String("service", "loginAlert"),
+		slog.String("func", "GetPreference"))
+
+	log.Info("GetPreference initiated")
+
+	user, err := las.userRepository.GetById(ctx, userID)
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return nil, domain.ErrGetLoginAlertPreference
+	}
+
+	if user == nil {
+		log.Warn("User not found with this id: " + userID)
+		return nil, domain.ErrUserNotFound
+	}
+
+	log.Info("GetPreference executed successfully")
+	return &domain.LoginAlertPreferenceResponse{Enabled: user.LoginAlertsEnabled}, nil
+}
+
+func (las *loginAlertService) UpdatePreference(ctx context.Context, userID string, enabled bool) error {
+	log := slog.With(
+		slog.String("service", "loginAlert"),
+		slog.String("func", "UpdatePreference"))
+
+	log.Info("UpdatePreference initiated")
+
+	if err := las.userRepository.SetLoginAlertsEnabled(ctx, userID, enabled); err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return domain.ErrUpdateLoginAlertPreference
+	}
+
+	log.Info("UpdatePreference executed successfully")
+	return nil
+}
+
+// Private session
+
+// checkDevice remembers the IP and user agent of the login and emails the user when they were
+// not seen within config.KnownDeviceRetention. The first device of an account is only remembered,
+// as there is nothing to compare it with yet.
+func (las *loginAlertService) checkDevice(user domain.User, clientInfo domain.ClientInfo) {
+	log := slog.With(
+		slog.String("service", "loginAlert"),
+		slog.String("func", "checkDevice"))
+
+	fingerprint := secure.HashToken(clientInfo.IP + "\n" + clientInfo.UserAgent)
+	knownDevice, err := las.knownDeviceRepository.GetByFingerprint(user.ID, fingerprint)
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return
+	}
+
+	now := time.Now()
+	if knownDevice != nil {
+		if err := las.knownDeviceRepository.UpdateLastSeenAt(knownDevice.ID); err != nil {
+			log.Error("Error: ", slog.Any("error", err))
+		}
+
+		// Devices are only forgotten hourly, so one may still be here past its retention.
+		if knownDevice.LastSeenAt.After(now.Add(-config.KnownDeviceRetention)) || !user.LoginAlertsEnabled {
+			return
+		}
+	} else {
+		count, err := las.knownDeviceRepository.CountByUserID(user.ID)
+		if err != nil {
+			log.Error("Error: ", slog.Any("error", err))
+			return
+		}
+
+		// A concurrent login from the same device creates it first; only that one sends the alert.
+		err = las.knownDeviceRepository.Create(domain.KnownDevice{
+			ID:          uuid.NewString(),
+			UserID:      user.ID,
+			Fingerprint: fingerprint,
+			LastSeenAt:  now,
+			CreatedAt:   now,
+		})
+		if err != nil {
+			log.Error("Error: ", slog.Any("error", err))
+			return
+		}
+
+		if count == 0 || !user.LoginAlertsEnabled {
+			return
+		}
+	}
+
+	notification := domain.Notification{
+		Type:       domain.NotificationNewLogin,
+		Name:       user.Name,
+		Locale:     user.Locale,
+		Timezone:   user.Timezone,
+		IP:         clientInfo.IP,
+		Device:     clientInfo.UserAgent,
+		Link:       config.SessionsURL,
+		OccurredAt: now,
+	}
+
+	location, err := las.geoIPResolver.Resolve(clientInfo.IP)
+	if err != nil {
+		log.Warn("Error trying to locate login IP", slog.Any("error", err))
+	} else if location != nil {
+		notification.Location = location.String()
+	}
+
+	las.emailService.Notify(notification, []string{user.Email})
+}
+
+// forgetDevices runs once at startup and then every knownDeviceCleanupInterval, deleting the
+// devices not seen within config.KnownDeviceRetention.
+func (las *loginAlertService) forgetDevices() {
+	log := slog.With(
+		slog.String("service", "loginAlert"),
+		slog.String("func", "forgetDevices"))
+
+	ticker := time.NewTicker(knownDeviceCleanupInterval)
+	defer ticker.Stop()
+
+	for ; ; <-ticker.C {
+		deleted, err := las.knownDeviceRepository.DeleteSeenBefore(time.Now().Add(-config.KnownDeviceRetention))
+		if err != nil {
+			log.Error("Error: ", slog.Any("error", err))
+			continue
+		}
+
+		if deleted > 0 {
+			log.Info("Known devices forgotten", slog.Int64("count", deleted))
+		}
+	}
+}
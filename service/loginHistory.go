@@ -0,0 +1,110 @@
+package service
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/OVillas/autentication/config"
+	"github.com/OVillas/autentication/domain"
+	"github.com/google/uuid"
+	"github.com/samber/do"
+)
+
+// loginHistoryCleanupInterval is how often the login attempts past their retention are deleted.
+const loginHistoryCleanupInterval = time.Hour
+
+type loginHistoryService struct {
+	i                      *do.Injector
+	loginHistoryRepository domain.LoginHistoryRepository
+}
+
+func NewLoginHistoryService(i *do.Injector) (domain.LoginHistoryService, error) {
+	loginHistoryRepository := do.MustInvoke[domain.LoginHistoryRepository](i)
+	lhs := &loginHistoryService{
+		i:                      i,
+		loginHistoryRepository: loginHistoryRepository,
+	}
+
+	go lhs.deleteExpired()
+
+	return lhs, nil
+}
+
+func (lhs *loginHistoryService) Record(userID string, outcome string, clientInfo domain.ClientInfo) {
+	log := slog.With(
+		slog.String("service", "loginHistory"),
+		slog.String("func", "Record"))
+
+	err := lhs.loginHistoryRepository.Create(domain.LoginAttempt{
+		ID:        uuid.NewString(),
+		UserID:    userID,
+		Outcome:   outcome,
+		IP:        clientInfo.IP,
+		UserAgent: clientInfo.UserAgent,
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		log.Error("Error trying to record login attempt", slog.String("userId", userID), slog.Any("error", err))
+	}
+}
+
+// GetByUserID reads a page of the latest domain.LoginHistoryLimit login attempts of the user. The
+// older ones are not listed, even while they are kept.
+func (lhs *loginHistoryService) GetByUserID(userID string, query domain.LoginHistoryQuery) (*domain.LoginHistoryPage, error) {
+	log := slog.With(
+		slog.String("service", "loginHistory"),
+		slog.String("func", "GetByUserID"))
+
+	log.Info("GetByUserID initiated")
+
+	offset := query.Offset()
+	loginHistoryPage := &domain.LoginHistoryPage{
+		Items:   []domain.LoginAttemptResponse{},
+		Page:    query.Page,
+		PerPage: query.PerPage,
+		HasPrev: query.Page > 1,
+	}
+
+	limit := max(min(query.PerPage, domain.LoginHistoryLimit-offset), 0)
+	loginAttempts, total, err := lhs.loginHistoryRepository.GetByUserID(userID, offset, limit)
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return nil, domain.ErrGetLoginHistory
+	}
+
+	loginAttempts = loginAttempts[:min(len(loginAttempts), limit)]
+
+	loginHistoryPage.Total = min(total, domain.LoginHistoryLimit)
+	loginHistoryPage.HasNext = int64(offset+len(loginAttempts)) < loginHistoryPage.Total
+	for _, loginAttempt := range loginAttempts {
+		loginHistoryPage.Items = append(loginHistoryPage.Items, *loginAttempt.ToLoginAttemptResponse())
+	}
+
+	log.Info("GetByUserID executed successfully")
+	return loginHistoryPage, nil
+}
+
+// Private session
+
+// deleteExpired runs once at startup and then every loginHistoryCleanupInterval, deleting the
+// login attempts older than config.LoginHistoryRetention.
+func (lhs *loginHistoryService) deleteExpired() {
+	log := slog.With(
+		slog.String("service", "loginHistory"),
+		slog.String("func", "deleteExpired"))
+
+	ticker := time.NewTicker(loginHistoryCleanupInterval)
+	defer ticker.Stop()
+
+	for ; ; <-ticker.C {
+		deleted, err := lhs.loginHistoryRepository.DeleteBefore(time.Now().Add(-config.LoginHistoryRetention))
+		if err != nil {
+			log.Error("Error: ", slog.Any("error", err))
+			continue
+		}
+
+		if deleted > 0 {
+			log.Info("Expired login attempts deleted", slog.Int64("count", deleted))
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"time"
+
+	"github.com/OVillas/autentication/config"
+	"github.com/OVillas/autentication/domain"
+	"github.com/OVillas/autentication/secure"
+	"github.com/samber/do"
+)
+
+type magicLinkService struct {
+	i                   *do.Injector
+	userRepository      domain.UserRepository
+	magicLinkRepository domain.MagicLinkRepository
+	emailService        domain.EmailService
+	userService         domain.UserService
+}
+
+func NewMagicLinkService(i *do.Injector) (domain.MagicLinkService, error) {
+	userRepository := do.MustInvoke[domain.UserRepository](i)
+	magicLinkRepository := do.MustInvoke[domain.MagicLinkRepository](i)
+	emailService := do.MustInvoke[domain.EmailService](i)
+	userService := do.MustInvoke[domain.UserService](i)
+	return &magicLinkService{
+		i:                   i,
+		userRepository:      userRepository,
+		magicLinkRepository: magicLinkRepository,
+		emailService:        emailService,
+		userService:         userService,
+	}, nil
+}
+
+// Send emails a login link when the address belongs to a user. Its outcome is never told to the
+// caller: unknown addresses and delivery failures are only logged, and the link is looked up and
+// sent in the background so the response time does not depend on the account existing.
+func (mls *magicLinkService) Send(ctx context.Context, tenantID string, email string) error {
+	log := slog.With(
+		slog.String("service", "magicLink"),
+		slog.String("func", "Send"))
+
+	log.Info("Send initiated")
+
+	go mls.send(context.WithoutCancel(ctx), tenantID, domain.NormalizeEmail(email))
+
+	log.Info("Send executed successfully")
+	return nil
+}
+
+// send creates the link for the user of email, if any, and emails it, for Send.
+func (mls *magicLinkService) send(ctx context.Context, tenantID string, email string) {
+	log := slog.With(
+		slog.String("service", "magicLink"),
+		slog.String("func", "send"))
+
+	user, err := mls.userRepository.GetByEmail(ctx, tenantID, email)
+	if err != nil {
+		log.Error("Failed to obtain user by email", slog.Any("error", err))
+		return
+	}
+
+	if user == nil {
+		log.Warn("User not found with this email: " + email)
+		return
+	}
+
+	token, err := secure.GenerateOpaqueToken()
+	if err != nil {
+		log.Error("Error trying to generate magic link token", slog.Any("error", err))
+		return
+	}
+
+	magicLink := domain.MagicLink{
+		TokenHash: secure.HashToken(token),
+		UserID:    user.ID,
+		ExpiresAt: time.Now().Add(domain.MagicLinkTTL),
+	}
+
+	if err := mls.magicLinkRepository.Create(magicLink); err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return
+	}
+
+	link := config.MagicLinkURL + "?token=" + url.QueryEscape(token)
+	subject := "Seu link de acesso"
+	content := fmt.Sprintf("<h1>Olá!</h1><p>Clique no link abaixo para entrar na sua conta. Ele vale por %d minutos e só pode ser usado uma vez.</p>"+
+		"<p><a href=\"%s\">Entrar</a></p><p>Se não foi você quem pediu, ignore este e-mail.</p>", int(domain.MagicLinkTTL.Minutes()), link)
+
+	if err := mls.emailService.SendEmail(subject, content, []string{user.Email}); err != nil {
+		log.Error("Error trying to send magic link", slog.Any("error", err))
+	}
+}
+
+// Verify consumes the link and continues the login of its user, which still stops at the
+// two-factor challenge when the account has it on.
+func (mls *magicLinkService) Verify(ctx context.Context, token string, deviceToken string, clientInfo domain.ClientInfo) (*domain.LoginResult, error) {
+	log := slog.With(
+		slog.String("service", "magicLink"),
+		slog.String("func", "Verify"))
+
+	log.Info("Verify initiated")
+
+	tokenHash := secure.HashToken(token)
+	magicLink, err := mls.magicLinkRepository.GetByTokenHash(tokenHash)
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return nil, domain.ErrInvalidMagicLink
+	}
+
+	if magicLink == nil {
+		log.Warn("Magic link not found")
+		return nil, domain.ErrInvalidMagicLink
+	}
+
+	// Checked before the link is used, so a request to another tenant cannot consume it.
+	user, err := mls.userRepository.GetById(ctx, magicLink.UserID)
+	if err != nil {
+		log.Error("Failed to obtain user by id", slog.Any("error", err))
+		return nil, domain.ErrGetUser
+	}
+
+	if user == nil || user.TenantID != clientInfo.TenantID {
+		log.Warn("Magic link of a user of another tenant")
+		return nil, domain.ErrInvalidMagicLink
+	}
+
+	used, err := mls.magicLinkRepository.Use(tokenHash)
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return nil, domain.ErrInvalidMagicLink
+	}
+
+	if !used {
+		log.Warn("Magic link expired or already used")
+		return nil, domain.ErrInvalidMagicLink
+	}
+
+	loginResult, err := mls.userService.ContinueLogin(ctx, magicLink.UserID, deviceToken, clientInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Info("Verify executed successfully")
+	return loginResult, nil
+}
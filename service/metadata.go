@@ -0,0 +1,111 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/OVillas/autentication/domain"
+	"github.com/samber/do"
+)
+
+type metadataService struct {
+	i              *do.Injector
+	userRepository domain.UserRepository
+	auditService   domain.AuditService
+}
+
+func NewMetadataService(i *do.Injector) (domain.MetadataService, error) {
+	userRepository := do.MustInvoke[domain.UserRepository](i)
+	auditService := do.MustInvoke[domain.AuditService](i)
+	return &metadataService{
+		i:              i,
+		userRepository: userRepository,
+		auditService:   auditService,
+	}, nil
+}
+
+func (ms *metadataService) UpdateMe(ctx context.Context, userID string, patch domain.MetadataPayLoad) (*domain.UserMetadata, error) {
+	log := slog.With(
+		slog.String("service", "metadata"),
+		slog.String("func", "UpdateMe"))
+
+	log.Info("UpdateMe initiated")
+
+	user, err := ms.userRepository.GetById(ctx, userID)
+	if err != nil {
+		log.Error("Failed to obtain user by id", slog.Any("error", err))
+		return nil, domain.ErrGetUser
+	}
+
+	if user == nil {
+		log.Warn("User not found with this id: " + userID)
+		return nil, domain.ErrUserNotFound
+	}
+
+	metadata, err := ms.update(ctx, user.ID, domain.MetadataSectionUser, patch)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Info("UpdateMe executed successfully")
+	return metadata, nil
+}
+
+// AdminUpdate records the keys it changed in the audit trail, not their values.
+func (ms *metadataService) AdminUpdate(ctx context.Context, viewer domain.Viewer, userID string, patch domain.MetadataPayLoad) (*domain.UserMetadata, error) {
+	log := slog.With(
+		slog.String("service", "metadata"),
+		slog.String("func", "AdminUpdate"))
+
+	log.Info("AdminUpdate initiated")
+
+	user, err := ms.userRepository.GetById(ctx, userID)
+	if err != nil {
+		log.Error("Failed to obtain user by id", slog.Any("error", err))
+		return nil, domain.ErrGetUser
+	}
+
+	if user == nil || !viewer.SameTenant(*user) {
+		log.Warn("User not found with this id: " + userID)
+		return nil, domain.ErrUserNotFound
+	}
+
+	metadata, err := ms.update(ctx, user.ID, domain.MetadataSectionAdmin, patch)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(patch))
+	for key := range patch {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	ms.auditService.Record(domain.AuditEventMetadataUpdated, user.ID, viewer.Actor(), slog.String("keys", strings.Join(keys, ",")))
+
+	log.Info("AdminUpdate executed successfully")
+	return metadata, nil
+}
+
+// Private session
+func (ms *metadataService) update(ctx context.Context, userID string, section string, patch domain.MetadataPayLoad) (*domain.UserMetadata, error) {
+	metadata, err := ms.userRepository.UpdateMetadata(ctx, userID, section, patch)
+	if errors.Is(err, domain.ErrUserNotFound) {
+		slog.Warn("User deleted by a concurrent request: " + userID)
+		return nil, err
+	}
+
+	if errors.Is(err, domain.ErrMetadataTooLarge) || errors.Is(err, domain.ErrMetadataTooManyKeys) {
+		slog.Warn("Metadata refused", slog.String("userId", userID), slog.Any("error", err))
+		return nil, err
+	}
+
+	if err != nil {
+		slog.Error("Error trying to update metadata", slog.String("userId", userID), slog.Any("error", err))
+		return nil, domain.ErrUpdateMetadata
+	}
+
+	return metadata, nil
+}
@@ -0,0 +1,247 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"log/slog"
+	"time"
+
+	"github.com/OVillas/autentication/auth"
+	"github.com/OVillas/autentication/config"
+	"github.com/OVillas/autentication/domain"
+	"github.com/OVillas/autentication/secure"
+	"github.com/samber/do"
+)
+
+type oauthService struct {
+	i                           *do.Injector
+	userService                 domain.UserService
+	oauthClientRepository       domain.OAuthClientRepository
+	authorizationCodeRepository domain.AuthorizationCodeRepository
+	tokenProvider               auth.TokenProvider
+}
+
+func NewOAuthService(i *do.Injector) (domain.OAuthService, error) {
+	userService := do.MustInvoke[domain.UserService](i)
+	oauthClientRepository := do.MustInvoke[domain.OAuthClientRepository](i)
+	authorizationCodeRepository := do.MustInvoke[domain.AuthorizationCodeRepository](i)
+	tokenProvider := do.MustInvoke[auth.TokenProvider](i)
+	return &oauthService{
+		i:                           i,
+		userService:                 userService,
+		oauthClientRepository:       oauthClientRepository,
+		authorizationCodeRepository: authorizationCodeRepository,
+		tokenProvider:               tokenProvider,
+	}, nil
+}
+
+// ValidateAuthorizeRequest checks the client and redirect URI before anything is shown to the
+// user. When either is wrong the error must not be sent to the redirect URI.
+func (oas *oauthService) ValidateAuthorizeRequest(payLoad domain.AuthorizePayLoad) error {
+	log := slog.With(
+		slog.String("service", "oauth"),
+		slog.String("func", "ValidateAuthorizeRequest"))
+
+	log.Info("ValidateAuthorizeRequest initiated")
+
+	oauthClient, err := oas.oauthClientRepository.GetById(payLoad.ClientID)
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return domain.ErrGetOAuthClient
+	}
+
+	if oauthClient == nil {
+		log.Warn("OAuth client not found with this id: " + payLoad.ClientID)
+		return domain.ErrOAuthClientNotFound
+	}
+
+	if !oauthClient.AllowsRedirectURI(payLoad.RedirectURI) {
+		log.Warn("Redirect uri not registered for client: " + payLoad.ClientID)
+		return domain.ErrInvalidRedirectURI
+	}
+
+	log.Info("ValidateAuthorizeRequest executed successfully")
+	return nil
+}
+
+// Authorize checks the credentials posted to the login form and issues an authorization code
+// bound to the client, the redirect URI and the PKCE challenge.
+func (oas *oauthService) Authorize(ctx context.Context, payLoad domain.AuthorizePayLoad, clientInfo domain.ClientInfo) (string, error) {
+	log := slog.With(
+		slog.String("service", "oauth"),
+		slog.String("func", "Authorize"))
+
+	log.Info("Authorize initiated")
+
+	if err := oas.ValidateAuthorizeRequest(payLoad); err != nil {
+		return "", err
+	}
+
+	user, err := oas.userService.Authenticate(ctx, payLoad.Username, payLoad.Password, clientInfo)
+	if err != nil {
+		log.Warn("Invalid credentials on authorize")
+		return "", err
+	}
+
+	code, err := secure.GenerateOpaqueToken()
+	if err != nil {
+		log.Error("Error trying to generate authorization code", slog.Any("error", err))
+		return "", domain.ErrCreateAuthorizationCode
+	}
+
+	authorizationCode := domain.AuthorizationCode{
+		CodeHash:      secure.HashToken(code),
+		ClientID:      payLoad.ClientID,
+		UserID:        user.Id,
+		RedirectURI:   payLoad.RedirectURI,
+		CodeChallenge: payLoad.CodeChallenge,
+		Scope:         payLoad.Scope,
+		ExpiresAt:     time.Now().Add(domain.AuthorizationCodeTTL),
+	}
+
+	if err := oas.authorizationCodeRepository.Create(authorizationCode); err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return "", domain.ErrCreateAuthorizationCode
+	}
+
+	log.Info("Authorize executed successfully")
+	return code, nil
+}
+
+func (oas *oauthService) Token(ctx context.Context, payLoad domain.OAuthTokenPayLoad, clientInfo domain.ClientInfo) (*domain.LoginResponse, error) {
+	log := slog.With(
+		slog.String("service", "oauth"),
+		slog.String("func", "Token"))
+
+	log.Info("Token initiated")
+
+	oauthClient, err := oas.authenticateClient(payLoad.ClientID, payLoad.ClientSecret)
+	if err != nil {
+		log.Warn("OAuth client authentication failed: " + payLoad.ClientID)
+		return nil, err
+	}
+
+	var loginResponse *domain.LoginResponse
+	switch payLoad.GrantType {
+	case domain.GrantTypeAuthorizationCode:
+		loginResponse, err = oas.exchangeAuthorizationCode(ctx, *oauthClient, payLoad, clientInfo)
+	case domain.GrantTypeRefreshToken:
+		loginResponse, err = oas.userService.Refresh(ctx, payLoad.RefreshToken, clientInfo)
+	default:
+		err = domain.ErrUnsupportedGrantType
+	}
+
+	if err != nil {
+		log.Warn("Token exchange failed", slog.Any("error", err))
+		return nil, err
+	}
+
+	log.Info("Token executed successfully")
+	return loginResponse, nil
+}
+
+// ClientCredentials issues an access token to a confidential client acting on its own behalf.
+// No refresh token is issued, the client authenticates again once the token expires.
+func (oas *oauthService) ClientCredentials(payLoad domain.OAuthTokenPayLoad) (*domain.ClientTokenResponse, error) {
+	log := slog.With(
+		slog.String("service", "oauth"),
+		slog.String("func", "ClientCredentials"))
+
+	log.Info("ClientCredentials initiated")
+
+	oauthClient, err := oas.authenticateClient(payLoad.ClientID, payLoad.ClientSecret)
+	if err != nil {
+		log.Warn("OAuth client authentication failed: " + payLoad.ClientID)
+		return nil, err
+	}
+
+	if oauthClient.Public {
+		log.Warn("Public client tried the client credentials grant: " + oauthClient.ID)
+		return nil, domain.ErrUnauthorizedClient
+	}
+
+	scope := oauthClient.GrantScopes(payLoad.Scope)
+	if scope == "" {
+		log.Warn("No allowed scope requested by client: " + oauthClient.ID)
+		return nil, domain.ErrInvalidScope
+	}
+
+	token, err := oas.tokenProvider.CreateClientToken(oauthClient.ID, scope)
+	if err != nil {
+		log.Error("error trying create client token.", slog.Any("error", err))
+		return nil, domain.ErrGenToken
+	}
+
+	log.Info("ClientCredentials executed successfully")
+	return &domain.ClientTokenResponse{
+		AccessToken: token,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(config.Token.TTL.Seconds()),
+		Scope:       scope,
+	}, nil
+}
+
+// exchangeAuthorizationCode burns the code before checking it, so a code is never accepted
+// twice even when the first attempt carried a wrong verifier.
+func (oas *oauthService) exchangeAuthorizationCode(ctx context.Context, oauthClient domain.OAuthClient, payLoad domain.OAuthTokenPayLoad, clientInfo domain.ClientInfo) (*domain.LoginResponse, error) {
+	codeHash := secure.HashToken(payLoad.Code)
+
+	used, err := oas.authorizationCodeRepository.Use(codeHash)
+	if err != nil {
+		return nil, domain.ErrCreateAuthorizationCode
+	}
+
+	if !used {
+		return nil, domain.ErrInvalidGrant
+	}
+
+	authorizationCode, err := oas.authorizationCodeRepository.GetByCodeHash(codeHash)
+	if err != nil || authorizationCode == nil {
+		return nil, domain.ErrInvalidGrant
+	}
+
+	if authorizationCode.ClientID != oauthClient.ID || authorizationCode.RedirectURI != payLoad.RedirectURI {
+		return nil, domain.ErrInvalidGrant
+	}
+
+	if !verifyCodeChallenge(authorizationCode.CodeChallenge, payLoad.CodeVerifier) {
+		return nil, domain.ErrInvalidGrant
+	}
+
+	return oas.userService.CreateSession(ctx, authorizationCode.UserID, clientInfo)
+}
+
+func (oas *oauthService) authenticateClient(clientID string, clientSecret string) (*domain.OAuthClient, error) {
+	if clientID == "" {
+		return nil, domain.ErrInvalidClient
+	}
+
+	oauthClient, err := oas.oauthClientRepository.GetById(clientID)
+	if err != nil {
+		return nil, domain.ErrGetOAuthClient
+	}
+
+	if oauthClient == nil {
+		return nil, domain.ErrInvalidClient
+	}
+
+	if oauthClient.Public {
+		return oauthClient, nil
+	}
+
+	if subtle.ConstantTimeCompare([]byte(secure.HashToken(clientSecret)), []byte(oauthClient.SecretHash)) != 1 {
+		return nil, domain.ErrInvalidClient
+	}
+
+	return oauthClient, nil
+}
+
+// verifyCodeChallenge applies the S256 transformation of RFC 7636 to the verifier.
+func verifyCodeChallenge(codeChallenge string, codeVerifier string) bool {
+	hash := sha256.Sum256([]byte(codeVerifier))
+	expected := base64.RawURLEncoding.EncodeToString(hash[:])
+
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(codeChallenge)) == 1
+}
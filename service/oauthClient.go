@@ -0,0 +1,131 @@
+package service
+
+import (
+	"log/slog"
+	"strings"
+
+	"github.com/OVillas/autentication/domain"
+	"github.com/OVillas/autentication/secure"
+	"github.com/google/uuid"
+	"github.com/samber/do"
+)
+
+type oauthClientService struct {
+	i                     *do.Injector
+	oauthClientRepository domain.OAuthClientRepository
+	auditService          domain.AuditService
+}
+
+func NewOAuthClientService(i *do.Injector) (domain.OAuthClientService, error) {
+	oauthClientRepository := do.MustInvoke[domain.OAuthClientRepository](i)
+	auditService := do.MustInvoke[domain.AuditService](i)
+	return &oauthClientService{
+		i:                     i,
+		oauthClientRepository: oauthClientRepository,
+		auditService:          auditService,
+	}, nil
+}
+
+// Create registers a client. Confidential clients get a secret that is only returned here.
+func (ocs *oauthClientService) Create(viewer domain.Viewer, payLoad domain.OAuthClientPayLoad) (*domain.OAuthClientResponse, error) {
+	log := slog.With(
+		slog.String("service", "oauthClient"),
+		slog.String("func", "Create"))
+
+	log.Info("Create initiated")
+
+	id, err := uuid.NewRandom()
+	if err != nil {
+		log.Error("Error trying to generate oauth client id", slog.Any("error", err))
+		return nil, domain.ErrCreateOAuthClient
+	}
+
+	var scopes []string
+	for _, scope := range payLoad.Scopes {
+		scopes = append(scopes, strings.TrimSpace(scope))
+	}
+
+	var redirectURIs []string
+	for _, redirectURI := range payLoad.RedirectURIs {
+		redirectURIs = append(redirectURIs, strings.TrimSpace(redirectURI))
+	}
+
+	oauthClient := domain.OAuthClient{
+		ID:           id.String(),
+		Name:         strings.TrimSpace(payLoad.Name),
+		RedirectURIs: strings.Join(redirectURIs, ","),
+		Scopes:       strings.Join(scopes, ","),
+		Public:       payLoad.Public,
+	}
+
+	var clientSecret string
+	if !payLoad.Public {
+		secret, err := secure.GenerateOpaqueToken()
+		if err != nil {
+			log.Error("Error trying to generate oauth client secret", slog.Any("error", err))
+			return nil, domain.ErrCreateOAuthClient
+		}
+
+		clientSecret = domain.OAuthClientSecretPrefix + secret
+		oauthClient.SecretHash = secure.HashToken(clientSecret)
+	}
+
+	if err := ocs.oauthClientRepository.Create(oauthClient); err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return nil, domain.ErrCreateOAuthClient
+	}
+
+	ocs.auditService.Record(domain.AuditEventOAuthClientCreated, oauthClient.ID, viewer.Actor(), slog.String("name", oauthClient.Name))
+
+	response := oauthClient.ToOAuthClientResponse()
+	response.ClientSecret = clientSecret
+
+	log.Info("Create executed successfully")
+	return response, nil
+}
+
+func (ocs *oauthClientService) GetAll() ([]domain.OAuthClientResponse, error) {
+	log := slog.With(
+		slog.String("service", "oauthClient"),
+		slog.String("func", "GetAll"))
+
+	log.Info("GetAll initiated")
+
+	oauthClients, err := ocs.oauthClientRepository.GetAll()
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return nil, domain.ErrGetOAuthClient
+	}
+
+	var response []domain.OAuthClientResponse
+	for _, oauthClient := range oauthClients {
+		response = append(response, *oauthClient.ToOAuthClientResponse())
+	}
+
+	log.Info("GetAll executed successfully")
+	return response, nil
+}
+
+func (ocs *oauthClientService) Delete(viewer domain.Viewer, id string) error {
+	log := slog.With(
+		slog.String("service", "oauthClient"),
+		slog.String("func", "Delete"))
+
+	log.Info("Delete initiated")
+
+	deleted, err := ocs.oauthClientRepository.Delete(id)
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return domain.ErrDeleteOAuthClient
+	}
+
+	if !deleted {
+		log.Warn("OAuth client not found with this id: " + id)
+		return domain.ErrOAuthClientNotFound
+	}
+
+	ocs.auditService.Record(domain.AuditEventOAuthClientDeleted, id, viewer.Actor())
+
+	log.Info("Delete executed successfully")
+	return nil
+}
@@ -0,0 +1,44 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/OVillas/autentication/domain"
+	"github.com/samber/do"
+)
+
+type oidcService struct {
+	i              *do.Injector
+	userRepository domain.UserRepository
+}
+
+func NewOIDCService(i *do.Injector) (domain.OIDCService, error) {
+	userRepository := do.MustInvoke[domain.UserRepository](i)
+	return &oidcService{
+		i:              i,
+		userRepository: userRepository,
+	}, nil
+}
+
+func (ois *oidcService) GetUserInfo(ctx context.Context, userID string) (*domain.UserInfoResponse, error) {
+	log := slog.With(
+		slog.String("service", "oidc"),
+		slog.String("func", "GetUserInfo"))
+
+	log.Info("GetUserInfo initiated")
+
+	user, err := ois.userRepository.GetById(ctx, userID)
+	if err != nil {
+		log.Error("Failed to obtain user by id", slog.Any("error", err))
+		return nil, domain.ErrGetUser
+	}
+
+	if user == nil {
+		log.Warn("User not found with this id: " + userID)
+		return nil, domain.ErrUserNotFound
+	}
+
+	log.Info("GetUserInfo executed successfully")
+	return user.ToUserInfoResponse(), nil
+}
@@ -0,0 +1,321 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"log/slog"
+
+	"github.com/OVillas/autentication/domain"
+	"github.com/google/uuid"
+	"github.com/samber/do"
+)
+
+type organizationService struct {
+	i                      *do.Injector
+	organizationRepository domain.OrganizationRepository
+	userRepository         domain.UserRepository
+	invitationService      domain.InvitationService
+	emailService           domain.EmailService
+	auditService           domain.AuditService
+}
+
+func NewOrganizationService(i *do.Injector) (domain.OrganizationService, error) {
+	organizationRepository := do.MustInvoke[domain.OrganizationRepository](i)
+	userRepository := do.MustInvoke[domain.UserRepository](i)
+	invitationService := do.MustInvoke[domain.InvitationService](i)
+	emailService := do.MustInvoke[domain.EmailService](i)
+	auditService := do.MustInvoke[domain.AuditService](i)
+	return &organizationService{
+		i:                      i,
+		organizationRepository: organizationRepository,
+		userRepository:         userRepository,
+		invitationService:      invitationService,
+		emailService:           emailService,
+		auditService:           auditService,
+	}, nil
+}
+
+// Create makes the viewer the first owner of a new organization.
+func (ogs *organizationService) Create(viewer domain.Viewer, payLoad domain.OrganizationPayLoad) (*domain.OrganizationResponse, error) {
+	log := slog.With(
+		slog.String("service", "organization"),
+		slog.String("func", "Create"))
+
+	log.Info("Create initiated")
+
+	organization := domain.Organization{ID: uuid.NewString(), Name: payLoad.Name}
+	owner := domain.Membership{OrganizationID: organization.ID, UserID: viewer.UserID, Role: domain.OrganizationRoleOwner}
+	if err := ogs.organizationRepository.Create(organization, owner); err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return nil, domain.ErrCreateOrganization
+	}
+
+	ogs.auditService.Record(domain.AuditEventOrganizationCreated, organization.ID, viewer.Actor(),
+		slog.String("name", organization.Name))
+
+	log.Info("Create executed successfully")
+	userOrganization := domain.UserOrganization{ID: organization.ID, Name: organization.Name, Role: owner.Role}
+	return userOrganization.ToOrganizationResponse(), nil
+}
+
+func (ogs *organizationService) GetMine(userID string) ([]domain.OrganizationResponse, error) {
+	log := slog.With(
+		slog.String("service", "organization"),
+		slog.String("func", "GetMine"))
+
+	log.Info("GetMine initiated")
+
+	organizations, err := ogs.organizationRepository.GetByUserID(userID)
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return nil, domain.ErrGetOrganization
+	}
+
+	organizationsResponse := make([]domain.OrganizationResponse, 0, len(organizations))
+	for _, organization := range organizations {
+		organizationsResponse = append(organizationsResponse, *organization.ToOrganizationResponse())
+	}
+
+	log.Info("GetMine executed successfully")
+	return organizationsResponse, nil
+}
+
+func (ogs *organizationService) GetMembers(viewer domain.Viewer, organizationID string) ([]domain.MemberResponse, error) {
+	log := slog.With(
+		slog.String("service", "organization"),
+		slog.String("func", "GetMembers"))
+
+	log.Info("GetMembers initiated")
+
+	if _, _, err := ogs.access(viewer, organizationID); err != nil {
+		return nil, err
+	}
+
+	members, err := ogs.organizationRepository.GetMembers(organizationID)
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return nil, domain.ErrGetOrganization
+	}
+
+	membersResponse := make([]domain.MemberResponse, 0, len(members))
+	for _, member := range members {
+		membersResponse = append(membersResponse, *member.ToMemberResponse())
+	}
+
+	log.Info("GetMembers executed successfully")
+	return membersResponse, nil
+}
+
+// InviteMember adds the registered user of the email right away and lets them know by email.
+// Anyone else is invited to register, joining the organization when they accept.
+func (ogs *organizationService) InviteMember(ctx context.Context, viewer domain.Viewer, organizationID string, payLoad domain.MemberInvitationPayLoad) (*domain.MemberInvitationResponse, error) {
+	log := slog.With(
+		slog.String("service", "organization"),
+		slog.String("func", "InviteMember"))
+
+	log.Info("InviteMember initiated")
+
+	organization, membership, err := ogs.access(viewer, organizationID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !membership.CanManage(payLoad.Role) {
+		log.Warn("Member invitation not allowed to: " + viewer.UserID)
+		return nil, domain.ErrUserNotAuthorized
+	}
+
+	user, err := ogs.userRepository.GetByEmail(ctx, viewer.ClientInfo.TenantID, payLoad.Email)
+	if err != nil {
+		log.Error("Failed to obtain user by email", slog.Any("error", err))
+		return nil, domain.ErrGetUser
+	}
+
+	if user == nil {
+		invitation, err := ogs.invitationService.InviteToOrganization(ctx, viewer, payLoad.Email, *organization, payLoad.Role)
+		if err != nil {
+			return nil, err
+		}
+
+		log.Info("InviteMember executed successfully")
+		return &domain.MemberInvitationResponse{Invitation: invitation}, nil
+	}
+
+	member := domain.Membership{OrganizationID: organization.ID, UserID: user.ID, Role: payLoad.Role}
+	added, err := ogs.organizationRepository.AddMember(member)
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return nil, domain.ErrAddMember
+	}
+
+	if !added {
+		log.Warn("User already a member: " + user.ID)
+		return nil, domain.ErrAlreadyMember
+	}
+
+	ogs.auditService.Record(domain.AuditEventMemberAdded, organization.ID, viewer.Actor(),
+		slog.String("user_id", user.ID), slog.String("role", member.Role))
+	go ogs.sendMemberAddedEmail(*user, *organization)
+
+	log.Info("InviteMember executed successfully")
+	return &domain.MemberInvitationResponse{Member: &domain.MemberResponse{
+		UserId:   user.ID,
+		Name:     user.Name,
+		Username: user.Username,
+		Role:     member.Role,
+	}}, nil
+}
+
+// UpdateMemberRole changes the role of a member. Admins manage members and admins, owners
+// manage everyone, and the last owner stays an owner.
+func (ogs *organizationService) UpdateMemberRole(viewer domain.Viewer, organizationID string, userID string, role string) error {
+	log := slog.With(
+		slog.String("service", "organization"),
+		slog.String("func", "UpdateMemberRole"))
+
+	log.Info("UpdateMemberRole initiated")
+
+	_, membership, err := ogs.access(viewer, organizationID)
+	if err != nil {
+		return err
+	}
+
+	target, err := ogs.getMember(organizationID, userID)
+	if err != nil {
+		return err
+	}
+
+	if !membership.CanManage(target.Role) || !membership.CanManage(role) {
+		log.Warn("Member role change not allowed to: " + viewer.UserID)
+		return domain.ErrUserNotAuthorized
+	}
+
+	err = ogs.organizationRepository.UpdateRole(organizationID, userID, role)
+	if errors.Is(err, domain.ErrLastOwner) || errors.Is(err, domain.ErrMemberNotFound) {
+		log.Warn("Member role change refused", slog.Any("error", err))
+		return err
+	}
+
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return domain.ErrUpdateMember
+	}
+
+	ogs.auditService.Record(domain.AuditEventMemberRoleChanged, organizationID, viewer.Actor(),
+		slog.String("user_id", userID), slog.String("from", target.Role), slog.String("to", role))
+
+	log.Info("UpdateMemberRole executed successfully")
+	return nil
+}
+
+// RemoveMember takes a member out of the organization. Members can always leave on their own,
+// except the last owner.
+func (ogs *organizationService) RemoveMember(viewer domain.Viewer, organizationID string, userID string) error {
+	log := slog.With(
+		slog.String("service", "organization"),
+		slog.String("func", "RemoveMember"))
+
+	log.Info("RemoveMember initiated")
+
+	_, membership, err := ogs.access(viewer, organizationID)
+	if err != nil {
+		return err
+	}
+
+	if userID != viewer.UserID {
+		target, err := ogs.getMember(organizationID, userID)
+		if err != nil {
+			return err
+		}
+
+		if !membership.CanManage(target.Role) {
+			log.Warn("Member removal not allowed to: " + viewer.UserID)
+			return domain.ErrUserNotAuthorized
+		}
+	}
+
+	err = ogs.organizationRepository.RemoveMember(organizationID, userID)
+	if errors.Is(err, domain.ErrLastOwner) || errors.Is(err, domain.ErrMemberNotFound) {
+		log.Warn("Member removal refused", slog.Any("error", err))
+		return err
+	}
+
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return domain.ErrRemoveMember
+	}
+
+	ogs.auditService.Record(domain.AuditEventMemberRemoved, organizationID, viewer.Actor(),
+		slog.String("user_id", userID))
+
+	log.Info("RemoveMember executed successfully")
+	return nil
+}
+
+// Private session
+// access returns the organization and the membership of the viewer in it. Admins of the API
+// act as owners of every organization.
+func (ogs *organizationService) access(viewer domain.Viewer, organizationID string) (*domain.Organization, *domain.Membership, error) {
+	log := slog.With(
+		slog.String("service", "organization"),
+		slog.String("func", "access"))
+
+	organization, err := ogs.organizationRepository.GetById(organizationID)
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return nil, nil, domain.ErrGetOrganization
+	}
+
+	if organization == nil {
+		log.Warn("Organization not found: " + organizationID)
+		return nil, nil, domain.ErrOrganizationNotFound
+	}
+
+	membership, err := ogs.organizationRepository.GetMembership(organizationID, viewer.UserID)
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return nil, nil, domain.ErrGetOrganization
+	}
+
+	if membership == nil && viewer.Admin {
+		membership = &domain.Membership{OrganizationID: organizationID, UserID: viewer.UserID, Role: domain.OrganizationRoleOwner}
+	}
+
+	if membership == nil {
+		log.Warn("Organization accessed by a non member: " + viewer.UserID)
+		return nil, nil, domain.ErrOrganizationNotFound
+	}
+
+	return organization, membership, nil
+}
+
+func (ogs *organizationService) getMember(organizationID string, userID string) (*domain.Membership, error) {
+	membership, err := ogs.organizationRepository.GetMembership(organizationID, userID)
+	if err != nil {
+		slog.Error("Error trying to get member", slog.Any("error", err))
+		return nil, domain.ErrGetOrganization
+	}
+
+	if membership == nil {
+		return nil, domain.ErrMemberNotFound
+	}
+
+	return membership, nil
+}
+
+func (ogs *organizationService) sendMemberAddedEmail(user domain.User, organization domain.Organization) {
+	log := slog.With(
+		slog.String("service", "organization"),
+		slog.String("func", "sendMemberAddedEmail"))
+
+	subject := "Você entrou em " + organization.Name
+	content := fmt.Sprintf("<h1>Olá, %s!</h1><p>Você foi adicionado à organização %s.</p>"+
+		"<p>Se não reconhece esta organização, você pode sair dela nas configurações da sua conta.</p>",
+		html.EscapeString(user.Name), html.EscapeString(organization.Name))
+
+	if err := ogs.emailService.SendEmail(subject, content, []string{user.Email}); err != nil {
+		log.Error("Error trying to send member added email", slog.Any("error", err))
+	}
+}
@@ -0,0 +1,32 @@
+package service
+
+import (
+	"log/slog"
+
+	"github.com/OVillas/autentication/config"
+	"github.com/OVillas/autentication/domain"
+	"github.com/OVillas/autentication/secure"
+)
+
+// checkPasswordBreached refuses passwords found in known breaches. When the check itself fails,
+// the password is accepted unless the operator asked to fail closed.
+func checkPasswordBreached(password string) error {
+	if !config.PasswordBreachCheck.Enabled {
+		return nil
+	}
+
+	breached, err := secure.IsPasswordBreached(password)
+	if err != nil {
+		slog.Error("Error trying to check breached passwords", slog.Any("error", err))
+		if config.PasswordBreachCheck.FailClosed {
+			return domain.ErrPasswordBreachCheck
+		}
+		return nil
+	}
+
+	if breached {
+		return domain.ErrPasswordBreached
+	}
+
+	return nil
+}
@@ -0,0 +1,110 @@
+package service
+
+import (
+	"log/slog"
+	"strings"
+
+	"github.com/OVillas/autentication/domain"
+	"github.com/OVillas/autentication/secure"
+	"github.com/google/uuid"
+	"github.com/samber/do"
+)
+
+type personalAccessTokenService struct {
+	i                             *do.Injector
+	personalAccessTokenRepository domain.PersonalAccessTokenRepository
+}
+
+func NewPersonalAccessTokenService(i *do.Injector) (domain.PersonalAccessTokenService, error) {
+	personalAccessTokenRepository := do.MustInvoke[domain.PersonalAccessTokenRepository](i)
+	return &personalAccessTokenService{
+		i:                             i,
+		personalAccessTokenRepository: personalAccessTokenRepository,
+	}, nil
+}
+
+func (pats *personalAccessTokenService) Create(userID string, payLoad domain.PersonalAccessTokenPayLoad) (*domain.PersonalAccessTokenResponse, error) {
+	log := slog.With(
+		slog.String("service", "personalAccessToken"),
+		slog.String("func", "Create"))
+
+	log.Info("Create initiated")
+
+	secret, err := secure.GenerateOpaqueToken()
+	if err != nil {
+		log.Error("Error trying to generate personal access token", slog.Any("error", err))
+		return nil, domain.ErrCreatePersonalAccessToken
+	}
+
+	id, err := uuid.NewRandom()
+	if err != nil {
+		log.Error("Error trying to generate personal access token id", slog.Any("error", err))
+		return nil, domain.ErrCreatePersonalAccessToken
+	}
+
+	token := domain.PersonalAccessTokenPrefix + secret
+	personalAccessToken := domain.PersonalAccessToken{
+		ID:        id.String(),
+		UserID:    userID,
+		Name:      strings.TrimSpace(payLoad.Name),
+		TokenHash: secure.HashToken(token),
+		Scopes:    strings.Join(payLoad.Scopes, ","),
+		ExpiresAt: payLoad.ExpiresAt,
+		CreatedAt: payLoad.CreatedAt,
+	}
+
+	if err := pats.personalAccessTokenRepository.Create(personalAccessToken); err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return nil, domain.ErrCreatePersonalAccessToken
+	}
+
+	response := personalAccessToken.ToPersonalAccessTokenResponse()
+	response.Token = token
+
+	log.Info("Create executed successfully")
+	return response, nil
+}
+
+func (pats *personalAccessTokenService) GetAll(userID string) ([]domain.PersonalAccessTokenResponse, error) {
+	log := slog.With(
+		slog.String("service", "personalAccessToken"),
+		slog.String("func", "GetAll"))
+
+	log.Info("GetAll initiated")
+
+	personalAccessTokens, err := pats.personalAccessTokenRepository.GetByUserID(userID)
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return nil, domain.ErrGetPersonalAccessToken
+	}
+
+	var response []domain.PersonalAccessTokenResponse
+	for _, personalAccessToken := range personalAccessTokens {
+		response = append(response, *personalAccessToken.ToPersonalAccessTokenResponse())
+	}
+
+	log.Info("GetAll executed successfully")
+	return response, nil
+}
+
+func (pats *personalAccessTokenService) Revoke(userID string, id string) error {
+	log := slog.With(
+		slog.String("service", "personalAccessToken"),
+		slog.String("func", "Revoke"))
+
+	log.Info("Revoke initiated")
+
+	revoked, err := pats.personalAccessTokenRepository.Revoke(userID, id)
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return domain.ErrRevokeToken
+	}
+
+	if !revoked {
+		log.Warn("Personal access token not found with this id: " + id)
+		return domain.ErrPersonalAccessTokenNotFound
+	}
+
+	log.Info("Revoke executed successfully")
+	return nil
+}
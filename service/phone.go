@@ -0,0 +1,218 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/OVillas/autentication/config"
+	"github.com/OVillas/autentication/domain"
+	"github.com/samber/do"
+)
+
+type phoneService struct {
+	i                       *do.Injector
+	userRepository          domain.UserRepository
+	confirmationCodeService domain.ConfirmationCodeService
+	auditService            domain.AuditService
+}
+
+func NewPhoneService(i *do.Injector) (domain.PhoneService, error) {
+	userRepository := do.MustInvoke[domain.UserRepository](i)
+	confirmationCodeService := do.MustInvoke[domain.ConfirmationCodeService](i)
+	auditService := do.MustInvoke[domain.AuditService](i)
+	return &phoneService{
+		i:                       i,
+		userRepository:          userRepository,
+		confirmationCodeService: confirmationCodeService,
+		auditService:            auditService,
+	}, nil
+}
+
+// SetPhoneNumber replaces the number of the user with an unconfirmed phoneNumber and texts it a
+// code. Setting the number the user already has only sends a new code while it is unconfirmed.
+func (ps *phoneService) SetPhoneNumber(ctx context.Context, userID string, phoneNumber string) error {
+	log := slog.With(
+		slog.String("service", "phone"),
+		slog.String("func", "SetPhoneNumber"))
+
+	log.Info("SetPhoneNumber initiated")
+
+	if config.SMS.Provider == config.SMSProviderNone {
+		log.Warn("Phone number set while SMS is disabled")
+		return domain.ErrSMSUnavailable
+	}
+
+	phoneNumber, err := domain.NormalizePhoneNumber(phoneNumber)
+	if err != nil {
+		return err
+	}
+
+	user, err := ps.getUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if user.PhoneNumber != nil && *user.PhoneNumber == phoneNumber {
+		if user.PhoneConfirmed {
+			log.Warn("Phone number already confirmed for user: " + userID)
+			return domain.ErrPhoneAlreadyConfirmed
+		}
+	} else if err := ps.userRepository.SetPhoneNumber(ctx, userID, &phoneNumber); err != nil {
+		if errors.Is(err, domain.ErrPhoneTaken) {
+			log.Warn("Phone number already taken: " + phoneNumber)
+			return err
+		}
+
+		log.Error("Error: ", slog.Any("error", err))
+		return domain.ErrUpdatePhoneNumber
+	}
+
+	if err := ps.confirmationCodeService.SendPhoneConfirmationCode(user.TenantID, phoneNumber); err != nil {
+		log.Warn("Phone confirmation code not sent", slog.Any("error", err))
+		return err
+	}
+
+	log.Info("SetPhoneNumber executed successfully")
+	return nil
+}
+
+// ResendConfirmation texts a new code, replacing the previous one, to the number waiting for
+// confirmation.
+func (ps *phoneService) ResendConfirmation(ctx context.Context, userID string) error {
+	log := slog.With(
+		slog.String("service", "phone"),
+		slog.String("func", "ResendConfirmation"))
+
+	log.Info("ResendConfirmation initiated")
+
+	user, err := ps.getUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if user.PhoneNumber == nil || user.PhoneConfirmed {
+		log.Warn("No phone number waiting for confirmation for user: " + userID)
+		return domain.ErrNoPhoneNumber
+	}
+
+	if err := ps.confirmationCodeService.SendPhoneConfirmationCode(user.TenantID, *user.PhoneNumber); err != nil {
+		log.Warn("Phone confirmation code not sent", slog.Any("error", err))
+		return err
+	}
+
+	log.Info("ResendConfirmation executed successfully")
+	return nil
+}
+
+func (ps *phoneService) ConfirmPhoneNumber(ctx context.Context, userID string, code string, clientInfo domain.ClientInfo) error {
+	log := slog.With(
+		slog.String("service", "phone"),
+		slog.String("func", "ConfirmPhoneNumber"))
+
+	log.Info("ConfirmPhoneNumber initiated")
+
+	user, err := ps.getUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if user.PhoneNumber == nil || user.PhoneConfirmed {
+		log.Warn("No phone number waiting for confirmation for user: " + userID)
+		return domain.ErrNoPhoneNumber
+	}
+
+	phoneNumber := *user.PhoneNumber
+	if err := ps.confirmationCodeService.CheckCode(user.TenantID, domain.ConfirmCode{Email: phoneNumber, Code: code}); err != nil {
+		log.Warn("Invalid phone confirmation code for user: "+userID, slog.Any("error", err))
+		return err
+	}
+
+	// The number may have been replaced while the code was being typed.
+	confirmed, err := ps.userRepository.ConfirmPhoneNumber(ctx, userID, phoneNumber)
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return domain.ErrUpdatePhoneNumber
+	}
+
+	if !confirmed {
+		log.Warn("Phone number replaced before confirmation for user: " + userID)
+		return domain.ErrNoPhoneNumber
+	}
+
+	ps.auditService.Record(domain.AuditEventPhoneConfirmed, userID, domain.UserActor(userID, clientInfo))
+
+	log.Info("ConfirmPhoneNumber executed successfully")
+	return nil
+}
+
+func (ps *phoneService) RemovePhoneNumber(ctx context.Context, userID string, clientInfo domain.ClientInfo) error {
+	log := slog.With(
+		slog.String("service", "phone"),
+		slog.String("func", "RemovePhoneNumber"))
+
+	log.Info("RemovePhoneNumber initiated")
+
+	user, err := ps.getUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if user.PhoneNumber == nil {
+		log.Info("RemovePhoneNumber executed successfully, no phone number")
+		return nil
+	}
+
+	if err := ps.userRepository.SetPhoneNumber(ctx, userID, nil); err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return domain.ErrUpdatePhoneNumber
+	}
+
+	ps.auditService.Record(domain.AuditEventPhoneRemoved, userID, domain.UserActor(userID, clientInfo))
+
+	log.Info("RemovePhoneNumber executed successfully")
+	return nil
+}
+
+// SendTwoFactorCode texts the code of a two-step login to the confirmed number of the user.
+func (ps *phoneService) SendTwoFactorCode(ctx context.Context, userID string) error {
+	log := slog.With(
+		slog.String("service", "phone"),
+		slog.String("func", "SendTwoFactorCode"))
+
+	log.Info("SendTwoFactorCode initiated")
+
+	user, err := ps.getUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if !user.CanReceiveSMS() {
+		log.Warn("No confirmed phone number to text the login code for user: " + userID)
+		return domain.ErrTwoFactorMethod
+	}
+
+	if err := ps.confirmationCodeService.SendTwoFactorSMS(user.TenantID, *user.PhoneNumber); err != nil {
+		log.Warn("Login code not sent by SMS", slog.Any("error", err))
+		return err
+	}
+
+	log.Info("SendTwoFactorCode executed successfully")
+	return nil
+}
+
+// Private session
+func (ps *phoneService) getUser(ctx context.Context, userID string) (*domain.User, error) {
+	user, err := ps.userRepository.GetById(ctx, userID)
+	if err != nil {
+		slog.Error("Failed to obtain user by id", slog.Any("error", err))
+		return nil, domain.ErrGetUser
+	}
+
+	if user == nil {
+		slog.Warn("User not found with this id: " + userID)
+		return nil, domain.ErrUserNotFound
+	}
+
+	return user, nil
+}
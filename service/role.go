@@ -0,0 +1,389 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/OVillas/autentication/config"
+	"github.com/OVillas/autentication/domain"
+	"github.com/google/uuid"
+	"github.com/samber/do"
+)
+
+type roleService struct {
+	i                    *do.Injector
+	roleRepository       domain.RoleRepository
+	permissionRepository domain.PermissionRepository
+	userRepository       domain.UserRepository
+	auditService         domain.AuditService
+}
+
+func NewRoleService(i *do.Injector) (domain.RoleService, error) {
+	roleRepository := do.MustInvoke[domain.RoleRepository](i)
+	permissionRepository := do.MustInvoke[domain.PermissionRepository](i)
+	userRepository := do.MustInvoke[domain.UserRepository](i)
+	auditService := do.MustInvoke[domain.AuditService](i)
+	return &roleService{
+		i:                    i,
+		roleRepository:       roleRepository,
+		permissionRepository: permissionRepository,
+		userRepository:       userRepository,
+		auditService:         auditService,
+	}, nil
+}
+
+func (rs *roleService) AssignRole(ctx context.Context, viewer domain.Viewer, userID string, roleName string) error {
+	log := slog.With(
+		slog.String("service", "role"),
+		slog.String("func", "AssignRole"))
+
+	log.Info("AssignRole initiated")
+
+	if !viewer.Admin {
+		log.Warn("Role assignment attempted by a non admin: " + viewer.UserID)
+		return domain.ErrUserNotAuthorized
+	}
+
+	role, err := rs.getUserAndRole(ctx, viewer, userID, roleName)
+	if err != nil {
+		return err
+	}
+
+	if _, err := rs.roleRepository.Assign(domain.UserRole{UserID: userID, RoleID: role.ID}); err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return domain.ErrAssignRole
+	}
+
+	rs.auditService.Record(domain.AuditEventRoleAssigned, userID, viewer.Actor(), slog.String("role", role.Name))
+
+	log.Info("AssignRole executed successfully")
+	return nil
+}
+
+// RevokeRole takes the role away from the user and ends the access tokens that carry it.
+func (rs *roleService) RevokeRole(ctx context.Context, viewer domain.Viewer, userID string, roleName string) error {
+	log := slog.With(
+		slog.String("service", "role"),
+		slog.String("func", "RevokeRole"))
+
+	log.Info("RevokeRole initiated")
+
+	if !viewer.Admin {
+		log.Warn("Role revocation attempted by a non admin: " + viewer.UserID)
+		return domain.ErrUserNotAuthorized
+	}
+
+	role, err := rs.getUserAndRole(ctx, viewer, userID, roleName)
+	if err != nil {
+		return err
+	}
+
+	if role.Name == domain.RoleAdmin {
+		admins, err := rs.roleRepository.CountUsers(role.ID)
+		if err != nil {
+			log.Error("Error: ", slog.Any("error", err))
+			return domain.ErrRevokeRole
+		}
+
+		if admins <= 1 {
+			log.Warn("Refused to revoke the last admin: " + userID)
+			return domain.ErrLastAdmin
+		}
+	}
+
+	revoked, err := rs.roleRepository.Revoke(userID, role.ID)
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return domain.ErrRevokeRole
+	}
+
+	if !revoked {
+		log.Warn("User does not have the role: " + roleName)
+		return domain.ErrRoleNotAssigned
+	}
+
+	if err := rs.userRepository.IncrementTokenVersion(ctx, userID); err != nil {
+		log.Error("Failed to increment token version", slog.Any("error", err))
+		return domain.ErrRevokeRole
+	}
+
+	rs.auditService.Record(domain.AuditEventRoleRevoked, userID, viewer.Actor(), slog.String("role", role.Name))
+
+	log.Info("RevokeRole executed successfully")
+	return nil
+}
+
+func (rs *roleService) CreateRole(viewer domain.Viewer, roleName string) error {
+	log := slog.With(
+		slog.String("service", "role"),
+		slog.String("func", "CreateRole"))
+
+	log.Info("CreateRole initiated")
+
+	if !viewer.Admin {
+		log.Warn("Role creation attempted by a non admin: " + viewer.UserID)
+		return domain.ErrUserNotAuthorized
+	}
+
+	role, err := rs.roleRepository.GetByName(roleName)
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return domain.ErrGetRole
+	}
+
+	if role != nil {
+		log.Warn("Role already exists: " + roleName)
+		return domain.ErrRoleExists
+	}
+
+	role = &domain.Role{ID: uuid.NewString(), Name: roleName}
+	if err := rs.roleRepository.Create(*role); err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return domain.ErrCreateRole
+	}
+
+	rs.auditService.Record(domain.AuditEventRoleCreated, role.ID, viewer.Actor(), slog.String("role", role.Name))
+
+	log.Info("CreateRole executed successfully")
+	return nil
+}
+
+// GrantPermission gives the permission to every user with the role. Their access tokens stop
+// working, so the next refresh issues tokens carrying it.
+func (rs *roleService) GrantPermission(ctx context.Context, viewer domain.Viewer, roleName string, permissionName string) error {
+	log := slog.With(
+		slog.String("service", "role"),
+		slog.String("func", "GrantPermission"))
+
+	log.Info("GrantPermission initiated")
+
+	role, permission, err := rs.getRoleAndPermission(viewer, roleName, permissionName)
+	if err != nil {
+		return err
+	}
+
+	granted, err := rs.permissionRepository.Grant(domain.RolePermission{RoleID: role.ID, PermissionID: permission.ID})
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return domain.ErrGrantPermission
+	}
+
+	if granted {
+		if err := rs.userRepository.IncrementTokenVersionByRole(ctx, role.ID); err != nil {
+			log.Error("Failed to increment token version", slog.Any("error", err))
+			return domain.ErrGrantPermission
+		}
+
+		rs.auditService.Record(domain.AuditEventPermissionGranted, role.ID, viewer.Actor(),
+			slog.String("role", role.Name), slog.String("permission", permission.Name))
+	}
+
+	log.Info("GrantPermission executed successfully")
+	return nil
+}
+
+// RevokePermission takes the permission away from the role, ending the access tokens of its
+// users right away.
+func (rs *roleService) RevokePermission(ctx context.Context, viewer domain.Viewer, roleName string, permissionName string) error {
+	log := slog.With(
+		slog.String("service", "role"),
+		slog.String("func", "RevokePermission"))
+
+	log.Info("RevokePermission initiated")
+
+	role, permission, err := rs.getRoleAndPermission(viewer, roleName, permissionName)
+	if err != nil {
+		return err
+	}
+
+	revoked, err := rs.permissionRepository.Revoke(role.ID, permission.ID)
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return domain.ErrRevokePermission
+	}
+
+	if !revoked {
+		log.Warn("Role does not have the permission: " + permissionName)
+		return domain.ErrPermissionNotGranted
+	}
+
+	if err := rs.userRepository.IncrementTokenVersionByRole(ctx, role.ID); err != nil {
+		log.Error("Failed to increment token version", slog.Any("error", err))
+		return domain.ErrRevokePermission
+	}
+
+	rs.auditService.Record(domain.AuditEventPermissionRevoked, role.ID, viewer.Actor(),
+		slog.String("role", role.Name), slog.String("permission", permission.Name))
+
+	log.Info("RevokePermission executed successfully")
+	return nil
+}
+
+// BootstrapAdmin runs at startup. It makes sure the permissions and the admin role exist, grants
+// every permission to the role and the role to the account of BOOTSTRAP_ADMIN_EMAIL, which must
+// already be registered.
+func (rs *roleService) BootstrapAdmin(ctx context.Context) error {
+	log := slog.With(
+		slog.String("service", "role"),
+		slog.String("func", "BootstrapAdmin"))
+
+	log.Info("BootstrapAdmin initiated")
+
+	role, err := rs.roleRepository.GetByName(domain.RoleAdmin)
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return domain.ErrBootstrapAdmin
+	}
+
+	if role == nil {
+		role = &domain.Role{ID: uuid.NewString(), Name: domain.RoleAdmin}
+		// Another instance starting at the same time may have created it first.
+		if err := rs.roleRepository.Create(*role); err != nil {
+			role, err = rs.roleRepository.GetByName(domain.RoleAdmin)
+			if err != nil || role == nil {
+				log.Error("Error trying to create the admin role", slog.Any("error", err))
+				return domain.ErrBootstrapAdmin
+			}
+		}
+	}
+
+	grantedAny := false
+	for _, permissionName := range domain.Permissions {
+		permission, err := rs.permissionRepository.GetByName(permissionName)
+		if err != nil {
+			log.Error("Error: ", slog.Any("error", err))
+			return domain.ErrBootstrapAdmin
+		}
+
+		if permission == nil {
+			permission = &domain.Permission{ID: uuid.NewString(), Name: permissionName}
+			if err := rs.permissionRepository.Create(*permission); err != nil {
+				permission, err = rs.permissionRepository.GetByName(permissionName)
+				if err != nil || permission == nil {
+					log.Error("Error trying to create a permission", slog.Any("error", err))
+					return domain.ErrBootstrapAdmin
+				}
+			}
+		}
+
+		granted, err := rs.permissionRepository.Grant(domain.RolePermission{RoleID: role.ID, PermissionID: permission.ID})
+		if err != nil {
+			log.Error("Error: ", slog.Any("error", err))
+			return domain.ErrBootstrapAdmin
+		}
+		grantedAny = grantedAny || granted
+	}
+
+	// Admins holding tokens issued before a new permission existed get it on their next refresh.
+	if grantedAny {
+		if err := rs.userRepository.IncrementTokenVersionByRole(ctx, role.ID); err != nil {
+			log.Error("Failed to increment token version", slog.Any("error", err))
+			return domain.ErrBootstrapAdmin
+		}
+	}
+
+	if config.BootstrapAdminEmail == "" {
+		log.Info("BootstrapAdmin executed successfully")
+		return nil
+	}
+
+	user, err := rs.userRepository.GetByEmail(ctx, config.DefaultTenant, config.BootstrapAdminEmail)
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return domain.ErrBootstrapAdmin
+	}
+
+	if user == nil {
+		log.Warn("No account to make admin with email: " + config.BootstrapAdminEmail)
+		return nil
+	}
+
+	assigned, err := rs.roleRepository.Assign(domain.UserRole{UserID: user.ID, RoleID: role.ID})
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return domain.ErrBootstrapAdmin
+	}
+
+	if assigned {
+		rs.auditService.Record(domain.AuditEventRoleAssigned, user.ID, domain.SystemActor, slog.String("role", role.Name))
+	}
+
+	log.Info("BootstrapAdmin executed successfully")
+	return nil
+}
+
+// Private session
+
+// getUserAndRole checks that the user exists in the tenant of viewer and returns the role named
+// roleName.
+func (rs *roleService) getUserAndRole(ctx context.Context, viewer domain.Viewer, userID string, roleName string) (*domain.Role, error) {
+	log := slog.With(
+		slog.String("service", "role"),
+		slog.String("func", "getUserAndRole"))
+
+	user, err := rs.userRepository.GetById(ctx, userID)
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return nil, domain.ErrGetUser
+	}
+
+	if user == nil || !viewer.SameTenant(*user) {
+		log.Warn("User not found with this id: " + userID)
+		return nil, domain.ErrUserNotFound
+	}
+
+	role, err := rs.roleRepository.GetByName(roleName)
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return nil, domain.ErrGetRole
+	}
+
+	if role == nil {
+		log.Warn("Role not found with this name: " + roleName)
+		return nil, domain.ErrRoleNotFound
+	}
+
+	return role, nil
+}
+
+// getRoleAndPermission checks that viewer may change the permissions of the role and returns
+// it with the permission. The admin role always keeps every permission.
+func (rs *roleService) getRoleAndPermission(viewer domain.Viewer, roleName string, permissionName string) (*domain.Role, *domain.Permission, error) {
+	log := slog.With(
+		slog.String("service", "role"),
+		slog.String("func", "getRoleAndPermission"))
+
+	if !viewer.Admin {
+		log.Warn("Role permissions change attempted by a non admin: " + viewer.UserID)
+		return nil, nil, domain.ErrUserNotAuthorized
+	}
+
+	if roleName == domain.RoleAdmin {
+		log.Warn("Refused to change the permissions of the admin role")
+		return nil, nil, domain.ErrAdminPermissionsImmutable
+	}
+
+	role, err := rs.roleRepository.GetByName(roleName)
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return nil, nil, domain.ErrGetRole
+	}
+
+	if role == nil {
+		log.Warn("Role not found with this name: " + roleName)
+		return nil, nil, domain.ErrRoleNotFound
+	}
+
+	permission, err := rs.permissionRepository.GetByName(permissionName)
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return nil, nil, domain.ErrGetPermission
+	}
+
+	if permission == nil {
+		log.Warn("Permission not found with this name: " + permissionName)
+		return nil, nil, domain.ErrPermissionNotFound
+	}
+
+	return role, permission, nil
+}
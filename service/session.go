@@ -0,0 +1,93 @@
+package service
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/OVillas/autentication/config"
+	"github.com/OVillas/autentication/domain"
+	"github.com/samber/do"
+)
+
+type sessionService struct {
+	i                      *do.Injector
+	refreshTokenRepository domain.RefreshTokenRepository
+	revokedTokenRepository domain.RevokedTokenRepository
+}
+
+func NewSessionService(i *do.Injector) (domain.SessionService, error) {
+	refreshTokenRepository := do.MustInvoke[domain.RefreshTokenRepository](i)
+	revokedTokenRepository := do.MustInvoke[domain.RevokedTokenRepository](i)
+	return &sessionService{
+		i:                      i,
+		refreshTokenRepository: refreshTokenRepository,
+		revokedTokenRepository: revokedTokenRepository,
+	}, nil
+}
+
+func (ss *sessionService) GetAll(userID string, currentSessionID string) ([]domain.SessionResponse, error) {
+	log := slog.With(
+		slog.String("service", "session"),
+		slog.String("func", "GetAll"))
+
+	log.Info("GetAll initiated")
+
+	refreshTokens, err := ss.refreshTokenRepository.GetActiveByUserID(userID)
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return nil, domain.ErrGetSession
+	}
+
+	var response []domain.SessionResponse
+	for _, refreshToken := range refreshTokens {
+		session := refreshToken.ToSessionResponse()
+		session.Current = refreshToken.FamilyID == currentSessionID
+		response = append(response, *session)
+	}
+
+	log.Info("GetAll executed successfully")
+	return response, nil
+}
+
+// Revoke ends a session: its refresh token family is revoked and the session id is added to the
+// revocation list for one access token lifetime, so access tokens carrying it as sid stop working.
+func (ss *sessionService) Revoke(userID string, sessionID string) error {
+	log := slog.With(
+		slog.String("service", "session"),
+		slog.String("func", "Revoke"))
+
+	log.Info("Revoke initiated")
+
+	refreshToken, err := ss.refreshTokenRepository.GetLatestByFamilyID(sessionID)
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return domain.ErrGetSession
+	}
+
+	if refreshToken == nil || !refreshToken.IsActive() {
+		log.Warn("Session not found with this id: " + sessionID)
+		return domain.ErrSessionNotFound
+	}
+
+	if refreshToken.UserID != userID {
+		log.Warn("User " + userID + " tried to revoke a session of another user")
+		return domain.ErrUserNotAuthorized
+	}
+
+	if err := ss.refreshTokenRepository.RevokeFamily(sessionID); err != nil {
+		log.Error("Error trying to revoke refresh tokens of session", slog.Any("error", err))
+		return domain.ErrRevokeToken
+	}
+
+	err = ss.revokedTokenRepository.Create(domain.RevokedToken{
+		JTI:       sessionID,
+		ExpiresAt: time.Now().Add(config.Token.TTL),
+	})
+	if err != nil {
+		log.Error("Error trying to revoke access tokens of session", slog.Any("error", err))
+		return domain.ErrRevokeToken
+	}
+
+	log.Info("Revoke executed successfully")
+	return nil
+}
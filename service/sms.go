@@ -0,0 +1,80 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/OVillas/autentication/config"
+	"github.com/OVillas/autentication/domain"
+	"github.com/samber/do"
+)
+
+const twilioMessagesURL = "https://api.twilio.com/2010-04-01/Accounts/%s/Messages.json"
+
+// NewSMSSender sends through the provider of config.SMS. Another domain.SMSSender, such as one
+// recording the messages in tests, can be provided in its place.
+func NewSMSSender(i *do.Injector) (domain.SMSSender, error) {
+	switch config.SMS.Provider {
+	case config.SMSProviderTwilio:
+		return &twilioSMSSender{
+			accountSID: config.SMS.TwilioAccountSID,
+			authToken:  config.SMS.TwilioAuthToken,
+			from:       config.SMS.From,
+		}, nil
+	case config.SMSProviderLog:
+		return logSMSSender{}, nil
+	default:
+		return noSMSSender{}, nil
+	}
+}
+
+type noSMSSender struct{}
+
+func (noSMSSender) Send(to string, message string) error {
+	return domain.ErrSMSUnavailable
+}
+
+// logSMSSender writes the messages, codes included, to the log instead of sending them. It is
+// only meant for development.
+type logSMSSender struct{}
+
+func (logSMSSender) Send(to string, message string) error {
+	slog.Info("SMS not sent, written to the log", slog.String("to", to), slog.String("message", message))
+	return nil
+}
+
+type twilioSMSSender struct {
+	accountSID string
+	authToken  string
+	from       string
+}
+
+func (tss *twilioSMSSender) Send(to string, message string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), config.SMS.Timeout)
+	defer cancel()
+
+	form := url.Values{"To": {to}, "From": {tss.from}, "Body": {message}}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		fmt.Sprintf(twilioMessagesURL, url.PathEscape(tss.accountSID)), strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.SetBasicAuth(tss.accountSID, tss.authToken)
+
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusCreated && response.StatusCode != http.StatusOK {
+		return fmt.Errorf("twilio returned status %d", response.StatusCode)
+	}
+
+	return nil
+}
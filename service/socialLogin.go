@@ -0,0 +1,380 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/OVillas/autentication/auth"
+	"github.com/OVillas/autentication/config"
+	"github.com/OVillas/autentication/domain"
+	"github.com/OVillas/autentication/secure"
+	"github.com/OVillas/autentication/util"
+	"github.com/google/uuid"
+	"github.com/samber/do"
+)
+
+const identityProviderTimeout = 10 * time.Second
+
+var usernameUnsafeCharacters = regexp.MustCompile(`[^a-z0-9._]`)
+
+type socialLoginService struct {
+	i                      *do.Injector
+	userService            domain.UserService
+	userRepository         domain.UserRepository
+	oauthStateRepository   domain.OAuthStateRepository
+	userIdentityRepository domain.UserIdentityRepository
+	revokedTokenRepository domain.RevokedTokenRepository
+	oauthProviders         auth.OAuthProviders
+}
+
+func NewSocialLoginService(i *do.Injector) (domain.SocialLoginService, error) {
+	userService := do.MustInvoke[domain.UserService](i)
+	userRepository := do.MustInvoke[domain.UserRepository](i)
+	oauthStateRepository := do.MustInvoke[domain.OAuthStateRepository](i)
+	userIdentityRepository := do.MustInvoke[domain.UserIdentityRepository](i)
+	revokedTokenRepository := do.MustInvoke[domain.RevokedTokenRepository](i)
+	oauthProviders := do.MustInvoke[auth.OAuthProviders](i)
+	return &socialLoginService{
+		i:                      i,
+		userService:            userService,
+		userRepository:         userRepository,
+		oauthStateRepository:   oauthStateRepository,
+		userIdentityRepository: userIdentityRepository,
+		revokedTokenRepository: revokedTokenRepository,
+		oauthProviders:         oauthProviders,
+	}, nil
+}
+
+// Begin stores a fresh state, nonce and PKCE verifier and returns the provider URL the user
+// must be redirected to.
+func (sls *socialLoginService) Begin(tenantID string, provider string) (string, error) {
+	log := slog.With(
+		slog.String("service", "socialLogin"),
+		slog.String("func", "Begin"))
+
+	log.Info("Begin initiated")
+
+	authCodeURL, err := sls.begin(provider, domain.OAuthState{TenantID: tenantID})
+	if err != nil {
+		return "", err
+	}
+
+	log.Info("Begin executed successfully")
+	return authCodeURL, nil
+}
+
+// BeginLink starts the same flow for a signed in user, bound to their session: the callback
+// attaches the identity to the user instead of signing in.
+func (sls *socialLoginService) BeginLink(tenantID string, provider string, userID string, sessionID string) (string, error) {
+	log := slog.With(
+		slog.String("service", "socialLogin"),
+		slog.String("func", "BeginLink"))
+
+	log.Info("BeginLink initiated")
+
+	authCodeURL, err := sls.begin(provider, domain.OAuthState{TenantID: tenantID, UserID: userID, SessionID: sessionID})
+	if err != nil {
+		return "", err
+	}
+
+	log.Info("BeginLink executed successfully")
+	return authCodeURL, nil
+}
+
+func (sls *socialLoginService) begin(provider string, oauthState domain.OAuthState) (string, error) {
+	log := slog.With(
+		slog.String("service", "socialLogin"),
+		slog.String("func", "begin"))
+
+	oauthProvider, ok := sls.oauthProviders[provider]
+	if !ok {
+		log.Warn("Identity provider not configured: " + provider)
+		return "", domain.ErrUnknownProvider
+	}
+
+	var secrets [3]string
+	for index := range secrets {
+		secret, err := secure.GenerateOpaqueToken()
+		if err != nil {
+			log.Error("Error trying to generate oauth state", slog.Any("error", err))
+			return "", domain.ErrCreateOAuthState
+		}
+		secrets[index] = secret
+	}
+	state, nonce, codeVerifier := secrets[0], secrets[1], secrets[2]
+
+	oauthState.StateHash = secure.HashToken(state)
+	oauthState.Provider = provider
+	oauthState.Nonce = nonce
+	oauthState.CodeVerifier = codeVerifier
+	oauthState.ExpiresAt = time.Now().Add(domain.OAuthStateTTL)
+
+	if err := sls.oauthStateRepository.Create(oauthState); err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return "", domain.ErrCreateOAuthState
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), identityProviderTimeout)
+	defer cancel()
+
+	authCodeURL, err := oauthProvider.AuthCodeURL(ctx, state, nonce, codeVerifier)
+	if err != nil {
+		log.Error("Error trying to reach identity provider", slog.Any("error", err))
+		return "", domain.ErrSocialLogin
+	}
+
+	return authCodeURL, nil
+}
+
+// Complete consumes the state, verifies the identity returned by the provider and opens a
+// session. A known provider account signs its user in; otherwise the account is matched by
+// verified email, and an email already registered with a password is refused rather than
+// silently taken over.
+func (sls *socialLoginService) Complete(ctx context.Context, provider string, state string, code string, clientInfo domain.ClientInfo) (*domain.SocialLoginResult, error) {
+	log := slog.With(
+		slog.String("service", "socialLogin"),
+		slog.String("func", "Complete"))
+
+	log.Info("Complete initiated")
+
+	oauthProvider, ok := sls.oauthProviders[provider]
+	if !ok {
+		log.Warn("Identity provider not configured: " + provider)
+		return nil, domain.ErrUnknownProvider
+	}
+
+	stateHash := secure.HashToken(state)
+	used, err := sls.oauthStateRepository.Use(stateHash)
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return nil, domain.ErrSocialLogin
+	}
+
+	if !used {
+		log.Warn("Invalid or replayed oauth state")
+		return nil, domain.ErrInvalidState
+	}
+
+	oauthState, err := sls.oauthStateRepository.GetByStateHash(stateHash)
+	if err != nil || oauthState == nil || oauthState.Provider != provider {
+		log.Warn("OAuth state does not belong to this provider")
+		return nil, domain.ErrInvalidState
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), identityProviderTimeout)
+	defer cancel()
+
+	identity, err := oauthProvider.Exchange(ctx, code, oauthState.CodeVerifier, oauthState.Nonce)
+	if err != nil {
+		log.Warn("Error trying to exchange code with identity provider", slog.Any("error", err))
+		return nil, domain.ErrSocialLogin
+	}
+
+	if oauthState.UserID != "" {
+		linkedIdentity, err := sls.link(*oauthState, *identity)
+		if err != nil {
+			log.Warn("Error trying to link identity", slog.Any("error", err))
+			return nil, err
+		}
+
+		log.Info("Complete executed successfully, identity linked")
+		return &domain.SocialLoginResult{LinkedIdentity: linkedIdentity}, nil
+	}
+
+	user, err := sls.findOrCreateUser(ctx, oauthState.TenantID, *identity)
+	if err != nil {
+		log.Warn("Error trying to find the user of the identity", slog.Any("error", err))
+		return nil, err
+	}
+
+	// The provider redirects every tenant to the same callback, the state tells which one began.
+	clientInfo.TenantID = oauthState.TenantID
+
+	loginResponse, err := sls.userService.CreateSession(ctx, user.ID, clientInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Info("Complete executed successfully")
+	return &domain.SocialLoginResult{LoginResponse: loginResponse}, nil
+}
+
+// link attaches the identity to the user who started the flow, provided the session they
+// started it from was not logged out meanwhile.
+func (sls *socialLoginService) link(oauthState domain.OAuthState, identity domain.ExternalIdentity) (*domain.UserIdentityResponse, error) {
+	revoked, err := sls.revokedTokenRepository.Exists(oauthState.SessionID)
+	if err != nil {
+		return nil, domain.ErrGetSession
+	}
+
+	if revoked {
+		return nil, domain.ErrInvalidState
+	}
+
+	userIdentity, err := sls.userIdentityRepository.GetByProvider(oauthState.TenantID, identity.Provider, identity.Subject)
+	if err != nil {
+		return nil, domain.ErrGetUserIdentity
+	}
+
+	if userIdentity != nil && userIdentity.UserID != oauthState.UserID {
+		return nil, domain.ErrIdentityLinked
+	}
+
+	if userIdentity != nil {
+		return userIdentity.ToUserIdentityResponse(), nil
+	}
+
+	if err := sls.createIdentity(oauthState.TenantID, oauthState.UserID, identity); err != nil {
+		return nil, domain.ErrCreateUserIdentity
+	}
+
+	userIdentity, err = sls.userIdentityRepository.GetByProvider(oauthState.TenantID, identity.Provider, identity.Subject)
+	if err != nil || userIdentity == nil {
+		return nil, domain.ErrGetUserIdentity
+	}
+
+	return userIdentity.ToUserIdentityResponse(), nil
+}
+
+func (sls *socialLoginService) findOrCreateUser(ctx context.Context, tenantID string, identity domain.ExternalIdentity) (*domain.User, error) {
+	userIdentity, err := sls.userIdentityRepository.GetByProvider(tenantID, identity.Provider, identity.Subject)
+	if err != nil {
+		return nil, domain.ErrGetUserIdentity
+	}
+
+	if userIdentity != nil {
+		user, err := sls.userRepository.GetById(ctx, userIdentity.UserID)
+		if err != nil {
+			return nil, domain.ErrGetUser
+		}
+
+		if user != nil {
+			return user, nil
+		}
+
+		// The user was deleted, forget the identity and sign up again.
+		if _, err := sls.userIdentityRepository.Delete(userIdentity.ID); err != nil {
+			return nil, domain.ErrCreateUserIdentity
+		}
+	}
+
+	if identity.Email == "" {
+		return nil, domain.ErrNoVerifiedEmail
+	}
+
+	if !identity.EmailVerified {
+		return nil, domain.ErrEmailNotVerified
+	}
+
+	user, err := sls.userRepository.GetByEmail(ctx, tenantID, identity.Email)
+	if err != nil {
+		return nil, domain.ErrGetUser
+	}
+
+	if user != nil && user.HasPassword() {
+		return nil, domain.ErrUserAlreadyRegistered
+	}
+
+	if user == nil && config.RegistrationMode == config.RegistrationInviteOnly {
+		return nil, domain.ErrRegistrationClosed
+	}
+
+	if user == nil {
+		user, err = sls.createUser(ctx, tenantID, identity)
+		if err != nil {
+			return nil, domain.ErrCreateUser
+		}
+	}
+
+	if err := sls.createIdentity(tenantID, user.ID, identity); err != nil {
+		return nil, domain.ErrCreateUserIdentity
+	}
+
+	return user, nil
+}
+
+func (sls *socialLoginService) createIdentity(tenantID string, userID string, identity domain.ExternalIdentity) error {
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return err
+	}
+
+	return sls.userIdentityRepository.Create(domain.UserIdentity{
+		ID:             id.String(),
+		TenantID:       tenantID,
+		UserID:         userID,
+		Provider:       identity.Provider,
+		ProviderUserID: identity.Subject,
+		Email:          identity.Email,
+	})
+}
+
+// createUser registers the user of an external identity with a confirmed email, an unusable
+// password and a username derived from the email.
+func (sls *socialLoginService) createUser(ctx context.Context, tenantID string, identity domain.ExternalIdentity) (*domain.User, error) {
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return nil, err
+	}
+
+	password, err := secure.GenerateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+
+	username, err := sls.newUsername(ctx, tenantID, identity.Email)
+	if err != nil {
+		return nil, err
+	}
+
+	name := strings.TrimSpace(identity.Name)
+	if name == "" {
+		name = username
+	}
+	if len(name) > 75 {
+		name = name[:75]
+	}
+
+	user := domain.User{
+		ID:             id.String(),
+		TenantID:       tenantID,
+		Name:           name,
+		Username:       username,
+		Email:          identity.Email,
+		Password:       domain.UnusablePasswordPrefix + password,
+		EmailConfirmed: true,
+		Active:         true,
+	}
+
+	if err := sls.userRepository.Create(ctx, user); err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+func (sls *socialLoginService) newUsername(ctx context.Context, tenantID string, email string) (string, error) {
+	base := strings.ToLower(strings.SplitN(email, "@", 2)[0])
+	base = usernameUnsafeCharacters.ReplaceAllString(base, "")
+	if len(base) > 60 {
+		base = base[:60]
+	}
+
+	for attempt := 0; attempt < 5; attempt++ {
+		username := base + "_" + util.GenerateOTP(6)
+
+		existing, err := sls.userRepository.GetByUsername(ctx, tenantID, username)
+		if err != nil {
+			return "", err
+		}
+
+		if existing == nil {
+			return username, nil
+		}
+	}
+
+	return "", domain.ErrCreateUser
+}
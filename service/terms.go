@@ -0,0 +1,54 @@
+package service
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/OVillas/autentication/domain"
+	"github.com/google/uuid"
+	"github.com/samber/do"
+)
+
+type termsService struct {
+	i                          *do.Injector
+	policyAcceptanceRepository domain.PolicyAcceptanceRepository
+}
+
+func NewTermsService(i *do.Injector) (domain.TermsService, error) {
+	policyAcceptanceRepository := do.MustInvoke[domain.PolicyAcceptanceRepository](i)
+	return &termsService{
+		i:                          i,
+		policyAcceptanceRepository: policyAcceptanceRepository,
+	}, nil
+}
+
+// Accept records that the user accepted version, which must be the current one, from the client
+// in clientInfo. Accepting the same version again adds another record.
+func (ts *termsService) Accept(userID string, version string, clientInfo domain.ClientInfo) error {
+	log := slog.With(
+		slog.String("service", "terms"),
+		slog.String("func", "Accept"))
+
+	log.Info("Accept initiated")
+
+	if err := domain.CheckTermsVersion(version); err != nil {
+		log.Warn("Outdated terms version accepted by user: "+userID, slog.String("version", version))
+		return err
+	}
+
+	err := ts.policyAcceptanceRepository.Create(domain.PolicyAcceptance{
+		ID:         uuid.NewString(),
+		UserID:     userID,
+		Version:    version,
+		IP:         clientInfo.IP,
+		UserAgent:  clientInfo.UserAgent,
+		AcceptedAt: time.Now(),
+	})
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return domain.ErrSavePolicyAcceptance
+	}
+
+	log.Info("Accept executed successfully")
+	return nil
+}
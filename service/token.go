@@ -0,0 +1,135 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/OVillas/autentication/auth"
+	"github.com/OVillas/autentication/domain"
+	"github.com/OVillas/autentication/secure"
+	"github.com/samber/do"
+)
+
+type tokenService struct {
+	i                      *do.Injector
+	userRepository         domain.UserRepository
+	refreshTokenRepository domain.RefreshTokenRepository
+	revokedTokenRepository domain.RevokedTokenRepository
+	oauthClientRepository  domain.OAuthClientRepository
+	tokenProvider          auth.TokenProvider
+}
+
+func NewTokenService(i *do.Injector) (domain.TokenService, error) {
+	userRepository := do.MustInvoke[domain.UserRepository](i)
+	refreshTokenRepository := do.MustInvoke[domain.RefreshTokenRepository](i)
+	revokedTokenRepository := do.MustInvoke[domain.RevokedTokenRepository](i)
+	oauthClientRepository := do.MustInvoke[domain.OAuthClientRepository](i)
+	tokenProvider := do.MustInvoke[auth.TokenProvider](i)
+	return &tokenService{
+		i:                      i,
+		userRepository:         userRepository,
+		refreshTokenRepository: refreshTokenRepository,
+		revokedTokenRepository: revokedTokenRepository,
+		oauthClientRepository:  oauthClientRepository,
+		tokenProvider:          tokenProvider,
+	}, nil
+}
+
+func (ts *tokenService) Introspect(ctx context.Context, token string) (*domain.IntrospectionResponse, error) {
+	log := slog.With(
+		slog.String("service", "token"),
+		slog.String("func", "Introspect"))
+
+	log.Info("Introspect initiated")
+
+	if claims, err := ts.tokenProvider.ParseToken(token); err == nil {
+		return ts.introspectAccessToken(ctx, *claims)
+	}
+
+	refreshToken, err := ts.refreshTokenRepository.GetByTokenHash(secure.HashToken(token))
+	if err != nil {
+		log.Error("Failed to obtain refresh token", slog.Any("error", err))
+		return nil, domain.ErrGetRefreshToken
+	}
+
+	if refreshToken == nil || !refreshToken.IsActive() {
+		log.Info("Introspect executed successfully, token inactive")
+		return &domain.IntrospectionResponse{Active: false}, nil
+	}
+
+	user, err := ts.userRepository.GetById(ctx, refreshToken.UserID)
+	if err != nil {
+		log.Error("Failed to obtain user by id", slog.Any("error", err))
+		return nil, domain.ErrGetUser
+	}
+
+	if user == nil {
+		return &domain.IntrospectionResponse{Active: false}, nil
+	}
+
+	log.Info("Introspect executed successfully")
+	return &domain.IntrospectionResponse{
+		Active:    true,
+		Sub:       user.ID,
+		Exp:       refreshToken.ExpiresAt.Unix(),
+		Iat:       refreshToken.CreatedAt.Unix(),
+		Username:  user.Username,
+		TokenType: "refresh_token",
+	}, nil
+}
+
+// Private session
+func (ts *tokenService) introspectAccessToken(ctx context.Context, claims domain.TokenClaims) (*domain.IntrospectionResponse, error) {
+	revoked, err := ts.revokedTokenRepository.Exists(claims.ID, claims.SessionID)
+	if err != nil {
+		return nil, domain.ErrRevokeToken
+	}
+
+	if revoked {
+		return &domain.IntrospectionResponse{Active: false}, nil
+	}
+
+	if claims.IsClient() {
+		return ts.introspectClientToken(claims)
+	}
+
+	user, err := ts.userRepository.GetById(ctx, claims.UserID)
+	if err != nil {
+		return nil, domain.ErrGetUser
+	}
+
+	if user == nil || user.TokenVersion != claims.Version {
+		return &domain.IntrospectionResponse{Active: false}, nil
+	}
+
+	return &domain.IntrospectionResponse{
+		Active:    true,
+		Sub:       claims.UserID,
+		Exp:       claims.ExpiresAt.Unix(),
+		Iat:       claims.IssuedAt.Unix(),
+		Scope:     claims.Scope,
+		Username:  user.Username,
+		TokenType: "access_token",
+	}, nil
+}
+
+func (ts *tokenService) introspectClientToken(claims domain.TokenClaims) (*domain.IntrospectionResponse, error) {
+	oauthClient, err := ts.oauthClientRepository.GetById(claims.ClientID)
+	if err != nil {
+		return nil, domain.ErrGetOAuthClient
+	}
+
+	if oauthClient == nil {
+		return &domain.IntrospectionResponse{Active: false}, nil
+	}
+
+	return &domain.IntrospectionResponse{
+		Active:    true,
+		Sub:       claims.ClientID,
+		ClientID:  claims.ClientID,
+		Exp:       claims.ExpiresAt.Unix(),
+		Iat:       claims.IssuedAt.Unix(),
+		Scope:     claims.Scope,
+		TokenType: "access_token",
+	}, nil
+}
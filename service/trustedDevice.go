@@ -0,0 +1,65 @@
+package service
+
+import (
+	"log/slog"
+
+	"github.com/OVillas/autentication/domain"
+	"github.com/samber/do"
+)
+
+type trustedDeviceService struct {
+	i                       *do.Injector
+	trustedDeviceRepository domain.TrustedDeviceRepository
+}
+
+func NewTrustedDeviceService(i *do.Injector) (domain.TrustedDeviceService, error) {
+	trustedDeviceRepository := do.MustInvoke[domain.TrustedDeviceRepository](i)
+	return &trustedDeviceService{
+		i:                       i,
+		trustedDeviceRepository: trustedDeviceRepository,
+	}, nil
+}
+
+func (tds *trustedDeviceService) GetAll(userID string) ([]domain.TrustedDeviceResponse, error) {
+	log := slog.With(
+		slog.String("service", "trustedDevice"),
+		slog.String("func", "GetAll"))
+
+	log.Info("GetAll initiated")
+
+	trustedDevices, err := tds.trustedDeviceRepository.GetActiveByUserID(userID)
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return nil, domain.ErrGetTrustedDevice
+	}
+
+	var response []domain.TrustedDeviceResponse
+	for _, trustedDevice := range trustedDevices {
+		response = append(response, *trustedDevice.ToTrustedDeviceResponse())
+	}
+
+	log.Info("GetAll executed successfully")
+	return response, nil
+}
+
+func (tds *trustedDeviceService) Revoke(userID string, id string) error {
+	log := slog.With(
+		slog.String("service", "trustedDevice"),
+		slog.String("func", "Revoke"))
+
+	log.Info("Revoke initiated")
+
+	deleted, err := tds.trustedDeviceRepository.Delete(userID, id)
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return domain.ErrRevokeTrustedDevice
+	}
+
+	if !deleted {
+		log.Warn("Trusted device not found with this id: " + id)
+		return domain.ErrTrustedDeviceNotFound
+	}
+
+	log.Info("Revoke executed successfully")
+	return nil
+}
@@ -1,49 +1,178 @@
 package service
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
 	"log/slog"
+	"net/url"
+	"time"
 
+	"github.com/OVillas/autentication/auth"
+	"github.com/OVillas/autentication/config"
 	"github.com/OVillas/autentication/domain"
 	"github.com/OVillas/autentication/secure"
 	"github.com/OVillas/autentication/util"
+	"github.com/google/uuid"
 	"github.com/samber/do"
 )
 
+const (
+	// accountPurgeInterval is how often scheduled deletions and the accounts past their
+	// retention are processed.
+	accountPurgeInterval = time.Hour
+	// deletionReminderLead is how long before a scheduled deletion its owner is reminded.
+	deletionReminderLead = 24 * time.Hour
+)
+
 type userService struct {
-	i                     *do.Injector
-	userRepository        domain.UserRepository
-	emailService          domain.EmailService
-	confimatioCodeService domain.ConfirmationCodeService
+	i                       *do.Injector
+	userRepository          domain.UserRepository
+	unitOfWork              domain.UnitOfWork
+	emailService            domain.EmailService
+	confimatioCodeService   domain.ConfirmationCodeService
+	refreshTokenRepository  domain.RefreshTokenRepository
+	revokedTokenRepository  domain.RevokedTokenRepository
+	recoveryCodeRepository  domain.RecoveryCodeRepository
+	trustedDeviceRepository domain.TrustedDeviceRepository
+	pendingEmailRepository  domain.PendingEmailRepository
+	loginFailureRepository  domain.LoginFailureRepository
+	roleRepository          domain.RoleRepository
+	permissionRepository    domain.PermissionRepository
+	organizationRepository  domain.OrganizationRepository
+	auditService            domain.AuditService
+	loginHistoryService     domain.LoginHistoryService
+	loginAlertService       domain.LoginAlertService
+	termsService            domain.TermsService
+	avatarStorage           domain.AvatarStorage
+	tokenProvider           auth.TokenProvider
 }
 
 func NewUserService(i *do.Injector) (domain.UserService, error) {
 	userRepository := do.MustInvoke[domain.UserRepository](i)
+	unitOfWork := do.MustInvoke[domain.UnitOfWork](i)
 	emailService := do.MustInvoke[domain.EmailService](i)
 	confimatioCodeService := do.MustInvoke[domain.ConfirmationCodeService](i)
-	return &userService{
-		i:                     i,
-		userRepository:        userRepository,
-		emailService:          emailService,
-		confimatioCodeService: confimatioCodeService,
-	}, nil
+	refreshTokenRepository := do.MustInvoke[domain.RefreshTokenRepository](i)
+	revokedTokenRepository := do.MustInvoke[domain.RevokedTokenRepository](i)
+	recoveryCodeRepository := do.MustInvoke[domain.RecoveryCodeRepository](i)
+	trustedDeviceRepository := do.MustInvoke[domain.TrustedDeviceRepository](i)
+	pendingEmailRepository := do.MustInvoke[domain.PendingEmailRepository](i)
+	loginFailureRepository := do.MustInvoke[domain.LoginFailureRepository](i)
+	roleRepository := do.MustInvoke[domain.RoleRepository](i)
+	permissionRepository := do.MustInvoke[domain.PermissionRepository](i)
+	organizationRepository := do.MustInvoke[domain.OrganizationRepository](i)
+	auditService := do.MustInvoke[domain.AuditService](i)
+	loginHistoryService := do.MustInvoke[domain.LoginHistoryService](i)
+	loginAlertService := do.MustInvoke[domain.LoginAlertService](i)
+	termsService := do.MustInvoke[domain.TermsService](i)
+	avatarStorage := do.MustInvoke[domain.AvatarStorage](i)
+	tokenProvider := do.MustInvoke[auth.TokenProvider](i)
+	us := &userService{
+		i:                       i,
+		userRepository:          userRepository,
+		unitOfWork:              unitOfWork,
+		emailService:            emailService,
+		confimatioCodeService:   confimatioCodeService,
+		refreshTokenRepository:  refreshTokenRepository,
+		revokedTokenRepository:  revokedTokenRepository,
+		recoveryCodeRepository:  recoveryCodeRepository,
+		trustedDeviceRepository: trustedDeviceRepository,
+		pendingEmailRepository:  pendingEmailRepository,
+		loginFailureRepository:  loginFailureRepository,
+		roleRepository:          roleRepository,
+		permissionRepository:    permissionRepository,
+		organizationRepository:  organizationRepository,
+		auditService:            auditService,
+		loginHistoryService:     loginHistoryService,
+		loginAlertService:       loginAlertService,
+		termsService:            termsService,
+		avatarStorage:           avatarStorage,
+		tokenProvider:           tokenProvider,
+	}
+
+	go us.purgeAccounts(context.Background())
+	if config.UnverifiedCleanup.Interval > 0 {
+		go us.cleanupUnverified(context.Background())
+	}
+
+	return us, nil
 }
 
-func (us *userService) Create(userPayLoad domain.UserPayLoad) error {
+// Create registers the user and sends the email confirmation code. With
+// config.UniformRegistration a taken email is not reported: its owner gets an email instead and
+// the caller sees the same success, so registration cannot be used to find accounts. The email of
+// a deleted account stays taken until it is purged, so that an admin can still restore it.
+// Registration is refused when config.RegistrationMode only lets invited users in, and records the
+// acceptance of the terms version in the payload.
+func (us *userService) Create(ctx context.Context, userPayLoad domain.UserPayLoad, clientInfo domain.ClientInfo) error {
 	log := slog.With(
 		slog.String("service", "user"),
 		slog.String("func", "Create"))
 
 	log.Info("Create initiated")
 
-	userResponse, err := us.userRepository.GetByEmail(userPayLoad.Email)
+	if config.RegistrationMode == config.RegistrationInviteOnly {
+		log.Warn("Open registration attempted in invite only mode")
+		return domain.ErrRegistrationClosed
+	}
+
+	if err := domain.CheckTermsVersion(userPayLoad.TermsVersion); err != nil {
+		log.Warn("Registration with outdated terms version: " + userPayLoad.TermsVersion)
+		return err
+	}
+
+	tenantID := clientInfo.TenantID
+	if err := checkPasswordBreached(userPayLoad.Password); err != nil {
+		log.Warn("Password refused by the breach check", slog.Any("error", err))
+		return err
+	}
+
+	userResponse, err := us.userRepository.GetByEmail(ctx, tenantID, userPayLoad.Email)
 	if err != nil {
 		log.Error("Error trying to get user from repository")
 		return domain.ErrGetUser
 	}
 
+	if userResponse != nil && config.UniformRegistration {
+		log.Warn("Registration with an already registered email: " + userPayLoad.Email)
+		secure.CheckDummyPassword(userPayLoad.Password)
+		go us.sendAlreadyRegisteredEmail(*userResponse)
+		return nil
+	}
+
 	if userResponse != nil {
 		log.Warn("There is already a registered user with this email: " + userPayLoad.Email)
-		return domain.ErrUserAlreadyRegistered
+		return domain.ErrEmailTaken
+	}
+
+	deletedUser, err := us.userRepository.GetDeletedByEmail(ctx, tenantID, userPayLoad.Email)
+	if err != nil {
+		log.Error("Error trying to get deleted user from repository", slog.Any("error", err))
+		return domain.ErrGetUser
+	}
+
+	if deletedUser != nil && config.UniformRegistration {
+		log.Warn("Registration with the email of a deleted account: " + userPayLoad.Email)
+		secure.CheckDummyPassword(userPayLoad.Password)
+		return nil
+	}
+
+	if deletedUser != nil {
+		log.Warn("Registration with the email of a deleted account: " + userPayLoad.Email)
+		return domain.ErrAccountDeleted
+	}
+
+	usernameTaken, err := us.userRepository.IsUsernameTaken(ctx, tenantID, userPayLoad.Username)
+	if err != nil {
+		log.Error("Error trying to check the username", slog.Any("error", err))
+		return domain.ErrGetUser
+	}
+
+	if usernameTaken {
+		log.Warn("Registration with a taken username: " + userPayLoad.Username)
+		return domain.ErrUsernameTaken
 	}
 
 	hashedPassword, err := secure.Hash(userPayLoad.Password)
@@ -57,102 +186,142 @@ func (us *userService) Create(userPayLoad domain.UserPayLoad) error {
 		log.Error("Error trying to convert userPayload to User")
 		return domain.ErrConvertUserPayLoadToUser
 	}
+	user.TenantID = tenantID
 	user.EmailConfirmed = false
 
-	if err := us.userRepository.Create(*user); err != nil {
-		log.Error("Error: ", err)
+	// The user and its confirmation code are committed together, and the code is only emailed
+	// once they are. A code refused by the rate limit of the sends does not undo the
+	// registration: the user asks for it again later.
+	var code string
+	err = us.unitOfWork.Do(ctx, func(tx domain.TxRepositories) error {
+		// The checks above can race with another registration, which the unique indexes then refuse.
+		if err := tx.Users.Create(ctx, *user); err != nil {
+			return err
+		}
+
+		var err error
+		code, err = us.confimatioCodeService.IssueConfirmationCode(tx.ConfirmationCodes, user.TenantID, user.Email)
+		if errors.Is(err, domain.ErrTooManyCodeRequests) {
+			log.Warn("Confirmation code not issued for user: "+user.ID, slog.Any("error", err))
+			return nil
+		}
+		return err
+	})
+	if errors.Is(err, domain.ErrEmailTaken) && config.UniformRegistration {
+		log.Warn("Concurrent registration with the same email: " + user.Email)
+		return nil
+	}
+
+	if errors.Is(err, domain.ErrEmailTaken) || errors.Is(err, domain.ErrUsernameTaken) {
+		log.Warn("Concurrent registration with the same email or username", slog.Any("error", err))
+		return err
+	}
+
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
 		return domain.ErrCreateUser
 	}
 
+	if userPayLoad.TermsVersion != "" {
+		if err := us.termsService.Accept(user.ID, userPayLoad.TermsVersion, clientInfo); err != nil {
+			log.Error("Terms acceptance not recorded for user: "+user.ID, slog.Any("error", err))
+		}
+	}
+
+	if code == "" {
+		log.Info("Create executed successfully, confirmation code not sent")
+		return nil
+	}
+
+	// The code can be requested again, so in uniform mode a failed send must not show.
+	if config.UniformRegistration {
+		go func() {
+			if err := us.confimatioCodeService.EmailConfirmationCode(context.WithoutCancel(ctx), user.TenantID, user.Email, code); err != nil {
+				log.Error("Error trying to send confirmation code", slog.Any("error", err))
+			}
+		}()
+	} else if err := us.confimatioCodeService.EmailConfirmationCode(ctx, user.TenantID, user.Email, code); err != nil {
+		log.Error("Error trying to send confirmation code", slog.Any("error", err))
+		return domain.ErrToSendConfirmationCode
+	}
+
 	log.Info("Create executed successfully")
 	return nil
 }
 
-func (us *userService) GetAll() ([]domain.UserResponse, error) {
+func (us *userService) GetAll(ctx context.Context, tenantID string, query domain.UserListQuery) (*domain.UserPage, error) {
 	log := slog.With(
 		slog.String("service", "user"),
 		slog.String("func", "GetAll"))
 
 	log.Info("GetAll initiated")
 
-	users, err := us.userRepository.GetAll()
+	users, total, err := us.userRepository.GetAll(ctx, tenantID, query)
 	if err != nil {
-		log.Error("Error: ", err)
+		log.Error("Error: ", slog.Any("error", err))
 		return nil, domain.ErrGetUser
 	}
 
 	log.Info("get all executed successfully")
-	if users == nil {
-		return nil, nil
-	}
-
-	var usersResponse []domain.UserResponse
-	for _, user := range users {
-		usersResponse = append(usersResponse, *user.ToUserResponse())
-	}
-
-	return usersResponse, nil
+	return newUserPage(users, total, query, (*domain.User).ToUserResponse), nil
 }
 
-func (us *userService) GetById(id string) (*domain.UserResponse, error) {
+// GetById returns the user as a *domain.UserResponse when viewer may see its account data, and as
+// a *domain.PublicUserResponse otherwise.
+func (us *userService) GetById(ctx context.Context, id string, viewer domain.Viewer) (any, error) {
 	log := slog.With(
 		slog.String("service", "user"),
 		slog.String("func", "GetById"))
 
 	log.Info("GetById initiated")
 
-	user, err := us.userRepository.GetById(id)
+	user, err := us.userRepository.GetById(domain.AllowCachedUser(ctx), id)
 	if err != nil {
-		log.Error("Error: ", err)
+		log.Error("Error: ", slog.Any("error", err))
 		return nil, domain.ErrGetUser
 	}
 
 	log.Info("GetById executed successfully")
-	if user == nil {
+	if user == nil || !viewer.SameTenant(*user) {
 		return nil, nil
 	}
 
-	userResponse := user.ToUserResponse()
+	if !viewer.CanSeeAccountOf(user.ID) {
+		return user.ToPublicUserResponse(), nil
+	}
 
-	return userResponse, err
+	return user.ToUserResponse(), nil
 }
 
-func (us *userService) GetByNameOrUsername(name string) ([]domain.UserResponse, error) {
+// GetByNameOrUsername searches the public profiles, so it never tells the account data of the
+// users found.
+func (us *userService) GetByNameOrUsername(ctx context.Context, tenantID string, name string, pageRequest domain.PageRequest) (*domain.PublicUserPage, error) {
 	log := slog.With(
 		slog.String("service", "user"),
 		slog.String("func", "GetByNameOrUsername"))
 
 	log.Info("GetByNameOrUsername initiated")
 
-	users, err := us.userRepository.GetByNameOrUsername(name)
+	users, total, err := us.userRepository.GetByNameOrUsername(ctx, tenantID, name, pageRequest)
 	if err != nil {
-		log.Error("Error: ", err)
+		log.Error("Error: ", slog.Any("error", err))
 		return nil, domain.ErrGetUser
 	}
 
 	log.Info("GetByNameOrUsername executed successfully")
-	if users == nil {
-		return nil, nil
-	}
-
-	var usersResponse []domain.UserResponse
-	for _, user := range users {
-		usersResponse = append(usersResponse, *user.ToUserResponse())
-	}
-
-	return usersResponse, err
+	return newUserPage(users, total, domain.UserListQuery{PageRequest: pageRequest}, (*domain.User).ToPublicUserResponse), nil
 }
 
-func (us *userService) GetByUsername(username string) (*domain.UserResponse, error) {
+func (us *userService) GetByUsername(ctx context.Context, tenantID string, username string) (*domain.UserResponse, error) {
 	log := slog.With(
 		slog.String("service", "user"),
 		slog.String("func", "GetByUsername"))
 
 	log.Info("GetByUsername initiated")
 
-	user, err := us.userRepository.GetByUsername(username)
+	user, err := us.userRepository.GetByUsername(domain.AllowCachedUser(ctx), tenantID, username)
 	if err != nil {
-		log.Error("Error: ", err)
+		log.Error("Error: ", slog.Any("error", err))
 		return nil, domain.ErrGetUser
 	}
 
@@ -166,16 +335,54 @@ func (us *userService) GetByUsername(username string) (*domain.UserResponse, err
 	return userResponse, nil
 }
 
-func (us *userService) GetByEmail(email string) (*domain.UserResponse, error) {
+// CheckAvailability only tells whether each field is free, never anything about the account
+// holding it. Usernames the policy refuses are never free, and emails are not checked at all under
+// config.UniformRegistration, which keeps registered emails from being enumerated.
+func (us *userService) CheckAvailability(ctx context.Context, tenantID string, query domain.AvailabilityQuery) (*domain.AvailabilityResponse, error) {
+	log := slog.With(
+		slog.String("service", "user"),
+		slog.String("func", "CheckAvailability"))
+
+	log.Info("CheckAvailability initiated")
+
+	var response domain.AvailabilityResponse
+	if query.Username != "" {
+		available := domain.CurrentUsernamePolicy().Check(query.Username) == nil
+		if available {
+			taken, err := us.userRepository.IsUsernameTaken(ctx, tenantID, query.Username)
+			if err != nil {
+				log.Error("Error: ", slog.Any("error", err))
+				return nil, domain.ErrGetUser
+			}
+			available = !taken
+		}
+		response.Username = &available
+	}
+
+	if query.Email != "" && !config.UniformRegistration {
+		taken, err := us.userRepository.IsEmailTaken(ctx, tenantID, query.Email)
+		if err != nil {
+			log.Error("Error: ", slog.Any("error", err))
+			return nil, domain.ErrGetUser
+		}
+		available := !taken
+		response.Email = &available
+	}
+
+	log.Info("CheckAvailability executed successfully")
+	return &response, nil
+}
+
+func (us *userService) GetByEmail(ctx context.Context, tenantID string, email string) (*domain.UserResponse, error) {
 	log := slog.With(
 		slog.String("service", "user"),
 		slog.String("func", "GetByEmail"))
 
 	log.Info("GetByEmail initiated")
 
-	user, err := us.userRepository.GetByEmail(email)
+	user, err := us.userRepository.GetByEmail(ctx, tenantID, email)
 	if err != nil {
-		log.Error("Error: ", err)
+		log.Error("Error: ", slog.Any("error", err))
 		return nil, domain.ErrGetUser
 	}
 
@@ -189,16 +396,20 @@ func (us *userService) GetByEmail(email string) (*domain.UserResponse, error) {
 	return userResponse, nil
 }
 
-func (us *userService) Update(id string, userUpdate domain.UserUpdatePayLoad) error {
+// Update changes the name and the username right away, leaving out the fields not in
+// userUpdate. A new email needs the current password and only becomes pending: it is switched
+// once the code sent to it is confirmed, and the old address is warned with a link that undoes
+// the change.
+func (us *userService) Update(ctx context.Context, id string, userUpdate domain.UserUpdatePayLoad) error {
 	log := slog.With(
 		slog.String("service", "user"),
 		slog.String("func", "update"))
 
 	log.Info("Update initiated")
 
-	user, err := us.userRepository.GetById(id)
+	user, err := us.userRepository.GetById(ctx, id)
 	if err != nil {
-		log.Error("Error: ", err)
+		log.Error("Error: ", slog.Any("error", err))
 		return domain.ErrGetUser
 	}
 
@@ -207,22 +418,56 @@ func (us *userService) Update(id string, userUpdate domain.UserUpdatePayLoad) er
 		return domain.ErrUserNotFound
 	}
 
-	if user.Email == userUpdate.Email {
-		log.Warn("Email same as above")
-		return domain.ErrSameEmail
+	var newEmail string
+	if userUpdate.Email != nil {
+		newEmail = domain.NormalizeEmail(*userUpdate.Email)
+		if user.Email == newEmail {
+			log.Warn("Email same as above")
+			return domain.ErrSameEmail
+		}
+
+		if err := secure.CheckPassword(user.Password, userUpdate.Password); err != nil {
+			log.Warn("invalid password to change the email of user: " + id)
+			return domain.ErrPasswordNotMatch
+		}
 	}
 
-	if userUpdate.Email != "" {
-		user.Email = userUpdate.Email
+	changes := userUpdate.Changes()
+	if username, ok := changes["Username"]; ok && username != user.Username {
+		taken, err := us.userRepository.IsUsernameTaken(ctx, user.TenantID, username.(string))
+		if err != nil {
+			log.Error("Error: ", slog.Any("error", err))
+			return domain.ErrGetUser
+		}
+
+		if taken {
+			log.Warn("Username already taken: " + username.(string))
+			return domain.ErrUsernameTaken
+		}
 	}
 
-	if userUpdate.Name != "" {
-		user.Name = userUpdate.Name
+	if len(changes) > 0 {
+		err := us.userRepository.Update(ctx, id, changes)
+		if errors.Is(err, domain.ErrUsernameTaken) {
+			log.Warn("Username taken by a concurrent update", slog.Any("error", err))
+			return err
+		}
+
+		if errors.Is(err, domain.ErrUserNotFound) {
+			log.Warn("User deleted by a concurrent request: " + id)
+			return err
+		}
+
+		if err != nil {
+			log.Error("Error: ", slog.Any("error", err))
+			return domain.ErrCreateUser
+		}
 	}
 
-	if err := us.userRepository.Update(id, *user); err != nil {
-		log.Error("Error: ", err)
-		return domain.ErrCreateUser
+	if newEmail != "" {
+		if err := us.requestEmailChange(ctx, *user, newEmail); err != nil {
+			return err
+		}
 	}
 
 	log.Info("Update executed successfully")
@@ -230,120 +475,2496 @@ func (us *userService) Update(id string, userUpdate domain.UserUpdatePayLoad) er
 	return nil
 }
 
-func (us *userService) Delete(id string) error {
+// Delete schedules the account for permanent removal after config.AccountDeletionGrace, once the
+// current password confirms a stolen session is not behind the request. Its sessions end, and
+// logging in again before the deletion cancels it.
+func (us *userService) Delete(ctx context.Context, id string, password string, clientInfo domain.ClientInfo) (*domain.DeletionScheduledResponse, error) {
 	log := slog.With(
 		slog.String("service", "user"),
 		slog.String("func", "delete"))
 
 	log.Info("Delete initiated")
 
-	user, err := us.userRepository.GetById(id)
+	user, err := us.userRepository.GetById(ctx, id)
 	if err != nil {
 		log.Error("Error trying to get user from repository")
-		return domain.ErrGetUser
+		return nil, domain.ErrGetUser
 	}
 
 	if user == nil {
 		log.Warn("User not found to delete")
-		return domain.ErrUserNotFound
+		return nil, domain.ErrUserNotFound
 	}
 
-	if err := us.userRepository.Delete(id); err != nil {
-		log.Error("Error: ", err)
-		return domain.ErrDeleteUser
+	if err := secure.CheckPassword(user.Password, password); err != nil {
+		log.Warn("invalid password to delete user: " + id)
+		return nil, domain.ErrPasswordNotMatch
+	}
+
+	deletionScheduledAt := time.Now().Add(config.AccountDeletionGrace)
+	if err := us.userRepository.ScheduleDeletion(ctx, id, deletionScheduledAt); err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return nil, domain.ErrScheduleDeletion
+	}
+
+	if err := us.refreshTokenRepository.RevokeAllByUserID(id); err != nil {
+		log.Error("Failed to revoke refresh tokens", slog.Any("error", err))
+		return nil, domain.ErrRevokeToken
+	}
+
+	if err := us.userRepository.IncrementTokenVersion(ctx, id); err != nil {
+		log.Error("Failed to increment token version", slog.Any("error", err))
+		return nil, domain.ErrRevokeToken
 	}
 
+	us.emailService.Notify(domain.Notification{
+		Type:     domain.NotificationDeletionScheduled,
+		Name:     user.Name,
+		Locale:   user.Locale,
+		Timezone: user.Timezone,
+		DueAt:    deletionScheduledAt,
+	}, []string{user.Email})
+
+	us.auditService.Record(domain.AuditEventDeletionScheduled, id, domain.UserActor(id, clientInfo))
+
 	log.Info("Delete executed successfully")
+	return &domain.DeletionScheduledResponse{DeletionScheduledAt: deletionScheduledAt}, nil
+}
+
+// Anonymize erases the personal data of the account right away, once the current password
+// confirms a stolen session is not behind the request. Unlike Delete it cannot be undone.
+func (us *userService) Anonymize(ctx context.Context, id string, password string, clientInfo domain.ClientInfo) error {
+	log := slog.With(
+		slog.String("service", "user"),
+		slog.String("func", "Anonymize"))
+
+	log.Info("Anonymize initiated")
+
+	user, err := us.userRepository.GetById(ctx, id)
+	if err != nil {
+		log.Error("Error trying to get user from repository")
+		return domain.ErrGetUser
+	}
+
+	if user == nil {
+		log.Warn("User not found to anonymize")
+		return domain.ErrUserNotFound
+	}
+
+	if err := secure.CheckPassword(user.Password, password); err != nil {
+		log.Warn("invalid password to anonymize user: " + id)
+		return domain.ErrPasswordNotMatch
+	}
+
+	if err := us.anonymize(ctx, *user, domain.UserActor(id, clientInfo)); err != nil {
+		return err
+	}
+
+	log.Info("Anonymize executed successfully")
 	return nil
 }
 
-func (us *userService) Login(login domain.Login) (string, error) {
+// Login opens a session, unless the account has two-factor authentication on: then only a
+// challenge is returned and LoginTwoFactor opens the session once the second factor is checked.
+func (us *userService) Login(ctx context.Context, login domain.Login, clientInfo domain.ClientInfo) (*domain.LoginResult, error) {
 	log := slog.With(
 		slog.String("service", "user"),
 		slog.String("func", "Login"))
 
 	log.Info("Login initiated")
-	var user *domain.User
-	var err error
 
-	var getBy func(string) (*domain.User, error)
+	user, err := us.checkCredentials(ctx, login.Identifier, login.Password, clientInfo)
+	if err != nil {
+		return nil, err
+	}
 
-	if util.IsEmailValid(login.Username) {
-		getBy = us.userRepository.GetByEmail
-	} else {
-		getBy = us.userRepository.GetByUsername
+	loginResult, err := us.continueLogin(ctx, *user, login.RememberMe, login.DeviceToken, clientInfo)
+	if err != nil {
+		return nil, err
 	}
 
-	user, err = getBy(login.Username)
+	log.Info("Login executed successfully")
+	return loginResult, nil
+}
+
+// ContinueLogin carries on a login whose first factor was proven without a password, such as
+// a magic link, asking for the second factor when the account has it on.
+func (us *userService) ContinueLogin(ctx context.Context, userID string, deviceToken string, clientInfo domain.ClientInfo) (*domain.LoginResult, error) {
+	log := slog.With(
+		slog.String("service", "user"),
+		slog.String("func", "ContinueLogin"))
+
+	log.Info("ContinueLogin initiated")
+
+	user, err := us.userRepository.GetById(ctx, userID)
 	if err != nil {
-		log.Warn("Failed to obtain user")
-		return "", domain.ErrGetUser
+		log.Error("Failed to obtain user by id", slog.Any("error", err))
+		return nil, domain.ErrGetUser
 	}
 
 	if user == nil {
-		log.Warn("User not found with this username: " + login.Username)
-		return "", domain.ErrUserNotFound
+		log.Warn("User not found with this id: " + userID)
+		return nil, domain.ErrUserNotFound
 	}
 
-	if err := secure.CheckPassword(user.Password, login.Password); err != nil {
-		log.Warn("invalid password for email: " + user.Email)
-		return "", domain.ErrPasswordNotMatch
+	if err := checkSuspension(*user); err != nil {
+		log.Warn("Login refused, account suspended: " + userID)
+		return nil, err
 	}
 
-	token, err := util.CreateToken(*user)
+	loginResult, err := us.continueLogin(ctx, *user, false, deviceToken, clientInfo)
 	if err != nil {
-		log.Error("error trying create token jwt. Error: ", err)
-		return "", domain.ErrGenToken
+		return nil, err
 	}
 
-	log.Info("Login executed successfully")
-	return token, nil
+	log.Info("ContinueLogin executed successfully")
+	return loginResult, nil
 }
 
-func (us *userService) ConfirmEmail(confirmCode domain.ConfirmCode) error {
+// LoginTwoFactor completes a login started with a challenge token. The token is revoked before
+// the session opens, so it cannot be replayed even by a concurrent request, and after
+// domain.TwoFactorChallengeMaxAttempts wrong codes, so it cannot be used to guess one.
+func (us *userService) LoginTwoFactor(ctx context.Context, claims domain.TokenClaims, payLoad domain.TwoFactorLoginPayLoad, clientInfo domain.ClientInfo) (*domain.LoginResult, error) {
 	log := slog.With(
 		slog.String("service", "user"),
-		slog.String("func", "ConfirmEmail"))
+		slog.String("func", "LoginTwoFactor"))
 
-	log.Info("Confirming email service initiated")
+	log.Info("LoginTwoFactor initiated")
 
-	user, err := us.confimatioCodeService.ConfirmCode(confirmCode)
+	user, err := us.userRepository.GetById(ctx, claims.UserID)
 	if err != nil {
-		log.Error("Error: ", err)
-		return err
+		log.Error("Failed to obtain user by id", slog.Any("error", err))
+		return nil, domain.ErrGetUser
 	}
 
-	if err := us.userRepository.ConfirmedEmail(user.ID); err != nil {
-		log.Error("Error: ", err)
-		return err
+	if user == nil {
+		log.Warn("User not found with this id: " + claims.UserID)
+		return nil, domain.ErrUserNotFound
 	}
-	log.Info("ConfirmEmail executed uccessfully")
-	return nil
+
+	if err := us.checkSecondFactor(ctx, *user, payLoad.Method, payLoad.Code); err != nil {
+		us.loginFailed(user.ID, domain.LoginOutcomeSecondFactor, domain.AnonymousActor(clientInfo),
+			slog.String("method", payLoad.Method))
+		if errors.Is(err, domain.ErrInvalidTwoFactor) {
+			us.twoFactorChallengeFailed(claims)
+		}
+		return nil, err
+	}
+
+	if err := checkSuspension(*user); err != nil {
+		log.Warn("Login refused, account suspended: " + user.ID)
+		return nil, err
+	}
+
+	err = us.revokedTokenRepository.Create(domain.RevokedToken{
+		JTI:       claims.ID,
+		ExpiresAt: claims.ExpiresAt,
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		log.Warn("Two-factor challenge already used", slog.Any("error", err))
+		return nil, domain.ErrInvalidToken
+	}
+
+	if !user.Active {
+		log.Info("Login waiting for reactivation")
+		return us.reactivationRequired(*user)
+	}
+
+	if user.MustResetPassword {
+		log.Info("Login waiting for password reset")
+		return us.passwordResetRequired(*user)
+	}
+
+	loginResponse, err := us.startSession(ctx, *user, payLoad.RememberMe, clientInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	if payLoad.TrustDevice {
+		deviceToken, err := us.trustDevice(user.ID, clientInfo)
+		if err != nil {
+			return nil, err
+		}
+
+		loginResponse.TrustedDeviceToken = deviceToken
+		loginResponse.TrustedDeviceExpiresAt = time.Now().Add(config.TrustedDeviceTTL)
+	}
+
+	log.Info("LoginTwoFactor executed successfully")
+	return &domain.LoginResult{LoginResponse: loginResponse}, nil
 }
 
-func (us *userService) CheckUserIDMatch(idFromToken string) error {
+// twoFactorChallengeFailed counts a wrong code against the challenge of claims and revokes the
+// challenge once it reaches domain.TwoFactorChallengeMaxAttempts.
+func (us *userService) twoFactorChallengeFailed(claims domain.TokenClaims) {
 	log := slog.With(
 		slog.String("service", "user"),
-		slog.String("func", "CheckUserIDMatch"))
+		slog.String("func", "twoFactorChallengeFailed"))
 
-	log.Info("CheckUserIDMatch service initiated")
+	attempts, err := us.loginFailureRepository.Increment("two_factor:" + claims.ID)
+	if err != nil {
+		log.Error("Error trying to count failed two-factor code", slog.Any("error", err))
+		return
+	}
 
-	user, err := us.userRepository.GetById(idFromToken)
+	if attempts < domain.TwoFactorChallengeMaxAttempts {
+		return
+	}
+
+	err = us.revokedTokenRepository.Create(domain.RevokedToken{
+		JTI:       claims.ID,
+		ExpiresAt: claims.ExpiresAt,
+		CreatedAt: time.Now(),
+	})
 	if err != nil {
-		log.Warn("Failed to obtain user by id")
+		log.Warn("Two-factor challenge already revoked", slog.Any("error", err))
+		return
+	}
+
+	log.Warn("Two-factor challenge revoked after too many wrong codes: " + claims.UserID)
+}
+
+func (us *userService) SendTwoFactorCode(ctx context.Context, userID string) error {
+	log := slog.With(
+		slog.String("service", "user"),
+		slog.String("func", "SendTwoFactorCode"))
+
+	log.Info("SendTwoFactorCode initiated")
+
+	user, err := us.userRepository.GetById(ctx, userID)
+	if err != nil {
+		log.Error("Failed to obtain user by id", slog.Any("error", err))
 		return domain.ErrGetUser
 	}
 
 	if user == nil {
-		log.Warn("User not found with this id: " + idFromToken)
+		log.Warn("User not found with this id: " + userID)
 		return domain.ErrUserNotFound
 	}
 
-	if user.ID != idFromToken {
-		log.Warn("User ID mismatch")
-		return domain.ErrUserIDMismatch
+	if err := us.confimatioCodeService.SendTwoFactorCode(user.TenantID, user.Email); err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return err
 	}
 
-	log.Info("CheckUserIDMatch executed successfully")
+	log.Info("SendTwoFactorCode executed successfully")
 	return nil
 }
+
+// Authenticate checks a username or email and password without opening a session, for flows
+// such as the OAuth authorization endpoint that hand out something else than tokens.
+func (us *userService) Authenticate(ctx context.Context, username string, password string, clientInfo domain.ClientInfo) (*domain.UserResponse, error) {
+	log := slog.With(
+		slog.String("service", "user"),
+		slog.String("func", "Authenticate"))
+
+	log.Info("Authenticate initiated")
+
+	user, err := us.checkCredentials(ctx, username, password, clientInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	if !user.Active {
+		log.Warn("Authentication to a deactivated account: " + user.ID)
+		return nil, domain.ErrAccountDeactivated
+	}
+
+	log.Info("Authenticate executed successfully")
+	return user.ToUserResponse(), nil
+}
+
+// CreateSession opens a session for a user whose identity was already proven elsewhere. It is
+// refused while the account is suspended, deactivated or must reset its password.
+func (us *userService) CreateSession(ctx context.Context, userID string, clientInfo domain.ClientInfo) (*domain.LoginResponse, error) {
+	log := slog.With(
+		slog.String("service", "user"),
+		slog.String("func", "CreateSession"))
+
+	log.Info("CreateSession initiated")
+
+	user, err := us.userRepository.GetById(ctx, userID)
+	if err != nil {
+		log.Error("Failed to obtain user by id", slog.Any("error", err))
+		return nil, domain.ErrGetUser
+	}
+
+	if user == nil {
+		log.Warn("User not found with this id: " + userID)
+		return nil, domain.ErrUserNotFound
+	}
+
+	if err := checkSuspension(*user); err != nil {
+		log.Warn("Session refused to a suspended account: " + userID)
+		return nil, err
+	}
+
+	// The reactivation and reset tokens are only handed out after a login, see continueLogin.
+	if !user.Active {
+		log.Warn("Session refused to a deactivated account: " + userID)
+		return nil, domain.ErrAccountDeactivated
+	}
+
+	if user.MustResetPassword {
+		log.Warn("Session refused until the password is reset: " + userID)
+		return nil, domain.ErrPasswordResetRequired
+	}
+
+	loginResponse, err := us.startSession(ctx, *user, false, clientInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Info("CreateSession executed successfully")
+	return loginResponse, nil
+}
+
+func (us *userService) Refresh(ctx context.Context, refreshToken string, clientInfo domain.ClientInfo) (*domain.LoginResponse, error) {
+	log := slog.With(
+		slog.String("service", "user"),
+		slog.String("func", "Refresh"))
+
+	log.Info("Refresh initiated")
+
+	storedToken, err := us.refreshTokenRepository.GetByTokenHash(secure.HashToken(refreshToken))
+	if err != nil {
+		log.Error("Failed to obtain refresh token", slog.Any("error", err))
+		return nil, domain.ErrGetRefreshToken
+	}
+
+	if storedToken == nil {
+		log.Warn("Refresh token not found")
+		return nil, domain.ErrInvalidToken
+	}
+
+	if storedToken.IsRotated() {
+		log.Warn("Rotated refresh token presented again, revoking all sessions of user: " + storedToken.UserID)
+		return nil, us.revokeReusedFamily(*storedToken)
+	}
+
+	if !storedToken.IsActive() {
+		log.Warn("Refresh token expired or revoked")
+		return nil, domain.ErrInvalidToken
+	}
+
+	if storedToken.TenantID != clientInfo.TenantID {
+		log.Warn("Refresh token presented to another tenant, session: " + storedToken.FamilyID)
+		return nil, domain.ErrInvalidToken
+	}
+
+	user, err := us.userRepository.GetById(ctx, storedToken.UserID)
+	if err != nil {
+		log.Error("Failed to obtain user by id", slog.Any("error", err))
+		return nil, domain.ErrGetUser
+	}
+
+	if user == nil {
+		log.Warn("User not found with this id: " + storedToken.UserID)
+		return nil, domain.ErrInvalidToken
+	}
+
+	if err := checkSuspension(*user); err != nil {
+		log.Warn("Refresh refused, account suspended: " + user.ID)
+		return nil, err
+	}
+
+	if err := us.checkTokenBinding(*storedToken, *user, clientInfo); err != nil {
+		log.Warn("Refresh token presented by a different client, session: " + storedToken.FamilyID)
+		return nil, err
+	}
+
+	if err := us.loadAccess(user); err != nil {
+		log.Error("Failed to obtain user roles and permissions", slog.Any("error", err))
+		return nil, err
+	}
+
+	if err := us.loadOrganization(user, storedToken.OrganizationID); err != nil {
+		log.Error("Failed to obtain the organization of the session", slog.Any("error", err))
+		return nil, err
+	}
+
+	newRefreshToken, newStoredToken, err := us.newRefreshToken(*storedToken, clientInfo)
+	if err != nil {
+		log.Error("error trying create refresh token.", slog.Any("error", err))
+		return nil, domain.ErrCreateRefreshToken
+	}
+
+	rotated, err := us.refreshTokenRepository.Rotate(storedToken.ID, newStoredToken.ID)
+	if err != nil {
+		log.Error("Failed to rotate refresh token", slog.Any("error", err))
+		return nil, domain.ErrCreateRefreshToken
+	}
+
+	if !rotated {
+		log.Warn("Refresh token rotated concurrently, revoking all sessions of user: " + storedToken.UserID)
+		return nil, us.revokeReusedFamily(*storedToken)
+	}
+
+	token, err := us.tokenProvider.CreateToken(*user, storedToken.FamilyID)
+	if err != nil {
+		log.Error("error trying create token jwt.", slog.Any("error", err))
+		return nil, domain.ErrGenToken
+	}
+
+	idToken, err := auth.CreateIDToken(*user)
+	if err != nil {
+		log.Error("error trying create id token.", slog.Any("error", err))
+		return nil, domain.ErrGenToken
+	}
+
+	loginResponse := newLoginResponse(*user, token, newRefreshToken, newStoredToken)
+	loginResponse.IDToken = idToken
+
+	log.Info("Refresh executed successfully")
+	return loginResponse, nil
+}
+
+// SwitchOrganization makes the session of claims speak for an organization the user belongs to,
+// or for none with an empty organizationID. The access token returned, and the ones the session
+// gets on refresh, carry it for as long as the user stays a member.
+func (us *userService) SwitchOrganization(ctx context.Context, claims domain.TokenClaims, organizationID string) (*domain.AccessTokenResponse, error) {
+	log := slog.With(
+		slog.String("service", "user"),
+		slog.String("func", "SwitchOrganization"))
+
+	log.Info("SwitchOrganization initiated")
+
+	user, err := us.userRepository.GetById(ctx, claims.UserID)
+	if err != nil {
+		log.Error("Failed to obtain user by id", slog.Any("error", err))
+		return nil, domain.ErrGetUser
+	}
+
+	if user == nil {
+		log.Warn("User not found with this id: " + claims.UserID)
+		return nil, domain.ErrUserNotFound
+	}
+
+	if err := us.loadAccess(user); err != nil {
+		log.Error("Failed to obtain user roles and permissions", slog.Any("error", err))
+		return nil, err
+	}
+
+	if err := us.loadOrganization(user, organizationID); err != nil {
+		log.Error("Failed to obtain the organization", slog.Any("error", err))
+		return nil, err
+	}
+
+	if user.OrganizationID != organizationID {
+		log.Warn("Switch to an organization the user is not a member of: " + organizationID)
+		return nil, domain.ErrOrganizationNotFound
+	}
+
+	if err := us.refreshTokenRepository.SetOrganization(claims.SessionID, organizationID); err != nil {
+		log.Error("Failed to set the organization of the session", slog.Any("error", err))
+		return nil, domain.ErrSwitchOrganization
+	}
+
+	token, err := us.tokenProvider.CreateToken(*user, claims.SessionID)
+	if err != nil {
+		log.Error("error trying create token jwt.", slog.Any("error", err))
+		return nil, domain.ErrGenToken
+	}
+
+	log.Info("SwitchOrganization executed successfully")
+	return &domain.AccessTokenResponse{
+		AccessToken: token,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(config.Token.TTL.Seconds()),
+	}, nil
+}
+
+func (us *userService) Logout(ctx context.Context, claims domain.TokenClaims, refreshToken string) error {
+	log := slog.With(
+		slog.String("service", "user"),
+		slog.String("func", "Logout"))
+
+	log.Info("Logout initiated")
+
+	revoked, err := us.revokedTokenRepository.Exists(claims.ID)
+	if err != nil {
+		log.Error("Failed to check access token revocation", slog.Any("error", err))
+		return domain.ErrRevokeToken
+	}
+
+	if revoked {
+		log.Warn("Access token already revoked")
+		return domain.ErrInvalidToken
+	}
+
+	storedToken, err := us.refreshTokenRepository.GetByTokenHash(secure.HashToken(refreshToken))
+	if err != nil {
+		log.Error("Failed to obtain refresh token", slog.Any("error", err))
+		return domain.ErrGetRefreshToken
+	}
+
+	if storedToken == nil || storedToken.UserID != claims.UserID || storedToken.RevokedAt != nil {
+		log.Warn("Refresh token not found, revoked or from another user")
+		return domain.ErrInvalidToken
+	}
+
+	if err := us.refreshTokenRepository.Revoke(storedToken.ID); err != nil {
+		log.Error("Failed to revoke refresh token", slog.Any("error", err))
+		return domain.ErrRevokeToken
+	}
+
+	err = us.revokedTokenRepository.Create(domain.RevokedToken{
+		JTI:       claims.ID,
+		ExpiresAt: claims.ExpiresAt,
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		log.Error("Failed to revoke access token", slog.Any("error", err))
+		return domain.ErrRevokeToken
+	}
+
+	log.Info("Logout executed successfully")
+	return nil
+}
+
+func (us *userService) LogoutAll(ctx context.Context, userID string, password string) error {
+	log := slog.With(
+		slog.String("service", "user"),
+		slog.String("func", "LogoutAll"))
+
+	log.Info("LogoutAll initiated")
+
+	user, err := us.userRepository.GetById(ctx, userID)
+	if err != nil {
+		log.Error("Failed to obtain user by id", slog.Any("error", err))
+		return domain.ErrGetUser
+	}
+
+	if user == nil {
+		log.Warn("User not found with this id: " + userID)
+		return domain.ErrUserNotFound
+	}
+
+	if err := secure.CheckPassword(user.Password, password); err != nil {
+		log.Warn("invalid password for email: " + user.Email)
+		return domain.ErrPasswordNotMatch
+	}
+
+	if err := us.refreshTokenRepository.RevokeAllByUserID(userID); err != nil {
+		log.Error("Failed to revoke refresh tokens", slog.Any("error", err))
+		return domain.ErrRevokeToken
+	}
+
+	if err := us.userRepository.IncrementTokenVersion(ctx, userID); err != nil {
+		log.Error("Failed to increment token version", slog.Any("error", err))
+		return domain.ErrRevokeToken
+	}
+
+	log.Info("LogoutAll executed successfully")
+	return nil
+}
+
+func (us *userService) ConfirmEmail(ctx context.Context, confirmCode domain.ConfirmCode, clientInfo domain.ClientInfo) error {
+	log := slog.With(
+		slog.String("service", "user"),
+		slog.String("func", "ConfirmEmail"))
+
+	log.Info("Confirming email service initiated")
+
+	pendingEmail, err := us.pendingEmailRepository.GetUnconfirmedByNewEmail(clientInfo.TenantID, domain.NormalizeEmail(confirmCode.Email))
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return domain.ErrGetPendingEmail
+	}
+
+	if pendingEmail != nil {
+		return us.confirmEmailChange(ctx, *pendingEmail, confirmCode, clientInfo)
+	}
+
+	user, err := us.confimatioCodeService.ConfirmCode(ctx, clientInfo.TenantID, confirmCode)
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return err
+	}
+
+	if err := us.userRepository.ConfirmedEmail(ctx, user.ID); err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return err
+	}
+	log.Info("ConfirmEmail executed uccessfully")
+	return nil
+}
+
+// ConfirmEmailByLink confirms the email of the user a verification link was issued to. The
+// token is revoked on first use, so the link cannot be replayed before it expires.
+func (us *userService) ConfirmEmailByLink(ctx context.Context, token string) error {
+	log := slog.With(
+		slog.String("service", "user"),
+		slog.String("func", "ConfirmEmailByLink"))
+
+	log.Info("ConfirmEmailByLink initiated")
+
+	claims, err := us.tokenProvider.ParseToken(token)
+	if err != nil || claims.Scope != domain.ScopeEmailVerification {
+		log.Warn("Invalid email verification token")
+		return domain.ErrInvalidConfirmationLink
+	}
+
+	user, err := us.userRepository.GetById(ctx, claims.UserID)
+	if err != nil {
+		log.Error("Failed to obtain user by id", slog.Any("error", err))
+		return domain.ErrGetUser
+	}
+
+	if user == nil || user.TokenVersion != claims.Version {
+		log.Warn("Email verification token no longer valid for user: " + claims.UserID)
+		return domain.ErrInvalidConfirmationLink
+	}
+
+	err = us.revokedTokenRepository.Create(domain.RevokedToken{
+		JTI:       claims.ID,
+		ExpiresAt: claims.ExpiresAt,
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		log.Warn("Email verification link already used", slog.Any("error", err))
+		return domain.ErrInvalidConfirmationLink
+	}
+
+	if err := us.userRepository.ConfirmedEmail(ctx, user.ID); err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return err
+	}
+
+	log.Info("ConfirmEmailByLink executed successfully")
+	return nil
+}
+
+// RevertEmailChange follows the link sent to the old address of an email change. A change still
+// waiting for its code is dropped; a confirmed one is undone and every session of the account
+// ends, since whoever made it may still hold one.
+func (us *userService) RevertEmailChange(ctx context.Context, token string, clientInfo domain.ClientInfo) error {
+	log := slog.With(
+		slog.String("service", "user"),
+		slog.String("func", "RevertEmailChange"))
+
+	log.Info("RevertEmailChange initiated")
+
+	revertTokenHash := secure.HashToken(token)
+	pendingEmail, err := us.pendingEmailRepository.GetByRevertTokenHash(revertTokenHash)
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return domain.ErrGetPendingEmail
+	}
+
+	if pendingEmail == nil || time.Now().After(pendingEmail.RevertExpiresAt) {
+		log.Warn("Revert link not found or expired")
+		return domain.ErrInvalidRevertLink
+	}
+
+	if pendingEmail.ConfirmedAt == nil {
+		if _, err := us.pendingEmailRepository.Delete(revertTokenHash); err != nil {
+			log.Error("Error: ", slog.Any("error", err))
+			return domain.ErrRevertEmailChange
+		}
+
+		log.Info("RevertEmailChange executed successfully, change cancelled")
+		return nil
+	}
+
+	user, err := us.userRepository.GetById(ctx, pendingEmail.UserID)
+	if err != nil {
+		log.Error("Failed to obtain user by id", slog.Any("error", err))
+		return domain.ErrGetUser
+	}
+
+	if user == nil {
+		log.Warn("User not found with this id: " + pendingEmail.UserID)
+		return domain.ErrInvalidRevertLink
+	}
+
+	owner, err := us.userRepository.GetByEmail(ctx, user.TenantID, pendingEmail.OldEmail)
+	if err != nil {
+		log.Error("Failed to obtain user by email", slog.Any("error", err))
+		return domain.ErrGetUser
+	}
+
+	if owner != nil && owner.ID != user.ID {
+		log.Warn("Previous email taken by another account: " + owner.ID)
+		return domain.ErrOldEmailUnavailable
+	}
+
+	deleted, err := us.pendingEmailRepository.Delete(revertTokenHash)
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return domain.ErrRevertEmailChange
+	}
+
+	if !deleted {
+		log.Warn("Revert link already used")
+		return domain.ErrInvalidRevertLink
+	}
+
+	if err := us.userRepository.UpdateEmail(ctx, user.ID, pendingEmail.OldEmail); err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return domain.ErrRevertEmailChange
+	}
+
+	if err := us.refreshTokenRepository.RevokeAllByUserID(user.ID); err != nil {
+		log.Error("Failed to revoke refresh tokens", slog.Any("error", err))
+		return domain.ErrRevokeToken
+	}
+
+	if err := us.userRepository.IncrementTokenVersion(ctx, user.ID); err != nil {
+		log.Error("Failed to increment token version", slog.Any("error", err))
+		return domain.ErrRevokeToken
+	}
+
+	us.auditService.Record(domain.AuditEventEmailChangeReverted, user.ID, domain.UserActor(user.ID, clientInfo))
+
+	log.Info("RevertEmailChange executed successfully")
+	return nil
+}
+
+// ResendConfirmation sends a new confirmation code, replacing the previous one, to accounts
+// still waiting for it. Unknown or confirmed emails, rate limited requests and failed deliveries
+// end silently so the caller cannot tell them apart.
+func (us *userService) ResendConfirmation(ctx context.Context, tenantID string, email string) error {
+	log := slog.With(
+		slog.String("service", "user"),
+		slog.String("func", "ResendConfirmation"))
+
+	log.Info("ResendConfirmation initiated")
+
+	user, err := us.userRepository.GetByEmail(ctx, tenantID, domain.NormalizeEmail(email))
+	if err != nil {
+		log.Error("Failed to obtain user by email", slog.Any("error", err))
+		return domain.ErrGetUser
+	}
+
+	if user == nil || user.EmailConfirmed {
+		log.Warn("No email waiting for confirmation: " + email)
+		return nil
+	}
+
+	err = us.confimatioCodeService.SendConfirmationCode(ctx, user.TenantID, user.Email)
+	if err != nil && errors.Is(err, domain.ErrTooManyCodeRequests) {
+		log.Warn("Confirmation code requested too often for email: " + user.Email)
+		return nil
+	}
+
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return nil
+	}
+
+	log.Info("ResendConfirmation executed successfully")
+	return nil
+}
+
+func (us *userService) CheckUserIDMatch(ctx context.Context, idFromToken string) error {
+	log := slog.With(
+		slog.String("service", "user"),
+		slog.String("func", "CheckUserIDMatch"))
+
+	log.Info("CheckUserIDMatch service initiated")
+
+	user, err := us.userRepository.GetById(domain.AllowCachedUser(ctx), idFromToken)
+	if err != nil {
+		log.Warn("Failed to obtain user by id")
+		return domain.ErrGetUser
+	}
+
+	if user == nil {
+		log.Warn("User not found with this id: " + idFromToken)
+		return domain.ErrUserNotFound
+	}
+
+	if user.ID != idFromToken {
+		log.Warn("User ID mismatch")
+		return domain.ErrUserIDMismatch
+	}
+
+	log.Info("CheckUserIDMatch executed successfully")
+	return nil
+}
+
+// EnableTOTP starts an authenticator app enrollment. The secret stays pending, and is replaced
+// by any new enrollment, until ConfirmTOTP checks a code generated from it.
+func (us *userService) EnableTOTP(ctx context.Context, userID string) (*domain.TOTPEnrollmentResponse, error) {
+	log := slog.With(
+		slog.String("service", "user"),
+		slog.String("func", "EnableTOTP"))
+
+	log.Info("EnableTOTP initiated")
+
+	user, err := us.userRepository.GetById(ctx, userID)
+	if err != nil {
+		log.Error("Failed to obtain user by id", slog.Any("error", err))
+		return nil, domain.ErrGetUser
+	}
+
+	if user == nil {
+		log.Warn("User not found with this id: " + userID)
+		return nil, domain.ErrUserNotFound
+	}
+
+	if user.TwoFactorAuthActive {
+		log.Warn("Two-factor authentication already enabled for user: " + userID)
+		return nil, domain.ErrTOTPAlreadyEnabled
+	}
+
+	key, err := secure.GenerateTOTPKey(user.Email)
+	if err != nil {
+		log.Error("Error trying to generate totp key", slog.Any("error", err))
+		return nil, domain.ErrEnableTOTP
+	}
+
+	encryptedSecret, err := secure.Encrypt(key.Secret)
+	if err != nil {
+		log.Error("Error trying to encrypt totp secret", slog.Any("error", err))
+		return nil, domain.ErrEnableTOTP
+	}
+
+	if err := us.userRepository.UpdateTOTPSecret(ctx, userID, encryptedSecret); err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return nil, domain.ErrEnableTOTP
+	}
+
+	recoveryCodes, err := us.replaceRecoveryCodes(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Info("EnableTOTP executed successfully")
+	return &domain.TOTPEnrollmentResponse{
+		Secret:        key.Secret,
+		OtpauthURI:    key.URI,
+		QRCode:        key.QRCode,
+		RecoveryCodes: recoveryCodes,
+	}, nil
+}
+
+func (us *userService) ConfirmTOTP(ctx context.Context, userID string, code string, clientInfo domain.ClientInfo) error {
+	log := slog.With(
+		slog.String("service", "user"),
+		slog.String("func", "ConfirmTOTP"))
+
+	log.Info("ConfirmTOTP initiated")
+
+	user, err := us.userRepository.GetById(ctx, userID)
+	if err != nil {
+		log.Error("Failed to obtain user by id", slog.Any("error", err))
+		return domain.ErrGetUser
+	}
+
+	if user == nil {
+		log.Warn("User not found with this id: " + userID)
+		return domain.ErrUserNotFound
+	}
+
+	if user.TwoFactorAuthActive {
+		log.Warn("Two-factor authentication already enabled for user: " + userID)
+		return domain.ErrTOTPAlreadyEnabled
+	}
+
+	if user.TOTPSecret == "" {
+		log.Warn("No pending totp enrollment for user: " + userID)
+		return domain.ErrTOTPNotEnrolled
+	}
+
+	secret, err := secure.Decrypt(user.TOTPSecret)
+	if err != nil {
+		log.Error("Error trying to decrypt totp secret", slog.Any("error", err))
+		return domain.ErrEnableTOTP
+	}
+
+	step, valid := secure.ValidateTOTP(code, secret)
+	if !valid {
+		log.Warn("Invalid totp code for user: " + userID)
+		return domain.ErrInvalidTOTPCode
+	}
+
+	// Recorded so the code that confirmed the app cannot also open a login.
+	used, err := us.userRepository.UseTOTPStep(ctx, userID, step)
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return domain.ErrEnableTOTP
+	}
+
+	if !used {
+		log.Warn("Totp code already used for user: " + userID)
+		return domain.ErrInvalidTOTPCode
+	}
+
+	activated, err := us.userRepository.ActivateTwoFactor(ctx, userID, user.TOTPSecret)
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return domain.ErrEnableTOTP
+	}
+
+	if !activated {
+		log.Warn("Totp enrollment replaced before confirmation for user: " + userID)
+		return domain.ErrInvalidTOTPCode
+	}
+
+	us.auditService.Record(domain.AuditEventTwoFactorEnabled, userID, domain.UserActor(userID, clientInfo))
+
+	us.emailService.Notify(domain.Notification{
+		Type:     domain.NotificationTwoFactorEnabled,
+		Name:     user.Name,
+		Locale:   user.Locale,
+		Timezone: user.Timezone,
+	}, []string{user.Email})
+
+	log.Info("ConfirmTOTP executed successfully")
+	return nil
+}
+
+func (us *userService) GetRecoveryCodeCount(ctx context.Context, userID string) (*domain.RecoveryCodeCountResponse, error) {
+	log := slog.With(
+		slog.String("service", "user"),
+		slog.String("func", "GetRecoveryCodeCount"))
+
+	log.Info("GetRecoveryCodeCount initiated")
+
+	remaining, err := us.recoveryCodeRepository.CountUnused(userID)
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return nil, domain.ErrGetRecoveryCodes
+	}
+
+	log.Info("GetRecoveryCodeCount executed successfully")
+	return &domain.RecoveryCodeCountResponse{Remaining: remaining}, nil
+}
+
+// RegenerateRecoveryCodes invalidates every previous recovery code. The password is asked again
+// so a stolen session cannot silently take over the account's fallback.
+func (us *userService) RegenerateRecoveryCodes(ctx context.Context, userID string, password string, clientInfo domain.ClientInfo) (*domain.RecoveryCodesResponse, error) {
+	log := slog.With(
+		slog.String("service", "user"),
+		slog.String("func", "RegenerateRecoveryCodes"))
+
+	log.Info("RegenerateRecoveryCodes initiated")
+
+	user, err := us.userRepository.GetById(ctx, userID)
+	if err != nil {
+		log.Error("Failed to obtain user by id", slog.Any("error", err))
+		return nil, domain.ErrGetUser
+	}
+
+	if user == nil {
+		log.Warn("User not found with this id: " + userID)
+		return nil, domain.ErrUserNotFound
+	}
+
+	if err := secure.CheckPassword(user.Password, password); err != nil {
+		log.Warn("invalid password for user: " + userID)
+		return nil, domain.ErrPasswordNotMatch
+	}
+
+	if !user.TwoFactorAuthActive {
+		log.Warn("Two-factor authentication not enabled for user: " + userID)
+		return nil, domain.ErrTwoFactorNotEnabled
+	}
+
+	recoveryCodes, err := us.replaceRecoveryCodes(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	us.auditService.Record(domain.AuditEventRecoveryCodesRegenerated, userID, domain.UserActor(userID, clientInfo))
+
+	log.Info("RegenerateRecoveryCodes executed successfully")
+	return &domain.RecoveryCodesResponse{RecoveryCodes: recoveryCodes}, nil
+}
+
+// DisableTwoFactor turns two-factor authentication off once the user proves both factors again.
+func (us *userService) DisableTwoFactor(ctx context.Context, userID string, payLoad domain.DisableTwoFactorPayLoad, clientInfo domain.ClientInfo) error {
+	log := slog.With(
+		slog.String("service", "user"),
+		slog.String("func", "DisableTwoFactor"))
+
+	log.Info("DisableTwoFactor initiated")
+
+	user, err := us.userRepository.GetById(ctx, userID)
+	if err != nil {
+		log.Error("Failed to obtain user by id", slog.Any("error", err))
+		return domain.ErrGetUser
+	}
+
+	if user == nil {
+		log.Warn("User not found with this id: " + userID)
+		return domain.ErrUserNotFound
+	}
+
+	if err := secure.CheckPassword(user.Password, payLoad.Password); err != nil {
+		log.Warn("invalid password for user: " + userID)
+		return domain.ErrPasswordNotMatch
+	}
+
+	if !user.TwoFactorAuthActive {
+		log.Warn("Two-factor authentication not enabled for user: " + userID)
+		return domain.ErrTwoFactorNotEnabled
+	}
+
+	if err := us.checkSecondFactor(ctx, *user, payLoad.Method, payLoad.Code); err != nil {
+		return err
+	}
+
+	if err := us.disableTwoFactor(ctx, *user, domain.UserActor(userID, clientInfo)); err != nil {
+		return err
+	}
+
+	log.Info("DisableTwoFactor executed successfully")
+	return nil
+}
+
+// AdminDisableTwoFactor is the support path for users locked out of every second factor.
+func (us *userService) AdminDisableTwoFactor(ctx context.Context, viewer domain.Viewer, userID string) error {
+	log := slog.With(
+		slog.String("service", "user"),
+		slog.String("func", "AdminDisableTwoFactor"))
+
+	log.Info("AdminDisableTwoFactor initiated")
+
+	user, err := us.userRepository.GetById(ctx, userID)
+	if err != nil {
+		log.Error("Failed to obtain user by id", slog.Any("error", err))
+		return domain.ErrGetUser
+	}
+
+	if user == nil || !viewer.SameTenant(*user) {
+		log.Warn("User not found with this id: " + userID)
+		return domain.ErrUserNotFound
+	}
+
+	if !user.TwoFactorAuthActive && user.TOTPSecret == "" {
+		log.Warn("Two-factor authentication not enabled for user: " + userID)
+		return domain.ErrTwoFactorNotEnabled
+	}
+
+	if err := us.disableTwoFactor(ctx, *user, viewer.Actor()); err != nil {
+		return err
+	}
+
+	log.Info("AdminDisableTwoFactor executed successfully")
+	return nil
+}
+
+// AdminForcePasswordReset flags an account believed compromised: its sessions end and the next
+// login only yields a reset token, until ResetPassword succeeds.
+func (us *userService) AdminForcePasswordReset(ctx context.Context, viewer domain.Viewer, userID string) error {
+	log := slog.With(
+		slog.String("service", "user"),
+		slog.String("func", "AdminForcePasswordReset"))
+
+	log.Info("AdminForcePasswordReset initiated")
+
+	user, err := us.userRepository.GetById(ctx, userID)
+	if err != nil {
+		log.Error("Failed to obtain user by id", slog.Any("error", err))
+		return domain.ErrGetUser
+	}
+
+	if user == nil || !viewer.SameTenant(*user) {
+		log.Warn("User not found with this id: " + userID)
+		return domain.ErrUserNotFound
+	}
+
+	if err := us.userRepository.SetMustResetPassword(ctx, userID, true); err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return domain.ErrForcePasswordReset
+	}
+
+	if err := us.refreshTokenRepository.RevokeAllByUserID(userID); err != nil {
+		log.Error("Failed to revoke refresh tokens", slog.Any("error", err))
+		return domain.ErrRevokeToken
+	}
+
+	if err := us.userRepository.IncrementTokenVersion(ctx, userID); err != nil {
+		log.Error("Failed to increment token version", slog.Any("error", err))
+		return domain.ErrRevokeToken
+	}
+
+	us.auditService.Record(domain.AuditEventPasswordResetForced, userID, viewer.Actor())
+
+	log.Info("AdminForcePasswordReset executed successfully")
+	return nil
+}
+
+// AdminSuspend keeps the user from logging in until payLoad.Until, or until AdminUnsuspend when
+// it is omitted. The sessions of the user end right away.
+func (us *userService) AdminSuspend(ctx context.Context, viewer domain.Viewer, userID string, payLoad domain.SuspendPayLoad) error {
+	log := slog.With(
+		slog.String("service", "user"),
+		slog.String("func", "AdminSuspend"))
+
+	log.Info("AdminSuspend initiated")
+
+	user, err := us.userRepository.GetById(ctx, userID)
+	if err != nil {
+		log.Error("Failed to obtain user by id", slog.Any("error", err))
+		return domain.ErrGetUser
+	}
+
+	if user == nil || !viewer.SameTenant(*user) {
+		log.Warn("User not found with this id: " + userID)
+		return domain.ErrUserNotFound
+	}
+
+	if err := us.userRepository.Suspend(ctx, userID, payLoad.Until); err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return domain.ErrSuspendUser
+	}
+
+	if err := us.refreshTokenRepository.RevokeAllByUserID(userID); err != nil {
+		log.Error("Failed to revoke refresh tokens", slog.Any("error", err))
+		return domain.ErrRevokeToken
+	}
+
+	details := []slog.Attr{slog.String("reason", payLoad.Reason)}
+	if payLoad.Until != nil {
+		details = append(details, slog.Time("until", *payLoad.Until))
+	}
+	us.auditService.Record(domain.AuditEventUserSuspended, userID, viewer.Actor(), details...)
+
+	log.Info("AdminSuspend executed successfully")
+	return nil
+}
+
+func (us *userService) AdminUnsuspend(ctx context.Context, viewer domain.Viewer, userID string, reason string) error {
+	log := slog.With(
+		slog.String("service", "user"),
+		slog.String("func", "AdminUnsuspend"))
+
+	log.Info("AdminUnsuspend initiated")
+
+	user, err := us.userRepository.GetById(ctx, userID)
+	if err != nil {
+		log.Error("Failed to obtain user by id", slog.Any("error", err))
+		return domain.ErrGetUser
+	}
+
+	if user == nil || !viewer.SameTenant(*user) {
+		log.Warn("User not found with this id: " + userID)
+		return domain.ErrUserNotFound
+	}
+
+	unsuspended, err := us.userRepository.Unsuspend(ctx, userID)
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return domain.ErrUnsuspendUser
+	}
+
+	if !unsuspended {
+		log.Warn("User is not suspended: " + userID)
+		return domain.ErrAccountNotSuspended
+	}
+
+	us.auditService.Record(domain.AuditEventUserUnsuspended, userID, viewer.Actor(), slog.String("reason", reason))
+
+	log.Info("AdminUnsuspend executed successfully")
+	return nil
+}
+
+// AdminDelete deletes the account right away, without the grace period of Delete. It is soft
+// deleted, so AdminRestore can still undo it until it is purged.
+func (us *userService) AdminDelete(ctx context.Context, viewer domain.Viewer, userID string) error {
+	log := slog.With(
+		slog.String("service", "user"),
+		slog.String("func", "AdminDelete"))
+
+	log.Info("AdminDelete initiated")
+
+	user, err := us.userRepository.GetById(ctx, userID)
+	if err != nil {
+		log.Error("Failed to obtain user by id", slog.Any("error", err))
+		return domain.ErrGetUser
+	}
+
+	if user == nil || !viewer.SameTenant(*user) {
+		log.Warn("User not found with this id: " + userID)
+		return domain.ErrUserNotFound
+	}
+
+	if err := us.refreshTokenRepository.RevokeAllByUserID(userID); err != nil {
+		log.Error("Failed to revoke refresh tokens", slog.Any("error", err))
+		return domain.ErrRevokeToken
+	}
+
+	err = us.userRepository.Delete(ctx, userID)
+	if errors.Is(err, domain.ErrUserNotFound) {
+		log.Warn("User deleted by a concurrent request: " + userID)
+		return err
+	}
+
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return domain.ErrDeleteUser
+	}
+
+	us.auditService.Record(domain.AuditEventUserDeleted, userID, viewer.Actor())
+
+	log.Info("AdminDelete executed successfully")
+	return nil
+}
+
+// AdminAnonymize erases the personal data of the account, such as when its owner asks support to.
+func (us *userService) AdminAnonymize(ctx context.Context, viewer domain.Viewer, userID string) error {
+	log := slog.With(
+		slog.String("service", "user"),
+		slog.String("func", "AdminAnonymize"))
+
+	log.Info("AdminAnonymize initiated")
+
+	user, err := us.userRepository.GetById(ctx, userID)
+	if err != nil {
+		log.Error("Failed to obtain user by id", slog.Any("error", err))
+		return domain.ErrGetUser
+	}
+
+	if user == nil || !viewer.SameTenant(*user) {
+		log.Warn("User not found with this id: " + userID)
+		return domain.ErrUserNotFound
+	}
+
+	if user.IsAnonymized() {
+		log.Warn("User already anonymized: " + userID)
+		return domain.ErrUserAlreadyAnonymized
+	}
+
+	if err := us.anonymize(ctx, *user, viewer.Actor()); err != nil {
+		return err
+	}
+
+	log.Info("AdminAnonymize executed successfully")
+	return nil
+}
+
+// AdminRestore undoes the deletion of an account not purged yet.
+func (us *userService) AdminRestore(ctx context.Context, viewer domain.Viewer, userID string) error {
+	log := slog.With(
+		slog.String("service", "user"),
+		slog.String("func", "AdminRestore"))
+
+	log.Info("AdminRestore initiated")
+
+	restored, err := us.userRepository.Restore(ctx, viewer.ClientInfo.TenantID, userID)
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return domain.ErrRestoreUser
+	}
+
+	if !restored {
+		log.Warn("No deleted user with this id: " + userID)
+		return domain.ErrUserNotFound
+	}
+
+	us.auditService.Record(domain.AuditEventUserRestored, userID, viewer.Actor())
+
+	log.Info("AdminRestore executed successfully")
+	return nil
+}
+
+// Deactivate disables the account until its owner logs in again and confirms the reactivation.
+// Its sessions end and it is left out of the user search meanwhile.
+func (us *userService) Deactivate(ctx context.Context, userID string, password string, clientInfo domain.ClientInfo) error {
+	log := slog.With(
+		slog.String("service", "user"),
+		slog.String("func", "Deactivate"))
+
+	log.Info("Deactivate initiated")
+
+	user, err := us.userRepository.GetById(ctx, userID)
+	if err != nil {
+		log.Error("Failed to obtain user by id", slog.Any("error", err))
+		return domain.ErrGetUser
+	}
+
+	if user == nil {
+		log.Warn("User not found with this id: " + userID)
+		return domain.ErrUserNotFound
+	}
+
+	if err := secure.CheckPassword(user.Password, password); err != nil {
+		log.Warn("invalid password to deactivate user: " + userID)
+		return domain.ErrPasswordNotMatch
+	}
+
+	if err := us.userRepository.SetActive(ctx, userID, false); err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return domain.ErrDeactivateUser
+	}
+
+	if err := us.refreshTokenRepository.RevokeAllByUserID(userID); err != nil {
+		log.Error("Failed to revoke refresh tokens", slog.Any("error", err))
+		return domain.ErrRevokeToken
+	}
+
+	if err := us.userRepository.IncrementTokenVersion(ctx, userID); err != nil {
+		log.Error("Failed to increment token version", slog.Any("error", err))
+		return domain.ErrRevokeToken
+	}
+
+	us.auditService.Record(domain.AuditEventUserDeactivated, userID, domain.UserActor(userID, clientInfo))
+
+	log.Info("Deactivate executed successfully")
+	return nil
+}
+
+// Reactivate confirms the reactivation offered by a login to a deactivated account and carries
+// on that login. The token is revoked first, so it works once.
+func (us *userService) Reactivate(ctx context.Context, claims domain.TokenClaims, clientInfo domain.ClientInfo) (*domain.LoginResult, error) {
+	log := slog.With(
+		slog.String("service", "user"),
+		slog.String("func", "Reactivate"))
+
+	log.Info("Reactivate initiated")
+
+	user, err := us.userRepository.GetById(ctx, claims.UserID)
+	if err != nil {
+		log.Error("Failed to obtain user by id", slog.Any("error", err))
+		return nil, domain.ErrGetUser
+	}
+
+	if user == nil {
+		log.Warn("User not found with this id: " + claims.UserID)
+		return nil, domain.ErrUserNotFound
+	}
+
+	err = us.revokedTokenRepository.Create(domain.RevokedToken{
+		JTI:       claims.ID,
+		ExpiresAt: claims.ExpiresAt,
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		log.Warn("Reactivation token already used", slog.Any("error", err))
+		return nil, domain.ErrInvalidToken
+	}
+
+	if err := checkSuspension(*user); err != nil {
+		log.Warn("Reactivation refused, account suspended: " + user.ID)
+		return nil, err
+	}
+
+	if !user.Active {
+		if err := us.userRepository.SetActive(ctx, user.ID, true); err != nil {
+			log.Error("Error: ", slog.Any("error", err))
+			return nil, domain.ErrReactivateUser
+		}
+
+		us.auditService.Record(domain.AuditEventUserReactivated, user.ID, domain.UserActor(user.ID, clientInfo))
+	}
+
+	if user.MustResetPassword {
+		log.Info("Login waiting for password reset")
+		return us.passwordResetRequired(*user)
+	}
+
+	loginResponse, err := us.startSession(ctx, *user, false, clientInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Info("Reactivate executed successfully")
+	return &domain.LoginResult{LoginResponse: loginResponse}, nil
+}
+
+// Private session
+func (us *userService) checkCredentials(ctx context.Context, identifier string, password string, clientInfo domain.ClientInfo) (*domain.User, error) {
+	log := slog.With(
+		slog.String("service", "user"),
+		slog.String("func", "checkCredentials"))
+
+	var getBy func(context.Context, string, string) (*domain.User, error)
+
+	if util.IsEmailValid(identifier) {
+		getBy = us.userRepository.GetByEmail
+	} else {
+		getBy = us.userRepository.GetByUsername
+	}
+
+	user, err := getBy(ctx, clientInfo.TenantID, identifier)
+	if err != nil {
+		log.Warn("Failed to obtain user")
+		return nil, domain.ErrGetUser
+	}
+
+	if user == nil {
+		log.Warn("User not found with this identifier: " + identifier)
+		secure.CheckDummyPassword(password)
+		us.auditService.Record(domain.AuditEventLoginFailed, "", domain.AnonymousActor(clientInfo),
+			slog.String("reason", "unknown_account"), slog.String("identifier", identifier))
+		return nil, us.unknownIdentifierFailed(clientInfo.TenantID, identifier)
+	}
+
+	if !user.HasPassword() {
+		log.Warn("Password login to an account without password: " + user.ID)
+		secure.CheckDummyPassword(password)
+		us.loginFailed(user.ID, domain.LoginOutcomeNoPassword, domain.AnonymousActor(clientInfo))
+		return nil, domain.ErrPasswordNotMatch
+	}
+
+	// The password is still hashed while the account waits, as for the other refusals.
+	if user.LockedUntil != nil && time.Now().Before(*user.LockedUntil) {
+		log.Warn("Login refused, account locked: " + user.ID)
+		secure.CheckDummyPassword(password)
+		us.loginFailed(user.ID, domain.LoginOutcomeLocked, domain.AnonymousActor(clientInfo))
+		return nil, &domain.AccountLockedError{RetryAfter: time.Until(*user.LockedUntil)}
+	}
+
+	if delay := loginDelay(user.FailedLoginAttempts, user.LastFailedLoginAt); delay > 0 {
+		log.Warn("Login refused, waiting for the login delay: " + user.ID)
+		secure.CheckDummyPassword(password)
+		return nil, &domain.LoginDelayedError{RetryAfter: delay}
+	}
+
+	if err := secure.CheckPassword(user.Password, password); err != nil {
+		log.Warn("invalid password for email: " + user.Email)
+		us.loginFailed(user.ID, domain.LoginOutcomeWrongPassword, domain.AnonymousActor(clientInfo))
+		return nil, us.registerFailedLogin(ctx, *user, clientInfo)
+	}
+
+	if user.FailedLoginAttempts > 0 || user.LastFailedLoginAt != nil || user.LockedUntil != nil {
+		if err := us.userRepository.ResetFailedLogins(ctx, user.ID); err != nil {
+			log.Error("Error trying to reset failed logins", slog.Any("error", err))
+		}
+	}
+
+	// Only told once the password matched, so the suspension is not disclosed to anyone else.
+	if err := checkSuspension(*user); err != nil {
+		log.Warn("Login refused, account suspended: " + user.ID)
+		us.loginFailed(user.ID, domain.LoginOutcomeSuspended, domain.UserActor(user.ID, clientInfo))
+		return nil, err
+	}
+
+	// Also only told once the password matched. The code is sent again, unless one was just sent.
+	if config.RequireConfirmedEmail && !user.EmailConfirmed {
+		log.Warn("Login refused, email not confirmed: " + user.ID)
+		us.loginFailed(user.ID, domain.LoginOutcomeUnconfirmed, domain.UserActor(user.ID, clientInfo))
+		go func() {
+			if err := us.confimatioCodeService.SendConfirmationCode(context.WithoutCancel(ctx), user.TenantID, user.Email); err != nil {
+				log.Warn("Confirmation code not sent again", slog.Any("error", err))
+			}
+		}()
+		return nil, domain.ErrEmailNotConfirmed
+	}
+
+	if secure.NeedsRehash(user.Password) {
+		us.rehashPassword(ctx, user, password)
+	}
+
+	return user, nil
+}
+
+// anonymize erases the personal data of user, keeping its ID for the audit trail. Its sessions,
+// identities, credentials and codes are deleted with it.
+func (us *userService) anonymize(ctx context.Context, user domain.User, actor domain.Actor) error {
+	log := slog.With(
+		slog.String("service", "user"),
+		slog.String("func", "anonymize"))
+
+	if err := us.userRepository.Anonymize(ctx, user.ID); err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return domain.ErrAnonymizeUser
+	}
+
+	us.deleteAvatar(user)
+	us.auditService.Record(domain.AuditEventUserAnonymized, user.ID, actor)
+	return nil
+}
+
+// deleteAvatar removes the uploaded avatar of a user that is gone or anonymized. A failure is
+// only logged, as the user is already removed.
+func (us *userService) deleteAvatar(user domain.User) {
+	if user.AvatarURL == nil {
+		return
+	}
+
+	if err := us.avatarStorage.Delete(user.ID); err != nil {
+		slog.Error("Error trying to delete avatar", slog.String("userId", user.ID), slog.Any("error", err))
+	}
+}
+
+// loginFailed records a failed login to the account of userID, with outcome as its reason, both
+// in the audit trail and in the login history of the account.
+func (us *userService) loginFailed(userID string, outcome string, actor domain.Actor, details ...slog.Attr) {
+	details = append([]slog.Attr{slog.String("reason", outcome)}, details...)
+	us.auditService.Record(domain.AuditEventLoginFailed, userID, actor, details...)
+	us.loginHistoryService.Record(userID, outcome, actor.ClientInfo)
+}
+
+// requestEmailChange records newEmail as pending and sends it a code. The old address gets a link
+// able to undo the change for domain.EmailChangeRevertTTL.
+func (us *userService) requestEmailChange(ctx context.Context, user domain.User, newEmail string) error {
+	log := slog.With(
+		slog.String("service", "user"),
+		slog.String("func", "requestEmailChange"))
+
+	owner, err := us.userRepository.GetByEmail(ctx, user.TenantID, newEmail)
+	if err != nil {
+		log.Error("Failed to obtain user by email", slog.Any("error", err))
+		return domain.ErrGetUser
+	}
+
+	if owner != nil {
+		log.Warn("Email change to an already registered email: " + newEmail)
+		return domain.ErrUserAlreadyRegistered
+	}
+
+	revertToken, err := secure.GenerateOpaqueToken()
+	if err != nil {
+		log.Error("Error trying to generate revert token", slog.Any("error", err))
+		return domain.ErrCreatePendingEmail
+	}
+
+	if err := us.confimatioCodeService.SendEmailChangeCode(user.TenantID, newEmail); err != nil {
+		log.Warn("Email change code not sent", slog.Any("error", err))
+		return err
+	}
+
+	err = us.pendingEmailRepository.Create(domain.PendingEmail{
+		RevertTokenHash: secure.HashToken(revertToken),
+		UserID:          user.ID,
+		OldEmail:        user.Email,
+		NewEmail:        newEmail,
+		RevertExpiresAt: time.Now().Add(domain.EmailChangeRevertTTL),
+	})
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return domain.ErrCreatePendingEmail
+	}
+
+	us.emailService.Notify(domain.Notification{
+		Type:     domain.NotificationEmailChangeRequested,
+		Name:     user.Name,
+		Locale:   user.Locale,
+		Timezone: user.Timezone,
+		NewEmail: newEmail,
+		Link:     config.EmailChangeRevertURL + "?token=" + url.QueryEscape(revertToken),
+	}, []string{user.Email})
+
+	return nil
+}
+
+// confirmEmailChange switches the account to the new address of pendingEmail once its code is
+// right. The change stays recorded so the old address can still revert it.
+func (us *userService) confirmEmailChange(ctx context.Context, pendingEmail domain.PendingEmail, confirmCode domain.ConfirmCode, clientInfo domain.ClientInfo) error {
+	log := slog.With(
+		slog.String("service", "user"),
+		slog.String("func", "confirmEmailChange"))
+
+	if err := us.confimatioCodeService.CheckCode(clientInfo.TenantID, domain.ConfirmCode{Email: pendingEmail.NewEmail, Code: confirmCode.Code}); err != nil {
+		return err
+	}
+
+	owner, err := us.userRepository.GetByEmail(ctx, clientInfo.TenantID, pendingEmail.NewEmail)
+	if err != nil {
+		log.Error("Failed to obtain user by email", slog.Any("error", err))
+		return domain.ErrGetUser
+	}
+
+	if owner != nil {
+		log.Warn("New email registered meanwhile: " + pendingEmail.NewEmail)
+		return domain.ErrUserAlreadyRegistered
+	}
+
+	confirmed, err := us.pendingEmailRepository.Confirm(pendingEmail.RevertTokenHash)
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return domain.ErrConfirmEmailChange
+	}
+
+	if !confirmed {
+		log.Warn("Email change already confirmed")
+		return domain.ErrOTPNotFound
+	}
+
+	err = us.userRepository.UpdateEmail(ctx, pendingEmail.UserID, pendingEmail.NewEmail)
+	if errors.Is(err, domain.ErrEmailTaken) {
+		log.Warn("New email taken by a concurrent registration: " + pendingEmail.NewEmail)
+		return err
+	}
+
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return domain.ErrConfirmEmailChange
+	}
+
+	us.auditService.Record(domain.AuditEventEmailChanged, pendingEmail.UserID, domain.UserActor(pendingEmail.UserID, clientInfo),
+		slog.String("email", pendingEmail.NewEmail))
+
+	log.Info("Email changed for user: " + pendingEmail.UserID)
+	return nil
+}
+
+// sendAlreadyRegisteredEmail tells the owner of an email that someone tried to register it again.
+func (us *userService) sendAlreadyRegisteredEmail(user domain.User) {
+	log := slog.With(
+		slog.String("service", "user"),
+		slog.String("func", "sendAlreadyRegisteredEmail"))
+
+	subject := "Você já tem uma conta"
+	content := fmt.Sprintf("<h1>Olá, %s!</h1><p>Alguém tentou criar uma conta com este e-mail, mas você já tem uma.</p>"+
+		"<p>Se foi você, basta entrar. Se esqueceu a senha, use a opção \"Esqueci minha senha\".</p>"+
+		"<p>Se não foi você, pode ignorar este e-mail.</p>", html.EscapeString(user.Name))
+
+	if err := us.emailService.SendEmail(subject, content, []string{user.Email}); err != nil {
+		log.Error("Error trying to send already registered email", slog.Any("error", err))
+	}
+}
+
+// registerFailedLogin counts a wrong password and locks the account once config.LoginLockout
+// allows no more. The error to answer the login with is returned: the lock when this failure
+// triggered it, ErrPasswordNotMatch otherwise. The failure is counted even when the client
+// already gave up on the login, or disconnecting would be a way around the lockout.
+func (us *userService) registerFailedLogin(ctx context.Context, user domain.User, clientInfo domain.ClientInfo) error {
+	log := slog.With(
+		slog.String("service", "user"),
+		slog.String("func", "registerFailedLogin"))
+
+	ctx = context.WithoutCancel(ctx)
+	attempts, err := us.userRepository.IncrementFailedLogins(ctx, user.ID)
+	if err != nil {
+		log.Error("Error trying to count failed login", slog.Any("error", err))
+		return domain.ErrPasswordNotMatch
+	}
+
+	if attempts < config.LoginLockout.MaxAttempts {
+		return domain.ErrPasswordNotMatch
+	}
+
+	lockedUntil := time.Now().Add(config.LoginLockout.Duration)
+	if err := us.userRepository.LockAccount(ctx, user.ID, lockedUntil); err != nil {
+		log.Error("Error trying to lock account", slog.Any("error", err))
+		return domain.ErrPasswordNotMatch
+	}
+
+	us.auditService.Record(domain.AuditEventAccountLocked, user.ID, domain.Actor{Type: domain.AuditActorSystem, ClientInfo: clientInfo})
+
+	subject := "Sua conta foi bloqueada temporariamente"
+	content := fmt.Sprintf("<h1>Olá, %s!</h1><p>Depois de %d tentativas de login com a senha errada, sua conta foi bloqueada até %s.</p>"+
+		"<p>Ela será desbloqueada automaticamente. Para desbloquear agora, redefina sua senha pela opção \"Esqueci minha senha\".</p>"+
+		"<p>Se não foi você, recomendamos redefinir a senha mesmo assim.</p>",
+		html.EscapeString(user.Name), attempts, lockedUntil.Format("02/01/2006 15:04"))
+
+	if err := us.emailService.SendEmail(subject, content, []string{user.Email}); err != nil {
+		log.Error("Error trying to send account locked email", slog.Any("error", err))
+	}
+
+	return &domain.AccountLockedError{RetryAfter: config.LoginLockout.Duration}
+}
+
+// unknownIdentifierFailed counts a login with an identifier of no account as registerFailedLogin
+// counts a wrong password, and answers it as a login to a locked or delayed account would be, so
+// the answers do not tell which identifiers have an account. Nobody is told about the lock.
+func (us *userService) unknownIdentifierFailed(tenantID string, identifier string) error {
+	log := slog.With(
+		slog.String("service", "user"),
+		slog.String("func", "unknownIdentifierFailed"))
+
+	key := tenantID + ":" + domain.NormalizeUsername(identifier)
+	if util.IsEmailValid(identifier) {
+		key = tenantID + ":" + domain.NormalizeEmail(identifier)
+	}
+
+	failures, err := us.loginFailureRepository.Get(key)
+	if err != nil {
+		log.Error("Error trying to get failed logins", slog.Any("error", err))
+		return domain.ErrUserNotFound
+	}
+
+	if failures.LockedUntil != nil && time.Now().Before(*failures.LockedUntil) {
+		return &domain.AccountLockedError{RetryAfter: time.Until(*failures.LockedUntil)}
+	}
+
+	if delay := loginDelay(failures.Attempts, failures.LastFailedAt); delay > 0 {
+		return &domain.LoginDelayedError{RetryAfter: delay}
+	}
+
+	attempts, err := us.loginFailureRepository.Increment(key)
+	if err != nil {
+		log.Error("Error trying to count failed login", slog.Any("error", err))
+		return domain.ErrUserNotFound
+	}
+
+	if attempts < config.LoginLockout.MaxAttempts {
+		return domain.ErrUserNotFound
+	}
+
+	if err := us.loginFailureRepository.Lock(key, time.Now().Add(config.LoginLockout.Duration)); err != nil {
+		log.Error("Error trying to lock identifier", slog.Any("error", err))
+		return domain.ErrUserNotFound
+	}
+
+	return &domain.AccountLockedError{RetryAfter: config.LoginLockout.Duration}
+}
+
+// loginDelay is how long an account, or identifier, with attempts failures in a row, the last at
+// lastFailedAt, still has to wait before its next password is checked: nothing for the first
+// config.LoginLockout.DelayAfter failures, then DelayBase, doubled with each further failure up to
+// DelayMax.
+func loginDelay(attempts int, lastFailedAt *time.Time) time.Duration {
+	excess := attempts - config.LoginLockout.DelayAfter
+	if lastFailedAt == nil || excess < 0 {
+		return 0
+	}
+
+	delay := config.LoginLockout.DelayBase
+	for i := 0; i < excess && delay < config.LoginLockout.DelayMax; i++ {
+		delay *= 2
+	}
+	if delay > config.LoginLockout.DelayMax {
+		delay = config.LoginLockout.DelayMax
+	}
+
+	return time.Until(lastFailedAt.Add(delay))
+}
+
+// rehashPassword moves a password hashed with an older algorithm or older parameters to the
+// current ones. It runs right after a successful check, the only moment the plain password is
+// known; a failure leaves the old hash in place and does not affect the login.
+func (us *userService) rehashPassword(ctx context.Context, user *domain.User, password string) {
+	log := slog.With(
+		slog.String("service", "user"),
+		slog.String("func", "rehashPassword"))
+
+	newHashedPassword, err := secure.Hash(password)
+	if err != nil {
+		log.Error("Error trying to hashed password", slog.Any("error", err))
+		return
+	}
+
+	if _, err := us.userRepository.RehashPassword(ctx, user.ID, user.Password, string(newHashedPassword)); err != nil {
+		log.Error("Error trying to rehash password", slog.Any("error", err))
+		return
+	}
+
+	user.Password = string(newHashedPassword)
+}
+
+func (us *userService) continueLogin(ctx context.Context, user domain.User, rememberMe bool, deviceToken string, clientInfo domain.ClientInfo) (*domain.LoginResult, error) {
+	log := slog.With(
+		slog.String("service", "user"),
+		slog.String("func", "continueLogin"))
+
+	if user.TwoFactorAuthActive && !us.isTrustedDevice(user.ID, deviceToken) {
+		challengeToken, err := us.tokenProvider.CreateTwoFactorChallengeToken(user)
+		if err != nil {
+			log.Error("error trying create two-factor challenge token.", slog.Any("error", err))
+			return nil, domain.ErrGenToken
+		}
+
+		methods := []string{domain.TwoFactorMethodEmail}
+		if user.TOTPSecret != "" {
+			methods = []string{domain.TwoFactorMethodTOTP, domain.TwoFactorMethodEmail, domain.TwoFactorMethodRecovery}
+		}
+		if user.CanReceiveSMS() {
+			methods = append(methods, domain.TwoFactorMethodSMS)
+		}
+
+		log.Info("Login waiting for second factor")
+		return &domain.LoginResult{Challenge: &domain.TwoFactorChallengeResponse{
+			TwoFactorRequired: true,
+			ChallengeToken:    challengeToken,
+			TokenType:         "Bearer",
+			ExpiresIn:         int64(domain.TwoFactorChallengeTTL.Seconds()),
+			Methods:           methods,
+		}}, nil
+	}
+
+	if !user.Active {
+		log.Info("Login waiting for reactivation")
+		return us.reactivationRequired(user)
+	}
+
+	if user.MustResetPassword {
+		log.Info("Login waiting for password reset")
+		return us.passwordResetRequired(user)
+	}
+
+	loginResponse, err := us.startSession(ctx, user, rememberMe, clientInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.LoginResult{LoginResponse: loginResponse}, nil
+}
+
+// reactivationRequired answers a login to a deactivated account with a reactivation token
+// instead of a session. It is only reached once every factor the account has was proven.
+func (us *userService) reactivationRequired(user domain.User) (*domain.LoginResult, error) {
+	log := slog.With(
+		slog.String("service", "user"),
+		slog.String("func", "reactivationRequired"))
+
+	reactivationToken, err := us.tokenProvider.CreateReactivationToken(user)
+	if err != nil {
+		log.Error("error trying create reactivation token.", slog.Any("error", err))
+		return nil, domain.ErrGenToken
+	}
+
+	return &domain.LoginResult{Deactivated: &domain.AccountDeactivatedResponse{
+		Error:             "Forbidden",
+		Message:           domain.ErrAccountDeactivated.Error(),
+		Code:              domain.AccountDeactivatedCode,
+		ReactivationToken: reactivationToken,
+		TokenType:         "Bearer",
+		ExpiresIn:         int64(domain.ReactivationTokenTTL.Seconds()),
+	}}, nil
+}
+
+// passwordResetRequired answers a login to a flagged account with a reset token instead of a
+// session. It is only reached once every factor the account has was proven.
+func (us *userService) passwordResetRequired(user domain.User) (*domain.LoginResult, error) {
+	log := slog.With(
+		slog.String("service", "user"),
+		slog.String("func", "passwordResetRequired"))
+
+	resetToken, err := us.tokenProvider.CreateResetPasswordToken(user)
+	if err != nil {
+		log.Error("error trying create reset password token.", slog.Any("error", err))
+		return nil, domain.ErrGenToken
+	}
+
+	return &domain.LoginResult{PasswordReset: &domain.PasswordResetRequiredResponse{
+		Code:       domain.PasswordResetRequiredCode,
+		ResetToken: resetToken,
+		TokenType:  "Bearer",
+		ExpiresIn:  int64(domain.ResetPasswordTokenTTL.Seconds()),
+	}}, nil
+}
+
+func (us *userService) checkSecondFactor(ctx context.Context, user domain.User, method string, code string) error {
+	log := slog.With(
+		slog.String("service", "user"),
+		slog.String("func", "checkSecondFactor"))
+
+	switch method {
+	case domain.TwoFactorMethodTOTP:
+		if user.TOTPSecret == "" {
+			log.Warn("Authenticator app not enrolled for user: " + user.ID)
+			return domain.ErrTwoFactorMethod
+		}
+
+		secret, err := secure.Decrypt(user.TOTPSecret)
+		if err != nil {
+			log.Error("Error trying to decrypt totp secret", slog.Any("error", err))
+			return domain.ErrGetUser
+		}
+
+		step, valid := secure.ValidateTOTP(code, secret)
+		if !valid {
+			log.Warn("Invalid totp code for user: " + user.ID)
+			return domain.ErrInvalidTwoFactor
+		}
+
+		used, err := us.userRepository.UseTOTPStep(ctx, user.ID, step)
+		if err != nil {
+			log.Error("Error trying to record totp step", slog.Any("error", err))
+			return domain.ErrGetUser
+		}
+
+		if !used {
+			log.Warn("Totp code already used for user: " + user.ID)
+			return domain.ErrInvalidTwoFactor
+		}
+	case domain.TwoFactorMethodEmail:
+		_, err := us.confimatioCodeService.ConfirmCode(ctx, user.TenantID, domain.ConfirmCode{Email: user.Email, Code: code})
+		if err != nil {
+			log.Warn("Invalid email code for user: "+user.ID, slog.Any("error", err))
+			return domain.ErrInvalidTwoFactor
+		}
+	case domain.TwoFactorMethodSMS:
+		if !user.CanReceiveSMS() {
+			log.Warn("No confirmed phone number for user: " + user.ID)
+			return domain.ErrTwoFactorMethod
+		}
+
+		err := us.confimatioCodeService.CheckCode(user.TenantID, domain.ConfirmCode{Email: *user.PhoneNumber, Code: code})
+		if err != nil {
+			log.Warn("Invalid sms code for user: "+user.ID, slog.Any("error", err))
+			return domain.ErrInvalidTwoFactor
+		}
+	case domain.TwoFactorMethodRecovery:
+		used, err := us.recoveryCodeRepository.Use(user.ID, secure.HashRecoveryCode(code))
+		if err != nil {
+			log.Error("Error trying to use recovery code", slog.Any("error", err))
+			return domain.ErrGetRecoveryCodes
+		}
+
+		if !used {
+			log.Warn("Invalid recovery code for user: " + user.ID)
+			return domain.ErrInvalidTwoFactor
+		}
+	default:
+		return domain.ErrTwoFactorMethod
+	}
+
+	return nil
+}
+
+func (us *userService) disableTwoFactor(ctx context.Context, user domain.User, actor domain.Actor) error {
+	log := slog.With(
+		slog.String("service", "user"),
+		slog.String("func", "disableTwoFactor"))
+
+	if err := us.userRepository.DisableTwoFactor(ctx, user.ID); err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return domain.ErrDisableTwoFactor
+	}
+
+	if err := us.recoveryCodeRepository.DeleteByUserID(user.ID); err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return domain.ErrDisableTwoFactor
+	}
+
+	if err := us.trustedDeviceRepository.DeleteByUserID(user.ID); err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return domain.ErrDisableTwoFactor
+	}
+
+	us.auditService.Record(domain.AuditEventTwoFactorDisabled, user.ID, actor)
+
+	us.emailService.Notify(domain.Notification{
+		Type:     domain.NotificationTwoFactorDisabled,
+		Name:     user.Name,
+		Locale:   user.Locale,
+		Timezone: user.Timezone,
+		ByAdmin:  actor.Type == domain.AuditActorAdmin,
+	}, []string{user.Email})
+
+	return nil
+}
+
+// isTrustedDevice reports whether the device token was issued to this user by a completed
+// two-step login and has not expired or been revoked.
+func (us *userService) isTrustedDevice(userID string, deviceToken string) bool {
+	log := slog.With(
+		slog.String("service", "user"),
+		slog.String("func", "isTrustedDevice"))
+
+	if deviceToken == "" {
+		return false
+	}
+
+	trustedDevice, err := us.trustedDeviceRepository.GetByTokenHash(secure.HashToken(deviceToken))
+	if err != nil {
+		log.Error("Error trying to get trusted device", slog.Any("error", err))
+		return false
+	}
+
+	if trustedDevice == nil || trustedDevice.UserID != userID || !trustedDevice.IsActive() {
+		return false
+	}
+
+	if err := us.trustedDeviceRepository.UpdateLastUsedAt(trustedDevice.ID); err != nil {
+		log.Warn("Error trying to record trusted device usage", slog.Any("error", err))
+	}
+
+	return true
+}
+
+func (us *userService) trustDevice(userID string, clientInfo domain.ClientInfo) (string, error) {
+	log := slog.With(
+		slog.String("service", "user"),
+		slog.String("func", "trustDevice"))
+
+	deviceToken, err := secure.GenerateOpaqueToken()
+	if err != nil {
+		log.Error("Error trying to generate device token", slog.Any("error", err))
+		return "", domain.ErrCreateTrustedDevice
+	}
+
+	err = us.trustedDeviceRepository.Create(domain.TrustedDevice{
+		ID:        uuid.NewString(),
+		UserID:    userID,
+		TokenHash: secure.HashToken(deviceToken),
+		UserAgent: clientInfo.UserAgent,
+		IP:        clientInfo.IP,
+		ExpiresAt: time.Now().Add(config.TrustedDeviceTTL),
+	})
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return "", domain.ErrCreateTrustedDevice
+	}
+
+	return deviceToken, nil
+}
+
+// replaceRecoveryCodes stores a new set of recovery codes, hashed, and returns them in clear
+// for the only time.
+func (us *userService) replaceRecoveryCodes(userID string) ([]string, error) {
+	log := slog.With(
+		slog.String("service", "user"),
+		slog.String("func", "replaceRecoveryCodes"))
+
+	codes := make([]string, domain.RecoveryCodeCount)
+	recoveryCodes := make([]domain.RecoveryCode, domain.RecoveryCodeCount)
+
+	for index := range codes {
+		code, err := secure.GenerateRecoveryCode()
+		if err != nil {
+			log.Error("Error trying to generate recovery code", slog.Any("error", err))
+			return nil, domain.ErrCreateRecoveryCodes
+		}
+
+		codes[index] = code
+		recoveryCodes[index] = domain.RecoveryCode{
+			ID:       uuid.NewString(),
+			UserID:   userID,
+			CodeHash: secure.HashRecoveryCode(code),
+		}
+	}
+
+	if err := us.recoveryCodeRepository.Replace(userID, recoveryCodes); err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return nil, domain.ErrCreateRecoveryCodes
+	}
+
+	return codes, nil
+}
+
+// checkSuspension refuses the user while an admin suspension is in effect.
+func checkSuspension(user domain.User) error {
+	if !user.IsSuspended() {
+		return nil
+	}
+
+	return &domain.AccountSuspendedError{Until: user.SuspendedUntil}
+}
+
+// loadAccess fills the roles and the effective permissions of user, which its access tokens
+// carry.
+func (us *userService) loadAccess(user *domain.User) error {
+	roles, err := us.roleRepository.GetNamesByUserID(user.ID)
+	if err != nil {
+		return domain.ErrGetRole
+	}
+
+	permissions, err := us.permissionRepository.GetNamesByUserID(user.ID)
+	if err != nil {
+		return domain.ErrGetPermission
+	}
+
+	user.Roles = roles
+	user.Permissions = permissions
+	return nil
+}
+
+// loadOrganization fills the organization of user and the role held in it, which its access
+// tokens carry. It is left empty when organizationID is, or when the user is no longer a member.
+func (us *userService) loadOrganization(user *domain.User, organizationID string) error {
+	if organizationID == "" {
+		return nil
+	}
+
+	membership, err := us.organizationRepository.GetMembership(organizationID, user.ID)
+	if err != nil {
+		return domain.ErrGetOrganization
+	}
+
+	if membership != nil {
+		user.OrganizationID = membership.OrganizationID
+		user.OrganizationRole = membership.Role
+	}
+
+	return nil
+}
+
+func (us *userService) startSession(ctx context.Context, user domain.User, rememberMe bool, clientInfo domain.ClientInfo) (*domain.LoginResponse, error) {
+	log := slog.With(
+		slog.String("service", "user"),
+		slog.String("func", "startSession"))
+
+	// Anonymized accounts have no credentials left; this only guards against one slipping through.
+	if user.IsAnonymized() {
+		log.Warn("Login refused, account anonymized: " + user.ID)
+		return nil, domain.ErrUserNotFound
+	}
+
+	if err := us.loadAccess(&user); err != nil {
+		log.Error("Failed to obtain user roles and permissions", slog.Any("error", err))
+		return nil, err
+	}
+
+	refreshToken, storedRefreshToken, err := us.createRefreshToken(user, rememberMe, clientInfo)
+	if err != nil && errors.Is(err, domain.ErrTooManySessions) {
+		log.Warn("Session limit reached for user: " + user.ID)
+		return nil, err
+	}
+
+	if err != nil {
+		log.Error("error trying create refresh token.", slog.Any("error", err))
+		return nil, domain.ErrCreateRefreshToken
+	}
+
+	token, err := us.tokenProvider.CreateToken(user, storedRefreshToken.FamilyID)
+	if err != nil {
+		log.Error("error trying create token jwt.", slog.Any("error", err))
+		return nil, domain.ErrGenToken
+	}
+
+	idToken, err := auth.CreateIDToken(user)
+	if err != nil {
+		log.Error("error trying create id token.", slog.Any("error", err))
+		return nil, domain.ErrGenToken
+	}
+
+	loginResponse := newLoginResponse(user, token, refreshToken, storedRefreshToken)
+	loginResponse.IDToken = idToken
+
+	// Logging in is how the owner takes back a scheduled deletion.
+	if user.DeletionScheduledAt != nil {
+		canceled, err := us.userRepository.CancelDeletion(ctx, user.ID)
+		if err != nil {
+			log.Error("Error trying to cancel scheduled deletion", slog.Any("error", err))
+		} else if canceled {
+			us.auditService.Record(domain.AuditEventDeletionCanceled, user.ID, domain.UserActor(user.ID, clientInfo))
+		}
+	}
+
+	us.auditService.Record(domain.AuditEventLoginSucceeded, user.ID, domain.UserActor(user.ID, clientInfo),
+		slog.String("sessionId", storedRefreshToken.FamilyID))
+	us.loginHistoryService.Record(user.ID, domain.LoginOutcomeSuccess, clientInfo)
+	us.loginAlertService.Check(user, clientInfo)
+
+	// Dated in the background, so the login does not wait for it.
+	go func() {
+		if err := us.userRepository.SetLastLoginAt(context.WithoutCancel(ctx), user.ID, time.Now()); err != nil {
+			log.Error("Error trying to set the last login", slog.Any("error", err))
+		}
+	}()
+
+	return loginResponse, nil
+}
+
+func (us *userService) createRefreshToken(user domain.User, persistent bool, clientInfo domain.ClientInfo) (string, *domain.RefreshToken, error) {
+	familyID, err := uuid.NewRandom()
+	if err != nil {
+		return "", nil, err
+	}
+
+	now := time.Now()
+	refreshToken, storedToken, err := buildRefreshToken(domain.RefreshToken{
+		UserID:     user.ID,
+		TenantID:   user.TenantID,
+		FamilyID:   familyID.String(),
+		Persistent: persistent,
+		MaxExpiry:  now.Add(config.SessionMaxLifetime),
+		SessionAt:  now,
+	}, clientInfo)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if config.MaxSessions <= 0 {
+		if err := us.refreshTokenRepository.Create(storedToken); err != nil {
+			return "", nil, err
+		}
+
+		return refreshToken, &storedToken, nil
+	}
+
+	evictOldest := config.SessionLimitPolicy == config.SessionLimitEvictOldest
+	created, evicted, err := us.refreshTokenRepository.CreateWithinLimit(storedToken, config.MaxSessions, evictOldest)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if !created {
+		return "", nil, domain.ErrTooManySessions
+	}
+
+	for _, sessionID := range evicted {
+		err := us.revokedTokenRepository.Create(domain.RevokedToken{
+			JTI:       sessionID,
+			ExpiresAt: time.Now().Add(config.Token.TTL),
+		})
+		if err != nil {
+			return "", nil, err
+		}
+	}
+
+	return refreshToken, &storedToken, nil
+}
+
+func (us *userService) newRefreshToken(previous domain.RefreshToken, clientInfo domain.ClientInfo) (string, *domain.RefreshToken, error) {
+	refreshToken, storedToken, err := buildRefreshToken(previous, clientInfo)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if err := us.refreshTokenRepository.Create(storedToken); err != nil {
+		return "", nil, err
+	}
+
+	return refreshToken, &storedToken, nil
+}
+
+// buildRefreshToken prepares the next token of the session described by previous. Its expiry
+// slides to now plus the idle TTL, the long one for sessions opened with remember me, but
+// never past the session MaxExpiry.
+func buildRefreshToken(previous domain.RefreshToken, clientInfo domain.ClientInfo) (string, domain.RefreshToken, error) {
+	refreshToken, err := secure.GenerateOpaqueToken()
+	if err != nil {
+		return "", domain.RefreshToken{}, err
+	}
+
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return "", domain.RefreshToken{}, err
+	}
+
+	ttl := config.RefreshTokenTTL
+	if previous.Persistent {
+		ttl = config.RememberMeTTL
+	}
+
+	expiresAt := time.Now().Add(ttl)
+	if !previous.MaxExpiry.IsZero() && expiresAt.After(previous.MaxExpiry) {
+		expiresAt = previous.MaxExpiry
+	}
+
+	storedToken := domain.RefreshToken{
+		ID:             id.String(),
+		UserID:         previous.UserID,
+		TenantID:       previous.TenantID,
+		FamilyID:       previous.FamilyID,
+		TokenHash:      secure.HashToken(refreshToken),
+		Persistent:     previous.Persistent,
+		UserAgent:      clientInfo.UserAgent,
+		IP:             clientInfo.IP,
+		ExpiresAt:      expiresAt,
+		MaxExpiry:      previous.MaxExpiry,
+		SessionAt:      previous.SessionAt,
+		OrganizationID: previous.OrganizationID,
+	}
+
+	if config.TokenBinding != config.TokenBindingOff {
+		storedToken.IPPrefix = util.IPPrefix(clientInfo.IP)
+		storedToken.UAHash = secure.HashToken(clientInfo.UserAgent)
+	}
+
+	return refreshToken, storedToken, nil
+}
+
+// checkTokenBinding compares the client refreshing a session with the one the token was issued
+// to. Tokens issued while binding was off carry no binding and always pass.
+func (us *userService) checkTokenBinding(refreshToken domain.RefreshToken, user domain.User, clientInfo domain.ClientInfo) error {
+	if config.TokenBinding == config.TokenBindingOff || (refreshToken.IPPrefix == "" && refreshToken.UAHash == "") {
+		return nil
+	}
+
+	if refreshToken.IPPrefix == util.IPPrefix(clientInfo.IP) && refreshToken.UAHash == secure.HashToken(clientInfo.UserAgent) {
+		return nil
+	}
+
+	if config.TokenBinding == config.TokenBindingReject {
+		return domain.ErrTokenBinding
+	}
+
+	subject := "Sua sessão foi usada de outro local"
+	content := fmt.Sprintf("<h1>Olá!</h1><p>Sua sessão foi renovada a partir de um novo local (%s). "+
+		"Se não foi você, encerre suas sessões e altere sua senha.</p>", html.EscapeString(clientInfo.IP))
+	if err := us.emailService.SendEmail(subject, content, []string{user.Email}); err != nil {
+		slog.Warn("Error trying to notify token binding mismatch", slog.Any("error", err))
+	}
+
+	return nil
+}
+
+// purgeAccounts runs once at startup and then every accountPurgeInterval. It reminds the owners
+// of accounts scheduled for deletion a day before it happens, permanently removes the accounts
+// whose scheduled deletion is due and the ones deleted longer than config.DeletedUserRetention ago.
+func (us *userService) purgeAccounts(ctx context.Context) {
+	log := slog.With(
+		slog.String("service", "user"),
+		slog.String("func", "purgeAccounts"))
+
+	ticker := time.NewTicker(accountPurgeInterval)
+	defer ticker.Stop()
+
+	for ; ; <-ticker.C {
+		us.remindScheduledDeletions(ctx)
+		us.runScheduledDeletions(ctx)
+
+		purged, err := us.userRepository.PurgeDeleted(ctx, time.Now().Add(-config.DeletedUserRetention))
+		if err != nil {
+			log.Error("Error: ", slog.Any("error", err))
+			continue
+		}
+
+		if purged > 0 {
+			log.Info("Deleted users purged", slog.Int64("count", purged))
+		}
+	}
+}
+
+func (us *userService) remindScheduledDeletions(ctx context.Context) {
+	log := slog.With(
+		slog.String("service", "user"),
+		slog.String("func", "remindScheduledDeletions"))
+
+	users, err := us.userRepository.GetDeletionReminderDue(ctx, time.Now().Add(deletionReminderLead))
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return
+	}
+
+	for _, user := range users {
+		if err := us.userRepository.SetDeletionReminded(ctx, user.ID); err != nil {
+			log.Error("Error: ", slog.Any("error", err))
+			continue
+		}
+
+		us.emailService.Notify(domain.Notification{
+			Type:     domain.NotificationDeletionReminder,
+			Name:     user.Name,
+			Locale:   user.Locale,
+			Timezone: user.Timezone,
+			DueAt:    *user.DeletionScheduledAt,
+		}, []string{user.Email})
+	}
+}
+
+func (us *userService) runScheduledDeletions(ctx context.Context) {
+	log := slog.With(
+		slog.String("service", "user"),
+		slog.String("func", "runScheduledDeletions"))
+
+	users, err := us.userRepository.GetDeletionDue(ctx, time.Now())
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return
+	}
+
+	for _, user := range users {
+		if err := us.userRepository.Purge(ctx, user.ID); err != nil {
+			log.Error("Error: ", slog.Any("error", err))
+			continue
+		}
+
+		us.deleteAvatar(user)
+		us.auditService.Record(domain.AuditEventUserPurged, user.ID, domain.SystemActor)
+	}
+}
+
+// cleanupUnverified runs once at startup and then every config.UnverifiedCleanup.Interval. It
+// deletes or anonymizes, as configured, the accounts that never confirmed their email nor logged
+// in within config.UnverifiedCleanup.MaxAge, so they stop holding their username and email. In dry
+// run it only counts them.
+func (us *userService) cleanupUnverified(ctx context.Context) {
+	log := slog.With(
+		slog.String("service", "user"),
+		slog.String("func", "cleanupUnverified"))
+
+	ticker := time.NewTicker(config.UnverifiedCleanup.Interval)
+	defer ticker.Stop()
+
+	for ; ; <-ticker.C {
+		createdBefore := time.Now().Add(-config.UnverifiedCleanup.MaxAge)
+
+		var found, removed, failed int
+		afterID := ""
+		for {
+			users, err := us.userRepository.GetUnverified(ctx, createdBefore, afterID, config.UnverifiedCleanup.BatchSize)
+			if err != nil {
+				log.Error("Error: ", slog.Any("error", err))
+				break
+			}
+
+			found += len(users)
+			for _, user := range users {
+				afterID = user.ID
+				if config.UnverifiedCleanup.DryRun {
+					continue
+				}
+
+				if err := us.removeUnverified(ctx, user); err != nil {
+					log.Error("Error: ", slog.String("userId", user.ID), slog.Any("error", err))
+					failed++
+					continue
+				}
+				removed++
+			}
+
+			if len(users) < config.UnverifiedCleanup.BatchSize {
+				break
+			}
+		}
+
+		if found > 0 {
+			log.Info("Unverified accounts cleaned up", slog.String("action", config.UnverifiedCleanup.Action),
+				slog.Bool("dryRun", config.UnverifiedCleanup.DryRun), slog.Int("found", found),
+				slog.Int("removed", removed), slog.Int("failed", failed))
+		}
+	}
+}
+
+func (us *userService) removeUnverified(ctx context.Context, user domain.User) error {
+	if config.UnverifiedCleanup.Action == config.UnverifiedCleanupAnonymize {
+		return us.anonymize(ctx, user, domain.SystemActor)
+	}
+
+	if err := us.userRepository.Purge(ctx, user.ID); err != nil {
+		return err
+	}
+
+	us.deleteAvatar(user)
+	us.auditService.Record(domain.AuditEventUserPurged, user.ID, domain.SystemActor, slog.String("reason", "unverified"))
+	return nil
+}
+
+// newUserPage builds the page of a listing from the users read by the repository, which holds
+// one user more than the page when another page follows. Each user is shown with toItem.
+func newUserPage[T any](users []domain.User, total int64, query domain.UserListQuery, toItem func(*domain.User) *T) *domain.Page[T] {
+	pageRequest := query.PageRequest
+	userPage := &domain.Page[T]{
+		Items:   make([]T, 0, len(users)),
+		PerPage: pageRequest.PerPage,
+		Total:   total,
+		HasNext: len(users) > pageRequest.PerPage,
+		HasPrev: pageRequest.After != nil || pageRequest.Page > 1,
+	}
+
+	if userPage.HasNext {
+		users = users[:pageRequest.PerPage]
+	}
+
+	for _, user := range users {
+		userPage.Items = append(userPage.Items, *toItem(&user))
+	}
+
+	if pageRequest.After == nil {
+		userPage.Page = pageRequest.Page
+	}
+
+	if userPage.HasNext {
+		last := users[len(users)-1]
+		userPage.NextCursor = query.CursorAt(last).Encode()
+	}
+
+	return userPage
+}
+
+func newLoginResponse(user domain.User, accessToken string, refreshToken string, storedToken *domain.RefreshToken) *domain.LoginResponse {
+	return &domain.LoginResponse{
+		AccessToken:           accessToken,
+		TokenType:             "Bearer",
+		ExpiresIn:             int64(config.Token.TTL.Seconds()),
+		RefreshToken:          refreshToken,
+		RefreshTokenExpiresAt: storedToken.ExpiresAt,
+		Persistent:            storedToken.Persistent,
+		User:                  *user.ToUserInfosResponse(),
+	}
+}
+
+func (us *userService) revokeReusedFamily(refreshToken domain.RefreshToken) error {
+	if err := us.refreshTokenRepository.RevokeFamily(refreshToken.FamilyID); err != nil {
+		return domain.ErrRevokeToken
+	}
+
+	if err := us.refreshTokenRepository.RevokeAllByUserID(refreshToken.UserID); err != nil {
+		return domain.ErrRevokeToken
+	}
+
+	return domain.ErrRefreshTokenReused
+}
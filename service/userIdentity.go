@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/OVillas/autentication/domain"
+	"github.com/samber/do"
+)
+
+type userIdentityService struct {
+	i                      *do.Injector
+	userRepository         domain.UserRepository
+	userIdentityRepository domain.UserIdentityRepository
+}
+
+func NewUserIdentityService(i *do.Injector) (domain.UserIdentityService, error) {
+	userRepository := do.MustInvoke[domain.UserRepository](i)
+	userIdentityRepository := do.MustInvoke[domain.UserIdentityRepository](i)
+	return &userIdentityService{
+		i:                      i,
+		userRepository:         userRepository,
+		userIdentityRepository: userIdentityRepository,
+	}, nil
+}
+
+func (uis *userIdentityService) GetAll(userID string) ([]domain.UserIdentityResponse, error) {
+	log := slog.With(
+		slog.String("service", "userIdentity"),
+		slog.String("func", "GetAll"))
+
+	log.Info("GetAll initiated")
+
+	userIdentities, err := uis.userIdentityRepository.GetByUserID(userID)
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return nil, domain.ErrGetUserIdentity
+	}
+
+	var response []domain.UserIdentityResponse
+	for _, userIdentity := range userIdentities {
+		response = append(response, *userIdentity.ToUserIdentityResponse())
+	}
+
+	log.Info("GetAll executed successfully")
+	return response, nil
+}
+
+// Unlink removes an identity unless it is the last way left to sign in: accounts without a
+// usable password keep at least one identity.
+func (uis *userIdentityService) Unlink(ctx context.Context, userID string, id string) error {
+	log := slog.With(
+		slog.String("service", "userIdentity"),
+		slog.String("func", "Unlink"))
+
+	log.Info("Unlink initiated")
+
+	user, err := uis.userRepository.GetById(ctx, userID)
+	if err != nil {
+		log.Error("Failed to obtain user by id", slog.Any("error", err))
+		return domain.ErrGetUser
+	}
+
+	if user == nil {
+		log.Warn("User not found with this id: " + userID)
+		return domain.ErrUserNotFound
+	}
+
+	userIdentities, err := uis.userIdentityRepository.GetByUserID(userID)
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return domain.ErrGetUserIdentity
+	}
+
+	found := false
+	for _, userIdentity := range userIdentities {
+		if userIdentity.ID == id {
+			found = true
+			break
+		}
+	}
+
+	if !found {
+		log.Warn("User identity not found with this id: " + id)
+		return domain.ErrUserIdentityNotFound
+	}
+
+	if !user.HasPassword() && len(userIdentities) == 1 {
+		log.Warn("Refusing to unlink the last login method of user: " + userID)
+		return domain.ErrLastLoginMethod
+	}
+
+	if _, err := uis.userIdentityRepository.Delete(id); err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return domain.ErrDeleteUserIdentity
+	}
+
+	log.Info("Unlink executed successfully")
+	return nil
+}
@@ -1,38 +1,64 @@
 package service
 
 import (
+	"context"
+	"errors"
 	"log/slog"
+	"strings"
+	"time"
 
+	"github.com/OVillas/autentication/auth"
 	"github.com/OVillas/autentication/domain"
 	"github.com/OVillas/autentication/secure"
-	"github.com/OVillas/autentication/util"
+	"github.com/nbutton23/zxcvbn-go"
 	"github.com/samber/do"
 )
 
 type userPasswordService struct {
-	i                       *do.Injector
-	userRepository          domain.UserRepository
-	confirmationCodeService domain.ConfirmationCodeService
+	i                          *do.Injector
+	userRepository             domain.UserRepository
+	trustedDeviceRepository    domain.TrustedDeviceRepository
+	refreshTokenRepository     domain.RefreshTokenRepository
+	confirmationCodeRepository domain.ConfirmationCodeRepository
+	revokedTokenRepository     domain.RevokedTokenRepository
+	confirmationCodeService    domain.ConfirmationCodeService
+	emailService               domain.EmailService
+	auditService               domain.AuditService
+	tokenProvider              auth.TokenProvider
 }
 
 func NewUserPasswordService(i *do.Injector) (domain.UserPasswordService, error) {
 	userRepository := do.MustInvoke[domain.UserRepository](i)
+	trustedDeviceRepository := do.MustInvoke[domain.TrustedDeviceRepository](i)
+	refreshTokenRepository := do.MustInvoke[domain.RefreshTokenRepository](i)
+	confirmationCodeRepository := do.MustInvoke[domain.ConfirmationCodeRepository](i)
+	revokedTokenRepository := do.MustInvoke[domain.RevokedTokenRepository](i)
 	confimatioCodeService := do.MustInvoke[domain.ConfirmationCodeService](i)
+	emailService := do.MustInvoke[domain.EmailService](i)
+	auditService := do.MustInvoke[domain.AuditService](i)
+	tokenProvider := do.MustInvoke[auth.TokenProvider](i)
 	return &userPasswordService{
-		i:                       i,
-		userRepository:          userRepository,
-		confirmationCodeService: confimatioCodeService,
+		i:                          i,
+		userRepository:             userRepository,
+		trustedDeviceRepository:    trustedDeviceRepository,
+		refreshTokenRepository:     refreshTokenRepository,
+		confirmationCodeRepository: confirmationCodeRepository,
+		revokedTokenRepository:     revokedTokenRepository,
+		confirmationCodeService:    confimatioCodeService,
+		emailService:               emailService,
+		auditService:               auditService,
+		tokenProvider:              tokenProvider,
 	}, nil
 }
 
-func (ups *userPasswordService) UpdatePassword(id string, updatePassword domain.UpdatePassword) error {
+func (ups *userPasswordService) UpdatePassword(ctx context.Context, id string, updatePassword domain.UpdatePassword, clientInfo domain.ClientInfo) error {
 	log := slog.With(
 		slog.String("service", "userPassword"),
 		slog.String("func", "Login"))
 
 	log.Info("UpdatePassword initiated")
 
-	user, err := ups.userRepository.GetById(id)
+	user, err := ups.userRepository.GetById(ctx, id)
 	if err != nil {
 		log.Error("failed to get user by id")
 		return domain.ErrGetUser
@@ -48,37 +74,101 @@ func (ups *userPasswordService) UpdatePassword(id string, updatePassword domain.
 		return domain.ErrPasswordNotMatch
 	}
 
+	if err := secure.CheckPassword(user.Password, updatePassword.New); err == nil {
+		log.Warn("new password equal to the current one")
+		return domain.ErrPasswordReuse
+	}
+
+	if strings.EqualFold(updatePassword.New, user.Email) || strings.EqualFold(updatePassword.New, user.Username) {
+		log.Warn("new password equal to the email or username")
+		return domain.ErrPasswordMatchesIdentity
+	}
+
+	if err := checkPasswordBreached(updatePassword.New); err != nil {
+		log.Warn("new password refused by the breach check", slog.Any("error", err))
+		return err
+	}
+
 	newHashedPassword, err := secure.Hash(updatePassword.New)
 	if err != nil {
 		log.Error("Error trying to hashed password")
 		return domain.ErrHashPassword
 	}
 
-	if err := ups.userRepository.UpdatePassword(id, string(newHashedPassword)); err != nil {
-		log.Error("Error: ", err)
+	// Devices trusted with the old password must prove the second factor again.
+	if err := ups.trustedDeviceRepository.DeleteByUserID(id); err != nil {
+		log.Error("Error trying to revoke trusted devices", slog.Any("error", err))
+		return domain.ErrRevokeTrustedDevice
+	}
+
+	err = ups.userRepository.UpdatePassword(ctx, id, string(newHashedPassword))
+	if errors.Is(err, domain.ErrUserNotFound) {
+		log.Warn("User deleted by a concurrent request: " + id)
+		return err
+	}
+
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
 		return domain.ErrUpdatePassword
 	}
 
+	ups.auditService.Record(domain.AuditEventPasswordChanged, id, domain.UserActor(id, clientInfo))
+
+	if err := ups.endSessions(ctx, *user, clientInfo, domain.NotificationPasswordChanged); err != nil {
+		return err
+	}
+
 	log.Info("UpdatePassword executed successfully")
 	return nil
 }
 
-func (ups *userPasswordService) ConfirmResetPasswordCode(confirmCode domain.ConfirmCode) (string, error) {
+// ForgotPassword sends a reset code when the email belongs to an account. Its outcome is never
+// told to the caller: unknown emails, rate limits and send failures are only logged, and the
+// code is sent in the background so the response time does not depend on the account existing.
+func (ups *userPasswordService) ForgotPassword(ctx context.Context, tenantID string, email string) error {
+	log := slog.With(
+		slog.String("service", "userPassword"),
+		slog.String("func", "ForgotPassword"))
+
+	log.Info("ForgotPassword initiated")
+
+	user, err := ups.userRepository.GetByEmail(ctx, tenantID, domain.NormalizeEmail(email))
+	if err != nil {
+		log.Error("Failed to obtain user by email", slog.Any("error", err))
+		return domain.ErrGetUser
+	}
+
+	if user == nil {
+		log.Warn("Password reset requested for unknown email: " + email)
+		return nil
+	}
+
+	go func() {
+		if err := ups.confirmationCodeService.SendConfirmationCode(context.WithoutCancel(ctx), user.TenantID, user.Email); err != nil {
+			log.Warn("Reset code not sent", slog.Any("error", err))
+		}
+	}()
+
+	log.Info("ForgotPassword executed successfully")
+	return nil
+}
+
+func (ups *userPasswordService) ConfirmResetPasswordCode(ctx context.Context, tenantID string, confirmCode domain.ConfirmCode) (string, error) {
 	log := slog.With(
 		slog.String("service", "userPassword"),
 		slog.String("func", "ConfirmResetPasswordCode"))
 
 	log.Info("ConfirmingResetPassword code service initiated")
 
-	user, err := ups.confirmationCodeService.ConfirmCode(confirmCode)
+	user, err := ups.confirmationCodeService.ConfirmCode(ctx, tenantID, confirmCode)
 	if err != nil {
-		log.Error("Error: ", err)
+		log.Error("Error: ", slog.Any("error", err))
 		return "", err
 	}
 
-	token, err := util.CreateResetPasswordToken(*user)
+	token, err := ups.tokenProvider.CreateResetPasswordToken(*user)
 	if err != nil {
-		log.Error("Error trying to create reset password token jwt. Error: ", err)
+		log.Error("Error trying to create reset password token jwt. Error: ", slog.Any("error", err))
 		return "", domain.ErrGenToken
 	}
 
@@ -86,14 +176,26 @@ func (ups *userPasswordService) ConfirmResetPasswordCode(confirmCode domain.Conf
 	return token, nil
 }
 
-func (ups *userPasswordService) ResetPassword(userId string, resetPassword domain.ResetPassword) error {
+// ResetPassword consumes the token returned by ConfirmResetPasswordCode: it must belong to userId
+// and works once, even when two resets race with it.
+func (ups *userPasswordService) ResetPassword(ctx context.Context, userId string, resetToken domain.TokenClaims, resetPassword domain.ResetPassword, clientInfo domain.ClientInfo) error {
 	log := slog.With(
 		slog.String("service", "userPassword"),
 		slog.String("func", "ResetPassword"))
 
 	log.Info("Reset password service initiated")
 
-	user, err := ups.userRepository.GetById(userId)
+	if resetToken.Scope != domain.ScopePasswordReset {
+		log.Warn("Token is not a reset password token")
+		return domain.ErrInvalidResetToken
+	}
+
+	if resetToken.UserID != userId {
+		log.Warn("Reset password token issued to another user: " + resetToken.UserID)
+		return domain.ErrUserIDMismatch
+	}
+
+	user, err := ups.userRepository.GetById(ctx, userId)
 	if err != nil {
 		log.Error("Failed to obtain user by id")
 		return domain.ErrGetUser
@@ -104,9 +206,19 @@ func (ups *userPasswordService) ResetPassword(userId string, resetPassword domai
 		return domain.ErrUserNotFound
 	}
 
+	if user.TokenVersion != resetToken.Version {
+		log.Warn("Reset password token no longer valid for user: " + userId)
+		return domain.ErrInvalidResetToken
+	}
+
 	if resetPassword.New != resetPassword.Confirm {
 		log.Warn("Passwords do not match")
-		return domain.ErrPasswordNotMatch
+		return domain.ErrPasswordConfirmationMismatch
+	}
+
+	if err := checkPasswordBreached(resetPassword.New); err != nil {
+		log.Warn("new password refused by the breach check", slog.Any("error", err))
+		return err
 	}
 
 	newHashedPassword, err := secure.Hash(resetPassword.New)
@@ -115,11 +227,131 @@ func (ups *userPasswordService) ResetPassword(userId string, resetPassword domai
 		return domain.ErrHashPassword
 	}
 
-	if err := ups.userRepository.UpdatePassword(user.ID, string(newHashedPassword)); err != nil {
-		log.Error("Error: ", err)
+	// The jti is the primary key of revoked tokens, so only one reset can record it.
+	err = ups.revokedTokenRepository.Create(domain.RevokedToken{
+		JTI:       resetToken.ID,
+		ExpiresAt: resetToken.ExpiresAt,
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		log.Warn("Reset password token already used", slog.Any("error", err))
+		return domain.ErrInvalidResetToken
+	}
+
+	if err := ups.trustedDeviceRepository.DeleteByUserID(user.ID); err != nil {
+		log.Error("Error trying to revoke trusted devices", slog.Any("error", err))
+		return domain.ErrRevokeTrustedDevice
+	}
+
+	err = ups.userRepository.UpdatePassword(ctx, user.ID, string(newHashedPassword))
+	if errors.Is(err, domain.ErrUserNotFound) {
+		log.Warn("User deleted by a concurrent request: " + user.ID)
+		return err
+	}
+
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
 		return domain.ErrUpdatePassword
 	}
 
+	// Proving the email is enough to lift a lockout.
+	if err := ups.userRepository.ResetFailedLogins(ctx, user.ID); err != nil {
+		log.Error("Error trying to unlock account", slog.Any("error", err))
+	}
+
+	if user.MustResetPassword {
+		if err := ups.userRepository.SetMustResetPassword(ctx, user.ID, false); err != nil {
+			log.Error("Error trying to clear the password reset requirement", slog.Any("error", err))
+		}
+	}
+
+	ups.auditService.Record(domain.AuditEventPasswordReset, user.ID, domain.UserActor(user.ID, clientInfo))
+
+	if err := ups.endSessions(ctx, *user, clientInfo, domain.NotificationPasswordReset); err != nil {
+		return err
+	}
+
 	log.Info("ResetPassword executed successfully")
 	return nil
 }
+
+// Strength rates a candidate password and lists the policy rules it breaks. The password is
+// never logged.
+func (ups *userPasswordService) Strength(payLoad domain.PasswordStrengthPayLoad) domain.PasswordStrengthResponse {
+	log := slog.With(
+		slog.String("service", "userPassword"),
+		slog.String("func", "Strength"))
+
+	log.Info("Strength initiated")
+
+	password := secure.NormalizePassword(payLoad.Password)
+
+	var userInputs []string
+	for _, input := range []string{payLoad.Email, payLoad.Username} {
+		if input != "" {
+			userInputs = append(userInputs, input)
+		}
+	}
+	if local, _, found := strings.Cut(payLoad.Email, "@"); found && local != "" {
+		userInputs = append(userInputs, local)
+	}
+
+	failedRules := domain.CurrentPasswordPolicy().Violations(password)
+	if (payLoad.Email != "" && strings.EqualFold(password, payLoad.Email)) ||
+		(payLoad.Username != "" && strings.EqualFold(password, payLoad.Username)) {
+		failedRules = append(failedRules, domain.PasswordPolicyError{
+			Rule:    domain.PasswordRuleIdentity,
+			Message: domain.ErrPasswordMatchesIdentity.Error(),
+		})
+	}
+
+	if failedRules == nil {
+		failedRules = []domain.PasswordPolicyError{}
+	}
+
+	strength := zxcvbn.PasswordStrength(password, userInputs)
+
+	log.Info("Strength executed successfully")
+	return domain.PasswordStrengthResponse{
+		Score:            strength.Score,
+		CrackTimeSeconds: strength.CrackTime,
+		CrackTimeDisplay: strength.CrackTimeDisplay,
+		Acceptable:       len(failedRules) == 0,
+		FailedRules:      failedRules,
+	}
+}
+
+// Private session
+
+// endSessions makes the old password worthless to whoever held it: every refresh token is
+// revoked, the token version bump invalidates access and reset tokens, and a reset code still
+// pending is dropped. The owner is then sent notificationType, telling where the change came from.
+func (ups *userPasswordService) endSessions(ctx context.Context, user domain.User, clientInfo domain.ClientInfo, notificationType string) error {
+	log := slog.With(
+		slog.String("service", "userPassword"),
+		slog.String("func", "endSessions"))
+
+	if err := ups.refreshTokenRepository.RevokeAllByUserID(user.ID); err != nil {
+		log.Error("Failed to revoke refresh tokens", slog.Any("error", err))
+		return domain.ErrRevokeToken
+	}
+
+	if err := ups.userRepository.IncrementTokenVersion(ctx, user.ID); err != nil {
+		log.Error("Failed to increment token version", slog.Any("error", err))
+		return domain.ErrRevokeToken
+	}
+
+	if err := ups.confirmationCodeRepository.Delete(user.TenantID, user.Email); err != nil {
+		log.Error("Failed to delete pending code", slog.Any("error", err))
+	}
+
+	ups.emailService.Notify(domain.Notification{
+		Type:     notificationType,
+		Name:     user.Name,
+		Locale:   user.Locale,
+		Timezone: user.Timezone,
+		IP:       clientInfo.IP,
+	}, []string{user.Email})
+
+	return nil
+}
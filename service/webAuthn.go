@@ -0,0 +1,471 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/OVillas/autentication/domain"
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/google/uuid"
+	"github.com/samber/do"
+)
+
+type webAuthnService struct {
+	i                            *do.Injector
+	webAuthn                     *webauthn.WebAuthn
+	userRepository               domain.UserRepository
+	webAuthnCredentialRepository domain.WebAuthnCredentialRepository
+	webAuthnSessionRepository    domain.WebAuthnSessionRepository
+	userService                  domain.UserService
+	auditService                 domain.AuditService
+}
+
+func NewWebAuthnService(i *do.Injector) (domain.WebAuthnService, error) {
+	webAuthn := do.MustInvoke[*webauthn.WebAuthn](i)
+	userRepository := do.MustInvoke[domain.UserRepository](i)
+	webAuthnCredentialRepository := do.MustInvoke[domain.WebAuthnCredentialRepository](i)
+	webAuthnSessionRepository := do.MustInvoke[domain.WebAuthnSessionRepository](i)
+	userService := do.MustInvoke[domain.UserService](i)
+	auditService := do.MustInvoke[domain.AuditService](i)
+	return &webAuthnService{
+		i:                            i,
+		webAuthn:                     webAuthn,
+		userRepository:               userRepository,
+		webAuthnCredentialRepository: webAuthnCredentialRepository,
+		webAuthnSessionRepository:    webAuthnSessionRepository,
+		userService:                  userService,
+		auditService:                 auditService,
+	}, nil
+}
+
+func (was *webAuthnService) BeginRegistration(ctx context.Context, userID string) (*domain.WebAuthnBeginResponse, error) {
+	log := slog.With(
+		slog.String("service", "webAuthn"),
+		slog.String("func", "BeginRegistration"))
+
+	log.Info("BeginRegistration initiated")
+
+	if was.webAuthn == nil {
+		return nil, domain.ErrWebAuthnDisabled
+	}
+
+	user, err := was.loadUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var exclusions []protocol.CredentialDescriptor
+	for _, credential := range user.credentials {
+		exclusions = append(exclusions, credential.Descriptor())
+	}
+
+	creation, sessionData, err := was.webAuthn.BeginRegistration(user, webauthn.WithExclusions(exclusions))
+	if err != nil {
+		log.Error("Error trying to begin passkey registration", slog.Any("error", err))
+		return nil, domain.ErrBeginWebAuthn
+	}
+
+	sessionID, err := was.saveSession(domain.WebAuthnCeremonyRegistration, userID, *sessionData)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Info("BeginRegistration executed successfully")
+	return &domain.WebAuthnBeginResponse{SessionID: sessionID, Options: creation}, nil
+}
+
+func (was *webAuthnService) FinishRegistration(ctx context.Context, userID string, payLoad domain.WebAuthnRegisterPayLoad) (*domain.WebAuthnCredentialResponse, error) {
+	log := slog.With(
+		slog.String("service", "webAuthn"),
+		slog.String("func", "FinishRegistration"))
+
+	log.Info("FinishRegistration initiated")
+
+	if was.webAuthn == nil {
+		return nil, domain.ErrWebAuthnDisabled
+	}
+
+	sessionData, err := was.useSession(payLoad.SessionID, domain.WebAuthnCeremonyRegistration, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := was.loadUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	parsedResponse, err := protocol.ParseCredentialCreationResponseBody(bytes.NewReader(payLoad.Credential))
+	if err != nil {
+		log.Warn("Invalid passkey registration response", slog.Any("error", err))
+		return nil, domain.ErrWebAuthnVerification
+	}
+
+	credential, err := was.webAuthn.CreateCredential(user, *sessionData, parsedResponse)
+	if err != nil {
+		log.Warn("Passkey registration refused", slog.Any("error", err))
+		return nil, domain.ErrWebAuthnVerification
+	}
+
+	name := strings.TrimSpace(payLoad.Name)
+	if name == "" {
+		name = "Passkey " + time.Now().Format("2006-01-02")
+	}
+
+	var transports []string
+	for _, transport := range credential.Transport {
+		transports = append(transports, string(transport))
+	}
+
+	webAuthnCredential := domain.WebAuthnCredential{
+		ID:              uuid.NewString(),
+		UserID:          userID,
+		CredentialID:    base64.RawURLEncoding.EncodeToString(credential.ID),
+		PublicKey:       credential.PublicKey,
+		AttestationType: credential.AttestationType,
+		AAGUID:          credential.Authenticator.AAGUID,
+		SignCount:       credential.Authenticator.SignCount,
+		Transports:      strings.Join(transports, ","),
+		BackupEligible:  credential.Flags.BackupEligible,
+		BackupState:     credential.Flags.BackupState,
+		Name:            name,
+	}
+
+	if err := was.webAuthnCredentialRepository.Create(webAuthnCredential); err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return nil, domain.ErrCreateWebAuthnCredential
+	}
+
+	log.Info("FinishRegistration executed successfully")
+	return webAuthnCredential.ToWebAuthnCredentialResponse(), nil
+}
+
+// BeginLogin starts a login without username: the browser offers every passkey it holds for
+// this relying party and the one picked tells who the user is.
+func (was *webAuthnService) BeginLogin() (*domain.WebAuthnBeginResponse, error) {
+	log := slog.With(
+		slog.String("service", "webAuthn"),
+		slog.String("func", "BeginLogin"))
+
+	log.Info("BeginLogin initiated")
+
+	if was.webAuthn == nil {
+		return nil, domain.ErrWebAuthnDisabled
+	}
+
+	assertion, sessionData, err := was.webAuthn.BeginDiscoverableLogin(webauthn.WithUserVerification(protocol.VerificationRequired))
+	if err != nil {
+		log.Error("Error trying to begin passkey login", slog.Any("error", err))
+		return nil, domain.ErrBeginWebAuthn
+	}
+
+	sessionID, err := was.saveSession(domain.WebAuthnCeremonyLogin, "", *sessionData)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Info("BeginLogin executed successfully")
+	return &domain.WebAuthnBeginResponse{SessionID: sessionID, Options: assertion}, nil
+}
+
+// FinishLogin opens a session for the owner of the passkey. A signature counter that did not
+// move forward refuses the login: it means another copy of the key has been used.
+func (was *webAuthnService) FinishLogin(ctx context.Context, payLoad domain.WebAuthnLoginPayLoad, clientInfo domain.ClientInfo) (*domain.LoginResponse, error) {
+	log := slog.With(
+		slog.String("service", "webAuthn"),
+		slog.String("func", "FinishLogin"))
+
+	log.Info("FinishLogin initiated")
+
+	if was.webAuthn == nil {
+		return nil, domain.ErrWebAuthnDisabled
+	}
+
+	sessionData, err := was.useSession(payLoad.SessionID, domain.WebAuthnCeremonyLogin, "")
+	if err != nil {
+		return nil, err
+	}
+
+	parsedResponse, err := protocol.ParseCredentialRequestResponseBody(bytes.NewReader(payLoad.Credential))
+	if err != nil {
+		log.Warn("Invalid passkey login response", slog.Any("error", err))
+		return nil, domain.ErrWebAuthnVerification
+	}
+
+	var owner *webAuthnUser
+	credential, err := was.webAuthn.ValidateDiscoverableLogin(func(rawID, userHandle []byte) (webauthn.User, error) {
+		user, err := was.loadUser(ctx, string(userHandle))
+		if err != nil {
+			return nil, err
+		}
+
+		owner = user
+		return user, nil
+	}, *sessionData, parsedResponse)
+	if err != nil {
+		log.Warn("Passkey login refused", slog.Any("error", err))
+		return nil, domain.ErrWebAuthnVerification
+	}
+
+	webAuthnCredential, err := was.webAuthnCredentialRepository.GetByCredentialID(base64.RawURLEncoding.EncodeToString(credential.ID))
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return nil, domain.ErrGetWebAuthnCredential
+	}
+
+	if webAuthnCredential == nil || webAuthnCredential.UserID != owner.user.ID {
+		log.Warn("Passkey no longer registered")
+		return nil, domain.ErrWebAuthnVerification
+	}
+
+	updated := false
+	if !credential.Authenticator.CloneWarning {
+		updated, err = was.webAuthnCredentialRepository.UpdateSignCount(webAuthnCredential.ID, credential.Authenticator.SignCount, credential.Flags.BackupState)
+		if err != nil {
+			log.Error("Error: ", slog.Any("error", err))
+			return nil, domain.ErrUpdateWebAuthnCredential
+		}
+	}
+
+	if !updated {
+		log.Warn("Passkey signature counter regression for credential: " + webAuthnCredential.ID)
+		was.auditService.Record(domain.AuditEventWebAuthnCloneWarning, owner.user.ID, domain.AnonymousActor(clientInfo),
+			slog.String("credentialId", webAuthnCredential.ID))
+		return nil, domain.ErrWebAuthnCloneWarning
+	}
+
+	loginResponse, err := was.userService.CreateSession(ctx, owner.user.ID, clientInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Info("FinishLogin executed successfully")
+	return loginResponse, nil
+}
+
+func (was *webAuthnService) GetAll(userID string) ([]domain.WebAuthnCredentialResponse, error) {
+	log := slog.With(
+		slog.String("service", "webAuthn"),
+		slog.String("func", "GetAll"))
+
+	log.Info("GetAll initiated")
+
+	webAuthnCredentials, err := was.webAuthnCredentialRepository.GetByUserID(userID)
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return nil, domain.ErrGetWebAuthnCredential
+	}
+
+	var response []domain.WebAuthnCredentialResponse
+	for _, webAuthnCredential := range webAuthnCredentials {
+		response = append(response, *webAuthnCredential.ToWebAuthnCredentialResponse())
+	}
+
+	log.Info("GetAll executed successfully")
+	return response, nil
+}
+
+func (was *webAuthnService) Rename(userID string, id string, name string) error {
+	log := slog.With(
+		slog.String("service", "webAuthn"),
+		slog.String("func", "Rename"))
+
+	log.Info("Rename initiated")
+
+	renamed, err := was.webAuthnCredentialRepository.Rename(userID, id, strings.TrimSpace(name))
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return domain.ErrUpdateWebAuthnCredential
+	}
+
+	if !renamed {
+		log.Warn("Passkey not found with this id: " + id)
+		return domain.ErrWebAuthnCredentialNotFound
+	}
+
+	log.Info("Rename executed successfully")
+	return nil
+}
+
+func (was *webAuthnService) Delete(userID string, id string) error {
+	log := slog.With(
+		slog.String("service", "webAuthn"),
+		slog.String("func", "Delete"))
+
+	log.Info("Delete initiated")
+
+	deleted, err := was.webAuthnCredentialRepository.Delete(userID, id)
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return domain.ErrDeleteWebAuthnCredential
+	}
+
+	if !deleted {
+		log.Warn("Passkey not found with this id: " + id)
+		return domain.ErrWebAuthnCredentialNotFound
+	}
+
+	log.Info("Delete executed successfully")
+	return nil
+}
+
+// Private session
+func (was *webAuthnService) loadUser(ctx context.Context, userID string) (*webAuthnUser, error) {
+	log := slog.With(
+		slog.String("service", "webAuthn"),
+		slog.String("func", "loadUser"))
+
+	user, err := was.userRepository.GetById(ctx, userID)
+	if err != nil {
+		log.Error("Failed to obtain user by id", slog.Any("error", err))
+		return nil, domain.ErrGetUser
+	}
+
+	if user == nil {
+		log.Warn("User not found with this id: " + userID)
+		return nil, domain.ErrUserNotFound
+	}
+
+	webAuthnCredentials, err := was.webAuthnCredentialRepository.GetByUserID(userID)
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return nil, domain.ErrGetWebAuthnCredential
+	}
+
+	webAuthnUser := &webAuthnUser{user: *user}
+	for _, webAuthnCredential := range webAuthnCredentials {
+		credential, err := toCredential(webAuthnCredential)
+		if err != nil {
+			log.Error("Error trying to decode passkey: "+webAuthnCredential.ID, slog.Any("error", err))
+			continue
+		}
+		webAuthnUser.credentials = append(webAuthnUser.credentials, *credential)
+	}
+
+	return webAuthnUser, nil
+}
+
+func (was *webAuthnService) saveSession(ceremony string, userID string, sessionData webauthn.SessionData) (string, error) {
+	log := slog.With(
+		slog.String("service", "webAuthn"),
+		slog.String("func", "saveSession"))
+
+	data, err := json.Marshal(sessionData)
+	if err != nil {
+		log.Error("Error trying to encode webauthn session", slog.Any("error", err))
+		return "", domain.ErrBeginWebAuthn
+	}
+
+	webAuthnSession := domain.WebAuthnSession{
+		ID:        uuid.NewString(),
+		Ceremony:  ceremony,
+		UserID:    userID,
+		Data:      string(data),
+		ExpiresAt: time.Now().Add(domain.WebAuthnSessionTTL),
+	}
+
+	if err := was.webAuthnSessionRepository.Create(webAuthnSession); err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return "", domain.ErrBeginWebAuthn
+	}
+
+	return webAuthnSession.ID, nil
+}
+
+// useSession consumes the ceremony before the authenticator response is checked, so each
+// challenge is verified at most once.
+func (was *webAuthnService) useSession(id string, ceremony string, userID string) (*webauthn.SessionData, error) {
+	log := slog.With(
+		slog.String("service", "webAuthn"),
+		slog.String("func", "useSession"))
+
+	webAuthnSession, err := was.webAuthnSessionRepository.GetById(id)
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return nil, domain.ErrInvalidWebAuthnSession
+	}
+
+	if webAuthnSession == nil || webAuthnSession.Ceremony != ceremony || webAuthnSession.UserID != userID {
+		log.Warn("Webauthn session not found for this ceremony: " + id)
+		return nil, domain.ErrInvalidWebAuthnSession
+	}
+
+	used, err := was.webAuthnSessionRepository.Use(id)
+	if err != nil {
+		log.Error("Error: ", slog.Any("error", err))
+		return nil, domain.ErrInvalidWebAuthnSession
+	}
+
+	if !used {
+		log.Warn("Webauthn session expired or already used: " + id)
+		return nil, domain.ErrInvalidWebAuthnSession
+	}
+
+	var sessionData webauthn.SessionData
+	if err := json.Unmarshal([]byte(webAuthnSession.Data), &sessionData); err != nil {
+		log.Error("Error trying to decode webauthn session", slog.Any("error", err))
+		return nil, domain.ErrInvalidWebAuthnSession
+	}
+
+	return &sessionData, nil
+}
+
+// webAuthnUser adapts a user and its stored passkeys to the webauthn library. The user handle
+// is the user id.
+type webAuthnUser struct {
+	user        domain.User
+	credentials []webauthn.Credential
+}
+
+func (wau *webAuthnUser) WebAuthnID() []byte {
+	return []byte(wau.user.ID)
+}
+
+func (wau *webAuthnUser) WebAuthnName() string {
+	return wau.user.Username
+}
+
+func (wau *webAuthnUser) WebAuthnDisplayName() string {
+	return wau.user.Name
+}
+
+func (wau *webAuthnUser) WebAuthnCredentials() []webauthn.Credential {
+	return wau.credentials
+}
+
+func (wau *webAuthnUser) WebAuthnIcon() string {
+	return ""
+}
+
+func toCredential(webAuthnCredential domain.WebAuthnCredential) (*webauthn.Credential, error) {
+	id, err := base64.RawURLEncoding.DecodeString(webAuthnCredential.CredentialID)
+	if err != nil {
+		return nil, err
+	}
+
+	var transports []protocol.AuthenticatorTransport
+	for _, transport := range strings.FieldsFunc(webAuthnCredential.Transports, func(r rune) bool { return r == ',' }) {
+		transports = append(transports, protocol.AuthenticatorTransport(transport))
+	}
+
+	return &webauthn.Credential{
+		ID:              id,
+		PublicKey:       webAuthnCredential.PublicKey,
+		AttestationType: webAuthnCredential.AttestationType,
+		Transport:       transports,
+		Flags: webauthn.CredentialFlags{
+			BackupEligible: webAuthnCredential.BackupEligible,
+			BackupState:    webAuthnCredential.BackupState,
+		},
+		Authenticator: webauthn.Authenticator{
+			AAGUID:    webAuthnCredential.AAGUID,
+			SignCount: webAuthnCredential.SignCount,
+		},
+	}, nil
+}
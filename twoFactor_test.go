@@ -0,0 +1,117 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/OVillas/autentication/domain"
+	"github.com/pquerna/otp/totp"
+)
+
+func TestTOTPCodeIsAcceptedOnce(t *testing.T) {
+	ts := newTestServer(t)
+	user := ts.register(t)
+	secret := ts.enrollTOTP(t, user)
+
+	// The code that confirmed the app cannot open a login, and neither can a code that already did.
+	if response := ts.loginTwoFactor(ts.challenge(t, user), secret.confirmed); response.Code != http.StatusUnauthorized {
+		t.Fatalf("code of the enrollment: status %d, want %d: %s", response.Code, http.StatusUnauthorized, response.Body)
+	}
+
+	code := secret.next(t)
+	if response := ts.loginTwoFactor(ts.challenge(t, user), code); response.Code != http.StatusOK {
+		t.Fatalf("new code: status %d, want %d: %s", response.Code, http.StatusOK, response.Body)
+	}
+
+	if response := ts.loginTwoFactor(ts.challenge(t, user), code); response.Code != http.StatusUnauthorized {
+		t.Errorf("code used again: status %d, want %d: %s", response.Code, http.StatusUnauthorized, response.Body)
+	}
+}
+
+func TestTwoFactorChallengeIsRevokedAfterTooManyWrongCodes(t *testing.T) {
+	ts := newTestServer(t)
+	user := ts.register(t)
+	secret := ts.enrollTOTP(t, user)
+	challenge := ts.challenge(t, user)
+
+	for n := 0; n < domain.TwoFactorChallengeMaxAttempts; n++ {
+		if response := ts.loginTwoFactor(challenge, "000000"); response.Code != http.StatusUnauthorized {
+			t.Fatalf("wrong code %d: status %d, want %d: %s", n+1, response.Code, http.StatusUnauthorized, response.Body)
+		}
+	}
+
+	if response := ts.loginTwoFactor(challenge, secret.next(t)); response.Code != http.StatusUnauthorized {
+		t.Fatalf("right code on a revoked challenge: status %d, want %d: %s", response.Code, http.StatusUnauthorized, response.Body)
+	}
+
+	// A new challenge starts the count over.
+	if response := ts.loginTwoFactor(ts.challenge(t, user), secret.next(t)); response.Code != http.StatusOK {
+		t.Errorf("right code on a new challenge: status %d, want %d: %s", response.Code, http.StatusOK, response.Body)
+	}
+}
+
+// testTOTP is the authenticator app of a user, with the code that confirmed it.
+type testTOTP struct {
+	secret    string
+	confirmed string
+}
+
+// next returns the code of the next 30 second step, which the API accepts for clock drift and
+// which no test used yet.
+func (tt testTOTP) next(t *testing.T) string {
+	t.Helper()
+
+	code, err := totp.GenerateCode(tt.secret, time.Now().Add(30*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return code
+}
+
+// enrollTOTP turns on two-factor authentication with an authenticator app for user.
+func (ts *testServer) enrollTOTP(t *testing.T, user testUser) testTOTP {
+	t.Helper()
+
+	token := ts.login(t, user, false).AccessToken
+	response := ts.request(http.MethodPost, "/v1/users/me/2fa/totp", nil, token)
+	if response.Code != http.StatusOK {
+		t.Fatalf("enable totp: status %d: %s", response.Code, response.Body)
+	}
+
+	secret := decode[domain.TOTPEnrollmentResponse](t, response).Secret
+	code, err := totp.GenerateCode(secret, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	response = ts.request(http.MethodPost, "/v1/users/me/2fa/totp/confirm", domain.TOTPCodePayLoad{Code: code}, token)
+	if response.Code != http.StatusNoContent {
+		t.Fatalf("confirm totp: status %d: %s", response.Code, response.Body)
+	}
+
+	return testTOTP{secret: secret, confirmed: code}
+}
+
+// challenge logs user in up to the second factor and returns the challenge token.
+func (ts *testServer) challenge(t *testing.T, user testUser) string {
+	t.Helper()
+
+	response := ts.request(http.MethodPost, "/v1/auth/login", domain.Login{Identifier: user.Username, Password: user.Password}, "")
+	if response.Code != http.StatusOK {
+		t.Fatalf("login: status %d: %s", response.Code, response.Body)
+	}
+
+	challenge := decode[domain.TwoFactorChallengeResponse](t, response)
+	if !challenge.TwoFactorRequired || challenge.ChallengeToken == "" {
+		t.Fatalf("login: got %s, want a two-factor challenge", response.Body)
+	}
+
+	return challenge.ChallengeToken
+}
+
+func (ts *testServer) loginTwoFactor(challenge string, code string) *httptest.ResponseRecorder {
+	return ts.request(http.MethodPost, "/v1/auth/login/2fa", domain.TwoFactorLoginPayLoad{Method: domain.TwoFactorMethodTOTP, Code: code}, challenge)
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/OVillas/autentication/domain"
+)
+
+func TestPatchChangesOnlyTheFieldsSent(t *testing.T) {
+	pointer := func(value string) *string { return &value }
+
+	tests := []struct {
+		name    string
+		payLoad func(user testUser) domain.UserUpdatePayLoad
+		status  int
+		want    func(user *domain.User)
+	}{
+		{"name", func(testUser) domain.UserUpdatePayLoad {
+			return domain.UserUpdatePayLoad{Name: pointer("Renamed User")}
+		}, http.StatusNoContent, func(user *domain.User) { user.Name = "Renamed User" }},
+		{"username", func(user testUser) domain.UserUpdatePayLoad {
+			return domain.UserUpdatePayLoad{Username: pointer("re" + user.Username)}
+		}, http.StatusNoContent, func(user *domain.User) { user.Username = "re" + user.Username }},
+		{"locale", func(testUser) domain.UserUpdatePayLoad {
+			return domain.UserUpdatePayLoad{Locale: pointer("pt-BR")}
+		}, http.StatusNoContent, func(user *domain.User) { user.Locale = "pt-BR" }},
+		{"timezone", func(testUser) domain.UserUpdatePayLoad {
+			return domain.UserUpdatePayLoad{Timezone: pointer("America/Sao_Paulo")}
+		}, http.StatusNoContent, func(user *domain.User) { user.Timezone = "America/Sao_Paulo" }},
+		// A new email only replaces the current one once the code sent to it is confirmed.
+		{"email", func(user testUser) domain.UserUpdatePayLoad {
+			return domain.UserUpdatePayLoad{Email: pointer("new" + user.Email), Password: user.Password}
+		}, http.StatusAccepted, func(*domain.User) {}},
+		{"nothing", func(testUser) domain.UserUpdatePayLoad {
+			return domain.UserUpdatePayLoad{}
+		}, http.StatusNoContent, func(*domain.User) {}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ts := newTestServer(t)
+			user := ts.register(t)
+			token := ts.login(t, user, false).AccessToken
+
+			want, err := ts.userRepository().GetById(context.Background(), user.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			test.want(want)
+
+			response := ts.request(http.MethodPatch, "/v1/users/me", test.payLoad(user), token)
+			if response.Code != test.status {
+				t.Fatalf("status %d, want %d: %s", response.Code, test.status, response.Body)
+			}
+
+			got, err := ts.userRepository().GetById(context.Background(), user.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got.Name != want.Name || got.Username != want.Username || got.Email != want.Email ||
+				got.Locale != want.Locale || got.Timezone != want.Timezone || got.Password != want.Password ||
+				got.EmailConfirmed != want.EmailConfirmed || !got.CreatedAt.Equal(want.CreatedAt) {
+				t.Errorf("got %+v\nwant %+v", got, want)
+			}
+		})
+	}
+}
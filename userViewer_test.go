@@ -0,0 +1,88 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/OVillas/autentication/domain"
+	"github.com/samber/do"
+)
+
+func TestGetByIdShowsTheAccountOnlyToTheUserAndAdmins(t *testing.T) {
+	ts := newTestServer(t)
+	user := ts.register(t)
+	admin := ts.register(t)
+	ts.makeAdmin(t, admin)
+
+	tests := []struct {
+		name        string
+		viewer      testUser
+		seesAccount bool
+	}{
+		{"the user", user, true},
+		{"an admin", admin, true},
+		{"another user", ts.register(t), false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			token := ts.login(t, test.viewer, false).AccessToken
+
+			response := ts.request(http.MethodGet, "/v1/users/"+user.ID, nil, token)
+			if response.Code != http.StatusOK {
+				t.Fatalf("status %d: %s", response.Code, response.Body)
+			}
+
+			profile := decode[map[string]any](t, response)
+			if profile["Id"] != user.ID || profile["Username"] != user.Username {
+				t.Errorf("got the profile %v, want the one of %s", profile, user.Username)
+			}
+
+			if test.seesAccount {
+				if profile["Email"] != user.Email {
+					t.Errorf("Email = %v, want %s", profile["Email"], user.Email)
+				}
+				return
+			}
+
+			if len(profile) != 3 || profile["Name"] == nil {
+				t.Errorf("got %v, want only the public profile", profile)
+			}
+		})
+	}
+}
+
+func TestSearchShowsOnlyPublicProfiles(t *testing.T) {
+	ts := newTestServer(t)
+	user := ts.register(t)
+	token := ts.login(t, user, false).AccessToken
+
+	response := ts.request(http.MethodGet, "/v1/users/name?name="+user.Username, nil, token)
+	if response.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", response.Code, response.Body)
+	}
+
+	page := decode[domain.Page[map[string]any]](t, response)
+	if len(page.Items) != 1 {
+		t.Fatalf("found %d users, want %s", len(page.Items), user.Username)
+	}
+
+	if profile := page.Items[0]; len(profile) != 3 || profile["Id"] != user.ID {
+		t.Errorf("got %v, want only the public profile of %s", profile, user.Username)
+	}
+}
+
+// makeAdmin assigns the admin role to user, for the tokens of its next logins.
+func (ts *testServer) makeAdmin(t *testing.T, user testUser) {
+	t.Helper()
+
+	roleRepository := do.MustInvoke[domain.RoleRepository](ts.i)
+	role, err := roleRepository.GetByName(domain.RoleAdmin)
+	if err != nil || role == nil {
+		t.Fatalf("the admin role does not exist: %v", err)
+	}
+
+	if _, err := roleRepository.Assign(domain.UserRole{UserID: user.ID, RoleID: role.ID}); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,22 @@
+package util
+
+import (
+	"net"
+)
+
+// IPPrefix returns the network an address belongs to, /24 for IPv4 and /64 for IPv6, so a
+// client moving between addresses of the same network keeps the same value.
+func IPPrefix(ip string) string {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return ""
+	}
+
+	if ipv4 := parsed.To4(); ipv4 != nil {
+		network := net.IPNet{IP: ipv4.Mask(net.CIDRMask(24, 32)), Mask: net.CIDRMask(24, 32)}
+		return network.String()
+	}
+
+	network := net.IPNet{IP: parsed.Mask(net.CIDRMask(64, 128)), Mask: net.CIDRMask(64, 128)}
+	return network.String()
+}
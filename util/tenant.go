@@ -0,0 +1,11 @@
+package util
+
+import (
+	"github.com/labstack/echo/v4"
+)
+
+// ExtractTenant returns the tenant the request was scoped to by the tenant middleware.
+func ExtractTenant(c echo.Context) string {
+	tenant, _ := c.Get(TenantContextKey).(string)
+	return tenant
+}
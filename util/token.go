@@ -2,106 +2,96 @@ package util
 
 import (
 	"crypto/rand"
-	"io"
-	"strings"
-	"time"
+	"math/big"
 
-	"github.com/OVillas/autentication/config"
 	"github.com/OVillas/autentication/domain"
-	"github.com/golang-jwt/jwt"
 	"github.com/labstack/echo/v4"
 )
 
-var table = [...]byte{'1', '2', '3', '4', '5', '6', '7', '8', '9', '0'}
-
-func CreateToken(user domain.User) (string, error) {
+const (
+	// UserIDContextKey is where the auth middleware stores the authenticated user's ID, whatever
+	// credential (access token or personal access token) was presented.
+	UserIDContextKey = "userId"
+	// TokenClaimsContextKey holds the claims of the access token that authenticated the request.
+	TokenClaimsContextKey = "tokenClaims"
+	// CallerTypeContextKey tells whether a user (domain.CallerUser), a machine client
+	// (domain.CallerClient), a service with an api key (domain.CallerApiKey) or an operator with
+	// the admin key (domain.CallerAdmin) made the request.
+	CallerTypeContextKey = "callerType"
+	// TenantContextKey holds the tenant the request is scoped to, set by the tenant middleware
+	// before anything else runs.
+	TenantContextKey = "tenant"
+)
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"id":    user.ID,
-		"name":  user.Name,
-		"email": user.Email,
-		"exp":   time.Now().Add(time.Hour * 6).Unix(),
-	})
+const (
+	OTPDigits       = "0123456789"
+	OTPAlphanumeric = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+)
 
-	tokenString, err := token.SignedString([]byte(config.SecretKey))
-	if err != nil {
-		return "", err
+func ExtractTokenClaims(c echo.Context) (*domain.TokenClaims, error) {
+	claims, ok := c.Get(TokenClaimsContextKey).(*domain.TokenClaims)
+	if !ok {
+		return nil, domain.ErrInvalidToken
 	}
 
-	return tokenString, nil
+	return claims, nil
 }
 
-func CreateResetPasswordToken(user domain.User) (string, error) {
-
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"id":  user.ID,
-		"exp": time.Now().Add(time.Hour * 6).Unix(),
-	})
+func IsClientCaller(c echo.Context) bool {
+	callerType, _ := c.Get(CallerTypeContextKey).(string)
+	return callerType == domain.CallerClient
+}
 
-	tokenString, err := token.SignedString([]byte(config.SecretKey))
-	if err != nil {
-		return "", err
+// HasRole reports whether the request was made with the admin key, which holds every role, or
+// by a user whose access token carries role.
+func HasRole(c echo.Context, role string) bool {
+	if callerType, _ := c.Get(CallerTypeContextKey).(string); callerType == domain.CallerAdmin {
+		return true
 	}
 
-	return tokenString, nil
-}
-
-func getVerificationKey(token *jwt.Token) (interface{}, error) {
-	if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-		return nil, domain.ErrUnexpectedSigningMethod
+	claims, err := ExtractTokenClaims(c)
+	if err != nil {
+		return false
 	}
 
-	return config.SecretKey, nil
+	return claims.UserID != "" && claims.HasRole(role)
 }
 
-func extractToken(c echo.Context) string {
-	token := c.Request().Header.Get("Authorization")
-
-	length := len(strings.Split(token, " "))
-	if length == 2 {
-		return strings.Split(token, " ")[1]
+func ExtractUserIdFromToken(c echo.Context) (string, error) {
+	if id, ok := c.Get(UserIDContextKey).(string); ok && id != "" {
+		return id, nil
 	}
-												
-	return ""
-}
 
-func ExtractUserIdFromToken(c echo.Context) (string, error) {
-	tokenString := extractToken(c)
-	token, err := jwt.Parse(tokenString, getVerificationKey)
+	claims, err := ExtractTokenClaims(c)
 	if err != nil {
 		return "", err
 	}
 
-	permissions, ok := token.Claims.(jwt.MapClaims)
-	if !ok && !token.Valid {
-		return "", domain.ErrInvalidToken
-	}
-
-	idInterface, exists := permissions["id"]
-	if !exists {
+	if claims.UserID == "" {
 		return "", domain.ErrIdNotFoundInPermissions
 	}
 
-	id, ok := idInterface.(string)
-	if !ok {
-		return "", domain.ErrIdIsNotAString
-	}
-
-	if err := IsValidUUID(id); err != nil {
+	if err := IsValidUUID(claims.UserID); err != nil {
 		return "", domain.ErrInvalidId
 	}
 
-	return id, nil
+	return claims.UserID, nil
 }
 
 func GenerateOTP(max int) string {
-	b := make([]byte, max)
-	n, err := io.ReadAtLeast(rand.Reader, b, max)
-	if n != max {
-		panic(err)
-	}
-	for i := 0; i < len(b); i++ {
-		b[i] = table[int(b[i])%len(table)]
+	return GenerateCode(max, OTPDigits)
+}
+
+// GenerateCode draws each character uniformly from alphabet with crypto/rand.
+func GenerateCode(length int, alphabet string) string {
+	b := make([]byte, length)
+	max := big.NewInt(int64(len(alphabet)))
+	for i := range b {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			panic(err)
+		}
+		b[i] = alphabet[n.Int64()]
 	}
 	return string(b)
 }